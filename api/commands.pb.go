@@ -22,6 +22,328 @@ const (
 	_ = protoimpl.EnforceVersion(protoimpl.MaxVersion - 20)
 )
 
+type ChangeOp int32
+
+const (
+	ChangeOp_CHANGE_OP_UNSPECIFIED  ChangeOp = 0
+	ChangeOp_CHANGE_OP_SET          ChangeOp = 1
+	ChangeOp_CHANGE_OP_DELETE       ChangeOp = 2
+	ChangeOp_CHANGE_OP_BATCH_DELETE ChangeOp = 3
+	// CHANGE_OP_SNAPSHOT is a snapshot boundary: the writes up to index are
+	// no longer retained, and some of them were skipped. Re-read the keys
+	// (with Scan, or from a backup) to catch up, then carry on.
+	ChangeOp_CHANGE_OP_SNAPSHOT ChangeOp = 4
+	// CHANGE_OP_PROGRESS carries no write: every write up to index has been
+	// sent. Only sent when asked for, see SubscribeRequest.
+	ChangeOp_CHANGE_OP_PROGRESS ChangeOp = 5
+	// CHANGE_OP_PUT is a write to a list, queue, lock, stream, rate limiter,
+	// Bloom filter or HyperLogLog: id now holds entry. One that deleted the
+	// key is a CHANGE_OP_DELETE.
+	ChangeOp_CHANGE_OP_PUT ChangeOp = 6
+)
+
+// Enum value maps for ChangeOp.
+var (
+	ChangeOp_name = map[int32]string{
+		0: "CHANGE_OP_UNSPECIFIED",
+		1: "CHANGE_OP_SET",
+		2: "CHANGE_OP_DELETE",
+		3: "CHANGE_OP_BATCH_DELETE",
+		4: "CHANGE_OP_SNAPSHOT",
+		5: "CHANGE_OP_PROGRESS",
+		6: "CHANGE_OP_PUT",
+	}
+	ChangeOp_value = map[string]int32{
+		"CHANGE_OP_UNSPECIFIED":  0,
+		"CHANGE_OP_SET":          1,
+		"CHANGE_OP_DELETE":       2,
+		"CHANGE_OP_BATCH_DELETE": 3,
+		"CHANGE_OP_SNAPSHOT":     4,
+		"CHANGE_OP_PROGRESS":     5,
+		"CHANGE_OP_PUT":          6,
+	}
+)
+
+func (x ChangeOp) Enum() *ChangeOp {
+	p := new(ChangeOp)
+	*p = x
+	return p
+}
+
+func (x ChangeOp) String() string {
+	return protoimpl.X.EnumStringOf(x.Descriptor(), protoreflect.EnumNumber(x))
+}
+
+func (ChangeOp) Descriptor() protoreflect.EnumDescriptor {
+	return file_api_commands_proto_enumTypes[0].Descriptor()
+}
+
+func (ChangeOp) Type() protoreflect.EnumType {
+	return &file_api_commands_proto_enumTypes[0]
+}
+
+func (x ChangeOp) Number() protoreflect.EnumNumber {
+	return protoreflect.EnumNumber(x)
+}
+
+// Deprecated: Use ChangeOp.Descriptor instead.
+func (ChangeOp) EnumDescriptor() ([]byte, []int) {
+	return file_api_commands_proto_rawDescGZIP(), []int{0}
+}
+
+type WatchEventType int32
+
+const (
+	WatchEventType_WATCH_EVENT_UNSPECIFIED WatchEventType = 0
+	WatchEventType_WATCH_EVENT_PUT         WatchEventType = 1
+	// WATCH_EVENT_DELETE is sent for deletes, and for keys the TTL cleanup
+	// removed after they expired.
+	WatchEventType_WATCH_EVENT_DELETE WatchEventType = 2
+)
+
+// Enum value maps for WatchEventType.
+var (
+	WatchEventType_name = map[int32]string{
+		0: "WATCH_EVENT_UNSPECIFIED",
+		1: "WATCH_EVENT_PUT",
+		2: "WATCH_EVENT_DELETE",
+	}
+	WatchEventType_value = map[string]int32{
+		"WATCH_EVENT_UNSPECIFIED": 0,
+		"WATCH_EVENT_PUT":         1,
+		"WATCH_EVENT_DELETE":      2,
+	}
+)
+
+func (x WatchEventType) Enum() *WatchEventType {
+	p := new(WatchEventType)
+	*p = x
+	return p
+}
+
+func (x WatchEventType) String() string {
+	return protoimpl.X.EnumStringOf(x.Descriptor(), protoreflect.EnumNumber(x))
+}
+
+func (WatchEventType) Descriptor() protoreflect.EnumDescriptor {
+	return file_api_commands_proto_enumTypes[1].Descriptor()
+}
+
+func (WatchEventType) Type() protoreflect.EnumType {
+	return &file_api_commands_proto_enumTypes[1]
+}
+
+func (x WatchEventType) Number() protoreflect.EnumNumber {
+	return protoreflect.EnumNumber(x)
+}
+
+// Deprecated: Use WatchEventType.Descriptor instead.
+func (WatchEventType) EnumDescriptor() ([]byte, []int) {
+	return file_api_commands_proto_rawDescGZIP(), []int{1}
+}
+
+type RateLimitAlgorithm int32
+
+const (
+	// TOKEN_BUCKET holds up to capacity tokens, and gains refill_per_second
+	// of them every second. It allows bursts of up to capacity.
+	RateLimitAlgorithm_TOKEN_BUCKET RateLimitAlgorithm = 0
+	// SLIDING_WINDOW lets through up to capacity within any window_ms. It
+	// approximates the sliding window from two fixed ones, weighing the
+	// previous one by how much of it the sliding window still covers.
+	RateLimitAlgorithm_SLIDING_WINDOW RateLimitAlgorithm = 1
+)
+
+// Enum value maps for RateLimitAlgorithm.
+var (
+	RateLimitAlgorithm_name = map[int32]string{
+		0: "TOKEN_BUCKET",
+		1: "SLIDING_WINDOW",
+	}
+	RateLimitAlgorithm_value = map[string]int32{
+		"TOKEN_BUCKET":   0,
+		"SLIDING_WINDOW": 1,
+	}
+)
+
+func (x RateLimitAlgorithm) Enum() *RateLimitAlgorithm {
+	p := new(RateLimitAlgorithm)
+	*p = x
+	return p
+}
+
+func (x RateLimitAlgorithm) String() string {
+	return protoimpl.X.EnumStringOf(x.Descriptor(), protoreflect.EnumNumber(x))
+}
+
+func (RateLimitAlgorithm) Descriptor() protoreflect.EnumDescriptor {
+	return file_api_commands_proto_enumTypes[2].Descriptor()
+}
+
+func (RateLimitAlgorithm) Type() protoreflect.EnumType {
+	return &file_api_commands_proto_enumTypes[2]
+}
+
+func (x RateLimitAlgorithm) Number() protoreflect.EnumNumber {
+	return protoreflect.EnumNumber(x)
+}
+
+// Deprecated: Use RateLimitAlgorithm.Descriptor instead.
+func (RateLimitAlgorithm) EnumDescriptor() ([]byte, []int) {
+	return file_api_commands_proto_rawDescGZIP(), []int{2}
+}
+
+type BitOperation int32
+
+const (
+	BitOperation_BIT_AND BitOperation = 0
+	BitOperation_BIT_OR  BitOperation = 1
+	BitOperation_BIT_XOR BitOperation = 2
+	// BIT_NOT takes a single source.
+	BitOperation_BIT_NOT BitOperation = 3
+)
+
+// Enum value maps for BitOperation.
+var (
+	BitOperation_name = map[int32]string{
+		0: "BIT_AND",
+		1: "BIT_OR",
+		2: "BIT_XOR",
+		3: "BIT_NOT",
+	}
+	BitOperation_value = map[string]int32{
+		"BIT_AND": 0,
+		"BIT_OR":  1,
+		"BIT_XOR": 2,
+		"BIT_NOT": 3,
+	}
+)
+
+func (x BitOperation) Enum() *BitOperation {
+	p := new(BitOperation)
+	*p = x
+	return p
+}
+
+func (x BitOperation) String() string {
+	return protoimpl.X.EnumStringOf(x.Descriptor(), protoreflect.EnumNumber(x))
+}
+
+func (BitOperation) Descriptor() protoreflect.EnumDescriptor {
+	return file_api_commands_proto_enumTypes[3].Descriptor()
+}
+
+func (BitOperation) Type() protoreflect.EnumType {
+	return &file_api_commands_proto_enumTypes[3]
+}
+
+func (x BitOperation) Number() protoreflect.EnumNumber {
+	return protoreflect.EnumNumber(x)
+}
+
+// Deprecated: Use BitOperation.Descriptor instead.
+func (BitOperation) EnumDescriptor() ([]byte, []int) {
+	return file_api_commands_proto_rawDescGZIP(), []int{3}
+}
+
+type BitFieldOp_Kind int32
+
+const (
+	BitFieldOp_GET    BitFieldOp_Kind = 0
+	BitFieldOp_SET    BitFieldOp_Kind = 1
+	BitFieldOp_INCRBY BitFieldOp_Kind = 2
+)
+
+// Enum value maps for BitFieldOp_Kind.
+var (
+	BitFieldOp_Kind_name = map[int32]string{
+		0: "GET",
+		1: "SET",
+		2: "INCRBY",
+	}
+	BitFieldOp_Kind_value = map[string]int32{
+		"GET":    0,
+		"SET":    1,
+		"INCRBY": 2,
+	}
+)
+
+func (x BitFieldOp_Kind) Enum() *BitFieldOp_Kind {
+	p := new(BitFieldOp_Kind)
+	*p = x
+	return p
+}
+
+func (x BitFieldOp_Kind) String() string {
+	return protoimpl.X.EnumStringOf(x.Descriptor(), protoreflect.EnumNumber(x))
+}
+
+func (BitFieldOp_Kind) Descriptor() protoreflect.EnumDescriptor {
+	return file_api_commands_proto_enumTypes[4].Descriptor()
+}
+
+func (BitFieldOp_Kind) Type() protoreflect.EnumType {
+	return &file_api_commands_proto_enumTypes[4]
+}
+
+func (x BitFieldOp_Kind) Number() protoreflect.EnumNumber {
+	return protoreflect.EnumNumber(x)
+}
+
+// Deprecated: Use BitFieldOp_Kind.Descriptor instead.
+func (BitFieldOp_Kind) EnumDescriptor() ([]byte, []int) {
+	return file_api_commands_proto_rawDescGZIP(), []int{89, 0}
+}
+
+type BitFieldOp_Overflow int32
+
+const (
+	BitFieldOp_WRAP BitFieldOp_Overflow = 0
+	BitFieldOp_SAT  BitFieldOp_Overflow = 1
+	BitFieldOp_FAIL BitFieldOp_Overflow = 2
+)
+
+// Enum value maps for BitFieldOp_Overflow.
+var (
+	BitFieldOp_Overflow_name = map[int32]string{
+		0: "WRAP",
+		1: "SAT",
+		2: "FAIL",
+	}
+	BitFieldOp_Overflow_value = map[string]int32{
+		"WRAP": 0,
+		"SAT":  1,
+		"FAIL": 2,
+	}
+)
+
+func (x BitFieldOp_Overflow) Enum() *BitFieldOp_Overflow {
+	p := new(BitFieldOp_Overflow)
+	*p = x
+	return p
+}
+
+func (x BitFieldOp_Overflow) String() string {
+	return protoimpl.X.EnumStringOf(x.Descriptor(), protoreflect.EnumNumber(x))
+}
+
+func (BitFieldOp_Overflow) Descriptor() protoreflect.EnumDescriptor {
+	return file_api_commands_proto_enumTypes[5].Descriptor()
+}
+
+func (BitFieldOp_Overflow) Type() protoreflect.EnumType {
+	return &file_api_commands_proto_enumTypes[5]
+}
+
+func (x BitFieldOp_Overflow) Number() protoreflect.EnumNumber {
+	return protoreflect.EnumNumber(x)
+}
+
+// Deprecated: Use BitFieldOp_Overflow.Descriptor instead.
+func (BitFieldOp_Overflow) EnumDescriptor() ([]byte, []int) {
+	return file_api_commands_proto_rawDescGZIP(), []int{89, 1}
+}
+
 type EchoRequest struct {
 	state         protoimpl.MessageState `protogen:"open.v1"`
 	Message       string                 `protobuf:"bytes,1,opt,name=message,proto3" json:"message,omitempty"`
@@ -111,10 +433,17 @@ func (x *EchoResponse) GetMessage() string {
 }
 
 type SetRequest struct {
-	state         protoimpl.MessageState `protogen:"open.v1"`
-	Id            string                 `protobuf:"bytes,1,opt,name=id,proto3" json:"id,omitempty"`
-	Value         string                 `protobuf:"bytes,2,opt,name=value,proto3" json:"value,omitempty"`
-	Ttl           int64                  `protobuf:"varint,3,opt,name=ttl,proto3" json:"ttl,omitempty"`
+	state protoimpl.MessageState `protogen:"open.v1"`
+	Id    string                 `protobuf:"bytes,1,opt,name=id,proto3" json:"id,omitempty"`
+	Value string                 `protobuf:"bytes,2,opt,name=value,proto3" json:"value,omitempty"`
+	Ttl   int64                  `protobuf:"varint,3,opt,name=ttl,proto3" json:"ttl,omitempty"`
+	// session makes the key ephemeral: it is deleted when the session ends,
+	// unless it is set again without one first. The session must not have
+	// ended.
+	Session string `protobuf:"bytes,4,opt,name=session,proto3" json:"session,omitempty"`
+	// raw_value is stored instead of value when not empty, for a value that
+	// isn't valid UTF-8, like the raw_value of a GetResponse.
+	RawValue      []byte `protobuf:"bytes,5,opt,name=raw_value,json=rawValue,proto3" json:"raw_value,omitempty"`
 	unknownFields protoimpl.UnknownFields
 	sizeCache     protoimpl.SizeCache
 }
@@ -170,16 +499,109 @@ func (x *SetRequest) GetTtl() int64 {
 	return 0
 }
 
+func (x *SetRequest) GetSession() string {
+	if x != nil {
+		return x.Session
+	}
+	return ""
+}
+
+func (x *SetRequest) GetRawValue() []byte {
+	if x != nil {
+		return x.RawValue
+	}
+	return nil
+}
+
+type SetResponse struct {
+	state protoimpl.MessageState `protogen:"open.v1"`
+	// applied is true once the write has been applied.
+	Applied bool `protobuf:"varint,1,opt,name=applied,proto3" json:"applied,omitempty"`
+	// previous_value is the value this write replaced, valid when
+	// had_previous is set. Expired values don't count.
+	PreviousValue string `protobuf:"bytes,2,opt,name=previous_value,json=previousValue,proto3" json:"previous_value,omitempty"`
+	HadPrevious   bool   `protobuf:"varint,3,opt,name=had_previous,json=hadPrevious,proto3" json:"had_previous,omitempty"`
+	// raw_previous_value holds the replaced value instead of previous_value
+	// when it isn't valid UTF-8, as strings written by SetBit, BitOp or
+	// BitField may not be.
+	RawPreviousValue []byte `protobuf:"bytes,4,opt,name=raw_previous_value,json=rawPreviousValue,proto3" json:"raw_previous_value,omitempty"`
+	unknownFields    protoimpl.UnknownFields
+	sizeCache        protoimpl.SizeCache
+}
+
+func (x *SetResponse) Reset() {
+	*x = SetResponse{}
+	mi := &file_api_commands_proto_msgTypes[3]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *SetResponse) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*SetResponse) ProtoMessage() {}
+
+func (x *SetResponse) ProtoReflect() protoreflect.Message {
+	mi := &file_api_commands_proto_msgTypes[3]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use SetResponse.ProtoReflect.Descriptor instead.
+func (*SetResponse) Descriptor() ([]byte, []int) {
+	return file_api_commands_proto_rawDescGZIP(), []int{3}
+}
+
+func (x *SetResponse) GetApplied() bool {
+	if x != nil {
+		return x.Applied
+	}
+	return false
+}
+
+func (x *SetResponse) GetPreviousValue() string {
+	if x != nil {
+		return x.PreviousValue
+	}
+	return ""
+}
+
+func (x *SetResponse) GetHadPrevious() bool {
+	if x != nil {
+		return x.HadPrevious
+	}
+	return false
+}
+
+func (x *SetResponse) GetRawPreviousValue() []byte {
+	if x != nil {
+		return x.RawPreviousValue
+	}
+	return nil
+}
+
 type GetRequest struct {
-	state         protoimpl.MessageState `protogen:"open.v1"`
-	Id            string                 `protobuf:"bytes,1,opt,name=id,proto3" json:"id,omitempty"`
-	unknownFields protoimpl.UnknownFields
-	sizeCache     protoimpl.SizeCache
+	state protoimpl.MessageState `protogen:"open.v1"`
+	Id    string                 `protobuf:"bytes,1,opt,name=id,proto3" json:"id,omitempty"`
+	// max_staleness_ms bounds how far behind the leader the serving node may
+	// be. Reads are always answered from the local replica; when this is set
+	// and the node hasn't heard from the leader within the bound, the read
+	// fails with UNAVAILABLE instead. 0 accepts any staleness.
+	MaxStalenessMs int64 `protobuf:"varint,2,opt,name=max_staleness_ms,json=maxStalenessMs,proto3" json:"max_staleness_ms,omitempty"`
+	unknownFields  protoimpl.UnknownFields
+	sizeCache      protoimpl.SizeCache
 }
 
 func (x *GetRequest) Reset() {
 	*x = GetRequest{}
-	mi := &file_api_commands_proto_msgTypes[3]
+	mi := &file_api_commands_proto_msgTypes[4]
 	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
 	ms.StoreMessageInfo(mi)
 }
@@ -191,7 +613,7 @@ func (x *GetRequest) String() string {
 func (*GetRequest) ProtoMessage() {}
 
 func (x *GetRequest) ProtoReflect() protoreflect.Message {
-	mi := &file_api_commands_proto_msgTypes[3]
+	mi := &file_api_commands_proto_msgTypes[4]
 	if x != nil {
 		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
 		if ms.LoadMessageInfo() == nil {
@@ -204,7 +626,7 @@ func (x *GetRequest) ProtoReflect() protoreflect.Message {
 
 // Deprecated: Use GetRequest.ProtoReflect.Descriptor instead.
 func (*GetRequest) Descriptor() ([]byte, []int) {
-	return file_api_commands_proto_rawDescGZIP(), []int{3}
+	return file_api_commands_proto_rawDescGZIP(), []int{4}
 }
 
 func (x *GetRequest) GetId() string {
@@ -214,16 +636,26 @@ func (x *GetRequest) GetId() string {
 	return ""
 }
 
+func (x *GetRequest) GetMaxStalenessMs() int64 {
+	if x != nil {
+		return x.MaxStalenessMs
+	}
+	return 0
+}
+
 type GetResponse struct {
-	state         protoimpl.MessageState `protogen:"open.v1"`
-	Value         string                 `protobuf:"bytes,1,opt,name=value,proto3" json:"value,omitempty"`
+	state protoimpl.MessageState `protogen:"open.v1"`
+	Value string                 `protobuf:"bytes,1,opt,name=value,proto3" json:"value,omitempty"`
+	// raw_value holds the value instead of value when it isn't valid UTF-8,
+	// as strings written by SetBit, BitOp or BitField may not be.
+	RawValue      []byte `protobuf:"bytes,2,opt,name=raw_value,json=rawValue,proto3" json:"raw_value,omitempty"`
 	unknownFields protoimpl.UnknownFields
 	sizeCache     protoimpl.SizeCache
 }
 
 func (x *GetResponse) Reset() {
 	*x = GetResponse{}
-	mi := &file_api_commands_proto_msgTypes[4]
+	mi := &file_api_commands_proto_msgTypes[5]
 	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
 	ms.StoreMessageInfo(mi)
 }
@@ -235,7 +667,7 @@ func (x *GetResponse) String() string {
 func (*GetResponse) ProtoMessage() {}
 
 func (x *GetResponse) ProtoReflect() protoreflect.Message {
-	mi := &file_api_commands_proto_msgTypes[4]
+	mi := &file_api_commands_proto_msgTypes[5]
 	if x != nil {
 		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
 		if ms.LoadMessageInfo() == nil {
@@ -248,7 +680,7 @@ func (x *GetResponse) ProtoReflect() protoreflect.Message {
 
 // Deprecated: Use GetResponse.ProtoReflect.Descriptor instead.
 func (*GetResponse) Descriptor() ([]byte, []int) {
-	return file_api_commands_proto_rawDescGZIP(), []int{4}
+	return file_api_commands_proto_rawDescGZIP(), []int{5}
 }
 
 func (x *GetResponse) GetValue() string {
@@ -258,6 +690,13 @@ func (x *GetResponse) GetValue() string {
 	return ""
 }
 
+func (x *GetResponse) GetRawValue() []byte {
+	if x != nil {
+		return x.RawValue
+	}
+	return nil
+}
+
 type DeleteRequest struct {
 	state         protoimpl.MessageState `protogen:"open.v1"`
 	Id            string                 `protobuf:"bytes,1,opt,name=id,proto3" json:"id,omitempty"`
@@ -267,7 +706,7 @@ type DeleteRequest struct {
 
 func (x *DeleteRequest) Reset() {
 	*x = DeleteRequest{}
-	mi := &file_api_commands_proto_msgTypes[5]
+	mi := &file_api_commands_proto_msgTypes[6]
 	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
 	ms.StoreMessageInfo(mi)
 }
@@ -279,7 +718,7 @@ func (x *DeleteRequest) String() string {
 func (*DeleteRequest) ProtoMessage() {}
 
 func (x *DeleteRequest) ProtoReflect() protoreflect.Message {
-	mi := &file_api_commands_proto_msgTypes[5]
+	mi := &file_api_commands_proto_msgTypes[6]
 	if x != nil {
 		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
 		if ms.LoadMessageInfo() == nil {
@@ -292,7 +731,7 @@ func (x *DeleteRequest) ProtoReflect() protoreflect.Message {
 
 // Deprecated: Use DeleteRequest.ProtoReflect.Descriptor instead.
 func (*DeleteRequest) Descriptor() ([]byte, []int) {
-	return file_api_commands_proto_rawDescGZIP(), []int{5}
+	return file_api_commands_proto_rawDescGZIP(), []int{6}
 }
 
 func (x *DeleteRequest) GetId() string {
@@ -303,15 +742,17 @@ func (x *DeleteRequest) GetId() string {
 }
 
 type DeleteResponse struct {
-	state         protoimpl.MessageState `protogen:"open.v1"`
-	DeleteCount   int64                  `protobuf:"varint,1,opt,name=delete_count,json=deleteCount,proto3" json:"delete_count,omitempty"`
+	state       protoimpl.MessageState `protogen:"open.v1"`
+	DeleteCount int64                  `protobuf:"varint,1,opt,name=delete_count,json=deleteCount,proto3" json:"delete_count,omitempty"`
+	// applied is false when there was nothing to delete.
+	Applied       bool `protobuf:"varint,2,opt,name=applied,proto3" json:"applied,omitempty"`
 	unknownFields protoimpl.UnknownFields
 	sizeCache     protoimpl.SizeCache
 }
 
 func (x *DeleteResponse) Reset() {
 	*x = DeleteResponse{}
-	mi := &file_api_commands_proto_msgTypes[6]
+	mi := &file_api_commands_proto_msgTypes[7]
 	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
 	ms.StoreMessageInfo(mi)
 }
@@ -323,7 +764,7 @@ func (x *DeleteResponse) String() string {
 func (*DeleteResponse) ProtoMessage() {}
 
 func (x *DeleteResponse) ProtoReflect() protoreflect.Message {
-	mi := &file_api_commands_proto_msgTypes[6]
+	mi := &file_api_commands_proto_msgTypes[7]
 	if x != nil {
 		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
 		if ms.LoadMessageInfo() == nil {
@@ -336,7 +777,7 @@ func (x *DeleteResponse) ProtoReflect() protoreflect.Message {
 
 // Deprecated: Use DeleteResponse.ProtoReflect.Descriptor instead.
 func (*DeleteResponse) Descriptor() ([]byte, []int) {
-	return file_api_commands_proto_rawDescGZIP(), []int{6}
+	return file_api_commands_proto_rawDescGZIP(), []int{7}
 }
 
 func (x *DeleteResponse) GetDeleteCount() int64 {
@@ -346,6 +787,13 @@ func (x *DeleteResponse) GetDeleteCount() int64 {
 	return 0
 }
 
+func (x *DeleteResponse) GetApplied() bool {
+	if x != nil {
+		return x.Applied
+	}
+	return false
+}
+
 type BatchDeleteRequest struct {
 	state         protoimpl.MessageState `protogen:"open.v1"`
 	Ids           []string               `protobuf:"bytes,1,rep,name=ids,proto3" json:"ids,omitempty"`
@@ -355,7 +803,7 @@ type BatchDeleteRequest struct {
 
 func (x *BatchDeleteRequest) Reset() {
 	*x = BatchDeleteRequest{}
-	mi := &file_api_commands_proto_msgTypes[7]
+	mi := &file_api_commands_proto_msgTypes[8]
 	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
 	ms.StoreMessageInfo(mi)
 }
@@ -367,7 +815,7 @@ func (x *BatchDeleteRequest) String() string {
 func (*BatchDeleteRequest) ProtoMessage() {}
 
 func (x *BatchDeleteRequest) ProtoReflect() protoreflect.Message {
-	mi := &file_api_commands_proto_msgTypes[7]
+	mi := &file_api_commands_proto_msgTypes[8]
 	if x != nil {
 		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
 		if ms.LoadMessageInfo() == nil {
@@ -380,7 +828,7 @@ func (x *BatchDeleteRequest) ProtoReflect() protoreflect.Message {
 
 // Deprecated: Use BatchDeleteRequest.ProtoReflect.Descriptor instead.
 func (*BatchDeleteRequest) Descriptor() ([]byte, []int) {
-	return file_api_commands_proto_rawDescGZIP(), []int{7}
+	return file_api_commands_proto_rawDescGZIP(), []int{8}
 }
 
 func (x *BatchDeleteRequest) GetIds() []string {
@@ -391,15 +839,17 @@ func (x *BatchDeleteRequest) GetIds() []string {
 }
 
 type BatchDeleteResponse struct {
-	state         protoimpl.MessageState `protogen:"open.v1"`
-	DeleteCount   int64                  `protobuf:"varint,1,opt,name=deleteCount,proto3" json:"deleteCount,omitempty"`
+	state       protoimpl.MessageState `protogen:"open.v1"`
+	DeleteCount int64                  `protobuf:"varint,1,opt,name=deleteCount,proto3" json:"deleteCount,omitempty"`
+	// applied is false when none of the keys existed.
+	Applied       bool `protobuf:"varint,2,opt,name=applied,proto3" json:"applied,omitempty"`
 	unknownFields protoimpl.UnknownFields
 	sizeCache     protoimpl.SizeCache
 }
 
 func (x *BatchDeleteResponse) Reset() {
 	*x = BatchDeleteResponse{}
-	mi := &file_api_commands_proto_msgTypes[8]
+	mi := &file_api_commands_proto_msgTypes[9]
 	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
 	ms.StoreMessageInfo(mi)
 }
@@ -411,7 +861,7 @@ func (x *BatchDeleteResponse) String() string {
 func (*BatchDeleteResponse) ProtoMessage() {}
 
 func (x *BatchDeleteResponse) ProtoReflect() protoreflect.Message {
-	mi := &file_api_commands_proto_msgTypes[8]
+	mi := &file_api_commands_proto_msgTypes[9]
 	if x != nil {
 		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
 		if ms.LoadMessageInfo() == nil {
@@ -424,7 +874,7 @@ func (x *BatchDeleteResponse) ProtoReflect() protoreflect.Message {
 
 // Deprecated: Use BatchDeleteResponse.ProtoReflect.Descriptor instead.
 func (*BatchDeleteResponse) Descriptor() ([]byte, []int) {
-	return file_api_commands_proto_rawDescGZIP(), []int{8}
+	return file_api_commands_proto_rawDescGZIP(), []int{9}
 }
 
 func (x *BatchDeleteResponse) GetDeleteCount() int64 {
@@ -434,6 +884,13 @@ func (x *BatchDeleteResponse) GetDeleteCount() int64 {
 	return 0
 }
 
+func (x *BatchDeleteResponse) GetApplied() bool {
+	if x != nil {
+		return x.Applied
+	}
+	return false
+}
+
 type GetExpiredKeysResponse struct {
 	state         protoimpl.MessageState `protogen:"open.v1"`
 	Ids           []string               `protobuf:"bytes,1,rep,name=ids,proto3" json:"ids,omitempty"`
@@ -443,7 +900,7 @@ type GetExpiredKeysResponse struct {
 
 func (x *GetExpiredKeysResponse) Reset() {
 	*x = GetExpiredKeysResponse{}
-	mi := &file_api_commands_proto_msgTypes[9]
+	mi := &file_api_commands_proto_msgTypes[10]
 	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
 	ms.StoreMessageInfo(mi)
 }
@@ -455,7 +912,7 @@ func (x *GetExpiredKeysResponse) String() string {
 func (*GetExpiredKeysResponse) ProtoMessage() {}
 
 func (x *GetExpiredKeysResponse) ProtoReflect() protoreflect.Message {
-	mi := &file_api_commands_proto_msgTypes[9]
+	mi := &file_api_commands_proto_msgTypes[10]
 	if x != nil {
 		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
 		if ms.LoadMessageInfo() == nil {
@@ -468,7 +925,7 @@ func (x *GetExpiredKeysResponse) ProtoReflect() protoreflect.Message {
 
 // Deprecated: Use GetExpiredKeysResponse.ProtoReflect.Descriptor instead.
 func (*GetExpiredKeysResponse) Descriptor() ([]byte, []int) {
-	return file_api_commands_proto_rawDescGZIP(), []int{9}
+	return file_api_commands_proto_rawDescGZIP(), []int{10}
 }
 
 func (x *GetExpiredKeysResponse) GetIds() []string {
@@ -478,42 +935,5332 @@ func (x *GetExpiredKeysResponse) GetIds() []string {
 	return nil
 }
 
-var File_api_commands_proto protoreflect.FileDescriptor
+type ScanRequest struct {
+	state protoimpl.MessageState `protogen:"open.v1"`
+	// pattern is a Redis-style glob; empty matches every key.
+	Pattern string `protobuf:"bytes,1,opt,name=pattern,proto3" json:"pattern,omitempty"`
+	// cursor is the next_cursor of the previous page, empty to start.
+	Cursor string `protobuf:"bytes,2,opt,name=cursor,proto3" json:"cursor,omitempty"`
+	Count  int64  `protobuf:"varint,3,opt,name=count,proto3" json:"count,omitempty"`
+	// max_staleness_ms bounds how far behind the leader the serving node may
+	// be, see GetRequest.
+	MaxStalenessMs int64 `protobuf:"varint,4,opt,name=max_staleness_ms,json=maxStalenessMs,proto3" json:"max_staleness_ms,omitempty"`
+	unknownFields  protoimpl.UnknownFields
+	sizeCache      protoimpl.SizeCache
+}
 
-const file_api_commands_proto_rawDesc = "" +
-	"\n" +
-	"\x12api/commands.proto\x12\bcommands\x1a\x1bgoogle/protobuf/empty.proto\"'\n" +
-	"\vEchoRequest\x12\x18\n" +
-	"\amessage\x18\x01 \x01(\tR\amessage\"(\n" +
+func (x *ScanRequest) Reset() {
+	*x = ScanRequest{}
+	mi := &file_api_commands_proto_msgTypes[11]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *ScanRequest) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*ScanRequest) ProtoMessage() {}
+
+func (x *ScanRequest) ProtoReflect() protoreflect.Message {
+	mi := &file_api_commands_proto_msgTypes[11]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use ScanRequest.ProtoReflect.Descriptor instead.
+func (*ScanRequest) Descriptor() ([]byte, []int) {
+	return file_api_commands_proto_rawDescGZIP(), []int{11}
+}
+
+func (x *ScanRequest) GetPattern() string {
+	if x != nil {
+		return x.Pattern
+	}
+	return ""
+}
+
+func (x *ScanRequest) GetCursor() string {
+	if x != nil {
+		return x.Cursor
+	}
+	return ""
+}
+
+func (x *ScanRequest) GetCount() int64 {
+	if x != nil {
+		return x.Count
+	}
+	return 0
+}
+
+func (x *ScanRequest) GetMaxStalenessMs() int64 {
+	if x != nil {
+		return x.MaxStalenessMs
+	}
+	return 0
+}
+
+type ScanResponse struct {
+	state protoimpl.MessageState `protogen:"open.v1"`
+	Ids   []string               `protobuf:"bytes,1,rep,name=ids,proto3" json:"ids,omitempty"`
+	// next_cursor is empty once the scan is complete.
+	NextCursor    string `protobuf:"bytes,2,opt,name=next_cursor,json=nextCursor,proto3" json:"next_cursor,omitempty"`
+	unknownFields protoimpl.UnknownFields
+	sizeCache     protoimpl.SizeCache
+}
+
+func (x *ScanResponse) Reset() {
+	*x = ScanResponse{}
+	mi := &file_api_commands_proto_msgTypes[12]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *ScanResponse) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*ScanResponse) ProtoMessage() {}
+
+func (x *ScanResponse) ProtoReflect() protoreflect.Message {
+	mi := &file_api_commands_proto_msgTypes[12]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use ScanResponse.ProtoReflect.Descriptor instead.
+func (*ScanResponse) Descriptor() ([]byte, []int) {
+	return file_api_commands_proto_rawDescGZIP(), []int{12}
+}
+
+func (x *ScanResponse) GetIds() []string {
+	if x != nil {
+		return x.Ids
+	}
+	return nil
+}
+
+func (x *ScanResponse) GetNextCursor() string {
+	if x != nil {
+		return x.NextCursor
+	}
+	return ""
+}
+
+type TTLRequest struct {
+	state protoimpl.MessageState `protogen:"open.v1"`
+	Id    string                 `protobuf:"bytes,1,opt,name=id,proto3" json:"id,omitempty"`
+	// max_staleness_ms bounds how far behind the leader the serving node may
+	// be, see GetRequest.
+	MaxStalenessMs int64 `protobuf:"varint,2,opt,name=max_staleness_ms,json=maxStalenessMs,proto3" json:"max_staleness_ms,omitempty"`
+	unknownFields  protoimpl.UnknownFields
+	sizeCache      protoimpl.SizeCache
+}
+
+func (x *TTLRequest) Reset() {
+	*x = TTLRequest{}
+	mi := &file_api_commands_proto_msgTypes[13]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *TTLRequest) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*TTLRequest) ProtoMessage() {}
+
+func (x *TTLRequest) ProtoReflect() protoreflect.Message {
+	mi := &file_api_commands_proto_msgTypes[13]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use TTLRequest.ProtoReflect.Descriptor instead.
+func (*TTLRequest) Descriptor() ([]byte, []int) {
+	return file_api_commands_proto_rawDescGZIP(), []int{13}
+}
+
+func (x *TTLRequest) GetId() string {
+	if x != nil {
+		return x.Id
+	}
+	return ""
+}
+
+func (x *TTLRequest) GetMaxStalenessMs() int64 {
+	if x != nil {
+		return x.MaxStalenessMs
+	}
+	return 0
+}
+
+type TTLResponse struct {
+	state protoimpl.MessageState `protogen:"open.v1"`
+	// ttl is the remaining time to live in milliseconds, -1 when the key
+	// never expires.
+	Ttl           int64 `protobuf:"varint,1,opt,name=ttl,proto3" json:"ttl,omitempty"`
+	unknownFields protoimpl.UnknownFields
+	sizeCache     protoimpl.SizeCache
+}
+
+func (x *TTLResponse) Reset() {
+	*x = TTLResponse{}
+	mi := &file_api_commands_proto_msgTypes[14]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *TTLResponse) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*TTLResponse) ProtoMessage() {}
+
+func (x *TTLResponse) ProtoReflect() protoreflect.Message {
+	mi := &file_api_commands_proto_msgTypes[14]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use TTLResponse.ProtoReflect.Descriptor instead.
+func (*TTLResponse) Descriptor() ([]byte, []int) {
+	return file_api_commands_proto_rawDescGZIP(), []int{14}
+}
+
+func (x *TTLResponse) GetTtl() int64 {
+	if x != nil {
+		return x.Ttl
+	}
+	return 0
+}
+
+type SubscribeRequest struct {
+	state protoimpl.MessageState `protogen:"open.v1"`
+	// from_index is the log index of the first write to stream, inclusive.
+	// 0 streams the writes applied from now on. To resume a stream, pass the
+	// index of the last event received: events sharing an index are always
+	// sent back to back, so this only repeats that one entry's writes.
+	FromIndex uint64 `protobuf:"varint,1,opt,name=from_index,json=fromIndex,proto3" json:"from_index,omitempty"`
+	// key_prefix limits the stream to writes to keys starting with it.
+	KeyPrefix string `protobuf:"bytes,2,opt,name=key_prefix,json=keyPrefix,proto3" json:"key_prefix,omitempty"`
+	// shard is the shard to stream the writes of, on a sharded cluster.
+	// Indexes are those of the shard's own log.
+	Shard uint32 `protobuf:"varint,3,opt,name=shard,proto3" json:"shard,omitempty"`
+	// progress_interval_ms, when set, makes an idle stream send a
+	// CHANGE_OP_PROGRESS event after that long without writes.
+	ProgressIntervalMs int64 `protobuf:"varint,4,opt,name=progress_interval_ms,json=progressIntervalMs,proto3" json:"progress_interval_ms,omitempty"`
+	unknownFields      protoimpl.UnknownFields
+	sizeCache          protoimpl.SizeCache
+}
+
+func (x *SubscribeRequest) Reset() {
+	*x = SubscribeRequest{}
+	mi := &file_api_commands_proto_msgTypes[15]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *SubscribeRequest) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*SubscribeRequest) ProtoMessage() {}
+
+func (x *SubscribeRequest) ProtoReflect() protoreflect.Message {
+	mi := &file_api_commands_proto_msgTypes[15]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use SubscribeRequest.ProtoReflect.Descriptor instead.
+func (*SubscribeRequest) Descriptor() ([]byte, []int) {
+	return file_api_commands_proto_rawDescGZIP(), []int{15}
+}
+
+func (x *SubscribeRequest) GetFromIndex() uint64 {
+	if x != nil {
+		return x.FromIndex
+	}
+	return 0
+}
+
+func (x *SubscribeRequest) GetKeyPrefix() string {
+	if x != nil {
+		return x.KeyPrefix
+	}
+	return ""
+}
+
+func (x *SubscribeRequest) GetShard() uint32 {
+	if x != nil {
+		return x.Shard
+	}
+	return 0
+}
+
+func (x *SubscribeRequest) GetProgressIntervalMs() int64 {
+	if x != nil {
+		return x.ProgressIntervalMs
+	}
+	return 0
+}
+
+type ChangeEvent struct {
+	state protoimpl.MessageState `protogen:"open.v1"`
+	// index and term are those of the log entry that carried the write. In
+	// single-node mode, index counts the writes since startup and term is 0.
+	Index uint64   `protobuf:"varint,1,opt,name=index,proto3" json:"index,omitempty"`
+	Term  uint64   `protobuf:"varint,2,opt,name=term,proto3" json:"term,omitempty"`
+	Op    ChangeOp `protobuf:"varint,3,opt,name=op,proto3,enum=commands.ChangeOp" json:"op,omitempty"`
+	// id is the key of a set, put or delete, ids the keys of a batch delete.
+	Id    string   `protobuf:"bytes,4,opt,name=id,proto3" json:"id,omitempty"`
+	Value string   `protobuf:"bytes,5,opt,name=value,proto3" json:"value,omitempty"`
+	Ids   []string `protobuf:"bytes,6,rep,name=ids,proto3" json:"ids,omitempty"`
+	// expires_at_ms is when a set or put key expires, in Unix milliseconds;
+	// 0 when it never does.
+	ExpiresAtMs int64 `protobuf:"varint,7,opt,name=expires_at_ms,json=expiresAtMs,proto3" json:"expires_at_ms,omitempty"`
+	// timestamp_ms is when the leader proposed the write, in Unix
+	// milliseconds.
+	TimestampMs int64 `protobuf:"varint,8,opt,name=timestamp_ms,json=timestampMs,proto3" json:"timestamp_ms,omitempty"`
+	// expired marks a batch delete made by the TTL cleanup, or by closing a
+	// session that ended. The ids of one made by the TTL cleanup are the keys
+	// it found still expired when it was applied, which it deleted, and those
+	// of a closed session the keys still attached to it.
+	Expired bool `protobuf:"varint,9,opt,name=expired,proto3" json:"expired,omitempty"`
+	// entry is the value a put stored, JSON-encoded with its type as in
+	// backups, e.g. {"type":"list","value":{...},"expiration":"..."}.
+	Entry []byte `protobuf:"bytes,10,opt,name=entry,proto3" json:"entry,omitempty"`
+	// raw_value holds the value of a set instead of value when it isn't
+	// valid UTF-8, as strings written by SetBit, BitOp or BitField may not
+	// be.
+	RawValue      []byte `protobuf:"bytes,11,opt,name=raw_value,json=rawValue,proto3" json:"raw_value,omitempty"`
+	unknownFields protoimpl.UnknownFields
+	sizeCache     protoimpl.SizeCache
+}
+
+func (x *ChangeEvent) Reset() {
+	*x = ChangeEvent{}
+	mi := &file_api_commands_proto_msgTypes[16]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *ChangeEvent) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*ChangeEvent) ProtoMessage() {}
+
+func (x *ChangeEvent) ProtoReflect() protoreflect.Message {
+	mi := &file_api_commands_proto_msgTypes[16]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use ChangeEvent.ProtoReflect.Descriptor instead.
+func (*ChangeEvent) Descriptor() ([]byte, []int) {
+	return file_api_commands_proto_rawDescGZIP(), []int{16}
+}
+
+func (x *ChangeEvent) GetIndex() uint64 {
+	if x != nil {
+		return x.Index
+	}
+	return 0
+}
+
+func (x *ChangeEvent) GetTerm() uint64 {
+	if x != nil {
+		return x.Term
+	}
+	return 0
+}
+
+func (x *ChangeEvent) GetOp() ChangeOp {
+	if x != nil {
+		return x.Op
+	}
+	return ChangeOp_CHANGE_OP_UNSPECIFIED
+}
+
+func (x *ChangeEvent) GetId() string {
+	if x != nil {
+		return x.Id
+	}
+	return ""
+}
+
+func (x *ChangeEvent) GetValue() string {
+	if x != nil {
+		return x.Value
+	}
+	return ""
+}
+
+func (x *ChangeEvent) GetIds() []string {
+	if x != nil {
+		return x.Ids
+	}
+	return nil
+}
+
+func (x *ChangeEvent) GetExpiresAtMs() int64 {
+	if x != nil {
+		return x.ExpiresAtMs
+	}
+	return 0
+}
+
+func (x *ChangeEvent) GetTimestampMs() int64 {
+	if x != nil {
+		return x.TimestampMs
+	}
+	return 0
+}
+
+func (x *ChangeEvent) GetExpired() bool {
+	if x != nil {
+		return x.Expired
+	}
+	return false
+}
+
+func (x *ChangeEvent) GetEntry() []byte {
+	if x != nil {
+		return x.Entry
+	}
+	return nil
+}
+
+func (x *ChangeEvent) GetRawValue() []byte {
+	if x != nil {
+		return x.RawValue
+	}
+	return nil
+}
+
+type WatchRequest struct {
+	state protoimpl.MessageState `protogen:"open.v1"`
+	// Types that are valid to be assigned to Request:
+	//
+	//	*WatchRequest_CreateRequest
+	//	*WatchRequest_CancelRequest
+	Request       isWatchRequest_Request `protobuf_oneof:"request"`
+	unknownFields protoimpl.UnknownFields
+	sizeCache     protoimpl.SizeCache
+}
+
+func (x *WatchRequest) Reset() {
+	*x = WatchRequest{}
+	mi := &file_api_commands_proto_msgTypes[17]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *WatchRequest) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*WatchRequest) ProtoMessage() {}
+
+func (x *WatchRequest) ProtoReflect() protoreflect.Message {
+	mi := &file_api_commands_proto_msgTypes[17]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use WatchRequest.ProtoReflect.Descriptor instead.
+func (*WatchRequest) Descriptor() ([]byte, []int) {
+	return file_api_commands_proto_rawDescGZIP(), []int{17}
+}
+
+func (x *WatchRequest) GetRequest() isWatchRequest_Request {
+	if x != nil {
+		return x.Request
+	}
+	return nil
+}
+
+func (x *WatchRequest) GetCreateRequest() *WatchCreateRequest {
+	if x != nil {
+		if x, ok := x.Request.(*WatchRequest_CreateRequest); ok {
+			return x.CreateRequest
+		}
+	}
+	return nil
+}
+
+func (x *WatchRequest) GetCancelRequest() *WatchCancelRequest {
+	if x != nil {
+		if x, ok := x.Request.(*WatchRequest_CancelRequest); ok {
+			return x.CancelRequest
+		}
+	}
+	return nil
+}
+
+type isWatchRequest_Request interface {
+	isWatchRequest_Request()
+}
+
+type WatchRequest_CreateRequest struct {
+	CreateRequest *WatchCreateRequest `protobuf:"bytes,1,opt,name=create_request,json=createRequest,proto3,oneof"`
+}
+
+type WatchRequest_CancelRequest struct {
+	CancelRequest *WatchCancelRequest `protobuf:"bytes,2,opt,name=cancel_request,json=cancelRequest,proto3,oneof"`
+}
+
+func (*WatchRequest_CreateRequest) isWatchRequest_Request() {}
+
+func (*WatchRequest_CancelRequest) isWatchRequest_Request() {}
+
+type WatchCreateRequest struct {
+	state protoimpl.MessageState `protogen:"open.v1"`
+	// key is the key to watch, or with prefix set the prefix of the keys to
+	// watch; an empty prefix watches every key.
+	Key    string `protobuf:"bytes,1,opt,name=key,proto3" json:"key,omitempty"`
+	Prefix bool   `protobuf:"varint,2,opt,name=prefix,proto3" json:"prefix,omitempty"`
+	// start_revision is the revision of the first change to stream,
+	// inclusive. 0 streams the changes made from now on. To resume a watch,
+	// pass the revision after the last one received.
+	StartRevision uint64 `protobuf:"varint,3,opt,name=start_revision,json=startRevision,proto3" json:"start_revision,omitempty"`
+	// watch_id names the watch in the responses; 0 lets the server pick one.
+	WatchId int64 `protobuf:"varint,4,opt,name=watch_id,json=watchId,proto3" json:"watch_id,omitempty"`
+	// shard is the shard a prefix watch follows, on a sharded cluster.
+	// A key watch follows the shard owning the key.
+	Shard uint32 `protobuf:"varint,5,opt,name=shard,proto3" json:"shard,omitempty"`
+	// progress_interval_ms, when set, makes an idle watch send a response
+	// without events after that long without changes.
+	ProgressIntervalMs int64 `protobuf:"varint,6,opt,name=progress_interval_ms,json=progressIntervalMs,proto3" json:"progress_interval_ms,omitempty"`
+	unknownFields      protoimpl.UnknownFields
+	sizeCache          protoimpl.SizeCache
+}
+
+func (x *WatchCreateRequest) Reset() {
+	*x = WatchCreateRequest{}
+	mi := &file_api_commands_proto_msgTypes[18]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *WatchCreateRequest) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*WatchCreateRequest) ProtoMessage() {}
+
+func (x *WatchCreateRequest) ProtoReflect() protoreflect.Message {
+	mi := &file_api_commands_proto_msgTypes[18]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use WatchCreateRequest.ProtoReflect.Descriptor instead.
+func (*WatchCreateRequest) Descriptor() ([]byte, []int) {
+	return file_api_commands_proto_rawDescGZIP(), []int{18}
+}
+
+func (x *WatchCreateRequest) GetKey() string {
+	if x != nil {
+		return x.Key
+	}
+	return ""
+}
+
+func (x *WatchCreateRequest) GetPrefix() bool {
+	if x != nil {
+		return x.Prefix
+	}
+	return false
+}
+
+func (x *WatchCreateRequest) GetStartRevision() uint64 {
+	if x != nil {
+		return x.StartRevision
+	}
+	return 0
+}
+
+func (x *WatchCreateRequest) GetWatchId() int64 {
+	if x != nil {
+		return x.WatchId
+	}
+	return 0
+}
+
+func (x *WatchCreateRequest) GetShard() uint32 {
+	if x != nil {
+		return x.Shard
+	}
+	return 0
+}
+
+func (x *WatchCreateRequest) GetProgressIntervalMs() int64 {
+	if x != nil {
+		return x.ProgressIntervalMs
+	}
+	return 0
+}
+
+type WatchCancelRequest struct {
+	state         protoimpl.MessageState `protogen:"open.v1"`
+	WatchId       int64                  `protobuf:"varint,1,opt,name=watch_id,json=watchId,proto3" json:"watch_id,omitempty"`
+	unknownFields protoimpl.UnknownFields
+	sizeCache     protoimpl.SizeCache
+}
+
+func (x *WatchCancelRequest) Reset() {
+	*x = WatchCancelRequest{}
+	mi := &file_api_commands_proto_msgTypes[19]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *WatchCancelRequest) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*WatchCancelRequest) ProtoMessage() {}
+
+func (x *WatchCancelRequest) ProtoReflect() protoreflect.Message {
+	mi := &file_api_commands_proto_msgTypes[19]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use WatchCancelRequest.ProtoReflect.Descriptor instead.
+func (*WatchCancelRequest) Descriptor() ([]byte, []int) {
+	return file_api_commands_proto_rawDescGZIP(), []int{19}
+}
+
+func (x *WatchCancelRequest) GetWatchId() int64 {
+	if x != nil {
+		return x.WatchId
+	}
+	return 0
+}
+
+type WatchResponse struct {
+	state   protoimpl.MessageState `protogen:"open.v1"`
+	WatchId int64                  `protobuf:"varint,1,opt,name=watch_id,json=watchId,proto3" json:"watch_id,omitempty"`
+	// created is set on the first response of a watch, sent once it is in
+	// place.
+	Created bool `protobuf:"varint,2,opt,name=created,proto3" json:"created,omitempty"`
+	// canceled is set on the last response of a watch: it was canceled by
+	// the client, couldn't be created, or can't go on. cancel_reason says
+	// why, unless the client canceled it.
+	Canceled     bool   `protobuf:"varint,3,opt,name=canceled,proto3" json:"canceled,omitempty"`
+	CancelReason string `protobuf:"bytes,4,opt,name=cancel_reason,json=cancelReason,proto3" json:"cancel_reason,omitempty"`
+	// compact_revision is set when the watch was canceled because the
+	// changes it asked for are no longer retained: the oldest revision that
+	// still is. Re-read the keys, then watch again from it.
+	CompactRevision uint64 `protobuf:"varint,5,opt,name=compact_revision,json=compactRevision,proto3" json:"compact_revision,omitempty"`
+	// revision is how far the watch has got: every change up to it has
+	// been sent, or predates the watch. Resume from the revision after it.
+	Revision uint64 `protobuf:"varint,6,opt,name=revision,proto3" json:"revision,omitempty"`
+	// events are the changes of one revision.
+	Events        []*WatchEvent `protobuf:"bytes,7,rep,name=events,proto3" json:"events,omitempty"`
+	unknownFields protoimpl.UnknownFields
+	sizeCache     protoimpl.SizeCache
+}
+
+func (x *WatchResponse) Reset() {
+	*x = WatchResponse{}
+	mi := &file_api_commands_proto_msgTypes[20]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *WatchResponse) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*WatchResponse) ProtoMessage() {}
+
+func (x *WatchResponse) ProtoReflect() protoreflect.Message {
+	mi := &file_api_commands_proto_msgTypes[20]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use WatchResponse.ProtoReflect.Descriptor instead.
+func (*WatchResponse) Descriptor() ([]byte, []int) {
+	return file_api_commands_proto_rawDescGZIP(), []int{20}
+}
+
+func (x *WatchResponse) GetWatchId() int64 {
+	if x != nil {
+		return x.WatchId
+	}
+	return 0
+}
+
+func (x *WatchResponse) GetCreated() bool {
+	if x != nil {
+		return x.Created
+	}
+	return false
+}
+
+func (x *WatchResponse) GetCanceled() bool {
+	if x != nil {
+		return x.Canceled
+	}
+	return false
+}
+
+func (x *WatchResponse) GetCancelReason() string {
+	if x != nil {
+		return x.CancelReason
+	}
+	return ""
+}
+
+func (x *WatchResponse) GetCompactRevision() uint64 {
+	if x != nil {
+		return x.CompactRevision
+	}
+	return 0
+}
+
+func (x *WatchResponse) GetRevision() uint64 {
+	if x != nil {
+		return x.Revision
+	}
+	return 0
+}
+
+func (x *WatchResponse) GetEvents() []*WatchEvent {
+	if x != nil {
+		return x.Events
+	}
+	return nil
+}
+
+type WatchEvent struct {
+	state protoimpl.MessageState `protogen:"open.v1"`
+	Type  WatchEventType         `protobuf:"varint,1,opt,name=type,proto3,enum=commands.WatchEventType" json:"type,omitempty"`
+	Key   string                 `protobuf:"bytes,2,opt,name=key,proto3" json:"key,omitempty"`
+	// value is the value of a put.
+	Value string `protobuf:"bytes,3,opt,name=value,proto3" json:"value,omitempty"`
+	// revision is the log index of the entry that carried the change. In
+	// single-node mode it counts the writes since startup.
+	Revision uint64 `protobuf:"varint,4,opt,name=revision,proto3" json:"revision,omitempty"`
+	// expires_at_ms is when a put key expires, in Unix milliseconds; 0 when
+	// it never does.
+	ExpiresAtMs int64 `protobuf:"varint,5,opt,name=expires_at_ms,json=expiresAtMs,proto3" json:"expires_at_ms,omitempty"`
+	// entry is set instead of value when the put was a write to a list,
+	// queue, lock, stream, rate limiter, Bloom filter or HyperLogLog, see
+	// ChangeEvent.entry.
+	Entry []byte `protobuf:"bytes,6,opt,name=entry,proto3" json:"entry,omitempty"`
+	// raw_value holds the value of a put instead of value when it isn't
+	// valid UTF-8, see ChangeEvent.raw_value.
+	RawValue      []byte `protobuf:"bytes,7,opt,name=raw_value,json=rawValue,proto3" json:"raw_value,omitempty"`
+	unknownFields protoimpl.UnknownFields
+	sizeCache     protoimpl.SizeCache
+}
+
+func (x *WatchEvent) Reset() {
+	*x = WatchEvent{}
+	mi := &file_api_commands_proto_msgTypes[21]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *WatchEvent) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*WatchEvent) ProtoMessage() {}
+
+func (x *WatchEvent) ProtoReflect() protoreflect.Message {
+	mi := &file_api_commands_proto_msgTypes[21]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use WatchEvent.ProtoReflect.Descriptor instead.
+func (*WatchEvent) Descriptor() ([]byte, []int) {
+	return file_api_commands_proto_rawDescGZIP(), []int{21}
+}
+
+func (x *WatchEvent) GetType() WatchEventType {
+	if x != nil {
+		return x.Type
+	}
+	return WatchEventType_WATCH_EVENT_UNSPECIFIED
+}
+
+func (x *WatchEvent) GetKey() string {
+	if x != nil {
+		return x.Key
+	}
+	return ""
+}
+
+func (x *WatchEvent) GetValue() string {
+	if x != nil {
+		return x.Value
+	}
+	return ""
+}
+
+func (x *WatchEvent) GetRevision() uint64 {
+	if x != nil {
+		return x.Revision
+	}
+	return 0
+}
+
+func (x *WatchEvent) GetExpiresAtMs() int64 {
+	if x != nil {
+		return x.ExpiresAtMs
+	}
+	return 0
+}
+
+func (x *WatchEvent) GetEntry() []byte {
+	if x != nil {
+		return x.Entry
+	}
+	return nil
+}
+
+func (x *WatchEvent) GetRawValue() []byte {
+	if x != nil {
+		return x.RawValue
+	}
+	return nil
+}
+
+type PushRequest struct {
+	state protoimpl.MessageState `protogen:"open.v1"`
+	Id    string                 `protobuf:"bytes,1,opt,name=id,proto3" json:"id,omitempty"`
+	// values are pushed one after the other: LPush leaves the last one
+	// first, like Redis' LPUSH.
+	Values        []string `protobuf:"bytes,2,rep,name=values,proto3" json:"values,omitempty"`
+	unknownFields protoimpl.UnknownFields
+	sizeCache     protoimpl.SizeCache
+}
+
+func (x *PushRequest) Reset() {
+	*x = PushRequest{}
+	mi := &file_api_commands_proto_msgTypes[22]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *PushRequest) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*PushRequest) ProtoMessage() {}
+
+func (x *PushRequest) ProtoReflect() protoreflect.Message {
+	mi := &file_api_commands_proto_msgTypes[22]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use PushRequest.ProtoReflect.Descriptor instead.
+func (*PushRequest) Descriptor() ([]byte, []int) {
+	return file_api_commands_proto_rawDescGZIP(), []int{22}
+}
+
+func (x *PushRequest) GetId() string {
+	if x != nil {
+		return x.Id
+	}
+	return ""
+}
+
+func (x *PushRequest) GetValues() []string {
+	if x != nil {
+		return x.Values
+	}
+	return nil
+}
+
+type PushResponse struct {
+	state protoimpl.MessageState `protogen:"open.v1"`
+	// length is the length of the list after the push.
+	Length        int64 `protobuf:"varint,1,opt,name=length,proto3" json:"length,omitempty"`
+	unknownFields protoimpl.UnknownFields
+	sizeCache     protoimpl.SizeCache
+}
+
+func (x *PushResponse) Reset() {
+	*x = PushResponse{}
+	mi := &file_api_commands_proto_msgTypes[23]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *PushResponse) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*PushResponse) ProtoMessage() {}
+
+func (x *PushResponse) ProtoReflect() protoreflect.Message {
+	mi := &file_api_commands_proto_msgTypes[23]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use PushResponse.ProtoReflect.Descriptor instead.
+func (*PushResponse) Descriptor() ([]byte, []int) {
+	return file_api_commands_proto_rawDescGZIP(), []int{23}
+}
+
+func (x *PushResponse) GetLength() int64 {
+	if x != nil {
+		return x.Length
+	}
+	return 0
+}
+
+type PopRequest struct {
+	state         protoimpl.MessageState `protogen:"open.v1"`
+	Id            string                 `protobuf:"bytes,1,opt,name=id,proto3" json:"id,omitempty"`
+	unknownFields protoimpl.UnknownFields
+	sizeCache     protoimpl.SizeCache
+}
+
+func (x *PopRequest) Reset() {
+	*x = PopRequest{}
+	mi := &file_api_commands_proto_msgTypes[24]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *PopRequest) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*PopRequest) ProtoMessage() {}
+
+func (x *PopRequest) ProtoReflect() protoreflect.Message {
+	mi := &file_api_commands_proto_msgTypes[24]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use PopRequest.ProtoReflect.Descriptor instead.
+func (*PopRequest) Descriptor() ([]byte, []int) {
+	return file_api_commands_proto_rawDescGZIP(), []int{24}
+}
+
+func (x *PopRequest) GetId() string {
+	if x != nil {
+		return x.Id
+	}
+	return ""
+}
+
+type PopResponse struct {
+	state protoimpl.MessageState `protogen:"open.v1"`
+	// found is false when the list was empty, or a blocking pop timed out.
+	Found bool `protobuf:"varint,1,opt,name=found,proto3" json:"found,omitempty"`
+	// id is the list the element was popped from.
+	Id            string `protobuf:"bytes,2,opt,name=id,proto3" json:"id,omitempty"`
+	Value         string `protobuf:"bytes,3,opt,name=value,proto3" json:"value,omitempty"`
+	unknownFields protoimpl.UnknownFields
+	sizeCache     protoimpl.SizeCache
+}
+
+func (x *PopResponse) Reset() {
+	*x = PopResponse{}
+	mi := &file_api_commands_proto_msgTypes[25]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *PopResponse) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*PopResponse) ProtoMessage() {}
+
+func (x *PopResponse) ProtoReflect() protoreflect.Message {
+	mi := &file_api_commands_proto_msgTypes[25]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use PopResponse.ProtoReflect.Descriptor instead.
+func (*PopResponse) Descriptor() ([]byte, []int) {
+	return file_api_commands_proto_rawDescGZIP(), []int{25}
+}
+
+func (x *PopResponse) GetFound() bool {
+	if x != nil {
+		return x.Found
+	}
+	return false
+}
+
+func (x *PopResponse) GetId() string {
+	if x != nil {
+		return x.Id
+	}
+	return ""
+}
+
+func (x *PopResponse) GetValue() string {
+	if x != nil {
+		return x.Value
+	}
+	return ""
+}
+
+type BlockingPopRequest struct {
+	state protoimpl.MessageState `protogen:"open.v1"`
+	// ids are the lists to pop from, the first holding an element first.
+	// In cluster mode they must all be in the same shard.
+	Ids []string `protobuf:"bytes,1,rep,name=ids,proto3" json:"ids,omitempty"`
+	// timeout_ms is how long to wait for an element; 0 waits until the
+	// client's deadline.
+	TimeoutMs     int64 `protobuf:"varint,2,opt,name=timeout_ms,json=timeoutMs,proto3" json:"timeout_ms,omitempty"`
+	unknownFields protoimpl.UnknownFields
+	sizeCache     protoimpl.SizeCache
+}
+
+func (x *BlockingPopRequest) Reset() {
+	*x = BlockingPopRequest{}
+	mi := &file_api_commands_proto_msgTypes[26]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *BlockingPopRequest) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*BlockingPopRequest) ProtoMessage() {}
+
+func (x *BlockingPopRequest) ProtoReflect() protoreflect.Message {
+	mi := &file_api_commands_proto_msgTypes[26]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use BlockingPopRequest.ProtoReflect.Descriptor instead.
+func (*BlockingPopRequest) Descriptor() ([]byte, []int) {
+	return file_api_commands_proto_rawDescGZIP(), []int{26}
+}
+
+func (x *BlockingPopRequest) GetIds() []string {
+	if x != nil {
+		return x.Ids
+	}
+	return nil
+}
+
+func (x *BlockingPopRequest) GetTimeoutMs() int64 {
+	if x != nil {
+		return x.TimeoutMs
+	}
+	return 0
+}
+
+type LLenRequest struct {
+	state protoimpl.MessageState `protogen:"open.v1"`
+	Id    string                 `protobuf:"bytes,1,opt,name=id,proto3" json:"id,omitempty"`
+	// max_staleness_ms works as in GetRequest.
+	MaxStalenessMs int64 `protobuf:"varint,2,opt,name=max_staleness_ms,json=maxStalenessMs,proto3" json:"max_staleness_ms,omitempty"`
+	unknownFields  protoimpl.UnknownFields
+	sizeCache      protoimpl.SizeCache
+}
+
+func (x *LLenRequest) Reset() {
+	*x = LLenRequest{}
+	mi := &file_api_commands_proto_msgTypes[27]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *LLenRequest) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*LLenRequest) ProtoMessage() {}
+
+func (x *LLenRequest) ProtoReflect() protoreflect.Message {
+	mi := &file_api_commands_proto_msgTypes[27]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use LLenRequest.ProtoReflect.Descriptor instead.
+func (*LLenRequest) Descriptor() ([]byte, []int) {
+	return file_api_commands_proto_rawDescGZIP(), []int{27}
+}
+
+func (x *LLenRequest) GetId() string {
+	if x != nil {
+		return x.Id
+	}
+	return ""
+}
+
+func (x *LLenRequest) GetMaxStalenessMs() int64 {
+	if x != nil {
+		return x.MaxStalenessMs
+	}
+	return 0
+}
+
+type LLenResponse struct {
+	state protoimpl.MessageState `protogen:"open.v1"`
+	// length doesn't count the jobs in flight of a queue.
+	Length        int64 `protobuf:"varint,1,opt,name=length,proto3" json:"length,omitempty"`
+	unknownFields protoimpl.UnknownFields
+	sizeCache     protoimpl.SizeCache
+}
+
+func (x *LLenResponse) Reset() {
+	*x = LLenResponse{}
+	mi := &file_api_commands_proto_msgTypes[28]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *LLenResponse) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*LLenResponse) ProtoMessage() {}
+
+func (x *LLenResponse) ProtoReflect() protoreflect.Message {
+	mi := &file_api_commands_proto_msgTypes[28]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use LLenResponse.ProtoReflect.Descriptor instead.
+func (*LLenResponse) Descriptor() ([]byte, []int) {
+	return file_api_commands_proto_rawDescGZIP(), []int{28}
+}
+
+func (x *LLenResponse) GetLength() int64 {
+	if x != nil {
+		return x.Length
+	}
+	return 0
+}
+
+type QueuePopRequest struct {
+	state protoimpl.MessageState `protogen:"open.v1"`
+	Id    string                 `protobuf:"bytes,1,opt,name=id,proto3" json:"id,omitempty"`
+	// visibility_timeout_ms is how long the job stays in flight before it
+	// goes back to the queue, unless it is acknowledged. Must be positive.
+	VisibilityTimeoutMs int64 `protobuf:"varint,2,opt,name=visibility_timeout_ms,json=visibilityTimeoutMs,proto3" json:"visibility_timeout_ms,omitempty"`
+	// block waits for an element like BLPop, for up to timeout_ms.
+	Block         bool  `protobuf:"varint,3,opt,name=block,proto3" json:"block,omitempty"`
+	TimeoutMs     int64 `protobuf:"varint,4,opt,name=timeout_ms,json=timeoutMs,proto3" json:"timeout_ms,omitempty"`
+	unknownFields protoimpl.UnknownFields
+	sizeCache     protoimpl.SizeCache
+}
+
+func (x *QueuePopRequest) Reset() {
+	*x = QueuePopRequest{}
+	mi := &file_api_commands_proto_msgTypes[29]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *QueuePopRequest) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*QueuePopRequest) ProtoMessage() {}
+
+func (x *QueuePopRequest) ProtoReflect() protoreflect.Message {
+	mi := &file_api_commands_proto_msgTypes[29]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use QueuePopRequest.ProtoReflect.Descriptor instead.
+func (*QueuePopRequest) Descriptor() ([]byte, []int) {
+	return file_api_commands_proto_rawDescGZIP(), []int{29}
+}
+
+func (x *QueuePopRequest) GetId() string {
+	if x != nil {
+		return x.Id
+	}
+	return ""
+}
+
+func (x *QueuePopRequest) GetVisibilityTimeoutMs() int64 {
+	if x != nil {
+		return x.VisibilityTimeoutMs
+	}
+	return 0
+}
+
+func (x *QueuePopRequest) GetBlock() bool {
+	if x != nil {
+		return x.Block
+	}
+	return false
+}
+
+func (x *QueuePopRequest) GetTimeoutMs() int64 {
+	if x != nil {
+		return x.TimeoutMs
+	}
+	return 0
+}
+
+type QueuePopResponse struct {
+	state protoimpl.MessageState `protogen:"open.v1"`
+	Found bool                   `protobuf:"varint,1,opt,name=found,proto3" json:"found,omitempty"`
+	// job_id acknowledges the job with QueueAck or QueueNack.
+	JobId string `protobuf:"bytes,2,opt,name=job_id,json=jobId,proto3" json:"job_id,omitempty"`
+	Value string `protobuf:"bytes,3,opt,name=value,proto3" json:"value,omitempty"`
+	// deadline_ms is when the job goes back to the queue, in Unix
+	// milliseconds.
+	DeadlineMs    int64 `protobuf:"varint,4,opt,name=deadline_ms,json=deadlineMs,proto3" json:"deadline_ms,omitempty"`
+	unknownFields protoimpl.UnknownFields
+	sizeCache     protoimpl.SizeCache
+}
+
+func (x *QueuePopResponse) Reset() {
+	*x = QueuePopResponse{}
+	mi := &file_api_commands_proto_msgTypes[30]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *QueuePopResponse) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*QueuePopResponse) ProtoMessage() {}
+
+func (x *QueuePopResponse) ProtoReflect() protoreflect.Message {
+	mi := &file_api_commands_proto_msgTypes[30]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use QueuePopResponse.ProtoReflect.Descriptor instead.
+func (*QueuePopResponse) Descriptor() ([]byte, []int) {
+	return file_api_commands_proto_rawDescGZIP(), []int{30}
+}
+
+func (x *QueuePopResponse) GetFound() bool {
+	if x != nil {
+		return x.Found
+	}
+	return false
+}
+
+func (x *QueuePopResponse) GetJobId() string {
+	if x != nil {
+		return x.JobId
+	}
+	return ""
+}
+
+func (x *QueuePopResponse) GetValue() string {
+	if x != nil {
+		return x.Value
+	}
+	return ""
+}
+
+func (x *QueuePopResponse) GetDeadlineMs() int64 {
+	if x != nil {
+		return x.DeadlineMs
+	}
+	return 0
+}
+
+type QueueJobRequest struct {
+	state         protoimpl.MessageState `protogen:"open.v1"`
+	Id            string                 `protobuf:"bytes,1,opt,name=id,proto3" json:"id,omitempty"`
+	JobId         string                 `protobuf:"bytes,2,opt,name=job_id,json=jobId,proto3" json:"job_id,omitempty"`
+	unknownFields protoimpl.UnknownFields
+	sizeCache     protoimpl.SizeCache
+}
+
+func (x *QueueJobRequest) Reset() {
+	*x = QueueJobRequest{}
+	mi := &file_api_commands_proto_msgTypes[31]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *QueueJobRequest) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*QueueJobRequest) ProtoMessage() {}
+
+func (x *QueueJobRequest) ProtoReflect() protoreflect.Message {
+	mi := &file_api_commands_proto_msgTypes[31]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use QueueJobRequest.ProtoReflect.Descriptor instead.
+func (*QueueJobRequest) Descriptor() ([]byte, []int) {
+	return file_api_commands_proto_rawDescGZIP(), []int{31}
+}
+
+func (x *QueueJobRequest) GetId() string {
+	if x != nil {
+		return x.Id
+	}
+	return ""
+}
+
+func (x *QueueJobRequest) GetJobId() string {
+	if x != nil {
+		return x.JobId
+	}
+	return ""
+}
+
+type QueueJobResponse struct {
+	state protoimpl.MessageState `protogen:"open.v1"`
+	// applied is false when the job wasn't in flight anymore: it was
+	// acknowledged already, or went back to the queue after its deadline.
+	Applied       bool `protobuf:"varint,1,opt,name=applied,proto3" json:"applied,omitempty"`
+	unknownFields protoimpl.UnknownFields
+	sizeCache     protoimpl.SizeCache
+}
+
+func (x *QueueJobResponse) Reset() {
+	*x = QueueJobResponse{}
+	mi := &file_api_commands_proto_msgTypes[32]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *QueueJobResponse) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*QueueJobResponse) ProtoMessage() {}
+
+func (x *QueueJobResponse) ProtoReflect() protoreflect.Message {
+	mi := &file_api_commands_proto_msgTypes[32]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use QueueJobResponse.ProtoReflect.Descriptor instead.
+func (*QueueJobResponse) Descriptor() ([]byte, []int) {
+	return file_api_commands_proto_rawDescGZIP(), []int{32}
+}
+
+func (x *QueueJobResponse) GetApplied() bool {
+	if x != nil {
+		return x.Applied
+	}
+	return false
+}
+
+type LockRequest struct {
+	state protoimpl.MessageState `protogen:"open.v1"`
+	// id names the lock. It is a key, which holds the lock while it is held.
+	Id string `protobuf:"bytes,1,opt,name=id,proto3" json:"id,omitempty"`
+	// session identifies the holder; the client picks it, and must use the
+	// same one to release or refresh the lock. Acquiring a lock the session
+	// already holds renews its lease and returns the same fencing token.
+	Session string `protobuf:"bytes,2,opt,name=session,proto3" json:"session,omitempty"`
+	// lease_ms is how long the lock is held unless it is refreshed. Must be
+	// positive.
+	LeaseMs int64 `protobuf:"varint,3,opt,name=lease_ms,json=leaseMs,proto3" json:"lease_ms,omitempty"`
+	// wait queues the request until the lock is released, for up to
+	// timeout_ms (0 waits until the client's deadline). Waiting requests
+	// get the lock in the order they arrived.
+	Wait          bool  `protobuf:"varint,4,opt,name=wait,proto3" json:"wait,omitempty"`
+	TimeoutMs     int64 `protobuf:"varint,5,opt,name=timeout_ms,json=timeoutMs,proto3" json:"timeout_ms,omitempty"`
+	unknownFields protoimpl.UnknownFields
+	sizeCache     protoimpl.SizeCache
+}
+
+func (x *LockRequest) Reset() {
+	*x = LockRequest{}
+	mi := &file_api_commands_proto_msgTypes[33]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *LockRequest) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*LockRequest) ProtoMessage() {}
+
+func (x *LockRequest) ProtoReflect() protoreflect.Message {
+	mi := &file_api_commands_proto_msgTypes[33]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use LockRequest.ProtoReflect.Descriptor instead.
+func (*LockRequest) Descriptor() ([]byte, []int) {
+	return file_api_commands_proto_rawDescGZIP(), []int{33}
+}
+
+func (x *LockRequest) GetId() string {
+	if x != nil {
+		return x.Id
+	}
+	return ""
+}
+
+func (x *LockRequest) GetSession() string {
+	if x != nil {
+		return x.Session
+	}
+	return ""
+}
+
+func (x *LockRequest) GetLeaseMs() int64 {
+	if x != nil {
+		return x.LeaseMs
+	}
+	return 0
+}
+
+func (x *LockRequest) GetWait() bool {
+	if x != nil {
+		return x.Wait
+	}
+	return false
+}
+
+func (x *LockRequest) GetTimeoutMs() int64 {
+	if x != nil {
+		return x.TimeoutMs
+	}
+	return 0
+}
+
+type LockResponse struct {
+	state    protoimpl.MessageState `protogen:"open.v1"`
+	Acquired bool                   `protobuf:"varint,1,opt,name=acquired,proto3" json:"acquired,omitempty"`
+	// fencing_token increases every time the lock changes hands. Pass it to
+	// the resources the lock protects, which should reject requests carrying
+	// a lower token than one they have seen.
+	FencingToken uint64 `protobuf:"varint,2,opt,name=fencing_token,json=fencingToken,proto3" json:"fencing_token,omitempty"`
+	// lease_expires_at_ms is when the lease ends, in Unix milliseconds.
+	LeaseExpiresAtMs int64 `protobuf:"varint,3,opt,name=lease_expires_at_ms,json=leaseExpiresAtMs,proto3" json:"lease_expires_at_ms,omitempty"`
+	// holder_session is the session holding the lock when it wasn't acquired,
+	// and lease_expires_at_ms the end of its lease.
+	HolderSession string `protobuf:"bytes,4,opt,name=holder_session,json=holderSession,proto3" json:"holder_session,omitempty"`
+	unknownFields protoimpl.UnknownFields
+	sizeCache     protoimpl.SizeCache
+}
+
+func (x *LockResponse) Reset() {
+	*x = LockResponse{}
+	mi := &file_api_commands_proto_msgTypes[34]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *LockResponse) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*LockResponse) ProtoMessage() {}
+
+func (x *LockResponse) ProtoReflect() protoreflect.Message {
+	mi := &file_api_commands_proto_msgTypes[34]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use LockResponse.ProtoReflect.Descriptor instead.
+func (*LockResponse) Descriptor() ([]byte, []int) {
+	return file_api_commands_proto_rawDescGZIP(), []int{34}
+}
+
+func (x *LockResponse) GetAcquired() bool {
+	if x != nil {
+		return x.Acquired
+	}
+	return false
+}
+
+func (x *LockResponse) GetFencingToken() uint64 {
+	if x != nil {
+		return x.FencingToken
+	}
+	return 0
+}
+
+func (x *LockResponse) GetLeaseExpiresAtMs() int64 {
+	if x != nil {
+		return x.LeaseExpiresAtMs
+	}
+	return 0
+}
+
+func (x *LockResponse) GetHolderSession() string {
+	if x != nil {
+		return x.HolderSession
+	}
+	return ""
+}
+
+type UnlockRequest struct {
+	state         protoimpl.MessageState `protogen:"open.v1"`
+	Id            string                 `protobuf:"bytes,1,opt,name=id,proto3" json:"id,omitempty"`
+	Session       string                 `protobuf:"bytes,2,opt,name=session,proto3" json:"session,omitempty"`
+	FencingToken  uint64                 `protobuf:"varint,3,opt,name=fencing_token,json=fencingToken,proto3" json:"fencing_token,omitempty"`
+	unknownFields protoimpl.UnknownFields
+	sizeCache     protoimpl.SizeCache
+}
+
+func (x *UnlockRequest) Reset() {
+	*x = UnlockRequest{}
+	mi := &file_api_commands_proto_msgTypes[35]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *UnlockRequest) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*UnlockRequest) ProtoMessage() {}
+
+func (x *UnlockRequest) ProtoReflect() protoreflect.Message {
+	mi := &file_api_commands_proto_msgTypes[35]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use UnlockRequest.ProtoReflect.Descriptor instead.
+func (*UnlockRequest) Descriptor() ([]byte, []int) {
+	return file_api_commands_proto_rawDescGZIP(), []int{35}
+}
+
+func (x *UnlockRequest) GetId() string {
+	if x != nil {
+		return x.Id
+	}
+	return ""
+}
+
+func (x *UnlockRequest) GetSession() string {
+	if x != nil {
+		return x.Session
+	}
+	return ""
+}
+
+func (x *UnlockRequest) GetFencingToken() uint64 {
+	if x != nil {
+		return x.FencingToken
+	}
+	return 0
+}
+
+type UnlockResponse struct {
+	state protoimpl.MessageState `protogen:"open.v1"`
+	// released is false when the session didn't hold the lock with that
+	// token anymore: its lease ran out, and it may have changed hands.
+	Released      bool `protobuf:"varint,1,opt,name=released,proto3" json:"released,omitempty"`
+	unknownFields protoimpl.UnknownFields
+	sizeCache     protoimpl.SizeCache
+}
+
+func (x *UnlockResponse) Reset() {
+	*x = UnlockResponse{}
+	mi := &file_api_commands_proto_msgTypes[36]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *UnlockResponse) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*UnlockResponse) ProtoMessage() {}
+
+func (x *UnlockResponse) ProtoReflect() protoreflect.Message {
+	mi := &file_api_commands_proto_msgTypes[36]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use UnlockResponse.ProtoReflect.Descriptor instead.
+func (*UnlockResponse) Descriptor() ([]byte, []int) {
+	return file_api_commands_proto_rawDescGZIP(), []int{36}
+}
+
+func (x *UnlockResponse) GetReleased() bool {
+	if x != nil {
+		return x.Released
+	}
+	return false
+}
+
+type RefreshLockRequest struct {
+	state        protoimpl.MessageState `protogen:"open.v1"`
+	Id           string                 `protobuf:"bytes,1,opt,name=id,proto3" json:"id,omitempty"`
+	Session      string                 `protobuf:"bytes,2,opt,name=session,proto3" json:"session,omitempty"`
+	FencingToken uint64                 `protobuf:"varint,3,opt,name=fencing_token,json=fencingToken,proto3" json:"fencing_token,omitempty"`
+	// lease_ms is the new lease, from now. Must be positive.
+	LeaseMs       int64 `protobuf:"varint,4,opt,name=lease_ms,json=leaseMs,proto3" json:"lease_ms,omitempty"`
+	unknownFields protoimpl.UnknownFields
+	sizeCache     protoimpl.SizeCache
+}
+
+func (x *RefreshLockRequest) Reset() {
+	*x = RefreshLockRequest{}
+	mi := &file_api_commands_proto_msgTypes[37]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *RefreshLockRequest) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*RefreshLockRequest) ProtoMessage() {}
+
+func (x *RefreshLockRequest) ProtoReflect() protoreflect.Message {
+	mi := &file_api_commands_proto_msgTypes[37]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use RefreshLockRequest.ProtoReflect.Descriptor instead.
+func (*RefreshLockRequest) Descriptor() ([]byte, []int) {
+	return file_api_commands_proto_rawDescGZIP(), []int{37}
+}
+
+func (x *RefreshLockRequest) GetId() string {
+	if x != nil {
+		return x.Id
+	}
+	return ""
+}
+
+func (x *RefreshLockRequest) GetSession() string {
+	if x != nil {
+		return x.Session
+	}
+	return ""
+}
+
+func (x *RefreshLockRequest) GetFencingToken() uint64 {
+	if x != nil {
+		return x.FencingToken
+	}
+	return 0
+}
+
+func (x *RefreshLockRequest) GetLeaseMs() int64 {
+	if x != nil {
+		return x.LeaseMs
+	}
+	return 0
+}
+
+type RefreshLockResponse struct {
+	state protoimpl.MessageState `protogen:"open.v1"`
+	// refreshed is false when the session didn't hold the lock with that
+	// token anymore.
+	Refreshed        bool  `protobuf:"varint,1,opt,name=refreshed,proto3" json:"refreshed,omitempty"`
+	LeaseExpiresAtMs int64 `protobuf:"varint,2,opt,name=lease_expires_at_ms,json=leaseExpiresAtMs,proto3" json:"lease_expires_at_ms,omitempty"`
+	unknownFields    protoimpl.UnknownFields
+	sizeCache        protoimpl.SizeCache
+}
+
+func (x *RefreshLockResponse) Reset() {
+	*x = RefreshLockResponse{}
+	mi := &file_api_commands_proto_msgTypes[38]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *RefreshLockResponse) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*RefreshLockResponse) ProtoMessage() {}
+
+func (x *RefreshLockResponse) ProtoReflect() protoreflect.Message {
+	mi := &file_api_commands_proto_msgTypes[38]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use RefreshLockResponse.ProtoReflect.Descriptor instead.
+func (*RefreshLockResponse) Descriptor() ([]byte, []int) {
+	return file_api_commands_proto_rawDescGZIP(), []int{38}
+}
+
+func (x *RefreshLockResponse) GetRefreshed() bool {
+	if x != nil {
+		return x.Refreshed
+	}
+	return false
+}
+
+func (x *RefreshLockResponse) GetLeaseExpiresAtMs() int64 {
+	if x != nil {
+		return x.LeaseExpiresAtMs
+	}
+	return 0
+}
+
+type CreateSessionRequest struct {
+	state protoimpl.MessageState `protogen:"open.v1"`
+	// id names the session; the server picks one when it is empty. Creating
+	// a session that exists keeps it alive with the new TTL, so retrying a
+	// lost response is safe. A session that ended can't be created again.
+	Id string `protobuf:"bytes,1,opt,name=id,proto3" json:"id,omitempty"`
+	// ttl_ms is how long the session lives without a keepalive. Must be
+	// positive.
+	TtlMs         int64 `protobuf:"varint,2,opt,name=ttl_ms,json=ttlMs,proto3" json:"ttl_ms,omitempty"`
+	unknownFields protoimpl.UnknownFields
+	sizeCache     protoimpl.SizeCache
+}
+
+func (x *CreateSessionRequest) Reset() {
+	*x = CreateSessionRequest{}
+	mi := &file_api_commands_proto_msgTypes[39]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *CreateSessionRequest) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*CreateSessionRequest) ProtoMessage() {}
+
+func (x *CreateSessionRequest) ProtoReflect() protoreflect.Message {
+	mi := &file_api_commands_proto_msgTypes[39]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use CreateSessionRequest.ProtoReflect.Descriptor instead.
+func (*CreateSessionRequest) Descriptor() ([]byte, []int) {
+	return file_api_commands_proto_rawDescGZIP(), []int{39}
+}
+
+func (x *CreateSessionRequest) GetId() string {
+	if x != nil {
+		return x.Id
+	}
+	return ""
+}
+
+func (x *CreateSessionRequest) GetTtlMs() int64 {
+	if x != nil {
+		return x.TtlMs
+	}
+	return 0
+}
+
+type CreateSessionResponse struct {
+	state         protoimpl.MessageState `protogen:"open.v1"`
+	Id            string                 `protobuf:"bytes,1,opt,name=id,proto3" json:"id,omitempty"`
+	ExpiresAtMs   int64                  `protobuf:"varint,2,opt,name=expires_at_ms,json=expiresAtMs,proto3" json:"expires_at_ms,omitempty"`
+	unknownFields protoimpl.UnknownFields
+	sizeCache     protoimpl.SizeCache
+}
+
+func (x *CreateSessionResponse) Reset() {
+	*x = CreateSessionResponse{}
+	mi := &file_api_commands_proto_msgTypes[40]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *CreateSessionResponse) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*CreateSessionResponse) ProtoMessage() {}
+
+func (x *CreateSessionResponse) ProtoReflect() protoreflect.Message {
+	mi := &file_api_commands_proto_msgTypes[40]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use CreateSessionResponse.ProtoReflect.Descriptor instead.
+func (*CreateSessionResponse) Descriptor() ([]byte, []int) {
+	return file_api_commands_proto_rawDescGZIP(), []int{40}
+}
+
+func (x *CreateSessionResponse) GetId() string {
+	if x != nil {
+		return x.Id
+	}
+	return ""
+}
+
+func (x *CreateSessionResponse) GetExpiresAtMs() int64 {
+	if x != nil {
+		return x.ExpiresAtMs
+	}
+	return 0
+}
+
+type KeepAliveRequest struct {
+	state         protoimpl.MessageState `protogen:"open.v1"`
+	Id            string                 `protobuf:"bytes,1,opt,name=id,proto3" json:"id,omitempty"`
+	unknownFields protoimpl.UnknownFields
+	sizeCache     protoimpl.SizeCache
+}
+
+func (x *KeepAliveRequest) Reset() {
+	*x = KeepAliveRequest{}
+	mi := &file_api_commands_proto_msgTypes[41]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *KeepAliveRequest) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*KeepAliveRequest) ProtoMessage() {}
+
+func (x *KeepAliveRequest) ProtoReflect() protoreflect.Message {
+	mi := &file_api_commands_proto_msgTypes[41]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use KeepAliveRequest.ProtoReflect.Descriptor instead.
+func (*KeepAliveRequest) Descriptor() ([]byte, []int) {
+	return file_api_commands_proto_rawDescGZIP(), []int{41}
+}
+
+func (x *KeepAliveRequest) GetId() string {
+	if x != nil {
+		return x.Id
+	}
+	return ""
+}
+
+type KeepAliveResponse struct {
+	state protoimpl.MessageState `protogen:"open.v1"`
+	// expires_at_ms is when the session ends unless it is kept alive again.
+	ExpiresAtMs   int64 `protobuf:"varint,1,opt,name=expires_at_ms,json=expiresAtMs,proto3" json:"expires_at_ms,omitempty"`
+	unknownFields protoimpl.UnknownFields
+	sizeCache     protoimpl.SizeCache
+}
+
+func (x *KeepAliveResponse) Reset() {
+	*x = KeepAliveResponse{}
+	mi := &file_api_commands_proto_msgTypes[42]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *KeepAliveResponse) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*KeepAliveResponse) ProtoMessage() {}
+
+func (x *KeepAliveResponse) ProtoReflect() protoreflect.Message {
+	mi := &file_api_commands_proto_msgTypes[42]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use KeepAliveResponse.ProtoReflect.Descriptor instead.
+func (*KeepAliveResponse) Descriptor() ([]byte, []int) {
+	return file_api_commands_proto_rawDescGZIP(), []int{42}
+}
+
+func (x *KeepAliveResponse) GetExpiresAtMs() int64 {
+	if x != nil {
+		return x.ExpiresAtMs
+	}
+	return 0
+}
+
+type CloseSessionRequest struct {
+	state         protoimpl.MessageState `protogen:"open.v1"`
+	Id            string                 `protobuf:"bytes,1,opt,name=id,proto3" json:"id,omitempty"`
+	unknownFields protoimpl.UnknownFields
+	sizeCache     protoimpl.SizeCache
+}
+
+func (x *CloseSessionRequest) Reset() {
+	*x = CloseSessionRequest{}
+	mi := &file_api_commands_proto_msgTypes[43]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *CloseSessionRequest) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*CloseSessionRequest) ProtoMessage() {}
+
+func (x *CloseSessionRequest) ProtoReflect() protoreflect.Message {
+	mi := &file_api_commands_proto_msgTypes[43]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use CloseSessionRequest.ProtoReflect.Descriptor instead.
+func (*CloseSessionRequest) Descriptor() ([]byte, []int) {
+	return file_api_commands_proto_rawDescGZIP(), []int{43}
+}
+
+func (x *CloseSessionRequest) GetId() string {
+	if x != nil {
+		return x.Id
+	}
+	return ""
+}
+
+type CloseSessionResponse struct {
+	state protoimpl.MessageState `protogen:"open.v1"`
+	// closed is false when the session had already ended and been cleaned
+	// up, or never existed.
+	Closed bool `protobuf:"varint,1,opt,name=closed,proto3" json:"closed,omitempty"`
+	// delete_count is the number of ephemeral keys deleted with it.
+	DeleteCount   int64 `protobuf:"varint,2,opt,name=delete_count,json=deleteCount,proto3" json:"delete_count,omitempty"`
+	unknownFields protoimpl.UnknownFields
+	sizeCache     protoimpl.SizeCache
+}
+
+func (x *CloseSessionResponse) Reset() {
+	*x = CloseSessionResponse{}
+	mi := &file_api_commands_proto_msgTypes[44]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *CloseSessionResponse) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*CloseSessionResponse) ProtoMessage() {}
+
+func (x *CloseSessionResponse) ProtoReflect() protoreflect.Message {
+	mi := &file_api_commands_proto_msgTypes[44]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use CloseSessionResponse.ProtoReflect.Descriptor instead.
+func (*CloseSessionResponse) Descriptor() ([]byte, []int) {
+	return file_api_commands_proto_rawDescGZIP(), []int{44}
+}
+
+func (x *CloseSessionResponse) GetClosed() bool {
+	if x != nil {
+		return x.Closed
+	}
+	return false
+}
+
+func (x *CloseSessionResponse) GetDeleteCount() int64 {
+	if x != nil {
+		return x.DeleteCount
+	}
+	return 0
+}
+
+// StreamTrim limits the length of a stream. Both limits apply when set.
+type StreamTrim struct {
+	state protoimpl.MessageState `protogen:"open.v1"`
+	// max_len keeps the max_len newest entries; 0 means no limit.
+	MaxLen int64 `protobuf:"varint,1,opt,name=max_len,json=maxLen,proto3" json:"max_len,omitempty"`
+	// min_id keeps the entries from this ID on.
+	MinId         string `protobuf:"bytes,2,opt,name=min_id,json=minId,proto3" json:"min_id,omitempty"`
+	unknownFields protoimpl.UnknownFields
+	sizeCache     protoimpl.SizeCache
+}
+
+func (x *StreamTrim) Reset() {
+	*x = StreamTrim{}
+	mi := &file_api_commands_proto_msgTypes[45]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *StreamTrim) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*StreamTrim) ProtoMessage() {}
+
+func (x *StreamTrim) ProtoReflect() protoreflect.Message {
+	mi := &file_api_commands_proto_msgTypes[45]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use StreamTrim.ProtoReflect.Descriptor instead.
+func (*StreamTrim) Descriptor() ([]byte, []int) {
+	return file_api_commands_proto_rawDescGZIP(), []int{45}
+}
+
+func (x *StreamTrim) GetMaxLen() int64 {
+	if x != nil {
+		return x.MaxLen
+	}
+	return 0
+}
+
+func (x *StreamTrim) GetMinId() string {
+	if x != nil {
+		return x.MinId
+	}
+	return ""
+}
+
+type StreamEntry struct {
+	state protoimpl.MessageState `protogen:"open.v1"`
+	// entry_id is "<ms>-<seq>": the time the entry was added, in Unix
+	// milliseconds, and a sequence number within that millisecond.
+	EntryId       string            `protobuf:"bytes,1,opt,name=entry_id,json=entryId,proto3" json:"entry_id,omitempty"`
+	Fields        map[string]string `protobuf:"bytes,2,rep,name=fields,proto3" json:"fields,omitempty" protobuf_key:"bytes,1,opt,name=key" protobuf_val:"bytes,2,opt,name=value"`
+	unknownFields protoimpl.UnknownFields
+	sizeCache     protoimpl.SizeCache
+}
+
+func (x *StreamEntry) Reset() {
+	*x = StreamEntry{}
+	mi := &file_api_commands_proto_msgTypes[46]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *StreamEntry) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*StreamEntry) ProtoMessage() {}
+
+func (x *StreamEntry) ProtoReflect() protoreflect.Message {
+	mi := &file_api_commands_proto_msgTypes[46]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use StreamEntry.ProtoReflect.Descriptor instead.
+func (*StreamEntry) Descriptor() ([]byte, []int) {
+	return file_api_commands_proto_rawDescGZIP(), []int{46}
+}
+
+func (x *StreamEntry) GetEntryId() string {
+	if x != nil {
+		return x.EntryId
+	}
+	return ""
+}
+
+func (x *StreamEntry) GetFields() map[string]string {
+	if x != nil {
+		return x.Fields
+	}
+	return nil
+}
+
+type XAddRequest struct {
+	state protoimpl.MessageState `protogen:"open.v1"`
+	Id    string                 `protobuf:"bytes,1,opt,name=id,proto3" json:"id,omitempty"`
+	// entry_id is the ID of the entry, which must be greater than the
+	// stream's last one; empty or "*" picks the next one.
+	EntryId string `protobuf:"bytes,2,opt,name=entry_id,json=entryId,proto3" json:"entry_id,omitempty"`
+	// fields must not be empty.
+	Fields map[string]string `protobuf:"bytes,3,rep,name=fields,proto3" json:"fields,omitempty" protobuf_key:"bytes,1,opt,name=key" protobuf_val:"bytes,2,opt,name=value"`
+	// trim is applied after the entry is added.
+	Trim          *StreamTrim `protobuf:"bytes,4,opt,name=trim,proto3" json:"trim,omitempty"`
+	unknownFields protoimpl.UnknownFields
+	sizeCache     protoimpl.SizeCache
+}
+
+func (x *XAddRequest) Reset() {
+	*x = XAddRequest{}
+	mi := &file_api_commands_proto_msgTypes[47]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *XAddRequest) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*XAddRequest) ProtoMessage() {}
+
+func (x *XAddRequest) ProtoReflect() protoreflect.Message {
+	mi := &file_api_commands_proto_msgTypes[47]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use XAddRequest.ProtoReflect.Descriptor instead.
+func (*XAddRequest) Descriptor() ([]byte, []int) {
+	return file_api_commands_proto_rawDescGZIP(), []int{47}
+}
+
+func (x *XAddRequest) GetId() string {
+	if x != nil {
+		return x.Id
+	}
+	return ""
+}
+
+func (x *XAddRequest) GetEntryId() string {
+	if x != nil {
+		return x.EntryId
+	}
+	return ""
+}
+
+func (x *XAddRequest) GetFields() map[string]string {
+	if x != nil {
+		return x.Fields
+	}
+	return nil
+}
+
+func (x *XAddRequest) GetTrim() *StreamTrim {
+	if x != nil {
+		return x.Trim
+	}
+	return nil
+}
+
+type XAddResponse struct {
+	state         protoimpl.MessageState `protogen:"open.v1"`
+	EntryId       string                 `protobuf:"bytes,1,opt,name=entry_id,json=entryId,proto3" json:"entry_id,omitempty"`
+	unknownFields protoimpl.UnknownFields
+	sizeCache     protoimpl.SizeCache
+}
+
+func (x *XAddResponse) Reset() {
+	*x = XAddResponse{}
+	mi := &file_api_commands_proto_msgTypes[48]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *XAddResponse) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*XAddResponse) ProtoMessage() {}
+
+func (x *XAddResponse) ProtoReflect() protoreflect.Message {
+	mi := &file_api_commands_proto_msgTypes[48]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use XAddResponse.ProtoReflect.Descriptor instead.
+func (*XAddResponse) Descriptor() ([]byte, []int) {
+	return file_api_commands_proto_rawDescGZIP(), []int{48}
+}
+
+func (x *XAddResponse) GetEntryId() string {
+	if x != nil {
+		return x.EntryId
+	}
+	return ""
+}
+
+type XRangeRequest struct {
+	state protoimpl.MessageState `protogen:"open.v1"`
+	Id    string                 `protobuf:"bytes,1,opt,name=id,proto3" json:"id,omitempty"`
+	// start and end are entry IDs, both included; "-" and "+" stand for the
+	// lowest and highest, and a "(" prefix excludes the bound. To read the
+	// entries after the last one seen, pass it as "(<id>" with end "+".
+	Start string `protobuf:"bytes,2,opt,name=start,proto3" json:"start,omitempty"`
+	End   string `protobuf:"bytes,3,opt,name=end,proto3" json:"end,omitempty"`
+	// count limits the number of entries returned; 0 means no limit.
+	Count int64 `protobuf:"varint,4,opt,name=count,proto3" json:"count,omitempty"`
+	// reverse returns the entries from end to start.
+	Reverse bool `protobuf:"varint,5,opt,name=reverse,proto3" json:"reverse,omitempty"`
+	// max_staleness_ms works as in GetRequest.
+	MaxStalenessMs int64 `protobuf:"varint,6,opt,name=max_staleness_ms,json=maxStalenessMs,proto3" json:"max_staleness_ms,omitempty"`
+	unknownFields  protoimpl.UnknownFields
+	sizeCache      protoimpl.SizeCache
+}
+
+func (x *XRangeRequest) Reset() {
+	*x = XRangeRequest{}
+	mi := &file_api_commands_proto_msgTypes[49]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *XRangeRequest) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*XRangeRequest) ProtoMessage() {}
+
+func (x *XRangeRequest) ProtoReflect() protoreflect.Message {
+	mi := &file_api_commands_proto_msgTypes[49]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use XRangeRequest.ProtoReflect.Descriptor instead.
+func (*XRangeRequest) Descriptor() ([]byte, []int) {
+	return file_api_commands_proto_rawDescGZIP(), []int{49}
+}
+
+func (x *XRangeRequest) GetId() string {
+	if x != nil {
+		return x.Id
+	}
+	return ""
+}
+
+func (x *XRangeRequest) GetStart() string {
+	if x != nil {
+		return x.Start
+	}
+	return ""
+}
+
+func (x *XRangeRequest) GetEnd() string {
+	if x != nil {
+		return x.End
+	}
+	return ""
+}
+
+func (x *XRangeRequest) GetCount() int64 {
+	if x != nil {
+		return x.Count
+	}
+	return 0
+}
+
+func (x *XRangeRequest) GetReverse() bool {
+	if x != nil {
+		return x.Reverse
+	}
+	return false
+}
+
+func (x *XRangeRequest) GetMaxStalenessMs() int64 {
+	if x != nil {
+		return x.MaxStalenessMs
+	}
+	return 0
+}
+
+type StreamEntriesResponse struct {
+	state         protoimpl.MessageState `protogen:"open.v1"`
+	Entries       []*StreamEntry         `protobuf:"bytes,1,rep,name=entries,proto3" json:"entries,omitempty"`
+	unknownFields protoimpl.UnknownFields
+	sizeCache     protoimpl.SizeCache
+}
+
+func (x *StreamEntriesResponse) Reset() {
+	*x = StreamEntriesResponse{}
+	mi := &file_api_commands_proto_msgTypes[50]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *StreamEntriesResponse) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*StreamEntriesResponse) ProtoMessage() {}
+
+func (x *StreamEntriesResponse) ProtoReflect() protoreflect.Message {
+	mi := &file_api_commands_proto_msgTypes[50]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use StreamEntriesResponse.ProtoReflect.Descriptor instead.
+func (*StreamEntriesResponse) Descriptor() ([]byte, []int) {
+	return file_api_commands_proto_rawDescGZIP(), []int{50}
+}
+
+func (x *StreamEntriesResponse) GetEntries() []*StreamEntry {
+	if x != nil {
+		return x.Entries
+	}
+	return nil
+}
+
+type XLenRequest struct {
+	state protoimpl.MessageState `protogen:"open.v1"`
+	Id    string                 `protobuf:"bytes,1,opt,name=id,proto3" json:"id,omitempty"`
+	// max_staleness_ms works as in GetRequest.
+	MaxStalenessMs int64 `protobuf:"varint,2,opt,name=max_staleness_ms,json=maxStalenessMs,proto3" json:"max_staleness_ms,omitempty"`
+	unknownFields  protoimpl.UnknownFields
+	sizeCache      protoimpl.SizeCache
+}
+
+func (x *XLenRequest) Reset() {
+	*x = XLenRequest{}
+	mi := &file_api_commands_proto_msgTypes[51]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *XLenRequest) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*XLenRequest) ProtoMessage() {}
+
+func (x *XLenRequest) ProtoReflect() protoreflect.Message {
+	mi := &file_api_commands_proto_msgTypes[51]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use XLenRequest.ProtoReflect.Descriptor instead.
+func (*XLenRequest) Descriptor() ([]byte, []int) {
+	return file_api_commands_proto_rawDescGZIP(), []int{51}
+}
+
+func (x *XLenRequest) GetId() string {
+	if x != nil {
+		return x.Id
+	}
+	return ""
+}
+
+func (x *XLenRequest) GetMaxStalenessMs() int64 {
+	if x != nil {
+		return x.MaxStalenessMs
+	}
+	return 0
+}
+
+type XLenResponse struct {
+	state         protoimpl.MessageState `protogen:"open.v1"`
+	Length        int64                  `protobuf:"varint,1,opt,name=length,proto3" json:"length,omitempty"`
+	unknownFields protoimpl.UnknownFields
+	sizeCache     protoimpl.SizeCache
+}
+
+func (x *XLenResponse) Reset() {
+	*x = XLenResponse{}
+	mi := &file_api_commands_proto_msgTypes[52]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *XLenResponse) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*XLenResponse) ProtoMessage() {}
+
+func (x *XLenResponse) ProtoReflect() protoreflect.Message {
+	mi := &file_api_commands_proto_msgTypes[52]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use XLenResponse.ProtoReflect.Descriptor instead.
+func (*XLenResponse) Descriptor() ([]byte, []int) {
+	return file_api_commands_proto_rawDescGZIP(), []int{52}
+}
+
+func (x *XLenResponse) GetLength() int64 {
+	if x != nil {
+		return x.Length
+	}
+	return 0
+}
+
+type XTrimRequest struct {
+	state         protoimpl.MessageState `protogen:"open.v1"`
+	Id            string                 `protobuf:"bytes,1,opt,name=id,proto3" json:"id,omitempty"`
+	Trim          *StreamTrim            `protobuf:"bytes,2,opt,name=trim,proto3" json:"trim,omitempty"`
+	unknownFields protoimpl.UnknownFields
+	sizeCache     protoimpl.SizeCache
+}
+
+func (x *XTrimRequest) Reset() {
+	*x = XTrimRequest{}
+	mi := &file_api_commands_proto_msgTypes[53]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *XTrimRequest) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*XTrimRequest) ProtoMessage() {}
+
+func (x *XTrimRequest) ProtoReflect() protoreflect.Message {
+	mi := &file_api_commands_proto_msgTypes[53]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use XTrimRequest.ProtoReflect.Descriptor instead.
+func (*XTrimRequest) Descriptor() ([]byte, []int) {
+	return file_api_commands_proto_rawDescGZIP(), []int{53}
+}
+
+func (x *XTrimRequest) GetId() string {
+	if x != nil {
+		return x.Id
+	}
+	return ""
+}
+
+func (x *XTrimRequest) GetTrim() *StreamTrim {
+	if x != nil {
+		return x.Trim
+	}
+	return nil
+}
+
+type XTrimResponse struct {
+	state protoimpl.MessageState `protogen:"open.v1"`
+	// trimmed is the number of entries removed.
+	Trimmed       int64 `protobuf:"varint,1,opt,name=trimmed,proto3" json:"trimmed,omitempty"`
+	unknownFields protoimpl.UnknownFields
+	sizeCache     protoimpl.SizeCache
+}
+
+func (x *XTrimResponse) Reset() {
+	*x = XTrimResponse{}
+	mi := &file_api_commands_proto_msgTypes[54]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *XTrimResponse) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*XTrimResponse) ProtoMessage() {}
+
+func (x *XTrimResponse) ProtoReflect() protoreflect.Message {
+	mi := &file_api_commands_proto_msgTypes[54]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use XTrimResponse.ProtoReflect.Descriptor instead.
+func (*XTrimResponse) Descriptor() ([]byte, []int) {
+	return file_api_commands_proto_rawDescGZIP(), []int{54}
+}
+
+func (x *XTrimResponse) GetTrimmed() int64 {
+	if x != nil {
+		return x.Trimmed
+	}
+	return 0
+}
+
+type XGroupCreateRequest struct {
+	state protoimpl.MessageState `protogen:"open.v1"`
+	Id    string                 `protobuf:"bytes,1,opt,name=id,proto3" json:"id,omitempty"`
+	Group string                 `protobuf:"bytes,2,opt,name=group,proto3" json:"group,omitempty"`
+	// start is the entry ID after which the group is delivered entries: "0"
+	// for all of them, "$" (the default) for those added from now on.
+	Start         string `protobuf:"bytes,3,opt,name=start,proto3" json:"start,omitempty"`
+	unknownFields protoimpl.UnknownFields
+	sizeCache     protoimpl.SizeCache
+}
+
+func (x *XGroupCreateRequest) Reset() {
+	*x = XGroupCreateRequest{}
+	mi := &file_api_commands_proto_msgTypes[55]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *XGroupCreateRequest) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*XGroupCreateRequest) ProtoMessage() {}
+
+func (x *XGroupCreateRequest) ProtoReflect() protoreflect.Message {
+	mi := &file_api_commands_proto_msgTypes[55]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use XGroupCreateRequest.ProtoReflect.Descriptor instead.
+func (*XGroupCreateRequest) Descriptor() ([]byte, []int) {
+	return file_api_commands_proto_rawDescGZIP(), []int{55}
+}
+
+func (x *XGroupCreateRequest) GetId() string {
+	if x != nil {
+		return x.Id
+	}
+	return ""
+}
+
+func (x *XGroupCreateRequest) GetGroup() string {
+	if x != nil {
+		return x.Group
+	}
+	return ""
+}
+
+func (x *XGroupCreateRequest) GetStart() string {
+	if x != nil {
+		return x.Start
+	}
+	return ""
+}
+
+type XGroupCreateResponse struct {
+	state protoimpl.MessageState `protogen:"open.v1"`
+	// created is false when the group already existed; it is left as it is.
+	Created       bool `protobuf:"varint,1,opt,name=created,proto3" json:"created,omitempty"`
+	unknownFields protoimpl.UnknownFields
+	sizeCache     protoimpl.SizeCache
+}
+
+func (x *XGroupCreateResponse) Reset() {
+	*x = XGroupCreateResponse{}
+	mi := &file_api_commands_proto_msgTypes[56]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *XGroupCreateResponse) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*XGroupCreateResponse) ProtoMessage() {}
+
+func (x *XGroupCreateResponse) ProtoReflect() protoreflect.Message {
+	mi := &file_api_commands_proto_msgTypes[56]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use XGroupCreateResponse.ProtoReflect.Descriptor instead.
+func (*XGroupCreateResponse) Descriptor() ([]byte, []int) {
+	return file_api_commands_proto_rawDescGZIP(), []int{56}
+}
+
+func (x *XGroupCreateResponse) GetCreated() bool {
+	if x != nil {
+		return x.Created
+	}
+	return false
+}
+
+type XReadGroupRequest struct {
+	state    protoimpl.MessageState `protogen:"open.v1"`
+	Id       string                 `protobuf:"bytes,1,opt,name=id,proto3" json:"id,omitempty"`
+	Group    string                 `protobuf:"bytes,2,opt,name=group,proto3" json:"group,omitempty"`
+	Consumer string                 `protobuf:"bytes,3,opt,name=consumer,proto3" json:"consumer,omitempty"`
+	// after ">" (the default) delivers entries no consumer of the group was
+	// delivered yet. An entry ID instead returns the entries after it still
+	// pending for the consumer, like after a crash.
+	After string `protobuf:"bytes,4,opt,name=after,proto3" json:"after,omitempty"`
+	// count limits the number of entries returned; 0 means no limit.
+	Count         int64 `protobuf:"varint,5,opt,name=count,proto3" json:"count,omitempty"`
+	unknownFields protoimpl.UnknownFields
+	sizeCache     protoimpl.SizeCache
+}
+
+func (x *XReadGroupRequest) Reset() {
+	*x = XReadGroupRequest{}
+	mi := &file_api_commands_proto_msgTypes[57]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *XReadGroupRequest) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*XReadGroupRequest) ProtoMessage() {}
+
+func (x *XReadGroupRequest) ProtoReflect() protoreflect.Message {
+	mi := &file_api_commands_proto_msgTypes[57]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use XReadGroupRequest.ProtoReflect.Descriptor instead.
+func (*XReadGroupRequest) Descriptor() ([]byte, []int) {
+	return file_api_commands_proto_rawDescGZIP(), []int{57}
+}
+
+func (x *XReadGroupRequest) GetId() string {
+	if x != nil {
+		return x.Id
+	}
+	return ""
+}
+
+func (x *XReadGroupRequest) GetGroup() string {
+	if x != nil {
+		return x.Group
+	}
+	return ""
+}
+
+func (x *XReadGroupRequest) GetConsumer() string {
+	if x != nil {
+		return x.Consumer
+	}
+	return ""
+}
+
+func (x *XReadGroupRequest) GetAfter() string {
+	if x != nil {
+		return x.After
+	}
+	return ""
+}
+
+func (x *XReadGroupRequest) GetCount() int64 {
+	if x != nil {
+		return x.Count
+	}
+	return 0
+}
+
+type XAckRequest struct {
+	state         protoimpl.MessageState `protogen:"open.v1"`
+	Id            string                 `protobuf:"bytes,1,opt,name=id,proto3" json:"id,omitempty"`
+	Group         string                 `protobuf:"bytes,2,opt,name=group,proto3" json:"group,omitempty"`
+	EntryIds      []string               `protobuf:"bytes,3,rep,name=entry_ids,json=entryIds,proto3" json:"entry_ids,omitempty"`
+	unknownFields protoimpl.UnknownFields
+	sizeCache     protoimpl.SizeCache
+}
+
+func (x *XAckRequest) Reset() {
+	*x = XAckRequest{}
+	mi := &file_api_commands_proto_msgTypes[58]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *XAckRequest) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*XAckRequest) ProtoMessage() {}
+
+func (x *XAckRequest) ProtoReflect() protoreflect.Message {
+	mi := &file_api_commands_proto_msgTypes[58]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use XAckRequest.ProtoReflect.Descriptor instead.
+func (*XAckRequest) Descriptor() ([]byte, []int) {
+	return file_api_commands_proto_rawDescGZIP(), []int{58}
+}
+
+func (x *XAckRequest) GetId() string {
+	if x != nil {
+		return x.Id
+	}
+	return ""
+}
+
+func (x *XAckRequest) GetGroup() string {
+	if x != nil {
+		return x.Group
+	}
+	return ""
+}
+
+func (x *XAckRequest) GetEntryIds() []string {
+	if x != nil {
+		return x.EntryIds
+	}
+	return nil
+}
+
+type XAckResponse struct {
+	state protoimpl.MessageState `protogen:"open.v1"`
+	// acked is the number of entries that were pending.
+	Acked         int64 `protobuf:"varint,1,opt,name=acked,proto3" json:"acked,omitempty"`
+	unknownFields protoimpl.UnknownFields
+	sizeCache     protoimpl.SizeCache
+}
+
+func (x *XAckResponse) Reset() {
+	*x = XAckResponse{}
+	mi := &file_api_commands_proto_msgTypes[59]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *XAckResponse) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*XAckResponse) ProtoMessage() {}
+
+func (x *XAckResponse) ProtoReflect() protoreflect.Message {
+	mi := &file_api_commands_proto_msgTypes[59]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use XAckResponse.ProtoReflect.Descriptor instead.
+func (*XAckResponse) Descriptor() ([]byte, []int) {
+	return file_api_commands_proto_rawDescGZIP(), []int{59}
+}
+
+func (x *XAckResponse) GetAcked() int64 {
+	if x != nil {
+		return x.Acked
+	}
+	return 0
+}
+
+type XClaimRequest struct {
+	state protoimpl.MessageState `protogen:"open.v1"`
+	Id    string                 `protobuf:"bytes,1,opt,name=id,proto3" json:"id,omitempty"`
+	Group string                 `protobuf:"bytes,2,opt,name=group,proto3" json:"group,omitempty"`
+	// consumer is the consumer the entries are handed over to.
+	Consumer string `protobuf:"bytes,3,opt,name=consumer,proto3" json:"consumer,omitempty"`
+	// min_idle_ms is how long an entry must have been pending since its last
+	// delivery to be claimed.
+	MinIdleMs int64 `protobuf:"varint,4,opt,name=min_idle_ms,json=minIdleMs,proto3" json:"min_idle_ms,omitempty"`
+	// entry_ids are the entries to claim; empty claims the oldest idle ones.
+	EntryIds []string `protobuf:"bytes,5,rep,name=entry_ids,json=entryIds,proto3" json:"entry_ids,omitempty"`
+	// count limits the number of entries claimed; 0 means no limit.
+	Count         int64 `protobuf:"varint,6,opt,name=count,proto3" json:"count,omitempty"`
+	unknownFields protoimpl.UnknownFields
+	sizeCache     protoimpl.SizeCache
+}
+
+func (x *XClaimRequest) Reset() {
+	*x = XClaimRequest{}
+	mi := &file_api_commands_proto_msgTypes[60]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *XClaimRequest) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*XClaimRequest) ProtoMessage() {}
+
+func (x *XClaimRequest) ProtoReflect() protoreflect.Message {
+	mi := &file_api_commands_proto_msgTypes[60]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use XClaimRequest.ProtoReflect.Descriptor instead.
+func (*XClaimRequest) Descriptor() ([]byte, []int) {
+	return file_api_commands_proto_rawDescGZIP(), []int{60}
+}
+
+func (x *XClaimRequest) GetId() string {
+	if x != nil {
+		return x.Id
+	}
+	return ""
+}
+
+func (x *XClaimRequest) GetGroup() string {
+	if x != nil {
+		return x.Group
+	}
+	return ""
+}
+
+func (x *XClaimRequest) GetConsumer() string {
+	if x != nil {
+		return x.Consumer
+	}
+	return ""
+}
+
+func (x *XClaimRequest) GetMinIdleMs() int64 {
+	if x != nil {
+		return x.MinIdleMs
+	}
+	return 0
+}
+
+func (x *XClaimRequest) GetEntryIds() []string {
+	if x != nil {
+		return x.EntryIds
+	}
+	return nil
+}
+
+func (x *XClaimRequest) GetCount() int64 {
+	if x != nil {
+		return x.Count
+	}
+	return 0
+}
+
+type XPendingRequest struct {
+	state protoimpl.MessageState `protogen:"open.v1"`
+	Id    string                 `protobuf:"bytes,1,opt,name=id,proto3" json:"id,omitempty"`
+	Group string                 `protobuf:"bytes,2,opt,name=group,proto3" json:"group,omitempty"`
+	// max_staleness_ms works as in GetRequest.
+	MaxStalenessMs int64 `protobuf:"varint,3,opt,name=max_staleness_ms,json=maxStalenessMs,proto3" json:"max_staleness_ms,omitempty"`
+	unknownFields  protoimpl.UnknownFields
+	sizeCache      protoimpl.SizeCache
+}
+
+func (x *XPendingRequest) Reset() {
+	*x = XPendingRequest{}
+	mi := &file_api_commands_proto_msgTypes[61]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *XPendingRequest) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*XPendingRequest) ProtoMessage() {}
+
+func (x *XPendingRequest) ProtoReflect() protoreflect.Message {
+	mi := &file_api_commands_proto_msgTypes[61]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use XPendingRequest.ProtoReflect.Descriptor instead.
+func (*XPendingRequest) Descriptor() ([]byte, []int) {
+	return file_api_commands_proto_rawDescGZIP(), []int{61}
+}
+
+func (x *XPendingRequest) GetId() string {
+	if x != nil {
+		return x.Id
+	}
+	return ""
+}
+
+func (x *XPendingRequest) GetGroup() string {
+	if x != nil {
+		return x.Group
+	}
+	return ""
+}
+
+func (x *XPendingRequest) GetMaxStalenessMs() int64 {
+	if x != nil {
+		return x.MaxStalenessMs
+	}
+	return 0
+}
+
+type PendingEntry struct {
+	state    protoimpl.MessageState `protogen:"open.v1"`
+	EntryId  string                 `protobuf:"bytes,1,opt,name=entry_id,json=entryId,proto3" json:"entry_id,omitempty"`
+	Consumer string                 `protobuf:"bytes,2,opt,name=consumer,proto3" json:"consumer,omitempty"`
+	// idle_ms is the time since the entry was last delivered.
+	IdleMs int64 `protobuf:"varint,3,opt,name=idle_ms,json=idleMs,proto3" json:"idle_ms,omitempty"`
+	// deliveries is how many times the entry was delivered.
+	Deliveries    int64 `protobuf:"varint,4,opt,name=deliveries,proto3" json:"deliveries,omitempty"`
+	unknownFields protoimpl.UnknownFields
+	sizeCache     protoimpl.SizeCache
+}
+
+func (x *PendingEntry) Reset() {
+	*x = PendingEntry{}
+	mi := &file_api_commands_proto_msgTypes[62]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *PendingEntry) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*PendingEntry) ProtoMessage() {}
+
+func (x *PendingEntry) ProtoReflect() protoreflect.Message {
+	mi := &file_api_commands_proto_msgTypes[62]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use PendingEntry.ProtoReflect.Descriptor instead.
+func (*PendingEntry) Descriptor() ([]byte, []int) {
+	return file_api_commands_proto_rawDescGZIP(), []int{62}
+}
+
+func (x *PendingEntry) GetEntryId() string {
+	if x != nil {
+		return x.EntryId
+	}
+	return ""
+}
+
+func (x *PendingEntry) GetConsumer() string {
+	if x != nil {
+		return x.Consumer
+	}
+	return ""
+}
+
+func (x *PendingEntry) GetIdleMs() int64 {
+	if x != nil {
+		return x.IdleMs
+	}
+	return 0
+}
+
+func (x *PendingEntry) GetDeliveries() int64 {
+	if x != nil {
+		return x.Deliveries
+	}
+	return 0
+}
+
+type XPendingResponse struct {
+	state protoimpl.MessageState `protogen:"open.v1"`
+	// entries are in ascending entry ID order.
+	Entries       []*PendingEntry `protobuf:"bytes,1,rep,name=entries,proto3" json:"entries,omitempty"`
+	unknownFields protoimpl.UnknownFields
+	sizeCache     protoimpl.SizeCache
+}
+
+func (x *XPendingResponse) Reset() {
+	*x = XPendingResponse{}
+	mi := &file_api_commands_proto_msgTypes[63]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *XPendingResponse) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*XPendingResponse) ProtoMessage() {}
+
+func (x *XPendingResponse) ProtoReflect() protoreflect.Message {
+	mi := &file_api_commands_proto_msgTypes[63]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use XPendingResponse.ProtoReflect.Descriptor instead.
+func (*XPendingResponse) Descriptor() ([]byte, []int) {
+	return file_api_commands_proto_rawDescGZIP(), []int{63}
+}
+
+func (x *XPendingResponse) GetEntries() []*PendingEntry {
+	if x != nil {
+		return x.Entries
+	}
+	return nil
+}
+
+type RateLimitRequest struct {
+	state protoimpl.MessageState `protogen:"open.v1"`
+	// id names the limiter. It is a key, which holds the limiter's state
+	// until the limiter is full again.
+	Id        string             `protobuf:"bytes,1,opt,name=id,proto3" json:"id,omitempty"`
+	Algorithm RateLimitAlgorithm `protobuf:"varint,2,opt,name=algorithm,proto3,enum=commands.RateLimitAlgorithm" json:"algorithm,omitempty"`
+	// capacity must be positive.
+	Capacity int64 `protobuf:"varint,3,opt,name=capacity,proto3" json:"capacity,omitempty"`
+	// refill_per_second must be positive for a TOKEN_BUCKET.
+	RefillPerSecond float64 `protobuf:"fixed64,4,opt,name=refill_per_second,json=refillPerSecond,proto3" json:"refill_per_second,omitempty"`
+	// window_ms must be positive for a SLIDING_WINDOW.
+	WindowMs int64 `protobuf:"varint,5,opt,name=window_ms,json=windowMs,proto3" json:"window_ms,omitempty"`
+	// cost is how much the request consumes, 1 when 0. It must not exceed
+	// capacity.
+	Cost          int64 `protobuf:"varint,6,opt,name=cost,proto3" json:"cost,omitempty"`
+	unknownFields protoimpl.UnknownFields
+	sizeCache     protoimpl.SizeCache
+}
+
+func (x *RateLimitRequest) Reset() {
+	*x = RateLimitRequest{}
+	mi := &file_api_commands_proto_msgTypes[64]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *RateLimitRequest) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*RateLimitRequest) ProtoMessage() {}
+
+func (x *RateLimitRequest) ProtoReflect() protoreflect.Message {
+	mi := &file_api_commands_proto_msgTypes[64]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use RateLimitRequest.ProtoReflect.Descriptor instead.
+func (*RateLimitRequest) Descriptor() ([]byte, []int) {
+	return file_api_commands_proto_rawDescGZIP(), []int{64}
+}
+
+func (x *RateLimitRequest) GetId() string {
+	if x != nil {
+		return x.Id
+	}
+	return ""
+}
+
+func (x *RateLimitRequest) GetAlgorithm() RateLimitAlgorithm {
+	if x != nil {
+		return x.Algorithm
+	}
+	return RateLimitAlgorithm_TOKEN_BUCKET
+}
+
+func (x *RateLimitRequest) GetCapacity() int64 {
+	if x != nil {
+		return x.Capacity
+	}
+	return 0
+}
+
+func (x *RateLimitRequest) GetRefillPerSecond() float64 {
+	if x != nil {
+		return x.RefillPerSecond
+	}
+	return 0
+}
+
+func (x *RateLimitRequest) GetWindowMs() int64 {
+	if x != nil {
+		return x.WindowMs
+	}
+	return 0
+}
+
+func (x *RateLimitRequest) GetCost() int64 {
+	if x != nil {
+		return x.Cost
+	}
+	return 0
+}
+
+type RateLimitResponse struct {
+	state   protoimpl.MessageState `protogen:"open.v1"`
+	Allowed bool                   `protobuf:"varint,1,opt,name=allowed,proto3" json:"allowed,omitempty"`
+	// remaining is what is left to consume after this request.
+	Remaining int64 `protobuf:"varint,2,opt,name=remaining,proto3" json:"remaining,omitempty"`
+	// retry_after_ms is, when the request wasn't allowed, how long until it
+	// would be.
+	RetryAfterMs  int64 `protobuf:"varint,3,opt,name=retry_after_ms,json=retryAfterMs,proto3" json:"retry_after_ms,omitempty"`
+	unknownFields protoimpl.UnknownFields
+	sizeCache     protoimpl.SizeCache
+}
+
+func (x *RateLimitResponse) Reset() {
+	*x = RateLimitResponse{}
+	mi := &file_api_commands_proto_msgTypes[65]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *RateLimitResponse) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*RateLimitResponse) ProtoMessage() {}
+
+func (x *RateLimitResponse) ProtoReflect() protoreflect.Message {
+	mi := &file_api_commands_proto_msgTypes[65]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use RateLimitResponse.ProtoReflect.Descriptor instead.
+func (*RateLimitResponse) Descriptor() ([]byte, []int) {
+	return file_api_commands_proto_rawDescGZIP(), []int{65}
+}
+
+func (x *RateLimitResponse) GetAllowed() bool {
+	if x != nil {
+		return x.Allowed
+	}
+	return false
+}
+
+func (x *RateLimitResponse) GetRemaining() int64 {
+	if x != nil {
+		return x.Remaining
+	}
+	return 0
+}
+
+func (x *RateLimitResponse) GetRetryAfterMs() int64 {
+	if x != nil {
+		return x.RetryAfterMs
+	}
+	return 0
+}
+
+type BFReserveRequest struct {
+	state protoimpl.MessageState `protogen:"open.v1"`
+	Id    string                 `protobuf:"bytes,1,opt,name=id,proto3" json:"id,omitempty"`
+	// error_rate is the highest false positive rate the filter should have
+	// while it holds no more than capacity items; between 0 and 1.
+	ErrorRate     float64 `protobuf:"fixed64,2,opt,name=error_rate,json=errorRate,proto3" json:"error_rate,omitempty"`
+	Capacity      int64   `protobuf:"varint,3,opt,name=capacity,proto3" json:"capacity,omitempty"`
+	unknownFields protoimpl.UnknownFields
+	sizeCache     protoimpl.SizeCache
+}
+
+func (x *BFReserveRequest) Reset() {
+	*x = BFReserveRequest{}
+	mi := &file_api_commands_proto_msgTypes[66]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *BFReserveRequest) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*BFReserveRequest) ProtoMessage() {}
+
+func (x *BFReserveRequest) ProtoReflect() protoreflect.Message {
+	mi := &file_api_commands_proto_msgTypes[66]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use BFReserveRequest.ProtoReflect.Descriptor instead.
+func (*BFReserveRequest) Descriptor() ([]byte, []int) {
+	return file_api_commands_proto_rawDescGZIP(), []int{66}
+}
+
+func (x *BFReserveRequest) GetId() string {
+	if x != nil {
+		return x.Id
+	}
+	return ""
+}
+
+func (x *BFReserveRequest) GetErrorRate() float64 {
+	if x != nil {
+		return x.ErrorRate
+	}
+	return 0
+}
+
+func (x *BFReserveRequest) GetCapacity() int64 {
+	if x != nil {
+		return x.Capacity
+	}
+	return 0
+}
+
+type BFReserveResponse struct {
+	state protoimpl.MessageState `protogen:"open.v1"`
+	// created is false when the key already held a Bloom filter, which
+	// keeps its size.
+	Created       bool `protobuf:"varint,1,opt,name=created,proto3" json:"created,omitempty"`
+	unknownFields protoimpl.UnknownFields
+	sizeCache     protoimpl.SizeCache
+}
+
+func (x *BFReserveResponse) Reset() {
+	*x = BFReserveResponse{}
+	mi := &file_api_commands_proto_msgTypes[67]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *BFReserveResponse) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*BFReserveResponse) ProtoMessage() {}
+
+func (x *BFReserveResponse) ProtoReflect() protoreflect.Message {
+	mi := &file_api_commands_proto_msgTypes[67]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use BFReserveResponse.ProtoReflect.Descriptor instead.
+func (*BFReserveResponse) Descriptor() ([]byte, []int) {
+	return file_api_commands_proto_rawDescGZIP(), []int{67}
+}
+
+func (x *BFReserveResponse) GetCreated() bool {
+	if x != nil {
+		return x.Created
+	}
+	return false
+}
+
+type BFAddRequest struct {
+	state protoimpl.MessageState `protogen:"open.v1"`
+	Id    string                 `protobuf:"bytes,1,opt,name=id,proto3" json:"id,omitempty"`
+	Items []string               `protobuf:"bytes,2,rep,name=items,proto3" json:"items,omitempty"`
+	// error_rate and capacity size the filter when BFAdd creates it. They
+	// default to 0.01 and 100.
+	ErrorRate     float64 `protobuf:"fixed64,3,opt,name=error_rate,json=errorRate,proto3" json:"error_rate,omitempty"`
+	Capacity      int64   `protobuf:"varint,4,opt,name=capacity,proto3" json:"capacity,omitempty"`
+	unknownFields protoimpl.UnknownFields
+	sizeCache     protoimpl.SizeCache
+}
+
+func (x *BFAddRequest) Reset() {
+	*x = BFAddRequest{}
+	mi := &file_api_commands_proto_msgTypes[68]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *BFAddRequest) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*BFAddRequest) ProtoMessage() {}
+
+func (x *BFAddRequest) ProtoReflect() protoreflect.Message {
+	mi := &file_api_commands_proto_msgTypes[68]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use BFAddRequest.ProtoReflect.Descriptor instead.
+func (*BFAddRequest) Descriptor() ([]byte, []int) {
+	return file_api_commands_proto_rawDescGZIP(), []int{68}
+}
+
+func (x *BFAddRequest) GetId() string {
+	if x != nil {
+		return x.Id
+	}
+	return ""
+}
+
+func (x *BFAddRequest) GetItems() []string {
+	if x != nil {
+		return x.Items
+	}
+	return nil
+}
+
+func (x *BFAddRequest) GetErrorRate() float64 {
+	if x != nil {
+		return x.ErrorRate
+	}
+	return 0
+}
+
+func (x *BFAddRequest) GetCapacity() int64 {
+	if x != nil {
+		return x.Capacity
+	}
+	return 0
+}
+
+type BFAddResponse struct {
+	state protoimpl.MessageState `protogen:"open.v1"`
+	// added tells, for each item, whether it was new to the filter.
+	Added         []bool `protobuf:"varint,1,rep,packed,name=added,proto3" json:"added,omitempty"`
+	unknownFields protoimpl.UnknownFields
+	sizeCache     protoimpl.SizeCache
+}
+
+func (x *BFAddResponse) Reset() {
+	*x = BFAddResponse{}
+	mi := &file_api_commands_proto_msgTypes[69]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *BFAddResponse) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*BFAddResponse) ProtoMessage() {}
+
+func (x *BFAddResponse) ProtoReflect() protoreflect.Message {
+	mi := &file_api_commands_proto_msgTypes[69]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use BFAddResponse.ProtoReflect.Descriptor instead.
+func (*BFAddResponse) Descriptor() ([]byte, []int) {
+	return file_api_commands_proto_rawDescGZIP(), []int{69}
+}
+
+func (x *BFAddResponse) GetAdded() []bool {
+	if x != nil {
+		return x.Added
+	}
+	return nil
+}
+
+type BFExistsRequest struct {
+	state protoimpl.MessageState `protogen:"open.v1"`
+	Id    string                 `protobuf:"bytes,1,opt,name=id,proto3" json:"id,omitempty"`
+	Items []string               `protobuf:"bytes,2,rep,name=items,proto3" json:"items,omitempty"`
+	// max_staleness_ms works as in GetRequest.
+	MaxStalenessMs int64 `protobuf:"varint,3,opt,name=max_staleness_ms,json=maxStalenessMs,proto3" json:"max_staleness_ms,omitempty"`
+	unknownFields  protoimpl.UnknownFields
+	sizeCache      protoimpl.SizeCache
+}
+
+func (x *BFExistsRequest) Reset() {
+	*x = BFExistsRequest{}
+	mi := &file_api_commands_proto_msgTypes[70]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *BFExistsRequest) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*BFExistsRequest) ProtoMessage() {}
+
+func (x *BFExistsRequest) ProtoReflect() protoreflect.Message {
+	mi := &file_api_commands_proto_msgTypes[70]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use BFExistsRequest.ProtoReflect.Descriptor instead.
+func (*BFExistsRequest) Descriptor() ([]byte, []int) {
+	return file_api_commands_proto_rawDescGZIP(), []int{70}
+}
+
+func (x *BFExistsRequest) GetId() string {
+	if x != nil {
+		return x.Id
+	}
+	return ""
+}
+
+func (x *BFExistsRequest) GetItems() []string {
+	if x != nil {
+		return x.Items
+	}
+	return nil
+}
+
+func (x *BFExistsRequest) GetMaxStalenessMs() int64 {
+	if x != nil {
+		return x.MaxStalenessMs
+	}
+	return 0
+}
+
+type BFExistsResponse struct {
+	state         protoimpl.MessageState `protogen:"open.v1"`
+	Exists        []bool                 `protobuf:"varint,1,rep,packed,name=exists,proto3" json:"exists,omitempty"`
+	unknownFields protoimpl.UnknownFields
+	sizeCache     protoimpl.SizeCache
+}
+
+func (x *BFExistsResponse) Reset() {
+	*x = BFExistsResponse{}
+	mi := &file_api_commands_proto_msgTypes[71]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *BFExistsResponse) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*BFExistsResponse) ProtoMessage() {}
+
+func (x *BFExistsResponse) ProtoReflect() protoreflect.Message {
+	mi := &file_api_commands_proto_msgTypes[71]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use BFExistsResponse.ProtoReflect.Descriptor instead.
+func (*BFExistsResponse) Descriptor() ([]byte, []int) {
+	return file_api_commands_proto_rawDescGZIP(), []int{71}
+}
+
+func (x *BFExistsResponse) GetExists() []bool {
+	if x != nil {
+		return x.Exists
+	}
+	return nil
+}
+
+type PFAddRequest struct {
+	state         protoimpl.MessageState `protogen:"open.v1"`
+	Id            string                 `protobuf:"bytes,1,opt,name=id,proto3" json:"id,omitempty"`
+	Items         []string               `protobuf:"bytes,2,rep,name=items,proto3" json:"items,omitempty"`
+	unknownFields protoimpl.UnknownFields
+	sizeCache     protoimpl.SizeCache
+}
+
+func (x *PFAddRequest) Reset() {
+	*x = PFAddRequest{}
+	mi := &file_api_commands_proto_msgTypes[72]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *PFAddRequest) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*PFAddRequest) ProtoMessage() {}
+
+func (x *PFAddRequest) ProtoReflect() protoreflect.Message {
+	mi := &file_api_commands_proto_msgTypes[72]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use PFAddRequest.ProtoReflect.Descriptor instead.
+func (*PFAddRequest) Descriptor() ([]byte, []int) {
+	return file_api_commands_proto_rawDescGZIP(), []int{72}
+}
+
+func (x *PFAddRequest) GetId() string {
+	if x != nil {
+		return x.Id
+	}
+	return ""
+}
+
+func (x *PFAddRequest) GetItems() []string {
+	if x != nil {
+		return x.Items
+	}
+	return nil
+}
+
+type PFAddResponse struct {
+	state protoimpl.MessageState `protogen:"open.v1"`
+	// changed is true when the HyperLogLog was created or its estimate may
+	// have changed.
+	Changed       bool `protobuf:"varint,1,opt,name=changed,proto3" json:"changed,omitempty"`
+	unknownFields protoimpl.UnknownFields
+	sizeCache     protoimpl.SizeCache
+}
+
+func (x *PFAddResponse) Reset() {
+	*x = PFAddResponse{}
+	mi := &file_api_commands_proto_msgTypes[73]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *PFAddResponse) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*PFAddResponse) ProtoMessage() {}
+
+func (x *PFAddResponse) ProtoReflect() protoreflect.Message {
+	mi := &file_api_commands_proto_msgTypes[73]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use PFAddResponse.ProtoReflect.Descriptor instead.
+func (*PFAddResponse) Descriptor() ([]byte, []int) {
+	return file_api_commands_proto_rawDescGZIP(), []int{73}
+}
+
+func (x *PFAddResponse) GetChanged() bool {
+	if x != nil {
+		return x.Changed
+	}
+	return false
+}
+
+type PFCountRequest struct {
+	state protoimpl.MessageState `protogen:"open.v1"`
+	// ids are the HyperLogLogs to count the union of. In cluster mode they
+	// must all be in the same shard.
+	Ids []string `protobuf:"bytes,1,rep,name=ids,proto3" json:"ids,omitempty"`
+	// max_staleness_ms works as in GetRequest.
+	MaxStalenessMs int64 `protobuf:"varint,2,opt,name=max_staleness_ms,json=maxStalenessMs,proto3" json:"max_staleness_ms,omitempty"`
+	unknownFields  protoimpl.UnknownFields
+	sizeCache      protoimpl.SizeCache
+}
+
+func (x *PFCountRequest) Reset() {
+	*x = PFCountRequest{}
+	mi := &file_api_commands_proto_msgTypes[74]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *PFCountRequest) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*PFCountRequest) ProtoMessage() {}
+
+func (x *PFCountRequest) ProtoReflect() protoreflect.Message {
+	mi := &file_api_commands_proto_msgTypes[74]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use PFCountRequest.ProtoReflect.Descriptor instead.
+func (*PFCountRequest) Descriptor() ([]byte, []int) {
+	return file_api_commands_proto_rawDescGZIP(), []int{74}
+}
+
+func (x *PFCountRequest) GetIds() []string {
+	if x != nil {
+		return x.Ids
+	}
+	return nil
+}
+
+func (x *PFCountRequest) GetMaxStalenessMs() int64 {
+	if x != nil {
+		return x.MaxStalenessMs
+	}
+	return 0
+}
+
+type PFCountResponse struct {
+	state         protoimpl.MessageState `protogen:"open.v1"`
+	Count         int64                  `protobuf:"varint,1,opt,name=count,proto3" json:"count,omitempty"`
+	unknownFields protoimpl.UnknownFields
+	sizeCache     protoimpl.SizeCache
+}
+
+func (x *PFCountResponse) Reset() {
+	*x = PFCountResponse{}
+	mi := &file_api_commands_proto_msgTypes[75]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *PFCountResponse) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*PFCountResponse) ProtoMessage() {}
+
+func (x *PFCountResponse) ProtoReflect() protoreflect.Message {
+	mi := &file_api_commands_proto_msgTypes[75]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use PFCountResponse.ProtoReflect.Descriptor instead.
+func (*PFCountResponse) Descriptor() ([]byte, []int) {
+	return file_api_commands_proto_rawDescGZIP(), []int{75}
+}
+
+func (x *PFCountResponse) GetCount() int64 {
+	if x != nil {
+		return x.Count
+	}
+	return 0
+}
+
+type PFMergeRequest struct {
+	state protoimpl.MessageState `protogen:"open.v1"`
+	// dest receives the union of itself and sources. In cluster mode they
+	// must all be in the same shard.
+	Dest          string   `protobuf:"bytes,1,opt,name=dest,proto3" json:"dest,omitempty"`
+	Sources       []string `protobuf:"bytes,2,rep,name=sources,proto3" json:"sources,omitempty"`
+	unknownFields protoimpl.UnknownFields
+	sizeCache     protoimpl.SizeCache
+}
+
+func (x *PFMergeRequest) Reset() {
+	*x = PFMergeRequest{}
+	mi := &file_api_commands_proto_msgTypes[76]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *PFMergeRequest) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*PFMergeRequest) ProtoMessage() {}
+
+func (x *PFMergeRequest) ProtoReflect() protoreflect.Message {
+	mi := &file_api_commands_proto_msgTypes[76]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use PFMergeRequest.ProtoReflect.Descriptor instead.
+func (*PFMergeRequest) Descriptor() ([]byte, []int) {
+	return file_api_commands_proto_rawDescGZIP(), []int{76}
+}
+
+func (x *PFMergeRequest) GetDest() string {
+	if x != nil {
+		return x.Dest
+	}
+	return ""
+}
+
+func (x *PFMergeRequest) GetSources() []string {
+	if x != nil {
+		return x.Sources
+	}
+	return nil
+}
+
+type PFMergeResponse struct {
+	state         protoimpl.MessageState `protogen:"open.v1"`
+	unknownFields protoimpl.UnknownFields
+	sizeCache     protoimpl.SizeCache
+}
+
+func (x *PFMergeResponse) Reset() {
+	*x = PFMergeResponse{}
+	mi := &file_api_commands_proto_msgTypes[77]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *PFMergeResponse) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*PFMergeResponse) ProtoMessage() {}
+
+func (x *PFMergeResponse) ProtoReflect() protoreflect.Message {
+	mi := &file_api_commands_proto_msgTypes[77]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use PFMergeResponse.ProtoReflect.Descriptor instead.
+func (*PFMergeResponse) Descriptor() ([]byte, []int) {
+	return file_api_commands_proto_rawDescGZIP(), []int{77}
+}
+
+type SetBitRequest struct {
+	state protoimpl.MessageState `protogen:"open.v1"`
+	Id    string                 `protobuf:"bytes,1,opt,name=id,proto3" json:"id,omitempty"`
+	// offset numbers bits from the most significant one of the first byte,
+	// up to 4294967295. The string grows with zero bytes to reach it.
+	Offset        int64 `protobuf:"varint,2,opt,name=offset,proto3" json:"offset,omitempty"`
+	Value         bool  `protobuf:"varint,3,opt,name=value,proto3" json:"value,omitempty"`
+	unknownFields protoimpl.UnknownFields
+	sizeCache     protoimpl.SizeCache
+}
+
+func (x *SetBitRequest) Reset() {
+	*x = SetBitRequest{}
+	mi := &file_api_commands_proto_msgTypes[78]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *SetBitRequest) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*SetBitRequest) ProtoMessage() {}
+
+func (x *SetBitRequest) ProtoReflect() protoreflect.Message {
+	mi := &file_api_commands_proto_msgTypes[78]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use SetBitRequest.ProtoReflect.Descriptor instead.
+func (*SetBitRequest) Descriptor() ([]byte, []int) {
+	return file_api_commands_proto_rawDescGZIP(), []int{78}
+}
+
+func (x *SetBitRequest) GetId() string {
+	if x != nil {
+		return x.Id
+	}
+	return ""
+}
+
+func (x *SetBitRequest) GetOffset() int64 {
+	if x != nil {
+		return x.Offset
+	}
+	return 0
+}
+
+func (x *SetBitRequest) GetValue() bool {
+	if x != nil {
+		return x.Value
+	}
+	return false
+}
+
+type SetBitResponse struct {
+	state protoimpl.MessageState `protogen:"open.v1"`
+	// previous is the bit's value before the request.
+	Previous      bool `protobuf:"varint,1,opt,name=previous,proto3" json:"previous,omitempty"`
+	unknownFields protoimpl.UnknownFields
+	sizeCache     protoimpl.SizeCache
+}
+
+func (x *SetBitResponse) Reset() {
+	*x = SetBitResponse{}
+	mi := &file_api_commands_proto_msgTypes[79]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *SetBitResponse) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*SetBitResponse) ProtoMessage() {}
+
+func (x *SetBitResponse) ProtoReflect() protoreflect.Message {
+	mi := &file_api_commands_proto_msgTypes[79]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use SetBitResponse.ProtoReflect.Descriptor instead.
+func (*SetBitResponse) Descriptor() ([]byte, []int) {
+	return file_api_commands_proto_rawDescGZIP(), []int{79}
+}
+
+func (x *SetBitResponse) GetPrevious() bool {
+	if x != nil {
+		return x.Previous
+	}
+	return false
+}
+
+type GetBitRequest struct {
+	state  protoimpl.MessageState `protogen:"open.v1"`
+	Id     string                 `protobuf:"bytes,1,opt,name=id,proto3" json:"id,omitempty"`
+	Offset int64                  `protobuf:"varint,2,opt,name=offset,proto3" json:"offset,omitempty"`
+	// max_staleness_ms works as in GetRequest.
+	MaxStalenessMs int64 `protobuf:"varint,3,opt,name=max_staleness_ms,json=maxStalenessMs,proto3" json:"max_staleness_ms,omitempty"`
+	unknownFields  protoimpl.UnknownFields
+	sizeCache      protoimpl.SizeCache
+}
+
+func (x *GetBitRequest) Reset() {
+	*x = GetBitRequest{}
+	mi := &file_api_commands_proto_msgTypes[80]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *GetBitRequest) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*GetBitRequest) ProtoMessage() {}
+
+func (x *GetBitRequest) ProtoReflect() protoreflect.Message {
+	mi := &file_api_commands_proto_msgTypes[80]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use GetBitRequest.ProtoReflect.Descriptor instead.
+func (*GetBitRequest) Descriptor() ([]byte, []int) {
+	return file_api_commands_proto_rawDescGZIP(), []int{80}
+}
+
+func (x *GetBitRequest) GetId() string {
+	if x != nil {
+		return x.Id
+	}
+	return ""
+}
+
+func (x *GetBitRequest) GetOffset() int64 {
+	if x != nil {
+		return x.Offset
+	}
+	return 0
+}
+
+func (x *GetBitRequest) GetMaxStalenessMs() int64 {
+	if x != nil {
+		return x.MaxStalenessMs
+	}
+	return 0
+}
+
+type GetBitResponse struct {
+	state         protoimpl.MessageState `protogen:"open.v1"`
+	Value         bool                   `protobuf:"varint,1,opt,name=value,proto3" json:"value,omitempty"`
+	unknownFields protoimpl.UnknownFields
+	sizeCache     protoimpl.SizeCache
+}
+
+func (x *GetBitResponse) Reset() {
+	*x = GetBitResponse{}
+	mi := &file_api_commands_proto_msgTypes[81]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *GetBitResponse) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*GetBitResponse) ProtoMessage() {}
+
+func (x *GetBitResponse) ProtoReflect() protoreflect.Message {
+	mi := &file_api_commands_proto_msgTypes[81]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use GetBitResponse.ProtoReflect.Descriptor instead.
+func (*GetBitResponse) Descriptor() ([]byte, []int) {
+	return file_api_commands_proto_rawDescGZIP(), []int{81}
+}
+
+func (x *GetBitResponse) GetValue() bool {
+	if x != nil {
+		return x.Value
+	}
+	return false
+}
+
+// BitRange is a range of a string from start to end inclusive, in bytes, or
+// in bits when bits is set. Negative positions count from the end: -1 is the
+// last byte, or bit.
+type BitRange struct {
+	state         protoimpl.MessageState `protogen:"open.v1"`
+	Start         int64                  `protobuf:"varint,1,opt,name=start,proto3" json:"start,omitempty"`
+	End           int64                  `protobuf:"varint,2,opt,name=end,proto3" json:"end,omitempty"`
+	Bits          bool                   `protobuf:"varint,3,opt,name=bits,proto3" json:"bits,omitempty"`
+	unknownFields protoimpl.UnknownFields
+	sizeCache     protoimpl.SizeCache
+}
+
+func (x *BitRange) Reset() {
+	*x = BitRange{}
+	mi := &file_api_commands_proto_msgTypes[82]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *BitRange) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*BitRange) ProtoMessage() {}
+
+func (x *BitRange) ProtoReflect() protoreflect.Message {
+	mi := &file_api_commands_proto_msgTypes[82]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use BitRange.ProtoReflect.Descriptor instead.
+func (*BitRange) Descriptor() ([]byte, []int) {
+	return file_api_commands_proto_rawDescGZIP(), []int{82}
+}
+
+func (x *BitRange) GetStart() int64 {
+	if x != nil {
+		return x.Start
+	}
+	return 0
+}
+
+func (x *BitRange) GetEnd() int64 {
+	if x != nil {
+		return x.End
+	}
+	return 0
+}
+
+func (x *BitRange) GetBits() bool {
+	if x != nil {
+		return x.Bits
+	}
+	return false
+}
+
+type BitCountRequest struct {
+	state protoimpl.MessageState `protogen:"open.v1"`
+	Id    string                 `protobuf:"bytes,1,opt,name=id,proto3" json:"id,omitempty"`
+	// range limits the count to part of the string; unset counts it all.
+	Range *BitRange `protobuf:"bytes,2,opt,name=range,proto3" json:"range,omitempty"`
+	// max_staleness_ms works as in GetRequest.
+	MaxStalenessMs int64 `protobuf:"varint,3,opt,name=max_staleness_ms,json=maxStalenessMs,proto3" json:"max_staleness_ms,omitempty"`
+	unknownFields  protoimpl.UnknownFields
+	sizeCache      protoimpl.SizeCache
+}
+
+func (x *BitCountRequest) Reset() {
+	*x = BitCountRequest{}
+	mi := &file_api_commands_proto_msgTypes[83]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *BitCountRequest) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*BitCountRequest) ProtoMessage() {}
+
+func (x *BitCountRequest) ProtoReflect() protoreflect.Message {
+	mi := &file_api_commands_proto_msgTypes[83]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use BitCountRequest.ProtoReflect.Descriptor instead.
+func (*BitCountRequest) Descriptor() ([]byte, []int) {
+	return file_api_commands_proto_rawDescGZIP(), []int{83}
+}
+
+func (x *BitCountRequest) GetId() string {
+	if x != nil {
+		return x.Id
+	}
+	return ""
+}
+
+func (x *BitCountRequest) GetRange() *BitRange {
+	if x != nil {
+		return x.Range
+	}
+	return nil
+}
+
+func (x *BitCountRequest) GetMaxStalenessMs() int64 {
+	if x != nil {
+		return x.MaxStalenessMs
+	}
+	return 0
+}
+
+type BitCountResponse struct {
+	state         protoimpl.MessageState `protogen:"open.v1"`
+	Count         int64                  `protobuf:"varint,1,opt,name=count,proto3" json:"count,omitempty"`
+	unknownFields protoimpl.UnknownFields
+	sizeCache     protoimpl.SizeCache
+}
+
+func (x *BitCountResponse) Reset() {
+	*x = BitCountResponse{}
+	mi := &file_api_commands_proto_msgTypes[84]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *BitCountResponse) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*BitCountResponse) ProtoMessage() {}
+
+func (x *BitCountResponse) ProtoReflect() protoreflect.Message {
+	mi := &file_api_commands_proto_msgTypes[84]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use BitCountResponse.ProtoReflect.Descriptor instead.
+func (*BitCountResponse) Descriptor() ([]byte, []int) {
+	return file_api_commands_proto_rawDescGZIP(), []int{84}
+}
+
+func (x *BitCountResponse) GetCount() int64 {
+	if x != nil {
+		return x.Count
+	}
+	return 0
+}
+
+type BitPosRequest struct {
+	state protoimpl.MessageState `protogen:"open.v1"`
+	Id    string                 `protobuf:"bytes,1,opt,name=id,proto3" json:"id,omitempty"`
+	// bit is the value to look for.
+	Bit bool `protobuf:"varint,2,opt,name=bit,proto3" json:"bit,omitempty"`
+	// range limits the search to part of the string; unset searches it all.
+	Range *BitRange `protobuf:"bytes,3,opt,name=range,proto3" json:"range,omitempty"`
+	// max_staleness_ms works as in GetRequest.
+	MaxStalenessMs int64 `protobuf:"varint,4,opt,name=max_staleness_ms,json=maxStalenessMs,proto3" json:"max_staleness_ms,omitempty"`
+	unknownFields  protoimpl.UnknownFields
+	sizeCache      protoimpl.SizeCache
+}
+
+func (x *BitPosRequest) Reset() {
+	*x = BitPosRequest{}
+	mi := &file_api_commands_proto_msgTypes[85]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *BitPosRequest) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*BitPosRequest) ProtoMessage() {}
+
+func (x *BitPosRequest) ProtoReflect() protoreflect.Message {
+	mi := &file_api_commands_proto_msgTypes[85]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use BitPosRequest.ProtoReflect.Descriptor instead.
+func (*BitPosRequest) Descriptor() ([]byte, []int) {
+	return file_api_commands_proto_rawDescGZIP(), []int{85}
+}
+
+func (x *BitPosRequest) GetId() string {
+	if x != nil {
+		return x.Id
+	}
+	return ""
+}
+
+func (x *BitPosRequest) GetBit() bool {
+	if x != nil {
+		return x.Bit
+	}
+	return false
+}
+
+func (x *BitPosRequest) GetRange() *BitRange {
+	if x != nil {
+		return x.Range
+	}
+	return nil
+}
+
+func (x *BitPosRequest) GetMaxStalenessMs() int64 {
+	if x != nil {
+		return x.MaxStalenessMs
+	}
+	return 0
+}
+
+type BitPosResponse struct {
+	state protoimpl.MessageState `protogen:"open.v1"`
+	// position is the offset of the first bit found, or -1. When looking
+	// for a 0 up to the end of the string, the string counts as padded with
+	// zero bits, so there always is one.
+	Position      int64 `protobuf:"varint,1,opt,name=position,proto3" json:"position,omitempty"`
+	unknownFields protoimpl.UnknownFields
+	sizeCache     protoimpl.SizeCache
+}
+
+func (x *BitPosResponse) Reset() {
+	*x = BitPosResponse{}
+	mi := &file_api_commands_proto_msgTypes[86]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *BitPosResponse) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*BitPosResponse) ProtoMessage() {}
+
+func (x *BitPosResponse) ProtoReflect() protoreflect.Message {
+	mi := &file_api_commands_proto_msgTypes[86]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use BitPosResponse.ProtoReflect.Descriptor instead.
+func (*BitPosResponse) Descriptor() ([]byte, []int) {
+	return file_api_commands_proto_rawDescGZIP(), []int{86}
+}
+
+func (x *BitPosResponse) GetPosition() int64 {
+	if x != nil {
+		return x.Position
+	}
+	return 0
+}
+
+type BitOpRequest struct {
+	state     protoimpl.MessageState `protogen:"open.v1"`
+	Operation BitOperation           `protobuf:"varint,1,opt,name=operation,proto3,enum=commands.BitOperation" json:"operation,omitempty"`
+	// dest receives the result, replacing whatever it held; an empty result
+	// deletes it. Sources shorter than the longest one, and missing ones,
+	// count as padded with zero bytes. In cluster mode dest and sources must
+	// all be in the same shard.
+	Dest          string   `protobuf:"bytes,2,opt,name=dest,proto3" json:"dest,omitempty"`
+	Sources       []string `protobuf:"bytes,3,rep,name=sources,proto3" json:"sources,omitempty"`
+	unknownFields protoimpl.UnknownFields
+	sizeCache     protoimpl.SizeCache
+}
+
+func (x *BitOpRequest) Reset() {
+	*x = BitOpRequest{}
+	mi := &file_api_commands_proto_msgTypes[87]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *BitOpRequest) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*BitOpRequest) ProtoMessage() {}
+
+func (x *BitOpRequest) ProtoReflect() protoreflect.Message {
+	mi := &file_api_commands_proto_msgTypes[87]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use BitOpRequest.ProtoReflect.Descriptor instead.
+func (*BitOpRequest) Descriptor() ([]byte, []int) {
+	return file_api_commands_proto_rawDescGZIP(), []int{87}
+}
+
+func (x *BitOpRequest) GetOperation() BitOperation {
+	if x != nil {
+		return x.Operation
+	}
+	return BitOperation_BIT_AND
+}
+
+func (x *BitOpRequest) GetDest() string {
+	if x != nil {
+		return x.Dest
+	}
+	return ""
+}
+
+func (x *BitOpRequest) GetSources() []string {
+	if x != nil {
+		return x.Sources
+	}
+	return nil
+}
+
+type BitOpResponse struct {
+	state protoimpl.MessageState `protogen:"open.v1"`
+	// length is the length of the string stored at dest.
+	Length        int64 `protobuf:"varint,1,opt,name=length,proto3" json:"length,omitempty"`
+	unknownFields protoimpl.UnknownFields
+	sizeCache     protoimpl.SizeCache
+}
+
+func (x *BitOpResponse) Reset() {
+	*x = BitOpResponse{}
+	mi := &file_api_commands_proto_msgTypes[88]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *BitOpResponse) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*BitOpResponse) ProtoMessage() {}
+
+func (x *BitOpResponse) ProtoReflect() protoreflect.Message {
+	mi := &file_api_commands_proto_msgTypes[88]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use BitOpResponse.ProtoReflect.Descriptor instead.
+func (*BitOpResponse) Descriptor() ([]byte, []int) {
+	return file_api_commands_proto_rawDescGZIP(), []int{88}
+}
+
+func (x *BitOpResponse) GetLength() int64 {
+	if x != nil {
+		return x.Length
+	}
+	return 0
+}
+
+type BitFieldOp struct {
+	state protoimpl.MessageState `protogen:"open.v1"`
+	Kind  BitFieldOp_Kind        `protobuf:"varint,1,opt,name=kind,proto3,enum=commands.BitFieldOp_Kind" json:"kind,omitempty"`
+	// signed and width are the integer's type: i1 to i64, or u1 to u63.
+	Signed bool  `protobuf:"varint,2,opt,name=signed,proto3" json:"signed,omitempty"`
+	Width  int64 `protobuf:"varint,3,opt,name=width,proto3" json:"width,omitempty"`
+	// offset is the bit the integer starts at, or its index among integers
+	// of its width when offset_in_units is set.
+	Offset        int64 `protobuf:"varint,4,opt,name=offset,proto3" json:"offset,omitempty"`
+	OffsetInUnits bool  `protobuf:"varint,5,opt,name=offset_in_units,json=offsetInUnits,proto3" json:"offset_in_units,omitempty"`
+	// value is what SET writes, and what INCRBY adds.
+	Value int64 `protobuf:"varint,6,opt,name=value,proto3" json:"value,omitempty"`
+	// overflow is what SET and INCRBY do with results that don't fit: wrap
+	// around, saturate, or fail leaving the integer as it was.
+	Overflow      BitFieldOp_Overflow `protobuf:"varint,7,opt,name=overflow,proto3,enum=commands.BitFieldOp_Overflow" json:"overflow,omitempty"`
+	unknownFields protoimpl.UnknownFields
+	sizeCache     protoimpl.SizeCache
+}
+
+func (x *BitFieldOp) Reset() {
+	*x = BitFieldOp{}
+	mi := &file_api_commands_proto_msgTypes[89]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *BitFieldOp) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*BitFieldOp) ProtoMessage() {}
+
+func (x *BitFieldOp) ProtoReflect() protoreflect.Message {
+	mi := &file_api_commands_proto_msgTypes[89]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use BitFieldOp.ProtoReflect.Descriptor instead.
+func (*BitFieldOp) Descriptor() ([]byte, []int) {
+	return file_api_commands_proto_rawDescGZIP(), []int{89}
+}
+
+func (x *BitFieldOp) GetKind() BitFieldOp_Kind {
+	if x != nil {
+		return x.Kind
+	}
+	return BitFieldOp_GET
+}
+
+func (x *BitFieldOp) GetSigned() bool {
+	if x != nil {
+		return x.Signed
+	}
+	return false
+}
+
+func (x *BitFieldOp) GetWidth() int64 {
+	if x != nil {
+		return x.Width
+	}
+	return 0
+}
+
+func (x *BitFieldOp) GetOffset() int64 {
+	if x != nil {
+		return x.Offset
+	}
+	return 0
+}
+
+func (x *BitFieldOp) GetOffsetInUnits() bool {
+	if x != nil {
+		return x.OffsetInUnits
+	}
+	return false
+}
+
+func (x *BitFieldOp) GetValue() int64 {
+	if x != nil {
+		return x.Value
+	}
+	return 0
+}
+
+func (x *BitFieldOp) GetOverflow() BitFieldOp_Overflow {
+	if x != nil {
+		return x.Overflow
+	}
+	return BitFieldOp_WRAP
+}
+
+type BitFieldRequest struct {
+	state protoimpl.MessageState `protogen:"open.v1"`
+	Id    string                 `protobuf:"bytes,1,opt,name=id,proto3" json:"id,omitempty"`
+	// ops run in order, as one write.
+	Ops           []*BitFieldOp `protobuf:"bytes,2,rep,name=ops,proto3" json:"ops,omitempty"`
+	unknownFields protoimpl.UnknownFields
+	sizeCache     protoimpl.SizeCache
+}
+
+func (x *BitFieldRequest) Reset() {
+	*x = BitFieldRequest{}
+	mi := &file_api_commands_proto_msgTypes[90]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *BitFieldRequest) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*BitFieldRequest) ProtoMessage() {}
+
+func (x *BitFieldRequest) ProtoReflect() protoreflect.Message {
+	mi := &file_api_commands_proto_msgTypes[90]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use BitFieldRequest.ProtoReflect.Descriptor instead.
+func (*BitFieldRequest) Descriptor() ([]byte, []int) {
+	return file_api_commands_proto_rawDescGZIP(), []int{90}
+}
+
+func (x *BitFieldRequest) GetId() string {
+	if x != nil {
+		return x.Id
+	}
+	return ""
+}
+
+func (x *BitFieldRequest) GetOps() []*BitFieldOp {
+	if x != nil {
+		return x.Ops
+	}
+	return nil
+}
+
+type BitFieldResult struct {
+	state protoimpl.MessageState `protogen:"open.v1"`
+	// value is the integer GET read, the one SET replaced or the one INCRBY
+	// left, unless failed: SET or INCRBY didn't fit with FAIL.
+	Value         int64 `protobuf:"varint,1,opt,name=value,proto3" json:"value,omitempty"`
+	Failed        bool  `protobuf:"varint,2,opt,name=failed,proto3" json:"failed,omitempty"`
+	unknownFields protoimpl.UnknownFields
+	sizeCache     protoimpl.SizeCache
+}
+
+func (x *BitFieldResult) Reset() {
+	*x = BitFieldResult{}
+	mi := &file_api_commands_proto_msgTypes[91]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *BitFieldResult) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*BitFieldResult) ProtoMessage() {}
+
+func (x *BitFieldResult) ProtoReflect() protoreflect.Message {
+	mi := &file_api_commands_proto_msgTypes[91]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use BitFieldResult.ProtoReflect.Descriptor instead.
+func (*BitFieldResult) Descriptor() ([]byte, []int) {
+	return file_api_commands_proto_rawDescGZIP(), []int{91}
+}
+
+func (x *BitFieldResult) GetValue() int64 {
+	if x != nil {
+		return x.Value
+	}
+	return 0
+}
+
+func (x *BitFieldResult) GetFailed() bool {
+	if x != nil {
+		return x.Failed
+	}
+	return false
+}
+
+type BitFieldResponse struct {
+	state         protoimpl.MessageState `protogen:"open.v1"`
+	Results       []*BitFieldResult      `protobuf:"bytes,1,rep,name=results,proto3" json:"results,omitempty"`
+	unknownFields protoimpl.UnknownFields
+	sizeCache     protoimpl.SizeCache
+}
+
+func (x *BitFieldResponse) Reset() {
+	*x = BitFieldResponse{}
+	mi := &file_api_commands_proto_msgTypes[92]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *BitFieldResponse) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*BitFieldResponse) ProtoMessage() {}
+
+func (x *BitFieldResponse) ProtoReflect() protoreflect.Message {
+	mi := &file_api_commands_proto_msgTypes[92]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use BitFieldResponse.ProtoReflect.Descriptor instead.
+func (*BitFieldResponse) Descriptor() ([]byte, []int) {
+	return file_api_commands_proto_rawDescGZIP(), []int{92}
+}
+
+func (x *BitFieldResponse) GetResults() []*BitFieldResult {
+	if x != nil {
+		return x.Results
+	}
+	return nil
+}
+
+var File_api_commands_proto protoreflect.FileDescriptor
+
+const file_api_commands_proto_rawDesc = "" +
+	"\n" +
+	"\x12api/commands.proto\x12\bcommands\x1a\x1bgoogle/protobuf/empty.proto\"'\n" +
+	"\vEchoRequest\x12\x18\n" +
+	"\amessage\x18\x01 \x01(\tR\amessage\"(\n" +
 	"\fEchoResponse\x12\x18\n" +
-	"\amessage\x18\x01 \x01(\tR\amessage\"D\n" +
+	"\amessage\x18\x01 \x01(\tR\amessage\"{\n" +
 	"\n" +
 	"SetRequest\x12\x0e\n" +
 	"\x02id\x18\x01 \x01(\tR\x02id\x12\x14\n" +
 	"\x05value\x18\x02 \x01(\tR\x05value\x12\x10\n" +
-	"\x03ttl\x18\x03 \x01(\x03R\x03ttl\"\x1c\n" +
+	"\x03ttl\x18\x03 \x01(\x03R\x03ttl\x12\x18\n" +
+	"\asession\x18\x04 \x01(\tR\asession\x12\x1b\n" +
+	"\traw_value\x18\x05 \x01(\fR\brawValue\"\x9f\x01\n" +
+	"\vSetResponse\x12\x18\n" +
+	"\aapplied\x18\x01 \x01(\bR\aapplied\x12%\n" +
+	"\x0eprevious_value\x18\x02 \x01(\tR\rpreviousValue\x12!\n" +
+	"\fhad_previous\x18\x03 \x01(\bR\vhadPrevious\x12,\n" +
+	"\x12raw_previous_value\x18\x04 \x01(\fR\x10rawPreviousValue\"F\n" +
 	"\n" +
 	"GetRequest\x12\x0e\n" +
-	"\x02id\x18\x01 \x01(\tR\x02id\"#\n" +
+	"\x02id\x18\x01 \x01(\tR\x02id\x12(\n" +
+	"\x10max_staleness_ms\x18\x02 \x01(\x03R\x0emaxStalenessMs\"@\n" +
 	"\vGetResponse\x12\x14\n" +
-	"\x05value\x18\x01 \x01(\tR\x05value\"\x1f\n" +
+	"\x05value\x18\x01 \x01(\tR\x05value\x12\x1b\n" +
+	"\traw_value\x18\x02 \x01(\fR\brawValue\"\x1f\n" +
 	"\rDeleteRequest\x12\x0e\n" +
-	"\x02id\x18\x01 \x01(\tR\x02id\"3\n" +
+	"\x02id\x18\x01 \x01(\tR\x02id\"M\n" +
 	"\x0eDeleteResponse\x12!\n" +
-	"\fdelete_count\x18\x01 \x01(\x03R\vdeleteCount\"&\n" +
+	"\fdelete_count\x18\x01 \x01(\x03R\vdeleteCount\x12\x18\n" +
+	"\aapplied\x18\x02 \x01(\bR\aapplied\"&\n" +
 	"\x12BatchDeleteRequest\x12\x10\n" +
-	"\x03ids\x18\x01 \x03(\tR\x03ids\"7\n" +
+	"\x03ids\x18\x01 \x03(\tR\x03ids\"Q\n" +
 	"\x13BatchDeleteResponse\x12 \n" +
-	"\vdeleteCount\x18\x01 \x01(\x03R\vdeleteCount\"*\n" +
+	"\vdeleteCount\x18\x01 \x01(\x03R\vdeleteCount\x12\x18\n" +
+	"\aapplied\x18\x02 \x01(\bR\aapplied\"*\n" +
 	"\x16GetExpiredKeysResponse\x12\x10\n" +
-	"\x03ids\x18\x01 \x03(\tR\x03ids2\xff\x02\n" +
+	"\x03ids\x18\x01 \x03(\tR\x03ids\"\x7f\n" +
+	"\vScanRequest\x12\x18\n" +
+	"\apattern\x18\x01 \x01(\tR\apattern\x12\x16\n" +
+	"\x06cursor\x18\x02 \x01(\tR\x06cursor\x12\x14\n" +
+	"\x05count\x18\x03 \x01(\x03R\x05count\x12(\n" +
+	"\x10max_staleness_ms\x18\x04 \x01(\x03R\x0emaxStalenessMs\"A\n" +
+	"\fScanResponse\x12\x10\n" +
+	"\x03ids\x18\x01 \x03(\tR\x03ids\x12\x1f\n" +
+	"\vnext_cursor\x18\x02 \x01(\tR\n" +
+	"nextCursor\"F\n" +
+	"\n" +
+	"TTLRequest\x12\x0e\n" +
+	"\x02id\x18\x01 \x01(\tR\x02id\x12(\n" +
+	"\x10max_staleness_ms\x18\x02 \x01(\x03R\x0emaxStalenessMs\"\x1f\n" +
+	"\vTTLResponse\x12\x10\n" +
+	"\x03ttl\x18\x01 \x01(\x03R\x03ttl\"\x98\x01\n" +
+	"\x10SubscribeRequest\x12\x1d\n" +
+	"\n" +
+	"from_index\x18\x01 \x01(\x04R\tfromIndex\x12\x1d\n" +
+	"\n" +
+	"key_prefix\x18\x02 \x01(\tR\tkeyPrefix\x12\x14\n" +
+	"\x05shard\x18\x03 \x01(\rR\x05shard\x120\n" +
+	"\x14progress_interval_ms\x18\x04 \x01(\x03R\x12progressIntervalMs\"\xa7\x02\n" +
+	"\vChangeEvent\x12\x14\n" +
+	"\x05index\x18\x01 \x01(\x04R\x05index\x12\x12\n" +
+	"\x04term\x18\x02 \x01(\x04R\x04term\x12\"\n" +
+	"\x02op\x18\x03 \x01(\x0e2\x12.commands.ChangeOpR\x02op\x12\x0e\n" +
+	"\x02id\x18\x04 \x01(\tR\x02id\x12\x14\n" +
+	"\x05value\x18\x05 \x01(\tR\x05value\x12\x10\n" +
+	"\x03ids\x18\x06 \x03(\tR\x03ids\x12\"\n" +
+	"\rexpires_at_ms\x18\a \x01(\x03R\vexpiresAtMs\x12!\n" +
+	"\ftimestamp_ms\x18\b \x01(\x03R\vtimestampMs\x12\x18\n" +
+	"\aexpired\x18\t \x01(\bR\aexpired\x12\x14\n" +
+	"\x05entry\x18\n" +
+	" \x01(\fR\x05entry\x12\x1b\n" +
+	"\traw_value\x18\v \x01(\fR\brawValue\"\xa7\x01\n" +
+	"\fWatchRequest\x12E\n" +
+	"\x0ecreate_request\x18\x01 \x01(\v2\x1c.commands.WatchCreateRequestH\x00R\rcreateRequest\x12E\n" +
+	"\x0ecancel_request\x18\x02 \x01(\v2\x1c.commands.WatchCancelRequestH\x00R\rcancelRequestB\t\n" +
+	"\arequest\"\xc8\x01\n" +
+	"\x12WatchCreateRequest\x12\x10\n" +
+	"\x03key\x18\x01 \x01(\tR\x03key\x12\x16\n" +
+	"\x06prefix\x18\x02 \x01(\bR\x06prefix\x12%\n" +
+	"\x0estart_revision\x18\x03 \x01(\x04R\rstartRevision\x12\x19\n" +
+	"\bwatch_id\x18\x04 \x01(\x03R\awatchId\x12\x14\n" +
+	"\x05shard\x18\x05 \x01(\rR\x05shard\x120\n" +
+	"\x14progress_interval_ms\x18\x06 \x01(\x03R\x12progressIntervalMs\"/\n" +
+	"\x12WatchCancelRequest\x12\x19\n" +
+	"\bwatch_id\x18\x01 \x01(\x03R\awatchId\"\xfa\x01\n" +
+	"\rWatchResponse\x12\x19\n" +
+	"\bwatch_id\x18\x01 \x01(\x03R\awatchId\x12\x18\n" +
+	"\acreated\x18\x02 \x01(\bR\acreated\x12\x1a\n" +
+	"\bcanceled\x18\x03 \x01(\bR\bcanceled\x12#\n" +
+	"\rcancel_reason\x18\x04 \x01(\tR\fcancelReason\x12)\n" +
+	"\x10compact_revision\x18\x05 \x01(\x04R\x0fcompactRevision\x12\x1a\n" +
+	"\brevision\x18\x06 \x01(\x04R\brevision\x12,\n" +
+	"\x06events\x18\a \x03(\v2\x14.commands.WatchEventR\x06events\"\xd5\x01\n" +
+	"\n" +
+	"WatchEvent\x12,\n" +
+	"\x04type\x18\x01 \x01(\x0e2\x18.commands.WatchEventTypeR\x04type\x12\x10\n" +
+	"\x03key\x18\x02 \x01(\tR\x03key\x12\x14\n" +
+	"\x05value\x18\x03 \x01(\tR\x05value\x12\x1a\n" +
+	"\brevision\x18\x04 \x01(\x04R\brevision\x12\"\n" +
+	"\rexpires_at_ms\x18\x05 \x01(\x03R\vexpiresAtMs\x12\x14\n" +
+	"\x05entry\x18\x06 \x01(\fR\x05entry\x12\x1b\n" +
+	"\traw_value\x18\a \x01(\fR\brawValue\"5\n" +
+	"\vPushRequest\x12\x0e\n" +
+	"\x02id\x18\x01 \x01(\tR\x02id\x12\x16\n" +
+	"\x06values\x18\x02 \x03(\tR\x06values\"&\n" +
+	"\fPushResponse\x12\x16\n" +
+	"\x06length\x18\x01 \x01(\x03R\x06length\"\x1c\n" +
+	"\n" +
+	"PopRequest\x12\x0e\n" +
+	"\x02id\x18\x01 \x01(\tR\x02id\"I\n" +
+	"\vPopResponse\x12\x14\n" +
+	"\x05found\x18\x01 \x01(\bR\x05found\x12\x0e\n" +
+	"\x02id\x18\x02 \x01(\tR\x02id\x12\x14\n" +
+	"\x05value\x18\x03 \x01(\tR\x05value\"E\n" +
+	"\x12BlockingPopRequest\x12\x10\n" +
+	"\x03ids\x18\x01 \x03(\tR\x03ids\x12\x1d\n" +
+	"\n" +
+	"timeout_ms\x18\x02 \x01(\x03R\ttimeoutMs\"G\n" +
+	"\vLLenRequest\x12\x0e\n" +
+	"\x02id\x18\x01 \x01(\tR\x02id\x12(\n" +
+	"\x10max_staleness_ms\x18\x02 \x01(\x03R\x0emaxStalenessMs\"&\n" +
+	"\fLLenResponse\x12\x16\n" +
+	"\x06length\x18\x01 \x01(\x03R\x06length\"\x8a\x01\n" +
+	"\x0fQueuePopRequest\x12\x0e\n" +
+	"\x02id\x18\x01 \x01(\tR\x02id\x122\n" +
+	"\x15visibility_timeout_ms\x18\x02 \x01(\x03R\x13visibilityTimeoutMs\x12\x14\n" +
+	"\x05block\x18\x03 \x01(\bR\x05block\x12\x1d\n" +
+	"\n" +
+	"timeout_ms\x18\x04 \x01(\x03R\ttimeoutMs\"v\n" +
+	"\x10QueuePopResponse\x12\x14\n" +
+	"\x05found\x18\x01 \x01(\bR\x05found\x12\x15\n" +
+	"\x06job_id\x18\x02 \x01(\tR\x05jobId\x12\x14\n" +
+	"\x05value\x18\x03 \x01(\tR\x05value\x12\x1f\n" +
+	"\vdeadline_ms\x18\x04 \x01(\x03R\n" +
+	"deadlineMs\"8\n" +
+	"\x0fQueueJobRequest\x12\x0e\n" +
+	"\x02id\x18\x01 \x01(\tR\x02id\x12\x15\n" +
+	"\x06job_id\x18\x02 \x01(\tR\x05jobId\",\n" +
+	"\x10QueueJobResponse\x12\x18\n" +
+	"\aapplied\x18\x01 \x01(\bR\aapplied\"\x85\x01\n" +
+	"\vLockRequest\x12\x0e\n" +
+	"\x02id\x18\x01 \x01(\tR\x02id\x12\x18\n" +
+	"\asession\x18\x02 \x01(\tR\asession\x12\x19\n" +
+	"\blease_ms\x18\x03 \x01(\x03R\aleaseMs\x12\x12\n" +
+	"\x04wait\x18\x04 \x01(\bR\x04wait\x12\x1d\n" +
+	"\n" +
+	"timeout_ms\x18\x05 \x01(\x03R\ttimeoutMs\"\xa5\x01\n" +
+	"\fLockResponse\x12\x1a\n" +
+	"\bacquired\x18\x01 \x01(\bR\bacquired\x12#\n" +
+	"\rfencing_token\x18\x02 \x01(\x04R\ffencingToken\x12-\n" +
+	"\x13lease_expires_at_ms\x18\x03 \x01(\x03R\x10leaseExpiresAtMs\x12%\n" +
+	"\x0eholder_session\x18\x04 \x01(\tR\rholderSession\"^\n" +
+	"\rUnlockRequest\x12\x0e\n" +
+	"\x02id\x18\x01 \x01(\tR\x02id\x12\x18\n" +
+	"\asession\x18\x02 \x01(\tR\asession\x12#\n" +
+	"\rfencing_token\x18\x03 \x01(\x04R\ffencingToken\",\n" +
+	"\x0eUnlockResponse\x12\x1a\n" +
+	"\breleased\x18\x01 \x01(\bR\breleased\"~\n" +
+	"\x12RefreshLockRequest\x12\x0e\n" +
+	"\x02id\x18\x01 \x01(\tR\x02id\x12\x18\n" +
+	"\asession\x18\x02 \x01(\tR\asession\x12#\n" +
+	"\rfencing_token\x18\x03 \x01(\x04R\ffencingToken\x12\x19\n" +
+	"\blease_ms\x18\x04 \x01(\x03R\aleaseMs\"b\n" +
+	"\x13RefreshLockResponse\x12\x1c\n" +
+	"\trefreshed\x18\x01 \x01(\bR\trefreshed\x12-\n" +
+	"\x13lease_expires_at_ms\x18\x02 \x01(\x03R\x10leaseExpiresAtMs\"=\n" +
+	"\x14CreateSessionRequest\x12\x0e\n" +
+	"\x02id\x18\x01 \x01(\tR\x02id\x12\x15\n" +
+	"\x06ttl_ms\x18\x02 \x01(\x03R\x05ttlMs\"K\n" +
+	"\x15CreateSessionResponse\x12\x0e\n" +
+	"\x02id\x18\x01 \x01(\tR\x02id\x12\"\n" +
+	"\rexpires_at_ms\x18\x02 \x01(\x03R\vexpiresAtMs\"\"\n" +
+	"\x10KeepAliveRequest\x12\x0e\n" +
+	"\x02id\x18\x01 \x01(\tR\x02id\"7\n" +
+	"\x11KeepAliveResponse\x12\"\n" +
+	"\rexpires_at_ms\x18\x01 \x01(\x03R\vexpiresAtMs\"%\n" +
+	"\x13CloseSessionRequest\x12\x0e\n" +
+	"\x02id\x18\x01 \x01(\tR\x02id\"Q\n" +
+	"\x14CloseSessionResponse\x12\x16\n" +
+	"\x06closed\x18\x01 \x01(\bR\x06closed\x12!\n" +
+	"\fdelete_count\x18\x02 \x01(\x03R\vdeleteCount\"<\n" +
+	"\n" +
+	"StreamTrim\x12\x17\n" +
+	"\amax_len\x18\x01 \x01(\x03R\x06maxLen\x12\x15\n" +
+	"\x06min_id\x18\x02 \x01(\tR\x05minId\"\x9e\x01\n" +
+	"\vStreamEntry\x12\x19\n" +
+	"\bentry_id\x18\x01 \x01(\tR\aentryId\x129\n" +
+	"\x06fields\x18\x02 \x03(\v2!.commands.StreamEntry.FieldsEntryR\x06fields\x1a9\n" +
+	"\vFieldsEntry\x12\x10\n" +
+	"\x03key\x18\x01 \x01(\tR\x03key\x12\x14\n" +
+	"\x05value\x18\x02 \x01(\tR\x05value:\x028\x01\"\xd8\x01\n" +
+	"\vXAddRequest\x12\x0e\n" +
+	"\x02id\x18\x01 \x01(\tR\x02id\x12\x19\n" +
+	"\bentry_id\x18\x02 \x01(\tR\aentryId\x129\n" +
+	"\x06fields\x18\x03 \x03(\v2!.commands.XAddRequest.FieldsEntryR\x06fields\x12(\n" +
+	"\x04trim\x18\x04 \x01(\v2\x14.commands.StreamTrimR\x04trim\x1a9\n" +
+	"\vFieldsEntry\x12\x10\n" +
+	"\x03key\x18\x01 \x01(\tR\x03key\x12\x14\n" +
+	"\x05value\x18\x02 \x01(\tR\x05value:\x028\x01\")\n" +
+	"\fXAddResponse\x12\x19\n" +
+	"\bentry_id\x18\x01 \x01(\tR\aentryId\"\xa1\x01\n" +
+	"\rXRangeRequest\x12\x0e\n" +
+	"\x02id\x18\x01 \x01(\tR\x02id\x12\x14\n" +
+	"\x05start\x18\x02 \x01(\tR\x05start\x12\x10\n" +
+	"\x03end\x18\x03 \x01(\tR\x03end\x12\x14\n" +
+	"\x05count\x18\x04 \x01(\x03R\x05count\x12\x18\n" +
+	"\areverse\x18\x05 \x01(\bR\areverse\x12(\n" +
+	"\x10max_staleness_ms\x18\x06 \x01(\x03R\x0emaxStalenessMs\"H\n" +
+	"\x15StreamEntriesResponse\x12/\n" +
+	"\aentries\x18\x01 \x03(\v2\x15.commands.StreamEntryR\aentries\"G\n" +
+	"\vXLenRequest\x12\x0e\n" +
+	"\x02id\x18\x01 \x01(\tR\x02id\x12(\n" +
+	"\x10max_staleness_ms\x18\x02 \x01(\x03R\x0emaxStalenessMs\"&\n" +
+	"\fXLenResponse\x12\x16\n" +
+	"\x06length\x18\x01 \x01(\x03R\x06length\"H\n" +
+	"\fXTrimRequest\x12\x0e\n" +
+	"\x02id\x18\x01 \x01(\tR\x02id\x12(\n" +
+	"\x04trim\x18\x02 \x01(\v2\x14.commands.StreamTrimR\x04trim\")\n" +
+	"\rXTrimResponse\x12\x18\n" +
+	"\atrimmed\x18\x01 \x01(\x03R\atrimmed\"Q\n" +
+	"\x13XGroupCreateRequest\x12\x0e\n" +
+	"\x02id\x18\x01 \x01(\tR\x02id\x12\x14\n" +
+	"\x05group\x18\x02 \x01(\tR\x05group\x12\x14\n" +
+	"\x05start\x18\x03 \x01(\tR\x05start\"0\n" +
+	"\x14XGroupCreateResponse\x12\x18\n" +
+	"\acreated\x18\x01 \x01(\bR\acreated\"\x81\x01\n" +
+	"\x11XReadGroupRequest\x12\x0e\n" +
+	"\x02id\x18\x01 \x01(\tR\x02id\x12\x14\n" +
+	"\x05group\x18\x02 \x01(\tR\x05group\x12\x1a\n" +
+	"\bconsumer\x18\x03 \x01(\tR\bconsumer\x12\x14\n" +
+	"\x05after\x18\x04 \x01(\tR\x05after\x12\x14\n" +
+	"\x05count\x18\x05 \x01(\x03R\x05count\"P\n" +
+	"\vXAckRequest\x12\x0e\n" +
+	"\x02id\x18\x01 \x01(\tR\x02id\x12\x14\n" +
+	"\x05group\x18\x02 \x01(\tR\x05group\x12\x1b\n" +
+	"\tentry_ids\x18\x03 \x03(\tR\bentryIds\"$\n" +
+	"\fXAckResponse\x12\x14\n" +
+	"\x05acked\x18\x01 \x01(\x03R\x05acked\"\xa4\x01\n" +
+	"\rXClaimRequest\x12\x0e\n" +
+	"\x02id\x18\x01 \x01(\tR\x02id\x12\x14\n" +
+	"\x05group\x18\x02 \x01(\tR\x05group\x12\x1a\n" +
+	"\bconsumer\x18\x03 \x01(\tR\bconsumer\x12\x1e\n" +
+	"\vmin_idle_ms\x18\x04 \x01(\x03R\tminIdleMs\x12\x1b\n" +
+	"\tentry_ids\x18\x05 \x03(\tR\bentryIds\x12\x14\n" +
+	"\x05count\x18\x06 \x01(\x03R\x05count\"a\n" +
+	"\x0fXPendingRequest\x12\x0e\n" +
+	"\x02id\x18\x01 \x01(\tR\x02id\x12\x14\n" +
+	"\x05group\x18\x02 \x01(\tR\x05group\x12(\n" +
+	"\x10max_staleness_ms\x18\x03 \x01(\x03R\x0emaxStalenessMs\"~\n" +
+	"\fPendingEntry\x12\x19\n" +
+	"\bentry_id\x18\x01 \x01(\tR\aentryId\x12\x1a\n" +
+	"\bconsumer\x18\x02 \x01(\tR\bconsumer\x12\x17\n" +
+	"\aidle_ms\x18\x03 \x01(\x03R\x06idleMs\x12\x1e\n" +
+	"\n" +
+	"deliveries\x18\x04 \x01(\x03R\n" +
+	"deliveries\"D\n" +
+	"\x10XPendingResponse\x120\n" +
+	"\aentries\x18\x01 \x03(\v2\x16.commands.PendingEntryR\aentries\"\xd7\x01\n" +
+	"\x10RateLimitRequest\x12\x0e\n" +
+	"\x02id\x18\x01 \x01(\tR\x02id\x12:\n" +
+	"\talgorithm\x18\x02 \x01(\x0e2\x1c.commands.RateLimitAlgorithmR\talgorithm\x12\x1a\n" +
+	"\bcapacity\x18\x03 \x01(\x03R\bcapacity\x12*\n" +
+	"\x11refill_per_second\x18\x04 \x01(\x01R\x0frefillPerSecond\x12\x1b\n" +
+	"\twindow_ms\x18\x05 \x01(\x03R\bwindowMs\x12\x12\n" +
+	"\x04cost\x18\x06 \x01(\x03R\x04cost\"q\n" +
+	"\x11RateLimitResponse\x12\x18\n" +
+	"\aallowed\x18\x01 \x01(\bR\aallowed\x12\x1c\n" +
+	"\tremaining\x18\x02 \x01(\x03R\tremaining\x12$\n" +
+	"\x0eretry_after_ms\x18\x03 \x01(\x03R\fretryAfterMs\"]\n" +
+	"\x10BFReserveRequest\x12\x0e\n" +
+	"\x02id\x18\x01 \x01(\tR\x02id\x12\x1d\n" +
+	"\n" +
+	"error_rate\x18\x02 \x01(\x01R\terrorRate\x12\x1a\n" +
+	"\bcapacity\x18\x03 \x01(\x03R\bcapacity\"-\n" +
+	"\x11BFReserveResponse\x12\x18\n" +
+	"\acreated\x18\x01 \x01(\bR\acreated\"o\n" +
+	"\fBFAddRequest\x12\x0e\n" +
+	"\x02id\x18\x01 \x01(\tR\x02id\x12\x14\n" +
+	"\x05items\x18\x02 \x03(\tR\x05items\x12\x1d\n" +
+	"\n" +
+	"error_rate\x18\x03 \x01(\x01R\terrorRate\x12\x1a\n" +
+	"\bcapacity\x18\x04 \x01(\x03R\bcapacity\"%\n" +
+	"\rBFAddResponse\x12\x14\n" +
+	"\x05added\x18\x01 \x03(\bR\x05added\"a\n" +
+	"\x0fBFExistsRequest\x12\x0e\n" +
+	"\x02id\x18\x01 \x01(\tR\x02id\x12\x14\n" +
+	"\x05items\x18\x02 \x03(\tR\x05items\x12(\n" +
+	"\x10max_staleness_ms\x18\x03 \x01(\x03R\x0emaxStalenessMs\"*\n" +
+	"\x10BFExistsResponse\x12\x16\n" +
+	"\x06exists\x18\x01 \x03(\bR\x06exists\"4\n" +
+	"\fPFAddRequest\x12\x0e\n" +
+	"\x02id\x18\x01 \x01(\tR\x02id\x12\x14\n" +
+	"\x05items\x18\x02 \x03(\tR\x05items\")\n" +
+	"\rPFAddResponse\x12\x18\n" +
+	"\achanged\x18\x01 \x01(\bR\achanged\"L\n" +
+	"\x0ePFCountRequest\x12\x10\n" +
+	"\x03ids\x18\x01 \x03(\tR\x03ids\x12(\n" +
+	"\x10max_staleness_ms\x18\x02 \x01(\x03R\x0emaxStalenessMs\"'\n" +
+	"\x0fPFCountResponse\x12\x14\n" +
+	"\x05count\x18\x01 \x01(\x03R\x05count\">\n" +
+	"\x0ePFMergeRequest\x12\x12\n" +
+	"\x04dest\x18\x01 \x01(\tR\x04dest\x12\x18\n" +
+	"\asources\x18\x02 \x03(\tR\asources\"\x11\n" +
+	"\x0fPFMergeResponse\"M\n" +
+	"\rSetBitRequest\x12\x0e\n" +
+	"\x02id\x18\x01 \x01(\tR\x02id\x12\x16\n" +
+	"\x06offset\x18\x02 \x01(\x03R\x06offset\x12\x14\n" +
+	"\x05value\x18\x03 \x01(\bR\x05value\",\n" +
+	"\x0eSetBitResponse\x12\x1a\n" +
+	"\bprevious\x18\x01 \x01(\bR\bprevious\"a\n" +
+	"\rGetBitRequest\x12\x0e\n" +
+	"\x02id\x18\x01 \x01(\tR\x02id\x12\x16\n" +
+	"\x06offset\x18\x02 \x01(\x03R\x06offset\x12(\n" +
+	"\x10max_staleness_ms\x18\x03 \x01(\x03R\x0emaxStalenessMs\"&\n" +
+	"\x0eGetBitResponse\x12\x14\n" +
+	"\x05value\x18\x01 \x01(\bR\x05value\"F\n" +
+	"\bBitRange\x12\x14\n" +
+	"\x05start\x18\x01 \x01(\x03R\x05start\x12\x10\n" +
+	"\x03end\x18\x02 \x01(\x03R\x03end\x12\x12\n" +
+	"\x04bits\x18\x03 \x01(\bR\x04bits\"u\n" +
+	"\x0fBitCountRequest\x12\x0e\n" +
+	"\x02id\x18\x01 \x01(\tR\x02id\x12(\n" +
+	"\x05range\x18\x02 \x01(\v2\x12.commands.BitRangeR\x05range\x12(\n" +
+	"\x10max_staleness_ms\x18\x03 \x01(\x03R\x0emaxStalenessMs\"(\n" +
+	"\x10BitCountResponse\x12\x14\n" +
+	"\x05count\x18\x01 \x01(\x03R\x05count\"\x85\x01\n" +
+	"\rBitPosRequest\x12\x0e\n" +
+	"\x02id\x18\x01 \x01(\tR\x02id\x12\x10\n" +
+	"\x03bit\x18\x02 \x01(\bR\x03bit\x12(\n" +
+	"\x05range\x18\x03 \x01(\v2\x12.commands.BitRangeR\x05range\x12(\n" +
+	"\x10max_staleness_ms\x18\x04 \x01(\x03R\x0emaxStalenessMs\",\n" +
+	"\x0eBitPosResponse\x12\x1a\n" +
+	"\bposition\x18\x01 \x01(\x03R\bposition\"r\n" +
+	"\fBitOpRequest\x124\n" +
+	"\toperation\x18\x01 \x01(\x0e2\x16.commands.BitOperationR\toperation\x12\x12\n" +
+	"\x04dest\x18\x02 \x01(\tR\x04dest\x12\x18\n" +
+	"\asources\x18\x03 \x03(\tR\asources\"'\n" +
+	"\rBitOpResponse\x12\x16\n" +
+	"\x06length\x18\x01 \x01(\x03R\x06length\"\xc9\x02\n" +
+	"\n" +
+	"BitFieldOp\x12-\n" +
+	"\x04kind\x18\x01 \x01(\x0e2\x19.commands.BitFieldOp.KindR\x04kind\x12\x16\n" +
+	"\x06signed\x18\x02 \x01(\bR\x06signed\x12\x14\n" +
+	"\x05width\x18\x03 \x01(\x03R\x05width\x12\x16\n" +
+	"\x06offset\x18\x04 \x01(\x03R\x06offset\x12&\n" +
+	"\x0foffset_in_units\x18\x05 \x01(\bR\roffsetInUnits\x12\x14\n" +
+	"\x05value\x18\x06 \x01(\x03R\x05value\x129\n" +
+	"\boverflow\x18\a \x01(\x0e2\x1d.commands.BitFieldOp.OverflowR\boverflow\"$\n" +
+	"\x04Kind\x12\a\n" +
+	"\x03GET\x10\x00\x12\a\n" +
+	"\x03SET\x10\x01\x12\n" +
+	"\n" +
+	"\x06INCRBY\x10\x02\"'\n" +
+	"\bOverflow\x12\b\n" +
+	"\x04WRAP\x10\x00\x12\a\n" +
+	"\x03SAT\x10\x01\x12\b\n" +
+	"\x04FAIL\x10\x02\"I\n" +
+	"\x0fBitFieldRequest\x12\x0e\n" +
+	"\x02id\x18\x01 \x01(\tR\x02id\x12&\n" +
+	"\x03ops\x18\x02 \x03(\v2\x14.commands.BitFieldOpR\x03ops\">\n" +
+	"\x0eBitFieldResult\x12\x14\n" +
+	"\x05value\x18\x01 \x01(\x03R\x05value\x12\x16\n" +
+	"\x06failed\x18\x02 \x01(\bR\x06failed\"F\n" +
+	"\x10BitFieldResponse\x122\n" +
+	"\aresults\x18\x01 \x03(\v2\x18.commands.BitFieldResultR\aresults*\xad\x01\n" +
+	"\bChangeOp\x12\x19\n" +
+	"\x15CHANGE_OP_UNSPECIFIED\x10\x00\x12\x11\n" +
+	"\rCHANGE_OP_SET\x10\x01\x12\x14\n" +
+	"\x10CHANGE_OP_DELETE\x10\x02\x12\x1a\n" +
+	"\x16CHANGE_OP_BATCH_DELETE\x10\x03\x12\x16\n" +
+	"\x12CHANGE_OP_SNAPSHOT\x10\x04\x12\x16\n" +
+	"\x12CHANGE_OP_PROGRESS\x10\x05\x12\x11\n" +
+	"\rCHANGE_OP_PUT\x10\x06*Z\n" +
+	"\x0eWatchEventType\x12\x1b\n" +
+	"\x17WATCH_EVENT_UNSPECIFIED\x10\x00\x12\x13\n" +
+	"\x0fWATCH_EVENT_PUT\x10\x01\x12\x16\n" +
+	"\x12WATCH_EVENT_DELETE\x10\x02*:\n" +
+	"\x12RateLimitAlgorithm\x12\x10\n" +
+	"\fTOKEN_BUCKET\x10\x00\x12\x12\n" +
+	"\x0eSLIDING_WINDOW\x10\x01*A\n" +
+	"\fBitOperation\x12\v\n" +
+	"\aBIT_AND\x10\x00\x12\n" +
+	"\n" +
+	"\x06BIT_OR\x10\x01\x12\v\n" +
+	"\aBIT_XOR\x10\x02\x12\v\n" +
+	"\aBIT_NOT\x10\x032\xe6\x17\n" +
 	"\bCommands\x125\n" +
-	"\x04Echo\x12\x15.commands.EchoRequest\x1a\x16.commands.EchoResponse\x123\n" +
-	"\x03Set\x12\x14.commands.SetRequest\x1a\x16.google.protobuf.Empty\x122\n" +
+	"\x04Echo\x12\x15.commands.EchoRequest\x1a\x16.commands.EchoResponse\x122\n" +
+	"\x03Set\x12\x14.commands.SetRequest\x1a\x15.commands.SetResponse\x122\n" +
 	"\x03Get\x12\x14.commands.GetRequest\x1a\x15.commands.GetResponse\x12;\n" +
 	"\x06Delete\x12\x17.commands.DeleteRequest\x1a\x18.commands.DeleteResponse\x12J\n" +
 	"\vBatchDelete\x12\x1c.commands.BatchDeleteRequest\x1a\x1d.commands.BatchDeleteResponse\x12J\n" +
-	"\x0eGetExpiredKeys\x12\x16.google.protobuf.Empty\x1a .commands.GetExpiredKeysResponseB\x15Z\x13memorabilia/api;apib\x06proto3"
+	"\x0eGetExpiredKeys\x12\x16.google.protobuf.Empty\x1a .commands.GetExpiredKeysResponse\x125\n" +
+	"\x04Scan\x12\x15.commands.ScanRequest\x1a\x16.commands.ScanResponse\x122\n" +
+	"\x03TTL\x12\x14.commands.TTLRequest\x1a\x15.commands.TTLResponse\x12@\n" +
+	"\tSubscribe\x12\x1a.commands.SubscribeRequest\x1a\x15.commands.ChangeEvent0\x01\x12<\n" +
+	"\x05Watch\x12\x16.commands.WatchRequest\x1a\x17.commands.WatchResponse(\x010\x01\x126\n" +
+	"\x05LPush\x12\x15.commands.PushRequest\x1a\x16.commands.PushResponse\x126\n" +
+	"\x05RPush\x12\x15.commands.PushRequest\x1a\x16.commands.PushResponse\x123\n" +
+	"\x04LPop\x12\x14.commands.PopRequest\x1a\x15.commands.PopResponse\x123\n" +
+	"\x04RPop\x12\x14.commands.PopRequest\x1a\x15.commands.PopResponse\x12<\n" +
+	"\x05BLPop\x12\x1c.commands.BlockingPopRequest\x1a\x15.commands.PopResponse\x12<\n" +
+	"\x05BRPop\x12\x1c.commands.BlockingPopRequest\x1a\x15.commands.PopResponse\x125\n" +
+	"\x04LLen\x12\x15.commands.LLenRequest\x1a\x16.commands.LLenResponse\x12A\n" +
+	"\bQueuePop\x12\x19.commands.QueuePopRequest\x1a\x1a.commands.QueuePopResponse\x12A\n" +
+	"\bQueueAck\x12\x19.commands.QueueJobRequest\x1a\x1a.commands.QueueJobResponse\x12B\n" +
+	"\tQueueNack\x12\x19.commands.QueueJobRequest\x1a\x1a.commands.QueueJobResponse\x125\n" +
+	"\x04Lock\x12\x15.commands.LockRequest\x1a\x16.commands.LockResponse\x12;\n" +
+	"\x06Unlock\x12\x17.commands.UnlockRequest\x1a\x18.commands.UnlockResponse\x12J\n" +
+	"\vRefreshLock\x12\x1c.commands.RefreshLockRequest\x1a\x1d.commands.RefreshLockResponse\x12P\n" +
+	"\rCreateSession\x12\x1e.commands.CreateSessionRequest\x1a\x1f.commands.CreateSessionResponse\x12D\n" +
+	"\tKeepAlive\x12\x1a.commands.KeepAliveRequest\x1a\x1b.commands.KeepAliveResponse\x12M\n" +
+	"\fCloseSession\x12\x1d.commands.CloseSessionRequest\x1a\x1e.commands.CloseSessionResponse\x125\n" +
+	"\x04XAdd\x12\x15.commands.XAddRequest\x1a\x16.commands.XAddResponse\x12B\n" +
+	"\x06XRange\x12\x17.commands.XRangeRequest\x1a\x1f.commands.StreamEntriesResponse\x125\n" +
+	"\x04XLen\x12\x15.commands.XLenRequest\x1a\x16.commands.XLenResponse\x128\n" +
+	"\x05XTrim\x12\x16.commands.XTrimRequest\x1a\x17.commands.XTrimResponse\x12M\n" +
+	"\fXGroupCreate\x12\x1d.commands.XGroupCreateRequest\x1a\x1e.commands.XGroupCreateResponse\x12J\n" +
+	"\n" +
+	"XReadGroup\x12\x1b.commands.XReadGroupRequest\x1a\x1f.commands.StreamEntriesResponse\x125\n" +
+	"\x04XAck\x12\x15.commands.XAckRequest\x1a\x16.commands.XAckResponse\x12B\n" +
+	"\x06XClaim\x12\x17.commands.XClaimRequest\x1a\x1f.commands.StreamEntriesResponse\x12A\n" +
+	"\bXPending\x12\x19.commands.XPendingRequest\x1a\x1a.commands.XPendingResponse\x12D\n" +
+	"\tRateLimit\x12\x1a.commands.RateLimitRequest\x1a\x1b.commands.RateLimitResponse\x12D\n" +
+	"\tBFReserve\x12\x1a.commands.BFReserveRequest\x1a\x1b.commands.BFReserveResponse\x128\n" +
+	"\x05BFAdd\x12\x16.commands.BFAddRequest\x1a\x17.commands.BFAddResponse\x12A\n" +
+	"\bBFExists\x12\x19.commands.BFExistsRequest\x1a\x1a.commands.BFExistsResponse\x128\n" +
+	"\x05PFAdd\x12\x16.commands.PFAddRequest\x1a\x17.commands.PFAddResponse\x12>\n" +
+	"\aPFCount\x12\x18.commands.PFCountRequest\x1a\x19.commands.PFCountResponse\x12>\n" +
+	"\aPFMerge\x12\x18.commands.PFMergeRequest\x1a\x19.commands.PFMergeResponse\x12;\n" +
+	"\x06SetBit\x12\x17.commands.SetBitRequest\x1a\x18.commands.SetBitResponse\x12;\n" +
+	"\x06GetBit\x12\x17.commands.GetBitRequest\x1a\x18.commands.GetBitResponse\x12A\n" +
+	"\bBitCount\x12\x19.commands.BitCountRequest\x1a\x1a.commands.BitCountResponse\x12;\n" +
+	"\x06BitPos\x12\x17.commands.BitPosRequest\x1a\x18.commands.BitPosResponse\x128\n" +
+	"\x05BitOp\x12\x16.commands.BitOpRequest\x1a\x17.commands.BitOpResponse\x12A\n" +
+	"\bBitField\x12\x19.commands.BitFieldRequest\x1a\x1a.commands.BitFieldResponseB\x15Z\x13memorabilia/api;apib\x06proto3"
 
 var (
 	file_api_commands_proto_rawDescOnce sync.Once
@@ -527,38 +6274,233 @@ func file_api_commands_proto_rawDescGZIP() []byte {
 	return file_api_commands_proto_rawDescData
 }
 
-var file_api_commands_proto_msgTypes = make([]protoimpl.MessageInfo, 10)
+var file_api_commands_proto_enumTypes = make([]protoimpl.EnumInfo, 6)
+var file_api_commands_proto_msgTypes = make([]protoimpl.MessageInfo, 95)
 var file_api_commands_proto_goTypes = []any{
-	(*EchoRequest)(nil),            // 0: commands.EchoRequest
-	(*EchoResponse)(nil),           // 1: commands.EchoResponse
-	(*SetRequest)(nil),             // 2: commands.SetRequest
-	(*GetRequest)(nil),             // 3: commands.GetRequest
-	(*GetResponse)(nil),            // 4: commands.GetResponse
-	(*DeleteRequest)(nil),          // 5: commands.DeleteRequest
-	(*DeleteResponse)(nil),         // 6: commands.DeleteResponse
-	(*BatchDeleteRequest)(nil),     // 7: commands.BatchDeleteRequest
-	(*BatchDeleteResponse)(nil),    // 8: commands.BatchDeleteResponse
-	(*GetExpiredKeysResponse)(nil), // 9: commands.GetExpiredKeysResponse
-	(*emptypb.Empty)(nil),          // 10: google.protobuf.Empty
+	(ChangeOp)(0),                  // 0: commands.ChangeOp
+	(WatchEventType)(0),            // 1: commands.WatchEventType
+	(RateLimitAlgorithm)(0),        // 2: commands.RateLimitAlgorithm
+	(BitOperation)(0),              // 3: commands.BitOperation
+	(BitFieldOp_Kind)(0),           // 4: commands.BitFieldOp.Kind
+	(BitFieldOp_Overflow)(0),       // 5: commands.BitFieldOp.Overflow
+	(*EchoRequest)(nil),            // 6: commands.EchoRequest
+	(*EchoResponse)(nil),           // 7: commands.EchoResponse
+	(*SetRequest)(nil),             // 8: commands.SetRequest
+	(*SetResponse)(nil),            // 9: commands.SetResponse
+	(*GetRequest)(nil),             // 10: commands.GetRequest
+	(*GetResponse)(nil),            // 11: commands.GetResponse
+	(*DeleteRequest)(nil),          // 12: commands.DeleteRequest
+	(*DeleteResponse)(nil),         // 13: commands.DeleteResponse
+	(*BatchDeleteRequest)(nil),     // 14: commands.BatchDeleteRequest
+	(*BatchDeleteResponse)(nil),    // 15: commands.BatchDeleteResponse
+	(*GetExpiredKeysResponse)(nil), // 16: commands.GetExpiredKeysResponse
+	(*ScanRequest)(nil),            // 17: commands.ScanRequest
+	(*ScanResponse)(nil),           // 18: commands.ScanResponse
+	(*TTLRequest)(nil),             // 19: commands.TTLRequest
+	(*TTLResponse)(nil),            // 20: commands.TTLResponse
+	(*SubscribeRequest)(nil),       // 21: commands.SubscribeRequest
+	(*ChangeEvent)(nil),            // 22: commands.ChangeEvent
+	(*WatchRequest)(nil),           // 23: commands.WatchRequest
+	(*WatchCreateRequest)(nil),     // 24: commands.WatchCreateRequest
+	(*WatchCancelRequest)(nil),     // 25: commands.WatchCancelRequest
+	(*WatchResponse)(nil),          // 26: commands.WatchResponse
+	(*WatchEvent)(nil),             // 27: commands.WatchEvent
+	(*PushRequest)(nil),            // 28: commands.PushRequest
+	(*PushResponse)(nil),           // 29: commands.PushResponse
+	(*PopRequest)(nil),             // 30: commands.PopRequest
+	(*PopResponse)(nil),            // 31: commands.PopResponse
+	(*BlockingPopRequest)(nil),     // 32: commands.BlockingPopRequest
+	(*LLenRequest)(nil),            // 33: commands.LLenRequest
+	(*LLenResponse)(nil),           // 34: commands.LLenResponse
+	(*QueuePopRequest)(nil),        // 35: commands.QueuePopRequest
+	(*QueuePopResponse)(nil),       // 36: commands.QueuePopResponse
+	(*QueueJobRequest)(nil),        // 37: commands.QueueJobRequest
+	(*QueueJobResponse)(nil),       // 38: commands.QueueJobResponse
+	(*LockRequest)(nil),            // 39: commands.LockRequest
+	(*LockResponse)(nil),           // 40: commands.LockResponse
+	(*UnlockRequest)(nil),          // 41: commands.UnlockRequest
+	(*UnlockResponse)(nil),         // 42: commands.UnlockResponse
+	(*RefreshLockRequest)(nil),     // 43: commands.RefreshLockRequest
+	(*RefreshLockResponse)(nil),    // 44: commands.RefreshLockResponse
+	(*CreateSessionRequest)(nil),   // 45: commands.CreateSessionRequest
+	(*CreateSessionResponse)(nil),  // 46: commands.CreateSessionResponse
+	(*KeepAliveRequest)(nil),       // 47: commands.KeepAliveRequest
+	(*KeepAliveResponse)(nil),      // 48: commands.KeepAliveResponse
+	(*CloseSessionRequest)(nil),    // 49: commands.CloseSessionRequest
+	(*CloseSessionResponse)(nil),   // 50: commands.CloseSessionResponse
+	(*StreamTrim)(nil),             // 51: commands.StreamTrim
+	(*StreamEntry)(nil),            // 52: commands.StreamEntry
+	(*XAddRequest)(nil),            // 53: commands.XAddRequest
+	(*XAddResponse)(nil),           // 54: commands.XAddResponse
+	(*XRangeRequest)(nil),          // 55: commands.XRangeRequest
+	(*StreamEntriesResponse)(nil),  // 56: commands.StreamEntriesResponse
+	(*XLenRequest)(nil),            // 57: commands.XLenRequest
+	(*XLenResponse)(nil),           // 58: commands.XLenResponse
+	(*XTrimRequest)(nil),           // 59: commands.XTrimRequest
+	(*XTrimResponse)(nil),          // 60: commands.XTrimResponse
+	(*XGroupCreateRequest)(nil),    // 61: commands.XGroupCreateRequest
+	(*XGroupCreateResponse)(nil),   // 62: commands.XGroupCreateResponse
+	(*XReadGroupRequest)(nil),      // 63: commands.XReadGroupRequest
+	(*XAckRequest)(nil),            // 64: commands.XAckRequest
+	(*XAckResponse)(nil),           // 65: commands.XAckResponse
+	(*XClaimRequest)(nil),          // 66: commands.XClaimRequest
+	(*XPendingRequest)(nil),        // 67: commands.XPendingRequest
+	(*PendingEntry)(nil),           // 68: commands.PendingEntry
+	(*XPendingResponse)(nil),       // 69: commands.XPendingResponse
+	(*RateLimitRequest)(nil),       // 70: commands.RateLimitRequest
+	(*RateLimitResponse)(nil),      // 71: commands.RateLimitResponse
+	(*BFReserveRequest)(nil),       // 72: commands.BFReserveRequest
+	(*BFReserveResponse)(nil),      // 73: commands.BFReserveResponse
+	(*BFAddRequest)(nil),           // 74: commands.BFAddRequest
+	(*BFAddResponse)(nil),          // 75: commands.BFAddResponse
+	(*BFExistsRequest)(nil),        // 76: commands.BFExistsRequest
+	(*BFExistsResponse)(nil),       // 77: commands.BFExistsResponse
+	(*PFAddRequest)(nil),           // 78: commands.PFAddRequest
+	(*PFAddResponse)(nil),          // 79: commands.PFAddResponse
+	(*PFCountRequest)(nil),         // 80: commands.PFCountRequest
+	(*PFCountResponse)(nil),        // 81: commands.PFCountResponse
+	(*PFMergeRequest)(nil),         // 82: commands.PFMergeRequest
+	(*PFMergeResponse)(nil),        // 83: commands.PFMergeResponse
+	(*SetBitRequest)(nil),          // 84: commands.SetBitRequest
+	(*SetBitResponse)(nil),         // 85: commands.SetBitResponse
+	(*GetBitRequest)(nil),          // 86: commands.GetBitRequest
+	(*GetBitResponse)(nil),         // 87: commands.GetBitResponse
+	(*BitRange)(nil),               // 88: commands.BitRange
+	(*BitCountRequest)(nil),        // 89: commands.BitCountRequest
+	(*BitCountResponse)(nil),       // 90: commands.BitCountResponse
+	(*BitPosRequest)(nil),          // 91: commands.BitPosRequest
+	(*BitPosResponse)(nil),         // 92: commands.BitPosResponse
+	(*BitOpRequest)(nil),           // 93: commands.BitOpRequest
+	(*BitOpResponse)(nil),          // 94: commands.BitOpResponse
+	(*BitFieldOp)(nil),             // 95: commands.BitFieldOp
+	(*BitFieldRequest)(nil),        // 96: commands.BitFieldRequest
+	(*BitFieldResult)(nil),         // 97: commands.BitFieldResult
+	(*BitFieldResponse)(nil),       // 98: commands.BitFieldResponse
+	nil,                            // 99: commands.StreamEntry.FieldsEntry
+	nil,                            // 100: commands.XAddRequest.FieldsEntry
+	(*emptypb.Empty)(nil),          // 101: google.protobuf.Empty
 }
 var file_api_commands_proto_depIdxs = []int32{
-	0,  // 0: commands.Commands.Echo:input_type -> commands.EchoRequest
-	2,  // 1: commands.Commands.Set:input_type -> commands.SetRequest
-	3,  // 2: commands.Commands.Get:input_type -> commands.GetRequest
-	5,  // 3: commands.Commands.Delete:input_type -> commands.DeleteRequest
-	7,  // 4: commands.Commands.BatchDelete:input_type -> commands.BatchDeleteRequest
-	10, // 5: commands.Commands.GetExpiredKeys:input_type -> google.protobuf.Empty
-	1,  // 6: commands.Commands.Echo:output_type -> commands.EchoResponse
-	10, // 7: commands.Commands.Set:output_type -> google.protobuf.Empty
-	4,  // 8: commands.Commands.Get:output_type -> commands.GetResponse
-	6,  // 9: commands.Commands.Delete:output_type -> commands.DeleteResponse
-	8,  // 10: commands.Commands.BatchDelete:output_type -> commands.BatchDeleteResponse
-	9,  // 11: commands.Commands.GetExpiredKeys:output_type -> commands.GetExpiredKeysResponse
-	6,  // [6:12] is the sub-list for method output_type
-	0,  // [0:6] is the sub-list for method input_type
-	0,  // [0:0] is the sub-list for extension type_name
-	0,  // [0:0] is the sub-list for extension extendee
-	0,  // [0:0] is the sub-list for field type_name
+	0,   // 0: commands.ChangeEvent.op:type_name -> commands.ChangeOp
+	24,  // 1: commands.WatchRequest.create_request:type_name -> commands.WatchCreateRequest
+	25,  // 2: commands.WatchRequest.cancel_request:type_name -> commands.WatchCancelRequest
+	27,  // 3: commands.WatchResponse.events:type_name -> commands.WatchEvent
+	1,   // 4: commands.WatchEvent.type:type_name -> commands.WatchEventType
+	99,  // 5: commands.StreamEntry.fields:type_name -> commands.StreamEntry.FieldsEntry
+	100, // 6: commands.XAddRequest.fields:type_name -> commands.XAddRequest.FieldsEntry
+	51,  // 7: commands.XAddRequest.trim:type_name -> commands.StreamTrim
+	52,  // 8: commands.StreamEntriesResponse.entries:type_name -> commands.StreamEntry
+	51,  // 9: commands.XTrimRequest.trim:type_name -> commands.StreamTrim
+	68,  // 10: commands.XPendingResponse.entries:type_name -> commands.PendingEntry
+	2,   // 11: commands.RateLimitRequest.algorithm:type_name -> commands.RateLimitAlgorithm
+	88,  // 12: commands.BitCountRequest.range:type_name -> commands.BitRange
+	88,  // 13: commands.BitPosRequest.range:type_name -> commands.BitRange
+	3,   // 14: commands.BitOpRequest.operation:type_name -> commands.BitOperation
+	4,   // 15: commands.BitFieldOp.kind:type_name -> commands.BitFieldOp.Kind
+	5,   // 16: commands.BitFieldOp.overflow:type_name -> commands.BitFieldOp.Overflow
+	95,  // 17: commands.BitFieldRequest.ops:type_name -> commands.BitFieldOp
+	97,  // 18: commands.BitFieldResponse.results:type_name -> commands.BitFieldResult
+	6,   // 19: commands.Commands.Echo:input_type -> commands.EchoRequest
+	8,   // 20: commands.Commands.Set:input_type -> commands.SetRequest
+	10,  // 21: commands.Commands.Get:input_type -> commands.GetRequest
+	12,  // 22: commands.Commands.Delete:input_type -> commands.DeleteRequest
+	14,  // 23: commands.Commands.BatchDelete:input_type -> commands.BatchDeleteRequest
+	101, // 24: commands.Commands.GetExpiredKeys:input_type -> google.protobuf.Empty
+	17,  // 25: commands.Commands.Scan:input_type -> commands.ScanRequest
+	19,  // 26: commands.Commands.TTL:input_type -> commands.TTLRequest
+	21,  // 27: commands.Commands.Subscribe:input_type -> commands.SubscribeRequest
+	23,  // 28: commands.Commands.Watch:input_type -> commands.WatchRequest
+	28,  // 29: commands.Commands.LPush:input_type -> commands.PushRequest
+	28,  // 30: commands.Commands.RPush:input_type -> commands.PushRequest
+	30,  // 31: commands.Commands.LPop:input_type -> commands.PopRequest
+	30,  // 32: commands.Commands.RPop:input_type -> commands.PopRequest
+	32,  // 33: commands.Commands.BLPop:input_type -> commands.BlockingPopRequest
+	32,  // 34: commands.Commands.BRPop:input_type -> commands.BlockingPopRequest
+	33,  // 35: commands.Commands.LLen:input_type -> commands.LLenRequest
+	35,  // 36: commands.Commands.QueuePop:input_type -> commands.QueuePopRequest
+	37,  // 37: commands.Commands.QueueAck:input_type -> commands.QueueJobRequest
+	37,  // 38: commands.Commands.QueueNack:input_type -> commands.QueueJobRequest
+	39,  // 39: commands.Commands.Lock:input_type -> commands.LockRequest
+	41,  // 40: commands.Commands.Unlock:input_type -> commands.UnlockRequest
+	43,  // 41: commands.Commands.RefreshLock:input_type -> commands.RefreshLockRequest
+	45,  // 42: commands.Commands.CreateSession:input_type -> commands.CreateSessionRequest
+	47,  // 43: commands.Commands.KeepAlive:input_type -> commands.KeepAliveRequest
+	49,  // 44: commands.Commands.CloseSession:input_type -> commands.CloseSessionRequest
+	53,  // 45: commands.Commands.XAdd:input_type -> commands.XAddRequest
+	55,  // 46: commands.Commands.XRange:input_type -> commands.XRangeRequest
+	57,  // 47: commands.Commands.XLen:input_type -> commands.XLenRequest
+	59,  // 48: commands.Commands.XTrim:input_type -> commands.XTrimRequest
+	61,  // 49: commands.Commands.XGroupCreate:input_type -> commands.XGroupCreateRequest
+	63,  // 50: commands.Commands.XReadGroup:input_type -> commands.XReadGroupRequest
+	64,  // 51: commands.Commands.XAck:input_type -> commands.XAckRequest
+	66,  // 52: commands.Commands.XClaim:input_type -> commands.XClaimRequest
+	67,  // 53: commands.Commands.XPending:input_type -> commands.XPendingRequest
+	70,  // 54: commands.Commands.RateLimit:input_type -> commands.RateLimitRequest
+	72,  // 55: commands.Commands.BFReserve:input_type -> commands.BFReserveRequest
+	74,  // 56: commands.Commands.BFAdd:input_type -> commands.BFAddRequest
+	76,  // 57: commands.Commands.BFExists:input_type -> commands.BFExistsRequest
+	78,  // 58: commands.Commands.PFAdd:input_type -> commands.PFAddRequest
+	80,  // 59: commands.Commands.PFCount:input_type -> commands.PFCountRequest
+	82,  // 60: commands.Commands.PFMerge:input_type -> commands.PFMergeRequest
+	84,  // 61: commands.Commands.SetBit:input_type -> commands.SetBitRequest
+	86,  // 62: commands.Commands.GetBit:input_type -> commands.GetBitRequest
+	89,  // 63: commands.Commands.BitCount:input_type -> commands.BitCountRequest
+	91,  // 64: commands.Commands.BitPos:input_type -> commands.BitPosRequest
+	93,  // 65: commands.Commands.BitOp:input_type -> commands.BitOpRequest
+	96,  // 66: commands.Commands.BitField:input_type -> commands.BitFieldRequest
+	7,   // 67: commands.Commands.Echo:output_type -> commands.EchoResponse
+	9,   // 68: commands.Commands.Set:output_type -> commands.SetResponse
+	11,  // 69: commands.Commands.Get:output_type -> commands.GetResponse
+	13,  // 70: commands.Commands.Delete:output_type -> commands.DeleteResponse
+	15,  // 71: commands.Commands.BatchDelete:output_type -> commands.BatchDeleteResponse
+	16,  // 72: commands.Commands.GetExpiredKeys:output_type -> commands.GetExpiredKeysResponse
+	18,  // 73: commands.Commands.Scan:output_type -> commands.ScanResponse
+	20,  // 74: commands.Commands.TTL:output_type -> commands.TTLResponse
+	22,  // 75: commands.Commands.Subscribe:output_type -> commands.ChangeEvent
+	26,  // 76: commands.Commands.Watch:output_type -> commands.WatchResponse
+	29,  // 77: commands.Commands.LPush:output_type -> commands.PushResponse
+	29,  // 78: commands.Commands.RPush:output_type -> commands.PushResponse
+	31,  // 79: commands.Commands.LPop:output_type -> commands.PopResponse
+	31,  // 80: commands.Commands.RPop:output_type -> commands.PopResponse
+	31,  // 81: commands.Commands.BLPop:output_type -> commands.PopResponse
+	31,  // 82: commands.Commands.BRPop:output_type -> commands.PopResponse
+	34,  // 83: commands.Commands.LLen:output_type -> commands.LLenResponse
+	36,  // 84: commands.Commands.QueuePop:output_type -> commands.QueuePopResponse
+	38,  // 85: commands.Commands.QueueAck:output_type -> commands.QueueJobResponse
+	38,  // 86: commands.Commands.QueueNack:output_type -> commands.QueueJobResponse
+	40,  // 87: commands.Commands.Lock:output_type -> commands.LockResponse
+	42,  // 88: commands.Commands.Unlock:output_type -> commands.UnlockResponse
+	44,  // 89: commands.Commands.RefreshLock:output_type -> commands.RefreshLockResponse
+	46,  // 90: commands.Commands.CreateSession:output_type -> commands.CreateSessionResponse
+	48,  // 91: commands.Commands.KeepAlive:output_type -> commands.KeepAliveResponse
+	50,  // 92: commands.Commands.CloseSession:output_type -> commands.CloseSessionResponse
+	54,  // 93: commands.Commands.XAdd:output_type -> commands.XAddResponse
+	56,  // 94: commands.Commands.XRange:output_type -> commands.StreamEntriesResponse
+	58,  // 95: commands.Commands.XLen:output_type -> commands.XLenResponse
+	60,  // 96: commands.Commands.XTrim:output_type -> commands.XTrimResponse
+	62,  // 97: commands.Commands.XGroupCreate:output_type -> commands.XGroupCreateResponse
+	56,  // 98: commands.Commands.XReadGroup:output_type -> commands.StreamEntriesResponse
+	65,  // 99: commands.Commands.XAck:output_type -> commands.XAckResponse
+	56,  // 100: commands.Commands.XClaim:output_type -> commands.StreamEntriesResponse
+	69,  // 101: commands.Commands.XPending:output_type -> commands.XPendingResponse
+	71,  // 102: commands.Commands.RateLimit:output_type -> commands.RateLimitResponse
+	73,  // 103: commands.Commands.BFReserve:output_type -> commands.BFReserveResponse
+	75,  // 104: commands.Commands.BFAdd:output_type -> commands.BFAddResponse
+	77,  // 105: commands.Commands.BFExists:output_type -> commands.BFExistsResponse
+	79,  // 106: commands.Commands.PFAdd:output_type -> commands.PFAddResponse
+	81,  // 107: commands.Commands.PFCount:output_type -> commands.PFCountResponse
+	83,  // 108: commands.Commands.PFMerge:output_type -> commands.PFMergeResponse
+	85,  // 109: commands.Commands.SetBit:output_type -> commands.SetBitResponse
+	87,  // 110: commands.Commands.GetBit:output_type -> commands.GetBitResponse
+	90,  // 111: commands.Commands.BitCount:output_type -> commands.BitCountResponse
+	92,  // 112: commands.Commands.BitPos:output_type -> commands.BitPosResponse
+	94,  // 113: commands.Commands.BitOp:output_type -> commands.BitOpResponse
+	98,  // 114: commands.Commands.BitField:output_type -> commands.BitFieldResponse
+	67,  // [67:115] is the sub-list for method output_type
+	19,  // [19:67] is the sub-list for method input_type
+	19,  // [19:19] is the sub-list for extension type_name
+	19,  // [19:19] is the sub-list for extension extendee
+	0,   // [0:19] is the sub-list for field type_name
 }
 
 func init() { file_api_commands_proto_init() }
@@ -566,18 +6508,23 @@ func file_api_commands_proto_init() {
 	if File_api_commands_proto != nil {
 		return
 	}
+	file_api_commands_proto_msgTypes[17].OneofWrappers = []any{
+		(*WatchRequest_CreateRequest)(nil),
+		(*WatchRequest_CancelRequest)(nil),
+	}
 	type x struct{}
 	out := protoimpl.TypeBuilder{
 		File: protoimpl.DescBuilder{
 			GoPackagePath: reflect.TypeOf(x{}).PkgPath(),
 			RawDescriptor: unsafe.Slice(unsafe.StringData(file_api_commands_proto_rawDesc), len(file_api_commands_proto_rawDesc)),
-			NumEnums:      0,
-			NumMessages:   10,
+			NumEnums:      6,
+			NumMessages:   95,
 			NumExtensions: 0,
 			NumServices:   1,
 		},
 		GoTypes:           file_api_commands_proto_goTypes,
 		DependencyIndexes: file_api_commands_proto_depIdxs,
+		EnumInfos:         file_api_commands_proto_enumTypes,
 		MessageInfos:      file_api_commands_proto_msgTypes,
 	}.Build()
 	File_api_commands_proto = out.File
@@ -26,6 +26,48 @@ const (
 	Commands_Delete_FullMethodName         = "/commands.Commands/Delete"
 	Commands_BatchDelete_FullMethodName    = "/commands.Commands/BatchDelete"
 	Commands_GetExpiredKeys_FullMethodName = "/commands.Commands/GetExpiredKeys"
+	Commands_Scan_FullMethodName           = "/commands.Commands/Scan"
+	Commands_TTL_FullMethodName            = "/commands.Commands/TTL"
+	Commands_Subscribe_FullMethodName      = "/commands.Commands/Subscribe"
+	Commands_Watch_FullMethodName          = "/commands.Commands/Watch"
+	Commands_LPush_FullMethodName          = "/commands.Commands/LPush"
+	Commands_RPush_FullMethodName          = "/commands.Commands/RPush"
+	Commands_LPop_FullMethodName           = "/commands.Commands/LPop"
+	Commands_RPop_FullMethodName           = "/commands.Commands/RPop"
+	Commands_BLPop_FullMethodName          = "/commands.Commands/BLPop"
+	Commands_BRPop_FullMethodName          = "/commands.Commands/BRPop"
+	Commands_LLen_FullMethodName           = "/commands.Commands/LLen"
+	Commands_QueuePop_FullMethodName       = "/commands.Commands/QueuePop"
+	Commands_QueueAck_FullMethodName       = "/commands.Commands/QueueAck"
+	Commands_QueueNack_FullMethodName      = "/commands.Commands/QueueNack"
+	Commands_Lock_FullMethodName           = "/commands.Commands/Lock"
+	Commands_Unlock_FullMethodName         = "/commands.Commands/Unlock"
+	Commands_RefreshLock_FullMethodName    = "/commands.Commands/RefreshLock"
+	Commands_CreateSession_FullMethodName  = "/commands.Commands/CreateSession"
+	Commands_KeepAlive_FullMethodName      = "/commands.Commands/KeepAlive"
+	Commands_CloseSession_FullMethodName   = "/commands.Commands/CloseSession"
+	Commands_XAdd_FullMethodName           = "/commands.Commands/XAdd"
+	Commands_XRange_FullMethodName         = "/commands.Commands/XRange"
+	Commands_XLen_FullMethodName           = "/commands.Commands/XLen"
+	Commands_XTrim_FullMethodName          = "/commands.Commands/XTrim"
+	Commands_XGroupCreate_FullMethodName   = "/commands.Commands/XGroupCreate"
+	Commands_XReadGroup_FullMethodName     = "/commands.Commands/XReadGroup"
+	Commands_XAck_FullMethodName           = "/commands.Commands/XAck"
+	Commands_XClaim_FullMethodName         = "/commands.Commands/XClaim"
+	Commands_XPending_FullMethodName       = "/commands.Commands/XPending"
+	Commands_RateLimit_FullMethodName      = "/commands.Commands/RateLimit"
+	Commands_BFReserve_FullMethodName      = "/commands.Commands/BFReserve"
+	Commands_BFAdd_FullMethodName          = "/commands.Commands/BFAdd"
+	Commands_BFExists_FullMethodName       = "/commands.Commands/BFExists"
+	Commands_PFAdd_FullMethodName          = "/commands.Commands/PFAdd"
+	Commands_PFCount_FullMethodName        = "/commands.Commands/PFCount"
+	Commands_PFMerge_FullMethodName        = "/commands.Commands/PFMerge"
+	Commands_SetBit_FullMethodName         = "/commands.Commands/SetBit"
+	Commands_GetBit_FullMethodName         = "/commands.Commands/GetBit"
+	Commands_BitCount_FullMethodName       = "/commands.Commands/BitCount"
+	Commands_BitPos_FullMethodName         = "/commands.Commands/BitPos"
+	Commands_BitOp_FullMethodName          = "/commands.Commands/BitOp"
+	Commands_BitField_FullMethodName       = "/commands.Commands/BitField"
 )
 
 // CommandsClient is the client API for Commands service.
@@ -33,11 +75,94 @@ const (
 // For semantics around ctx use and closing/ending streaming RPCs, please refer to https://pkg.go.dev/google.golang.org/grpc/?tab=doc#ClientConn.NewStream.
 type CommandsClient interface {
 	Echo(ctx context.Context, in *EchoRequest, opts ...grpc.CallOption) (*EchoResponse, error)
-	Set(ctx context.Context, in *SetRequest, opts ...grpc.CallOption) (*emptypb.Empty, error)
+	Set(ctx context.Context, in *SetRequest, opts ...grpc.CallOption) (*SetResponse, error)
 	Get(ctx context.Context, in *GetRequest, opts ...grpc.CallOption) (*GetResponse, error)
 	Delete(ctx context.Context, in *DeleteRequest, opts ...grpc.CallOption) (*DeleteResponse, error)
 	BatchDelete(ctx context.Context, in *BatchDeleteRequest, opts ...grpc.CallOption) (*BatchDeleteResponse, error)
 	GetExpiredKeys(ctx context.Context, in *emptypb.Empty, opts ...grpc.CallOption) (*GetExpiredKeysResponse, error)
+	Scan(ctx context.Context, in *ScanRequest, opts ...grpc.CallOption) (*ScanResponse, error)
+	TTL(ctx context.Context, in *TTLRequest, opts ...grpc.CallOption) (*TTLResponse, error)
+	// Subscribe streams the committed writes, in the order they were
+	// applied, until the client cancels it.
+	Subscribe(ctx context.Context, in *SubscribeRequest, opts ...grpc.CallOption) (grpc.ServerStreamingClient[ChangeEvent], error)
+	// Watch streams the changes to keys and key prefixes, like etcd's Watch.
+	// The client creates and cancels watches with the requests it sends;
+	// every response names the watch it is for.
+	Watch(ctx context.Context, opts ...grpc.CallOption) (grpc.BidiStreamingClient[WatchRequest, WatchResponse], error)
+	// LPush and RPush add elements to the head or the tail of a list,
+	// creating it if needed.
+	LPush(ctx context.Context, in *PushRequest, opts ...grpc.CallOption) (*PushResponse, error)
+	RPush(ctx context.Context, in *PushRequest, opts ...grpc.CallOption) (*PushResponse, error)
+	// LPop and RPop remove the first or the last element of a list.
+	LPop(ctx context.Context, in *PopRequest, opts ...grpc.CallOption) (*PopResponse, error)
+	RPop(ctx context.Context, in *PopRequest, opts ...grpc.CallOption) (*PopResponse, error)
+	// BLPop and BRPop pop from the first of several lists holding an
+	// element, waiting for one to be pushed when none does. Waiting pops are
+	// served in the order they arrived.
+	BLPop(ctx context.Context, in *BlockingPopRequest, opts ...grpc.CallOption) (*PopResponse, error)
+	BRPop(ctx context.Context, in *BlockingPopRequest, opts ...grpc.CallOption) (*PopResponse, error)
+	LLen(ctx context.Context, in *LLenRequest, opts ...grpc.CallOption) (*LLenResponse, error)
+	// QueuePop, QueueAck and QueueNack use a list as a reliable queue: a
+	// popped element becomes a job in flight, which goes back to the head of
+	// the list unless it is acknowledged within its visibility timeout.
+	QueuePop(ctx context.Context, in *QueuePopRequest, opts ...grpc.CallOption) (*QueuePopResponse, error)
+	QueueAck(ctx context.Context, in *QueueJobRequest, opts ...grpc.CallOption) (*QueueJobResponse, error)
+	QueueNack(ctx context.Context, in *QueueJobRequest, opts ...grpc.CallOption) (*QueueJobResponse, error)
+	// Lock acquires a lock for a session, with a lease and a fencing token;
+	// see LockRequest. Unlock releases it, and RefreshLock renews its lease.
+	Lock(ctx context.Context, in *LockRequest, opts ...grpc.CallOption) (*LockResponse, error)
+	Unlock(ctx context.Context, in *UnlockRequest, opts ...grpc.CallOption) (*UnlockResponse, error)
+	RefreshLock(ctx context.Context, in *RefreshLockRequest, opts ...grpc.CallOption) (*RefreshLockResponse, error)
+	// CreateSession starts a client session, which ends unless KeepAlive is
+	// called within its TTL. Keys Set with the session are ephemeral: they
+	// are deleted when it ends, or when CloseSession closes it.
+	CreateSession(ctx context.Context, in *CreateSessionRequest, opts ...grpc.CallOption) (*CreateSessionResponse, error)
+	KeepAlive(ctx context.Context, in *KeepAliveRequest, opts ...grpc.CallOption) (*KeepAliveResponse, error)
+	CloseSession(ctx context.Context, in *CloseSessionRequest, opts ...grpc.CallOption) (*CloseSessionResponse, error)
+	// XAdd appends an entry to a stream, creating it if needed, under a new
+	// ID greater than every one before it. XRange reads entries by ID range,
+	// XLen counts them and XTrim drops the oldest ones.
+	XAdd(ctx context.Context, in *XAddRequest, opts ...grpc.CallOption) (*XAddResponse, error)
+	XRange(ctx context.Context, in *XRangeRequest, opts ...grpc.CallOption) (*StreamEntriesResponse, error)
+	XLen(ctx context.Context, in *XLenRequest, opts ...grpc.CallOption) (*XLenResponse, error)
+	XTrim(ctx context.Context, in *XTrimRequest, opts ...grpc.CallOption) (*XTrimResponse, error)
+	// XGroupCreate creates a consumer group, whose consumers share the
+	// entries of a stream: XReadGroup delivers each entry to one of them,
+	// where it stays pending until XAck acknowledges it. XClaim hands
+	// entries pending for too long over to another consumer, and XPending
+	// lists the pending entries.
+	XGroupCreate(ctx context.Context, in *XGroupCreateRequest, opts ...grpc.CallOption) (*XGroupCreateResponse, error)
+	XReadGroup(ctx context.Context, in *XReadGroupRequest, opts ...grpc.CallOption) (*StreamEntriesResponse, error)
+	XAck(ctx context.Context, in *XAckRequest, opts ...grpc.CallOption) (*XAckResponse, error)
+	XClaim(ctx context.Context, in *XClaimRequest, opts ...grpc.CallOption) (*StreamEntriesResponse, error)
+	XPending(ctx context.Context, in *XPendingRequest, opts ...grpc.CallOption) (*XPendingResponse, error)
+	// RateLimit checks and consumes, in one step, from a rate limiter
+	// stored at a key; see RateLimitRequest.
+	RateLimit(ctx context.Context, in *RateLimitRequest, opts ...grpc.CallOption) (*RateLimitResponse, error)
+	// BFReserve creates a Bloom filter sized for a capacity and error rate.
+	// BFAdd adds items to one, creating it if needed, and BFExists tells
+	// whether items may have been added: it can be wrong about items that
+	// weren't, never about items that were.
+	BFReserve(ctx context.Context, in *BFReserveRequest, opts ...grpc.CallOption) (*BFReserveResponse, error)
+	BFAdd(ctx context.Context, in *BFAddRequest, opts ...grpc.CallOption) (*BFAddResponse, error)
+	BFExists(ctx context.Context, in *BFExistsRequest, opts ...grpc.CallOption) (*BFExistsResponse, error)
+	// PFAdd adds items to a HyperLogLog, creating it if needed. PFCount
+	// estimates the number of distinct items added to one or more of them,
+	// and PFMerge stores their union.
+	PFAdd(ctx context.Context, in *PFAddRequest, opts ...grpc.CallOption) (*PFAddResponse, error)
+	PFCount(ctx context.Context, in *PFCountRequest, opts ...grpc.CallOption) (*PFCountResponse, error)
+	PFMerge(ctx context.Context, in *PFMergeRequest, opts ...grpc.CallOption) (*PFMergeResponse, error)
+	// SetBit and GetBit write and read single bits of a string, which
+	// BitCount counts and BitPos searches. BitOp combines strings bitwise
+	// into another, and BitField reads and writes integers packed in one.
+	// Strings written this way may not be valid UTF-8: GetResponse carries
+	// them in raw_value.
+	SetBit(ctx context.Context, in *SetBitRequest, opts ...grpc.CallOption) (*SetBitResponse, error)
+	GetBit(ctx context.Context, in *GetBitRequest, opts ...grpc.CallOption) (*GetBitResponse, error)
+	BitCount(ctx context.Context, in *BitCountRequest, opts ...grpc.CallOption) (*BitCountResponse, error)
+	BitPos(ctx context.Context, in *BitPosRequest, opts ...grpc.CallOption) (*BitPosResponse, error)
+	BitOp(ctx context.Context, in *BitOpRequest, opts ...grpc.CallOption) (*BitOpResponse, error)
+	BitField(ctx context.Context, in *BitFieldRequest, opts ...grpc.CallOption) (*BitFieldResponse, error)
 }
 
 type commandsClient struct {
@@ -58,9 +183,9 @@ func (c *commandsClient) Echo(ctx context.Context, in *EchoRequest, opts ...grpc
 	return out, nil
 }
 
-func (c *commandsClient) Set(ctx context.Context, in *SetRequest, opts ...grpc.CallOption) (*emptypb.Empty, error) {
+func (c *commandsClient) Set(ctx context.Context, in *SetRequest, opts ...grpc.CallOption) (*SetResponse, error) {
 	cOpts := append([]grpc.CallOption{grpc.StaticMethod()}, opts...)
-	out := new(emptypb.Empty)
+	out := new(SetResponse)
 	err := c.cc.Invoke(ctx, Commands_Set_FullMethodName, in, out, cOpts...)
 	if err != nil {
 		return nil, err
@@ -108,169 +233,1548 @@ func (c *commandsClient) GetExpiredKeys(ctx context.Context, in *emptypb.Empty,
 	return out, nil
 }
 
+func (c *commandsClient) Scan(ctx context.Context, in *ScanRequest, opts ...grpc.CallOption) (*ScanResponse, error) {
+	cOpts := append([]grpc.CallOption{grpc.StaticMethod()}, opts...)
+	out := new(ScanResponse)
+	err := c.cc.Invoke(ctx, Commands_Scan_FullMethodName, in, out, cOpts...)
+	if err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+func (c *commandsClient) TTL(ctx context.Context, in *TTLRequest, opts ...grpc.CallOption) (*TTLResponse, error) {
+	cOpts := append([]grpc.CallOption{grpc.StaticMethod()}, opts...)
+	out := new(TTLResponse)
+	err := c.cc.Invoke(ctx, Commands_TTL_FullMethodName, in, out, cOpts...)
+	if err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+func (c *commandsClient) Subscribe(ctx context.Context, in *SubscribeRequest, opts ...grpc.CallOption) (grpc.ServerStreamingClient[ChangeEvent], error) {
+	cOpts := append([]grpc.CallOption{grpc.StaticMethod()}, opts...)
+	stream, err := c.cc.NewStream(ctx, &Commands_ServiceDesc.Streams[0], Commands_Subscribe_FullMethodName, cOpts...)
+	if err != nil {
+		return nil, err
+	}
+	x := &grpc.GenericClientStream[SubscribeRequest, ChangeEvent]{ClientStream: stream}
+	if err := x.ClientStream.SendMsg(in); err != nil {
+		return nil, err
+	}
+	if err := x.ClientStream.CloseSend(); err != nil {
+		return nil, err
+	}
+	return x, nil
+}
+
+// This type alias is provided for backwards compatibility with existing code that references the prior non-generic stream type by name.
+type Commands_SubscribeClient = grpc.ServerStreamingClient[ChangeEvent]
+
+func (c *commandsClient) Watch(ctx context.Context, opts ...grpc.CallOption) (grpc.BidiStreamingClient[WatchRequest, WatchResponse], error) {
+	cOpts := append([]grpc.CallOption{grpc.StaticMethod()}, opts...)
+	stream, err := c.cc.NewStream(ctx, &Commands_ServiceDesc.Streams[1], Commands_Watch_FullMethodName, cOpts...)
+	if err != nil {
+		return nil, err
+	}
+	x := &grpc.GenericClientStream[WatchRequest, WatchResponse]{ClientStream: stream}
+	return x, nil
+}
+
+// This type alias is provided for backwards compatibility with existing code that references the prior non-generic stream type by name.
+type Commands_WatchClient = grpc.BidiStreamingClient[WatchRequest, WatchResponse]
+
+func (c *commandsClient) LPush(ctx context.Context, in *PushRequest, opts ...grpc.CallOption) (*PushResponse, error) {
+	cOpts := append([]grpc.CallOption{grpc.StaticMethod()}, opts...)
+	out := new(PushResponse)
+	err := c.cc.Invoke(ctx, Commands_LPush_FullMethodName, in, out, cOpts...)
+	if err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+func (c *commandsClient) RPush(ctx context.Context, in *PushRequest, opts ...grpc.CallOption) (*PushResponse, error) {
+	cOpts := append([]grpc.CallOption{grpc.StaticMethod()}, opts...)
+	out := new(PushResponse)
+	err := c.cc.Invoke(ctx, Commands_RPush_FullMethodName, in, out, cOpts...)
+	if err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+func (c *commandsClient) LPop(ctx context.Context, in *PopRequest, opts ...grpc.CallOption) (*PopResponse, error) {
+	cOpts := append([]grpc.CallOption{grpc.StaticMethod()}, opts...)
+	out := new(PopResponse)
+	err := c.cc.Invoke(ctx, Commands_LPop_FullMethodName, in, out, cOpts...)
+	if err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+func (c *commandsClient) RPop(ctx context.Context, in *PopRequest, opts ...grpc.CallOption) (*PopResponse, error) {
+	cOpts := append([]grpc.CallOption{grpc.StaticMethod()}, opts...)
+	out := new(PopResponse)
+	err := c.cc.Invoke(ctx, Commands_RPop_FullMethodName, in, out, cOpts...)
+	if err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+func (c *commandsClient) BLPop(ctx context.Context, in *BlockingPopRequest, opts ...grpc.CallOption) (*PopResponse, error) {
+	cOpts := append([]grpc.CallOption{grpc.StaticMethod()}, opts...)
+	out := new(PopResponse)
+	err := c.cc.Invoke(ctx, Commands_BLPop_FullMethodName, in, out, cOpts...)
+	if err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+func (c *commandsClient) BRPop(ctx context.Context, in *BlockingPopRequest, opts ...grpc.CallOption) (*PopResponse, error) {
+	cOpts := append([]grpc.CallOption{grpc.StaticMethod()}, opts...)
+	out := new(PopResponse)
+	err := c.cc.Invoke(ctx, Commands_BRPop_FullMethodName, in, out, cOpts...)
+	if err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+func (c *commandsClient) LLen(ctx context.Context, in *LLenRequest, opts ...grpc.CallOption) (*LLenResponse, error) {
+	cOpts := append([]grpc.CallOption{grpc.StaticMethod()}, opts...)
+	out := new(LLenResponse)
+	err := c.cc.Invoke(ctx, Commands_LLen_FullMethodName, in, out, cOpts...)
+	if err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+func (c *commandsClient) QueuePop(ctx context.Context, in *QueuePopRequest, opts ...grpc.CallOption) (*QueuePopResponse, error) {
+	cOpts := append([]grpc.CallOption{grpc.StaticMethod()}, opts...)
+	out := new(QueuePopResponse)
+	err := c.cc.Invoke(ctx, Commands_QueuePop_FullMethodName, in, out, cOpts...)
+	if err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+func (c *commandsClient) QueueAck(ctx context.Context, in *QueueJobRequest, opts ...grpc.CallOption) (*QueueJobResponse, error) {
+	cOpts := append([]grpc.CallOption{grpc.StaticMethod()}, opts...)
+	out := new(QueueJobResponse)
+	err := c.cc.Invoke(ctx, Commands_QueueAck_FullMethodName, in, out, cOpts...)
+	if err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+func (c *commandsClient) QueueNack(ctx context.Context, in *QueueJobRequest, opts ...grpc.CallOption) (*QueueJobResponse, error) {
+	cOpts := append([]grpc.CallOption{grpc.StaticMethod()}, opts...)
+	out := new(QueueJobResponse)
+	err := c.cc.Invoke(ctx, Commands_QueueNack_FullMethodName, in, out, cOpts...)
+	if err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+func (c *commandsClient) Lock(ctx context.Context, in *LockRequest, opts ...grpc.CallOption) (*LockResponse, error) {
+	cOpts := append([]grpc.CallOption{grpc.StaticMethod()}, opts...)
+	out := new(LockResponse)
+	err := c.cc.Invoke(ctx, Commands_Lock_FullMethodName, in, out, cOpts...)
+	if err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+func (c *commandsClient) Unlock(ctx context.Context, in *UnlockRequest, opts ...grpc.CallOption) (*UnlockResponse, error) {
+	cOpts := append([]grpc.CallOption{grpc.StaticMethod()}, opts...)
+	out := new(UnlockResponse)
+	err := c.cc.Invoke(ctx, Commands_Unlock_FullMethodName, in, out, cOpts...)
+	if err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+func (c *commandsClient) RefreshLock(ctx context.Context, in *RefreshLockRequest, opts ...grpc.CallOption) (*RefreshLockResponse, error) {
+	cOpts := append([]grpc.CallOption{grpc.StaticMethod()}, opts...)
+	out := new(RefreshLockResponse)
+	err := c.cc.Invoke(ctx, Commands_RefreshLock_FullMethodName, in, out, cOpts...)
+	if err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+func (c *commandsClient) CreateSession(ctx context.Context, in *CreateSessionRequest, opts ...grpc.CallOption) (*CreateSessionResponse, error) {
+	cOpts := append([]grpc.CallOption{grpc.StaticMethod()}, opts...)
+	out := new(CreateSessionResponse)
+	err := c.cc.Invoke(ctx, Commands_CreateSession_FullMethodName, in, out, cOpts...)
+	if err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+func (c *commandsClient) KeepAlive(ctx context.Context, in *KeepAliveRequest, opts ...grpc.CallOption) (*KeepAliveResponse, error) {
+	cOpts := append([]grpc.CallOption{grpc.StaticMethod()}, opts...)
+	out := new(KeepAliveResponse)
+	err := c.cc.Invoke(ctx, Commands_KeepAlive_FullMethodName, in, out, cOpts...)
+	if err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+func (c *commandsClient) CloseSession(ctx context.Context, in *CloseSessionRequest, opts ...grpc.CallOption) (*CloseSessionResponse, error) {
+	cOpts := append([]grpc.CallOption{grpc.StaticMethod()}, opts...)
+	out := new(CloseSessionResponse)
+	err := c.cc.Invoke(ctx, Commands_CloseSession_FullMethodName, in, out, cOpts...)
+	if err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+func (c *commandsClient) XAdd(ctx context.Context, in *XAddRequest, opts ...grpc.CallOption) (*XAddResponse, error) {
+	cOpts := append([]grpc.CallOption{grpc.StaticMethod()}, opts...)
+	out := new(XAddResponse)
+	err := c.cc.Invoke(ctx, Commands_XAdd_FullMethodName, in, out, cOpts...)
+	if err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+func (c *commandsClient) XRange(ctx context.Context, in *XRangeRequest, opts ...grpc.CallOption) (*StreamEntriesResponse, error) {
+	cOpts := append([]grpc.CallOption{grpc.StaticMethod()}, opts...)
+	out := new(StreamEntriesResponse)
+	err := c.cc.Invoke(ctx, Commands_XRange_FullMethodName, in, out, cOpts...)
+	if err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+func (c *commandsClient) XLen(ctx context.Context, in *XLenRequest, opts ...grpc.CallOption) (*XLenResponse, error) {
+	cOpts := append([]grpc.CallOption{grpc.StaticMethod()}, opts...)
+	out := new(XLenResponse)
+	err := c.cc.Invoke(ctx, Commands_XLen_FullMethodName, in, out, cOpts...)
+	if err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+func (c *commandsClient) XTrim(ctx context.Context, in *XTrimRequest, opts ...grpc.CallOption) (*XTrimResponse, error) {
+	cOpts := append([]grpc.CallOption{grpc.StaticMethod()}, opts...)
+	out := new(XTrimResponse)
+	err := c.cc.Invoke(ctx, Commands_XTrim_FullMethodName, in, out, cOpts...)
+	if err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+func (c *commandsClient) XGroupCreate(ctx context.Context, in *XGroupCreateRequest, opts ...grpc.CallOption) (*XGroupCreateResponse, error) {
+	cOpts := append([]grpc.CallOption{grpc.StaticMethod()}, opts...)
+	out := new(XGroupCreateResponse)
+	err := c.cc.Invoke(ctx, Commands_XGroupCreate_FullMethodName, in, out, cOpts...)
+	if err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+func (c *commandsClient) XReadGroup(ctx context.Context, in *XReadGroupRequest, opts ...grpc.CallOption) (*StreamEntriesResponse, error) {
+	cOpts := append([]grpc.CallOption{grpc.StaticMethod()}, opts...)
+	out := new(StreamEntriesResponse)
+	err := c.cc.Invoke(ctx, Commands_XReadGroup_FullMethodName, in, out, cOpts...)
+	if err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+func (c *commandsClient) XAck(ctx context.Context, in *XAckRequest, opts ...grpc.CallOption) (*XAckResponse, error) {
+	cOpts := append([]grpc.CallOption{grpc.StaticMethod()}, opts...)
+	out := new(XAckResponse)
+	err := c.cc.Invoke(ctx, Commands_XAck_FullMethodName, in, out, cOpts...)
+	if err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+func (c *commandsClient) XClaim(ctx context.Context, in *XClaimRequest, opts ...grpc.CallOption) (*StreamEntriesResponse, error) {
+	cOpts := append([]grpc.CallOption{grpc.StaticMethod()}, opts...)
+	out := new(StreamEntriesResponse)
+	err := c.cc.Invoke(ctx, Commands_XClaim_FullMethodName, in, out, cOpts...)
+	if err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+func (c *commandsClient) XPending(ctx context.Context, in *XPendingRequest, opts ...grpc.CallOption) (*XPendingResponse, error) {
+	cOpts := append([]grpc.CallOption{grpc.StaticMethod()}, opts...)
+	out := new(XPendingResponse)
+	err := c.cc.Invoke(ctx, Commands_XPending_FullMethodName, in, out, cOpts...)
+	if err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+func (c *commandsClient) RateLimit(ctx context.Context, in *RateLimitRequest, opts ...grpc.CallOption) (*RateLimitResponse, error) {
+	cOpts := append([]grpc.CallOption{grpc.StaticMethod()}, opts...)
+	out := new(RateLimitResponse)
+	err := c.cc.Invoke(ctx, Commands_RateLimit_FullMethodName, in, out, cOpts...)
+	if err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+func (c *commandsClient) BFReserve(ctx context.Context, in *BFReserveRequest, opts ...grpc.CallOption) (*BFReserveResponse, error) {
+	cOpts := append([]grpc.CallOption{grpc.StaticMethod()}, opts...)
+	out := new(BFReserveResponse)
+	err := c.cc.Invoke(ctx, Commands_BFReserve_FullMethodName, in, out, cOpts...)
+	if err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+func (c *commandsClient) BFAdd(ctx context.Context, in *BFAddRequest, opts ...grpc.CallOption) (*BFAddResponse, error) {
+	cOpts := append([]grpc.CallOption{grpc.StaticMethod()}, opts...)
+	out := new(BFAddResponse)
+	err := c.cc.Invoke(ctx, Commands_BFAdd_FullMethodName, in, out, cOpts...)
+	if err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+func (c *commandsClient) BFExists(ctx context.Context, in *BFExistsRequest, opts ...grpc.CallOption) (*BFExistsResponse, error) {
+	cOpts := append([]grpc.CallOption{grpc.StaticMethod()}, opts...)
+	out := new(BFExistsResponse)
+	err := c.cc.Invoke(ctx, Commands_BFExists_FullMethodName, in, out, cOpts...)
+	if err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+func (c *commandsClient) PFAdd(ctx context.Context, in *PFAddRequest, opts ...grpc.CallOption) (*PFAddResponse, error) {
+	cOpts := append([]grpc.CallOption{grpc.StaticMethod()}, opts...)
+	out := new(PFAddResponse)
+	err := c.cc.Invoke(ctx, Commands_PFAdd_FullMethodName, in, out, cOpts...)
+	if err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+func (c *commandsClient) PFCount(ctx context.Context, in *PFCountRequest, opts ...grpc.CallOption) (*PFCountResponse, error) {
+	cOpts := append([]grpc.CallOption{grpc.StaticMethod()}, opts...)
+	out := new(PFCountResponse)
+	err := c.cc.Invoke(ctx, Commands_PFCount_FullMethodName, in, out, cOpts...)
+	if err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+func (c *commandsClient) PFMerge(ctx context.Context, in *PFMergeRequest, opts ...grpc.CallOption) (*PFMergeResponse, error) {
+	cOpts := append([]grpc.CallOption{grpc.StaticMethod()}, opts...)
+	out := new(PFMergeResponse)
+	err := c.cc.Invoke(ctx, Commands_PFMerge_FullMethodName, in, out, cOpts...)
+	if err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+func (c *commandsClient) SetBit(ctx context.Context, in *SetBitRequest, opts ...grpc.CallOption) (*SetBitResponse, error) {
+	cOpts := append([]grpc.CallOption{grpc.StaticMethod()}, opts...)
+	out := new(SetBitResponse)
+	err := c.cc.Invoke(ctx, Commands_SetBit_FullMethodName, in, out, cOpts...)
+	if err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+func (c *commandsClient) GetBit(ctx context.Context, in *GetBitRequest, opts ...grpc.CallOption) (*GetBitResponse, error) {
+	cOpts := append([]grpc.CallOption{grpc.StaticMethod()}, opts...)
+	out := new(GetBitResponse)
+	err := c.cc.Invoke(ctx, Commands_GetBit_FullMethodName, in, out, cOpts...)
+	if err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+func (c *commandsClient) BitCount(ctx context.Context, in *BitCountRequest, opts ...grpc.CallOption) (*BitCountResponse, error) {
+	cOpts := append([]grpc.CallOption{grpc.StaticMethod()}, opts...)
+	out := new(BitCountResponse)
+	err := c.cc.Invoke(ctx, Commands_BitCount_FullMethodName, in, out, cOpts...)
+	if err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+func (c *commandsClient) BitPos(ctx context.Context, in *BitPosRequest, opts ...grpc.CallOption) (*BitPosResponse, error) {
+	cOpts := append([]grpc.CallOption{grpc.StaticMethod()}, opts...)
+	out := new(BitPosResponse)
+	err := c.cc.Invoke(ctx, Commands_BitPos_FullMethodName, in, out, cOpts...)
+	if err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+func (c *commandsClient) BitOp(ctx context.Context, in *BitOpRequest, opts ...grpc.CallOption) (*BitOpResponse, error) {
+	cOpts := append([]grpc.CallOption{grpc.StaticMethod()}, opts...)
+	out := new(BitOpResponse)
+	err := c.cc.Invoke(ctx, Commands_BitOp_FullMethodName, in, out, cOpts...)
+	if err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+func (c *commandsClient) BitField(ctx context.Context, in *BitFieldRequest, opts ...grpc.CallOption) (*BitFieldResponse, error) {
+	cOpts := append([]grpc.CallOption{grpc.StaticMethod()}, opts...)
+	out := new(BitFieldResponse)
+	err := c.cc.Invoke(ctx, Commands_BitField_FullMethodName, in, out, cOpts...)
+	if err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
 // CommandsServer is the server API for Commands service.
 // All implementations must embed UnimplementedCommandsServer
 // for forward compatibility.
 type CommandsServer interface {
 	Echo(context.Context, *EchoRequest) (*EchoResponse, error)
-	Set(context.Context, *SetRequest) (*emptypb.Empty, error)
+	Set(context.Context, *SetRequest) (*SetResponse, error)
 	Get(context.Context, *GetRequest) (*GetResponse, error)
 	Delete(context.Context, *DeleteRequest) (*DeleteResponse, error)
 	BatchDelete(context.Context, *BatchDeleteRequest) (*BatchDeleteResponse, error)
 	GetExpiredKeys(context.Context, *emptypb.Empty) (*GetExpiredKeysResponse, error)
+	Scan(context.Context, *ScanRequest) (*ScanResponse, error)
+	TTL(context.Context, *TTLRequest) (*TTLResponse, error)
+	// Subscribe streams the committed writes, in the order they were
+	// applied, until the client cancels it.
+	Subscribe(*SubscribeRequest, grpc.ServerStreamingServer[ChangeEvent]) error
+	// Watch streams the changes to keys and key prefixes, like etcd's Watch.
+	// The client creates and cancels watches with the requests it sends;
+	// every response names the watch it is for.
+	Watch(grpc.BidiStreamingServer[WatchRequest, WatchResponse]) error
+	// LPush and RPush add elements to the head or the tail of a list,
+	// creating it if needed.
+	LPush(context.Context, *PushRequest) (*PushResponse, error)
+	RPush(context.Context, *PushRequest) (*PushResponse, error)
+	// LPop and RPop remove the first or the last element of a list.
+	LPop(context.Context, *PopRequest) (*PopResponse, error)
+	RPop(context.Context, *PopRequest) (*PopResponse, error)
+	// BLPop and BRPop pop from the first of several lists holding an
+	// element, waiting for one to be pushed when none does. Waiting pops are
+	// served in the order they arrived.
+	BLPop(context.Context, *BlockingPopRequest) (*PopResponse, error)
+	BRPop(context.Context, *BlockingPopRequest) (*PopResponse, error)
+	LLen(context.Context, *LLenRequest) (*LLenResponse, error)
+	// QueuePop, QueueAck and QueueNack use a list as a reliable queue: a
+	// popped element becomes a job in flight, which goes back to the head of
+	// the list unless it is acknowledged within its visibility timeout.
+	QueuePop(context.Context, *QueuePopRequest) (*QueuePopResponse, error)
+	QueueAck(context.Context, *QueueJobRequest) (*QueueJobResponse, error)
+	QueueNack(context.Context, *QueueJobRequest) (*QueueJobResponse, error)
+	// Lock acquires a lock for a session, with a lease and a fencing token;
+	// see LockRequest. Unlock releases it, and RefreshLock renews its lease.
+	Lock(context.Context, *LockRequest) (*LockResponse, error)
+	Unlock(context.Context, *UnlockRequest) (*UnlockResponse, error)
+	RefreshLock(context.Context, *RefreshLockRequest) (*RefreshLockResponse, error)
+	// CreateSession starts a client session, which ends unless KeepAlive is
+	// called within its TTL. Keys Set with the session are ephemeral: they
+	// are deleted when it ends, or when CloseSession closes it.
+	CreateSession(context.Context, *CreateSessionRequest) (*CreateSessionResponse, error)
+	KeepAlive(context.Context, *KeepAliveRequest) (*KeepAliveResponse, error)
+	CloseSession(context.Context, *CloseSessionRequest) (*CloseSessionResponse, error)
+	// XAdd appends an entry to a stream, creating it if needed, under a new
+	// ID greater than every one before it. XRange reads entries by ID range,
+	// XLen counts them and XTrim drops the oldest ones.
+	XAdd(context.Context, *XAddRequest) (*XAddResponse, error)
+	XRange(context.Context, *XRangeRequest) (*StreamEntriesResponse, error)
+	XLen(context.Context, *XLenRequest) (*XLenResponse, error)
+	XTrim(context.Context, *XTrimRequest) (*XTrimResponse, error)
+	// XGroupCreate creates a consumer group, whose consumers share the
+	// entries of a stream: XReadGroup delivers each entry to one of them,
+	// where it stays pending until XAck acknowledges it. XClaim hands
+	// entries pending for too long over to another consumer, and XPending
+	// lists the pending entries.
+	XGroupCreate(context.Context, *XGroupCreateRequest) (*XGroupCreateResponse, error)
+	XReadGroup(context.Context, *XReadGroupRequest) (*StreamEntriesResponse, error)
+	XAck(context.Context, *XAckRequest) (*XAckResponse, error)
+	XClaim(context.Context, *XClaimRequest) (*StreamEntriesResponse, error)
+	XPending(context.Context, *XPendingRequest) (*XPendingResponse, error)
+	// RateLimit checks and consumes, in one step, from a rate limiter
+	// stored at a key; see RateLimitRequest.
+	RateLimit(context.Context, *RateLimitRequest) (*RateLimitResponse, error)
+	// BFReserve creates a Bloom filter sized for a capacity and error rate.
+	// BFAdd adds items to one, creating it if needed, and BFExists tells
+	// whether items may have been added: it can be wrong about items that
+	// weren't, never about items that were.
+	BFReserve(context.Context, *BFReserveRequest) (*BFReserveResponse, error)
+	BFAdd(context.Context, *BFAddRequest) (*BFAddResponse, error)
+	BFExists(context.Context, *BFExistsRequest) (*BFExistsResponse, error)
+	// PFAdd adds items to a HyperLogLog, creating it if needed. PFCount
+	// estimates the number of distinct items added to one or more of them,
+	// and PFMerge stores their union.
+	PFAdd(context.Context, *PFAddRequest) (*PFAddResponse, error)
+	PFCount(context.Context, *PFCountRequest) (*PFCountResponse, error)
+	PFMerge(context.Context, *PFMergeRequest) (*PFMergeResponse, error)
+	// SetBit and GetBit write and read single bits of a string, which
+	// BitCount counts and BitPos searches. BitOp combines strings bitwise
+	// into another, and BitField reads and writes integers packed in one.
+	// Strings written this way may not be valid UTF-8: GetResponse carries
+	// them in raw_value.
+	SetBit(context.Context, *SetBitRequest) (*SetBitResponse, error)
+	GetBit(context.Context, *GetBitRequest) (*GetBitResponse, error)
+	BitCount(context.Context, *BitCountRequest) (*BitCountResponse, error)
+	BitPos(context.Context, *BitPosRequest) (*BitPosResponse, error)
+	BitOp(context.Context, *BitOpRequest) (*BitOpResponse, error)
+	BitField(context.Context, *BitFieldRequest) (*BitFieldResponse, error)
+	mustEmbedUnimplementedCommandsServer()
+}
+
+// UnimplementedCommandsServer must be embedded to have
+// forward compatible implementations.
+//
+// NOTE: this should be embedded by value instead of pointer to avoid a nil
+// pointer dereference when methods are called.
+type UnimplementedCommandsServer struct{}
+
+func (UnimplementedCommandsServer) Echo(context.Context, *EchoRequest) (*EchoResponse, error) {
+	return nil, status.Errorf(codes.Unimplemented, "method Echo not implemented")
+}
+func (UnimplementedCommandsServer) Set(context.Context, *SetRequest) (*SetResponse, error) {
+	return nil, status.Errorf(codes.Unimplemented, "method Set not implemented")
+}
+func (UnimplementedCommandsServer) Get(context.Context, *GetRequest) (*GetResponse, error) {
+	return nil, status.Errorf(codes.Unimplemented, "method Get not implemented")
+}
+func (UnimplementedCommandsServer) Delete(context.Context, *DeleteRequest) (*DeleteResponse, error) {
+	return nil, status.Errorf(codes.Unimplemented, "method Delete not implemented")
+}
+func (UnimplementedCommandsServer) BatchDelete(context.Context, *BatchDeleteRequest) (*BatchDeleteResponse, error) {
+	return nil, status.Errorf(codes.Unimplemented, "method BatchDelete not implemented")
+}
+func (UnimplementedCommandsServer) GetExpiredKeys(context.Context, *emptypb.Empty) (*GetExpiredKeysResponse, error) {
+	return nil, status.Errorf(codes.Unimplemented, "method GetExpiredKeys not implemented")
+}
+func (UnimplementedCommandsServer) Scan(context.Context, *ScanRequest) (*ScanResponse, error) {
+	return nil, status.Errorf(codes.Unimplemented, "method Scan not implemented")
+}
+func (UnimplementedCommandsServer) TTL(context.Context, *TTLRequest) (*TTLResponse, error) {
+	return nil, status.Errorf(codes.Unimplemented, "method TTL not implemented")
+}
+func (UnimplementedCommandsServer) Subscribe(*SubscribeRequest, grpc.ServerStreamingServer[ChangeEvent]) error {
+	return status.Errorf(codes.Unimplemented, "method Subscribe not implemented")
+}
+func (UnimplementedCommandsServer) Watch(grpc.BidiStreamingServer[WatchRequest, WatchResponse]) error {
+	return status.Errorf(codes.Unimplemented, "method Watch not implemented")
+}
+func (UnimplementedCommandsServer) LPush(context.Context, *PushRequest) (*PushResponse, error) {
+	return nil, status.Errorf(codes.Unimplemented, "method LPush not implemented")
+}
+func (UnimplementedCommandsServer) RPush(context.Context, *PushRequest) (*PushResponse, error) {
+	return nil, status.Errorf(codes.Unimplemented, "method RPush not implemented")
+}
+func (UnimplementedCommandsServer) LPop(context.Context, *PopRequest) (*PopResponse, error) {
+	return nil, status.Errorf(codes.Unimplemented, "method LPop not implemented")
+}
+func (UnimplementedCommandsServer) RPop(context.Context, *PopRequest) (*PopResponse, error) {
+	return nil, status.Errorf(codes.Unimplemented, "method RPop not implemented")
+}
+func (UnimplementedCommandsServer) BLPop(context.Context, *BlockingPopRequest) (*PopResponse, error) {
+	return nil, status.Errorf(codes.Unimplemented, "method BLPop not implemented")
+}
+func (UnimplementedCommandsServer) BRPop(context.Context, *BlockingPopRequest) (*PopResponse, error) {
+	return nil, status.Errorf(codes.Unimplemented, "method BRPop not implemented")
+}
+func (UnimplementedCommandsServer) LLen(context.Context, *LLenRequest) (*LLenResponse, error) {
+	return nil, status.Errorf(codes.Unimplemented, "method LLen not implemented")
+}
+func (UnimplementedCommandsServer) QueuePop(context.Context, *QueuePopRequest) (*QueuePopResponse, error) {
+	return nil, status.Errorf(codes.Unimplemented, "method QueuePop not implemented")
+}
+func (UnimplementedCommandsServer) QueueAck(context.Context, *QueueJobRequest) (*QueueJobResponse, error) {
+	return nil, status.Errorf(codes.Unimplemented, "method QueueAck not implemented")
+}
+func (UnimplementedCommandsServer) QueueNack(context.Context, *QueueJobRequest) (*QueueJobResponse, error) {
+	return nil, status.Errorf(codes.Unimplemented, "method QueueNack not implemented")
+}
+func (UnimplementedCommandsServer) Lock(context.Context, *LockRequest) (*LockResponse, error) {
+	return nil, status.Errorf(codes.Unimplemented, "method Lock not implemented")
+}
+func (UnimplementedCommandsServer) Unlock(context.Context, *UnlockRequest) (*UnlockResponse, error) {
+	return nil, status.Errorf(codes.Unimplemented, "method Unlock not implemented")
+}
+func (UnimplementedCommandsServer) RefreshLock(context.Context, *RefreshLockRequest) (*RefreshLockResponse, error) {
+	return nil, status.Errorf(codes.Unimplemented, "method RefreshLock not implemented")
+}
+func (UnimplementedCommandsServer) CreateSession(context.Context, *CreateSessionRequest) (*CreateSessionResponse, error) {
+	return nil, status.Errorf(codes.Unimplemented, "method CreateSession not implemented")
+}
+func (UnimplementedCommandsServer) KeepAlive(context.Context, *KeepAliveRequest) (*KeepAliveResponse, error) {
+	return nil, status.Errorf(codes.Unimplemented, "method KeepAlive not implemented")
+}
+func (UnimplementedCommandsServer) CloseSession(context.Context, *CloseSessionRequest) (*CloseSessionResponse, error) {
+	return nil, status.Errorf(codes.Unimplemented, "method CloseSession not implemented")
+}
+func (UnimplementedCommandsServer) XAdd(context.Context, *XAddRequest) (*XAddResponse, error) {
+	return nil, status.Errorf(codes.Unimplemented, "method XAdd not implemented")
+}
+func (UnimplementedCommandsServer) XRange(context.Context, *XRangeRequest) (*StreamEntriesResponse, error) {
+	return nil, status.Errorf(codes.Unimplemented, "method XRange not implemented")
+}
+func (UnimplementedCommandsServer) XLen(context.Context, *XLenRequest) (*XLenResponse, error) {
+	return nil, status.Errorf(codes.Unimplemented, "method XLen not implemented")
+}
+func (UnimplementedCommandsServer) XTrim(context.Context, *XTrimRequest) (*XTrimResponse, error) {
+	return nil, status.Errorf(codes.Unimplemented, "method XTrim not implemented")
+}
+func (UnimplementedCommandsServer) XGroupCreate(context.Context, *XGroupCreateRequest) (*XGroupCreateResponse, error) {
+	return nil, status.Errorf(codes.Unimplemented, "method XGroupCreate not implemented")
+}
+func (UnimplementedCommandsServer) XReadGroup(context.Context, *XReadGroupRequest) (*StreamEntriesResponse, error) {
+	return nil, status.Errorf(codes.Unimplemented, "method XReadGroup not implemented")
+}
+func (UnimplementedCommandsServer) XAck(context.Context, *XAckRequest) (*XAckResponse, error) {
+	return nil, status.Errorf(codes.Unimplemented, "method XAck not implemented")
+}
+func (UnimplementedCommandsServer) XClaim(context.Context, *XClaimRequest) (*StreamEntriesResponse, error) {
+	return nil, status.Errorf(codes.Unimplemented, "method XClaim not implemented")
+}
+func (UnimplementedCommandsServer) XPending(context.Context, *XPendingRequest) (*XPendingResponse, error) {
+	return nil, status.Errorf(codes.Unimplemented, "method XPending not implemented")
+}
+func (UnimplementedCommandsServer) RateLimit(context.Context, *RateLimitRequest) (*RateLimitResponse, error) {
+	return nil, status.Errorf(codes.Unimplemented, "method RateLimit not implemented")
+}
+func (UnimplementedCommandsServer) BFReserve(context.Context, *BFReserveRequest) (*BFReserveResponse, error) {
+	return nil, status.Errorf(codes.Unimplemented, "method BFReserve not implemented")
+}
+func (UnimplementedCommandsServer) BFAdd(context.Context, *BFAddRequest) (*BFAddResponse, error) {
+	return nil, status.Errorf(codes.Unimplemented, "method BFAdd not implemented")
+}
+func (UnimplementedCommandsServer) BFExists(context.Context, *BFExistsRequest) (*BFExistsResponse, error) {
+	return nil, status.Errorf(codes.Unimplemented, "method BFExists not implemented")
+}
+func (UnimplementedCommandsServer) PFAdd(context.Context, *PFAddRequest) (*PFAddResponse, error) {
+	return nil, status.Errorf(codes.Unimplemented, "method PFAdd not implemented")
+}
+func (UnimplementedCommandsServer) PFCount(context.Context, *PFCountRequest) (*PFCountResponse, error) {
+	return nil, status.Errorf(codes.Unimplemented, "method PFCount not implemented")
+}
+func (UnimplementedCommandsServer) PFMerge(context.Context, *PFMergeRequest) (*PFMergeResponse, error) {
+	return nil, status.Errorf(codes.Unimplemented, "method PFMerge not implemented")
+}
+func (UnimplementedCommandsServer) SetBit(context.Context, *SetBitRequest) (*SetBitResponse, error) {
+	return nil, status.Errorf(codes.Unimplemented, "method SetBit not implemented")
+}
+func (UnimplementedCommandsServer) GetBit(context.Context, *GetBitRequest) (*GetBitResponse, error) {
+	return nil, status.Errorf(codes.Unimplemented, "method GetBit not implemented")
+}
+func (UnimplementedCommandsServer) BitCount(context.Context, *BitCountRequest) (*BitCountResponse, error) {
+	return nil, status.Errorf(codes.Unimplemented, "method BitCount not implemented")
+}
+func (UnimplementedCommandsServer) BitPos(context.Context, *BitPosRequest) (*BitPosResponse, error) {
+	return nil, status.Errorf(codes.Unimplemented, "method BitPos not implemented")
+}
+func (UnimplementedCommandsServer) BitOp(context.Context, *BitOpRequest) (*BitOpResponse, error) {
+	return nil, status.Errorf(codes.Unimplemented, "method BitOp not implemented")
+}
+func (UnimplementedCommandsServer) BitField(context.Context, *BitFieldRequest) (*BitFieldResponse, error) {
+	return nil, status.Errorf(codes.Unimplemented, "method BitField not implemented")
+}
+func (UnimplementedCommandsServer) mustEmbedUnimplementedCommandsServer() {}
+func (UnimplementedCommandsServer) testEmbeddedByValue()                  {}
+
+// UnsafeCommandsServer may be embedded to opt out of forward compatibility for this service.
+// Use of this interface is not recommended, as added methods to CommandsServer will
+// result in compilation errors.
+type UnsafeCommandsServer interface {
 	mustEmbedUnimplementedCommandsServer()
 }
 
-// UnimplementedCommandsServer must be embedded to have
-// forward compatible implementations.
-//
-// NOTE: this should be embedded by value instead of pointer to avoid a nil
-// pointer dereference when methods are called.
-type UnimplementedCommandsServer struct{}
+func RegisterCommandsServer(s grpc.ServiceRegistrar, srv CommandsServer) {
+	// If the following call pancis, it indicates UnimplementedCommandsServer was
+	// embedded by pointer and is nil.  This will cause panics if an
+	// unimplemented method is ever invoked, so we test this at initialization
+	// time to prevent it from happening at runtime later due to I/O.
+	if t, ok := srv.(interface{ testEmbeddedByValue() }); ok {
+		t.testEmbeddedByValue()
+	}
+	s.RegisterService(&Commands_ServiceDesc, srv)
+}
+
+func _Commands_Echo_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
+	in := new(EchoRequest)
+	if err := dec(in); err != nil {
+		return nil, err
+	}
+	if interceptor == nil {
+		return srv.(CommandsServer).Echo(ctx, in)
+	}
+	info := &grpc.UnaryServerInfo{
+		Server:     srv,
+		FullMethod: Commands_Echo_FullMethodName,
+	}
+	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+		return srv.(CommandsServer).Echo(ctx, req.(*EchoRequest))
+	}
+	return interceptor(ctx, in, info, handler)
+}
+
+func _Commands_Set_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
+	in := new(SetRequest)
+	if err := dec(in); err != nil {
+		return nil, err
+	}
+	if interceptor == nil {
+		return srv.(CommandsServer).Set(ctx, in)
+	}
+	info := &grpc.UnaryServerInfo{
+		Server:     srv,
+		FullMethod: Commands_Set_FullMethodName,
+	}
+	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+		return srv.(CommandsServer).Set(ctx, req.(*SetRequest))
+	}
+	return interceptor(ctx, in, info, handler)
+}
+
+func _Commands_Get_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
+	in := new(GetRequest)
+	if err := dec(in); err != nil {
+		return nil, err
+	}
+	if interceptor == nil {
+		return srv.(CommandsServer).Get(ctx, in)
+	}
+	info := &grpc.UnaryServerInfo{
+		Server:     srv,
+		FullMethod: Commands_Get_FullMethodName,
+	}
+	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+		return srv.(CommandsServer).Get(ctx, req.(*GetRequest))
+	}
+	return interceptor(ctx, in, info, handler)
+}
+
+func _Commands_Delete_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
+	in := new(DeleteRequest)
+	if err := dec(in); err != nil {
+		return nil, err
+	}
+	if interceptor == nil {
+		return srv.(CommandsServer).Delete(ctx, in)
+	}
+	info := &grpc.UnaryServerInfo{
+		Server:     srv,
+		FullMethod: Commands_Delete_FullMethodName,
+	}
+	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+		return srv.(CommandsServer).Delete(ctx, req.(*DeleteRequest))
+	}
+	return interceptor(ctx, in, info, handler)
+}
+
+func _Commands_BatchDelete_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
+	in := new(BatchDeleteRequest)
+	if err := dec(in); err != nil {
+		return nil, err
+	}
+	if interceptor == nil {
+		return srv.(CommandsServer).BatchDelete(ctx, in)
+	}
+	info := &grpc.UnaryServerInfo{
+		Server:     srv,
+		FullMethod: Commands_BatchDelete_FullMethodName,
+	}
+	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+		return srv.(CommandsServer).BatchDelete(ctx, req.(*BatchDeleteRequest))
+	}
+	return interceptor(ctx, in, info, handler)
+}
+
+func _Commands_GetExpiredKeys_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
+	in := new(emptypb.Empty)
+	if err := dec(in); err != nil {
+		return nil, err
+	}
+	if interceptor == nil {
+		return srv.(CommandsServer).GetExpiredKeys(ctx, in)
+	}
+	info := &grpc.UnaryServerInfo{
+		Server:     srv,
+		FullMethod: Commands_GetExpiredKeys_FullMethodName,
+	}
+	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+		return srv.(CommandsServer).GetExpiredKeys(ctx, req.(*emptypb.Empty))
+	}
+	return interceptor(ctx, in, info, handler)
+}
+
+func _Commands_Scan_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
+	in := new(ScanRequest)
+	if err := dec(in); err != nil {
+		return nil, err
+	}
+	if interceptor == nil {
+		return srv.(CommandsServer).Scan(ctx, in)
+	}
+	info := &grpc.UnaryServerInfo{
+		Server:     srv,
+		FullMethod: Commands_Scan_FullMethodName,
+	}
+	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+		return srv.(CommandsServer).Scan(ctx, req.(*ScanRequest))
+	}
+	return interceptor(ctx, in, info, handler)
+}
+
+func _Commands_TTL_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
+	in := new(TTLRequest)
+	if err := dec(in); err != nil {
+		return nil, err
+	}
+	if interceptor == nil {
+		return srv.(CommandsServer).TTL(ctx, in)
+	}
+	info := &grpc.UnaryServerInfo{
+		Server:     srv,
+		FullMethod: Commands_TTL_FullMethodName,
+	}
+	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+		return srv.(CommandsServer).TTL(ctx, req.(*TTLRequest))
+	}
+	return interceptor(ctx, in, info, handler)
+}
+
+func _Commands_Subscribe_Handler(srv interface{}, stream grpc.ServerStream) error {
+	m := new(SubscribeRequest)
+	if err := stream.RecvMsg(m); err != nil {
+		return err
+	}
+	return srv.(CommandsServer).Subscribe(m, &grpc.GenericServerStream[SubscribeRequest, ChangeEvent]{ServerStream: stream})
+}
+
+// This type alias is provided for backwards compatibility with existing code that references the prior non-generic stream type by name.
+type Commands_SubscribeServer = grpc.ServerStreamingServer[ChangeEvent]
+
+func _Commands_Watch_Handler(srv interface{}, stream grpc.ServerStream) error {
+	return srv.(CommandsServer).Watch(&grpc.GenericServerStream[WatchRequest, WatchResponse]{ServerStream: stream})
+}
+
+// This type alias is provided for backwards compatibility with existing code that references the prior non-generic stream type by name.
+type Commands_WatchServer = grpc.BidiStreamingServer[WatchRequest, WatchResponse]
+
+func _Commands_LPush_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
+	in := new(PushRequest)
+	if err := dec(in); err != nil {
+		return nil, err
+	}
+	if interceptor == nil {
+		return srv.(CommandsServer).LPush(ctx, in)
+	}
+	info := &grpc.UnaryServerInfo{
+		Server:     srv,
+		FullMethod: Commands_LPush_FullMethodName,
+	}
+	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+		return srv.(CommandsServer).LPush(ctx, req.(*PushRequest))
+	}
+	return interceptor(ctx, in, info, handler)
+}
+
+func _Commands_RPush_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
+	in := new(PushRequest)
+	if err := dec(in); err != nil {
+		return nil, err
+	}
+	if interceptor == nil {
+		return srv.(CommandsServer).RPush(ctx, in)
+	}
+	info := &grpc.UnaryServerInfo{
+		Server:     srv,
+		FullMethod: Commands_RPush_FullMethodName,
+	}
+	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+		return srv.(CommandsServer).RPush(ctx, req.(*PushRequest))
+	}
+	return interceptor(ctx, in, info, handler)
+}
+
+func _Commands_LPop_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
+	in := new(PopRequest)
+	if err := dec(in); err != nil {
+		return nil, err
+	}
+	if interceptor == nil {
+		return srv.(CommandsServer).LPop(ctx, in)
+	}
+	info := &grpc.UnaryServerInfo{
+		Server:     srv,
+		FullMethod: Commands_LPop_FullMethodName,
+	}
+	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+		return srv.(CommandsServer).LPop(ctx, req.(*PopRequest))
+	}
+	return interceptor(ctx, in, info, handler)
+}
+
+func _Commands_RPop_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
+	in := new(PopRequest)
+	if err := dec(in); err != nil {
+		return nil, err
+	}
+	if interceptor == nil {
+		return srv.(CommandsServer).RPop(ctx, in)
+	}
+	info := &grpc.UnaryServerInfo{
+		Server:     srv,
+		FullMethod: Commands_RPop_FullMethodName,
+	}
+	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+		return srv.(CommandsServer).RPop(ctx, req.(*PopRequest))
+	}
+	return interceptor(ctx, in, info, handler)
+}
+
+func _Commands_BLPop_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
+	in := new(BlockingPopRequest)
+	if err := dec(in); err != nil {
+		return nil, err
+	}
+	if interceptor == nil {
+		return srv.(CommandsServer).BLPop(ctx, in)
+	}
+	info := &grpc.UnaryServerInfo{
+		Server:     srv,
+		FullMethod: Commands_BLPop_FullMethodName,
+	}
+	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+		return srv.(CommandsServer).BLPop(ctx, req.(*BlockingPopRequest))
+	}
+	return interceptor(ctx, in, info, handler)
+}
+
+func _Commands_BRPop_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
+	in := new(BlockingPopRequest)
+	if err := dec(in); err != nil {
+		return nil, err
+	}
+	if interceptor == nil {
+		return srv.(CommandsServer).BRPop(ctx, in)
+	}
+	info := &grpc.UnaryServerInfo{
+		Server:     srv,
+		FullMethod: Commands_BRPop_FullMethodName,
+	}
+	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+		return srv.(CommandsServer).BRPop(ctx, req.(*BlockingPopRequest))
+	}
+	return interceptor(ctx, in, info, handler)
+}
+
+func _Commands_LLen_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
+	in := new(LLenRequest)
+	if err := dec(in); err != nil {
+		return nil, err
+	}
+	if interceptor == nil {
+		return srv.(CommandsServer).LLen(ctx, in)
+	}
+	info := &grpc.UnaryServerInfo{
+		Server:     srv,
+		FullMethod: Commands_LLen_FullMethodName,
+	}
+	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+		return srv.(CommandsServer).LLen(ctx, req.(*LLenRequest))
+	}
+	return interceptor(ctx, in, info, handler)
+}
+
+func _Commands_QueuePop_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
+	in := new(QueuePopRequest)
+	if err := dec(in); err != nil {
+		return nil, err
+	}
+	if interceptor == nil {
+		return srv.(CommandsServer).QueuePop(ctx, in)
+	}
+	info := &grpc.UnaryServerInfo{
+		Server:     srv,
+		FullMethod: Commands_QueuePop_FullMethodName,
+	}
+	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+		return srv.(CommandsServer).QueuePop(ctx, req.(*QueuePopRequest))
+	}
+	return interceptor(ctx, in, info, handler)
+}
+
+func _Commands_QueueAck_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
+	in := new(QueueJobRequest)
+	if err := dec(in); err != nil {
+		return nil, err
+	}
+	if interceptor == nil {
+		return srv.(CommandsServer).QueueAck(ctx, in)
+	}
+	info := &grpc.UnaryServerInfo{
+		Server:     srv,
+		FullMethod: Commands_QueueAck_FullMethodName,
+	}
+	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+		return srv.(CommandsServer).QueueAck(ctx, req.(*QueueJobRequest))
+	}
+	return interceptor(ctx, in, info, handler)
+}
+
+func _Commands_QueueNack_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
+	in := new(QueueJobRequest)
+	if err := dec(in); err != nil {
+		return nil, err
+	}
+	if interceptor == nil {
+		return srv.(CommandsServer).QueueNack(ctx, in)
+	}
+	info := &grpc.UnaryServerInfo{
+		Server:     srv,
+		FullMethod: Commands_QueueNack_FullMethodName,
+	}
+	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+		return srv.(CommandsServer).QueueNack(ctx, req.(*QueueJobRequest))
+	}
+	return interceptor(ctx, in, info, handler)
+}
+
+func _Commands_Lock_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
+	in := new(LockRequest)
+	if err := dec(in); err != nil {
+		return nil, err
+	}
+	if interceptor == nil {
+		return srv.(CommandsServer).Lock(ctx, in)
+	}
+	info := &grpc.UnaryServerInfo{
+		Server:     srv,
+		FullMethod: Commands_Lock_FullMethodName,
+	}
+	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+		return srv.(CommandsServer).Lock(ctx, req.(*LockRequest))
+	}
+	return interceptor(ctx, in, info, handler)
+}
+
+func _Commands_Unlock_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
+	in := new(UnlockRequest)
+	if err := dec(in); err != nil {
+		return nil, err
+	}
+	if interceptor == nil {
+		return srv.(CommandsServer).Unlock(ctx, in)
+	}
+	info := &grpc.UnaryServerInfo{
+		Server:     srv,
+		FullMethod: Commands_Unlock_FullMethodName,
+	}
+	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+		return srv.(CommandsServer).Unlock(ctx, req.(*UnlockRequest))
+	}
+	return interceptor(ctx, in, info, handler)
+}
+
+func _Commands_RefreshLock_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
+	in := new(RefreshLockRequest)
+	if err := dec(in); err != nil {
+		return nil, err
+	}
+	if interceptor == nil {
+		return srv.(CommandsServer).RefreshLock(ctx, in)
+	}
+	info := &grpc.UnaryServerInfo{
+		Server:     srv,
+		FullMethod: Commands_RefreshLock_FullMethodName,
+	}
+	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+		return srv.(CommandsServer).RefreshLock(ctx, req.(*RefreshLockRequest))
+	}
+	return interceptor(ctx, in, info, handler)
+}
+
+func _Commands_CreateSession_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
+	in := new(CreateSessionRequest)
+	if err := dec(in); err != nil {
+		return nil, err
+	}
+	if interceptor == nil {
+		return srv.(CommandsServer).CreateSession(ctx, in)
+	}
+	info := &grpc.UnaryServerInfo{
+		Server:     srv,
+		FullMethod: Commands_CreateSession_FullMethodName,
+	}
+	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+		return srv.(CommandsServer).CreateSession(ctx, req.(*CreateSessionRequest))
+	}
+	return interceptor(ctx, in, info, handler)
+}
+
+func _Commands_KeepAlive_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
+	in := new(KeepAliveRequest)
+	if err := dec(in); err != nil {
+		return nil, err
+	}
+	if interceptor == nil {
+		return srv.(CommandsServer).KeepAlive(ctx, in)
+	}
+	info := &grpc.UnaryServerInfo{
+		Server:     srv,
+		FullMethod: Commands_KeepAlive_FullMethodName,
+	}
+	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+		return srv.(CommandsServer).KeepAlive(ctx, req.(*KeepAliveRequest))
+	}
+	return interceptor(ctx, in, info, handler)
+}
+
+func _Commands_CloseSession_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
+	in := new(CloseSessionRequest)
+	if err := dec(in); err != nil {
+		return nil, err
+	}
+	if interceptor == nil {
+		return srv.(CommandsServer).CloseSession(ctx, in)
+	}
+	info := &grpc.UnaryServerInfo{
+		Server:     srv,
+		FullMethod: Commands_CloseSession_FullMethodName,
+	}
+	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+		return srv.(CommandsServer).CloseSession(ctx, req.(*CloseSessionRequest))
+	}
+	return interceptor(ctx, in, info, handler)
+}
+
+func _Commands_XAdd_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
+	in := new(XAddRequest)
+	if err := dec(in); err != nil {
+		return nil, err
+	}
+	if interceptor == nil {
+		return srv.(CommandsServer).XAdd(ctx, in)
+	}
+	info := &grpc.UnaryServerInfo{
+		Server:     srv,
+		FullMethod: Commands_XAdd_FullMethodName,
+	}
+	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+		return srv.(CommandsServer).XAdd(ctx, req.(*XAddRequest))
+	}
+	return interceptor(ctx, in, info, handler)
+}
+
+func _Commands_XRange_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
+	in := new(XRangeRequest)
+	if err := dec(in); err != nil {
+		return nil, err
+	}
+	if interceptor == nil {
+		return srv.(CommandsServer).XRange(ctx, in)
+	}
+	info := &grpc.UnaryServerInfo{
+		Server:     srv,
+		FullMethod: Commands_XRange_FullMethodName,
+	}
+	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+		return srv.(CommandsServer).XRange(ctx, req.(*XRangeRequest))
+	}
+	return interceptor(ctx, in, info, handler)
+}
+
+func _Commands_XLen_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
+	in := new(XLenRequest)
+	if err := dec(in); err != nil {
+		return nil, err
+	}
+	if interceptor == nil {
+		return srv.(CommandsServer).XLen(ctx, in)
+	}
+	info := &grpc.UnaryServerInfo{
+		Server:     srv,
+		FullMethod: Commands_XLen_FullMethodName,
+	}
+	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+		return srv.(CommandsServer).XLen(ctx, req.(*XLenRequest))
+	}
+	return interceptor(ctx, in, info, handler)
+}
+
+func _Commands_XTrim_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
+	in := new(XTrimRequest)
+	if err := dec(in); err != nil {
+		return nil, err
+	}
+	if interceptor == nil {
+		return srv.(CommandsServer).XTrim(ctx, in)
+	}
+	info := &grpc.UnaryServerInfo{
+		Server:     srv,
+		FullMethod: Commands_XTrim_FullMethodName,
+	}
+	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+		return srv.(CommandsServer).XTrim(ctx, req.(*XTrimRequest))
+	}
+	return interceptor(ctx, in, info, handler)
+}
+
+func _Commands_XGroupCreate_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
+	in := new(XGroupCreateRequest)
+	if err := dec(in); err != nil {
+		return nil, err
+	}
+	if interceptor == nil {
+		return srv.(CommandsServer).XGroupCreate(ctx, in)
+	}
+	info := &grpc.UnaryServerInfo{
+		Server:     srv,
+		FullMethod: Commands_XGroupCreate_FullMethodName,
+	}
+	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+		return srv.(CommandsServer).XGroupCreate(ctx, req.(*XGroupCreateRequest))
+	}
+	return interceptor(ctx, in, info, handler)
+}
+
+func _Commands_XReadGroup_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
+	in := new(XReadGroupRequest)
+	if err := dec(in); err != nil {
+		return nil, err
+	}
+	if interceptor == nil {
+		return srv.(CommandsServer).XReadGroup(ctx, in)
+	}
+	info := &grpc.UnaryServerInfo{
+		Server:     srv,
+		FullMethod: Commands_XReadGroup_FullMethodName,
+	}
+	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+		return srv.(CommandsServer).XReadGroup(ctx, req.(*XReadGroupRequest))
+	}
+	return interceptor(ctx, in, info, handler)
+}
+
+func _Commands_XAck_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
+	in := new(XAckRequest)
+	if err := dec(in); err != nil {
+		return nil, err
+	}
+	if interceptor == nil {
+		return srv.(CommandsServer).XAck(ctx, in)
+	}
+	info := &grpc.UnaryServerInfo{
+		Server:     srv,
+		FullMethod: Commands_XAck_FullMethodName,
+	}
+	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+		return srv.(CommandsServer).XAck(ctx, req.(*XAckRequest))
+	}
+	return interceptor(ctx, in, info, handler)
+}
+
+func _Commands_XClaim_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
+	in := new(XClaimRequest)
+	if err := dec(in); err != nil {
+		return nil, err
+	}
+	if interceptor == nil {
+		return srv.(CommandsServer).XClaim(ctx, in)
+	}
+	info := &grpc.UnaryServerInfo{
+		Server:     srv,
+		FullMethod: Commands_XClaim_FullMethodName,
+	}
+	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+		return srv.(CommandsServer).XClaim(ctx, req.(*XClaimRequest))
+	}
+	return interceptor(ctx, in, info, handler)
+}
 
-func (UnimplementedCommandsServer) Echo(context.Context, *EchoRequest) (*EchoResponse, error) {
-	return nil, status.Errorf(codes.Unimplemented, "method Echo not implemented")
+func _Commands_XPending_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
+	in := new(XPendingRequest)
+	if err := dec(in); err != nil {
+		return nil, err
+	}
+	if interceptor == nil {
+		return srv.(CommandsServer).XPending(ctx, in)
+	}
+	info := &grpc.UnaryServerInfo{
+		Server:     srv,
+		FullMethod: Commands_XPending_FullMethodName,
+	}
+	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+		return srv.(CommandsServer).XPending(ctx, req.(*XPendingRequest))
+	}
+	return interceptor(ctx, in, info, handler)
 }
-func (UnimplementedCommandsServer) Set(context.Context, *SetRequest) (*emptypb.Empty, error) {
-	return nil, status.Errorf(codes.Unimplemented, "method Set not implemented")
+
+func _Commands_RateLimit_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
+	in := new(RateLimitRequest)
+	if err := dec(in); err != nil {
+		return nil, err
+	}
+	if interceptor == nil {
+		return srv.(CommandsServer).RateLimit(ctx, in)
+	}
+	info := &grpc.UnaryServerInfo{
+		Server:     srv,
+		FullMethod: Commands_RateLimit_FullMethodName,
+	}
+	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+		return srv.(CommandsServer).RateLimit(ctx, req.(*RateLimitRequest))
+	}
+	return interceptor(ctx, in, info, handler)
 }
-func (UnimplementedCommandsServer) Get(context.Context, *GetRequest) (*GetResponse, error) {
-	return nil, status.Errorf(codes.Unimplemented, "method Get not implemented")
+
+func _Commands_BFReserve_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
+	in := new(BFReserveRequest)
+	if err := dec(in); err != nil {
+		return nil, err
+	}
+	if interceptor == nil {
+		return srv.(CommandsServer).BFReserve(ctx, in)
+	}
+	info := &grpc.UnaryServerInfo{
+		Server:     srv,
+		FullMethod: Commands_BFReserve_FullMethodName,
+	}
+	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+		return srv.(CommandsServer).BFReserve(ctx, req.(*BFReserveRequest))
+	}
+	return interceptor(ctx, in, info, handler)
 }
-func (UnimplementedCommandsServer) Delete(context.Context, *DeleteRequest) (*DeleteResponse, error) {
-	return nil, status.Errorf(codes.Unimplemented, "method Delete not implemented")
+
+func _Commands_BFAdd_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
+	in := new(BFAddRequest)
+	if err := dec(in); err != nil {
+		return nil, err
+	}
+	if interceptor == nil {
+		return srv.(CommandsServer).BFAdd(ctx, in)
+	}
+	info := &grpc.UnaryServerInfo{
+		Server:     srv,
+		FullMethod: Commands_BFAdd_FullMethodName,
+	}
+	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+		return srv.(CommandsServer).BFAdd(ctx, req.(*BFAddRequest))
+	}
+	return interceptor(ctx, in, info, handler)
 }
-func (UnimplementedCommandsServer) BatchDelete(context.Context, *BatchDeleteRequest) (*BatchDeleteResponse, error) {
-	return nil, status.Errorf(codes.Unimplemented, "method BatchDelete not implemented")
+
+func _Commands_BFExists_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
+	in := new(BFExistsRequest)
+	if err := dec(in); err != nil {
+		return nil, err
+	}
+	if interceptor == nil {
+		return srv.(CommandsServer).BFExists(ctx, in)
+	}
+	info := &grpc.UnaryServerInfo{
+		Server:     srv,
+		FullMethod: Commands_BFExists_FullMethodName,
+	}
+	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+		return srv.(CommandsServer).BFExists(ctx, req.(*BFExistsRequest))
+	}
+	return interceptor(ctx, in, info, handler)
 }
-func (UnimplementedCommandsServer) GetExpiredKeys(context.Context, *emptypb.Empty) (*GetExpiredKeysResponse, error) {
-	return nil, status.Errorf(codes.Unimplemented, "method GetExpiredKeys not implemented")
+
+func _Commands_PFAdd_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
+	in := new(PFAddRequest)
+	if err := dec(in); err != nil {
+		return nil, err
+	}
+	if interceptor == nil {
+		return srv.(CommandsServer).PFAdd(ctx, in)
+	}
+	info := &grpc.UnaryServerInfo{
+		Server:     srv,
+		FullMethod: Commands_PFAdd_FullMethodName,
+	}
+	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+		return srv.(CommandsServer).PFAdd(ctx, req.(*PFAddRequest))
+	}
+	return interceptor(ctx, in, info, handler)
 }
-func (UnimplementedCommandsServer) mustEmbedUnimplementedCommandsServer() {}
-func (UnimplementedCommandsServer) testEmbeddedByValue()                  {}
 
-// UnsafeCommandsServer may be embedded to opt out of forward compatibility for this service.
-// Use of this interface is not recommended, as added methods to CommandsServer will
-// result in compilation errors.
-type UnsafeCommandsServer interface {
-	mustEmbedUnimplementedCommandsServer()
+func _Commands_PFCount_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
+	in := new(PFCountRequest)
+	if err := dec(in); err != nil {
+		return nil, err
+	}
+	if interceptor == nil {
+		return srv.(CommandsServer).PFCount(ctx, in)
+	}
+	info := &grpc.UnaryServerInfo{
+		Server:     srv,
+		FullMethod: Commands_PFCount_FullMethodName,
+	}
+	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+		return srv.(CommandsServer).PFCount(ctx, req.(*PFCountRequest))
+	}
+	return interceptor(ctx, in, info, handler)
 }
 
-func RegisterCommandsServer(s grpc.ServiceRegistrar, srv CommandsServer) {
-	// If the following call pancis, it indicates UnimplementedCommandsServer was
-	// embedded by pointer and is nil.  This will cause panics if an
-	// unimplemented method is ever invoked, so we test this at initialization
-	// time to prevent it from happening at runtime later due to I/O.
-	if t, ok := srv.(interface{ testEmbeddedByValue() }); ok {
-		t.testEmbeddedByValue()
+func _Commands_PFMerge_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
+	in := new(PFMergeRequest)
+	if err := dec(in); err != nil {
+		return nil, err
 	}
-	s.RegisterService(&Commands_ServiceDesc, srv)
+	if interceptor == nil {
+		return srv.(CommandsServer).PFMerge(ctx, in)
+	}
+	info := &grpc.UnaryServerInfo{
+		Server:     srv,
+		FullMethod: Commands_PFMerge_FullMethodName,
+	}
+	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+		return srv.(CommandsServer).PFMerge(ctx, req.(*PFMergeRequest))
+	}
+	return interceptor(ctx, in, info, handler)
 }
 
-func _Commands_Echo_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
-	in := new(EchoRequest)
+func _Commands_SetBit_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
+	in := new(SetBitRequest)
 	if err := dec(in); err != nil {
 		return nil, err
 	}
 	if interceptor == nil {
-		return srv.(CommandsServer).Echo(ctx, in)
+		return srv.(CommandsServer).SetBit(ctx, in)
 	}
 	info := &grpc.UnaryServerInfo{
 		Server:     srv,
-		FullMethod: Commands_Echo_FullMethodName,
+		FullMethod: Commands_SetBit_FullMethodName,
 	}
 	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
-		return srv.(CommandsServer).Echo(ctx, req.(*EchoRequest))
+		return srv.(CommandsServer).SetBit(ctx, req.(*SetBitRequest))
 	}
 	return interceptor(ctx, in, info, handler)
 }
 
-func _Commands_Set_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
-	in := new(SetRequest)
+func _Commands_GetBit_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
+	in := new(GetBitRequest)
 	if err := dec(in); err != nil {
 		return nil, err
 	}
 	if interceptor == nil {
-		return srv.(CommandsServer).Set(ctx, in)
+		return srv.(CommandsServer).GetBit(ctx, in)
 	}
 	info := &grpc.UnaryServerInfo{
 		Server:     srv,
-		FullMethod: Commands_Set_FullMethodName,
+		FullMethod: Commands_GetBit_FullMethodName,
 	}
 	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
-		return srv.(CommandsServer).Set(ctx, req.(*SetRequest))
+		return srv.(CommandsServer).GetBit(ctx, req.(*GetBitRequest))
 	}
 	return interceptor(ctx, in, info, handler)
 }
 
-func _Commands_Get_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
-	in := new(GetRequest)
+func _Commands_BitCount_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
+	in := new(BitCountRequest)
 	if err := dec(in); err != nil {
 		return nil, err
 	}
 	if interceptor == nil {
-		return srv.(CommandsServer).Get(ctx, in)
+		return srv.(CommandsServer).BitCount(ctx, in)
 	}
 	info := &grpc.UnaryServerInfo{
 		Server:     srv,
-		FullMethod: Commands_Get_FullMethodName,
+		FullMethod: Commands_BitCount_FullMethodName,
 	}
 	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
-		return srv.(CommandsServer).Get(ctx, req.(*GetRequest))
+		return srv.(CommandsServer).BitCount(ctx, req.(*BitCountRequest))
 	}
 	return interceptor(ctx, in, info, handler)
 }
 
-func _Commands_Delete_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
-	in := new(DeleteRequest)
+func _Commands_BitPos_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
+	in := new(BitPosRequest)
 	if err := dec(in); err != nil {
 		return nil, err
 	}
 	if interceptor == nil {
-		return srv.(CommandsServer).Delete(ctx, in)
+		return srv.(CommandsServer).BitPos(ctx, in)
 	}
 	info := &grpc.UnaryServerInfo{
 		Server:     srv,
-		FullMethod: Commands_Delete_FullMethodName,
+		FullMethod: Commands_BitPos_FullMethodName,
 	}
 	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
-		return srv.(CommandsServer).Delete(ctx, req.(*DeleteRequest))
+		return srv.(CommandsServer).BitPos(ctx, req.(*BitPosRequest))
 	}
 	return interceptor(ctx, in, info, handler)
 }
 
-func _Commands_BatchDelete_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
-	in := new(BatchDeleteRequest)
+func _Commands_BitOp_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
+	in := new(BitOpRequest)
 	if err := dec(in); err != nil {
 		return nil, err
 	}
 	if interceptor == nil {
-		return srv.(CommandsServer).BatchDelete(ctx, in)
+		return srv.(CommandsServer).BitOp(ctx, in)
 	}
 	info := &grpc.UnaryServerInfo{
 		Server:     srv,
-		FullMethod: Commands_BatchDelete_FullMethodName,
+		FullMethod: Commands_BitOp_FullMethodName,
 	}
 	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
-		return srv.(CommandsServer).BatchDelete(ctx, req.(*BatchDeleteRequest))
+		return srv.(CommandsServer).BitOp(ctx, req.(*BitOpRequest))
 	}
 	return interceptor(ctx, in, info, handler)
 }
 
-func _Commands_GetExpiredKeys_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
-	in := new(emptypb.Empty)
+func _Commands_BitField_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
+	in := new(BitFieldRequest)
 	if err := dec(in); err != nil {
 		return nil, err
 	}
 	if interceptor == nil {
-		return srv.(CommandsServer).GetExpiredKeys(ctx, in)
+		return srv.(CommandsServer).BitField(ctx, in)
 	}
 	info := &grpc.UnaryServerInfo{
 		Server:     srv,
-		FullMethod: Commands_GetExpiredKeys_FullMethodName,
+		FullMethod: Commands_BitField_FullMethodName,
 	}
 	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
-		return srv.(CommandsServer).GetExpiredKeys(ctx, req.(*emptypb.Empty))
+		return srv.(CommandsServer).BitField(ctx, req.(*BitFieldRequest))
 	}
 	return interceptor(ctx, in, info, handler)
 }
@@ -306,7 +1810,179 @@ var Commands_ServiceDesc = grpc.ServiceDesc{
 			MethodName: "GetExpiredKeys",
 			Handler:    _Commands_GetExpiredKeys_Handler,
 		},
+		{
+			MethodName: "Scan",
+			Handler:    _Commands_Scan_Handler,
+		},
+		{
+			MethodName: "TTL",
+			Handler:    _Commands_TTL_Handler,
+		},
+		{
+			MethodName: "LPush",
+			Handler:    _Commands_LPush_Handler,
+		},
+		{
+			MethodName: "RPush",
+			Handler:    _Commands_RPush_Handler,
+		},
+		{
+			MethodName: "LPop",
+			Handler:    _Commands_LPop_Handler,
+		},
+		{
+			MethodName: "RPop",
+			Handler:    _Commands_RPop_Handler,
+		},
+		{
+			MethodName: "BLPop",
+			Handler:    _Commands_BLPop_Handler,
+		},
+		{
+			MethodName: "BRPop",
+			Handler:    _Commands_BRPop_Handler,
+		},
+		{
+			MethodName: "LLen",
+			Handler:    _Commands_LLen_Handler,
+		},
+		{
+			MethodName: "QueuePop",
+			Handler:    _Commands_QueuePop_Handler,
+		},
+		{
+			MethodName: "QueueAck",
+			Handler:    _Commands_QueueAck_Handler,
+		},
+		{
+			MethodName: "QueueNack",
+			Handler:    _Commands_QueueNack_Handler,
+		},
+		{
+			MethodName: "Lock",
+			Handler:    _Commands_Lock_Handler,
+		},
+		{
+			MethodName: "Unlock",
+			Handler:    _Commands_Unlock_Handler,
+		},
+		{
+			MethodName: "RefreshLock",
+			Handler:    _Commands_RefreshLock_Handler,
+		},
+		{
+			MethodName: "CreateSession",
+			Handler:    _Commands_CreateSession_Handler,
+		},
+		{
+			MethodName: "KeepAlive",
+			Handler:    _Commands_KeepAlive_Handler,
+		},
+		{
+			MethodName: "CloseSession",
+			Handler:    _Commands_CloseSession_Handler,
+		},
+		{
+			MethodName: "XAdd",
+			Handler:    _Commands_XAdd_Handler,
+		},
+		{
+			MethodName: "XRange",
+			Handler:    _Commands_XRange_Handler,
+		},
+		{
+			MethodName: "XLen",
+			Handler:    _Commands_XLen_Handler,
+		},
+		{
+			MethodName: "XTrim",
+			Handler:    _Commands_XTrim_Handler,
+		},
+		{
+			MethodName: "XGroupCreate",
+			Handler:    _Commands_XGroupCreate_Handler,
+		},
+		{
+			MethodName: "XReadGroup",
+			Handler:    _Commands_XReadGroup_Handler,
+		},
+		{
+			MethodName: "XAck",
+			Handler:    _Commands_XAck_Handler,
+		},
+		{
+			MethodName: "XClaim",
+			Handler:    _Commands_XClaim_Handler,
+		},
+		{
+			MethodName: "XPending",
+			Handler:    _Commands_XPending_Handler,
+		},
+		{
+			MethodName: "RateLimit",
+			Handler:    _Commands_RateLimit_Handler,
+		},
+		{
+			MethodName: "BFReserve",
+			Handler:    _Commands_BFReserve_Handler,
+		},
+		{
+			MethodName: "BFAdd",
+			Handler:    _Commands_BFAdd_Handler,
+		},
+		{
+			MethodName: "BFExists",
+			Handler:    _Commands_BFExists_Handler,
+		},
+		{
+			MethodName: "PFAdd",
+			Handler:    _Commands_PFAdd_Handler,
+		},
+		{
+			MethodName: "PFCount",
+			Handler:    _Commands_PFCount_Handler,
+		},
+		{
+			MethodName: "PFMerge",
+			Handler:    _Commands_PFMerge_Handler,
+		},
+		{
+			MethodName: "SetBit",
+			Handler:    _Commands_SetBit_Handler,
+		},
+		{
+			MethodName: "GetBit",
+			Handler:    _Commands_GetBit_Handler,
+		},
+		{
+			MethodName: "BitCount",
+			Handler:    _Commands_BitCount_Handler,
+		},
+		{
+			MethodName: "BitPos",
+			Handler:    _Commands_BitPos_Handler,
+		},
+		{
+			MethodName: "BitOp",
+			Handler:    _Commands_BitOp_Handler,
+		},
+		{
+			MethodName: "BitField",
+			Handler:    _Commands_BitField_Handler,
+		},
+	},
+	Streams: []grpc.StreamDesc{
+		{
+			StreamName:    "Subscribe",
+			Handler:       _Commands_Subscribe_Handler,
+			ServerStreams: true,
+		},
+		{
+			StreamName:    "Watch",
+			Handler:       _Commands_Watch_Handler,
+			ServerStreams: true,
+			ClientStreams: true,
+		},
 	},
-	Streams:  []grpc.StreamDesc{},
 	Metadata: "api/commands.proto",
 }
@@ -0,0 +1,564 @@
+// Code generated by protoc-gen-go. DO NOT EDIT.
+// versions:
+// 	protoc-gen-go v1.36.6
+// 	protoc        v7.35.0
+// source: api/pubsub.proto
+
+package api
+
+import (
+	protoreflect "google.golang.org/protobuf/reflect/protoreflect"
+	protoimpl "google.golang.org/protobuf/runtime/protoimpl"
+	reflect "reflect"
+	sync "sync"
+	unsafe "unsafe"
+)
+
+const (
+	// Verify that this generated code is sufficiently up-to-date.
+	_ = protoimpl.EnforceVersion(20 - protoimpl.MinVersion)
+	// Verify that runtime/protoimpl is sufficiently up-to-date.
+	_ = protoimpl.EnforceVersion(protoimpl.MaxVersion - 20)
+)
+
+type KeyspaceEventType int32
+
+const (
+	KeyspaceEventType_KEYSPACE_EVENT_UNSPECIFIED KeyspaceEventType = 0
+	// KEYSPACE_EVENT_SET: the key was given a value.
+	KeyspaceEventType_KEYSPACE_EVENT_SET KeyspaceEventType = 1
+	// KEYSPACE_EVENT_DEL: the key was deleted.
+	KeyspaceEventType_KEYSPACE_EVENT_DEL KeyspaceEventType = 2
+	// KEYSPACE_EVENT_EXPIRED: the TTL cleanup removed the key after it
+	// expired.
+	KeyspaceEventType_KEYSPACE_EVENT_EXPIRED KeyspaceEventType = 3
+	// KEYSPACE_EVENT_EVICTED: the key was removed from this node's store
+	// because its slot moved to another shard.
+	KeyspaceEventType_KEYSPACE_EVENT_EVICTED KeyspaceEventType = 4
+)
+
+// Enum value maps for KeyspaceEventType.
+var (
+	KeyspaceEventType_name = map[int32]string{
+		0: "KEYSPACE_EVENT_UNSPECIFIED",
+		1: "KEYSPACE_EVENT_SET",
+		2: "KEYSPACE_EVENT_DEL",
+		3: "KEYSPACE_EVENT_EXPIRED",
+		4: "KEYSPACE_EVENT_EVICTED",
+	}
+	KeyspaceEventType_value = map[string]int32{
+		"KEYSPACE_EVENT_UNSPECIFIED": 0,
+		"KEYSPACE_EVENT_SET":         1,
+		"KEYSPACE_EVENT_DEL":         2,
+		"KEYSPACE_EVENT_EXPIRED":     3,
+		"KEYSPACE_EVENT_EVICTED":     4,
+	}
+)
+
+func (x KeyspaceEventType) Enum() *KeyspaceEventType {
+	p := new(KeyspaceEventType)
+	*p = x
+	return p
+}
+
+func (x KeyspaceEventType) String() string {
+	return protoimpl.X.EnumStringOf(x.Descriptor(), protoreflect.EnumNumber(x))
+}
+
+func (KeyspaceEventType) Descriptor() protoreflect.EnumDescriptor {
+	return file_api_pubsub_proto_enumTypes[0].Descriptor()
+}
+
+func (KeyspaceEventType) Type() protoreflect.EnumType {
+	return &file_api_pubsub_proto_enumTypes[0]
+}
+
+func (x KeyspaceEventType) Number() protoreflect.EnumNumber {
+	return protoreflect.EnumNumber(x)
+}
+
+// Deprecated: Use KeyspaceEventType.Descriptor instead.
+func (KeyspaceEventType) EnumDescriptor() ([]byte, []int) {
+	return file_api_pubsub_proto_rawDescGZIP(), []int{0}
+}
+
+type PublishRequest struct {
+	state         protoimpl.MessageState `protogen:"open.v1"`
+	Channel       string                 `protobuf:"bytes,1,opt,name=channel,proto3" json:"channel,omitempty"`
+	Message       string                 `protobuf:"bytes,2,opt,name=message,proto3" json:"message,omitempty"`
+	unknownFields protoimpl.UnknownFields
+	sizeCache     protoimpl.SizeCache
+}
+
+func (x *PublishRequest) Reset() {
+	*x = PublishRequest{}
+	mi := &file_api_pubsub_proto_msgTypes[0]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *PublishRequest) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*PublishRequest) ProtoMessage() {}
+
+func (x *PublishRequest) ProtoReflect() protoreflect.Message {
+	mi := &file_api_pubsub_proto_msgTypes[0]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use PublishRequest.ProtoReflect.Descriptor instead.
+func (*PublishRequest) Descriptor() ([]byte, []int) {
+	return file_api_pubsub_proto_rawDescGZIP(), []int{0}
+}
+
+func (x *PublishRequest) GetChannel() string {
+	if x != nil {
+		return x.Channel
+	}
+	return ""
+}
+
+func (x *PublishRequest) GetMessage() string {
+	if x != nil {
+		return x.Message
+	}
+	return ""
+}
+
+type PublishResponse struct {
+	state protoimpl.MessageState `protogen:"open.v1"`
+	// receivers is how many subscriptions the message was delivered to,
+	// across the nodes that could be reached. One subscribed both to the
+	// channel and to a matching pattern counts, and receives it, twice.
+	Receivers     int64 `protobuf:"varint,1,opt,name=receivers,proto3" json:"receivers,omitempty"`
+	unknownFields protoimpl.UnknownFields
+	sizeCache     protoimpl.SizeCache
+}
+
+func (x *PublishResponse) Reset() {
+	*x = PublishResponse{}
+	mi := &file_api_pubsub_proto_msgTypes[1]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *PublishResponse) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*PublishResponse) ProtoMessage() {}
+
+func (x *PublishResponse) ProtoReflect() protoreflect.Message {
+	mi := &file_api_pubsub_proto_msgTypes[1]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use PublishResponse.ProtoReflect.Descriptor instead.
+func (*PublishResponse) Descriptor() ([]byte, []int) {
+	return file_api_pubsub_proto_rawDescGZIP(), []int{1}
+}
+
+func (x *PublishResponse) GetReceivers() int64 {
+	if x != nil {
+		return x.Receivers
+	}
+	return 0
+}
+
+type ChannelSubscribeRequest struct {
+	state         protoimpl.MessageState `protogen:"open.v1"`
+	Channels      []string               `protobuf:"bytes,1,rep,name=channels,proto3" json:"channels,omitempty"`
+	unknownFields protoimpl.UnknownFields
+	sizeCache     protoimpl.SizeCache
+}
+
+func (x *ChannelSubscribeRequest) Reset() {
+	*x = ChannelSubscribeRequest{}
+	mi := &file_api_pubsub_proto_msgTypes[2]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *ChannelSubscribeRequest) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*ChannelSubscribeRequest) ProtoMessage() {}
+
+func (x *ChannelSubscribeRequest) ProtoReflect() protoreflect.Message {
+	mi := &file_api_pubsub_proto_msgTypes[2]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use ChannelSubscribeRequest.ProtoReflect.Descriptor instead.
+func (*ChannelSubscribeRequest) Descriptor() ([]byte, []int) {
+	return file_api_pubsub_proto_rawDescGZIP(), []int{2}
+}
+
+func (x *ChannelSubscribeRequest) GetChannels() []string {
+	if x != nil {
+		return x.Channels
+	}
+	return nil
+}
+
+type PatternSubscribeRequest struct {
+	state         protoimpl.MessageState `protogen:"open.v1"`
+	Patterns      []string               `protobuf:"bytes,1,rep,name=patterns,proto3" json:"patterns,omitempty"`
+	unknownFields protoimpl.UnknownFields
+	sizeCache     protoimpl.SizeCache
+}
+
+func (x *PatternSubscribeRequest) Reset() {
+	*x = PatternSubscribeRequest{}
+	mi := &file_api_pubsub_proto_msgTypes[3]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *PatternSubscribeRequest) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*PatternSubscribeRequest) ProtoMessage() {}
+
+func (x *PatternSubscribeRequest) ProtoReflect() protoreflect.Message {
+	mi := &file_api_pubsub_proto_msgTypes[3]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use PatternSubscribeRequest.ProtoReflect.Descriptor instead.
+func (*PatternSubscribeRequest) Descriptor() ([]byte, []int) {
+	return file_api_pubsub_proto_rawDescGZIP(), []int{3}
+}
+
+func (x *PatternSubscribeRequest) GetPatterns() []string {
+	if x != nil {
+		return x.Patterns
+	}
+	return nil
+}
+
+type PubSubMessage struct {
+	state   protoimpl.MessageState `protogen:"open.v1"`
+	Channel string                 `protobuf:"bytes,1,opt,name=channel,proto3" json:"channel,omitempty"`
+	// pattern is the pattern the channel matched, for PSubscribe.
+	Pattern string `protobuf:"bytes,2,opt,name=pattern,proto3" json:"pattern,omitempty"`
+	Message string `protobuf:"bytes,3,opt,name=message,proto3" json:"message,omitempty"`
+	// dropped counts the messages left out before this one because the
+	// subscriber wasn't keeping up, when the server drops them.
+	Dropped       uint64 `protobuf:"varint,4,opt,name=dropped,proto3" json:"dropped,omitempty"`
+	unknownFields protoimpl.UnknownFields
+	sizeCache     protoimpl.SizeCache
+}
+
+func (x *PubSubMessage) Reset() {
+	*x = PubSubMessage{}
+	mi := &file_api_pubsub_proto_msgTypes[4]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *PubSubMessage) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*PubSubMessage) ProtoMessage() {}
+
+func (x *PubSubMessage) ProtoReflect() protoreflect.Message {
+	mi := &file_api_pubsub_proto_msgTypes[4]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use PubSubMessage.ProtoReflect.Descriptor instead.
+func (*PubSubMessage) Descriptor() ([]byte, []int) {
+	return file_api_pubsub_proto_rawDescGZIP(), []int{4}
+}
+
+func (x *PubSubMessage) GetChannel() string {
+	if x != nil {
+		return x.Channel
+	}
+	return ""
+}
+
+func (x *PubSubMessage) GetPattern() string {
+	if x != nil {
+		return x.Pattern
+	}
+	return ""
+}
+
+func (x *PubSubMessage) GetMessage() string {
+	if x != nil {
+		return x.Message
+	}
+	return ""
+}
+
+func (x *PubSubMessage) GetDropped() uint64 {
+	if x != nil {
+		return x.Dropped
+	}
+	return 0
+}
+
+type WatchKeyspaceRequest struct {
+	state protoimpl.MessageState `protogen:"open.v1"`
+	// pattern is a glob the keys must match; empty matches every key.
+	Pattern string `protobuf:"bytes,1,opt,name=pattern,proto3" json:"pattern,omitempty"`
+	// events limits the stream to these types of events; empty streams all
+	// of them.
+	Events        []KeyspaceEventType `protobuf:"varint,2,rep,packed,name=events,proto3,enum=commands.KeyspaceEventType" json:"events,omitempty"`
+	unknownFields protoimpl.UnknownFields
+	sizeCache     protoimpl.SizeCache
+}
+
+func (x *WatchKeyspaceRequest) Reset() {
+	*x = WatchKeyspaceRequest{}
+	mi := &file_api_pubsub_proto_msgTypes[5]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *WatchKeyspaceRequest) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*WatchKeyspaceRequest) ProtoMessage() {}
+
+func (x *WatchKeyspaceRequest) ProtoReflect() protoreflect.Message {
+	mi := &file_api_pubsub_proto_msgTypes[5]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use WatchKeyspaceRequest.ProtoReflect.Descriptor instead.
+func (*WatchKeyspaceRequest) Descriptor() ([]byte, []int) {
+	return file_api_pubsub_proto_rawDescGZIP(), []int{5}
+}
+
+func (x *WatchKeyspaceRequest) GetPattern() string {
+	if x != nil {
+		return x.Pattern
+	}
+	return ""
+}
+
+func (x *WatchKeyspaceRequest) GetEvents() []KeyspaceEventType {
+	if x != nil {
+		return x.Events
+	}
+	return nil
+}
+
+type KeyspaceEvent struct {
+	state protoimpl.MessageState `protogen:"open.v1"`
+	Type  KeyspaceEventType      `protobuf:"varint,1,opt,name=type,proto3,enum=commands.KeyspaceEventType" json:"type,omitempty"`
+	Key   string                 `protobuf:"bytes,2,opt,name=key,proto3" json:"key,omitempty"`
+	// dropped counts the events left out before this one because the
+	// watcher wasn't keeping up, when the server drops them.
+	Dropped       uint64 `protobuf:"varint,3,opt,name=dropped,proto3" json:"dropped,omitempty"`
+	unknownFields protoimpl.UnknownFields
+	sizeCache     protoimpl.SizeCache
+}
+
+func (x *KeyspaceEvent) Reset() {
+	*x = KeyspaceEvent{}
+	mi := &file_api_pubsub_proto_msgTypes[6]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *KeyspaceEvent) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*KeyspaceEvent) ProtoMessage() {}
+
+func (x *KeyspaceEvent) ProtoReflect() protoreflect.Message {
+	mi := &file_api_pubsub_proto_msgTypes[6]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use KeyspaceEvent.ProtoReflect.Descriptor instead.
+func (*KeyspaceEvent) Descriptor() ([]byte, []int) {
+	return file_api_pubsub_proto_rawDescGZIP(), []int{6}
+}
+
+func (x *KeyspaceEvent) GetType() KeyspaceEventType {
+	if x != nil {
+		return x.Type
+	}
+	return KeyspaceEventType_KEYSPACE_EVENT_UNSPECIFIED
+}
+
+func (x *KeyspaceEvent) GetKey() string {
+	if x != nil {
+		return x.Key
+	}
+	return ""
+}
+
+func (x *KeyspaceEvent) GetDropped() uint64 {
+	if x != nil {
+		return x.Dropped
+	}
+	return 0
+}
+
+var File_api_pubsub_proto protoreflect.FileDescriptor
+
+const file_api_pubsub_proto_rawDesc = "" +
+	"\n" +
+	"\x10api/pubsub.proto\x12\bcommands\"D\n" +
+	"\x0ePublishRequest\x12\x18\n" +
+	"\achannel\x18\x01 \x01(\tR\achannel\x12\x18\n" +
+	"\amessage\x18\x02 \x01(\tR\amessage\"/\n" +
+	"\x0fPublishResponse\x12\x1c\n" +
+	"\treceivers\x18\x01 \x01(\x03R\treceivers\"5\n" +
+	"\x17ChannelSubscribeRequest\x12\x1a\n" +
+	"\bchannels\x18\x01 \x03(\tR\bchannels\"5\n" +
+	"\x17PatternSubscribeRequest\x12\x1a\n" +
+	"\bpatterns\x18\x01 \x03(\tR\bpatterns\"w\n" +
+	"\rPubSubMessage\x12\x18\n" +
+	"\achannel\x18\x01 \x01(\tR\achannel\x12\x18\n" +
+	"\apattern\x18\x02 \x01(\tR\apattern\x12\x18\n" +
+	"\amessage\x18\x03 \x01(\tR\amessage\x12\x18\n" +
+	"\adropped\x18\x04 \x01(\x04R\adropped\"e\n" +
+	"\x14WatchKeyspaceRequest\x12\x18\n" +
+	"\apattern\x18\x01 \x01(\tR\apattern\x123\n" +
+	"\x06events\x18\x02 \x03(\x0e2\x1b.commands.KeyspaceEventTypeR\x06events\"l\n" +
+	"\rKeyspaceEvent\x12/\n" +
+	"\x04type\x18\x01 \x01(\x0e2\x1b.commands.KeyspaceEventTypeR\x04type\x12\x10\n" +
+	"\x03key\x18\x02 \x01(\tR\x03key\x12\x18\n" +
+	"\adropped\x18\x03 \x01(\x04R\adropped*\x9b\x01\n" +
+	"\x11KeyspaceEventType\x12\x1e\n" +
+	"\x1aKEYSPACE_EVENT_UNSPECIFIED\x10\x00\x12\x16\n" +
+	"\x12KEYSPACE_EVENT_SET\x10\x01\x12\x16\n" +
+	"\x12KEYSPACE_EVENT_DEL\x10\x02\x12\x1a\n" +
+	"\x16KEYSPACE_EVENT_EXPIRED\x10\x03\x12\x1a\n" +
+	"\x16KEYSPACE_EVENT_EVICTED\x10\x042\xab\x02\n" +
+	"\x06PubSub\x12>\n" +
+	"\aPublish\x12\x18.commands.PublishRequest\x1a\x19.commands.PublishResponse\x12I\n" +
+	"\tSubscribe\x12!.commands.ChannelSubscribeRequest\x1a\x17.commands.PubSubMessage0\x01\x12J\n" +
+	"\n" +
+	"PSubscribe\x12!.commands.PatternSubscribeRequest\x1a\x17.commands.PubSubMessage0\x01\x12J\n" +
+	"\rWatchKeyspace\x12\x1e.commands.WatchKeyspaceRequest\x1a\x17.commands.KeyspaceEvent0\x01B\x15Z\x13memorabilia/api;apib\x06proto3"
+
+var (
+	file_api_pubsub_proto_rawDescOnce sync.Once
+	file_api_pubsub_proto_rawDescData []byte
+)
+
+func file_api_pubsub_proto_rawDescGZIP() []byte {
+	file_api_pubsub_proto_rawDescOnce.Do(func() {
+		file_api_pubsub_proto_rawDescData = protoimpl.X.CompressGZIP(unsafe.Slice(unsafe.StringData(file_api_pubsub_proto_rawDesc), len(file_api_pubsub_proto_rawDesc)))
+	})
+	return file_api_pubsub_proto_rawDescData
+}
+
+var file_api_pubsub_proto_enumTypes = make([]protoimpl.EnumInfo, 1)
+var file_api_pubsub_proto_msgTypes = make([]protoimpl.MessageInfo, 7)
+var file_api_pubsub_proto_goTypes = []any{
+	(KeyspaceEventType)(0),          // 0: commands.KeyspaceEventType
+	(*PublishRequest)(nil),          // 1: commands.PublishRequest
+	(*PublishResponse)(nil),         // 2: commands.PublishResponse
+	(*ChannelSubscribeRequest)(nil), // 3: commands.ChannelSubscribeRequest
+	(*PatternSubscribeRequest)(nil), // 4: commands.PatternSubscribeRequest
+	(*PubSubMessage)(nil),           // 5: commands.PubSubMessage
+	(*WatchKeyspaceRequest)(nil),    // 6: commands.WatchKeyspaceRequest
+	(*KeyspaceEvent)(nil),           // 7: commands.KeyspaceEvent
+}
+var file_api_pubsub_proto_depIdxs = []int32{
+	0, // 0: commands.WatchKeyspaceRequest.events:type_name -> commands.KeyspaceEventType
+	0, // 1: commands.KeyspaceEvent.type:type_name -> commands.KeyspaceEventType
+	1, // 2: commands.PubSub.Publish:input_type -> commands.PublishRequest
+	3, // 3: commands.PubSub.Subscribe:input_type -> commands.ChannelSubscribeRequest
+	4, // 4: commands.PubSub.PSubscribe:input_type -> commands.PatternSubscribeRequest
+	6, // 5: commands.PubSub.WatchKeyspace:input_type -> commands.WatchKeyspaceRequest
+	2, // 6: commands.PubSub.Publish:output_type -> commands.PublishResponse
+	5, // 7: commands.PubSub.Subscribe:output_type -> commands.PubSubMessage
+	5, // 8: commands.PubSub.PSubscribe:output_type -> commands.PubSubMessage
+	7, // 9: commands.PubSub.WatchKeyspace:output_type -> commands.KeyspaceEvent
+	6, // [6:10] is the sub-list for method output_type
+	2, // [2:6] is the sub-list for method input_type
+	2, // [2:2] is the sub-list for extension type_name
+	2, // [2:2] is the sub-list for extension extendee
+	0, // [0:2] is the sub-list for field type_name
+}
+
+func init() { file_api_pubsub_proto_init() }
+func file_api_pubsub_proto_init() {
+	if File_api_pubsub_proto != nil {
+		return
+	}
+	type x struct{}
+	out := protoimpl.TypeBuilder{
+		File: protoimpl.DescBuilder{
+			GoPackagePath: reflect.TypeOf(x{}).PkgPath(),
+			RawDescriptor: unsafe.Slice(unsafe.StringData(file_api_pubsub_proto_rawDesc), len(file_api_pubsub_proto_rawDesc)),
+			NumEnums:      1,
+			NumMessages:   7,
+			NumExtensions: 0,
+			NumServices:   1,
+		},
+		GoTypes:           file_api_pubsub_proto_goTypes,
+		DependencyIndexes: file_api_pubsub_proto_depIdxs,
+		EnumInfos:         file_api_pubsub_proto_enumTypes,
+		MessageInfos:      file_api_pubsub_proto_msgTypes,
+	}.Build()
+	File_api_pubsub_proto = out.File
+	file_api_pubsub_proto_goTypes = nil
+	file_api_pubsub_proto_depIdxs = nil
+}
@@ -0,0 +1,273 @@
+// Code generated by protoc-gen-go-grpc. DO NOT EDIT.
+// versions:
+// - protoc-gen-go-grpc v1.5.1
+// - protoc             v7.35.0
+// source: api/pubsub.proto
+
+package api
+
+import (
+	context "context"
+	grpc "google.golang.org/grpc"
+	codes "google.golang.org/grpc/codes"
+	status "google.golang.org/grpc/status"
+)
+
+// This is a compile-time assertion to ensure that this generated file
+// is compatible with the grpc package it is being compiled against.
+// Requires gRPC-Go v1.64.0 or later.
+const _ = grpc.SupportPackageIsVersion9
+
+const (
+	PubSub_Publish_FullMethodName       = "/commands.PubSub/Publish"
+	PubSub_Subscribe_FullMethodName     = "/commands.PubSub/Subscribe"
+	PubSub_PSubscribe_FullMethodName    = "/commands.PubSub/PSubscribe"
+	PubSub_WatchKeyspace_FullMethodName = "/commands.PubSub/WatchKeyspace"
+)
+
+// PubSubClient is the client API for PubSub service.
+//
+// For semantics around ctx use and closing/ending streaming RPCs, please refer to https://pkg.go.dev/google.golang.org/grpc/?tab=doc#ClientConn.NewStream.
+//
+// PubSub is fire-and-forget messaging, like Redis' PUBLISH and SUBSCRIBE.
+// Messages aren't stored: only the subscribers connected when one is
+// published receive it.
+type PubSubClient interface {
+	// Publish sends a message to the subscribers of a channel, on every
+	// node of the cluster.
+	Publish(ctx context.Context, in *PublishRequest, opts ...grpc.CallOption) (*PublishResponse, error)
+	// Subscribe streams the messages published to any of the channels,
+	// until the client cancels it. The response headers are sent once the
+	// subscription is in place.
+	Subscribe(ctx context.Context, in *ChannelSubscribeRequest, opts ...grpc.CallOption) (grpc.ServerStreamingClient[PubSubMessage], error)
+	// PSubscribe streams the messages published to channels matching any of
+	// the glob patterns, like Subscribe.
+	PSubscribe(ctx context.Context, in *PatternSubscribeRequest, opts ...grpc.CallOption) (grpc.ServerStreamingClient[PubSubMessage], error)
+	// WatchKeyspace streams the changes to keys made on the node serving it,
+	// like Redis' keyspace notifications, until the client cancels it. The
+	// response headers are sent once the watch is in place.
+	WatchKeyspace(ctx context.Context, in *WatchKeyspaceRequest, opts ...grpc.CallOption) (grpc.ServerStreamingClient[KeyspaceEvent], error)
+}
+
+type pubSubClient struct {
+	cc grpc.ClientConnInterface
+}
+
+func NewPubSubClient(cc grpc.ClientConnInterface) PubSubClient {
+	return &pubSubClient{cc}
+}
+
+func (c *pubSubClient) Publish(ctx context.Context, in *PublishRequest, opts ...grpc.CallOption) (*PublishResponse, error) {
+	cOpts := append([]grpc.CallOption{grpc.StaticMethod()}, opts...)
+	out := new(PublishResponse)
+	err := c.cc.Invoke(ctx, PubSub_Publish_FullMethodName, in, out, cOpts...)
+	if err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+func (c *pubSubClient) Subscribe(ctx context.Context, in *ChannelSubscribeRequest, opts ...grpc.CallOption) (grpc.ServerStreamingClient[PubSubMessage], error) {
+	cOpts := append([]grpc.CallOption{grpc.StaticMethod()}, opts...)
+	stream, err := c.cc.NewStream(ctx, &PubSub_ServiceDesc.Streams[0], PubSub_Subscribe_FullMethodName, cOpts...)
+	if err != nil {
+		return nil, err
+	}
+	x := &grpc.GenericClientStream[ChannelSubscribeRequest, PubSubMessage]{ClientStream: stream}
+	if err := x.ClientStream.SendMsg(in); err != nil {
+		return nil, err
+	}
+	if err := x.ClientStream.CloseSend(); err != nil {
+		return nil, err
+	}
+	return x, nil
+}
+
+// This type alias is provided for backwards compatibility with existing code that references the prior non-generic stream type by name.
+type PubSub_SubscribeClient = grpc.ServerStreamingClient[PubSubMessage]
+
+func (c *pubSubClient) PSubscribe(ctx context.Context, in *PatternSubscribeRequest, opts ...grpc.CallOption) (grpc.ServerStreamingClient[PubSubMessage], error) {
+	cOpts := append([]grpc.CallOption{grpc.StaticMethod()}, opts...)
+	stream, err := c.cc.NewStream(ctx, &PubSub_ServiceDesc.Streams[1], PubSub_PSubscribe_FullMethodName, cOpts...)
+	if err != nil {
+		return nil, err
+	}
+	x := &grpc.GenericClientStream[PatternSubscribeRequest, PubSubMessage]{ClientStream: stream}
+	if err := x.ClientStream.SendMsg(in); err != nil {
+		return nil, err
+	}
+	if err := x.ClientStream.CloseSend(); err != nil {
+		return nil, err
+	}
+	return x, nil
+}
+
+// This type alias is provided for backwards compatibility with existing code that references the prior non-generic stream type by name.
+type PubSub_PSubscribeClient = grpc.ServerStreamingClient[PubSubMessage]
+
+func (c *pubSubClient) WatchKeyspace(ctx context.Context, in *WatchKeyspaceRequest, opts ...grpc.CallOption) (grpc.ServerStreamingClient[KeyspaceEvent], error) {
+	cOpts := append([]grpc.CallOption{grpc.StaticMethod()}, opts...)
+	stream, err := c.cc.NewStream(ctx, &PubSub_ServiceDesc.Streams[2], PubSub_WatchKeyspace_FullMethodName, cOpts...)
+	if err != nil {
+		return nil, err
+	}
+	x := &grpc.GenericClientStream[WatchKeyspaceRequest, KeyspaceEvent]{ClientStream: stream}
+	if err := x.ClientStream.SendMsg(in); err != nil {
+		return nil, err
+	}
+	if err := x.ClientStream.CloseSend(); err != nil {
+		return nil, err
+	}
+	return x, nil
+}
+
+// This type alias is provided for backwards compatibility with existing code that references the prior non-generic stream type by name.
+type PubSub_WatchKeyspaceClient = grpc.ServerStreamingClient[KeyspaceEvent]
+
+// PubSubServer is the server API for PubSub service.
+// All implementations must embed UnimplementedPubSubServer
+// for forward compatibility.
+//
+// PubSub is fire-and-forget messaging, like Redis' PUBLISH and SUBSCRIBE.
+// Messages aren't stored: only the subscribers connected when one is
+// published receive it.
+type PubSubServer interface {
+	// Publish sends a message to the subscribers of a channel, on every
+	// node of the cluster.
+	Publish(context.Context, *PublishRequest) (*PublishResponse, error)
+	// Subscribe streams the messages published to any of the channels,
+	// until the client cancels it. The response headers are sent once the
+	// subscription is in place.
+	Subscribe(*ChannelSubscribeRequest, grpc.ServerStreamingServer[PubSubMessage]) error
+	// PSubscribe streams the messages published to channels matching any of
+	// the glob patterns, like Subscribe.
+	PSubscribe(*PatternSubscribeRequest, grpc.ServerStreamingServer[PubSubMessage]) error
+	// WatchKeyspace streams the changes to keys made on the node serving it,
+	// like Redis' keyspace notifications, until the client cancels it. The
+	// response headers are sent once the watch is in place.
+	WatchKeyspace(*WatchKeyspaceRequest, grpc.ServerStreamingServer[KeyspaceEvent]) error
+	mustEmbedUnimplementedPubSubServer()
+}
+
+// UnimplementedPubSubServer must be embedded to have
+// forward compatible implementations.
+//
+// NOTE: this should be embedded by value instead of pointer to avoid a nil
+// pointer dereference when methods are called.
+type UnimplementedPubSubServer struct{}
+
+func (UnimplementedPubSubServer) Publish(context.Context, *PublishRequest) (*PublishResponse, error) {
+	return nil, status.Errorf(codes.Unimplemented, "method Publish not implemented")
+}
+func (UnimplementedPubSubServer) Subscribe(*ChannelSubscribeRequest, grpc.ServerStreamingServer[PubSubMessage]) error {
+	return status.Errorf(codes.Unimplemented, "method Subscribe not implemented")
+}
+func (UnimplementedPubSubServer) PSubscribe(*PatternSubscribeRequest, grpc.ServerStreamingServer[PubSubMessage]) error {
+	return status.Errorf(codes.Unimplemented, "method PSubscribe not implemented")
+}
+func (UnimplementedPubSubServer) WatchKeyspace(*WatchKeyspaceRequest, grpc.ServerStreamingServer[KeyspaceEvent]) error {
+	return status.Errorf(codes.Unimplemented, "method WatchKeyspace not implemented")
+}
+func (UnimplementedPubSubServer) mustEmbedUnimplementedPubSubServer() {}
+func (UnimplementedPubSubServer) testEmbeddedByValue()                {}
+
+// UnsafePubSubServer may be embedded to opt out of forward compatibility for this service.
+// Use of this interface is not recommended, as added methods to PubSubServer will
+// result in compilation errors.
+type UnsafePubSubServer interface {
+	mustEmbedUnimplementedPubSubServer()
+}
+
+func RegisterPubSubServer(s grpc.ServiceRegistrar, srv PubSubServer) {
+	// If the following call pancis, it indicates UnimplementedPubSubServer was
+	// embedded by pointer and is nil.  This will cause panics if an
+	// unimplemented method is ever invoked, so we test this at initialization
+	// time to prevent it from happening at runtime later due to I/O.
+	if t, ok := srv.(interface{ testEmbeddedByValue() }); ok {
+		t.testEmbeddedByValue()
+	}
+	s.RegisterService(&PubSub_ServiceDesc, srv)
+}
+
+func _PubSub_Publish_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
+	in := new(PublishRequest)
+	if err := dec(in); err != nil {
+		return nil, err
+	}
+	if interceptor == nil {
+		return srv.(PubSubServer).Publish(ctx, in)
+	}
+	info := &grpc.UnaryServerInfo{
+		Server:     srv,
+		FullMethod: PubSub_Publish_FullMethodName,
+	}
+	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+		return srv.(PubSubServer).Publish(ctx, req.(*PublishRequest))
+	}
+	return interceptor(ctx, in, info, handler)
+}
+
+func _PubSub_Subscribe_Handler(srv interface{}, stream grpc.ServerStream) error {
+	m := new(ChannelSubscribeRequest)
+	if err := stream.RecvMsg(m); err != nil {
+		return err
+	}
+	return srv.(PubSubServer).Subscribe(m, &grpc.GenericServerStream[ChannelSubscribeRequest, PubSubMessage]{ServerStream: stream})
+}
+
+// This type alias is provided for backwards compatibility with existing code that references the prior non-generic stream type by name.
+type PubSub_SubscribeServer = grpc.ServerStreamingServer[PubSubMessage]
+
+func _PubSub_PSubscribe_Handler(srv interface{}, stream grpc.ServerStream) error {
+	m := new(PatternSubscribeRequest)
+	if err := stream.RecvMsg(m); err != nil {
+		return err
+	}
+	return srv.(PubSubServer).PSubscribe(m, &grpc.GenericServerStream[PatternSubscribeRequest, PubSubMessage]{ServerStream: stream})
+}
+
+// This type alias is provided for backwards compatibility with existing code that references the prior non-generic stream type by name.
+type PubSub_PSubscribeServer = grpc.ServerStreamingServer[PubSubMessage]
+
+func _PubSub_WatchKeyspace_Handler(srv interface{}, stream grpc.ServerStream) error {
+	m := new(WatchKeyspaceRequest)
+	if err := stream.RecvMsg(m); err != nil {
+		return err
+	}
+	return srv.(PubSubServer).WatchKeyspace(m, &grpc.GenericServerStream[WatchKeyspaceRequest, KeyspaceEvent]{ServerStream: stream})
+}
+
+// This type alias is provided for backwards compatibility with existing code that references the prior non-generic stream type by name.
+type PubSub_WatchKeyspaceServer = grpc.ServerStreamingServer[KeyspaceEvent]
+
+// PubSub_ServiceDesc is the grpc.ServiceDesc for PubSub service.
+// It's only intended for direct use with grpc.RegisterService,
+// and not to be introspected or modified (even as a copy)
+var PubSub_ServiceDesc = grpc.ServiceDesc{
+	ServiceName: "commands.PubSub",
+	HandlerType: (*PubSubServer)(nil),
+	Methods: []grpc.MethodDesc{
+		{
+			MethodName: "Publish",
+			Handler:    _PubSub_Publish_Handler,
+		},
+	},
+	Streams: []grpc.StreamDesc{
+		{
+			StreamName:    "Subscribe",
+			Handler:       _PubSub_Subscribe_Handler,
+			ServerStreams: true,
+		},
+		{
+			StreamName:    "PSubscribe",
+			Handler:       _PubSub_PSubscribe_Handler,
+			ServerStreams: true,
+		},
+		{
+			StreamName:    "WatchKeyspace",
+			Handler:       _PubSub_WatchKeyspace_Handler,
+			ServerStreams: true,
+		},
+	},
+	Metadata: "api/pubsub.proto",
+}
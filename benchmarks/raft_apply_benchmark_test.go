@@ -0,0 +1,100 @@
+package benchmarks
+
+import (
+	"fmt"
+	"io"
+	"log/slog"
+	"net"
+	"path/filepath"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/mateenbagheri/memorabilia/pkg/cluster"
+	"github.com/mateenbagheri/memorabilia/pkg/core"
+	"github.com/mateenbagheri/memorabilia/pkg/replication"
+)
+
+// BenchmarkRaftApply measures write throughput of a 3-node in-process
+// cluster, with batching off (batch=1) and on. Writers run in parallel, as
+// concurrent gRPC clients would; batching only helps when they overlap.
+//
+//	go test ./benchmarks -run '^$' -bench RaftApply -cpu 8
+func BenchmarkRaftApply(b *testing.B) {
+	for _, size := range []int{1, 16, 64} {
+		b.Run(fmt.Sprintf("batch=%d", size), func(b *testing.B) {
+			leader := startRaftCluster(b, 3, size)
+
+			var seq atomic.Int64
+			b.SetParallelism(16)
+			b.ResetTimer()
+			b.RunParallel(func(pb *testing.PB) {
+				for pb.Next() {
+					_, err := leader.Apply(&replication.RaftCommand{
+						Op:    replication.OpSet,
+						Key:   fmt.Sprintf("key-%d", seq.Add(1)),
+						Value: "value",
+					})
+					if err != nil {
+						b.Error(err)
+						return
+					}
+				}
+			})
+		})
+	}
+}
+
+// startRaftCluster starts size nodes on loopback, bootstraps the first and
+// joins the rest to it. It returns the leader.
+func startRaftCluster(b *testing.B, size, batchSize int) *replication.Node {
+	b.Helper()
+	logger := slog.New(slog.NewTextHandler(io.Discard, nil))
+	dir := b.TempDir()
+
+	nodes := make([]*replication.Node, size)
+	addrs := make([]string, size)
+	for i := range nodes {
+		id := fmt.Sprintf("n%d", i+1)
+		addrs[i] = freeAddr(b)
+		cfg := &cluster.Config{
+			NodeID:         id,
+			RaftBindAddr:   addrs[i],
+			DataDir:        filepath.Join(dir, id),
+			Bootstrap:      i == 0,
+			ApplyBatchSize: batchSize,
+		}
+		fsm := replication.NewFSM(core.NewInMemoryCommandRepository())
+		node, err := replication.NewNode(cfg, fsm, logger)
+		if err != nil {
+			b.Fatalf("start %s: %v", id, err)
+		}
+		b.Cleanup(func() { _ = node.Shutdown() })
+		nodes[i] = node
+	}
+
+	leader := nodes[0]
+	deadline := time.Now().Add(10 * time.Second)
+	for !leader.IsLeader() {
+		if time.Now().After(deadline) {
+			b.Fatal("bootstrap node never became leader")
+		}
+		time.Sleep(10 * time.Millisecond)
+	}
+	for i := 1; i < size; i++ {
+		if err := leader.Join(nodes[i].NodeID(), addrs[i]); err != nil {
+			b.Fatalf("join %s: %v", nodes[i].NodeID(), err)
+		}
+	}
+	return leader
+}
+
+func freeAddr(b *testing.B) string {
+	b.Helper()
+	l, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		b.Fatalf("find free port: %v", err)
+	}
+	defer l.Close()
+	return l.Addr().String()
+}
@@ -7,11 +7,15 @@ import (
 	"os"
 	"path/filepath"
 	"strconv"
+	"strings"
 	"time"
 
 	"github.com/mateenbagheri/memorabilia/pkg/cluster"
 	"github.com/mateenbagheri/memorabilia/pkg/core"
+	"github.com/mateenbagheri/memorabilia/pkg/pubsub"
 	"github.com/mateenbagheri/memorabilia/pkg/replication"
+	"github.com/mateenbagheri/memorabilia/pkg/sharding"
+	"github.com/mateenbagheri/memorabilia/pkg/standby"
 	"github.com/mateenbagheri/memorabilia/server"
 )
 
@@ -26,6 +30,30 @@ const (
 	envDataDir       = "MEMORABILIA_DATA_DIR"
 	envBootstrap     = "MEMORABILIA_BOOTSTRAP"
 	envLeaderHTTP    = "MEMORABILIA_LEADER_HTTP"
+	envHTTPAdvertise = "MEMORABILIA_HTTP_ADVERTISE_ADDR"
+	envLeaveOnStop   = "MEMORABILIA_LEAVE_ON_SHUTDOWN"
+	envNonVoter      = "MEMORABILIA_NON_VOTER"
+	envGRPCAdvertise = "MEMORABILIA_GRPC_ADVERTISE_ADDR"
+	envJoinSeeds     = "MEMORABILIA_JOIN_SEEDS"
+	envJoinTimeout   = "MEMORABILIA_JOIN_TIMEOUT"
+	envBatchSize     = "MEMORABILIA_APPLY_BATCH_SIZE"
+	envBatchLinger   = "MEMORABILIA_APPLY_BATCH_LINGER"
+	envApplyTimeout  = "MEMORABILIA_APPLY_TIMEOUT"
+	envReadyMaxLag   = "MEMORABILIA_READY_MAX_LAG"
+	envClockTick     = "MEMORABILIA_CLOCK_TICK_INTERVAL"
+	envRaftStorage   = "MEMORABILIA_RAFT_STORAGE"
+	envHeartbeat     = "MEMORABILIA_RAFT_HEARTBEAT_TIMEOUT"
+	envElection      = "MEMORABILIA_RAFT_ELECTION_TIMEOUT"
+	envLease         = "MEMORABILIA_RAFT_LEASE_TIMEOUT"
+	envSnapInterval  = "MEMORABILIA_SNAPSHOT_INTERVAL"
+	envSnapThreshold = "MEMORABILIA_SNAPSHOT_THRESHOLD"
+	envTrailingLogs  = "MEMORABILIA_TRAILING_LOGS"
+	envSnapRetain    = "MEMORABILIA_SNAPSHOT_RETAIN"
+	envPortStride    = "MEMORABILIA_SHARD_PORT_STRIDE"
+	envReplicateFrom = "MEMORABILIA_REPLICATE_FROM"
+	envReplicateMgmt = "MEMORABILIA_REPLICATE_FROM_MGMT"
+	envPubSubBuffer  = "MEMORABILIA_PUBSUB_BUFFER"
+	envPubSubPolicy  = "MEMORABILIA_PUBSUB_SLOW_CONSUMER"
 
 	// Defaults
 	defaultGRPCPort     = "50051"
@@ -33,9 +61,7 @@ const (
 	defaultHTTPMgmtAddr = "0.0.0.0:8081"
 	defaultDataDir      = "./data"
 	defaultTTLCleanupMS = int64(60000)
-
-	// Raft settings
-	clusterJoinTimeout = 10 * time.Second
+	defaultJoinTimeout  = 60 * time.Second
 )
 
 func main() {
@@ -48,6 +74,14 @@ func main() {
 		envOrDefaultInt64(envTTLCleanupMS, defaultTTLCleanupMS),
 		"TTL cleanup job interval in milliseconds")
 
+	pubsubBuffer := flag.Int("pubsub-buffer",
+		int(envOrDefaultInt64(envPubSubBuffer, pubsub.DefaultBufferSize)),
+		"Messages a Pub/Sub subscriber, or events a keyspace watcher, may fall behind by before --pubsub-slow-consumer applies")
+
+	pubsubPolicy := flag.String("pubsub-slow-consumer",
+		envOrDefault(envPubSubPolicy, string(pubsub.PolicyDisconnect)),
+		"What happens to a Pub/Sub subscriber falling further behind: 'disconnect' ends its stream, 'drop' leaves messages out")
+
 	// Raft flags (only matters when --node-id is set)
 	nodeID := flag.String("node-id",
 		envOrDefault(envNodeID, ""),
@@ -63,7 +97,15 @@ func main() {
 
 	httpMgmtAddr := flag.String("http-mgmt-addr",
 		envOrDefault(envHTTPMgmtAddr, defaultHTTPMgmtAddr),
-		"HTTP management server address (/raft/join, /raft/leader, /raft/peers)")
+		"HTTP management server address (/healthz, /readyz; plus /raft/* in Raft mode)")
+
+	httpAdvertise := flag.String("http-advertise-addr",
+		envOrDefault(envHTTPAdvertise, ""),
+		"HTTP management address other nodes dial to reach this one (defaults to http-mgmt-addr, with the raft advertise host if it binds 0.0.0.0)")
+
+	grpcAdvertise := flag.String("grpc-advertise-addr",
+		envOrDefault(envGRPCAdvertise, ""),
+		"gRPC address clients dial to reach this node, sent as the leader hint when a follower rejects a write (defaults to the port, with the raft advertise host)")
 
 	dataDir := flag.String("data-dir",
 		envOrDefault(envDataDir, defaultDataDir),
@@ -77,19 +119,113 @@ func main() {
 		envOrDefault(envLeaderHTTP, ""),
 		"HTTP management address of the cluster leader to join, e.g. '127.0.0.1:8081'")
 
+	joinSeeds := flag.String("join-seeds",
+		envOrDefault(envJoinSeeds, ""),
+		"Comma-separated HTTP management addresses of existing members to join through, tried after --leader-http. Any member works; followers redirect to the leader.")
+
+	joinTimeout := flag.Duration("join-timeout",
+		envOrDefaultDuration(envJoinTimeout, defaultJoinTimeout),
+		"How long to keep retrying the join at startup before giving up")
+
+	leaveOnShutdown := flag.Bool("leave-on-shutdown",
+		envOrDefaultBool(envLeaveOnStop, false),
+		"Remove this node from the Raft configuration on graceful shutdown. Use when scaling down, not for restarts.")
+
+	nonVoter := flag.Bool("non-voter",
+		envOrDefaultBool(envNonVoter, false),
+		"Join as a non-voting read replica: replicates and serves reads, but is not part of the write quorum.")
+
+	applyBatchSize := flag.Int("apply-batch-size",
+		int(envOrDefaultInt64(envBatchSize, replication.DefaultApplyBatchSize)),
+		"Max concurrent writes the leader coalesces into one Raft log entry (1 disables batching)")
+
+	applyBatchLinger := flag.Duration("apply-batch-linger",
+		envOrDefaultDuration(envBatchLinger, 0),
+		"How long the leader waits for more writes to join a batch (0 = only batch writes already queued)")
+
+	applyTimeout := flag.Duration("apply-timeout",
+		envOrDefaultDuration(envApplyTimeout, 0),
+		"How long a write waits to be enqueued into Raft before failing [5s]")
+
+	clockTick := flag.Duration("clock-tick-interval",
+		envOrDefaultDuration(envClockTick, 0),
+		"How often an idle leader advances the cluster clock while keys, sessions or queue jobs wait to expire [1s]")
+
+	readyMaxLag := flag.Uint64("ready-max-lag",
+		uint64(envOrDefaultInt64(envReadyMaxLag, 0)),
+		"Committed entries a node may have left to apply and still report ready on /readyz")
+
+	// Raft tuning flags. 0 keeps hashicorp/raft's default, shown in brackets.
+	raftStorage := flag.String("raft-storage",
+		envOrDefault(envRaftStorage, cluster.StorageBolt),
+		"Where Raft keeps its log and snapshots: 'bolt' (files under --data-dir) or 'memory' (lost on restart; for tests and disposable caches; a restarted bootstrap node must rejoin instead of bootstrapping again)")
+
+	heartbeatTimeout := flag.Duration("raft-heartbeat-timeout",
+		envOrDefaultDuration(envHeartbeat, 0),
+		"How long a follower goes without hearing from the leader before starting an election [1s]")
+
+	electionTimeout := flag.Duration("raft-election-timeout",
+		envOrDefaultDuration(envElection, 0),
+		"How long a candidate waits for votes before retrying the election [1s]")
+
+	leaseTimeout := flag.Duration("raft-lease-timeout",
+		envOrDefaultDuration(envLease, 0),
+		"How long a leader stays leader without reaching a quorum [500ms]")
+
+	snapshotInterval := flag.Duration("snapshot-interval",
+		envOrDefaultDuration(envSnapInterval, 0),
+		"How often Raft checks whether a snapshot is due [2m]")
+
+	snapshotThreshold := flag.Uint64("snapshot-threshold",
+		uint64(envOrDefaultInt64(envSnapThreshold, 0)),
+		"Log entries since the last snapshot that make a new one due [8192]")
+
+	trailingLogs := flag.Uint64("trailing-logs",
+		uint64(envOrDefaultInt64(envTrailingLogs, 0)),
+		"Log entries kept after a snapshot for lagging followers [10240]")
+
+	snapshotRetain := flag.Int("snapshot-retain",
+		int(envOrDefaultInt64(envSnapRetain, 0)),
+		"Snapshots kept on disk [3]")
+
+	shardPortStride := flag.Int("shard-port-stride",
+		int(envOrDefaultInt64(envPortStride, sharding.DefaultPortStride)),
+		"Distance between the Raft ports of a node's shard groups: shard N listens N*stride ports above --raft-addr. Only read when the first shard is added.")
+
+	// Standby flags (Raft mode only)
+	replicateFrom := flag.String("replicate-from",
+		envOrDefault(envReplicateFrom, ""),
+		"gRPC address of a node of the primary cluster to follow; makes this cluster a standby of it")
+
+	replicateFromMgmt := flag.String("replicate-from-mgmt",
+		envOrDefault(envReplicateMgmt, ""),
+		"HTTP management address of a node of the primary cluster, to read its progress and download backups from (required with --replicate-from)")
+
 	flag.Parse()
 
 	logger := slog.New(slog.NewJSONHandler(os.Stdout, &slog.HandlerOptions{Level: slog.LevelInfo}))
 	repo := core.NewInMemoryCommandRepository()
 
+	policy, err := pubsub.ParsePolicy(*pubsubPolicy)
+	if err != nil {
+		logger.Error("invalid --pubsub-slow-consumer", slog.String("error", err.Error()))
+		os.Exit(1)
+	}
+
 	// Single node mode
 	if *nodeID == "" {
+		if *replicateFrom != "" || *replicateFromMgmt != "" {
+			logger.Error("--replicate-from needs Raft mode: set --node-id")
+			os.Exit(1)
+		}
 		logger.Info("no --node-id / MEMORABILIA_NODE_ID provided, starting in single-node mode (no replication)")
 		srv := server.New(
 			server.WithPort(*grpcPort),
 			server.WithLogger(logger),
 			server.WithCommandsRepository(repo),
+			server.WithHTTPMgmtAddr(*httpMgmtAddr),
 			server.WithTTLCleanupTime(*ttlCleanupMs),
+			server.WithPubSub(*pubsubBuffer, policy),
 		)
 		srv.Start()
 		return
@@ -97,13 +233,35 @@ func main() {
 
 	// Raft mode
 	cfg := &cluster.Config{
-		NodeID:         *nodeID,
-		RaftBindAddr:   *raftAddr,
-		AdvertiseAddr:  *advertiseAddr,
-		HTTPMgmtAddr:   *httpMgmtAddr,
-		DataDir:        filepath.Join(*dataDir, *nodeID),
-		Bootstrap:      *bootstrap,
-		LeaderHTTPAddr: *leaderHTTP,
+		NodeID:           *nodeID,
+		RaftBindAddr:     *raftAddr,
+		AdvertiseAddr:    *advertiseAddr,
+		HTTPMgmtAddr:     *httpMgmtAddr,
+		HTTPAdvertise:    *httpAdvertise,
+		GRPCAddr:         ":" + *grpcPort,
+		GRPCAdvertise:    *grpcAdvertise,
+		DataDir:          filepath.Join(*dataDir, *nodeID),
+		Bootstrap:        *bootstrap,
+		LeaderHTTPAddr:   *leaderHTTP,
+		JoinSeeds:        splitList(*joinSeeds),
+		LeaveOnShutdown:  *leaveOnShutdown,
+		NonVoter:         *nonVoter,
+		ApplyBatchSize:   *applyBatchSize,
+		ApplyBatchLinger: *applyBatchLinger,
+		ApplyTimeout:     *applyTimeout,
+		ReadyMaxLag:      *readyMaxLag,
+
+		ClockTickInterval: *clockTick,
+
+		Storage:            *raftStorage,
+		HeartbeatTimeout:   *heartbeatTimeout,
+		ElectionTimeout:    *electionTimeout,
+		LeaderLeaseTimeout: *leaseTimeout,
+		SnapshotInterval:   *snapshotInterval,
+		SnapshotThreshold:  *snapshotThreshold,
+		TrailingLogs:       *trailingLogs,
+		SnapshotRetain:     *snapshotRetain,
+		ShardPortStride:    *shardPortStride,
 	}
 
 	fsm := replication.NewFSM(repo)
@@ -115,25 +273,58 @@ func main() {
 	}
 
 	// Non-bootstrap nodes register with the cluster before serving traffic.
-	if !cfg.Bootstrap && cfg.LeaderHTTPAddr != "" {
-		ctx, cancel := context.WithTimeout(context.Background(), clusterJoinTimeout)
-		defer cancel()
-		logger.Info("joining cluster via leader", slog.String("leaderHTTP", cfg.LeaderHTTPAddr))
-		if err := raftNode.JoinViaLeader(ctx, cfg.LeaderHTTPAddr, cfg.NodeID, cfg.RaftBindAddr); err != nil {
+	// This runs on every start, not just the first: joining is idempotent, and
+	// it lets a node that comes back under a new address update its entry.
+	if seeds := cfg.Seeds(); !cfg.Bootstrap && len(seeds) > 0 {
+		ctx, cancel := context.WithTimeout(context.Background(), *joinTimeout)
+		logger.Info("joining cluster", slog.Any("seeds", seeds))
+		join := replication.JoinRequest{
+			NodeID:   cfg.NodeID,
+			RaftAddr: cfg.RaftAdvertiseAddr(),
+			HTTPAddr: cfg.HTTPAdvertiseAddr(),
+			GRPCAddr: cfg.GRPCAdvertiseAddr(),
+			NonVoter: cfg.NonVoter,
+		}
+		err := raftNode.JoinCluster(ctx, seeds, join)
+		cancel()
+		if err != nil {
 			logger.Error("failed to join cluster", slog.String("error", err.Error()))
 			os.Exit(1)
 		}
 		logger.Info("successfully joined cluster")
 	}
 
-	srv := server.New(
+	// Shard groups other than the metadata group get a fresh store each and
+	// fill it from their own Raft log.
+	shardHost := replication.NewShardHost(raftNode, func() core.CommandsRepository {
+		return core.NewInMemoryCommandRepository()
+	})
+
+	options := []server.Option{
 		server.WithPort(*grpcPort),
 		server.WithLogger(logger),
 		server.WithCommandsRepository(repo),
 		server.WithRaft(raftNode, fsm, cfg),
+		server.WithShards(shardHost),
 		server.WithHTTPMgmtAddr(*httpMgmtAddr),
 		server.WithTTLCleanupTime(*ttlCleanupMs),
-	)
+		server.WithPubSub(*pubsubBuffer, policy),
+	}
+
+	if *replicateFrom != "" || *replicateFromMgmt != "" {
+		agent, err := standby.NewAgent(standby.Config{
+			PrimaryAddr:     *replicateFrom,
+			PrimaryMgmtAddr: *replicateFromMgmt,
+		}, raftNode, fsm, logger)
+		if err != nil {
+			logger.Error("failed to create standby agent", slog.String("error", err.Error()))
+			os.Exit(1)
+		}
+		logger.Info("running as a standby", slog.String("primary", *replicateFrom))
+		options = append(options, server.WithStandby(agent))
+	}
+
+	srv := server.New(options...)
 	srv.Start()
 }
 
@@ -159,6 +350,18 @@ func envOrDefaultBool(key string, def bool) bool {
 	return b
 }
 
+func envOrDefaultDuration(key string, def time.Duration) time.Duration {
+	v := os.Getenv(key)
+	if v == "" {
+		return def
+	}
+	d, err := time.ParseDuration(v)
+	if err != nil {
+		return def
+	}
+	return d
+}
+
 func envOrDefaultInt64(key string, def int64) int64 {
 	v := os.Getenv(key)
 	if v == "" {
@@ -170,3 +373,14 @@ func envOrDefaultInt64(key string, def int64) int64 {
 	}
 	return i
 }
+
+// splitList splits a comma-separated flag value, dropping empty entries.
+func splitList(s string) []string {
+	var out []string
+	for _, part := range strings.Split(s, ",") {
+		if part = strings.TrimSpace(part); part != "" {
+			out = append(out, part)
+		}
+	}
+	return out
+}
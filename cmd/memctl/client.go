@@ -0,0 +1,157 @@
+package main
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"strings"
+
+	"github.com/mateenbagheri/memorabilia/api"
+	"github.com/mateenbagheri/memorabilia/pkg/replication"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/credentials/insecure"
+)
+
+// client wraps the two ways memctl talks to a node: the gRPC data API and
+// the plain HTTP management API.
+type client struct {
+	conn     *grpc.ClientConn
+	commands api.CommandsClient
+	pubsub   api.PubSubClient
+
+	mgmtAddr string
+	http     *http.Client
+}
+
+func newClient(grpcAddr, mgmtAddr string) (*client, error) {
+	conn, err := grpc.NewClient(grpcAddr, grpc.WithTransportCredentials(insecure.NewCredentials()))
+	if err != nil {
+		return nil, fmt.Errorf("connect %q: %w", grpcAddr, err)
+	}
+	return &client{
+		conn:     conn,
+		commands: api.NewCommandsClient(conn),
+		pubsub:   api.NewPubSubClient(conn),
+		mgmtAddr: mgmtAddr,
+		http:     &http.Client{},
+	}, nil
+}
+
+func (c *client) Close() error {
+	return c.conn.Close()
+}
+
+// mgmtGet issues GET path against the management server and returns the body.
+func (c *client) mgmtGet(ctx context.Context, path string) ([]byte, error) {
+	return c.mgmtDo(ctx, http.MethodGet, path, nil)
+}
+
+// mgmtPost issues POST path with body JSON-encoded (or no body when nil).
+func (c *client) mgmtPost(ctx context.Context, path string, body any) ([]byte, error) {
+	var payload io.Reader
+	if body != nil {
+		b, err := json.Marshal(body)
+		if err != nil {
+			return nil, fmt.Errorf("encode request: %w", err)
+		}
+		payload = bytes.NewReader(b)
+	}
+	return c.mgmtDo(ctx, http.MethodPost, path, payload)
+}
+
+func (c *client) mgmtDo(ctx context.Context, method, path string, body io.Reader) ([]byte, error) {
+	resp, err := c.send(ctx, c.mgmtAddr, method, path, body)
+	if err != nil {
+		return nil, err
+	}
+	defer resp.Body.Close()
+
+	b, err := io.ReadAll(resp.Body)
+	if err != nil {
+		return nil, fmt.Errorf("%s %s: read body: %w", method, path, err)
+	}
+	return b, nil
+}
+
+// mgmtDownload issues GET path and streams the body into w, for responses
+// too large to buffer. It returns the response headers.
+func (c *client) mgmtDownload(ctx context.Context, path string, w io.Writer) (http.Header, error) {
+	resp, err := c.send(ctx, c.mgmtAddr, http.MethodGet, path, nil)
+	if err != nil {
+		return nil, err
+	}
+	defer resp.Body.Close()
+
+	if _, err := io.Copy(w, resp.Body); err != nil {
+		return nil, fmt.Errorf("GET %s: read body: %w", path, err)
+	}
+	return resp.Header, nil
+}
+
+// mgmtUpload POSTs body to a leader-only path. A follower answers 421 with
+// the leader's management address, in which case the upload is retried
+// there once.
+func (c *client) mgmtUpload(ctx context.Context, path string, body []byte) error {
+	addr := c.mgmtAddr
+	for attempt := 0; ; attempt++ {
+		resp, err := c.send(ctx, addr, http.MethodPost, path, bytes.NewReader(body))
+		var sErr *statusError
+		if errors.As(err, &sErr) && sErr.code == http.StatusMisdirectedRequest && sErr.leaderHTTP != "" && attempt == 0 {
+			addr = sErr.leaderHTTP
+			continue
+		}
+		if err != nil {
+			return err
+		}
+		resp.Body.Close()
+		return nil
+	}
+}
+
+// statusError is a non-2xx management API response.
+type statusError struct {
+	method, path string
+	status       string
+	code         int
+	body         string
+	leaderHTTP   string
+}
+
+func (e *statusError) Error() string {
+	return fmt.Sprintf("%s %s: %s: %s", e.method, e.path, e.status, e.body)
+}
+
+// send issues the request against the management server at addr. Non-2xx
+// responses are turned into a *statusError; otherwise the caller must close
+// the response body.
+func (c *client) send(ctx context.Context, addr, method, path string, body io.Reader) (*http.Response, error) {
+	req, err := http.NewRequestWithContext(ctx, method, "http://"+addr+path, body)
+	if err != nil {
+		return nil, fmt.Errorf("build request: %w", err)
+	}
+	if body != nil {
+		req.Header.Set("Content-Type", "application/json")
+	}
+
+	resp, err := c.http.Do(req)
+	if err != nil {
+		return nil, fmt.Errorf("%s %s: %w", method, path, err)
+	}
+	if resp.StatusCode/100 != 2 {
+		defer resp.Body.Close()
+		b, _ := io.ReadAll(io.LimitReader(resp.Body, 4096))
+		return nil, &statusError{
+			method:     method,
+			path:       path,
+			status:     resp.Status,
+			code:       resp.StatusCode,
+			body:       strings.TrimSpace(string(b)),
+			leaderHTTP: resp.Header.Get(replication.LeaderHTTPHeader),
+		}
+	}
+	return resp, nil
+}
@@ -0,0 +1,1977 @@
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"flag"
+	"fmt"
+	"os"
+	"path/filepath"
+	"slices"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/mateenbagheri/memorabilia/api"
+	"github.com/mateenbagheri/memorabilia/pkg/replication"
+	"github.com/mateenbagheri/memorabilia/pkg/sharding"
+	"github.com/mateenbagheri/memorabilia/pkg/standby"
+)
+
+// runFunc executes a command once its flags are parsed.
+type runFunc func(ctx context.Context, a *app, args []string) (result, error)
+
+type command struct {
+	name    string
+	usage   string
+	summary string
+
+	// minArgs and maxArgs bound the positional arguments; maxArgs < 0 means
+	// unbounded.
+	minArgs int
+	maxArgs int
+
+	// keyArg marks commands whose positional arguments are keys, so the REPL
+	// can tab-complete them.
+	keyArg bool
+
+	// streaming marks commands that print their output as it arrives, until
+	// interrupted. They run without the per-command timeout.
+	streaming bool
+
+	// blocking marks commands that may wait on the server for longer than
+	// the per-command timeout. They run without it, until they return or
+	// are interrupted.
+	blocking bool
+
+	// setup registers the command's flags on fs and returns the function
+	// that runs it. Flags are bound through closures so every invocation
+	// (one per REPL line) starts from fresh defaults.
+	setup func(fs *flag.FlagSet) runFunc
+}
+
+// commands is the full command table, in the order "help" lists them.
+var commands []command
+
+func init() {
+	commands = []command{
+		// -- data commands (gRPC) --
+		{
+			name: "get", usage: "[-max-staleness d] <key>", summary: "print the value stored at key",
+			minArgs: 1, maxArgs: 1, keyArg: true,
+			setup: setupGet,
+		},
+		{
+			name: "set", usage: "[-ttl duration] [-session id] <key> <value>", summary: "store value at key",
+			minArgs: 2, maxArgs: 2, keyArg: true,
+			setup: setupSet,
+		},
+		{
+			name: "del", usage: "<key> [key...]", summary: "delete one or more keys",
+			minArgs: 1, maxArgs: -1, keyArg: true,
+			setup: noFlags(runDel),
+		},
+		{
+			name: "scan", usage: "[-count n] [-max-staleness d] [pattern]", summary: "list keys matching a glob pattern",
+			minArgs: 0, maxArgs: 1,
+			setup: setupScan,
+		},
+		{
+			name: "ttl", usage: "[-max-staleness d] <key>", summary: "print the remaining time to live of key",
+			minArgs: 1, maxArgs: 1, keyArg: true,
+			setup: setupTTL,
+		},
+		{
+			name: "subscribe", usage: "[-from index] [-prefix p] [-shard id]", summary: "print committed writes as they happen, until interrupted",
+			minArgs: 0, maxArgs: 0, streaming: true,
+			setup: setupSubscribe,
+		},
+		{
+			name: "watch", usage: "[-prefix] [-rev n] [-shard id] <key>", summary: "print the changes to a key or prefix as they happen, until interrupted",
+			minArgs: 1, maxArgs: 1, streaming: true,
+			setup: setupWatch,
+		},
+		{
+			name: "lpush", usage: "<key> <value> [value...]", summary: "add values to the head of a list",
+			minArgs: 2, maxArgs: -1, keyArg: true,
+			setup: noFlags(push(true)),
+		},
+		{
+			name: "rpush", usage: "<key> <value> [value...]", summary: "add values to the tail of a list",
+			minArgs: 2, maxArgs: -1, keyArg: true,
+			setup: noFlags(push(false)),
+		},
+		{
+			name: "lpop", usage: "<key>", summary: "remove and print the first element of a list",
+			minArgs: 1, maxArgs: 1, keyArg: true,
+			setup: noFlags(pop(true)),
+		},
+		{
+			name: "rpop", usage: "<key>", summary: "remove and print the last element of a list",
+			minArgs: 1, maxArgs: 1, keyArg: true,
+			setup: noFlags(pop(false)),
+		},
+		{
+			name: "blpop", usage: "[-wait d] <key> [key...]", summary: "lpop from the first non-empty list, waiting for an element",
+			minArgs: 1, maxArgs: -1, keyArg: true, blocking: true,
+			setup: blockingPop(true),
+		},
+		{
+			name: "brpop", usage: "[-wait d] <key> [key...]", summary: "rpop from the first non-empty list, waiting for an element",
+			minArgs: 1, maxArgs: -1, keyArg: true, blocking: true,
+			setup: blockingPop(false),
+		},
+		{
+			name: "llen", usage: "[-max-staleness d] <key>", summary: "print the length of a list",
+			minArgs: 1, maxArgs: 1, keyArg: true,
+			setup: setupLLen,
+		},
+		{
+			name: "qpop", usage: "[-visibility d] [-wait d] <key>", summary: "pop a job from a reliable queue",
+			minArgs: 1, maxArgs: 1, keyArg: true, blocking: true,
+			setup: setupQueuePop,
+		},
+		{
+			name: "ack", usage: "<key> <job-id>", summary: "acknowledge a job popped with qpop",
+			minArgs: 2, maxArgs: 2, keyArg: true,
+			setup: noFlags(finishJob(true)),
+		},
+		{
+			name: "nack", usage: "<key> <job-id>", summary: "put a job popped with qpop back at the head of its queue",
+			minArgs: 2, maxArgs: 2, keyArg: true,
+			setup: noFlags(finishJob(false)),
+		},
+		{
+			name: "lock", usage: "[-lease d] [-wait d] <key> <session>", summary: "acquire a lock and print its fencing token",
+			minArgs: 2, maxArgs: 2, keyArg: true, blocking: true,
+			setup: setupLock,
+		},
+		{
+			name: "unlock", usage: "<key> <session> <token>", summary: "release a lock",
+			minArgs: 3, maxArgs: 3, keyArg: true,
+			setup: noFlags(runUnlock),
+		},
+		{
+			name: "refresh-lock", usage: "[-lease d] <key> <session> <token>", summary: "renew the lease of a lock",
+			minArgs: 3, maxArgs: 3, keyArg: true,
+			setup: setupRefreshLock,
+		},
+		{
+			name: "session", usage: "[-ttl d] [id]", summary: "create a session and keep it alive until interrupted, then close it",
+			minArgs: 0, maxArgs: 1, streaming: true,
+			setup: setupSession,
+		},
+		{
+			name: "keepalive", usage: "<id>", summary: "keep a session alive for another TTL",
+			minArgs: 1, maxArgs: 1,
+			setup: noFlags(runKeepAlive),
+		},
+		{
+			name: "close-session", usage: "<id>", summary: "close a session and delete its ephemeral keys",
+			minArgs: 1, maxArgs: 1,
+			setup: noFlags(runCloseSession),
+		},
+		{
+			name: "xadd", usage: "[-id id] [-maxlen n] [-minid id] <key> <field> <value> [field value...]", summary: "append an entry to a stream",
+			minArgs: 3, maxArgs: -1, keyArg: true,
+			setup: setupXAdd,
+		},
+		{
+			name: "xrange", usage: "[-count n] [-rev] [-max-staleness d] <key> [start] [end]", summary: "print the entries of a stream from start to end (default - +)",
+			minArgs: 1, maxArgs: 3, keyArg: true,
+			setup: setupXRange,
+		},
+		{
+			name: "xlen", usage: "[-max-staleness d] <key>", summary: "print the number of entries in a stream",
+			minArgs: 1, maxArgs: 1, keyArg: true,
+			setup: setupXLen,
+		},
+		{
+			name: "xtrim", usage: "[-maxlen n] [-minid id] <key>", summary: "drop the oldest entries of a stream",
+			minArgs: 1, maxArgs: 1, keyArg: true,
+			setup: setupXTrim,
+		},
+		{
+			name: "xgroup-create", usage: "[-start id] <key> <group>", summary: "create a consumer group of a stream",
+			minArgs: 2, maxArgs: 2, keyArg: true,
+			setup: setupXGroupCreate,
+		},
+		{
+			name: "xreadgroup", usage: "[-count n] [-after id] <key> <group> <consumer>", summary: "read new entries of a stream as a consumer of a group",
+			minArgs: 3, maxArgs: 3, keyArg: true,
+			setup: setupXReadGroup,
+		},
+		{
+			name: "xack", usage: "<key> <group> <id> [id...]", summary: "acknowledge entries pending in a consumer group",
+			minArgs: 3, maxArgs: -1, keyArg: true,
+			setup: noFlags(runXAck),
+		},
+		{
+			name: "xclaim", usage: "[-min-idle d] [-count n] <key> <group> <consumer> [id...]", summary: "hand idle pending entries over to a consumer",
+			minArgs: 3, maxArgs: -1, keyArg: true,
+			setup: setupXClaim,
+		},
+		{
+			name: "xpending", usage: "[-max-staleness d] <key> <group>", summary: "list the pending entries of a consumer group",
+			minArgs: 2, maxArgs: 2, keyArg: true,
+			setup: setupXPending,
+		},
+		{
+			name: "ratelimit", usage: "[-capacity n] [-rate r | -window d] [-cost n] <key>", summary: "consume from a token bucket, or a sliding window with -window",
+			minArgs: 1, maxArgs: 1, keyArg: true,
+			setup: setupRateLimit,
+		},
+		{
+			name: "bf-reserve", usage: "[-error-rate r] [-capacity n] <key>", summary: "create a Bloom filter sized for capacity items at an error rate",
+			minArgs: 1, maxArgs: 1, keyArg: true,
+			setup: setupBFReserve,
+		},
+		{
+			name: "bf-add", usage: "[-error-rate r] [-capacity n] <key> <item> [item...]", summary: "add items to a Bloom filter, creating it if needed",
+			minArgs: 2, maxArgs: -1, keyArg: true,
+			setup: setupBFAdd,
+		},
+		{
+			name: "bf-exists", usage: "[-max-staleness d] <key> <item> [item...]", summary: "print whether items may be in a Bloom filter",
+			minArgs: 2, maxArgs: -1, keyArg: true,
+			setup: setupBFExists,
+		},
+		{
+			name: "pfadd", usage: "<key> [item...]", summary: "add items to a HyperLogLog, creating it if needed",
+			minArgs: 1, maxArgs: -1, keyArg: true,
+			setup: noFlags(runPFAdd),
+		},
+		{
+			name: "pfcount", usage: "[-max-staleness d] <key> [key...]", summary: "estimate the number of distinct items in HyperLogLogs",
+			minArgs: 1, maxArgs: -1, keyArg: true,
+			setup: setupPFCount,
+		},
+		{
+			name: "pfmerge", usage: "<dest> [source...]", summary: "store the union of HyperLogLogs in dest",
+			minArgs: 1, maxArgs: -1, keyArg: true,
+			setup: noFlags(runPFMerge),
+		},
+		{
+			name: "setbit", usage: "<key> <offset> <0|1>", summary: "set or clear a bit of a string, printing its previous value",
+			minArgs: 3, maxArgs: 3, keyArg: true,
+			setup: noFlags(runSetBit),
+		},
+		{
+			name: "getbit", usage: "[-max-staleness d] <key> <offset>", summary: "print a bit of a string",
+			minArgs: 2, maxArgs: 2, keyArg: true,
+			setup: setupGetBit,
+		},
+		{
+			name: "bitcount", usage: "[-bit] [-max-staleness d] <key> [start end]", summary: "count the set bits of a string, within a byte or bit (-bit) range",
+			minArgs: 1, maxArgs: 3, keyArg: true,
+			setup: setupBitCount,
+		},
+		{
+			name: "bitpos", usage: "[-bit] [-max-staleness d] <key> <0|1> [start [end]]", summary: "print the offset of the first 0 or 1 bit of a string",
+			minArgs: 2, maxArgs: 4, keyArg: true,
+			setup: setupBitPos,
+		},
+		{
+			name: "bitop", usage: "<and|or|xor|not> <dest> <key> [key...]", summary: "store the bitwise operation of strings in dest",
+			minArgs: 3, maxArgs: -1,
+			setup: noFlags(runBitOp),
+		},
+		{
+			name: "bitfield", usage: "<key> [get type offset] [set type offset value] [incrby type offset increment] [overflow wrap|sat|fail]...", summary: "read and write integers packed in a string, e.g. incrby u8 #2 1",
+			minArgs: 1, maxArgs: -1, keyArg: true,
+			setup: noFlags(runBitField),
+		},
+		{
+			name: "publish", usage: "<channel> <message>", summary: "publish a message to a Pub/Sub channel",
+			minArgs: 2, maxArgs: 2,
+			setup: noFlags(runPublish),
+		},
+		{
+			name: "listen", usage: "[-pattern] <channel> [channel...]", summary: "print the messages published to channels, until interrupted",
+			minArgs: 1, maxArgs: -1, streaming: true,
+			setup: setupListen,
+		},
+		{
+			name: "watch-keyspace", usage: "[-events set,del,expired,evicted] [pattern]", summary: "print the changes to keys matching a glob pattern, until interrupted",
+			minArgs: 0, maxArgs: 1, streaming: true,
+			setup: setupWatchKeyspace,
+		},
+
+		// -- cluster commands (HTTP management) --
+		{
+			name: "status", usage: "", summary: "show the leader and cluster size",
+			minArgs: 0, maxArgs: 0,
+			setup: noFlags(runStatus),
+		},
+		{
+			name: "peers", usage: "", summary: "list every server in the Raft configuration",
+			minArgs: 0, maxArgs: 0,
+			setup: noFlags(runPeers),
+		},
+		{
+			name: "join", usage: "[-non-voter] [-http addr] <node-id> <raft-addr>", summary: "add a node to the cluster",
+			minArgs: 2, maxArgs: 2,
+			setup: setupJoin,
+		},
+		{
+			name: "remove", usage: "<node-id>", summary: "remove a node from the cluster",
+			minArgs: 1, maxArgs: 1,
+			setup: noFlags(runRemove),
+		},
+		{
+			name: "leave", usage: "", summary: "remove the node memctl talks to from the cluster",
+			minArgs: 0, maxArgs: 0,
+			setup: noFlags(runLeave),
+		},
+		{
+			name: "transfer-leadership", usage: "[node-id]", summary: "move leadership to node-id, or to the best follower",
+			minArgs: 0, maxArgs: 1,
+			setup: noFlags(runTransferLeadership),
+		},
+		{
+			name: "promote", usage: "<node-id>", summary: "make a non-voting read replica a voter",
+			minArgs: 1, maxArgs: 1,
+			setup: noFlags(memberChange("/raft/promote")),
+		},
+		{
+			name: "demote", usage: "<node-id>", summary: "make a voter a non-voting read replica",
+			minArgs: 1, maxArgs: 1,
+			setup: noFlags(memberChange("/raft/demote")),
+		},
+		{
+			name: "snapshot", usage: "", summary: "take a Raft snapshot on the node now",
+			minArgs: 0, maxArgs: 0,
+			setup: noFlags(runSnapshot),
+		},
+		{
+			name: "backup", usage: "[-shard id] <file>", summary: "download a consistent backup of the node's state, or of its replica of a shard, to file",
+			minArgs: 1, maxArgs: 1,
+			setup: setupBackup,
+		},
+		{
+			name: "restore", usage: "[-shard id] <file>", summary: "replace the whole cluster's state, or a shard's, with a backup file",
+			minArgs: 1, maxArgs: 1,
+			setup: setupRestore,
+		},
+
+		// -- shard commands (HTTP management) --
+		{
+			name: "shards", usage: "", summary: "list shards, the slots they own and the node's replicas",
+			minArgs: 0, maxArgs: 0,
+			setup: noFlags(runShards),
+		},
+		{
+			name: "shard-add", usage: "[-slots list] <shard-id> <node-id> [node-id...]", summary: "add a shard on the given nodes, optionally moving slots to it",
+			minArgs: 2, maxArgs: -1,
+			setup: setupShardAdd,
+		},
+		{
+			name: "move-slots", usage: "<slots> <shard-id>", summary: "move slots (e.g. 0-99,512) and their keys to a shard",
+			minArgs: 2, maxArgs: 2,
+			setup: noFlags(runMoveSlots),
+		},
+		{
+			name: "move-replica", usage: "<shard-id> <from-node> <to-node>", summary: "move a shard's replica to another node",
+			minArgs: 3, maxArgs: 3,
+			setup: noFlags(runMoveReplica),
+		},
+		{
+			name: "shard-remove", usage: "<shard-id>", summary: "remove a shard that owns no slots",
+			minArgs: 1, maxArgs: 1,
+			setup: noFlags(runShardRemove),
+		},
+
+		// -- standby commands (HTTP management) --
+		{
+			name: "standby", usage: "", summary: "show how far a standby node is behind its primary cluster",
+			minArgs: 0, maxArgs: 0,
+			setup: noFlags(runStandby),
+		},
+
+		{
+			name: "help", usage: "", summary: "list commands",
+			minArgs: 0, maxArgs: 0,
+			setup: noFlags(runHelp),
+		},
+	}
+}
+
+func lookupCommand(name string) (command, bool) {
+	for _, cmd := range commands {
+		if cmd.name == name {
+			return cmd, true
+		}
+	}
+	return command{}, false
+}
+
+func noFlags(run runFunc) func(*flag.FlagSet) runFunc {
+	return func(*flag.FlagSet) runFunc { return run }
+}
+
+// -- data commands --
+
+// stalenessFlag registers the -max-staleness flag shared by read commands.
+func stalenessFlag(fs *flag.FlagSet) *time.Duration {
+	return fs.Duration("max-staleness", 0, "fail instead of answering from a replica that may be older than this (0: any)")
+}
+
+func setupGet(fs *flag.FlagSet) runFunc {
+	maxStaleness := stalenessFlag(fs)
+	return func(ctx context.Context, a *app, args []string) (result, error) {
+		resp, err := a.client.commands.Get(ctx, &api.GetRequest{
+			Id:             args[0],
+			MaxStalenessMs: maxStaleness.Milliseconds(),
+		})
+		if err != nil {
+			return result{}, err
+		}
+		if raw := resp.GetRawValue(); raw != nil {
+			// Not valid UTF-8: quote it, and let JSON encode it in base64.
+			return result{
+				rows: [][]string{{strconv.Quote(string(raw))}},
+				data: map[string]any{"key": args[0], "raw_value": raw},
+			}, nil
+		}
+		return result{
+			rows: [][]string{{resp.GetValue()}},
+			data: map[string]string{"key": args[0], "value": resp.GetValue()},
+		}, nil
+	}
+}
+
+func setupSet(fs *flag.FlagSet) runFunc {
+	ttl := fs.String("ttl", "", "time to live, as a Go duration (10s) or milliseconds (10000)")
+	session := fs.String("session", "", "session the key belongs to; it is deleted when the session ends")
+	return func(ctx context.Context, a *app, args []string) (result, error) {
+		ttlMs, err := parseTTL(*ttl)
+		if err != nil {
+			return result{}, err
+		}
+		resp, err := a.client.commands.Set(ctx, &api.SetRequest{Id: args[0], Value: args[1], Ttl: ttlMs, Session: *session})
+		if err != nil {
+			return result{}, err
+		}
+		if !resp.GetHadPrevious() {
+			return okResult(), nil
+		}
+		if raw := resp.GetRawPreviousValue(); raw != nil {
+			return result{
+				rows: [][]string{{"OK"}, {fmt.Sprintf("(previous %q)", raw)}},
+				data: map[string]any{"ok": true, "raw_previous": raw},
+			}, nil
+		}
+		return result{
+			rows: [][]string{{"OK"}, {fmt.Sprintf("(previous %q)", resp.GetPreviousValue())}},
+			data: map[string]any{"ok": true, "previous": resp.GetPreviousValue()},
+		}, nil
+	}
+}
+
+func runDel(ctx context.Context, a *app, args []string) (result, error) {
+	var count int64
+	if len(args) == 1 {
+		resp, err := a.client.commands.Delete(ctx, &api.DeleteRequest{Id: args[0]})
+		if err != nil {
+			return result{}, err
+		}
+		count = resp.GetDeleteCount()
+	} else {
+		resp, err := a.client.commands.BatchDelete(ctx, &api.BatchDeleteRequest{Ids: args})
+		if err != nil {
+			return result{}, err
+		}
+		count = resp.GetDeleteCount()
+	}
+	return result{
+		rows: [][]string{{fmt.Sprintf("(deleted %d)", count)}},
+		data: map[string]int64{"deleted": count},
+	}, nil
+}
+
+func setupScan(fs *flag.FlagSet) runFunc {
+	count := fs.Int64("count", 100, "keys fetched per page")
+	maxStaleness := stalenessFlag(fs)
+	return func(ctx context.Context, a *app, args []string) (result, error) {
+		pattern := ""
+		if len(args) == 1 {
+			pattern = args[0]
+		}
+		keys, err := scanAll(ctx, a.client.commands, &api.ScanRequest{
+			Pattern:        pattern,
+			Count:          *count,
+			MaxStalenessMs: maxStaleness.Milliseconds(),
+		})
+		if err != nil {
+			return result{}, err
+		}
+		rows := make([][]string, len(keys))
+		for i, k := range keys {
+			rows[i] = []string{k}
+		}
+		return result{header: []string{"KEY"}, rows: rows, data: keys}, nil
+	}
+}
+
+// scanAll follows Scan cursors, starting from req, until the server reports
+// the scan complete.
+func scanAll(ctx context.Context, c api.CommandsClient, req *api.ScanRequest) ([]string, error) {
+	keys := []string{}
+	for {
+		resp, err := c.Scan(ctx, req)
+		if err != nil {
+			return nil, err
+		}
+		keys = append(keys, resp.GetIds()...)
+		req.Cursor = resp.GetNextCursor()
+		if req.Cursor == "" {
+			return keys, nil
+		}
+	}
+}
+
+func setupTTL(fs *flag.FlagSet) runFunc {
+	maxStaleness := stalenessFlag(fs)
+	return func(ctx context.Context, a *app, args []string) (result, error) {
+		resp, err := a.client.commands.TTL(ctx, &api.TTLRequest{
+			Id:             args[0],
+			MaxStalenessMs: maxStaleness.Milliseconds(),
+		})
+		if err != nil {
+			return result{}, err
+		}
+		shown := "no expiration"
+		if resp.GetTtl() >= 0 {
+			shown = (time.Duration(resp.GetTtl()) * time.Millisecond).String()
+		}
+		return result{
+			rows: [][]string{{shown}},
+			data: map[string]any{"key": args[0], "ttl_ms": resp.GetTtl()},
+		}, nil
+	}
+}
+
+// changeView is the JSON form of a change printed by "subscribe".
+type changeView struct {
+	Index       uint64          `json:"index"`
+	Term        uint64          `json:"term"`
+	Op          string          `json:"op"`
+	Key         string          `json:"key,omitempty"`
+	Keys        []string        `json:"keys,omitempty"`
+	Value       string          `json:"value,omitempty"`
+	RawValue    []byte          `json:"raw_value,omitempty"`
+	Entry       json.RawMessage `json:"entry,omitempty"`
+	ExpiresAtMs int64           `json:"expires_at_ms,omitempty"`
+}
+
+// valueCell returns how a value is shown in a table: value, or raw quoted
+// when it isn't valid UTF-8.
+func valueCell(value string, raw []byte) string {
+	if raw != nil {
+		return strconv.Quote(string(raw))
+	}
+	return value
+}
+
+func setupSubscribe(fs *flag.FlagSet) runFunc {
+	from := fs.Uint64("from", 0, "first log index to print (0: only new writes)")
+	prefix := fs.String("prefix", "", "only print writes to keys with this prefix")
+	shard := fs.Uint("shard", 0, "shard to follow, on a sharded cluster")
+	return func(ctx context.Context, a *app, args []string) (result, error) {
+		stream, err := a.client.commands.Subscribe(ctx, &api.SubscribeRequest{
+			FromIndex: *from,
+			KeyPrefix: *prefix,
+			Shard:     uint32(*shard),
+		})
+		if err != nil {
+			return result{}, err
+		}
+		for {
+			event, err := stream.Recv()
+			if err != nil {
+				if ctx.Err() != nil {
+					return result{}, nil
+				}
+				return result{}, err
+			}
+			view := changeView{
+				Index:       event.GetIndex(),
+				Term:        event.GetTerm(),
+				Op:          strings.ToLower(strings.TrimPrefix(event.GetOp().String(), "CHANGE_OP_")),
+				Key:         event.GetId(),
+				Keys:        event.GetIds(),
+				Value:       event.GetValue(),
+				RawValue:    event.GetRawValue(),
+				Entry:       event.GetEntry(),
+				ExpiresAtMs: event.GetExpiresAtMs(),
+			}
+			keys := view.Key
+			if len(view.Keys) > 0 {
+				keys = strings.Join(view.Keys, ",")
+			}
+			row := []string{strconv.FormatUint(view.Index, 10), view.Op, keys, valueCell(view.Value, view.RawValue) + string(view.Entry)}
+			if err := a.out.print(result{rows: [][]string{row}, data: view}); err != nil {
+				return result{}, err
+			}
+		}
+	}
+}
+
+// watchEventView is the JSON form of an event printed by "watch".
+type watchEventView struct {
+	Revision    uint64          `json:"revision"`
+	Type        string          `json:"type"`
+	Key         string          `json:"key"`
+	Value       string          `json:"value,omitempty"`
+	RawValue    []byte          `json:"raw_value,omitempty"`
+	Entry       json.RawMessage `json:"entry,omitempty"`
+	ExpiresAtMs int64           `json:"expires_at_ms,omitempty"`
+}
+
+func setupWatch(fs *flag.FlagSet) runFunc {
+	prefix := fs.Bool("prefix", false, "watch every key starting with the argument")
+	rev := fs.Uint64("rev", 0, "first revision to print (0: only new changes)")
+	shard := fs.Uint("shard", 0, "shard a prefix watch follows, on a sharded cluster")
+	return func(ctx context.Context, a *app, args []string) (result, error) {
+		stream, err := a.client.commands.Watch(ctx)
+		if err != nil {
+			return result{}, err
+		}
+		err = stream.Send(&api.WatchRequest{Request: &api.WatchRequest_CreateRequest{CreateRequest: &api.WatchCreateRequest{
+			Key:           args[0],
+			Prefix:        *prefix,
+			StartRevision: *rev,
+			Shard:         uint32(*shard),
+		}}})
+		if err != nil {
+			return result{}, err
+		}
+		for {
+			resp, err := stream.Recv()
+			if err != nil {
+				if ctx.Err() != nil {
+					return result{}, nil
+				}
+				return result{}, err
+			}
+			if resp.GetCompactRevision() > 0 {
+				return result{}, fmt.Errorf("%s; watch again from revision %d", resp.GetCancelReason(), resp.GetCompactRevision())
+			}
+			if resp.GetCanceled() {
+				return result{}, errors.New(resp.GetCancelReason())
+			}
+			for _, event := range resp.GetEvents() {
+				view := watchEventView{
+					Revision:    event.GetRevision(),
+					Type:        strings.ToLower(strings.TrimPrefix(event.GetType().String(), "WATCH_EVENT_")),
+					Key:         event.GetKey(),
+					Value:       event.GetValue(),
+					RawValue:    event.GetRawValue(),
+					Entry:       event.GetEntry(),
+					ExpiresAtMs: event.GetExpiresAtMs(),
+				}
+				row := []string{strconv.FormatUint(view.Revision, 10), view.Type, view.Key, valueCell(view.Value, view.RawValue) + string(view.Entry)}
+				if err := a.out.print(result{rows: [][]string{row}, data: view}); err != nil {
+					return result{}, err
+				}
+			}
+		}
+	}
+}
+
+func push(left bool) runFunc {
+	return func(ctx context.Context, a *app, args []string) (result, error) {
+		req := &api.PushRequest{Id: args[0], Values: args[1:]}
+		push := a.client.commands.RPush
+		if left {
+			push = a.client.commands.LPush
+		}
+		resp, err := push(ctx, req)
+		if err != nil {
+			return result{}, err
+		}
+		return result{
+			rows: [][]string{{fmt.Sprintf("(length %d)", resp.GetLength())}},
+			data: map[string]int64{"length": resp.GetLength()},
+		}, nil
+	}
+}
+
+func pop(left bool) runFunc {
+	return func(ctx context.Context, a *app, args []string) (result, error) {
+		req := &api.PopRequest{Id: args[0]}
+		pop := a.client.commands.RPop
+		if left {
+			pop = a.client.commands.LPop
+		}
+		resp, err := pop(ctx, req)
+		if err != nil {
+			return result{}, err
+		}
+		return popResult(resp), nil
+	}
+}
+
+func blockingPop(left bool) func(fs *flag.FlagSet) runFunc {
+	return func(fs *flag.FlagSet) runFunc {
+		wait := fs.Duration("wait", 0, "how long to wait for an element (0: until interrupted)")
+		return func(ctx context.Context, a *app, args []string) (result, error) {
+			req := &api.BlockingPopRequest{Ids: args, TimeoutMs: wait.Milliseconds()}
+			pop := a.client.commands.BRPop
+			if left {
+				pop = a.client.commands.BLPop
+			}
+			resp, err := pop(ctx, req)
+			if err != nil {
+				return result{}, err
+			}
+			return popResult(resp), nil
+		}
+	}
+}
+
+func popResult(resp *api.PopResponse) result {
+	if !resp.GetFound() {
+		return result{rows: [][]string{{"(empty)"}}, data: map[string]bool{"found": false}}
+	}
+	return result{
+		header: []string{"KEY", "VALUE"},
+		rows:   [][]string{{resp.GetId(), resp.GetValue()}},
+		data:   map[string]any{"found": true, "key": resp.GetId(), "value": resp.GetValue()},
+	}
+}
+
+func setupLLen(fs *flag.FlagSet) runFunc {
+	maxStaleness := stalenessFlag(fs)
+	return func(ctx context.Context, a *app, args []string) (result, error) {
+		resp, err := a.client.commands.LLen(ctx, &api.LLenRequest{
+			Id:             args[0],
+			MaxStalenessMs: maxStaleness.Milliseconds(),
+		})
+		if err != nil {
+			return result{}, err
+		}
+		return result{
+			rows: [][]string{{strconv.FormatInt(resp.GetLength(), 10)}},
+			data: map[string]any{"key": args[0], "length": resp.GetLength()},
+		}, nil
+	}
+}
+
+func setupQueuePop(fs *flag.FlagSet) runFunc {
+	visibility := fs.Duration("visibility", 30*time.Second, "how long the job may go unacknowledged before it is handed out again")
+	wait := fs.Duration("wait", -1, "how long to wait for a job (0: until interrupted; negative: don't wait)")
+	return func(ctx context.Context, a *app, args []string) (result, error) {
+		req := &api.QueuePopRequest{Id: args[0], VisibilityTimeoutMs: visibility.Milliseconds()}
+		if *wait >= 0 {
+			req.Block = true
+			req.TimeoutMs = wait.Milliseconds()
+		}
+		resp, err := a.client.commands.QueuePop(ctx, req)
+		if err != nil {
+			return result{}, err
+		}
+		if !resp.GetFound() {
+			return result{rows: [][]string{{"(empty)"}}, data: map[string]bool{"found": false}}, nil
+		}
+		deadline := time.UnixMilli(resp.GetDeadlineMs())
+		return result{
+			header: []string{"JOB", "VALUE", "DEADLINE"},
+			rows:   [][]string{{resp.GetJobId(), resp.GetValue(), deadline.Format(time.RFC3339)}},
+			data: map[string]any{
+				"found":       true,
+				"job_id":      resp.GetJobId(),
+				"value":       resp.GetValue(),
+				"deadline_ms": resp.GetDeadlineMs(),
+			},
+		}, nil
+	}
+}
+
+func finishJob(ack bool) runFunc {
+	return func(ctx context.Context, a *app, args []string) (result, error) {
+		req := &api.QueueJobRequest{Id: args[0], JobId: args[1]}
+		finish := a.client.commands.QueueNack
+		if ack {
+			finish = a.client.commands.QueueAck
+		}
+		resp, err := finish(ctx, req)
+		if err != nil {
+			return result{}, err
+		}
+		if !resp.GetApplied() {
+			return result{
+				rows: [][]string{{"(not in flight)"}},
+				data: map[string]bool{"ok": false},
+			}, nil
+		}
+		return okResult(), nil
+	}
+}
+
+func setupLock(fs *flag.FlagSet) runFunc {
+	lease := fs.Duration("lease", 30*time.Second, "how long the lock is held unless refreshed")
+	wait := fs.Duration("wait", -1, "how long to wait for the lock (0: until interrupted; negative: don't wait)")
+	return func(ctx context.Context, a *app, args []string) (result, error) {
+		req := &api.LockRequest{Id: args[0], Session: args[1], LeaseMs: lease.Milliseconds()}
+		if *wait >= 0 {
+			req.Wait = true
+			req.TimeoutMs = wait.Milliseconds()
+		}
+		resp, err := a.client.commands.Lock(ctx, req)
+		if err != nil {
+			return result{}, err
+		}
+		expires := time.UnixMilli(resp.GetLeaseExpiresAtMs()).Format(time.RFC3339)
+		if !resp.GetAcquired() {
+			return result{
+				rows: [][]string{{fmt.Sprintf("(held by %s until %s)", resp.GetHolderSession(), expires)}},
+				data: map[string]any{
+					"acquired":            false,
+					"holder_session":      resp.GetHolderSession(),
+					"lease_expires_at_ms": resp.GetLeaseExpiresAtMs(),
+				},
+			}, nil
+		}
+		return result{
+			header: []string{"TOKEN", "EXPIRES"},
+			rows:   [][]string{{strconv.FormatUint(resp.GetFencingToken(), 10), expires}},
+			data: map[string]any{
+				"acquired":            true,
+				"fencing_token":       resp.GetFencingToken(),
+				"lease_expires_at_ms": resp.GetLeaseExpiresAtMs(),
+			},
+		}, nil
+	}
+}
+
+func runUnlock(ctx context.Context, a *app, args []string) (result, error) {
+	token, err := strconv.ParseUint(args[2], 10, 64)
+	if err != nil {
+		return result{}, fmt.Errorf("invalid token %q", args[2])
+	}
+	resp, err := a.client.commands.Unlock(ctx, &api.UnlockRequest{Id: args[0], Session: args[1], FencingToken: token})
+	if err != nil {
+		return result{}, err
+	}
+	if !resp.GetReleased() {
+		return result{rows: [][]string{{"(not held)"}}, data: map[string]bool{"ok": false}}, nil
+	}
+	return okResult(), nil
+}
+
+func setupRefreshLock(fs *flag.FlagSet) runFunc {
+	lease := fs.Duration("lease", 30*time.Second, "the new lease, from now")
+	return func(ctx context.Context, a *app, args []string) (result, error) {
+		token, err := strconv.ParseUint(args[2], 10, 64)
+		if err != nil {
+			return result{}, fmt.Errorf("invalid token %q", args[2])
+		}
+		resp, err := a.client.commands.RefreshLock(ctx, &api.RefreshLockRequest{
+			Id:           args[0],
+			Session:      args[1],
+			FencingToken: token,
+			LeaseMs:      lease.Milliseconds(),
+		})
+		if err != nil {
+			return result{}, err
+		}
+		if !resp.GetRefreshed() {
+			return result{rows: [][]string{{"(not held)"}}, data: map[string]bool{"ok": false}}, nil
+		}
+		expires := time.UnixMilli(resp.GetLeaseExpiresAtMs()).Format(time.RFC3339)
+		return result{
+			rows: [][]string{{"OK"}, {fmt.Sprintf("(expires %s)", expires)}},
+			data: map[string]any{"ok": true, "lease_expires_at_ms": resp.GetLeaseExpiresAtMs()},
+		}, nil
+	}
+}
+
+func setupSession(fs *flag.FlagSet) runFunc {
+	ttl := fs.Duration("ttl", 10*time.Second, "how long the session lives without a keepalive")
+	return func(ctx context.Context, a *app, args []string) (result, error) {
+		if ttl.Milliseconds() <= 0 {
+			return result{}, errors.New("-ttl must be at least 1ms")
+		}
+		req := &api.CreateSessionRequest{TtlMs: ttl.Milliseconds()}
+		if len(args) > 0 {
+			req.Id = args[0]
+		}
+		created, err := a.client.commands.CreateSession(ctx, req)
+		if err != nil {
+			return result{}, err
+		}
+		view := map[string]any{"id": created.GetId(), "expires_at_ms": created.GetExpiresAtMs()}
+		if err := a.out.print(result{rows: [][]string{{created.GetId()}}, data: view}); err != nil {
+			return result{}, err
+		}
+
+		// Keep alive three times per TTL, so one lost keepalive doesn't end
+		// the session.
+		ticker := time.NewTicker(*ttl / 3)
+		defer ticker.Stop()
+		for {
+			select {
+			case <-ctx.Done():
+				// Interrupted: close the session, so its keys go now rather
+				// than after its TTL.
+				closeCtx, cancel := context.WithTimeout(context.Background(), a.timeout)
+				defer cancel()
+				_, err := a.client.commands.CloseSession(closeCtx, &api.CloseSessionRequest{Id: created.GetId()})
+				return result{}, err
+			case <-ticker.C:
+				if _, err := a.client.commands.KeepAlive(ctx, &api.KeepAliveRequest{Id: created.GetId()}); err != nil && ctx.Err() == nil {
+					return result{}, err
+				}
+			}
+		}
+	}
+}
+
+func runKeepAlive(ctx context.Context, a *app, args []string) (result, error) {
+	resp, err := a.client.commands.KeepAlive(ctx, &api.KeepAliveRequest{Id: args[0]})
+	if err != nil {
+		return result{}, err
+	}
+	expires := time.UnixMilli(resp.GetExpiresAtMs()).Format(time.RFC3339)
+	return result{
+		rows: [][]string{{"OK"}, {fmt.Sprintf("(expires %s)", expires)}},
+		data: map[string]any{"ok": true, "expires_at_ms": resp.GetExpiresAtMs()},
+	}, nil
+}
+
+func runCloseSession(ctx context.Context, a *app, args []string) (result, error) {
+	resp, err := a.client.commands.CloseSession(ctx, &api.CloseSessionRequest{Id: args[0]})
+	if err != nil {
+		return result{}, err
+	}
+	if !resp.GetClosed() {
+		return result{rows: [][]string{{"(no such session)"}}, data: map[string]bool{"ok": false}}, nil
+	}
+	return result{
+		rows: [][]string{{"OK"}, {fmt.Sprintf("(%d keys deleted)", resp.GetDeleteCount())}},
+		data: map[string]any{"ok": true, "delete_count": resp.GetDeleteCount()},
+	}, nil
+}
+
+func streamTrimFlags(fs *flag.FlagSet) func() *api.StreamTrim {
+	maxLen := fs.Int64("maxlen", 0, "keep only this many newest entries (0: no limit)")
+	minID := fs.String("minid", "", "drop the entries before this ID")
+	return func() *api.StreamTrim {
+		return &api.StreamTrim{MaxLen: *maxLen, MinId: *minID}
+	}
+}
+
+func setupXAdd(fs *flag.FlagSet) runFunc {
+	id := fs.String("id", "*", "ID of the entry (*: the next one)")
+	trim := streamTrimFlags(fs)
+	return func(ctx context.Context, a *app, args []string) (result, error) {
+		if len(args[1:])%2 != 0 {
+			return result{}, errors.New("fields and values must come in pairs")
+		}
+		fields := make(map[string]string, len(args[1:])/2)
+		for i := 1; i < len(args); i += 2 {
+			fields[args[i]] = args[i+1]
+		}
+		resp, err := a.client.commands.XAdd(ctx, &api.XAddRequest{Id: args[0], EntryId: *id, Fields: fields, Trim: trim()})
+		if err != nil {
+			return result{}, err
+		}
+		return result{
+			rows: [][]string{{resp.GetEntryId()}},
+			data: map[string]string{"entry_id": resp.GetEntryId()},
+		}, nil
+	}
+}
+
+func setupXRange(fs *flag.FlagSet) runFunc {
+	count := fs.Int64("count", 0, "maximum number of entries (0: all)")
+	reverse := fs.Bool("rev", false, "print the entries from end to start")
+	maxStaleness := stalenessFlag(fs)
+	return func(ctx context.Context, a *app, args []string) (result, error) {
+		req := &api.XRangeRequest{Id: args[0], Count: *count, Reverse: *reverse, MaxStalenessMs: maxStaleness.Milliseconds()}
+		if len(args) > 1 {
+			req.Start = args[1]
+		}
+		if len(args) > 2 {
+			req.End = args[2]
+		}
+		resp, err := a.client.commands.XRange(ctx, req)
+		if err != nil {
+			return result{}, err
+		}
+		return streamEntriesResult(resp.GetEntries()), nil
+	}
+}
+
+func setupXLen(fs *flag.FlagSet) runFunc {
+	maxStaleness := stalenessFlag(fs)
+	return func(ctx context.Context, a *app, args []string) (result, error) {
+		resp, err := a.client.commands.XLen(ctx, &api.XLenRequest{Id: args[0], MaxStalenessMs: maxStaleness.Milliseconds()})
+		if err != nil {
+			return result{}, err
+		}
+		return result{
+			rows: [][]string{{strconv.FormatInt(resp.GetLength(), 10)}},
+			data: map[string]any{"key": args[0], "length": resp.GetLength()},
+		}, nil
+	}
+}
+
+func setupXTrim(fs *flag.FlagSet) runFunc {
+	trim := streamTrimFlags(fs)
+	return func(ctx context.Context, a *app, args []string) (result, error) {
+		resp, err := a.client.commands.XTrim(ctx, &api.XTrimRequest{Id: args[0], Trim: trim()})
+		if err != nil {
+			return result{}, err
+		}
+		return result{
+			rows: [][]string{{fmt.Sprintf("(%d trimmed)", resp.GetTrimmed())}},
+			data: map[string]int64{"trimmed": resp.GetTrimmed()},
+		}, nil
+	}
+}
+
+func setupXGroupCreate(fs *flag.FlagSet) runFunc {
+	start := fs.String("start", "$", "deliver the entries after this ID (0: all, $: new ones)")
+	return func(ctx context.Context, a *app, args []string) (result, error) {
+		resp, err := a.client.commands.XGroupCreate(ctx, &api.XGroupCreateRequest{Id: args[0], Group: args[1], Start: *start})
+		if err != nil {
+			return result{}, err
+		}
+		if !resp.GetCreated() {
+			return result{rows: [][]string{{"(exists)"}}, data: map[string]bool{"created": false}}, nil
+		}
+		return result{rows: [][]string{{"OK"}}, data: map[string]bool{"created": true}}, nil
+	}
+}
+
+func setupXReadGroup(fs *flag.FlagSet) runFunc {
+	count := fs.Int64("count", 0, "maximum number of entries (0: all)")
+	after := fs.String("after", ">", "read new entries (>), or the consumer's pending entries after this ID")
+	return func(ctx context.Context, a *app, args []string) (result, error) {
+		resp, err := a.client.commands.XReadGroup(ctx, &api.XReadGroupRequest{
+			Id:       args[0],
+			Group:    args[1],
+			Consumer: args[2],
+			After:    *after,
+			Count:    *count,
+		})
+		if err != nil {
+			return result{}, err
+		}
+		return streamEntriesResult(resp.GetEntries()), nil
+	}
+}
+
+func runXAck(ctx context.Context, a *app, args []string) (result, error) {
+	resp, err := a.client.commands.XAck(ctx, &api.XAckRequest{Id: args[0], Group: args[1], EntryIds: args[2:]})
+	if err != nil {
+		return result{}, err
+	}
+	return result{
+		rows: [][]string{{strconv.FormatInt(resp.GetAcked(), 10)}},
+		data: map[string]int64{"acked": resp.GetAcked()},
+	}, nil
+}
+
+func setupXClaim(fs *flag.FlagSet) runFunc {
+	minIdle := fs.Duration("min-idle", time.Minute, "claim entries pending for at least this long")
+	count := fs.Int64("count", 0, "maximum number of entries (0: all)")
+	return func(ctx context.Context, a *app, args []string) (result, error) {
+		resp, err := a.client.commands.XClaim(ctx, &api.XClaimRequest{
+			Id:        args[0],
+			Group:     args[1],
+			Consumer:  args[2],
+			MinIdleMs: minIdle.Milliseconds(),
+			EntryIds:  args[3:],
+			Count:     *count,
+		})
+		if err != nil {
+			return result{}, err
+		}
+		return streamEntriesResult(resp.GetEntries()), nil
+	}
+}
+
+func setupXPending(fs *flag.FlagSet) runFunc {
+	maxStaleness := stalenessFlag(fs)
+	return func(ctx context.Context, a *app, args []string) (result, error) {
+		resp, err := a.client.commands.XPending(ctx, &api.XPendingRequest{Id: args[0], Group: args[1], MaxStalenessMs: maxStaleness.Milliseconds()})
+		if err != nil {
+			return result{}, err
+		}
+		if len(resp.GetEntries()) == 0 {
+			return result{rows: [][]string{{"(empty)"}}, data: []any{}}, nil
+		}
+		rows := make([][]string, 0, len(resp.GetEntries()))
+		view := make([]map[string]any, 0, len(resp.GetEntries()))
+		for _, p := range resp.GetEntries() {
+			idle := time.Duration(p.GetIdleMs()) * time.Millisecond
+			rows = append(rows, []string{p.GetEntryId(), p.GetConsumer(), idle.String(), strconv.FormatInt(p.GetDeliveries(), 10)})
+			view = append(view, map[string]any{
+				"entry_id":   p.GetEntryId(),
+				"consumer":   p.GetConsumer(),
+				"idle_ms":    p.GetIdleMs(),
+				"deliveries": p.GetDeliveries(),
+			})
+		}
+		return result{header: []string{"ID", "CONSUMER", "IDLE", "DELIVERIES"}, rows: rows, data: view}, nil
+	}
+}
+
+// streamEntriesResult shows stream entries one per row, with their fields as
+// field=value pairs sorted by field.
+func streamEntriesResult(entries []*api.StreamEntry) result {
+	if len(entries) == 0 {
+		return result{rows: [][]string{{"(empty)"}}, data: []any{}}
+	}
+	rows := make([][]string, 0, len(entries))
+	view := make([]map[string]any, 0, len(entries))
+	for _, entry := range entries {
+		pairs := make([]string, 0, len(entry.GetFields()))
+		for field, value := range entry.GetFields() {
+			pairs = append(pairs, field+"="+value)
+		}
+		slices.Sort(pairs)
+		rows = append(rows, []string{entry.GetEntryId(), strings.Join(pairs, " ")})
+		view = append(view, map[string]any{"entry_id": entry.GetEntryId(), "fields": entry.GetFields()})
+	}
+	return result{header: []string{"ID", "FIELDS"}, rows: rows, data: view}
+}
+
+func setupRateLimit(fs *flag.FlagSet) runFunc {
+	capacity := fs.Int64("capacity", 10, "bucket size, or what a window lets through")
+	rate := fs.Float64("rate", 1, "tokens the bucket gains per second")
+	window := fs.Duration("window", 0, "use a sliding window of this length instead of a token bucket")
+	cost := fs.Int64("cost", 1, "how much to consume")
+	return func(ctx context.Context, a *app, args []string) (result, error) {
+		req := &api.RateLimitRequest{Id: args[0], Capacity: *capacity, RefillPerSecond: *rate, Cost: *cost}
+		if *window > 0 {
+			req.Algorithm = api.RateLimitAlgorithm_SLIDING_WINDOW
+			req.WindowMs = window.Milliseconds()
+		}
+		resp, err := a.client.commands.RateLimit(ctx, req)
+		if err != nil {
+			return result{}, err
+		}
+		view := map[string]any{
+			"allowed":        resp.GetAllowed(),
+			"remaining":      resp.GetRemaining(),
+			"retry_after_ms": resp.GetRetryAfterMs(),
+		}
+		if !resp.GetAllowed() {
+			retryAfter := time.Duration(resp.GetRetryAfterMs()) * time.Millisecond
+			return result{rows: [][]string{{fmt.Sprintf("(denied, retry after %s)", retryAfter)}}, data: view}, nil
+		}
+		return result{rows: [][]string{{"OK"}, {fmt.Sprintf("(%d remaining)", resp.GetRemaining())}}, data: view}, nil
+	}
+}
+
+func setupBFReserve(fs *flag.FlagSet) runFunc {
+	errorRate := fs.Float64("error-rate", 0.01, "false positive rate at capacity")
+	capacity := fs.Int64("capacity", 100, "number of items the filter is sized for")
+	return func(ctx context.Context, a *app, args []string) (result, error) {
+		resp, err := a.client.commands.BFReserve(ctx, &api.BFReserveRequest{Id: args[0], ErrorRate: *errorRate, Capacity: *capacity})
+		if err != nil {
+			return result{}, err
+		}
+		if !resp.GetCreated() {
+			return result{rows: [][]string{{"(exists)"}}, data: map[string]bool{"created": false}}, nil
+		}
+		return result{rows: [][]string{{"OK"}}, data: map[string]bool{"created": true}}, nil
+	}
+}
+
+func setupBFAdd(fs *flag.FlagSet) runFunc {
+	errorRate := fs.Float64("error-rate", 0.01, "false positive rate at capacity, when creating the filter")
+	capacity := fs.Int64("capacity", 100, "number of items to size the filter for, when creating it")
+	return func(ctx context.Context, a *app, args []string) (result, error) {
+		resp, err := a.client.commands.BFAdd(ctx, &api.BFAddRequest{Id: args[0], Items: args[1:], ErrorRate: *errorRate, Capacity: *capacity})
+		if err != nil {
+			return result{}, err
+		}
+		return itemFlagsResult("ADDED", "added", args[1:], resp.GetAdded()), nil
+	}
+}
+
+func setupBFExists(fs *flag.FlagSet) runFunc {
+	maxStaleness := stalenessFlag(fs)
+	return func(ctx context.Context, a *app, args []string) (result, error) {
+		resp, err := a.client.commands.BFExists(ctx, &api.BFExistsRequest{Id: args[0], Items: args[1:], MaxStalenessMs: maxStaleness.Milliseconds()})
+		if err != nil {
+			return result{}, err
+		}
+		return itemFlagsResult("EXISTS", "exists", args[1:], resp.GetExists()), nil
+	}
+}
+
+// itemFlagsResult renders a flag per item, as returned by bf-add and
+// bf-exists.
+func itemFlagsResult(column, field string, items []string, flags []bool) result {
+	rows := make([][]string, len(items))
+	view := make([]map[string]any, len(items))
+	for i, item := range items {
+		flag := i < len(flags) && flags[i]
+		rows[i] = []string{item, strconv.FormatBool(flag)}
+		view[i] = map[string]any{"item": item, field: flag}
+	}
+	return result{header: []string{"ITEM", column}, rows: rows, data: view}
+}
+
+func runPFAdd(ctx context.Context, a *app, args []string) (result, error) {
+	resp, err := a.client.commands.PFAdd(ctx, &api.PFAddRequest{Id: args[0], Items: args[1:]})
+	if err != nil {
+		return result{}, err
+	}
+	if !resp.GetChanged() {
+		return result{rows: [][]string{{"(unchanged)"}}, data: map[string]bool{"changed": false}}, nil
+	}
+	return result{rows: [][]string{{"OK"}}, data: map[string]bool{"changed": true}}, nil
+}
+
+func setupPFCount(fs *flag.FlagSet) runFunc {
+	maxStaleness := stalenessFlag(fs)
+	return func(ctx context.Context, a *app, args []string) (result, error) {
+		resp, err := a.client.commands.PFCount(ctx, &api.PFCountRequest{Ids: args, MaxStalenessMs: maxStaleness.Milliseconds()})
+		if err != nil {
+			return result{}, err
+		}
+		return result{
+			rows: [][]string{{strconv.FormatInt(resp.GetCount(), 10)}},
+			data: map[string]int64{"count": resp.GetCount()},
+		}, nil
+	}
+}
+
+func runPFMerge(ctx context.Context, a *app, args []string) (result, error) {
+	if _, err := a.client.commands.PFMerge(ctx, &api.PFMergeRequest{Dest: args[0], Sources: args[1:]}); err != nil {
+		return result{}, err
+	}
+	return result{rows: [][]string{{"OK"}}, data: map[string]bool{"ok": true}}, nil
+}
+
+func runSetBit(ctx context.Context, a *app, args []string) (result, error) {
+	offset, err := strconv.ParseInt(args[1], 10, 64)
+	if err != nil {
+		return result{}, fmt.Errorf("invalid offset %q", args[1])
+	}
+	bit, err := parseBit(args[2])
+	if err != nil {
+		return result{}, err
+	}
+	resp, err := a.client.commands.SetBit(ctx, &api.SetBitRequest{Id: args[0], Offset: offset, Value: bit})
+	if err != nil {
+		return result{}, err
+	}
+	return bitResult("previous", resp.GetPrevious()), nil
+}
+
+func setupGetBit(fs *flag.FlagSet) runFunc {
+	maxStaleness := stalenessFlag(fs)
+	return func(ctx context.Context, a *app, args []string) (result, error) {
+		offset, err := strconv.ParseInt(args[1], 10, 64)
+		if err != nil {
+			return result{}, fmt.Errorf("invalid offset %q", args[1])
+		}
+		resp, err := a.client.commands.GetBit(ctx, &api.GetBitRequest{Id: args[0], Offset: offset, MaxStalenessMs: maxStaleness.Milliseconds()})
+		if err != nil {
+			return result{}, err
+		}
+		return bitResult("value", resp.GetValue()), nil
+	}
+}
+
+// parseBit parses a bit given as 0 or 1.
+func parseBit(s string) (bool, error) {
+	switch s {
+	case "0":
+		return false, nil
+	case "1":
+		return true, nil
+	default:
+		return false, fmt.Errorf("invalid bit %q: want 0 or 1", s)
+	}
+}
+
+// bitResult renders a single bit as 0 or 1.
+func bitResult(field string, bit bool) result {
+	value := 0
+	if bit {
+		value = 1
+	}
+	return result{rows: [][]string{{strconv.Itoa(value)}}, data: map[string]int{field: value}}
+}
+
+// parseBitRange parses the optional start and end of a range, in bits when
+// bits is set. end defaults to -1, the end of the string.
+func parseBitRange(args []string, bits bool) (*api.BitRange, error) {
+	if len(args) == 0 {
+		return nil, nil
+	}
+	rng := &api.BitRange{End: -1, Bits: bits}
+	var err error
+	if rng.Start, err = strconv.ParseInt(args[0], 10, 64); err != nil {
+		return nil, fmt.Errorf("invalid start %q", args[0])
+	}
+	if len(args) > 1 {
+		if rng.End, err = strconv.ParseInt(args[1], 10, 64); err != nil {
+			return nil, fmt.Errorf("invalid end %q", args[1])
+		}
+	}
+	return rng, nil
+}
+
+func setupBitCount(fs *flag.FlagSet) runFunc {
+	bits := fs.Bool("bit", false, "count start and end in bits rather than bytes")
+	maxStaleness := stalenessFlag(fs)
+	return func(ctx context.Context, a *app, args []string) (result, error) {
+		if len(args) == 2 {
+			return result{}, errors.New("bitcount takes both start and end, or neither")
+		}
+		rng, err := parseBitRange(args[1:], *bits)
+		if err != nil {
+			return result{}, err
+		}
+		resp, err := a.client.commands.BitCount(ctx, &api.BitCountRequest{Id: args[0], Range: rng, MaxStalenessMs: maxStaleness.Milliseconds()})
+		if err != nil {
+			return result{}, err
+		}
+		return result{
+			rows: [][]string{{strconv.FormatInt(resp.GetCount(), 10)}},
+			data: map[string]int64{"count": resp.GetCount()},
+		}, nil
+	}
+}
+
+func setupBitPos(fs *flag.FlagSet) runFunc {
+	bits := fs.Bool("bit", false, "count start and end in bits rather than bytes")
+	maxStaleness := stalenessFlag(fs)
+	return func(ctx context.Context, a *app, args []string) (result, error) {
+		bit, err := parseBit(args[1])
+		if err != nil {
+			return result{}, err
+		}
+		rng, err := parseBitRange(args[2:], *bits)
+		if err != nil {
+			return result{}, err
+		}
+		resp, err := a.client.commands.BitPos(ctx, &api.BitPosRequest{Id: args[0], Bit: bit, Range: rng, MaxStalenessMs: maxStaleness.Milliseconds()})
+		if err != nil {
+			return result{}, err
+		}
+		return result{
+			rows: [][]string{{strconv.FormatInt(resp.GetPosition(), 10)}},
+			data: map[string]int64{"position": resp.GetPosition()},
+		}, nil
+	}
+}
+
+func runBitOp(ctx context.Context, a *app, args []string) (result, error) {
+	operation, ok := api.BitOperation_value["BIT_"+strings.ToUpper(args[0])]
+	if !ok {
+		return result{}, fmt.Errorf("unknown operation %q: want and, or, xor or not", args[0])
+	}
+	resp, err := a.client.commands.BitOp(ctx, &api.BitOpRequest{
+		Operation: api.BitOperation(operation),
+		Dest:      args[1],
+		Sources:   args[2:],
+	})
+	if err != nil {
+		return result{}, err
+	}
+	return result{
+		rows: [][]string{{strconv.FormatInt(resp.GetLength(), 10)}},
+		data: map[string]int64{"length": resp.GetLength()},
+	}, nil
+}
+
+func runBitField(ctx context.Context, a *app, args []string) (result, error) {
+	ops, err := parseBitFieldOps(args[1:])
+	if err != nil {
+		return result{}, err
+	}
+	resp, err := a.client.commands.BitField(ctx, &api.BitFieldRequest{Id: args[0], Ops: ops})
+	if err != nil {
+		return result{}, err
+	}
+	rows := make([][]string, len(resp.GetResults()))
+	view := make([]any, len(resp.GetResults()))
+	for i, r := range resp.GetResults() {
+		if r.GetFailed() {
+			rows[i] = []string{"(overflow)"}
+			continue
+		}
+		rows[i] = []string{strconv.FormatInt(r.GetValue(), 10)}
+		view[i] = r.GetValue()
+	}
+	return result{rows: rows, data: view}, nil
+}
+
+// parseBitFieldOps parses Redis-style BITFIELD subcommands: "get type
+// offset", "set type offset value", "incrby type offset increment" and
+// "overflow wrap|sat|fail", which applies to the subcommands after it. A type
+// is i or u followed by a width, an offset a bit offset, or an index among
+// integers of the type's width when prefixed with #.
+func parseBitFieldOps(args []string) ([]*api.BitFieldOp, error) {
+	var ops []*api.BitFieldOp
+	overflow := api.BitFieldOp_WRAP
+	for len(args) > 0 {
+		sub := strings.ToUpper(args[0])
+		if sub == "OVERFLOW" {
+			if len(args) < 2 {
+				return nil, errors.New("overflow needs wrap, sat or fail")
+			}
+			value, ok := api.BitFieldOp_Overflow_value[strings.ToUpper(args[1])]
+			if !ok {
+				return nil, fmt.Errorf("unknown overflow %q: want wrap, sat or fail", args[1])
+			}
+			overflow = api.BitFieldOp_Overflow(value)
+			args = args[2:]
+			continue
+		}
+		kind, ok := api.BitFieldOp_Kind_value[sub]
+		if !ok {
+			return nil, fmt.Errorf("unknown subcommand %q: want get, set, incrby or overflow", args[0])
+		}
+		n := 3
+		if kind != int32(api.BitFieldOp_GET) {
+			n = 4
+		}
+		if len(args) < n {
+			return nil, fmt.Errorf("%s needs %d arguments", args[0], n-1)
+		}
+		op := &api.BitFieldOp{Kind: api.BitFieldOp_Kind(kind), Overflow: overflow}
+		typ := strings.ToLower(args[1])
+		width, err := strconv.ParseInt(typ[min(1, len(typ)):], 10, 64)
+		if err != nil || (typ[0] != 'i' && typ[0] != 'u') {
+			return nil, fmt.Errorf("invalid type %q: want i or u followed by a width, as in u8", args[1])
+		}
+		op.Signed, op.Width = typ[0] == 'i', width
+		offset, inUnits := strings.CutPrefix(args[2], "#")
+		if op.Offset, err = strconv.ParseInt(offset, 10, 64); err != nil {
+			return nil, fmt.Errorf("invalid offset %q", args[2])
+		}
+		op.OffsetInUnits = inUnits
+		if n == 4 {
+			if op.Value, err = strconv.ParseInt(args[3], 10, 64); err != nil {
+				return nil, fmt.Errorf("invalid value %q", args[3])
+			}
+		}
+		ops = append(ops, op)
+		args = args[n:]
+	}
+	if len(ops) == 0 {
+		return nil, errors.New("bitfield needs a get, set or incrby")
+	}
+	return ops, nil
+}
+
+func runPublish(ctx context.Context, a *app, args []string) (result, error) {
+	resp, err := a.client.pubsub.Publish(ctx, &api.PublishRequest{Channel: args[0], Message: args[1]})
+	if err != nil {
+		return result{}, err
+	}
+	return result{
+		rows: [][]string{{strconv.FormatInt(resp.GetReceivers(), 10)}},
+		data: map[string]int64{"receivers": resp.GetReceivers()},
+	}, nil
+}
+
+// messageView is the JSON form of a message printed by "listen".
+type messageView struct {
+	Channel string `json:"channel"`
+	Pattern string `json:"pattern,omitempty"`
+	Message string `json:"message"`
+	Dropped uint64 `json:"dropped,omitempty"`
+}
+
+func setupListen(fs *flag.FlagSet) runFunc {
+	pattern := fs.Bool("pattern", false, "treat the arguments as glob patterns of channels")
+	return func(ctx context.Context, a *app, args []string) (result, error) {
+		var stream api.PubSub_SubscribeClient
+		var err error
+		if *pattern {
+			stream, err = a.client.pubsub.PSubscribe(ctx, &api.PatternSubscribeRequest{Patterns: args})
+		} else {
+			stream, err = a.client.pubsub.Subscribe(ctx, &api.ChannelSubscribeRequest{Channels: args})
+		}
+		if err != nil {
+			return result{}, err
+		}
+		for {
+			msg, err := stream.Recv()
+			if err != nil {
+				if ctx.Err() != nil {
+					return result{}, nil
+				}
+				return result{}, err
+			}
+			if msg.GetDropped() > 0 {
+				fmt.Fprintf(os.Stderr, "memctl: %d messages dropped\n", msg.GetDropped())
+			}
+			view := messageView{Channel: msg.GetChannel(), Pattern: msg.GetPattern(), Message: msg.GetMessage(), Dropped: msg.GetDropped()}
+			row := []string{view.Channel, view.Message}
+			if err := a.out.print(result{rows: [][]string{row}, data: view}); err != nil {
+				return result{}, err
+			}
+		}
+	}
+}
+
+// keyspaceEventView is the JSON form of an event printed by "watch-keyspace".
+type keyspaceEventView struct {
+	Type    string `json:"type"`
+	Key     string `json:"key"`
+	Dropped uint64 `json:"dropped,omitempty"`
+}
+
+func setupWatchKeyspace(fs *flag.FlagSet) runFunc {
+	events := fs.String("events", "", "comma-separated types of events to print (default: all)")
+	return func(ctx context.Context, a *app, args []string) (result, error) {
+		req := &api.WatchKeyspaceRequest{}
+		if len(args) > 0 {
+			req.Pattern = args[0]
+		}
+		if *events != "" {
+			for _, name := range strings.Split(*events, ",") {
+				t, ok := api.KeyspaceEventType_value["KEYSPACE_EVENT_"+strings.ToUpper(strings.TrimSpace(name))]
+				if !ok || t == 0 {
+					return result{}, fmt.Errorf("unknown event type %q", name)
+				}
+				req.Events = append(req.Events, api.KeyspaceEventType(t))
+			}
+		}
+		stream, err := a.client.pubsub.WatchKeyspace(ctx, req)
+		if err != nil {
+			return result{}, err
+		}
+		for {
+			event, err := stream.Recv()
+			if err != nil {
+				if ctx.Err() != nil {
+					return result{}, nil
+				}
+				return result{}, err
+			}
+			if event.GetDropped() > 0 {
+				fmt.Fprintf(os.Stderr, "memctl: %d events dropped\n", event.GetDropped())
+			}
+			view := keyspaceEventView{
+				Type:    strings.ToLower(strings.TrimPrefix(event.GetType().String(), "KEYSPACE_EVENT_")),
+				Key:     event.GetKey(),
+				Dropped: event.GetDropped(),
+			}
+			row := []string{view.Type, view.Key}
+			if err := a.out.print(result{rows: [][]string{row}, data: view}); err != nil {
+				return result{}, err
+			}
+		}
+	}
+}
+
+// -- cluster commands --
+
+// peer mirrors the JSON shape of cluster.Peer returned by /raft/peers.
+type peer struct {
+	ID       string `json:"ID"`
+	Address  string `json:"Address"`
+	Role     string `json:"Role"`
+	Leader   bool   `json:"Leader"`
+	HTTPAddr string `json:"HTTPAddr,omitempty"`
+	GRPCAddr string `json:"GRPCAddr,omitempty"`
+}
+
+func fetchPeers(ctx context.Context, a *app) ([]peer, error) {
+	body, err := a.client.mgmtGet(ctx, "/raft/peers")
+	if err != nil {
+		return nil, err
+	}
+	var peers []peer
+	if err := json.Unmarshal(body, &peers); err != nil {
+		return nil, fmt.Errorf("decode peers: %w", err)
+	}
+	return peers, nil
+}
+
+func fetchLeader(ctx context.Context, a *app) (string, error) {
+	body, err := a.client.mgmtGet(ctx, "/raft/leader")
+	if err != nil {
+		return "", err
+	}
+	return strings.TrimSpace(string(body)), nil
+}
+
+func runStatus(ctx context.Context, a *app, args []string) (result, error) {
+	leader, err := fetchLeader(ctx, a)
+	if err != nil {
+		return result{}, err
+	}
+	peers, err := fetchPeers(ctx, a)
+	if err != nil {
+		return result{}, err
+	}
+	return result{
+		header: []string{"LEADER", "SERVERS"},
+		rows:   [][]string{{leader, strconv.Itoa(len(peers))}},
+		data:   map[string]any{"leader": leader, "servers": len(peers)},
+	}, nil
+}
+
+func runPeers(ctx context.Context, a *app, args []string) (result, error) {
+	peers, err := fetchPeers(ctx, a)
+	if err != nil {
+		return result{}, err
+	}
+
+	rows := make([][]string, len(peers))
+	for i, p := range peers {
+		isLeader := ""
+		if p.Leader {
+			isLeader = "*"
+		}
+		rows[i] = []string{p.ID, p.Address, p.Role, isLeader, p.HTTPAddr, p.GRPCAddr}
+	}
+	return result{header: []string{"ID", "ADDRESS", "ROLE", "LEADER", "HTTP", "GRPC"}, rows: rows, data: peers}, nil
+}
+
+func setupJoin(fs *flag.FlagSet) runFunc {
+	nonVoter := fs.Bool("non-voter", false, "join as a non-voting read replica")
+	httpAddr := fs.String("http", "", "management address of the joining node, used for request forwarding")
+	return func(ctx context.Context, a *app, args []string) (result, error) {
+		_, err := a.client.mgmtPost(ctx, "/raft/join", replication.JoinRequest{
+			NodeID:   args[0],
+			RaftAddr: args[1],
+			HTTPAddr: *httpAddr,
+			NonVoter: *nonVoter,
+		})
+		if err != nil {
+			return result{}, err
+		}
+		return okResult(), nil
+	}
+}
+
+// memberChange returns a command that POSTs its node-id argument to path.
+func memberChange(path string) runFunc {
+	return func(ctx context.Context, a *app, args []string) (result, error) {
+		if _, err := a.client.mgmtPost(ctx, path, replication.MemberRequest{NodeID: args[0]}); err != nil {
+			return result{}, err
+		}
+		return okResult(), nil
+	}
+}
+
+func runRemove(ctx context.Context, a *app, args []string) (result, error) {
+	_, err := a.client.mgmtPost(ctx, "/raft/remove", replication.RemoveRequest{NodeID: args[0]})
+	if err != nil {
+		return result{}, err
+	}
+	return okResult(), nil
+}
+
+func runLeave(ctx context.Context, a *app, args []string) (result, error) {
+	_, err := a.client.mgmtPost(ctx, "/raft/leave", nil)
+	if err != nil {
+		return result{}, err
+	}
+	return okResult(), nil
+}
+
+func runTransferLeadership(ctx context.Context, a *app, args []string) (result, error) {
+	req := replication.TransferLeadershipRequest{}
+	if len(args) == 1 {
+		req.NodeID = args[0]
+	}
+	if _, err := a.client.mgmtPost(ctx, "/raft/transfer-leadership", req); err != nil {
+		return result{}, err
+	}
+	// Report where leadership landed; best-effort since the new leader may
+	// not be known to the node we asked just yet.
+	leader, _ := fetchLeader(ctx, a)
+	return result{
+		rows: [][]string{{"OK", leader}},
+		data: map[string]any{"ok": true, "leader": leader},
+	}, nil
+}
+
+func runSnapshot(ctx context.Context, a *app, args []string) (result, error) {
+	body, err := a.client.mgmtPost(ctx, "/raft/snapshot", nil)
+	if err != nil {
+		return result{}, err
+	}
+	var info replication.SnapshotInfo
+	if err := json.Unmarshal(body, &info); err != nil {
+		return result{}, fmt.Errorf("decode snapshot: %w", err)
+	}
+	return result{
+		header: []string{"ID", "INDEX", "TERM", "SIZE", "NEW"},
+		rows: [][]string{{
+			info.ID,
+			strconv.FormatUint(info.Index, 10),
+			strconv.FormatUint(info.Term, 10),
+			strconv.FormatInt(info.Size, 10),
+			strconv.FormatBool(info.Created),
+		}},
+		data: info,
+	}, nil
+}
+
+// shardFlag registers the -shard flag of backup and restore.
+func shardFlag(fs *flag.FlagSet) *string {
+	return fs.String("shard", "", "back up or restore this shard instead of the metadata group")
+}
+
+// backupRoute returns the management route for op ("backup" or "restore"):
+// the /raft one of the node's own group, or the /shards one of shard.
+func backupRoute(op, shard string) (string, error) {
+	if shard == "" {
+		return "/raft/" + op, nil
+	}
+	id, err := parseShardID(shard)
+	if err != nil {
+		return "", err
+	}
+	return fmt.Sprintf("/shards/%s?shard=%d", op, id), nil
+}
+
+// setupBackup downloads into a temporary file next to the target and renames
+// it into place, so an interrupted download never leaves a truncated backup
+// under the requested name.
+func setupBackup(fs *flag.FlagSet) runFunc {
+	shard := shardFlag(fs)
+	return func(ctx context.Context, a *app, args []string) (result, error) {
+		route, err := backupRoute("backup", *shard)
+		if err != nil {
+			return result{}, err
+		}
+		path := args[0]
+		tmp, err := os.CreateTemp(filepath.Dir(path), filepath.Base(path)+".*.tmp")
+		if err != nil {
+			return result{}, fmt.Errorf("create backup file: %w", err)
+		}
+		defer os.Remove(tmp.Name())
+
+		header, err := a.client.mgmtDownload(ctx, route, tmp)
+		if err != nil {
+			tmp.Close()
+			return result{}, err
+		}
+		if err := tmp.Close(); err != nil {
+			return result{}, fmt.Errorf("write backup file: %w", err)
+		}
+		if err := os.Rename(tmp.Name(), path); err != nil {
+			return result{}, fmt.Errorf("write backup file: %w", err)
+		}
+
+		index := header.Get(replication.SnapshotIndexHeader)
+		term := header.Get(replication.SnapshotTermHeader)
+		return result{
+			header: []string{"FILE", "INDEX", "TERM"},
+			rows:   [][]string{{path, index, term}},
+			data:   map[string]string{"file": path, "index": index, "term": term},
+		}, nil
+	}
+}
+
+func setupRestore(fs *flag.FlagSet) runFunc {
+	shard := shardFlag(fs)
+	return func(ctx context.Context, a *app, args []string) (result, error) {
+		route, err := backupRoute("restore", *shard)
+		if err != nil {
+			return result{}, err
+		}
+		backup, err := os.ReadFile(args[0])
+		if err != nil {
+			return result{}, fmt.Errorf("read backup file: %w", err)
+		}
+		if err := a.client.mgmtUpload(ctx, route, backup); err != nil {
+			return result{}, err
+		}
+		return okResult(), nil
+	}
+}
+
+// -- shard commands --
+
+// shardsView mirrors the JSON shape of server.ShardsResponse.
+type shardsView struct {
+	Version uint64 `json:"version"`
+	Shards  []struct {
+		ID        uint32   `json:"id"`
+		Members   []string `json:"members"`
+		Slots     string   `json:"slots"`
+		SlotCount int      `json:"slot_count"`
+	} `json:"shards"`
+	Migrating map[int]uint32 `json:"migrating"`
+	Local     []struct {
+		ID          uint32 `json:"id"`
+		State       string `json:"state"`
+		LeaderID    string `json:"leader_id"`
+		FrozenSlots string `json:"frozen_slots"`
+	} `json:"local"`
+}
+
+func runShards(ctx context.Context, a *app, args []string) (result, error) {
+	body, err := a.client.mgmtGet(ctx, "/shards")
+	if err != nil {
+		return result{}, err
+	}
+	var view shardsView
+	if err := json.Unmarshal(body, &view); err != nil {
+		return result{}, fmt.Errorf("decode shards: %w", err)
+	}
+
+	local := make(map[uint32]string, len(view.Local))
+	for _, l := range view.Local {
+		local[l.ID] = l.State
+	}
+	incoming := make(map[uint32]int)
+	for _, to := range view.Migrating {
+		incoming[to]++
+	}
+
+	rows := make([][]string, len(view.Shards))
+	for i, s := range view.Shards {
+		members := strings.Join(s.Members, ",")
+		if members == "" {
+			members = "(all)"
+		}
+		rows[i] = []string{
+			strconv.FormatUint(uint64(s.ID), 10),
+			members,
+			strconv.Itoa(s.SlotCount),
+			s.Slots,
+			strconv.Itoa(incoming[s.ID]),
+			local[s.ID],
+		}
+	}
+	return result{
+		header: []string{"ID", "MEMBERS", "SLOTS", "RANGES", "INCOMING", "LOCAL"},
+		rows:   rows,
+		data:   view,
+	}, nil
+}
+
+func parseShardID(s string) (sharding.ShardID, error) {
+	id, err := strconv.ParseUint(s, 10, 32)
+	if err != nil {
+		return 0, fmt.Errorf("invalid shard id %q", s)
+	}
+	return sharding.ShardID(id), nil
+}
+
+func setupShardAdd(fs *flag.FlagSet) runFunc {
+	slots := fs.String("slots", "", "slots to move to the new shard, e.g. 0-99,512")
+	return func(ctx context.Context, a *app, args []string) (result, error) {
+		id, err := parseShardID(args[0])
+		if err != nil {
+			return result{}, err
+		}
+		req := replication.AddShardRequest{ShardID: id, Members: args[1:]}
+		if *slots != "" {
+			if req.Slots, err = sharding.ParseSlots(*slots); err != nil {
+				return result{}, err
+			}
+		}
+		if _, err := a.client.mgmtPost(ctx, "/shards/add", req); err != nil {
+			return result{}, err
+		}
+		return okResult(), nil
+	}
+}
+
+func runMoveSlots(ctx context.Context, a *app, args []string) (result, error) {
+	slots, err := sharding.ParseSlots(args[0])
+	if err != nil {
+		return result{}, err
+	}
+	to, err := parseShardID(args[1])
+	if err != nil {
+		return result{}, err
+	}
+	if _, err := a.client.mgmtPost(ctx, "/shards/move-slots", replication.MoveSlotsRequest{Slots: slots, To: to}); err != nil {
+		return result{}, err
+	}
+	return okResult(), nil
+}
+
+func runMoveReplica(ctx context.Context, a *app, args []string) (result, error) {
+	id, err := parseShardID(args[0])
+	if err != nil {
+		return result{}, err
+	}
+	req := replication.MoveReplicaRequest{ShardID: id, From: args[1], To: args[2]}
+	if _, err := a.client.mgmtPost(ctx, "/shards/move-replica", req); err != nil {
+		return result{}, err
+	}
+	return okResult(), nil
+}
+
+func runShardRemove(ctx context.Context, a *app, args []string) (result, error) {
+	id, err := parseShardID(args[0])
+	if err != nil {
+		return result{}, err
+	}
+	if _, err := a.client.mgmtPost(ctx, "/shards/remove", replication.RemoveShardRequest{ShardID: id}); err != nil {
+		return result{}, err
+	}
+	return okResult(), nil
+}
+
+func runHelp(ctx context.Context, a *app, args []string) (result, error) {
+	rows := make([][]string, len(commands))
+	names := make([]string, len(commands))
+	for i, cmd := range commands {
+		rows[i] = []string{cmd.name, cmd.usage, cmd.summary}
+		names[i] = cmd.name
+	}
+	return result{header: []string{"COMMAND", "ARGS", "DESCRIPTION"}, rows: rows, data: names}, nil
+}
+
+// parseTTL accepts either a Go duration ("1m30s") or a bare number of
+// milliseconds, which is what the gRPC API takes. Empty means no expiry.
+func parseTTL(s string) (int64, error) {
+	if s == "" {
+		return 0, nil
+	}
+	if ms, err := strconv.ParseInt(s, 10, 64); err == nil {
+		return ms, nil
+	}
+	d, err := time.ParseDuration(s)
+	if err != nil {
+		return 0, fmt.Errorf("invalid ttl %q: want a duration like 10s or milliseconds", s)
+	}
+	return d.Milliseconds(), nil
+}
+
+func runStandby(ctx context.Context, a *app, args []string) (result, error) {
+	body, err := a.client.mgmtGet(ctx, "/standby")
+	if err != nil {
+		return result{}, err
+	}
+	var status standby.Status
+	if err := json.Unmarshal(body, &status); err != nil {
+		return result{}, fmt.Errorf("decode standby status: %w", err)
+	}
+
+	lag := "-"
+	if status.LagSeconds >= 0 {
+		lag = (time.Duration(status.LagSeconds * float64(time.Second))).Round(time.Millisecond).String()
+	}
+	return result{
+		header: []string{"PRIMARY", "STATE", "POSITION", "PRIMARY INDEX", "LAG ENTRIES", "LAG", "RESYNCS", "LAST ERROR"},
+		rows: [][]string{{
+			status.Primary,
+			string(status.State),
+			strconv.FormatUint(status.Position, 10),
+			strconv.FormatUint(status.PrimaryIndex, 10),
+			strconv.FormatUint(status.LagEntries, 10),
+			lag,
+			strconv.FormatUint(status.Resyncs, 10),
+			status.LastError,
+		}},
+		data: status,
+	}, nil
+}
@@ -0,0 +1,97 @@
+package main
+
+import (
+	"bytes"
+	"strings"
+	"testing"
+
+	"github.com/mateenbagheri/memorabilia/api"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestParseTTL(t *testing.T) {
+	tests := []struct {
+		in      string
+		want    int64
+		wantErr bool
+	}{
+		{"", 0, false},
+		{"1500", 1500, false},
+		{"10s", 10000, false},
+		{"1m30s", 90000, false},
+		{"soon", 0, true},
+	}
+	for _, tt := range tests {
+		got, err := parseTTL(tt.in)
+		if tt.wantErr {
+			assert.Error(t, err, "input %q", tt.in)
+			continue
+		}
+		require.NoError(t, err, "input %q", tt.in)
+		assert.Equal(t, tt.want, got, "input %q", tt.in)
+	}
+}
+
+func TestParseBitFieldOps(t *testing.T) {
+	ops, err := parseBitFieldOps(strings.Fields("get u8 0 overflow sat incrby i5 #2 -3 set u1 100 1"))
+	require.NoError(t, err)
+	require.Len(t, ops, 3)
+	assert.Equal(t, api.BitFieldOp_GET, ops[0].GetKind())
+	assert.Equal(t, api.BitFieldOp_WRAP, ops[0].GetOverflow())
+	assert.Equal(t, int64(8), ops[0].GetWidth())
+	assert.False(t, ops[0].GetSigned())
+	assert.Equal(t, api.BitFieldOp_INCRBY, ops[1].GetKind())
+	assert.Equal(t, api.BitFieldOp_SAT, ops[1].GetOverflow())
+	assert.True(t, ops[1].GetSigned())
+	assert.Equal(t, int64(2), ops[1].GetOffset())
+	assert.True(t, ops[1].GetOffsetInUnits())
+	assert.Equal(t, int64(-3), ops[1].GetValue())
+	assert.Equal(t, api.BitFieldOp_SAT, ops[2].GetOverflow())
+
+	for _, bad := range []string{"", "get x8 0", "get u8", "set u8 0", "incr u8 0 1", "overflow maybe get u8 0", "get u8 #x"} {
+		_, err := parseBitFieldOps(strings.Fields(bad))
+		assert.Error(t, err, "input %q", bad)
+	}
+}
+
+func TestPrinter(t *testing.T) {
+	res := result{
+		header: []string{"ID", "ADDRESS"},
+		rows:   [][]string{{"n1", "127.0.0.1:7001"}},
+		data:   map[string]string{"id": "n1"},
+	}
+
+	var buf bytes.Buffer
+	p, err := newPrinter(&buf, formatTable)
+	require.NoError(t, err)
+	require.NoError(t, p.print(res))
+	assert.Equal(t, "ID  ADDRESS\nn1  127.0.0.1:7001\n", buf.String())
+
+	buf.Reset()
+	p, err = newPrinter(&buf, formatJSON)
+	require.NoError(t, err)
+	require.NoError(t, p.print(res))
+	assert.JSONEq(t, `{"id":"n1"}`, buf.String())
+
+	_, err = newPrinter(&buf, "yaml")
+	assert.Error(t, err)
+}
+
+func TestCompleteWord(t *testing.T) {
+	var buf bytes.Buffer
+	a := &app{out: &printer{w: &buf, format: formatTable}}
+
+	line, pos, ok := a.completeWord("ge", "ge", []string{"get", "set"})
+	require.True(t, ok)
+	assert.Equal(t, "get ", line)
+	assert.Equal(t, len("get "), pos)
+
+	line, _, ok = a.completeWord("get user:", "user:", []string{"user:10", "user:11"})
+	require.True(t, ok)
+	assert.Equal(t, "get user:1", line)
+
+	_, _, ok = a.completeWord("get user:1", "user:1", []string{"user:10", "user:11"})
+	assert.False(t, ok, "ambiguous completion should list candidates instead")
+	assert.Contains(t, buf.String(), "user:10  user:11")
+}
@@ -0,0 +1,164 @@
+// memctl is the command-line client and admin tool for memorabilia.
+//
+// It talks to two endpoints of a node:
+//   - the gRPC port for data operations (get, set, del, scan, ttl, subscribe,
+//     watch), lists and queues (lpush, blpop, qpop, ...), locks (lock,
+//     unlock, refresh-lock), sessions (session, keepalive, close-session),
+//     streams (xadd, xrange, xreadgroup, ...), rate limiters (ratelimit),
+//     Bloom filters (bf-reserve, bf-add, bf-exists), HyperLogLogs (pfadd,
+//     pfcount, pfmerge), bitmaps (setbit, bitcount, bitop, bitfield, ...),
+//     Pub/Sub (publish, listen) and keyspace notifications (watch-keyspace)
+//   - the HTTP management port for cluster operations: membership (status,
+//     peers, join, remove, leave, transfer-leadership, promote, demote),
+//     snapshots and backups (snapshot, backup, restore), sharding (shards,
+//     shard-add, move-slots, move-replica, shard-remove) and standby
+//     replication (standby)
+//
+// Run it with a command to execute that command once, or without one to
+// start an interactive REPL:
+//
+//	memctl --addr=127.0.0.1:50051 set -ttl=10s foo bar
+//	memctl --mgmt=127.0.0.1:8081 -o json peers
+//	memctl
+package main
+
+import (
+	"context"
+	"errors"
+	"flag"
+	"fmt"
+	"os"
+	"os/signal"
+	"time"
+)
+
+const (
+	// Environment variables
+	envAddr = "MEMCTL_ADDR"
+	envMgmt = "MEMCTL_MGMT"
+
+	// Defaults
+	defaultAddr    = "127.0.0.1:50051"
+	defaultMgmt    = "127.0.0.1:8081"
+	defaultTimeout = 5 * time.Second
+)
+
+func main() {
+	addr := flag.String("addr",
+		envOrDefault(envAddr, defaultAddr),
+		"gRPC address of a memorabilia node")
+
+	mgmt := flag.String("mgmt",
+		envOrDefault(envMgmt, defaultMgmt),
+		"HTTP management address of a memorabilia node")
+
+	format := flag.String("o", string(formatTable),
+		"output format: table or json")
+
+	timeout := flag.Duration("timeout", defaultTimeout,
+		"per-command timeout")
+
+	flag.Usage = usage
+	flag.Parse()
+
+	out, err := newPrinter(os.Stdout, outputFormat(*format))
+	if err != nil {
+		fatal(err)
+	}
+
+	cl, err := newClient(*addr, *mgmt)
+	if err != nil {
+		fatal(err)
+	}
+	defer cl.Close()
+
+	app := &app{client: cl, out: out, timeout: *timeout}
+
+	if flag.NArg() == 0 {
+		if err := app.repl(); err != nil {
+			fatal(err)
+		}
+		return
+	}
+
+	if err := app.run(context.Background(), flag.Args()); err != nil {
+		if errors.Is(err, flag.ErrHelp) {
+			os.Exit(2)
+		}
+		fatal(err)
+	}
+}
+
+// app bundles everything a command needs to run, so the one-shot and REPL
+// modes share exactly the same dispatch path.
+type app struct {
+	client  *client
+	out     *printer
+	timeout time.Duration
+}
+
+// run looks up args[0] in the command table and executes it.
+func (a *app) run(ctx context.Context, args []string) error {
+	cmd, ok := lookupCommand(args[0])
+	if !ok {
+		return fmt.Errorf("unknown command %q (try \"help\")", args[0])
+	}
+
+	fs := flag.NewFlagSet(cmd.name, flag.ContinueOnError)
+	fs.SetOutput(os.Stderr)
+	fs.Usage = func() {
+		fmt.Fprintf(os.Stderr, "usage: %s %s\n", cmd.name, cmd.usage)
+		fs.PrintDefaults()
+	}
+	run := cmd.setup(fs)
+	if err := fs.Parse(args[1:]); err != nil {
+		return err
+	}
+	if fs.NArg() < cmd.minArgs || (cmd.maxArgs >= 0 && fs.NArg() > cmd.maxArgs) {
+		fs.Usage()
+		return flag.ErrHelp
+	}
+
+	if cmd.streaming {
+		ctx, stop := signal.NotifyContext(ctx, os.Interrupt)
+		defer stop()
+		_, err := run(ctx, a, fs.Args())
+		return err
+	}
+
+	var cancel context.CancelFunc
+	if cmd.blocking {
+		ctx, cancel = signal.NotifyContext(ctx, os.Interrupt)
+	} else {
+		ctx, cancel = context.WithTimeout(ctx, a.timeout)
+	}
+	defer cancel()
+
+	res, err := run(ctx, a, fs.Args())
+	if err != nil {
+		return err
+	}
+	return a.out.print(res)
+}
+
+func usage() {
+	fmt.Fprintf(os.Stderr, "usage: memctl [flags] [command [args]]\n\nflags:\n")
+	flag.PrintDefaults()
+	fmt.Fprintf(os.Stderr, "\ncommands:\n")
+	for _, cmd := range commands {
+		fmt.Fprintf(os.Stderr, "  %-20s %s\n", cmd.name, cmd.summary)
+	}
+	fmt.Fprintf(os.Stderr, "\nWithout a command, memctl starts an interactive REPL.\n")
+}
+
+func fatal(err error) {
+	fmt.Fprintln(os.Stderr, "memctl:", err)
+	os.Exit(1)
+}
+
+func envOrDefault(key, def string) string {
+	if v := os.Getenv(key); v != "" {
+		return v
+	}
+	return def
+}
@@ -0,0 +1,64 @@
+package main
+
+import (
+	"encoding/json"
+	"fmt"
+	"io"
+	"strings"
+	"text/tabwriter"
+)
+
+type outputFormat string
+
+const (
+	formatTable outputFormat = "table"
+	formatJSON  outputFormat = "json"
+)
+
+// result is what every command returns. Commands fill in both views so the
+// printer can render either without knowing anything about the command.
+type result struct {
+	// header and rows make up the table view. A result without a header is
+	// printed as bare rows, which suits single values like "get".
+	header []string
+	rows   [][]string
+
+	// data is encoded as-is in the JSON view.
+	data any
+}
+
+// okResult is returned by commands that have nothing to show on success.
+func okResult() result {
+	return result{rows: [][]string{{"OK"}}, data: map[string]bool{"ok": true}}
+}
+
+type printer struct {
+	w      io.Writer
+	format outputFormat
+}
+
+func newPrinter(w io.Writer, format outputFormat) (*printer, error) {
+	switch format {
+	case formatTable, formatJSON:
+		return &printer{w: w, format: format}, nil
+	default:
+		return nil, fmt.Errorf("unknown output format %q (want table or json)", format)
+	}
+}
+
+func (p *printer) print(res result) error {
+	if p.format == formatJSON {
+		enc := json.NewEncoder(p.w)
+		enc.SetIndent("", "  ")
+		return enc.Encode(res.data)
+	}
+
+	tw := tabwriter.NewWriter(p.w, 0, 0, 2, ' ', 0)
+	if len(res.header) > 0 {
+		fmt.Fprintln(tw, strings.Join(res.header, "\t"))
+	}
+	for _, row := range res.rows {
+		fmt.Fprintln(tw, strings.Join(row, "\t"))
+	}
+	return tw.Flush()
+}
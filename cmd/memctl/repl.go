@@ -0,0 +1,245 @@
+package main
+
+import (
+	"bufio"
+	"context"
+	"errors"
+	"flag"
+	"fmt"
+	"io"
+	"os"
+	"os/signal"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/mateenbagheri/memorabilia/api"
+	"golang.org/x/term"
+)
+
+const (
+	replPrompt = "memctl> "
+
+	// completionTimeout bounds the Scan issued when tab-completing a key, so
+	// a slow node never freezes the prompt.
+	completionTimeout = 500 * time.Millisecond
+	completionLimit   = 50
+)
+
+// repl runs an interactive session until EOF, "exit" or "quit".
+//
+// On a terminal, lines are read through x/term which provides in-session
+// history (up/down arrows) and calls complete on TAB. When stdin is not a
+// terminal (e.g. `memctl < script.txt`) lines are read plainly, so a file of
+// commands can be piped through the same dispatch path.
+func (a *app) repl() error {
+	fd := int(os.Stdin.Fd())
+	if !term.IsTerminal(fd) {
+		return a.replLoop(newPlainReader(os.Stdin))
+	}
+
+	oldState, err := term.MakeRaw(fd)
+	if err != nil {
+		return fmt.Errorf("repl: raw mode: %w", err)
+	}
+	defer term.Restore(fd, oldState)
+
+	t := term.NewTerminal(struct {
+		io.Reader
+		io.Writer
+	}{os.Stdin, os.Stdout}, replPrompt)
+	t.AutoCompleteCallback = a.complete
+
+	// Command output goes through the terminal so it is translated to
+	// CRLF while the tty is in raw mode.
+	out := *a.out
+	out.w = t
+	a.out = &out
+
+	return a.replLoop(&rawTerminal{Terminal: t, fd: fd, cooked: oldState})
+}
+
+type lineReader interface {
+	ReadLine() (string, error)
+}
+
+// rawTerminal is the x/term line editor over a tty in raw mode, in which
+// Ctrl-C is just a byte and raises no SIGINT.
+type rawTerminal struct {
+	*term.Terminal
+	fd     int
+	cooked *term.State
+}
+
+// suspend puts the tty back in the mode it had before the REPL started, so
+// Ctrl-C interrupts the command about to run, and returns the function that
+// switches to raw mode again for the next prompt.
+func (t *rawTerminal) suspend() (resume func() error, err error) {
+	if err := term.Restore(t.fd, t.cooked); err != nil {
+		return nil, fmt.Errorf("repl: restore terminal: %w", err)
+	}
+	return func() error {
+		if _, err := term.MakeRaw(t.fd); err != nil {
+			return fmt.Errorf("repl: raw mode: %w", err)
+		}
+		return nil
+	}, nil
+}
+
+func (a *app) replLoop(r lineReader) error {
+	for {
+		line, err := r.ReadLine()
+		if errors.Is(err, io.EOF) {
+			return nil
+		}
+		if err != nil {
+			return err
+		}
+
+		args := strings.Fields(line)
+		if len(args) == 0 {
+			continue
+		}
+		if args[0] == "exit" || args[0] == "quit" {
+			return nil
+		}
+
+		if err := a.runLine(r, args); err != nil && !errors.Is(err, flag.ErrHelp) {
+			fmt.Fprintf(a.out.w, "(error) %v\n", err)
+		}
+	}
+}
+
+// runLine runs one command typed at the prompt. Ctrl-C cancels it, rather
+// than ending memctl: streaming and blocking commands stop waiting, and any
+// other command gives up on its request.
+func (a *app) runLine(r lineReader, args []string) error {
+	if t, ok := r.(*rawTerminal); ok {
+		resume, err := t.suspend()
+		if err != nil {
+			return err
+		}
+		defer func() {
+			if err := resume(); err != nil {
+				fmt.Fprintf(a.out.w, "(error) %v\n", err)
+			}
+		}()
+	}
+
+	ctx, stop := signal.NotifyContext(context.Background(), os.Interrupt)
+	defer stop()
+	return a.run(ctx, args)
+}
+
+// complete is the x/term AutoCompleteCallback. It completes command names in
+// the first word and, for key-taking commands, existing keys in later words
+// by issuing a prefix Scan against the node.
+func (a *app) complete(line string, pos int, key rune) (string, int, bool) {
+	if key != '\t' || pos != len(line) {
+		return "", 0, false
+	}
+
+	words := strings.Fields(line)
+	completingNew := len(line) > 0 && line[len(line)-1] == ' '
+	if len(words) == 0 || (len(words) == 1 && !completingNew) {
+		prefix := ""
+		if len(words) == 1 {
+			prefix = words[0]
+		}
+		names := make([]string, 0, len(commands)+2)
+		for _, cmd := range commands {
+			names = append(names, cmd.name)
+		}
+		names = append(names, "exit", "quit")
+		return a.completeWord(line, prefix, names)
+	}
+
+	cmd, ok := lookupCommand(words[0])
+	if !ok || !cmd.keyArg {
+		return "", 0, false
+	}
+	prefix := ""
+	if !completingNew {
+		prefix = words[len(words)-1]
+	}
+	if strings.HasPrefix(prefix, "-") {
+		return "", 0, false
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), completionTimeout)
+	defer cancel()
+	keys, err := scanAll(ctx, a.client.commands, &api.ScanRequest{
+		Pattern: escapeGlob(prefix) + "*",
+		Count:   completionLimit,
+	})
+	if err != nil {
+		return "", 0, false
+	}
+	return a.completeWord(line, prefix, keys)
+}
+
+// completeWord replaces the trailing prefix in line with the longest common
+// prefix of the matching candidates. When that does not extend the input and
+// there are several candidates, they are listed below the prompt instead.
+func (a *app) completeWord(line, prefix string, candidates []string) (string, int, bool) {
+	var matches []string
+	for _, c := range candidates {
+		if strings.HasPrefix(c, prefix) {
+			matches = append(matches, c)
+		}
+	}
+	if len(matches) == 0 {
+		return "", 0, false
+	}
+	sort.Strings(matches)
+
+	common := matches[0]
+	for _, m := range matches[1:] {
+		for !strings.HasPrefix(m, common) {
+			common = common[:len(common)-1]
+		}
+	}
+
+	if len(matches) > 1 && common == prefix {
+		fmt.Fprintf(a.out.w, "\n%s\n", strings.Join(matches, "  "))
+		return "", 0, false
+	}
+
+	completed := line[:len(line)-len(prefix)] + common
+	if len(matches) == 1 {
+		completed += " "
+	}
+	return completed, len(completed), true
+}
+
+// escapeGlob quotes glob metacharacters so a typed prefix is matched literally.
+func escapeGlob(s string) string {
+	var b strings.Builder
+	for _, r := range s {
+		switch r {
+		case '*', '?', '[', ']', '\\':
+			b.WriteByte('\\')
+		}
+		b.WriteRune(r)
+	}
+	return b.String()
+}
+
+// plainReader adapts a non-terminal stdin to lineReader.
+type plainReader struct {
+	scanner *bufio.Scanner
+}
+
+func newPlainReader(r io.Reader) *plainReader {
+	return &plainReader{scanner: bufio.NewScanner(r)}
+}
+
+func (p *plainReader) ReadLine() (string, error) {
+	if !p.scanner.Scan() {
+		if err := p.scanner.Err(); err != nil {
+			return "", err
+		}
+		return "", io.EOF
+	}
+	return p.scanner.Text(), nil
+}
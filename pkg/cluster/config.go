@@ -1,5 +1,20 @@
 package cluster
 
+import "time"
+
+// Storage backends for the Raft log, stable store and snapshots.
+const (
+	// StorageBolt keeps Raft state in BoltDB files under DataDir. Default.
+	StorageBolt = "bolt"
+
+	// StorageMemory keeps Raft state in memory only. Meant for tests and
+	// caches whose contents may be lost: a restarted node starts empty and
+	// catches up from the leader. A node that bootstrapped a cluster must
+	// rejoin it rather than bootstrap again; it leaves a marker in DataDir
+	// and refuses to.
+	StorageMemory = "memory"
+)
+
 type Config struct {
 	NodeID string
 
@@ -20,17 +35,127 @@ type Config struct {
 	// Example: "0.0.0.0:8081"
 	HTTPMgmtAddr string
 
+	// HTTPAdvertise is the HTTP management address other nodes dial to reach
+	// this one, e.g. when forwarding a request to the leader. Leave empty to
+	// derive it from HTTPMgmtAddr (see HTTPAdvertiseAddr).
+	// Example: "10.0.1.5:8081"
+	HTTPAdvertise string
+
+	// GRPCAddr is the address the gRPC server listens on.
+	// Example: ":50051"
+	GRPCAddr string
+
+	// GRPCAdvertise is the gRPC address clients dial to reach this node. It
+	// is sent as the leader hint when a follower rejects a write. Leave empty
+	// to derive it from GRPCAddr (see GRPCAdvertiseAddr).
+	// Example: "10.0.1.5:50051"
+	GRPCAdvertise string
+
 	// DataDir is where BoltDB log/stable stores and snapshots are written.
 	// Created on startup if absent. Add to .gitignore.
 	// Example: "./data/node1"
 	DataDir string
 
+	// Storage selects where Raft state lives: StorageBolt (the default when
+	// empty) or StorageMemory.
+	Storage string
+
 	// Bootstrap must be true only on the very first node of a brand-new cluster.
 	// Subsequent nodes set LeaderHTTPAddr and join via HTTP.
 	Bootstrap bool
 
+	// BootstrapPeers, when set with Bootstrap, is the whole initial
+	// membership (node ID → Raft address) to bootstrap with, instead of a
+	// cluster of this node alone. Every node listed must bootstrap with the
+	// same peers. Used to start shard groups.
+	BootstrapPeers map[string]string
+
 	// LeaderHTTPAddr is the HTTP management address of the current leader.
 	// Non-bootstrap nodes send their JoinRequest here at startup.
 	// Example: "10.0.1.5:8081"
 	LeaderHTTPAddr string
+
+	// JoinSeeds are further HTTP management addresses tried, after
+	// LeaderHTTPAddr, when joining. Any member works: followers redirect the
+	// join to the leader.
+	// Example: []string{"10.0.1.6:8081", "10.0.1.7:8081"}
+	JoinSeeds []string
+
+	// NonVoter makes the node join as a non-voting member (a read replica):
+	// it replicates the log and serves reads but is not part of the write
+	// quorum and never becomes leader. Only used when joining.
+	NonVoter bool
+
+	// ApplyBatchSize caps how many concurrent writes the leader coalesces
+	// into a single Raft log entry. 0 uses the default, 1 disables batching.
+	ApplyBatchSize int
+
+	// ApplyBatchLinger is how long the leader waits for more writes to join
+	// a batch. 0 only batches writes that are already queued, adding no
+	// latency; a small value (e.g. 1ms) trades latency for bigger batches.
+	ApplyBatchLinger time.Duration
+
+	// Raft tuning. Zero values keep hashicorp/raft's defaults (in brackets).
+	//
+	// HeartbeatTimeout [1s] and ElectionTimeout [1s] bound how long a
+	// follower waits without hearing from the leader before calling an
+	// election. LeaderLeaseTimeout [500ms] is how long a leader stays leader
+	// without reaching a quorum. Lower values fail over faster on a quiet,
+	// low-latency network, but risk needless elections on a busy one.
+	HeartbeatTimeout   time.Duration
+	ElectionTimeout    time.Duration
+	LeaderLeaseTimeout time.Duration
+
+	// SnapshotInterval [2m] is how often Raft checks whether to snapshot,
+	// and it does so once SnapshotThreshold [8192] entries were appended
+	// since the last one. TrailingLogs [10240] entries are kept after a
+	// snapshot so slightly lagging followers can catch up from the log
+	// instead of receiving the whole snapshot.
+	SnapshotInterval  time.Duration
+	SnapshotThreshold uint64
+	TrailingLogs      uint64
+
+	// SnapshotRetain is how many snapshots are kept on disk [3].
+	SnapshotRetain int
+
+	// ApplyTimeout bounds how long a write waits to be enqueued into Raft
+	// before failing [5s].
+	ApplyTimeout time.Duration
+
+	// ClockTickInterval is how often an idle leader advances the cluster
+	// clock while something waits on a deadline [1s]. It bounds how late a
+	// key, lock lease, session or queue job expires on a cluster that sees no
+	// writes; lower it for tighter expiry at the cost of more log entries.
+	ClockTickInterval time.Duration
+
+	// ShardPortStride is the distance between the Raft ports of this node's
+	// shard groups: shard N listens PortStride*N ports above RaftBindAddr.
+	// Only read when the first shard is added, after which the value stored
+	// in the shard map applies to every node [sharding.DefaultPortStride].
+	ShardPortStride int
+
+	// ReadyMaxLag is how many committed entries a node may have left to apply
+	// and still report ready [0]. Raise it on a busy cluster, where a healthy
+	// follower is almost never exactly caught up.
+	ReadyMaxLag uint64
+
+	// LeaveOnShutdown makes a graceful shutdown remove this node from the
+	// Raft configuration, so the remaining members compute quorum without it.
+	// Leave false for restarts, where the node is expected to come back.
+	LeaveOnShutdown bool
+}
+
+// Seeds returns every address to try when joining: LeaderHTTPAddr first, then
+// JoinSeeds, without duplicates or empty entries.
+func (c *Config) Seeds() []string {
+	seen := make(map[string]bool)
+	var seeds []string
+	for _, addr := range append([]string{c.LeaderHTTPAddr}, c.JoinSeeds...) {
+		if addr == "" || seen[addr] {
+			continue
+		}
+		seen[addr] = true
+		seeds = append(seeds, addr)
+	}
+	return seeds
 }
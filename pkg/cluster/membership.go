@@ -29,6 +29,69 @@ func (m *Membership) AddVoter(nodeID, raftAddr string) error {
 	return m.node.Join(nodeID, raftAddr)
 }
 
+// AddNonvoter registers a new non-voting member: it receives the replicated
+// log and can serve reads, but never counts towards quorum or stands for
+// election. Only the leader can do this.
+func (m *Membership) AddNonvoter(nodeID, raftAddr string) error {
+	if !m.node.IsLeader() {
+		return fmt.Errorf("membership: not the leader (leader raft addr: %q)", m.node.LeaderRaftAddr())
+	}
+	f := m.node.Raft().AddNonvoter(raft.ServerID(nodeID), raft.ServerAddress(raftAddr), 0, 0)
+	if err := f.Error(); err != nil {
+		return fmt.Errorf("membership: add nonvoter %q: %w", nodeID, err)
+	}
+	return nil
+}
+
+// Promote turns an existing non-voter into a voter. Only the leader can do this.
+func (m *Membership) Promote(nodeID string) error {
+	srv, err := m.server(nodeID)
+	if err != nil {
+		return err
+	}
+	if srv.Suffrage == raft.Voter {
+		return fmt.Errorf("membership: %q is already a voter", nodeID)
+	}
+	f := m.node.Raft().AddVoter(srv.ID, srv.Address, 0, 0)
+	if err := f.Error(); err != nil {
+		return fmt.Errorf("membership: promote %q: %w", nodeID, err)
+	}
+	return nil
+}
+
+// Demote turns an existing voter into a non-voter. Only the leader can do this.
+func (m *Membership) Demote(nodeID string) error {
+	srv, err := m.server(nodeID)
+	if err != nil {
+		return err
+	}
+	if srv.Suffrage == raft.Nonvoter {
+		return fmt.Errorf("membership: %q is already a non-voter", nodeID)
+	}
+	f := m.node.Raft().DemoteVoter(srv.ID, 0, 0)
+	if err := f.Error(); err != nil {
+		return fmt.Errorf("membership: demote %q: %w", nodeID, err)
+	}
+	return nil
+}
+
+// server looks nodeID up in the configuration, on the leader only.
+func (m *Membership) server(nodeID string) (raft.Server, error) {
+	if !m.node.IsLeader() {
+		return raft.Server{}, fmt.Errorf("membership: not the leader")
+	}
+	servers, err := m.Servers()
+	if err != nil {
+		return raft.Server{}, err
+	}
+	for _, srv := range servers {
+		if srv.ID == raft.ServerID(nodeID) {
+			return srv, nil
+		}
+	}
+	return raft.Server{}, fmt.Errorf("membership: %q is not a cluster member", nodeID)
+}
+
 // RemoveServer removes a node from the cluster by ID. Obviously only the leader can do this.
 func (m *Membership) RemoveServer(nodeID string) error {
 	if !m.node.IsLeader() {
@@ -50,6 +113,54 @@ func (m *Membership) Servers() ([]raft.Server, error) {
 	return f.Configuration().Servers, nil
 }
 
+// Peer is one entry of the cluster configuration as reported by /raft/peers.
+// The embedded raft.Server keeps the original ID/Address/Suffrage fields, so
+// existing consumers of that endpoint keep working.
+type Peer struct {
+	raft.Server
+
+	// Role is "voter", "nonvoter" or "staging", the readable form of Suffrage.
+	Role   string
+	Leader bool
+
+	// HTTPAddr and GRPCAddr are the member's management and gRPC addresses
+	// when it registered them.
+	HTTPAddr string `json:",omitempty"`
+	GRPCAddr string `json:",omitempty"`
+}
+
+// Peers returns every member with its role and whether it currently leads.
+func (m *Membership) Peers() ([]Peer, error) {
+	servers, err := m.Servers()
+	if err != nil {
+		return nil, err
+	}
+	leader := m.node.LeaderRaftAddr()
+	peers := make([]Peer, len(servers))
+	for i, srv := range servers {
+		peers[i] = Peer{
+			Server: srv,
+			Role:   RoleOf(srv.Suffrage),
+			Leader: leader != "" && string(srv.Address) == leader,
+		}
+	}
+	return peers, nil
+}
+
+// RoleOf returns the readable name of a Raft suffrage.
+func RoleOf(s raft.ServerSuffrage) string {
+	switch s {
+	case raft.Voter:
+		return "voter"
+	case raft.Nonvoter:
+		return "nonvoter"
+	case raft.Staging:
+		return "staging"
+	default:
+		return "unknown"
+	}
+}
+
 // LeaderRaftAddr returns the Raft transport address of the current leader known to this node.
 func (m *Membership) LeaderRaftAddr() string {
 	return m.node.LeaderRaftAddr()
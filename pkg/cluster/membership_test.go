@@ -0,0 +1,15 @@
+package cluster
+
+import (
+	"testing"
+
+	"github.com/hashicorp/raft"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestRoleOf(t *testing.T) {
+	assert.Equal(t, "voter", RoleOf(raft.Voter))
+	assert.Equal(t, "nonvoter", RoleOf(raft.Nonvoter))
+	assert.Equal(t, "staging", RoleOf(raft.Staging))
+	assert.Equal(t, "unknown", RoleOf(raft.ServerSuffrage(42)))
+}
@@ -0,0 +1,82 @@
+package cluster
+
+import (
+	"net"
+)
+
+// NodeMeta is what the cluster knows about a member beyond its Raft
+// address. It is replicated through the FSM so that any node can look up,
+// for example, the HTTP management address of the current leader and
+// forward a request there.
+type NodeMeta struct {
+	NodeID   string `json:"node_id"`
+	RaftAddr string `json:"raft_addr"`
+
+	// HTTPAddr is the dialable HTTP management address of the node.
+	HTTPAddr string `json:"http_addr,omitempty"`
+
+	// GRPCAddr is the dialable gRPC address of the node, handed to clients
+	// that sent a write to a follower.
+	GRPCAddr string `json:"grpc_addr,omitempty"`
+}
+
+// HTTPAdvertiseAddr returns the HTTP management address other nodes should
+// dial to reach this one.
+//
+// HTTPMgmtAddr is usually a bind address such as "0.0.0.0:8081", which is not
+// dialable from another machine. When HTTPAdvertiseAddr is unset and the bind
+// host is unspecified, the host of the Raft advertise address is borrowed
+// instead, since that is already known to be reachable by peers.
+func (c *Config) HTTPAdvertiseAddr() string {
+	if c.HTTPAdvertise != "" {
+		return c.HTTPAdvertise
+	}
+	return borrowHost(c.HTTPMgmtAddr, c.RaftAdvertiseAddr())
+}
+
+// GRPCAdvertiseAddr returns the gRPC address clients should dial to reach
+// this node, derived from GRPCAddr the same way as HTTPAdvertiseAddr.
+func (c *Config) GRPCAdvertiseAddr() string {
+	if c.GRPCAdvertise != "" {
+		return c.GRPCAdvertise
+	}
+	if c.GRPCAddr == "" {
+		return ""
+	}
+	return borrowHost(c.GRPCAddr, c.RaftAdvertiseAddr())
+}
+
+// RaftAdvertiseAddr returns AdvertiseAddr, falling back to RaftBindAddr.
+func (c *Config) RaftAdvertiseAddr() string {
+	if c.AdvertiseAddr != "" {
+		return c.AdvertiseAddr
+	}
+	return c.RaftBindAddr
+}
+
+// Meta returns the NodeMeta describing this node.
+func (c *Config) Meta() NodeMeta {
+	return NodeMeta{
+		NodeID:   c.NodeID,
+		RaftAddr: c.RaftAdvertiseAddr(),
+		HTTPAddr: c.HTTPAdvertiseAddr(),
+		GRPCAddr: c.GRPCAdvertiseAddr(),
+	}
+}
+
+// borrowHost returns addr with its host replaced by the host of from when
+// addr's host is empty or unspecified (0.0.0.0, ::).
+func borrowHost(addr, from string) string {
+	host, port, err := net.SplitHostPort(addr)
+	if err != nil {
+		return addr
+	}
+	if host != "" && !net.ParseIP(host).IsUnspecified() {
+		return addr
+	}
+	fromHost, _, err := net.SplitHostPort(from)
+	if err != nil || fromHost == "" || net.ParseIP(fromHost).IsUnspecified() {
+		return addr
+	}
+	return net.JoinHostPort(fromHost, port)
+}
@@ -0,0 +1,67 @@
+package cluster
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestConfig_HTTPAdvertiseAddr(t *testing.T) {
+	tests := []struct {
+		name string
+		cfg  Config
+		want string
+	}{
+		{
+			name: "explicit advertise address wins",
+			cfg:  Config{HTTPMgmtAddr: "0.0.0.0:8081", HTTPAdvertise: "mgmt.n1:8081", RaftBindAddr: "10.0.0.1:7000"},
+			want: "mgmt.n1:8081",
+		},
+		{
+			name: "specific bind host is used as-is",
+			cfg:  Config{HTTPMgmtAddr: "127.0.0.1:8081", RaftBindAddr: "10.0.0.1:7000"},
+			want: "127.0.0.1:8081",
+		},
+		{
+			name: "unspecified bind host borrows raft advertise host",
+			cfg:  Config{HTTPMgmtAddr: "0.0.0.0:8081", RaftBindAddr: "0.0.0.0:7000", AdvertiseAddr: "10.0.0.5:7000"},
+			want: "10.0.0.5:8081",
+		},
+		{
+			name: "empty bind host borrows raft bind host",
+			cfg:  Config{HTTPMgmtAddr: ":8081", RaftBindAddr: "10.0.0.1:7000"},
+			want: "10.0.0.1:8081",
+		},
+		{
+			name: "nothing dialable to borrow",
+			cfg:  Config{HTTPMgmtAddr: "0.0.0.0:8081", RaftBindAddr: "0.0.0.0:7000"},
+			want: "0.0.0.0:8081",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			assert.Equal(t, tt.want, tt.cfg.HTTPAdvertiseAddr())
+		})
+	}
+}
+
+func TestConfig_GRPCAdvertiseAddr(t *testing.T) {
+	cfg := Config{GRPCAddr: ":50051", RaftBindAddr: "0.0.0.0:7000", AdvertiseAddr: "10.0.0.5:7000"}
+	assert.Equal(t, "10.0.0.5:50051", cfg.GRPCAdvertiseAddr())
+
+	cfg.GRPCAdvertise = "grpc.n1:50051"
+	assert.Equal(t, "grpc.n1:50051", cfg.GRPCAdvertiseAddr())
+
+	assert.Empty(t, (&Config{RaftBindAddr: "10.0.0.1:7000"}).GRPCAdvertiseAddr())
+}
+
+func TestConfig_Seeds(t *testing.T) {
+	cfg := Config{
+		LeaderHTTPAddr: "10.0.0.1:8081",
+		JoinSeeds:      []string{"10.0.0.2:8081", "", "10.0.0.1:8081", "10.0.0.3:8081"},
+	}
+	assert.Equal(t, []string{"10.0.0.1:8081", "10.0.0.2:8081", "10.0.0.3:8081"}, cfg.Seeds())
+
+	assert.Empty(t, (&Config{}).Seeds())
+}
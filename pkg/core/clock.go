@@ -0,0 +1,59 @@
+package core
+
+import (
+	"time"
+
+	"github.com/mateenbagheri/memorabilia/pkg/types"
+)
+
+// Clock tells the repository what time it is when deciding whether a key has
+// expired.
+type Clock interface {
+	Now() time.Time
+}
+
+type systemClock struct{}
+
+func (systemClock) Now() time.Time { return time.Now() }
+
+// SystemClock reads the local wall clock. Repositories use it unless told
+// otherwise; in Raft mode the FSM swaps in the replicated cluster clock so
+// every replica agrees on what has expired.
+var SystemClock Clock = systemClock{}
+
+// SetClock replaces the clock used for expiry decisions.
+func (imc *InMemoryCommandRepository) SetClock(clock Clock) {
+	imc.mu.Lock()
+	defer imc.mu.Unlock()
+	imc.clock = clock
+}
+
+// now returns the current time according to the repository's clock. The
+// caller must hold imc.mu.
+func (imc *InMemoryCommandRepository) now() time.Time {
+	if imc.clock == nil {
+		return time.Now()
+	}
+	return imc.clock.Now()
+}
+
+// HasDeadlines reports whether anything in the repository is waiting for its
+// clock to pass a deadline: a key that expires, a session, or a queue job in
+// flight. While there is none, stopping the clock changes nothing.
+func (imc *InMemoryCommandRepository) HasDeadlines() bool {
+	imc.mu.RLock()
+	defer imc.mu.RUnlock()
+
+	if len(imc.sessions) > 0 {
+		return true
+	}
+	for _, entry := range imc.store {
+		if !entry.Expiration.IsZero() {
+			return true
+		}
+		if list, ok := entry.Column.(types.List); ok && len(list.InFlight) > 0 {
+			return true
+		}
+	}
+	return false
+}
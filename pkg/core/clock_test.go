@@ -0,0 +1,76 @@
+package core
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+type fixedClock time.Time
+
+func (c fixedClock) Now() time.Time { return time.Time(c) }
+
+func TestInMemoryCommandRepository_SetClock(t *testing.T) {
+	ctx := context.Background()
+	t0 := time.Date(2001, 1, 1, 0, 0, 0, 0, time.UTC)
+
+	imc := NewInMemoryCommandRepository()
+	imc.SetClock(fixedClock(t0))
+	require.NoError(t, imc.Set(ctx, "k", "v", t0.Add(time.Second)))
+
+	// Long past by the wall clock, but live by the repository's clock.
+	_, err := imc.Get(ctx, "k")
+	require.NoError(t, err)
+	ttl, err := imc.TTL(ctx, "k")
+	require.NoError(t, err)
+	assert.Equal(t, time.Second, ttl)
+
+	imc.SetClock(fixedClock(t0.Add(2 * time.Second)))
+	_, err = imc.Get(ctx, "k")
+	assert.ErrorIs(t, err, ErrKeyExpiredForGetOp)
+	keys, err := imc.GetExpiredKeys(ctx)
+	require.NoError(t, err)
+	assert.Equal(t, []string{"k"}, keys)
+}
+
+func TestInMemoryCommandRepository_HasDeadlines(t *testing.T) {
+	ctx := context.Background()
+
+	t.Run("persistent keys", func(t *testing.T) {
+		imc := NewInMemoryCommandRepository()
+		require.NoError(t, imc.Set(ctx, "k", "v", time.Time{}))
+		_, err := imc.Push(ctx, "q", []string{"a"}, false)
+		require.NoError(t, err)
+		assert.False(t, imc.HasDeadlines())
+	})
+
+	t.Run("key with an expiration", func(t *testing.T) {
+		imc := NewInMemoryCommandRepository()
+		require.NoError(t, imc.Set(ctx, "k", "v", time.Now().Add(time.Minute)))
+		assert.True(t, imc.HasDeadlines())
+	})
+
+	t.Run("session", func(t *testing.T) {
+		imc := NewInMemoryCommandRepository()
+		_, err := imc.CreateSession(ctx, "s", time.Minute)
+		require.NoError(t, err)
+		assert.True(t, imc.HasDeadlines())
+	})
+
+	t.Run("job in flight", func(t *testing.T) {
+		imc := NewInMemoryCommandRepository()
+		_, err := imc.Push(ctx, "q", []string{"a"}, false)
+		require.NoError(t, err)
+		job, ok, err := imc.QueuePop(ctx, "q", time.Minute)
+		require.NoError(t, err)
+		require.True(t, ok)
+		assert.True(t, imc.HasDeadlines())
+
+		_, err = imc.QueueAck(ctx, "q", job.ID)
+		require.NoError(t, err)
+		assert.False(t, imc.HasDeadlines())
+	})
+}
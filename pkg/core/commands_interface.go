@@ -12,12 +12,82 @@ import (
 type CommandsRepository interface {
 	Get(ctx context.Context, key string) (value string, err error)
 	Set(ctx context.Context, key, value string, expiration time.Time) (err error)
+	GetSet(ctx context.Context, key, value string, expiration time.Time) (previous string, existed bool, err error)
 	BatchDelete(ctx context.Context, keys []string) (deleteCount int64)
 	Delete(ctx context.Context, key string) (deleteCount int64)
+	Expire(ctx context.Context, keys []string) (deleted []string)
+	Evict(ctx context.Context, keys []string) (deleteCount int64)
 	GetExpiredKeys(ctx context.Context) (keys []string, err error)
 	Cleanup(ctx context.Context) (deleteCount int64, err error)
+	Scan(ctx context.Context, pattern, cursor string, count int64) (keys []string, nextCursor string, err error)
+	TTL(ctx context.Context, key string) (ttl time.Duration, err error)
+
+	// Lists and reliable queues
+	Push(ctx context.Context, key string, values []string, left bool) (length int64, err error)
+	Pop(ctx context.Context, keys []string, left bool) (key, value string, ok bool, err error)
+	Len(ctx context.Context, key string) (length int64, err error)
+	QueuePop(ctx context.Context, key string, visibility time.Duration) (job types.Job, ok bool, err error)
+	QueueAck(ctx context.Context, key, id string) (acked bool, err error)
+	QueueNack(ctx context.Context, key, id string) (requeued bool, err error)
+	QueueLen(ctx context.Context, key string) (ready, inFlight int64, err error)
+
+	// Locks
+	Lock(ctx context.Context, key, session string, lease time.Duration, token uint64) (holder types.Lock, expiration time.Time, acquired bool, err error)
+	Unlock(ctx context.Context, key, session string, token uint64) (released bool, err error)
+	RefreshLock(ctx context.Context, key, session string, token uint64, lease time.Duration) (expiration time.Time, refreshed bool, err error)
+	LockHolder(ctx context.Context, key string) (holder types.Lock, expiration time.Time, held bool, err error)
+
+	// Sessions and ephemeral keys
+	CreateSession(ctx context.Context, id string, ttl time.Duration) (deadline time.Time, err error)
+	KeepAlive(ctx context.Context, id string) (deadline time.Time, err error)
+	CloseSession(ctx context.Context, id string, keys []string, expired bool) (closed bool, deleted []string)
+	SessionKeys(ctx context.Context, id string) (keys []string)
+	ExpiredSessions(ctx context.Context) (ids []string)
+	GetSetEphemeral(ctx context.Context, key, value string, expiration time.Time, session string) (previous string, existed bool, err error)
+
+	// Streams and consumer groups
+	XAdd(ctx context.Context, key, id string, fields map[string]string, trim StreamTrim) (added string, err error)
+	XTrim(ctx context.Context, key string, trim StreamTrim) (trimmed int64, err error)
+	XRange(ctx context.Context, key, start, end string, count int64, reverse bool) (entries []types.StreamEntry, err error)
+	XLen(ctx context.Context, key string) (length int64, err error)
+	XGroupCreate(ctx context.Context, key, group, start string) (created bool, err error)
+	XReadGroup(ctx context.Context, key, group, consumer, after string, count int64) (entries []types.StreamEntry, err error)
+	XAck(ctx context.Context, key, group string, ids []string) (acked int64, err error)
+	XClaim(ctx context.Context, key, group, consumer string, minIdle time.Duration, ids []string, count int64) (entries []types.StreamEntry, err error)
+	XPending(ctx context.Context, key, group string) (pending []types.PendingEntry, err error)
+
+	// Rate limiters
+	RateLimit(ctx context.Context, key string, policy RateLimitPolicy, cost int64) (allowed bool, remaining int64, retryAfter time.Duration, err error)
+
+	// Bloom filters
+	BFReserve(ctx context.Context, key string, bits, hashes int64) (created bool, err error)
+	BFAdd(ctx context.Context, key string, items []string, bits, hashes int64) (added []bool, err error)
+	BFExists(ctx context.Context, key string, items []string) (exists []bool, err error)
+
+	// HyperLogLogs
+	PFAdd(ctx context.Context, key string, items []string) (changed bool, err error)
+	PFCount(ctx context.Context, keys []string) (count int64, err error)
+	PFMerge(ctx context.Context, dest string, sources []string) error
+
+	// Bitmaps and bitfields
+	SetBit(ctx context.Context, key string, offset int64, bit bool) (previous bool, err error)
+	GetBit(ctx context.Context, key string, offset int64) (bit bool, err error)
+	BitCount(ctx context.Context, key string, rng BitRange) (count int64, err error)
+	BitPos(ctx context.Context, key string, bit bool, rng BitRange) (position int64, err error)
+	BitOp(ctx context.Context, op, dest string, sources []string) (length int64, err error)
+	BitField(ctx context.Context, key string, ops []BitFieldOp) (results []BitFieldResult, err error)
 
 	// Raft related
 	Dump() (map[string]types.ColumnValueWithTTL, error)
 	Load(map[string]types.ColumnValueWithTTL) error
+	Merge(map[string]types.ColumnValueWithTTL) error
+	Entry(key string) (entry types.ColumnValueWithTTL, ok bool)
+	Put(key string, entry types.ColumnValueWithTTL)
+	DumpSessions() map[string]types.Session
+	LoadSessions(map[string]types.Session)
+	SetClock(clock Clock)
+	HasDeadlines() bool
+
+	// SetNotifier reports the changes to keys, see KeyspaceEvent.
+	SetNotifier(notify KeyspaceNotifier)
 }
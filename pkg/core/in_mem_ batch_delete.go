@@ -3,6 +3,8 @@ package core
 import "context"
 
 // BatchDelete removes multiple key-value pairs from the in-memory store.
+// The whole batch is removed under a single lock, so readers never observe
+// it half-applied.
 //
 // Parameters:
 //   - ctx: Context for request-scoped values, cancellation, and deadlines.
@@ -11,8 +13,41 @@ import "context"
 // Returns:
 //   - deleteCount: The number of keys that were successfully deleted.
 func (imc *InMemoryCommandRepository) BatchDelete(ctx context.Context, keys []string) (deleteCount int64) {
+	imc.mu.Lock()
+	defer imc.mu.Unlock()
 	for _, key := range keys {
-		deleteCount += imc.Delete(ctx, key)
+		deleteCount += imc.deleteLocked(key, EventDel)
+	}
+	return deleteCount
+}
+
+// Expire removes keys like BatchDelete, for the TTL cleanup: their removal is
+// reported as EventExpired rather than EventDel. The keys were collected
+// earlier by GetExpiredKeys, so any that was written again in the meantime
+// and is no longer expired is kept. It returns the keys it removed.
+func (imc *InMemoryCommandRepository) Expire(ctx context.Context, keys []string) (deleted []string) {
+	imc.mu.Lock()
+	defer imc.mu.Unlock()
+	now := imc.now()
+	for _, key := range keys {
+		entry, ok := imc.store[key]
+		if !ok || !imc.expiredLocked(entry, now) {
+			continue
+		}
+		imc.deleteLocked(key, EventExpired)
+		deleted = append(deleted, key)
+	}
+	return deleted
+}
+
+// Evict removes keys like BatchDelete, for keys that leave the store for
+// another reason than a delete or their expiry: their removal is reported as
+// EventEvicted.
+func (imc *InMemoryCommandRepository) Evict(ctx context.Context, keys []string) (deleteCount int64) {
+	imc.mu.Lock()
+	defer imc.mu.Unlock()
+	for _, key := range keys {
+		deleteCount += imc.deleteLocked(key, EventEvicted)
 	}
 	return deleteCount
 }
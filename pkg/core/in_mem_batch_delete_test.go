@@ -3,9 +3,11 @@ package core
 import (
 	"context"
 	"testing"
+	"time"
 
 	"github.com/mateenbagheri/memorabilia/pkg/types"
 	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
 )
 
 func TestInMemoryCommandRepository_BatchDelete(t *testing.T) {
@@ -115,3 +117,40 @@ func TestInMemoryCommandRepository_BatchDelete(t *testing.T) {
 		})
 	}
 }
+
+func TestInMemoryCommandRepository_Expire_RechecksExpiry(t *testing.T) {
+	ctx := context.Background()
+	t0 := time.Date(2001, 1, 1, 0, 0, 0, 0, time.UTC)
+
+	imc := NewInMemoryCommandRepository()
+	imc.SetClock(fixedClock(t0))
+	require.NoError(t, imc.Set(ctx, "expired", "v", t0.Add(time.Second)))
+	require.NoError(t, imc.Set(ctx, "reset", "v", t0.Add(time.Second)))
+	require.NoError(t, imc.Set(ctx, "extended", "v", t0.Add(time.Second)))
+	// Attached to its session as it was being closed.
+	_, err := imc.CreateSession(ctx, "s", time.Minute)
+	require.NoError(t, err)
+	_, _, err = imc.GetSetEphemeral(ctx, "orphan", "v", time.Time{}, "s")
+	require.NoError(t, err)
+	imc.CloseSession(ctx, "s", nil, false)
+
+	imc.SetClock(fixedClock(t0.Add(2 * time.Second)))
+	keys, err := imc.GetExpiredKeys(ctx)
+	require.NoError(t, err)
+	assert.ElementsMatch(t, []string{"expired", "reset", "extended", "orphan"}, keys)
+
+	// Written again between collecting the keys and expiring them.
+	require.NoError(t, imc.Set(ctx, "reset", "new", time.Time{}))
+	require.NoError(t, imc.Set(ctx, "extended", "new", t0.Add(time.Minute)))
+
+	assert.ElementsMatch(t, []string{"expired", "orphan"}, imc.Expire(ctx, append(keys, "missing")))
+	for _, key := range []string{"expired", "orphan"} {
+		_, err = imc.Get(ctx, key)
+		assert.Error(t, err, key)
+	}
+	for _, key := range []string{"reset", "extended"} {
+		got, err := imc.Get(ctx, key)
+		require.NoError(t, err, key)
+		assert.Equal(t, "new", got, key)
+	}
+}
@@ -0,0 +1,443 @@
+package core
+
+import (
+	"context"
+	"fmt"
+	"math/bits"
+
+	"github.com/mateenbagheri/memorabilia/pkg/types"
+)
+
+// MaxBitOffset is the highest bit offset of a string, making it 512MiB.
+const MaxBitOffset = 1<<32 - 1
+
+// Bitwise operations of BitOp.
+const (
+	BitAnd = "AND"
+	BitOr  = "OR"
+	BitXor = "XOR"
+	BitNot = "NOT"
+)
+
+// Operations and overflow behaviors of a BitFieldOp.
+const (
+	BitFieldGet    = "GET"
+	BitFieldSet    = "SET"
+	BitFieldIncrBy = "INCRBY"
+
+	OverflowWrap = "WRAP"
+	OverflowSat  = "SAT"
+	OverflowFail = "FAIL"
+)
+
+// BitRange is a range of a string from Start to End inclusive, counted in
+// bytes, or in bits when Bits is set. Negative positions count from the end
+// of the string: -1 is its last byte, or bit. BitRange{End: -1} is the whole
+// string.
+type BitRange struct {
+	Start, End int64
+	Bits       bool
+}
+
+// BitFieldOp is an operation of BitField on the integer of Width bits at bit
+// Offset of a string, signed or not. Signed integers are up to 64 bits wide,
+// unsigned ones up to 63. BitFieldSet writes Value and BitFieldIncrBy adds
+// Value; when the result doesn't fit, Overflow says whether it wraps around,
+// the default, saturates, or fails leaving the integer as it was.
+type BitFieldOp struct {
+	Op       string
+	Signed   bool
+	Width    int64
+	Offset   int64
+	Value    int64
+	Overflow string
+}
+
+// BitFieldResult is the result of a BitFieldOp: the integer a BitFieldGet
+// read, the one a BitFieldSet replaced or the one a BitFieldIncrBy left.
+// Failed reports a BitFieldSet or BitFieldIncrBy that didn't fit with
+// OverflowFail, leaving Value 0.
+type BitFieldResult struct {
+	Value  int64
+	Failed bool
+}
+
+// SetBit sets or clears the bit at offset of the string at key, bits being
+// numbered from the most significant bit of the first byte. The string is
+// created, or grown with zero bytes, as needed, and keeps its expiration.
+//
+// Returns:
+//   - previous: The bit's value before the call.
+//   - err: ErrWrongType if key holds something other than a string.
+func (imc *InMemoryCommandRepository) SetBit(ctx context.Context, key string, offset int64, bit bool) (previous bool, err error) {
+	if offset < 0 || offset > MaxBitOffset {
+		return false, fmt.Errorf("bit offset %d out of range", offset)
+	}
+	imc.mu.Lock()
+	defer imc.mu.Unlock()
+
+	value, entry, err := imc.bitmapLocked(key)
+	if err != nil {
+		return false, err
+	}
+	previous = bitAt(value, offset)
+	if previous == bit && offset/8 < int64(len(value)) {
+		return previous, nil
+	}
+	value = growBitmap(value, offset/8+1, true)
+	setBitAt(value, offset, bit)
+	imc.storeBitmapLocked(key, value, entry)
+	return previous, nil
+}
+
+// GetBit returns the bit at offset of the string at key. Bits past its end,
+// or of a missing key, are 0.
+func (imc *InMemoryCommandRepository) GetBit(ctx context.Context, key string, offset int64) (bit bool, err error) {
+	imc.mu.RLock()
+	defer imc.mu.RUnlock()
+
+	value, _, err := imc.bitmapLocked(key)
+	return bitAt(value, offset), err
+}
+
+// BitCount returns the number of set bits of the string at key within rng.
+func (imc *InMemoryCommandRepository) BitCount(ctx context.Context, key string, rng BitRange) (count int64, err error) {
+	imc.mu.RLock()
+	defer imc.mu.RUnlock()
+
+	value, _, err := imc.bitmapLocked(key)
+	if err != nil {
+		return 0, err
+	}
+	from, to := rng.resolve(int64(len(value)))
+	for ; from < to && from%8 != 0; from++ {
+		if bitAt(value, from) {
+			count++
+		}
+	}
+	for ; from+8 <= to; from += 8 {
+		count += int64(bits.OnesCount8(value[from/8]))
+	}
+	for ; from < to; from++ {
+		if bitAt(value, from) {
+			count++
+		}
+	}
+	return count, nil
+}
+
+// BitPos returns the offset of the first bit of the string at key within rng
+// equal to bit, or -1 if there is none. When looking for a 0 in a range that
+// reaches the end of the string, the string counts as padded with zero bits:
+// a string with all its bits set gives the offset just past its end, and so
+// does a missing key.
+func (imc *InMemoryCommandRepository) BitPos(ctx context.Context, key string, bit bool, rng BitRange) (position int64, err error) {
+	imc.mu.RLock()
+	defer imc.mu.RUnlock()
+
+	value, _, err := imc.bitmapLocked(key)
+	if err != nil {
+		return 0, err
+	}
+	from, to := rng.resolve(int64(len(value)))
+	skip := byte(0)
+	if !bit {
+		skip = 0xff
+	}
+	for from < to {
+		if from%8 == 0 && from+8 <= to {
+			if b := value[from/8]; b != skip {
+				if !bit {
+					b = ^b
+				}
+				return from + int64(bits.LeadingZeros8(b)), nil
+			}
+			from += 8
+			continue
+		}
+		if bitAt(value, from) == bit {
+			return from, nil
+		}
+		from++
+	}
+	if !bit && to == int64(len(value))*8 {
+		return to, nil
+	}
+	return -1, nil
+}
+
+// BitOp stores at dest the bitwise op, one of BitAnd, BitOr, BitXor and
+// BitNot, of the strings at sources, replacing whatever dest held. Strings
+// shorter than the longest one, and missing keys, count as padded with zero
+// bytes. BitNot takes a single source. An empty result deletes dest.
+//
+// Returns:
+//   - length: The length of the string stored at dest.
+//   - err: ErrWrongType if one of sources holds something other than a
+//     string.
+func (imc *InMemoryCommandRepository) BitOp(ctx context.Context, op, dest string, sources []string) (length int64, err error) {
+	switch op {
+	case BitAnd, BitOr, BitXor:
+	case BitNot:
+		if len(sources) != 1 {
+			return 0, fmt.Errorf("bit op %s takes one source, not %d", op, len(sources))
+		}
+	default:
+		return 0, fmt.Errorf("unknown bit op %q", op)
+	}
+	imc.mu.Lock()
+	defer imc.mu.Unlock()
+
+	values := make([][]byte, len(sources))
+	for i, key := range sources {
+		if values[i], _, err = imc.bitmapLocked(key); err != nil {
+			return 0, err
+		}
+		length = max(length, int64(len(values[i])))
+	}
+	result := make([]byte, length)
+	for i := range result {
+		var b byte
+		for j, value := range values {
+			var v byte
+			if i < len(value) {
+				v = value[i]
+			}
+			switch {
+			case j == 0:
+				b = v
+			case op == BitAnd:
+				b &= v
+			case op == BitOr:
+				b |= v
+			case op == BitXor:
+				b ^= v
+			}
+		}
+		if op == BitNot {
+			b = ^b
+		}
+		result[i] = b
+	}
+	if length == 0 {
+		imc.deleteLocked(dest, EventDel)
+		return 0, nil
+	}
+	imc.storeBitmapLocked(dest, result, types.ColumnValueWithTTL{})
+	return length, nil
+}
+
+// BitField runs ops, in order, on the integers packed in the string at key,
+// growing it with zero bytes as the writes need; see BitFieldOp. The string
+// keeps its expiration.
+//
+// Returns:
+//   - results: The result of each op.
+//   - err: ErrWrongType if key holds something other than a string.
+func (imc *InMemoryCommandRepository) BitField(ctx context.Context, key string, ops []BitFieldOp) (results []BitFieldResult, err error) {
+	for _, op := range ops {
+		if err := op.validate(); err != nil {
+			return nil, err
+		}
+	}
+	imc.mu.Lock()
+	defer imc.mu.Unlock()
+
+	value, entry, err := imc.bitmapLocked(key)
+	if err != nil {
+		return nil, err
+	}
+	changed := false
+	results = make([]BitFieldResult, len(ops))
+	for i, op := range ops {
+		old := op.read(value)
+		var v int64
+		var ok bool
+		switch op.Op {
+		case BitFieldGet:
+			results[i].Value = old
+			continue
+		case BitFieldSet:
+			v, ok = op.fit(op.Value, 0)
+			results[i] = BitFieldResult{Value: old, Failed: !ok}
+		case BitFieldIncrBy:
+			sum := old + op.Value
+			var overflow int
+			switch {
+			case op.Value > 0 && sum < old:
+				overflow = 1
+			case op.Value < 0 && sum > old:
+				overflow = -1
+			}
+			v, ok = op.fit(sum, overflow)
+			results[i] = BitFieldResult{Value: v, Failed: !ok}
+		}
+		if !ok {
+			results[i] = BitFieldResult{Failed: true}
+			continue
+		}
+		// value may be shared with snapshots until copied once.
+		value = growBitmap(value, (op.Offset+op.Width+7)/8, !changed)
+		op.write(value, v)
+		changed = true
+	}
+	if changed {
+		imc.storeBitmapLocked(key, value, entry)
+	}
+	return results, nil
+}
+
+// bitmapLocked returns the bytes of the string at key, nil when it is
+// missing, and its entry. They may be shared with snapshots, so they must be
+// copied before they are modified. The caller must hold imc.mu.
+func (imc *InMemoryCommandRepository) bitmapLocked(key string) (value []byte, entry types.ColumnValueWithTTL, err error) {
+	entry, ok := imc.store[key]
+	if !ok || (!entry.Expiration.IsZero() && imc.now().After(entry.Expiration)) {
+		return nil, types.ColumnValueWithTTL{}, nil
+	}
+	switch column := entry.Column.(type) {
+	case types.Bytes:
+		return column.Val, entry, nil
+	case types.String, types.Integer, types.Float:
+		return []byte(column.ToString()), entry, nil
+	default:
+		return nil, types.ColumnValueWithTTL{}, ErrWrongType
+	}
+}
+
+// storeBitmapLocked stores value at key, keeping the expiration and session
+// of entry, the key's previous entry. The caller must hold imc.mu.
+func (imc *InMemoryCommandRepository) storeBitmapLocked(key string, value []byte, entry types.ColumnValueWithTTL) {
+	imc.store[key] = types.ColumnValueWithTTL{
+		Column:     types.Bytes{Val: value},
+		Expiration: entry.Expiration,
+		Session:    entry.Session,
+	}
+	imc.emit(EventSet, key)
+}
+
+// growBitmap returns value grown with zero bytes to at least length bytes:
+// a copy when value is shared, or when it has to grow.
+func growBitmap(value []byte, length int64, shared bool) []byte {
+	if !shared && int64(len(value)) >= length {
+		return value
+	}
+	grown := make([]byte, max(int64(len(value)), length))
+	copy(grown, value)
+	return grown
+}
+
+// bitAt returns the bit at offset of value, 0 past its end.
+func bitAt(value []byte, offset int64) bool {
+	return offset/8 < int64(len(value)) && value[offset/8]&(0x80>>(offset%8)) != 0
+}
+
+// setBitAt sets the bit at offset of value, which must be long enough.
+func setBitAt(value []byte, offset int64, bit bool) {
+	mask := byte(0x80) >> (offset % 8)
+	if bit {
+		value[offset/8] |= mask
+	} else {
+		value[offset/8] &^= mask
+	}
+}
+
+// resolve returns the bits of a string of length bytes that rng covers, from
+// from up to to exclusive.
+func (rng BitRange) resolve(length int64) (from, to int64) {
+	size, unit := length, int64(8)
+	if rng.Bits {
+		size, unit = length*8, 1
+	}
+	start, end := rng.Start, rng.End
+	if start < 0 {
+		start += size
+	}
+	if end < 0 {
+		end += size
+	}
+	start, end = max(start, 0), min(max(end, 0), size-1)
+	if start > end {
+		return 0, 0
+	}
+	return start * unit, (end + 1) * unit
+}
+
+// validate checks op.
+func (op BitFieldOp) validate() error {
+	switch op.Op {
+	case BitFieldGet, BitFieldSet, BitFieldIncrBy:
+	default:
+		return fmt.Errorf("unknown bitfield op %q", op.Op)
+	}
+	switch op.Overflow {
+	case "", OverflowWrap, OverflowSat, OverflowFail:
+	default:
+		return fmt.Errorf("unknown bitfield overflow %q", op.Overflow)
+	}
+	maxWidth := int64(63)
+	if op.Signed {
+		maxWidth = 64
+	}
+	if op.Width < 1 || op.Width > maxWidth {
+		return fmt.Errorf("bitfield width %d out of range", op.Width)
+	}
+	if op.Offset < 0 || op.Offset+op.Width-1 > MaxBitOffset {
+		return fmt.Errorf("bitfield offset %d out of range", op.Offset)
+	}
+	return nil
+}
+
+// read returns the integer op works on in value.
+func (op BitFieldOp) read(value []byte) int64 {
+	var u uint64
+	for i := int64(0); i < op.Width; i++ {
+		u <<= 1
+		if bitAt(value, op.Offset+i) {
+			u |= 1
+		}
+	}
+	return op.wrap(int64(u))
+}
+
+// write stores v as the integer op works on in value, which must be long
+// enough.
+func (op BitFieldOp) write(value []byte, v int64) {
+	for i := int64(0); i < op.Width; i++ {
+		setBitAt(value, op.Offset+i, v>>(op.Width-1-i)&1 != 0)
+	}
+}
+
+// fit brings v into the range of op's integers, as op.Overflow says, and
+// reports whether it could. overflow is 1 or -1 when v is the result of an
+// addition that overflowed int64 upwards or downwards; v is still exact
+// modulo 2^64 then.
+func (op BitFieldOp) fit(v int64, overflow int) (int64, bool) {
+	low, high := int64(0), int64(1)<<op.Width-1
+	if op.Signed {
+		low, high = -1<<(op.Width-1), 1<<(op.Width-1)-1
+	}
+	if overflow == 0 && v >= low && v <= high {
+		return v, true
+	}
+	switch op.Overflow {
+	case OverflowSat:
+		if overflow > 0 || (overflow == 0 && v > high) {
+			return high, true
+		}
+		return low, true
+	case OverflowFail:
+		return 0, false
+	default:
+		return op.wrap(v), true
+	}
+}
+
+// wrap returns v modulo 2^op.Width, as one of op's integers.
+func (op BitFieldOp) wrap(v int64) int64 {
+	shift := 64 - op.Width
+	if op.Signed {
+		return v << shift >> shift
+	}
+	return int64(uint64(v) << shift >> shift)
+}
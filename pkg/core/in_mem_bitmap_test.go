@@ -0,0 +1,188 @@
+package core
+
+import (
+	"context"
+	"math"
+	"testing"
+	"time"
+
+	"github.com/mateenbagheri/memorabilia/pkg/types"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestInMemoryCommandRepository_SetBit_GetBit(t *testing.T) {
+	ctx := context.Background()
+	imc := NewInMemoryCommandRepository()
+
+	previous, err := imc.SetBit(ctx, "flags", 7, true)
+	require.NoError(t, err)
+	assert.False(t, previous)
+	previous, err = imc.SetBit(ctx, "flags", 7, true)
+	require.NoError(t, err)
+	assert.True(t, previous)
+	_, err = imc.SetBit(ctx, "flags", 8, true)
+	require.NoError(t, err)
+	value, err := imc.Get(ctx, "flags")
+	require.NoError(t, err)
+	assert.Equal(t, "\x01\x80", value)
+
+	for offset, want := range map[int64]bool{6: false, 7: true, 8: true, 1000: false} {
+		bit, err := imc.GetBit(ctx, "flags", offset)
+		require.NoError(t, err)
+		assert.Equal(t, want, bit, "offset %d", offset)
+	}
+
+	// Strings set as text are bitmaps too, and keep their expiration.
+	expiration := time.Now().Add(time.Hour)
+	require.NoError(t, imc.Set(ctx, "str", "a", expiration))
+	previous, err = imc.SetBit(ctx, "str", 6, true)
+	require.NoError(t, err)
+	assert.False(t, previous)
+	value, err = imc.Get(ctx, "str")
+	require.NoError(t, err)
+	assert.Equal(t, "c", value)
+	assert.Equal(t, expiration, imc.store["str"].Expiration)
+
+	// Values already handed out, to snapshots say, never change.
+	snapshot := imc.store["flags"].Column.(types.Bytes)
+	_, err = imc.SetBit(ctx, "flags", 0, true)
+	require.NoError(t, err)
+	assert.Equal(t, []byte{0x01, 0x80}, snapshot.Val)
+
+	_, err = imc.PFAdd(ctx, "hll", []string{"a"})
+	require.NoError(t, err)
+	_, err = imc.SetBit(ctx, "hll", 0, true)
+	assert.ErrorIs(t, err, ErrWrongType)
+}
+
+func TestInMemoryCommandRepository_BitCount_BitPos(t *testing.T) {
+	ctx := context.Background()
+	imc := NewInMemoryCommandRepository()
+	require.NoError(t, imc.Set(ctx, "k", "\x00\xff\xf0", time.Time{}))
+
+	for _, tt := range []struct {
+		rng  BitRange
+		want int64
+	}{
+		{BitRange{End: -1}, 12},
+		{BitRange{Start: 1, End: 1}, 8},
+		{BitRange{Start: -1, End: -1}, 4},
+		{BitRange{Start: 5, End: 12, Bits: true}, 5},
+		{BitRange{Start: 2, End: 1}, 0},
+	} {
+		count, err := imc.BitCount(ctx, "k", tt.rng)
+		require.NoError(t, err)
+		assert.Equal(t, tt.want, count, "range %+v", tt.rng)
+	}
+
+	for _, tt := range []struct {
+		bit  bool
+		rng  BitRange
+		want int64
+	}{
+		{true, BitRange{End: -1}, 8},
+		{false, BitRange{End: -1}, 0},
+		{false, BitRange{Start: 1, End: -1}, 20},
+		{true, BitRange{Start: 9, End: -1, Bits: true}, 9},
+		{false, BitRange{Start: 1, End: 1}, -1},
+		{true, BitRange{Start: 0, End: 0}, -1},
+	} {
+		position, err := imc.BitPos(ctx, "k", tt.bit, tt.rng)
+		require.NoError(t, err)
+		assert.Equal(t, tt.want, position, "bit %v range %+v", tt.bit, tt.rng)
+	}
+
+	// A string with every bit set is padded with a zero.
+	require.NoError(t, imc.Set(ctx, "ones", "\xff", time.Time{}))
+	position, err := imc.BitPos(ctx, "ones", false, BitRange{End: -1})
+	require.NoError(t, err)
+	assert.Equal(t, int64(8), position)
+	position, err = imc.BitPos(ctx, "missing", true, BitRange{End: -1})
+	require.NoError(t, err)
+	assert.Equal(t, int64(-1), position)
+}
+
+func TestInMemoryCommandRepository_BitOp(t *testing.T) {
+	ctx := context.Background()
+	imc := NewInMemoryCommandRepository()
+	require.NoError(t, imc.Set(ctx, "a", "\x0f\xff", time.Time{}))
+	require.NoError(t, imc.Set(ctx, "b", "\x3c", time.Time{}))
+
+	for op, want := range map[string]string{
+		BitAnd: "\x0c\x00",
+		BitOr:  "\x3f\xff",
+		BitXor: "\x33\xff",
+	} {
+		length, err := imc.BitOp(ctx, op, "dest", []string{"a", "b", "missing"})
+		require.NoError(t, err)
+		assert.Equal(t, int64(2), length)
+		value, err := imc.Get(ctx, "dest")
+		require.NoError(t, err)
+		if op == BitAnd {
+			want = "\x00\x00" // the missing key counts as zeros
+		}
+		assert.Equal(t, want, value, op)
+	}
+	_, err := imc.BitOp(ctx, BitNot, "dest", []string{"b"})
+	require.NoError(t, err)
+	value, err := imc.Get(ctx, "dest")
+	require.NoError(t, err)
+	assert.Equal(t, "\xc3", value)
+
+	length, err := imc.BitOp(ctx, BitOr, "dest", []string{"missing"})
+	require.NoError(t, err)
+	assert.Zero(t, length)
+	_, err = imc.Get(ctx, "dest")
+	assert.ErrorIs(t, err, ErrNotFoundForGetOp)
+
+	_, err = imc.BitOp(ctx, BitNot, "dest", []string{"a", "b"})
+	assert.Error(t, err)
+}
+
+func TestInMemoryCommandRepository_BitField(t *testing.T) {
+	ctx := context.Background()
+	imc := NewInMemoryCommandRepository()
+
+	results, err := imc.BitField(ctx, "k", []BitFieldOp{
+		{Op: BitFieldSet, Width: 8, Offset: 0, Value: 200},
+		{Op: BitFieldGet, Signed: true, Width: 8, Offset: 0},
+		{Op: BitFieldIncrBy, Width: 8, Offset: 0, Value: 100},
+		{Op: BitFieldIncrBy, Width: 8, Offset: 0, Value: 250, Overflow: OverflowSat},
+		{Op: BitFieldIncrBy, Width: 8, Offset: 0, Value: 1, Overflow: OverflowFail},
+		{Op: BitFieldSet, Signed: true, Width: 4, Offset: 12, Value: -3},
+		{Op: BitFieldGet, Signed: true, Width: 4, Offset: 12},
+	})
+	require.NoError(t, err)
+	assert.Equal(t, []BitFieldResult{
+		{Value: 0},
+		{Value: -56},
+		{Value: 44}, // 300 wraps around
+		{Value: 255},
+		{Failed: true},
+		{Value: 0},
+		{Value: -3},
+	}, results)
+	value, err := imc.Get(ctx, "k")
+	require.NoError(t, err)
+	assert.Equal(t, "\xff\x0d", value)
+
+	// 64-bit signed integers saturate rather than overflow int64.
+	results, err = imc.BitField(ctx, "wide", []BitFieldOp{
+		{Op: BitFieldSet, Signed: true, Width: 64, Value: math.MaxInt64},
+		{Op: BitFieldIncrBy, Signed: true, Width: 64, Value: 1, Overflow: OverflowSat},
+		{Op: BitFieldIncrBy, Signed: true, Width: 64, Value: 1},
+	})
+	require.NoError(t, err)
+	assert.Equal(t, int64(math.MaxInt64), results[1].Value)
+	assert.Equal(t, int64(math.MinInt64), results[2].Value)
+
+	// Reads alone don't create the key.
+	_, err = imc.BitField(ctx, "missing", []BitFieldOp{{Op: BitFieldGet, Width: 8}})
+	require.NoError(t, err)
+	_, err = imc.Get(ctx, "missing")
+	assert.ErrorIs(t, err, ErrNotFoundForGetOp)
+
+	_, err = imc.BitField(ctx, "k", []BitFieldOp{{Op: BitFieldGet, Width: 64}})
+	assert.Error(t, err)
+}
@@ -0,0 +1,157 @@
+package core
+
+import (
+	"context"
+	"encoding/binary"
+	"errors"
+	"hash/fnv"
+	"math"
+	"slices"
+	"time"
+
+	"github.com/mateenbagheri/memorabilia/pkg/types"
+)
+
+// MaxBloomBits caps the size of a Bloom filter, at 128MiB.
+const MaxBloomBits = 1 << 30
+
+// ErrBloomTooLarge is returned by BloomGeometry when a filter would need more
+// than MaxBloomBits.
+var ErrBloomTooLarge = errors.New("bloom filter too large")
+
+// BloomGeometry returns the number of bits, a multiple of 8, and of hashes per
+// item of the smallest Bloom filter holding capacity items with a false
+// positive rate of at most errorRate, which must be between 0 and 1.
+//
+// The geometry is computed once, by whoever creates the filter, and then
+// travels with it: replicas never recompute it, so they can't disagree on it.
+func BloomGeometry(errorRate float64, capacity int64) (bits, hashes int64, err error) {
+	m := math.Ceil(-float64(capacity) * math.Log(errorRate) / (math.Ln2 * math.Ln2))
+	if m > MaxBloomBits {
+		return 0, 0, ErrBloomTooLarge
+	}
+	bits = (max(int64(m), 8) + 7) / 8 * 8
+	hashes = max(int64(math.Round(float64(bits)/float64(capacity)*math.Ln2)), 1)
+	return bits, hashes, nil
+}
+
+// BFReserve creates an empty Bloom filter of bits bits, setting hashes of them
+// per item, at key.
+//
+// Returns:
+//   - created: false when key already holds a Bloom filter; it is left as it
+//     is.
+//   - err: ErrWrongType if key holds something else.
+func (imc *InMemoryCommandRepository) BFReserve(ctx context.Context, key string, bits, hashes int64) (created bool, err error) {
+	imc.mu.Lock()
+	defer imc.mu.Unlock()
+
+	_, exists, _, err := imc.bloomLocked(key)
+	if err != nil || exists {
+		return false, err
+	}
+	imc.store[key] = types.ColumnValueWithTTL{Column: types.BloomFilter{Bits: make([]byte, bits/8), Hashes: hashes}}
+	imc.emit(EventSet, key)
+	return true, nil
+}
+
+// BFAdd adds items to the Bloom filter at key, creating it with bits bits and
+// hashes hashes per item if needed; an existing filter keeps its geometry.
+//
+// Returns:
+//   - added: For each item, whether it is new to the filter. A false positive
+//     reports an item as not new.
+//   - err: ErrWrongType if key holds something other than a Bloom filter.
+func (imc *InMemoryCommandRepository) BFAdd(ctx context.Context, key string, items []string, bits, hashes int64) (added []bool, err error) {
+	imc.mu.Lock()
+	defer imc.mu.Unlock()
+
+	filter, exists, expiration, err := imc.bloomLocked(key)
+	if err != nil {
+		return nil, err
+	}
+	if !exists {
+		filter = types.BloomFilter{Bits: make([]byte, bits/8), Hashes: hashes}
+	}
+	changed := !exists
+	added = make([]bool, len(items))
+	for i, item := range items {
+		for _, bit := range bloomBits(filter, item) {
+			mask := byte(1) << (bit % 8)
+			if filter.Bits[bit/8]&mask != 0 {
+				continue
+			}
+			if !changed {
+				// The bitmap is shared with snapshots: copy it before the
+				// first change.
+				filter.Bits = slices.Clone(filter.Bits)
+				changed = true
+			}
+			filter.Bits[bit/8] |= mask
+			added[i] = true
+		}
+	}
+	if changed {
+		imc.store[key] = types.ColumnValueWithTTL{Column: filter, Expiration: expiration}
+		imc.emit(EventSet, key)
+	}
+	return added, nil
+}
+
+// BFExists reports, for each of items, whether it may have been added to the
+// Bloom filter at key. false is definite; true is wrong at most as often as
+// the filter's error rate while it holds no more than its capacity.
+func (imc *InMemoryCommandRepository) BFExists(ctx context.Context, key string, items []string) (exists []bool, err error) {
+	imc.mu.RLock()
+	defer imc.mu.RUnlock()
+
+	filter, found, _, err := imc.bloomLocked(key)
+	if err != nil {
+		return nil, err
+	}
+	exists = make([]bool, len(items))
+	if !found {
+		return exists, nil
+	}
+	for i, item := range items {
+		exists[i] = true
+		for _, bit := range bloomBits(filter, item) {
+			if filter.Bits[bit/8]&(1<<(bit%8)) == 0 {
+				exists[i] = false
+				break
+			}
+		}
+	}
+	return exists, nil
+}
+
+// bloomLocked returns the Bloom filter at key and its expiration, and whether
+// there is one. The caller must hold imc.mu.
+func (imc *InMemoryCommandRepository) bloomLocked(key string) (filter types.BloomFilter, exists bool, expiration time.Time, err error) {
+	entry, ok := imc.store[key]
+	if !ok || (!entry.Expiration.IsZero() && imc.now().After(entry.Expiration)) {
+		return types.BloomFilter{}, false, time.Time{}, nil
+	}
+	filter, ok = entry.Column.(types.BloomFilter)
+	if !ok {
+		return types.BloomFilter{}, false, time.Time{}, ErrWrongType
+	}
+	return filter, true, entry.Expiration, nil
+}
+
+// bloomBits returns the bits of filter that item sets. They derive from the
+// two halves of item's 128-bit FNV-1a hash by double hashing, so they are the
+// same on every node and across restarts.
+func bloomBits(filter types.BloomFilter, item string) []uint64 {
+	h := fnv.New128a()
+	h.Write([]byte(item))
+	sum := h.Sum(nil)
+	h1 := binary.BigEndian.Uint64(sum[:8])
+	h2 := binary.BigEndian.Uint64(sum[8:])
+	m := uint64(len(filter.Bits)) * 8
+	bits := make([]uint64, filter.Hashes)
+	for i := range bits {
+		bits[i] = (h1 + uint64(i)*h2) % m
+	}
+	return bits
+}
@@ -0,0 +1,83 @@
+package core
+
+import (
+	"context"
+	"fmt"
+	"testing"
+	"time"
+
+	"github.com/mateenbagheri/memorabilia/pkg/types"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestBloomGeometry(t *testing.T) {
+	bits, hashes, err := BloomGeometry(0.01, 1000)
+	require.NoError(t, err)
+	assert.Equal(t, int64(9592), bits)
+	assert.Equal(t, int64(7), hashes)
+
+	_, _, err = BloomGeometry(1e-9, 1<<30)
+	assert.ErrorIs(t, err, ErrBloomTooLarge)
+}
+
+func TestInMemoryCommandRepository_Bloom(t *testing.T) {
+	ctx := context.Background()
+	imc := NewInMemoryCommandRepository()
+	bits, hashes, err := BloomGeometry(0.01, 1000)
+	require.NoError(t, err)
+
+	created, err := imc.BFReserve(ctx, "bf", bits, hashes)
+	require.NoError(t, err)
+	assert.True(t, created)
+	created, err = imc.BFReserve(ctx, "bf", 8, 1)
+	require.NoError(t, err)
+	assert.False(t, created)
+
+	added, err := imc.BFAdd(ctx, "bf", []string{"a", "b", "a"}, 8, 1)
+	require.NoError(t, err)
+	assert.Equal(t, []bool{true, true, false}, added)
+	exists, err := imc.BFExists(ctx, "bf", []string{"a", "b", "c"})
+	require.NoError(t, err)
+	assert.Equal(t, []bool{true, true, false}, exists)
+	exists, err = imc.BFExists(ctx, "missing", []string{"a"})
+	require.NoError(t, err)
+	assert.Equal(t, []bool{false}, exists)
+
+	// The filter kept the geometry it was reserved with.
+	snapshot := imc.store["bf"].Column.(types.BloomFilter)
+	assert.Len(t, snapshot.Bits, int(bits/8))
+	assert.Equal(t, hashes, snapshot.Hashes)
+
+	// Values already handed out, to snapshots say, never change.
+	before := append([]byte(nil), snapshot.Bits...)
+	_, err = imc.BFAdd(ctx, "bf", []string{"d"}, 8, 1)
+	require.NoError(t, err)
+	assert.Equal(t, before, snapshot.Bits)
+
+	// The false positive rate stays within the error rate at capacity.
+	items := make([]string, 1000)
+	for i := range items {
+		items[i] = fmt.Sprint("item-", i)
+	}
+	_, err = imc.BFAdd(ctx, "full", items, bits, hashes)
+	require.NoError(t, err)
+	for i := range items {
+		items[i] = fmt.Sprint("other-", i)
+	}
+	exists, err = imc.BFExists(ctx, "full", items)
+	require.NoError(t, err)
+	var positives int
+	for _, e := range exists {
+		if e {
+			positives++
+		}
+	}
+	assert.LessOrEqual(t, positives, 20)
+
+	require.NoError(t, imc.Set(ctx, "str", "1", time.Time{}))
+	_, err = imc.BFAdd(ctx, "str", []string{"a"}, bits, hashes)
+	assert.ErrorIs(t, err, ErrWrongType)
+	_, err = imc.Get(ctx, "bf")
+	assert.ErrorIs(t, err, ErrWrongType)
+}
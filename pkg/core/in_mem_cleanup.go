@@ -10,7 +10,7 @@ func (r *InMemoryCommandRepository) Cleanup(ctx context.Context) (deleteCount in
 		return 0, err
 	}
 
-	deleteCount = r.BatchDelete(ctx, keys)
+	deleteCount = int64(len(r.Expire(ctx, keys)))
 
 	return deleteCount, nil
 }
@@ -12,16 +12,30 @@ var _ CommandsRepository = (*InMemoryCommandRepository)(nil)
 type InMemoryCommandRepository struct {
 	mu    sync.RWMutex
 	store map[string]types.ColumnValueWithTTL
+
+	// sessions are the client sessions, by ID. Their ephemeral keys name
+	// them in the store.
+	sessions map[string]types.Session
+
+	// clock decides expiry; nil means the local wall clock.
+	clock Clock
+
+	// notify receives the keyspace events; nil when nobody listens.
+	notify KeyspaceNotifier
 }
 
 func NewInMemoryCommandRepository() *InMemoryCommandRepository {
 	return &InMemoryCommandRepository{
-		store: make(map[string]types.ColumnValueWithTTL),
+		store:    make(map[string]types.ColumnValueWithTTL),
+		sessions: make(map[string]types.Session),
+		clock:    SystemClock,
 	}
 }
 
 func NewInMemoryCommandRepositoryWithInitialStore(store map[string]types.ColumnValueWithTTL) *InMemoryCommandRepository {
 	return &InMemoryCommandRepository{
-		store: store,
+		store:    store,
+		sessions: make(map[string]types.Session),
+		clock:    SystemClock,
 	}
 }
@@ -5,14 +5,26 @@ import (
 )
 
 // Delete removes a single key-value pair from the in-memory store if it exists.
+// It ensures thread safety by locking the repository during the operation.
 //
 // Parameters:
 //   - ctx: Context for request-scoped values, cancellation, and deadlines.
 //   - key: The key of the key-value pair to delete.
+//
+// Returns:
+//   - deleteCount: 1 if the key existed, 0 otherwise.
 func (imc *InMemoryCommandRepository) Delete(ctx context.Context, key string) (deleteCount int64) {
-	if val, exists := imc.store[key]; exists {
+	imc.mu.Lock()
+	defer imc.mu.Unlock()
+	return imc.deleteLocked(key, EventDel)
+}
+
+// deleteLocked is Delete without the locking, reporting the removal as
+// eventType; the caller must hold imc.mu.
+func (imc *InMemoryCommandRepository) deleteLocked(key string, eventType KeyspaceEventType) (deleteCount int64) {
+	if _, exists := imc.store[key]; exists {
 		delete(imc.store, key)
-		_ = val.Column.Type()
+		imc.emit(eventType, key)
 		return 1
 	}
 	return 0
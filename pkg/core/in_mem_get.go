@@ -3,7 +3,8 @@ package core
 import (
 	"context"
 	"errors"
-	"time"
+
+	"github.com/mateenbagheri/memorabilia/pkg/types"
 )
 
 var ErrNotFoundForGetOp = errors.New("value for given key was not found")
@@ -27,9 +28,13 @@ func (imc *InMemoryCommandRepository) Get(ctx context.Context, key string) (valu
 		return "", ErrNotFoundForGetOp
 	}
 
-	if !valueWithTTL.Expiration.IsZero() && time.Now().After(valueWithTTL.Expiration) {
+	if !valueWithTTL.Expiration.IsZero() && imc.now().After(valueWithTTL.Expiration) {
 		return "", ErrKeyExpiredForGetOp
 	}
+	switch valueWithTTL.Column.(type) {
+	case types.List, types.Lock, types.Stream, types.RateLimiter, types.BloomFilter, types.HyperLogLog:
+		return "", ErrWrongType
+	}
 
 	return valueWithTTL.Column.ToString(), nil
 }
@@ -3,9 +3,13 @@ package core
 import (
 	"context"
 	"time"
+
+	"github.com/mateenbagheri/memorabilia/pkg/types"
 )
 
-// GetExpiredKeys returns a list of keys whose expiration time has passed.
+// GetExpiredKeys returns a list of keys whose expiration time has passed,
+// and the ephemeral keys whose session is gone: keys that were attached to a
+// session while it was being closed, or restored from a backup without it.
 //
 // Parameters:
 //   - ctx: Context for request-scoped values, cancellation, and deadlines.
@@ -17,11 +21,22 @@ func (imc *InMemoryCommandRepository) GetExpiredKeys(ctx context.Context) (keys
 	imc.mu.RLock()
 	defer imc.mu.RUnlock()
 
-	now := time.Now()
+	now := imc.now()
 	for key, val := range imc.store {
-		if !val.Expiration.IsZero() && now.After(val.Expiration) {
+		if imc.expiredLocked(val, now) {
 			keys = append(keys, key)
 		}
 	}
 	return keys, nil
 }
+
+// expiredLocked reports whether the TTL cleanup removes val as of now: when
+// its expiration has passed, or when it is an ephemeral key whose session is
+// gone. The caller must hold imc.mu.
+func (imc *InMemoryCommandRepository) expiredLocked(val types.ColumnValueWithTTL, now time.Time) bool {
+	if !val.Expiration.IsZero() && now.After(val.Expiration) {
+		return true
+	}
+	_, ok := imc.sessions[val.Session]
+	return val.Session != "" && !ok
+}
@@ -0,0 +1,53 @@
+package core
+
+import (
+	"context"
+	"time"
+
+	"github.com/mateenbagheri/memorabilia/pkg/types"
+)
+
+// GetSet stores value under key like Set, and returns the value it replaced.
+// The read and the write happen under one lock, so no other write can slip
+// in between them.
+//
+// Parameters:
+//   - ctx: Context for request-scoped values, cancellation, and deadlines.
+//   - key: The key to associate with the value.
+//   - value: The value to store.
+//   - expiration: The expiration time for the new value, time.Time{} for none.
+//
+// Returns:
+//   - previous: The replaced value, as a string.
+//   - existed: Whether there was a live value to replace. An expired value
+//     counts as absent, the same as for Get.
+//   - err: An error if any issues occur during the operation.
+func (imc *InMemoryCommandRepository) GetSet(
+	ctx context.Context,
+	key, value string,
+	expiration time.Time,
+) (previous string, existed bool, err error) {
+	imc.mu.Lock()
+	defer imc.mu.Unlock()
+	previous, existed = imc.getSetLocked(key, value, expiration, "")
+	return previous, existed, nil
+}
+
+// getSetLocked is GetSet without the locking, making key ephemeral when
+// session isn't empty; the caller must hold imc.mu.
+func (imc *InMemoryCommandRepository) getSetLocked(key, value string, expiration time.Time, session string) (previous string, existed bool) {
+	if old, ok := imc.store[key]; ok {
+		if old.Expiration.IsZero() || imc.now().Before(old.Expiration) {
+			previous, existed = old.Column.ToString(), true
+		}
+	}
+
+	_, columnValue := types.DetectColumnType(value)
+	imc.store[key] = types.ColumnValueWithTTL{
+		Column:     columnValue,
+		Expiration: expiration,
+		Session:    session,
+	}
+	imc.emit(EventSet, key)
+	return previous, existed
+}
@@ -0,0 +1,56 @@
+package core
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestInMemoryCommandRepository_GetSet(t *testing.T) {
+	ctx := context.Background()
+
+	tests := []struct {
+		name         string
+		setup        func(imc *InMemoryCommandRepository)
+		wantPrevious string
+		wantExisted  bool
+	}{
+		{
+			name:  "new key",
+			setup: func(imc *InMemoryCommandRepository) {},
+		},
+		{
+			name: "overwrites live key",
+			setup: func(imc *InMemoryCommandRepository) {
+				_ = imc.Set(ctx, "k", "old", time.Time{})
+			},
+			wantPrevious: "old",
+			wantExisted:  true,
+		},
+		{
+			name: "expired key counts as absent",
+			setup: func(imc *InMemoryCommandRepository) {
+				_ = imc.Set(ctx, "k", "old", time.Now().Add(-time.Second))
+			},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			imc := NewInMemoryCommandRepository()
+			tt.setup(imc)
+
+			previous, existed, err := imc.GetSet(ctx, "k", "new", time.Time{})
+			require.NoError(t, err)
+			assert.Equal(t, tt.wantPrevious, previous)
+			assert.Equal(t, tt.wantExisted, existed)
+
+			got, err := imc.Get(ctx, "k")
+			require.NoError(t, err)
+			assert.Equal(t, "new", got)
+		})
+	}
+}
@@ -0,0 +1,171 @@
+package core
+
+import (
+	"context"
+	"hash/fnv"
+	"math"
+	"math/bits"
+	"slices"
+	"time"
+
+	"github.com/mateenbagheri/memorabilia/pkg/types"
+)
+
+// hllPrecision is the number of hash bits that pick a HyperLogLog register:
+// 2^14 registers give a standard error of about 0.81%.
+const (
+	hllPrecision = 14
+	hllRegisters = 1 << hllPrecision
+)
+
+// PFAdd adds items to the HyperLogLog at key, creating it if needed.
+//
+// Returns:
+//   - changed: Whether the HyperLogLog was created or its estimate may have
+//     changed.
+//   - err: ErrWrongType if key holds something other than a HyperLogLog.
+func (imc *InMemoryCommandRepository) PFAdd(ctx context.Context, key string, items []string) (changed bool, err error) {
+	imc.mu.Lock()
+	defer imc.mu.Unlock()
+
+	hll, exists, expiration, err := imc.hyperLogLogLocked(key)
+	if err != nil {
+		return false, err
+	}
+	if !exists {
+		hll = types.HyperLogLog{Registers: make([]byte, hllRegisters)}
+	}
+	changed = !exists
+	for _, item := range items {
+		register, rank := hllRegister(item)
+		if hll.Registers[register] >= rank {
+			continue
+		}
+		if !changed {
+			// The registers are shared with snapshots: copy them before the
+			// first change.
+			hll.Registers = slices.Clone(hll.Registers)
+			changed = true
+		}
+		hll.Registers[register] = rank
+	}
+	if changed {
+		imc.store[key] = types.ColumnValueWithTTL{Column: hll, Expiration: expiration}
+		imc.emit(EventSet, key)
+	}
+	return changed, nil
+}
+
+// PFCount estimates the number of distinct items added to the HyperLogLogs at
+// keys, counting those added to several of them once. Missing keys count as
+// empty.
+//
+// Returns:
+//   - count: The estimate.
+//   - err: ErrWrongType if one of keys holds something other than a
+//     HyperLogLog.
+func (imc *InMemoryCommandRepository) PFCount(ctx context.Context, keys []string) (count int64, err error) {
+	imc.mu.RLock()
+	defer imc.mu.RUnlock()
+
+	registers, err := imc.hyperLogLogUnionLocked(nil, keys)
+	if err != nil {
+		return 0, err
+	}
+	return hllEstimate(registers), nil
+}
+
+// PFMerge stores the union of the HyperLogLogs at dest and sources at dest,
+// so that it counts every item added to any of them. Missing keys count as
+// empty; dest is created if needed and keeps its expiration otherwise.
+func (imc *InMemoryCommandRepository) PFMerge(ctx context.Context, dest string, sources []string) error {
+	imc.mu.Lock()
+	defer imc.mu.Unlock()
+
+	hll, _, expiration, err := imc.hyperLogLogLocked(dest)
+	if err != nil {
+		return err
+	}
+	registers, err := imc.hyperLogLogUnionLocked(hll.Registers, sources)
+	if err != nil {
+		return err
+	}
+	imc.store[dest] = types.ColumnValueWithTTL{Column: types.HyperLogLog{Registers: registers}, Expiration: expiration}
+	imc.emit(EventSet, dest)
+	return nil
+}
+
+// hyperLogLogLocked returns the HyperLogLog at key and its expiration, and
+// whether there is one. The caller must hold imc.mu.
+func (imc *InMemoryCommandRepository) hyperLogLogLocked(key string) (hll types.HyperLogLog, exists bool, expiration time.Time, err error) {
+	entry, ok := imc.store[key]
+	if !ok || (!entry.Expiration.IsZero() && imc.now().After(entry.Expiration)) {
+		return types.HyperLogLog{}, false, time.Time{}, nil
+	}
+	hll, ok = entry.Column.(types.HyperLogLog)
+	if !ok {
+		return types.HyperLogLog{}, false, time.Time{}, ErrWrongType
+	}
+	return hll, true, entry.Expiration, nil
+}
+
+// hyperLogLogUnionLocked returns a new set of registers holding the union of
+// registers, which may be nil, and the HyperLogLogs at keys. The caller must
+// hold imc.mu.
+func (imc *InMemoryCommandRepository) hyperLogLogUnionLocked(registers []byte, keys []string) ([]byte, error) {
+	union := make([]byte, hllRegisters)
+	copy(union, registers)
+	for _, key := range keys {
+		hll, _, _, err := imc.hyperLogLogLocked(key)
+		if err != nil {
+			return nil, err
+		}
+		for i, rank := range hll.Registers {
+			union[i] = max(union[i], rank)
+		}
+	}
+	return union, nil
+}
+
+// hllRegister returns the register item falls in and its rank there: the
+// position of the first set bit in the rest of its hash. The hash is FNV-1a,
+// finalized so that all of its bits depend on all of item, which keeps the
+// registers the same on every node and across restarts.
+func hllRegister(item string) (register int, rank byte) {
+	h := fnv.New64a()
+	h.Write([]byte(item))
+	x := fmix64(h.Sum64())
+	register = int(x >> (64 - hllPrecision))
+	rank = byte(bits.LeadingZeros64(x<<hllPrecision|1<<(hllPrecision-1))) + 1
+	return register, rank
+}
+
+// fmix64 is MurmurHash3's finalizer, which mixes every bit of x into all
+// bits of the result.
+func fmix64(x uint64) uint64 {
+	x ^= x >> 33
+	x *= 0xff51afd7ed558ccd
+	x ^= x >> 33
+	x *= 0xc4ceb9fe1a85ec53
+	x ^= x >> 33
+	return x
+}
+
+// hllEstimate returns the HyperLogLog estimate of registers, corrected by
+// linear counting for small cardinalities.
+func hllEstimate(registers []byte) int64 {
+	const m = float64(hllRegisters)
+	var sum float64
+	var zeros int
+	for _, rank := range registers {
+		sum += math.Ldexp(1, -int(rank))
+		if rank == 0 {
+			zeros++
+		}
+	}
+	estimate := 0.7213 / (1 + 1.079/m) * m * m / sum
+	if estimate <= 2.5*m && zeros > 0 {
+		estimate = m * math.Log(m/float64(zeros))
+	}
+	return int64(math.Round(estimate))
+}
@@ -0,0 +1,63 @@
+package core
+
+import (
+	"context"
+	"fmt"
+	"testing"
+	"time"
+
+	"github.com/mateenbagheri/memorabilia/pkg/types"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestInMemoryCommandRepository_HyperLogLog(t *testing.T) {
+	ctx := context.Background()
+	imc := NewInMemoryCommandRepository()
+
+	changed, err := imc.PFAdd(ctx, "hll", []string{"a", "b", "c"})
+	require.NoError(t, err)
+	assert.True(t, changed)
+	changed, err = imc.PFAdd(ctx, "hll", []string{"a", "b"})
+	require.NoError(t, err)
+	assert.False(t, changed)
+	count, err := imc.PFCount(ctx, []string{"hll"})
+	require.NoError(t, err)
+	assert.Equal(t, int64(3), count)
+	count, err = imc.PFCount(ctx, []string{"missing"})
+	require.NoError(t, err)
+	assert.Zero(t, count)
+
+	// Estimates of large cardinalities stay within a few standard errors.
+	items := make([]string, 0, 100_000)
+	for i := 0; i < 100_000; i++ {
+		items = append(items, fmt.Sprint("item-", i))
+	}
+	_, err = imc.PFAdd(ctx, "first", items[:60_000])
+	require.NoError(t, err)
+	_, err = imc.PFAdd(ctx, "second", items[40_000:])
+	require.NoError(t, err)
+	count, err = imc.PFCount(ctx, []string{"first", "second"})
+	require.NoError(t, err)
+	assert.InEpsilon(t, 100_000, count, 0.03)
+
+	snapshot := imc.store["first"].Column.(types.HyperLogLog)
+	before := append([]byte(nil), snapshot.Registers...)
+	require.NoError(t, imc.PFMerge(ctx, "first", []string{"second", "missing"}))
+	assert.Equal(t, before, snapshot.Registers)
+	merged, err := imc.PFCount(ctx, []string{"first"})
+	require.NoError(t, err)
+	assert.Equal(t, count, merged)
+
+	require.NoError(t, imc.PFMerge(ctx, "empty", nil))
+	count, err = imc.PFCount(ctx, []string{"empty"})
+	require.NoError(t, err)
+	assert.Zero(t, count)
+
+	require.NoError(t, imc.Set(ctx, "str", "1", time.Time{}))
+	_, err = imc.PFCount(ctx, []string{"hll", "str"})
+	assert.ErrorIs(t, err, ErrWrongType)
+	assert.ErrorIs(t, imc.PFMerge(ctx, "hll", []string{"str"}), ErrWrongType)
+	_, err = imc.Get(ctx, "hll")
+	assert.ErrorIs(t, err, ErrWrongType)
+}
@@ -0,0 +1,111 @@
+package core
+
+import (
+	"context"
+	"errors"
+	"slices"
+	"time"
+
+	"github.com/mateenbagheri/memorabilia/pkg/types"
+)
+
+// ErrWrongType is returned by operations against a key holding another kind
+// of value than they work on, like a list push to a key holding a string.
+var ErrWrongType = errors.New("operation against a key holding the wrong kind of value")
+
+// Push adds values to the list at key, creating it if needed: at its head
+// when left is set, one after the other like Redis' LPUSH, so the last value
+// ends up first; at its tail otherwise.
+//
+// Returns:
+//   - length: The length of the list after the push.
+//   - err: ErrWrongType if key holds something other than a list.
+func (imc *InMemoryCommandRepository) Push(ctx context.Context, key string, values []string, left bool) (length int64, err error) {
+	imc.mu.Lock()
+	defer imc.mu.Unlock()
+
+	list, expiration, err := imc.listLocked(key)
+	if err != nil {
+		return 0, err
+	}
+	if left {
+		val := make([]string, 0, len(values)+len(list.Val))
+		for i := len(values) - 1; i >= 0; i-- {
+			val = append(val, values[i])
+		}
+		list.Val = append(val, list.Val...)
+	} else {
+		list.Val = append(list.Val, values...)
+	}
+	imc.storeListLocked(key, list, expiration)
+	return int64(len(list.Val)), nil
+}
+
+// Pop removes an element from the first of keys holding a non-empty list:
+// its first element when left is set, its last one otherwise. A list left
+// empty is deleted.
+//
+// Returns:
+//   - key, value: The key popped from, and the element.
+//   - ok: false when every list is empty or missing.
+//   - err: ErrWrongType if one of keys holds something other than a list.
+func (imc *InMemoryCommandRepository) Pop(ctx context.Context, keys []string, left bool) (key, value string, ok bool, err error) {
+	imc.mu.Lock()
+	defer imc.mu.Unlock()
+
+	for _, key := range keys {
+		list, expiration, err := imc.listLocked(key)
+		if err != nil {
+			return "", "", false, err
+		}
+		if len(list.Val) == 0 {
+			continue
+		}
+		if left {
+			value, list.Val = list.Val[0], list.Val[1:]
+		} else {
+			last := len(list.Val) - 1
+			// Clipped, so that a later push doesn't overwrite the popped
+			// element in an array a snapshot still holds.
+			value, list.Val = list.Val[last], slices.Clip(list.Val[:last])
+		}
+		imc.storeListLocked(key, list, expiration)
+		return key, value, true, nil
+	}
+	return "", "", false, nil
+}
+
+// Len returns the length of the list at key, 0 when it is missing. Jobs in
+// flight (see QueuePop) don't count.
+func (imc *InMemoryCommandRepository) Len(ctx context.Context, key string) (length int64, err error) {
+	imc.mu.RLock()
+	defer imc.mu.RUnlock()
+
+	list, _, err := imc.listLocked(key)
+	return int64(len(list.Val)), err
+}
+
+// listLocked returns the list at key and its expiration: an empty list when
+// key is missing or has expired. The caller must hold imc.mu.
+func (imc *InMemoryCommandRepository) listLocked(key string) (list types.List, expiration time.Time, err error) {
+	entry, ok := imc.store[key]
+	if !ok || (!entry.Expiration.IsZero() && imc.now().After(entry.Expiration)) {
+		return types.List{}, time.Time{}, nil
+	}
+	list, ok = entry.Column.(types.List)
+	if !ok {
+		return types.List{}, time.Time{}, ErrWrongType
+	}
+	return list, entry.Expiration, nil
+}
+
+// storeListLocked stores list at key, or deletes key when the list is empty
+// and has no jobs in flight. The caller must hold imc.mu.
+func (imc *InMemoryCommandRepository) storeListLocked(key string, list types.List, expiration time.Time) {
+	if len(list.Val) == 0 && len(list.InFlight) == 0 {
+		imc.deleteLocked(key, EventDel)
+		return
+	}
+	imc.store[key] = types.ColumnValueWithTTL{Column: list, Expiration: expiration}
+	imc.emit(EventSet, key)
+}
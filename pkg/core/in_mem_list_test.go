@@ -0,0 +1,75 @@
+package core
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestInMemoryCommandRepository_PushPop(t *testing.T) {
+	ctx := context.Background()
+	imc := NewInMemoryCommandRepository()
+
+	length, err := imc.Push(ctx, "l", []string{"b", "c"}, false)
+	require.NoError(t, err)
+	assert.Equal(t, int64(2), length)
+	length, err = imc.Push(ctx, "l", []string{"a", "z"}, true)
+	require.NoError(t, err)
+	assert.Equal(t, int64(4), length)
+
+	// The empty and missing lists are skipped.
+	key, value, ok, err := imc.Pop(ctx, []string{"missing", "l"}, true)
+	require.NoError(t, err)
+	assert.True(t, ok)
+	assert.Equal(t, "l", key)
+	assert.Equal(t, "z", value)
+	_, value, _, err = imc.Pop(ctx, []string{"l"}, false)
+	require.NoError(t, err)
+	assert.Equal(t, "c", value)
+
+	// A push after a pop from the tail leaves a snapshot taken before alone.
+	dump, err := imc.Dump()
+	require.NoError(t, err)
+	_, err = imc.Push(ctx, "l", []string{"d"}, false)
+	require.NoError(t, err)
+	assert.Equal(t, `["a","b"]`, dump["l"].Column.ToString())
+
+	length, err = imc.Len(ctx, "l")
+	require.NoError(t, err)
+	assert.Equal(t, int64(3), length)
+	for _, want := range []string{"a", "b", "d"} {
+		_, value, ok, err = imc.Pop(ctx, []string{"l"}, true)
+		require.NoError(t, err)
+		require.True(t, ok)
+		assert.Equal(t, want, value)
+	}
+	_, _, ok, err = imc.Pop(ctx, []string{"l"}, true)
+	require.NoError(t, err)
+	assert.False(t, ok)
+	_, err = imc.TTL(ctx, "l")
+	assert.ErrorIs(t, err, ErrNotFoundForTTLOp, "an emptied list is deleted")
+}
+
+func TestInMemoryCommandRepository_List_WrongType(t *testing.T) {
+	ctx := context.Background()
+	imc := NewInMemoryCommandRepository()
+	require.NoError(t, imc.Set(ctx, "s", "v", time.Time{}))
+	_, err := imc.Push(ctx, "l", []string{"a"}, false)
+	require.NoError(t, err)
+
+	_, err = imc.Push(ctx, "s", []string{"a"}, false)
+	assert.ErrorIs(t, err, ErrWrongType)
+	_, _, _, err = imc.Pop(ctx, []string{"s", "l"}, true)
+	assert.ErrorIs(t, err, ErrWrongType)
+	_, err = imc.Get(ctx, "l")
+	assert.ErrorIs(t, err, ErrWrongType)
+
+	// Set replaces a list like any other value.
+	require.NoError(t, imc.Set(ctx, "l", "v", time.Time{}))
+	value, err := imc.Get(ctx, "l")
+	require.NoError(t, err)
+	assert.Equal(t, "v", value)
+}
@@ -0,0 +1,92 @@
+package core
+
+import (
+	"context"
+	"time"
+
+	"github.com/mateenbagheri/memorabilia/pkg/types"
+)
+
+// Lock acquires the lock at key for session, with the fencing token token and
+// a lease ending lease from now. A session acquiring a lock it already holds
+// keeps its token and gets a new lease, so that retrying a lost response is
+// safe. A lock whose lease has run out is free.
+//
+// Returns:
+//   - holder: The lock as it is now: session's, or its current holder's.
+//   - expiration: The end of holder's lease.
+//   - acquired: Whether session holds the lock.
+//   - err: ErrWrongType if key holds something other than a lock.
+func (imc *InMemoryCommandRepository) Lock(ctx context.Context, key, session string, lease time.Duration, token uint64) (holder types.Lock, expiration time.Time, acquired bool, err error) {
+	imc.mu.Lock()
+	defer imc.mu.Unlock()
+
+	holder, expiration, held, err := imc.lockLocked(key)
+	if err != nil {
+		return types.Lock{}, time.Time{}, false, err
+	}
+	if held && holder.Session != session {
+		return holder, expiration, false, nil
+	}
+	expiration = imc.now().Add(lease)
+	if held {
+		imc.store[key] = types.ColumnValueWithTTL{Column: holder, Expiration: expiration}
+		return holder, expiration, true, nil
+	}
+	holder = types.Lock{Session: session, Token: token}
+	imc.store[key] = types.ColumnValueWithTTL{Column: holder, Expiration: expiration}
+	imc.emit(EventSet, key)
+	return holder, expiration, true, nil
+}
+
+// Unlock releases the lock at key, provided session holds it with the
+// fencing token token. It reports whether it did.
+func (imc *InMemoryCommandRepository) Unlock(ctx context.Context, key, session string, token uint64) (released bool, err error) {
+	imc.mu.Lock()
+	defer imc.mu.Unlock()
+
+	holder, _, held, err := imc.lockLocked(key)
+	if err != nil || !held || holder != (types.Lock{Session: session, Token: token}) {
+		return false, err
+	}
+	imc.deleteLocked(key, EventDel)
+	return true, nil
+}
+
+// RefreshLock extends the lease of the lock at key to lease from now,
+// provided session holds it with the fencing token token. It returns the end
+// of the new lease, and whether it extended it.
+func (imc *InMemoryCommandRepository) RefreshLock(ctx context.Context, key, session string, token uint64, lease time.Duration) (expiration time.Time, refreshed bool, err error) {
+	imc.mu.Lock()
+	defer imc.mu.Unlock()
+
+	holder, _, held, err := imc.lockLocked(key)
+	if err != nil || !held || holder != (types.Lock{Session: session, Token: token}) {
+		return time.Time{}, false, err
+	}
+	expiration = imc.now().Add(lease)
+	imc.store[key] = types.ColumnValueWithTTL{Column: holder, Expiration: expiration}
+	return expiration, true, nil
+}
+
+// LockHolder returns the lock at key and the end of its lease. held is false
+// when the lock is free.
+func (imc *InMemoryCommandRepository) LockHolder(ctx context.Context, key string) (holder types.Lock, expiration time.Time, held bool, err error) {
+	imc.mu.RLock()
+	defer imc.mu.RUnlock()
+	return imc.lockLocked(key)
+}
+
+// lockLocked returns the lock at key and the end of its lease; held is false
+// when key is missing or the lease has run out. The caller must hold imc.mu.
+func (imc *InMemoryCommandRepository) lockLocked(key string) (holder types.Lock, expiration time.Time, held bool, err error) {
+	entry, ok := imc.store[key]
+	if !ok || (!entry.Expiration.IsZero() && imc.now().After(entry.Expiration)) {
+		return types.Lock{}, time.Time{}, false, nil
+	}
+	holder, ok = entry.Column.(types.Lock)
+	if !ok {
+		return types.Lock{}, time.Time{}, false, ErrWrongType
+	}
+	return holder, entry.Expiration, true, nil
+}
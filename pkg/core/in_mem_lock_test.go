@@ -0,0 +1,69 @@
+package core
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/mateenbagheri/memorabilia/pkg/types"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestInMemoryCommandRepository_Lock(t *testing.T) {
+	ctx := context.Background()
+	t0 := time.Date(2001, 1, 1, 0, 0, 0, 0, time.UTC)
+	imc := NewInMemoryCommandRepository()
+	imc.SetClock(fixedClock(t0))
+
+	holder, expiration, acquired, err := imc.Lock(ctx, "l", "a", time.Second, 10)
+	require.NoError(t, err)
+	assert.True(t, acquired)
+	assert.Equal(t, types.Lock{Session: "a", Token: 10}, holder)
+	assert.Equal(t, t0.Add(time.Second), expiration)
+
+	// Another session gets the current holder; the holder itself keeps its
+	// token and gets a new lease.
+	holder, _, acquired, err = imc.Lock(ctx, "l", "b", time.Second, 11)
+	require.NoError(t, err)
+	assert.False(t, acquired)
+	assert.Equal(t, "a", holder.Session)
+	holder, expiration, acquired, err = imc.Lock(ctx, "l", "a", time.Minute, 12)
+	require.NoError(t, err)
+	assert.True(t, acquired)
+	assert.Equal(t, uint64(10), holder.Token)
+	assert.Equal(t, t0.Add(time.Minute), expiration)
+
+	// Releasing and refreshing take the holder's session and token.
+	released, err := imc.Unlock(ctx, "l", "a", 12)
+	require.NoError(t, err)
+	assert.False(t, released)
+	_, refreshed, err := imc.RefreshLock(ctx, "l", "b", 10, time.Hour)
+	require.NoError(t, err)
+	assert.False(t, refreshed)
+	expiration, refreshed, err = imc.RefreshLock(ctx, "l", "a", 10, time.Hour)
+	require.NoError(t, err)
+	assert.True(t, refreshed)
+	assert.Equal(t, t0.Add(time.Hour), expiration)
+	released, err = imc.Unlock(ctx, "l", "a", 10)
+	require.NoError(t, err)
+	assert.True(t, released)
+	_, _, held, err := imc.LockHolder(ctx, "l")
+	require.NoError(t, err)
+	assert.False(t, held)
+
+	// A lock whose lease ran out is free.
+	_, _, _, err = imc.Lock(ctx, "l", "a", time.Second, 13)
+	require.NoError(t, err)
+	imc.SetClock(fixedClock(t0.Add(2 * time.Second)))
+	holder, _, acquired, err = imc.Lock(ctx, "l", "b", time.Second, 14)
+	require.NoError(t, err)
+	assert.True(t, acquired)
+	assert.Equal(t, types.Lock{Session: "b", Token: 14}, holder)
+
+	_, err = imc.Get(ctx, "l")
+	assert.ErrorIs(t, err, ErrWrongType)
+	require.NoError(t, imc.Set(ctx, "s", "v", time.Time{}))
+	_, _, _, err = imc.Lock(ctx, "s", "a", time.Second, 15)
+	assert.ErrorIs(t, err, ErrWrongType)
+}
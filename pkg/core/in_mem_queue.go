@@ -0,0 +1,116 @@
+package core
+
+import (
+	"context"
+	"slices"
+	"strconv"
+	"time"
+
+	"github.com/mateenbagheri/memorabilia/pkg/types"
+)
+
+// QueuePop pops the first element of the list at key as a job of a reliable
+// queue: the element moves to the list's jobs in flight until QueueAck
+// removes it or QueueNack puts it back, and goes back to the head of the
+// list if neither happens within visibility. Jobs whose deadline has passed
+// go back before popping, so they are popped again before newer elements.
+//
+// Returns:
+//   - job: The job, whose ID acknowledges it.
+//   - ok: false when the list is empty or missing.
+//   - err: ErrWrongType if key holds something other than a list.
+func (imc *InMemoryCommandRepository) QueuePop(ctx context.Context, key string, visibility time.Duration) (job types.Job, ok bool, err error) {
+	imc.mu.Lock()
+	defer imc.mu.Unlock()
+
+	list, expiration, err := imc.listLocked(key)
+	if err != nil {
+		return types.Job{}, false, err
+	}
+	now := imc.now()
+	list = requeueExpired(list, now)
+	if len(list.Val) == 0 {
+		return types.Job{}, false, nil
+	}
+
+	list.NextJob++
+	job = types.Job{
+		ID:       strconv.FormatUint(list.NextJob, 10),
+		Value:    list.Val[0],
+		Deadline: now.Add(visibility),
+	}
+	list.Val = list.Val[1:]
+	list.InFlight = append(list.InFlight, job)
+	imc.storeListLocked(key, list, expiration)
+	return job, true, nil
+}
+
+// QueueAck removes the job id from the jobs in flight of the list at key,
+// once it has been processed. It reports whether the job was in flight: it
+// isn't anymore once it went back to the list, after its deadline passed.
+func (imc *InMemoryCommandRepository) QueueAck(ctx context.Context, key, id string) (acked bool, err error) {
+	return imc.finishJob(key, id, false)
+}
+
+// QueueNack puts the job id in flight back at the head of the list at key,
+// to be popped again. It reports whether the job was in flight.
+func (imc *InMemoryCommandRepository) QueueNack(ctx context.Context, key, id string) (requeued bool, err error) {
+	return imc.finishJob(key, id, true)
+}
+
+// finishJob removes the job id from the jobs in flight of the list at key,
+// putting its element back at the head of the list when requeue is set.
+func (imc *InMemoryCommandRepository) finishJob(key, id string, requeue bool) (bool, error) {
+	imc.mu.Lock()
+	defer imc.mu.Unlock()
+
+	list, expiration, err := imc.listLocked(key)
+	if err != nil {
+		return false, err
+	}
+	i := slices.IndexFunc(list.InFlight, func(job types.Job) bool { return job.ID == id })
+	if i < 0 {
+		return false, nil
+	}
+	if requeue {
+		list.Val = append([]string{list.InFlight[i].Value}, list.Val...)
+	}
+	list.InFlight = append(slices.Clone(list.InFlight[:i]), list.InFlight[i+1:]...)
+	imc.storeListLocked(key, list, expiration)
+	return true, nil
+}
+
+// QueueLen counts the elements of the queue at key: those QueuePop would
+// pop, including the jobs whose deadline has passed, and the jobs still in
+// flight.
+func (imc *InMemoryCommandRepository) QueueLen(ctx context.Context, key string) (ready, inFlight int64, err error) {
+	imc.mu.RLock()
+	defer imc.mu.RUnlock()
+
+	list, _, err := imc.listLocked(key)
+	if err != nil {
+		return 0, 0, err
+	}
+	list = requeueExpired(list, imc.now())
+	return int64(len(list.Val)), int64(len(list.InFlight)), nil
+}
+
+// requeueExpired moves the jobs of list whose deadline has passed by now back
+// to the head of the list, in the order they were popped.
+func requeueExpired(list types.List, now time.Time) types.List {
+	var expired []string
+	var kept []types.Job
+	for _, job := range list.InFlight {
+		if now.After(job.Deadline) {
+			expired = append(expired, job.Value)
+		} else {
+			kept = append(kept, job)
+		}
+	}
+	if len(expired) == 0 {
+		return list
+	}
+	list.Val = append(expired, list.Val...)
+	list.InFlight = kept
+	return list
+}
@@ -0,0 +1,74 @@
+package core
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestInMemoryCommandRepository_Queue(t *testing.T) {
+	ctx := context.Background()
+	t0 := time.Date(2001, 1, 1, 0, 0, 0, 0, time.UTC)
+	imc := NewInMemoryCommandRepository()
+	imc.SetClock(fixedClock(t0))
+	_, err := imc.Push(ctx, "q", []string{"a", "b", "c"}, false)
+	require.NoError(t, err)
+
+	a, ok, err := imc.QueuePop(ctx, "q", time.Second)
+	require.NoError(t, err)
+	require.True(t, ok)
+	assert.Equal(t, "a", a.Value)
+	assert.Equal(t, t0.Add(time.Second), a.Deadline)
+	b, _, err := imc.QueuePop(ctx, "q", time.Minute)
+	require.NoError(t, err)
+	assert.Equal(t, "b", b.Value)
+	assert.NotEqual(t, a.ID, b.ID)
+	length, err := imc.Len(ctx, "q")
+	require.NoError(t, err)
+	assert.Equal(t, int64(1), length)
+
+	// a is nacked, and popped again before c.
+	requeued, err := imc.QueueNack(ctx, "q", a.ID)
+	require.NoError(t, err)
+	assert.True(t, requeued)
+	a, _, err = imc.QueuePop(ctx, "q", time.Second)
+	require.NoError(t, err)
+	assert.Equal(t, "a", a.Value)
+
+	// Once its deadline has passed, a goes back to the head of the queue:
+	// its ID doesn't ack anything anymore.
+	imc.SetClock(fixedClock(t0.Add(2 * time.Second)))
+	ready, inFlight, err := imc.QueueLen(ctx, "q")
+	require.NoError(t, err)
+	assert.Equal(t, int64(2), ready)
+	assert.Equal(t, int64(1), inFlight)
+	again, _, err := imc.QueuePop(ctx, "q", time.Second)
+	require.NoError(t, err)
+	assert.Equal(t, "a", again.Value)
+	acked, err := imc.QueueAck(ctx, "q", a.ID)
+	require.NoError(t, err)
+	assert.False(t, acked)
+
+	for _, job := range []string{again.ID, b.ID} {
+		acked, err = imc.QueueAck(ctx, "q", job)
+		require.NoError(t, err)
+		assert.True(t, acked)
+	}
+	c, _, err := imc.QueuePop(ctx, "q", time.Second)
+	require.NoError(t, err)
+	assert.Equal(t, "c", c.Value)
+	_, ok, err = imc.QueuePop(ctx, "q", time.Second)
+	require.NoError(t, err)
+	assert.False(t, ok)
+
+	// The key lives on while a job is in flight.
+	_, err = imc.TTL(ctx, "q")
+	require.NoError(t, err)
+	_, err = imc.QueueAck(ctx, "q", c.ID)
+	require.NoError(t, err)
+	_, err = imc.TTL(ctx, "q")
+	assert.ErrorIs(t, err, ErrNotFoundForTTLOp)
+}
@@ -32,3 +32,36 @@ func (imc *InMemoryCommandRepository) Load(src map[string]types.ColumnValueWithT
 
 	return nil
 }
+
+// Merge copies src into the store, replacing keys that already exist and
+// leaving every other key alone. Used to import keys moved in from another
+// shard.
+func (imc *InMemoryCommandRepository) Merge(src map[string]types.ColumnValueWithTTL) error {
+	imc.mu.Lock()
+	defer imc.mu.Unlock()
+
+	maps.Copy(imc.store, src)
+
+	return nil
+}
+
+// Entry returns the entry at key, expired or not, and whether there is one.
+// Used to record the writes to typed values in the change log.
+func (imc *InMemoryCommandRepository) Entry(key string) (entry types.ColumnValueWithTTL, ok bool) {
+	imc.mu.RLock()
+	defer imc.mu.RUnlock()
+
+	entry, ok = imc.store[key]
+	return entry, ok
+}
+
+// Put stores entry at key as it is, replacing whatever key holds. Used by
+// standby clusters to apply the writes to typed values recorded by the
+// primary.
+func (imc *InMemoryCommandRepository) Put(key string, entry types.ColumnValueWithTTL) {
+	imc.mu.Lock()
+	defer imc.mu.Unlock()
+
+	imc.store[key] = entry
+	imc.emit(EventSet, key)
+}
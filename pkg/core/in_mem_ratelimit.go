@@ -0,0 +1,119 @@
+package core
+
+import (
+	"context"
+	"fmt"
+	"math"
+	"time"
+
+	"github.com/mateenbagheri/memorabilia/pkg/types"
+)
+
+// Rate limiter algorithms, see RateLimitPolicy.
+const (
+	TokenBucket   = "token_bucket"
+	SlidingWindow = "sliding_window"
+)
+
+// RateLimitPolicy describes a rate limiter. A TokenBucket holds up to
+// Capacity tokens and gains RefillRate of them per second. A SlidingWindow
+// lets through up to Capacity within any Window, weighing the previous window
+// by how much of it the sliding window still covers.
+type RateLimitPolicy struct {
+	Algorithm  string
+	Capacity   int64
+	RefillRate float64
+	Window     time.Duration
+}
+
+// RateLimit consumes cost from the rate limiter at key, provided it has that
+// much left, creating it full if needed. The limiter's state expires once it
+// would be full again, so idle limiters take no room. A limiter last used
+// with another algorithm starts over.
+//
+// Returns:
+//   - allowed: Whether cost was consumed.
+//   - remaining: What is left to consume after this call.
+//   - retryAfter: When not allowed, how long until cost would be.
+//   - err: ErrWrongType if key holds something other than a rate limiter.
+func (imc *InMemoryCommandRepository) RateLimit(ctx context.Context, key string, policy RateLimitPolicy, cost int64) (allowed bool, remaining int64, retryAfter time.Duration, err error) {
+	imc.mu.Lock()
+	defer imc.mu.Unlock()
+
+	var state types.RateLimiter
+	fresh := true
+	if entry, ok := imc.store[key]; ok && (entry.Expiration.IsZero() || !imc.now().After(entry.Expiration)) {
+		if state, ok = entry.Column.(types.RateLimiter); !ok {
+			return false, 0, 0, ErrWrongType
+		}
+		fresh = state.Algorithm != policy.Algorithm
+	}
+
+	now := imc.now()
+	var expiration time.Time
+	switch policy.Algorithm {
+	case TokenBucket:
+		tokens := float64(policy.Capacity)
+		if !fresh {
+			elapsed := max(now.Sub(state.Updated), 0)
+			tokens = min(tokens, state.Tokens+elapsed.Seconds()*policy.RefillRate)
+		}
+		if tokens < float64(cost) {
+			return false, int64(tokens), seconds((float64(cost) - tokens) / policy.RefillRate), nil
+		}
+		tokens -= float64(cost)
+		state = types.RateLimiter{Algorithm: policy.Algorithm, Updated: now, Tokens: tokens}
+		expiration = now.Add(seconds((float64(policy.Capacity) - tokens) / policy.RefillRate))
+		remaining = int64(tokens)
+	case SlidingWindow:
+		window := policy.Window
+		start := now.Truncate(window)
+		var count, previous int64
+		switch {
+		case fresh || state.Updated.Before(start.Add(-window)):
+		case state.Updated.Before(start):
+			previous = state.Count
+		default:
+			count, previous = state.Count, state.Previous
+		}
+		elapsed := now.Sub(start)
+		covered := 1 - float64(elapsed)/float64(window)
+		left := float64(policy.Capacity) - float64(previous)*covered - float64(count)
+		if left < float64(cost) {
+			return false, max(int64(left), 0), slidingRetryAfter(policy, count, previous, cost, elapsed), nil
+		}
+		count += cost
+		state = types.RateLimiter{Algorithm: policy.Algorithm, Updated: start, Count: count, Previous: previous}
+		expiration = start.Add(2 * window)
+		remaining = int64(left) - cost
+	default:
+		return false, 0, 0, fmt.Errorf("unknown rate limiter algorithm %q", policy.Algorithm)
+	}
+	imc.store[key] = types.ColumnValueWithTTL{Column: state, Expiration: expiration}
+	imc.emit(EventSet, key)
+	return true, remaining, 0, nil
+}
+
+// slidingRetryAfter returns how long until a sliding window elapsed into its
+// current window, with count consumed in it and previous in the one before,
+// lets cost through.
+func slidingRetryAfter(policy RateLimitPolicy, count, previous, cost int64, elapsed time.Duration) time.Duration {
+	window := float64(policy.Window)
+	capacity := float64(policy.Capacity)
+	if count+cost <= policy.Capacity {
+		// Wait for the previous window to slide out far enough.
+		at := window * (1 - (capacity-float64(count+cost))/float64(previous))
+		return time.Duration(math.Ceil(at)) - elapsed
+	}
+	// Wait for the next window, where count becomes the previous one.
+	var at float64
+	if count > policy.Capacity-cost {
+		at = window * (1 - (capacity-float64(cost))/float64(count))
+	}
+	return policy.Window - elapsed + time.Duration(math.Ceil(at))
+}
+
+// seconds converts s seconds to a duration, rounded up.
+func seconds(s float64) time.Duration {
+	return time.Duration(math.Ceil(s * float64(time.Second)))
+}
@@ -0,0 +1,86 @@
+package core
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestInMemoryCommandRepository_RateLimit_TokenBucket(t *testing.T) {
+	ctx := context.Background()
+	t0 := time.Date(2001, 1, 1, 0, 0, 0, 0, time.UTC)
+	imc := NewInMemoryCommandRepository()
+	imc.SetClock(fixedClock(t0))
+	policy := RateLimitPolicy{Algorithm: TokenBucket, Capacity: 2, RefillRate: 1}
+
+	for _, want := range []int64{1, 0} {
+		allowed, remaining, _, err := imc.RateLimit(ctx, "api:alice", policy, 1)
+		require.NoError(t, err)
+		assert.True(t, allowed)
+		assert.Equal(t, want, remaining)
+	}
+	allowed, _, retryAfter, err := imc.RateLimit(ctx, "api:alice", policy, 1)
+	require.NoError(t, err)
+	assert.False(t, allowed)
+	assert.Equal(t, time.Second, retryAfter)
+
+	imc.SetClock(fixedClock(t0.Add(500 * time.Millisecond)))
+	_, _, retryAfter, err = imc.RateLimit(ctx, "api:alice", policy, 1)
+	require.NoError(t, err)
+	assert.Equal(t, 500*time.Millisecond, retryAfter)
+
+	// The state expires once the bucket would be full again.
+	imc.SetClock(fixedClock(t0.Add(time.Second)))
+	allowed, remaining, _, err := imc.RateLimit(ctx, "api:alice", policy, 1)
+	require.NoError(t, err)
+	assert.True(t, allowed)
+	assert.Zero(t, remaining)
+	ttl, err := imc.TTL(ctx, "api:alice")
+	require.NoError(t, err)
+	assert.Equal(t, 2*time.Second, ttl)
+
+	_, err = imc.Get(ctx, "api:alice")
+	assert.ErrorIs(t, err, ErrWrongType)
+	require.NoError(t, imc.Set(ctx, "str", "1", time.Time{}))
+	_, _, _, err = imc.RateLimit(ctx, "str", policy, 1)
+	assert.ErrorIs(t, err, ErrWrongType)
+}
+
+func TestInMemoryCommandRepository_RateLimit_SlidingWindow(t *testing.T) {
+	ctx := context.Background()
+	t0 := time.Date(2001, 1, 1, 0, 0, 0, 0, time.UTC)
+	imc := NewInMemoryCommandRepository()
+	imc.SetClock(fixedClock(t0))
+	policy := RateLimitPolicy{Algorithm: SlidingWindow, Capacity: 10, Window: time.Minute}
+
+	allowed, remaining, _, err := imc.RateLimit(ctx, "k", policy, 10)
+	require.NoError(t, err)
+	assert.True(t, allowed)
+	assert.Zero(t, remaining)
+
+	// In the next window, the previous one still counts for the part of it
+	// the sliding window covers: 9 of 10 after 6s.
+	allowed, _, retryAfter, err := imc.RateLimit(ctx, "k", policy, 1)
+	require.NoError(t, err)
+	assert.False(t, allowed)
+	assert.Equal(t, 66*time.Second, retryAfter)
+	imc.SetClock(fixedClock(t0.Add(90 * time.Second)))
+	allowed, remaining, _, err = imc.RateLimit(ctx, "k", policy, 5)
+	require.NoError(t, err)
+	assert.True(t, allowed)
+	assert.Zero(t, remaining)
+	allowed, _, retryAfter, err = imc.RateLimit(ctx, "k", policy, 1)
+	require.NoError(t, err)
+	assert.False(t, allowed)
+	assert.Equal(t, 6*time.Second, retryAfter) // 4 of the previous window and 5 of this one
+
+	// Two windows later, nothing counts any more.
+	imc.SetClock(fixedClock(t0.Add(3 * time.Minute)))
+	allowed, remaining, _, err = imc.RateLimit(ctx, "k", policy, 1)
+	require.NoError(t, err)
+	assert.True(t, allowed)
+	assert.Equal(t, int64(9), remaining)
+}
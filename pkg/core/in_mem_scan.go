@@ -0,0 +1,60 @@
+package core
+
+import (
+	"context"
+	"sort"
+
+	"github.com/mateenbagheri/memorabilia/pkg/utils/glob"
+)
+
+// DefaultScanCount is the page size used by Scan when count is not positive.
+const DefaultScanCount = 100
+
+// Scan iterates over live keys in lexical order, one page at a time.
+//
+// Parameters:
+//   - ctx: Context for request-scoped values, cancellation, and deadlines.
+//   - pattern: A Redis-style glob (see glob.Match). Empty means every key.
+//   - cursor: The nextCursor returned by the previous call, or "" to start.
+//   - count: Maximum number of keys to return. Defaults to DefaultScanCount.
+//
+// Returns:
+//   - keys: Up to count matching keys that sort after cursor.
+//   - nextCursor: Pass this to the next call; "" when the scan is complete.
+//   - err: An error if any issue occurs (always nil in current implementation).
+//
+// The cursor is simply the last key of the page, so a scan that races with
+// writes never returns a key twice, but may miss keys inserted behind it.
+func (imc *InMemoryCommandRepository) Scan(
+	ctx context.Context,
+	pattern, cursor string,
+	count int64,
+) (keys []string, nextCursor string, err error) {
+	if count <= 0 {
+		count = DefaultScanCount
+	}
+
+	imc.mu.RLock()
+	candidates := make([]string, 0, len(imc.store))
+	now := imc.now()
+	for key, val := range imc.store {
+		if key <= cursor {
+			continue
+		}
+		if !val.Expiration.IsZero() && now.After(val.Expiration) {
+			continue
+		}
+		if pattern != "" && !glob.Match(pattern, key) {
+			continue
+		}
+		candidates = append(candidates, key)
+	}
+	imc.mu.RUnlock()
+
+	sort.Strings(candidates)
+	if int64(len(candidates)) > count {
+		keys = candidates[:count]
+		return keys, keys[len(keys)-1], nil
+	}
+	return candidates, "", nil
+}
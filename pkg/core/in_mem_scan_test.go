@@ -0,0 +1,49 @@
+package core
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/mateenbagheri/memorabilia/pkg/types"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestInMemoryCommandRepository_Scan(t *testing.T) {
+	ctx := context.Background()
+	imc := NewInMemoryCommandRepositoryWithInitialStore(
+		map[string]types.ColumnValueWithTTL{
+			"user:1":    {Column: types.String{Val: "a"}},
+			"user:2":    {Column: types.String{Val: "b"}},
+			"user:3":    {Column: types.String{Val: "c"}},
+			"session:1": {Column: types.String{Val: "d"}},
+			"user:old":  {Column: types.String{Val: "e"}, Expiration: time.Now().Add(-time.Minute)},
+		},
+	)
+
+	t.Run("All keys in lexical order", func(t *testing.T) {
+		keys, next, err := imc.Scan(ctx, "", "", 0)
+		require.NoError(t, err)
+		assert.Equal(t, []string{"session:1", "user:1", "user:2", "user:3"}, keys)
+		assert.Equal(t, "", next, "a complete scan should not return a cursor")
+	})
+
+	t.Run("Pattern filters keys", func(t *testing.T) {
+		keys, _, err := imc.Scan(ctx, "user:*", "", 0)
+		require.NoError(t, err)
+		assert.Equal(t, []string{"user:1", "user:2", "user:3"}, keys)
+	})
+
+	t.Run("Pages follow the cursor", func(t *testing.T) {
+		keys, next, err := imc.Scan(ctx, "user:*", "", 2)
+		require.NoError(t, err)
+		assert.Equal(t, []string{"user:1", "user:2"}, keys)
+		assert.Equal(t, "user:2", next)
+
+		keys, next, err = imc.Scan(ctx, "user:*", next, 2)
+		require.NoError(t, err)
+		assert.Equal(t, []string{"user:3"}, keys)
+		assert.Equal(t, "", next)
+	})
+}
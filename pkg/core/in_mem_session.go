@@ -0,0 +1,151 @@
+package core
+
+import (
+	"context"
+	"errors"
+	"maps"
+	"time"
+
+	"github.com/mateenbagheri/memorabilia/pkg/types"
+)
+
+// ErrSessionNotFound is returned by operations naming a session that doesn't
+// exist or has ended. A session that ended can't be kept alive or created
+// again: its ephemeral keys are on their way out.
+var ErrSessionNotFound = errors.New("session not found or ended")
+
+// CreateSession starts the session id, which ends ttl from now unless it is
+// kept alive. Creating a session that exists keeps it alive with the new
+// ttl instead, so that retrying a lost response is safe.
+//
+// Returns:
+//   - deadline: When the session ends unless it is kept alive.
+//   - err: ErrSessionNotFound if the session id has ended.
+func (imc *InMemoryCommandRepository) CreateSession(ctx context.Context, id string, ttl time.Duration) (deadline time.Time, err error) {
+	imc.mu.Lock()
+	defer imc.mu.Unlock()
+
+	if session, ok := imc.sessions[id]; ok && !imc.sessionLiveLocked(session) {
+		return time.Time{}, ErrSessionNotFound
+	}
+	deadline = imc.now().Add(ttl)
+	imc.sessions[id] = types.Session{TTL: ttl, Deadline: deadline}
+	return deadline, nil
+}
+
+// KeepAlive moves the deadline of the session id to its TTL from now, and
+// returns it. It returns ErrSessionNotFound if the session doesn't exist or
+// has ended.
+func (imc *InMemoryCommandRepository) KeepAlive(ctx context.Context, id string) (deadline time.Time, err error) {
+	imc.mu.Lock()
+	defer imc.mu.Unlock()
+
+	session, ok := imc.sessions[id]
+	if !ok || !imc.sessionLiveLocked(session) {
+		return time.Time{}, ErrSessionNotFound
+	}
+	session.Deadline = imc.now().Add(session.TTL)
+	imc.sessions[id] = session
+	return session.Deadline, nil
+}
+
+// CloseSession ends the session id and deletes keys, its ephemeral keys as
+// found by SessionKeys, under a single lock. The keys were collected earlier,
+// so any that was set again in the meantime without the session, or with
+// another one, is kept. Their removal is reported as EventExpired when
+// expired is set, for a session that ended by itself, and as EventDel
+// otherwise.
+//
+// Returns:
+//   - closed: Whether the session existed.
+//   - deleted: The keys deleted.
+func (imc *InMemoryCommandRepository) CloseSession(ctx context.Context, id string, keys []string, expired bool) (closed bool, deleted []string) {
+	imc.mu.Lock()
+	defer imc.mu.Unlock()
+
+	_, closed = imc.sessions[id]
+	delete(imc.sessions, id)
+	eventType := EventDel
+	if expired {
+		eventType = EventExpired
+	}
+	for _, key := range keys {
+		if entry, ok := imc.store[key]; !ok || entry.Session != id {
+			continue
+		}
+		imc.deleteLocked(key, eventType)
+		deleted = append(deleted, key)
+	}
+	return closed, deleted
+}
+
+// SessionKeys returns the ephemeral keys of the session id.
+func (imc *InMemoryCommandRepository) SessionKeys(ctx context.Context, id string) (keys []string) {
+	imc.mu.RLock()
+	defer imc.mu.RUnlock()
+
+	for key, val := range imc.store {
+		if val.Session == id {
+			keys = append(keys, key)
+		}
+	}
+	return keys
+}
+
+// ExpiredSessions returns the sessions whose deadline has passed, for the
+// cleanup to close.
+func (imc *InMemoryCommandRepository) ExpiredSessions(ctx context.Context) (ids []string) {
+	imc.mu.RLock()
+	defer imc.mu.RUnlock()
+
+	for id, session := range imc.sessions {
+		if !imc.sessionLiveLocked(session) {
+			ids = append(ids, id)
+		}
+	}
+	return ids
+}
+
+// GetSetEphemeral is GetSet for a key belonging to the session session: the
+// key is deleted when the session ends, unless it is set again without one
+// first. It returns ErrSessionNotFound if the session doesn't exist or has
+// ended.
+func (imc *InMemoryCommandRepository) GetSetEphemeral(
+	ctx context.Context,
+	key, value string,
+	expiration time.Time,
+	session string,
+) (previous string, existed bool, err error) {
+	imc.mu.Lock()
+	defer imc.mu.Unlock()
+
+	if s, ok := imc.sessions[session]; !ok || !imc.sessionLiveLocked(s) {
+		return "", false, ErrSessionNotFound
+	}
+	previous, existed = imc.getSetLocked(key, value, expiration, session)
+	return previous, existed, nil
+}
+
+// DumpSessions returns a copy of the sessions for Raft snapshotting.
+func (imc *InMemoryCommandRepository) DumpSessions() map[string]types.Session {
+	imc.mu.RLock()
+	defer imc.mu.RUnlock()
+	return maps.Clone(imc.sessions)
+}
+
+// LoadSessions replaces the sessions with src. Used by FSM.Restore() next to
+// Load.
+func (imc *InMemoryCommandRepository) LoadSessions(src map[string]types.Session) {
+	imc.mu.Lock()
+	defer imc.mu.Unlock()
+
+	dst := make(map[string]types.Session, len(src))
+	maps.Copy(dst, src)
+	imc.sessions = dst
+}
+
+// sessionLiveLocked reports whether session hasn't ended. The caller must
+// hold imc.mu.
+func (imc *InMemoryCommandRepository) sessionLiveLocked(session types.Session) bool {
+	return !imc.now().After(session.Deadline)
+}
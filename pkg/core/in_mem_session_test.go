@@ -0,0 +1,104 @@
+package core
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestInMemoryCommandRepository_Session(t *testing.T) {
+	ctx := context.Background()
+	t0 := time.Date(2001, 1, 1, 0, 0, 0, 0, time.UTC)
+	imc := NewInMemoryCommandRepository()
+	imc.SetClock(fixedClock(t0))
+
+	_, _, err := imc.GetSetEphemeral(ctx, "svc/a", "1", time.Time{}, "s")
+	assert.ErrorIs(t, err, ErrSessionNotFound)
+
+	deadline, err := imc.CreateSession(ctx, "s", 10*time.Second)
+	require.NoError(t, err)
+	assert.Equal(t, t0.Add(10*time.Second), deadline)
+	for _, key := range []string{"svc/a", "svc/b"} {
+		_, _, err = imc.GetSetEphemeral(ctx, key, "1", time.Time{}, "s")
+		require.NoError(t, err)
+	}
+	require.NoError(t, imc.Set(ctx, "other", "1", time.Time{}))
+	assert.ElementsMatch(t, []string{"svc/a", "svc/b"}, imc.SessionKeys(ctx, "s"))
+
+	// Setting a key without the session makes it a plain key again.
+	_, _, err = imc.GetSet(ctx, "svc/b", "2", time.Time{})
+	require.NoError(t, err)
+	assert.Equal(t, []string{"svc/a"}, imc.SessionKeys(ctx, "s"))
+
+	imc.SetClock(fixedClock(t0.Add(8 * time.Second)))
+	deadline, err = imc.KeepAlive(ctx, "s")
+	require.NoError(t, err)
+	assert.Equal(t, t0.Add(18*time.Second), deadline)
+	assert.Empty(t, imc.ExpiredSessions(ctx))
+
+	// An ended session can't be kept alive or created again; the cleanup
+	// closes it.
+	imc.SetClock(fixedClock(t0.Add(20 * time.Second)))
+	_, err = imc.KeepAlive(ctx, "s")
+	assert.ErrorIs(t, err, ErrSessionNotFound)
+	_, err = imc.CreateSession(ctx, "s", time.Minute)
+	assert.ErrorIs(t, err, ErrSessionNotFound)
+	assert.Equal(t, []string{"s"}, imc.ExpiredSessions(ctx))
+
+	closed, deleted := imc.CloseSession(ctx, "s", imc.SessionKeys(ctx, "s"), true)
+	assert.True(t, closed)
+	assert.Equal(t, []string{"svc/a"}, deleted)
+	_, err = imc.Get(ctx, "svc/a")
+	assert.ErrorIs(t, err, ErrNotFoundForGetOp)
+	assert.Empty(t, imc.ExpiredSessions(ctx))
+	_, err = imc.CreateSession(ctx, "s", time.Minute)
+	assert.NoError(t, err)
+}
+
+func TestInMemoryCommandRepository_CloseSession_KeepsKeysSetAgain(t *testing.T) {
+	ctx := context.Background()
+	imc := NewInMemoryCommandRepository()
+	_, err := imc.CreateSession(ctx, "s", time.Minute)
+	require.NoError(t, err)
+	for _, key := range []string{"svc/a", "svc/b"} {
+		_, _, err = imc.GetSetEphemeral(ctx, key, "1", time.Time{}, "s")
+		require.NoError(t, err)
+	}
+	keys := imc.SessionKeys(ctx, "s")
+
+	// svc/b is set again without the session after its keys were collected.
+	_, _, err = imc.GetSet(ctx, "svc/b", "2", time.Time{})
+	require.NoError(t, err)
+
+	closed, deleted := imc.CloseSession(ctx, "s", keys, false)
+	assert.True(t, closed)
+	assert.Equal(t, []string{"svc/a"}, deleted)
+	_, err = imc.Get(ctx, "svc/a")
+	assert.ErrorIs(t, err, ErrNotFoundForGetOp)
+	val, err := imc.Get(ctx, "svc/b")
+	require.NoError(t, err)
+	assert.Equal(t, "2", val)
+}
+
+func TestInMemoryCommandRepository_GetExpiredKeys_OrphanedEphemeralKeys(t *testing.T) {
+	ctx := context.Background()
+	imc := NewInMemoryCommandRepository()
+	_, err := imc.CreateSession(ctx, "s", time.Minute)
+	require.NoError(t, err)
+	_, _, err = imc.GetSetEphemeral(ctx, "k", "v", time.Time{}, "s")
+	require.NoError(t, err)
+
+	sessions := imc.DumpSessions()
+	imc.LoadSessions(nil)
+	keys, err := imc.GetExpiredKeys(ctx)
+	require.NoError(t, err)
+	assert.Equal(t, []string{"k"}, keys)
+
+	imc.LoadSessions(sessions)
+	keys, err = imc.GetExpiredKeys(ctx)
+	require.NoError(t, err)
+	assert.Empty(t, keys)
+}
@@ -31,5 +31,6 @@ func (imc *InMemoryCommandRepository) Set(
 		Column:     columnValue,
 		Expiration: expiration,
 	}
+	imc.emit(EventSet, key)
 	return nil
 }
@@ -0,0 +1,434 @@
+package core
+
+import (
+	"context"
+	"errors"
+	"maps"
+	"slices"
+	"strings"
+	"time"
+
+	"github.com/mateenbagheri/memorabilia/pkg/types"
+)
+
+// ErrInvalidStreamID is returned for stream IDs that can't be parsed, and for
+// entry IDs no greater than the stream's last one.
+var ErrInvalidStreamID = types.ErrInvalidStreamID
+
+// ErrNoSuchGroup is returned by consumer group operations against a group, or
+// a stream, that doesn't exist.
+var ErrNoSuchGroup = errors.New("no such stream or consumer group")
+
+// StreamTrim limits the length of a stream: to its MaxLen newest entries when
+// MaxLen is positive, and to the entries from MinID on when it is set.
+type StreamTrim struct {
+	MaxLen int64
+	MinID  string
+}
+
+// XAdd appends an entry with fields to the stream at key, creating it if
+// needed, then trims the stream. id is the entry's ID; when empty or "*" the
+// entry gets the next one: the cluster clock's time in milliseconds, or the
+// last ID's with its sequence number bumped when the clock hasn't passed it.
+//
+// Returns:
+//   - added: The ID of the entry.
+//   - err: ErrInvalidStreamID if id isn't greater than the last ID, and
+//     ErrWrongType if key holds something other than a stream.
+func (imc *InMemoryCommandRepository) XAdd(ctx context.Context, key, id string, fields map[string]string, trim StreamTrim) (added string, err error) {
+	imc.mu.Lock()
+	defer imc.mu.Unlock()
+
+	stream, expiration, err := imc.streamLocked(key)
+	if err != nil {
+		return "", err
+	}
+	var entryID types.StreamID
+	if id == "" || id == "*" {
+		entryID = types.StreamID{Ms: uint64(imc.now().UnixMilli())}
+		if entryID.Compare(stream.LastID) <= 0 {
+			entryID = stream.LastID.Next()
+		}
+	} else if entryID, err = types.ParseStreamID(id); err != nil {
+		return "", err
+	}
+	if entryID.Compare(stream.LastID) <= 0 {
+		return "", ErrInvalidStreamID
+	}
+	minID, err := parseMinID(trim.MinID)
+	if err != nil {
+		return "", err
+	}
+	stream.Entries = append(stream.Entries, types.StreamEntry{ID: entryID, Fields: maps.Clone(fields)})
+	stream.LastID = entryID
+	stream, _ = trimStream(stream, trim.MaxLen, minID)
+	imc.store[key] = types.ColumnValueWithTTL{Column: stream, Expiration: expiration}
+	imc.emit(EventSet, key)
+	return entryID.String(), nil
+}
+
+// XTrim trims the stream at key, and returns how many entries it removed.
+func (imc *InMemoryCommandRepository) XTrim(ctx context.Context, key string, trim StreamTrim) (trimmed int64, err error) {
+	imc.mu.Lock()
+	defer imc.mu.Unlock()
+
+	stream, expiration, err := imc.streamLocked(key)
+	if err != nil {
+		return 0, err
+	}
+	minID, err := parseMinID(trim.MinID)
+	if err != nil {
+		return 0, err
+	}
+	stream, trimmed = trimStream(stream, trim.MaxLen, minID)
+	if trimmed > 0 {
+		imc.store[key] = types.ColumnValueWithTTL{Column: stream, Expiration: expiration}
+		imc.emit(EventSet, key)
+	}
+	return trimmed, nil
+}
+
+// XRange returns the entries of the stream at key with IDs from start to
+// end, at most count of them when count is positive: the first ones, or the
+// last ones in descending order when reverse is set. start and end are IDs,
+// where "-" and "+" stand for the lowest and highest, and a "(" prefix makes
+// the bound exclusive. An ID without a sequence number as end stands for the
+// last ID of its millisecond.
+func (imc *InMemoryCommandRepository) XRange(ctx context.Context, key, start, end string, count int64, reverse bool) (entries []types.StreamEntry, err error) {
+	low, err := parseRangeBound(start, false)
+	if err != nil {
+		return nil, err
+	}
+	high, err := parseRangeBound(end, true)
+	if err != nil {
+		return nil, err
+	}
+
+	imc.mu.RLock()
+	defer imc.mu.RUnlock()
+
+	stream, _, err := imc.streamLocked(key)
+	if err != nil || low.Compare(high) > 0 {
+		return nil, err
+	}
+	from, to := entriesBetween(stream.Entries, low, high)
+	entries = slices.Clone(stream.Entries[from:to])
+	if reverse {
+		slices.Reverse(entries)
+	}
+	if count > 0 && int64(len(entries)) > count {
+		entries = entries[:count]
+	}
+	return entries, nil
+}
+
+// XLen returns the number of entries in the stream at key, 0 when it is
+// missing.
+func (imc *InMemoryCommandRepository) XLen(ctx context.Context, key string) (length int64, err error) {
+	imc.mu.RLock()
+	defer imc.mu.RUnlock()
+
+	stream, _, err := imc.streamLocked(key)
+	return int64(len(stream.Entries)), err
+}
+
+// XGroupCreate creates the consumer group group of the stream at key,
+// creating an empty stream if needed. The group is delivered the entries
+// after start, an ID; "$" stands for the stream's last ID, so that the group
+// only sees entries added from now on.
+//
+// Returns:
+//   - created: false when the group already exists; it is left as it is.
+//   - err: ErrInvalidStreamID if start can't be parsed, and ErrWrongType if
+//     key holds something other than a stream.
+func (imc *InMemoryCommandRepository) XGroupCreate(ctx context.Context, key, group, start string) (created bool, err error) {
+	imc.mu.Lock()
+	defer imc.mu.Unlock()
+
+	stream, expiration, err := imc.streamLocked(key)
+	if err != nil {
+		return false, err
+	}
+	if _, ok := stream.Groups[group]; ok {
+		return false, nil
+	}
+	lastDelivered := stream.LastID
+	if start != "$" {
+		if lastDelivered, err = types.ParseStreamID(start); err != nil {
+			return false, err
+		}
+	}
+	stream.Groups = maps.Clone(stream.Groups)
+	if stream.Groups == nil {
+		stream.Groups = make(map[string]types.ConsumerGroup)
+	}
+	stream.Groups[group] = types.ConsumerGroup{LastDelivered: lastDelivered}
+	imc.store[key] = types.ColumnValueWithTTL{Column: stream, Expiration: expiration}
+	imc.emit(EventSet, key)
+	return true, nil
+}
+
+// XReadGroup reads entries of the stream at key as consumer of group, at
+// most count of them when count is positive. With after ">" it delivers
+// entries no consumer of the group was delivered yet, adding them to the
+// group's pending entries; with an ID it returns the entries after it still
+// pending for consumer, delivering nothing new. Pending entries whose stream
+// entry was trimmed are left out.
+//
+// Returns:
+//   - entries: The entries read.
+//   - err: ErrNoSuchGroup if the group doesn't exist.
+func (imc *InMemoryCommandRepository) XReadGroup(ctx context.Context, key, group, consumer, after string, count int64) (entries []types.StreamEntry, err error) {
+	imc.mu.Lock()
+	defer imc.mu.Unlock()
+
+	stream, expiration, g, err := imc.streamGroupLocked(key, group)
+	if err != nil {
+		return nil, err
+	}
+	if after != ">" {
+		from, err := types.ParseStreamID(after)
+		if err != nil {
+			return nil, err
+		}
+		for _, p := range g.Pending {
+			if count > 0 && int64(len(entries)) == count {
+				break
+			}
+			if p.Consumer != consumer || p.ID.Compare(from) <= 0 {
+				continue
+			}
+			if entry, ok := findEntry(stream.Entries, p.ID); ok {
+				entries = append(entries, entry)
+			}
+		}
+		return entries, nil
+	}
+
+	_, from := entriesBetween(stream.Entries, types.StreamID{}, g.LastDelivered)
+	entries = stream.Entries[from:]
+	if count > 0 && int64(len(entries)) > count {
+		entries = entries[:count]
+	}
+	if len(entries) == 0 {
+		return nil, nil
+	}
+	now := imc.now()
+	pending := slices.Clip(g.Pending)
+	for _, entry := range entries {
+		pending = append(pending, types.PendingEntry{ID: entry.ID, Consumer: consumer, Delivered: now, Deliveries: 1})
+	}
+	g.Pending = pending
+	g.LastDelivered = entries[len(entries)-1].ID
+	imc.storeGroupLocked(key, stream, expiration, group, g)
+	return slices.Clone(entries), nil
+}
+
+// XAck acknowledges the pending entries ids of group, removing them from its
+// pending entries, and returns how many were pending.
+func (imc *InMemoryCommandRepository) XAck(ctx context.Context, key, group string, ids []string) (acked int64, err error) {
+	acks := make(map[types.StreamID]bool, len(ids))
+	for _, id := range ids {
+		parsed, err := types.ParseStreamID(id)
+		if err != nil {
+			return 0, err
+		}
+		acks[parsed] = true
+	}
+
+	imc.mu.Lock()
+	defer imc.mu.Unlock()
+
+	stream, expiration, g, err := imc.streamGroupLocked(key, group)
+	if err != nil {
+		return 0, err
+	}
+	pending := make([]types.PendingEntry, 0, len(g.Pending))
+	for _, p := range g.Pending {
+		if acks[p.ID] {
+			acked++
+			continue
+		}
+		pending = append(pending, p)
+	}
+	if acked > 0 {
+		g.Pending = pending
+		imc.storeGroupLocked(key, stream, expiration, group, g)
+	}
+	return acked, nil
+}
+
+// XClaim hands pending entries of group idle for at least minIdle over to
+// consumer, counting a new delivery of each, and returns them. It claims the
+// pending entries ids, or when ids is empty the oldest idle ones, at most
+// count of them when count is positive. Pending entries whose stream entry
+// was trimmed can't be processed any more: they are dropped instead.
+func (imc *InMemoryCommandRepository) XClaim(ctx context.Context, key, group, consumer string, minIdle time.Duration, ids []string, count int64) (entries []types.StreamEntry, err error) {
+	var claims map[types.StreamID]bool
+	if len(ids) > 0 {
+		claims = make(map[types.StreamID]bool, len(ids))
+		for _, id := range ids {
+			parsed, err := types.ParseStreamID(id)
+			if err != nil {
+				return nil, err
+			}
+			claims[parsed] = true
+		}
+	}
+
+	imc.mu.Lock()
+	defer imc.mu.Unlock()
+
+	stream, expiration, g, err := imc.streamGroupLocked(key, group)
+	if err != nil {
+		return nil, err
+	}
+	now := imc.now()
+	changed := false
+	pending := make([]types.PendingEntry, 0, len(g.Pending))
+	for _, p := range g.Pending {
+		full := count > 0 && int64(len(entries)) == count
+		if full || (claims != nil && !claims[p.ID]) || now.Sub(p.Delivered) < minIdle {
+			pending = append(pending, p)
+			continue
+		}
+		changed = true
+		entry, ok := findEntry(stream.Entries, p.ID)
+		if !ok {
+			continue
+		}
+		p.Consumer, p.Delivered, p.Deliveries = consumer, now, p.Deliveries+1
+		pending = append(pending, p)
+		entries = append(entries, entry)
+	}
+	if changed {
+		g.Pending = pending
+		imc.storeGroupLocked(key, stream, expiration, group, g)
+	}
+	return entries, nil
+}
+
+// XPending returns the pending entries of group, in ascending ID order.
+func (imc *InMemoryCommandRepository) XPending(ctx context.Context, key, group string) (pending []types.PendingEntry, err error) {
+	imc.mu.RLock()
+	defer imc.mu.RUnlock()
+
+	_, _, g, err := imc.streamGroupLocked(key, group)
+	if err != nil {
+		return nil, err
+	}
+	return slices.Clone(g.Pending), nil
+}
+
+// streamLocked returns the stream at key and its expiration: an empty stream
+// when key is missing or has expired. The caller must hold imc.mu.
+func (imc *InMemoryCommandRepository) streamLocked(key string) (stream types.Stream, expiration time.Time, err error) {
+	entry, ok := imc.store[key]
+	if !ok || (!entry.Expiration.IsZero() && imc.now().After(entry.Expiration)) {
+		return types.Stream{}, time.Time{}, nil
+	}
+	stream, ok = entry.Column.(types.Stream)
+	if !ok {
+		return types.Stream{}, time.Time{}, ErrWrongType
+	}
+	return stream, entry.Expiration, nil
+}
+
+// streamGroupLocked returns the stream at key, its expiration and its
+// consumer group group, or ErrNoSuchGroup. The caller must hold imc.mu.
+func (imc *InMemoryCommandRepository) streamGroupLocked(key, group string) (stream types.Stream, expiration time.Time, g types.ConsumerGroup, err error) {
+	stream, expiration, err = imc.streamLocked(key)
+	if err != nil {
+		return types.Stream{}, time.Time{}, types.ConsumerGroup{}, err
+	}
+	g, ok := stream.Groups[group]
+	if !ok {
+		return types.Stream{}, time.Time{}, types.ConsumerGroup{}, ErrNoSuchGroup
+	}
+	return stream, expiration, g, nil
+}
+
+// storeGroupLocked stores stream at key with its consumer group group
+// replaced by g. The caller must hold imc.mu.
+func (imc *InMemoryCommandRepository) storeGroupLocked(key string, stream types.Stream, expiration time.Time, group string, g types.ConsumerGroup) {
+	stream.Groups = maps.Clone(stream.Groups)
+	stream.Groups[group] = g
+	imc.store[key] = types.ColumnValueWithTTL{Column: stream, Expiration: expiration}
+	imc.emit(EventSet, key)
+}
+
+// trimStream removes the entries of stream beyond its maxLen newest ones,
+// when maxLen is positive, and those before minID. It returns the trimmed
+// stream, and how many entries it removed.
+func trimStream(stream types.Stream, maxLen int64, minID types.StreamID) (types.Stream, int64) {
+	drop, _ := entriesBetween(stream.Entries, minID, types.MaxStreamID)
+	if maxLen > 0 && int64(len(stream.Entries)-drop) > maxLen {
+		drop = len(stream.Entries) - int(maxLen)
+	}
+	stream.Entries = stream.Entries[drop:]
+	return stream, int64(drop)
+}
+
+// entriesBetween returns the bounds of the entries with IDs from low to high
+// in entries, sorted by ID.
+func entriesBetween(entries []types.StreamEntry, low, high types.StreamID) (from, to int) {
+	from, _ = slices.BinarySearchFunc(entries, low, func(e types.StreamEntry, id types.StreamID) int {
+		return e.ID.Compare(id)
+	})
+	to, found := slices.BinarySearchFunc(entries, high, func(e types.StreamEntry, id types.StreamID) int {
+		return e.ID.Compare(id)
+	})
+	if found {
+		to++
+	}
+	return from, to
+}
+
+// findEntry returns the entry with ID id in entries, sorted by ID.
+func findEntry(entries []types.StreamEntry, id types.StreamID) (types.StreamEntry, bool) {
+	from, to := entriesBetween(entries, id, id)
+	if from == to {
+		return types.StreamEntry{}, false
+	}
+	return entries[from], true
+}
+
+// parseMinID parses the MinID of a StreamTrim, where empty stands for none.
+func parseMinID(s string) (types.StreamID, error) {
+	if s == "" {
+		return types.StreamID{}, nil
+	}
+	return types.ParseStreamID(s)
+}
+
+// parseRangeBound parses a bound of XRange: the start of the range, or its
+// end when end is set.
+func parseRangeBound(s string, end bool) (types.StreamID, error) {
+	switch s {
+	case "-":
+		return types.StreamID{}, nil
+	case "+":
+		return types.MaxStreamID, nil
+	}
+	text, exclusive := strings.CutPrefix(s, "(")
+	id, err := types.ParseStreamID(text)
+	if err != nil {
+		return types.StreamID{}, err
+	}
+	if end && !strings.Contains(text, "-") {
+		id.Seq = types.MaxStreamID.Seq
+	}
+	switch {
+	case exclusive && end:
+		if id == (types.StreamID{}) {
+			return types.StreamID{}, ErrInvalidStreamID
+		}
+		return id.Prev(), nil
+	case exclusive:
+		if id == types.MaxStreamID {
+			return types.StreamID{}, ErrInvalidStreamID
+		}
+		return id.Next(), nil
+	}
+	return id, nil
+}
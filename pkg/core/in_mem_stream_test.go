@@ -0,0 +1,139 @@
+package core
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/mateenbagheri/memorabilia/pkg/types"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func streamIDs(entries []types.StreamEntry) []string {
+	ids := make([]string, len(entries))
+	for i, entry := range entries {
+		ids[i] = entry.ID.String()
+	}
+	return ids
+}
+
+func TestInMemoryCommandRepository_XAdd_XRange(t *testing.T) {
+	ctx := context.Background()
+	t0 := time.UnixMilli(1000)
+	imc := NewInMemoryCommandRepository()
+	imc.SetClock(fixedClock(t0))
+
+	// IDs within the same millisecond get increasing sequence numbers.
+	for _, want := range []string{"1000-0", "1000-1", "1000-2"} {
+		id, err := imc.XAdd(ctx, "s", "*", map[string]string{"n": want}, StreamTrim{})
+		require.NoError(t, err)
+		assert.Equal(t, want, id)
+	}
+	_, err := imc.XAdd(ctx, "s", "1000-2", map[string]string{"n": "x"}, StreamTrim{})
+	assert.ErrorIs(t, err, ErrInvalidStreamID)
+	id, err := imc.XAdd(ctx, "s", "2000-5", map[string]string{"n": "x"}, StreamTrim{})
+	require.NoError(t, err)
+	assert.Equal(t, "2000-5", id)
+	// The clock is behind the last ID, which new IDs follow.
+	id, err = imc.XAdd(ctx, "s", "", map[string]string{"n": "y"}, StreamTrim{})
+	require.NoError(t, err)
+	assert.Equal(t, "2000-6", id)
+
+	entries, err := imc.XRange(ctx, "s", "-", "+", 0, false)
+	require.NoError(t, err)
+	assert.Equal(t, []string{"1000-0", "1000-1", "1000-2", "2000-5", "2000-6"}, streamIDs(entries))
+	assert.Equal(t, map[string]string{"n": "1000-1"}, entries[1].Fields)
+	entries, err = imc.XRange(ctx, "s", "(1000-1", "1000", 0, false)
+	require.NoError(t, err)
+	assert.Equal(t, []string{"1000-2"}, streamIDs(entries))
+	entries, err = imc.XRange(ctx, "s", "-", "+", 2, true)
+	require.NoError(t, err)
+	assert.Equal(t, []string{"2000-6", "2000-5"}, streamIDs(entries))
+	_, err = imc.XRange(ctx, "s", "x", "+", 0, false)
+	assert.ErrorIs(t, err, ErrInvalidStreamID)
+
+	// Trimming keeps the newest entries, or those from an ID on; the last ID
+	// stays.
+	trimmed, err := imc.XTrim(ctx, "s", StreamTrim{MinID: "1000-1"})
+	require.NoError(t, err)
+	assert.Equal(t, int64(1), trimmed)
+	id, err = imc.XAdd(ctx, "s", "*", map[string]string{"n": "z"}, StreamTrim{MaxLen: 2})
+	require.NoError(t, err)
+	assert.Equal(t, "2000-7", id)
+	length, err := imc.XLen(ctx, "s")
+	require.NoError(t, err)
+	assert.Equal(t, int64(2), length)
+
+	_, err = imc.Get(ctx, "s")
+	assert.ErrorIs(t, err, ErrWrongType)
+	require.NoError(t, imc.Set(ctx, "str", "1", time.Time{}))
+	_, err = imc.XAdd(ctx, "str", "*", map[string]string{"n": "1"}, StreamTrim{})
+	assert.ErrorIs(t, err, ErrWrongType)
+}
+
+func TestInMemoryCommandRepository_ConsumerGroups(t *testing.T) {
+	ctx := context.Background()
+	t0 := time.UnixMilli(1000)
+	imc := NewInMemoryCommandRepository()
+	imc.SetClock(fixedClock(t0))
+
+	_, err := imc.XReadGroup(ctx, "s", "g", "a", ">", 0)
+	assert.ErrorIs(t, err, ErrNoSuchGroup)
+	for i := 0; i < 3; i++ {
+		_, err = imc.XAdd(ctx, "s", "*", map[string]string{"n": "1"}, StreamTrim{})
+		require.NoError(t, err)
+	}
+	created, err := imc.XGroupCreate(ctx, "s", "g", "0")
+	require.NoError(t, err)
+	assert.True(t, created)
+	created, err = imc.XGroupCreate(ctx, "s", "g", "$")
+	require.NoError(t, err)
+	assert.False(t, created)
+
+	// Each entry goes to one consumer, and stays pending until acknowledged.
+	entries, err := imc.XReadGroup(ctx, "s", "g", "a", ">", 2)
+	require.NoError(t, err)
+	assert.Equal(t, []string{"1000-0", "1000-1"}, streamIDs(entries))
+	entries, err = imc.XReadGroup(ctx, "s", "g", "b", ">", 0)
+	require.NoError(t, err)
+	assert.Equal(t, []string{"1000-2"}, streamIDs(entries))
+	entries, err = imc.XReadGroup(ctx, "s", "g", "b", ">", 0)
+	require.NoError(t, err)
+	assert.Empty(t, entries)
+	entries, err = imc.XReadGroup(ctx, "s", "g", "a", "0", 0)
+	require.NoError(t, err)
+	assert.Equal(t, []string{"1000-0", "1000-1"}, streamIDs(entries))
+
+	acked, err := imc.XAck(ctx, "s", "g", []string{"1000-0", "1000-9"})
+	require.NoError(t, err)
+	assert.Equal(t, int64(1), acked)
+	pending, err := imc.XPending(ctx, "s", "g")
+	require.NoError(t, err)
+	require.Len(t, pending, 2)
+	assert.Equal(t, types.PendingEntry{ID: types.StreamID{Ms: 1000, Seq: 1}, Consumer: "a", Delivered: t0, Deliveries: 1}, pending[0])
+
+	// Entries idle long enough can be claimed by another consumer.
+	entries, err = imc.XClaim(ctx, "s", "g", "b", time.Minute, nil, 0)
+	require.NoError(t, err)
+	assert.Empty(t, entries)
+	imc.SetClock(fixedClock(t0.Add(2 * time.Minute)))
+	entries, err = imc.XClaim(ctx, "s", "g", "b", time.Minute, nil, 1)
+	require.NoError(t, err)
+	assert.Equal(t, []string{"1000-1"}, streamIDs(entries))
+	pending, err = imc.XPending(ctx, "s", "g")
+	require.NoError(t, err)
+	assert.Equal(t, "b", pending[0].Consumer)
+	assert.Equal(t, int64(2), pending[0].Deliveries)
+
+	// A claimed entry that was trimmed is dropped.
+	_, err = imc.XTrim(ctx, "s", StreamTrim{MaxLen: 1})
+	require.NoError(t, err)
+	entries, err = imc.XClaim(ctx, "s", "g", "c", 0, []string{"1000-1", "1000-2"}, 0)
+	require.NoError(t, err)
+	assert.Equal(t, []string{"1000-2"}, streamIDs(entries))
+	pending, err = imc.XPending(ctx, "s", "g")
+	require.NoError(t, err)
+	require.Len(t, pending, 1)
+	assert.Equal(t, "c", pending[0].Consumer)
+}
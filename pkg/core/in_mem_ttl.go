@@ -0,0 +1,40 @@
+package core
+
+import (
+	"context"
+	"errors"
+	"time"
+)
+
+// NoExpiration is the TTL reported for keys that never expire.
+const NoExpiration time.Duration = -1
+
+var ErrNotFoundForTTLOp = errors.New("no live value for given key")
+
+// TTL returns how long the given key has left to live.
+//
+// Parameters:
+//   - ctx: Context for request-scoped values, cancellation, and deadlines.
+//   - key: The key to inspect.
+//
+// Returns:
+//   - ttl: The remaining time to live, or NoExpiration if the key is persistent.
+//   - err: ErrNotFoundForTTLOp if the key does not exist or has already expired.
+func (imc *InMemoryCommandRepository) TTL(ctx context.Context, key string) (ttl time.Duration, err error) {
+	imc.mu.RLock()
+	defer imc.mu.RUnlock()
+
+	valueWithTTL, ok := imc.store[key]
+	if !ok {
+		return 0, ErrNotFoundForTTLOp
+	}
+	if valueWithTTL.Expiration.IsZero() {
+		return NoExpiration, nil
+	}
+
+	remaining := valueWithTTL.Expiration.Sub(imc.now())
+	if remaining <= 0 {
+		return 0, ErrNotFoundForTTLOp
+	}
+	return remaining, nil
+}
@@ -0,0 +1,36 @@
+package core
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/mateenbagheri/memorabilia/pkg/types"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestInMemoryCommandRepository_TTL(t *testing.T) {
+	ctx := context.Background()
+	imc := NewInMemoryCommandRepositoryWithInitialStore(
+		map[string]types.ColumnValueWithTTL{
+			"persistent": {Column: types.String{Val: "a"}},
+			"volatile":   {Column: types.String{Val: "b"}, Expiration: time.Now().Add(time.Minute)},
+			"expired":    {Column: types.String{Val: "c"}, Expiration: time.Now().Add(-time.Minute)},
+		},
+	)
+
+	ttl, err := imc.TTL(ctx, "persistent")
+	require.NoError(t, err)
+	assert.Equal(t, NoExpiration, ttl)
+
+	ttl, err = imc.TTL(ctx, "volatile")
+	require.NoError(t, err)
+	assert.InDelta(t, time.Minute, ttl, float64(time.Second))
+
+	_, err = imc.TTL(ctx, "expired")
+	assert.ErrorIs(t, err, ErrNotFoundForTTLOp)
+
+	_, err = imc.TTL(ctx, "missing")
+	assert.ErrorIs(t, err, ErrNotFoundForTTLOp)
+}
@@ -0,0 +1,46 @@
+package core
+
+// KeyspaceEventType is what happened to a key, in a KeyspaceEvent.
+type KeyspaceEventType string
+
+const (
+	// EventSet is emitted when a key is given a value, new or not.
+	EventSet KeyspaceEventType = "set"
+
+	// EventDel is emitted when a key is deleted.
+	EventDel KeyspaceEventType = "del"
+
+	// EventExpired is emitted when the TTL cleanup removes an expired key,
+	// which may be up to a cleanup interval after it expired.
+	EventExpired KeyspaceEventType = "expired"
+
+	// EventEvicted is emitted when a key is removed without having been
+	// deleted or having expired: when its slot moved to another shard.
+	EventEvicted KeyspaceEventType = "evicted"
+)
+
+// KeyspaceEvent describes a change to a key.
+type KeyspaceEvent struct {
+	Type KeyspaceEventType
+	Key  string
+}
+
+// KeyspaceNotifier receives the events of a repository. It is called with the
+// repository locked, in the order the changes are made, so it must return
+// quickly and must not call back into the repository.
+type KeyspaceNotifier func(event KeyspaceEvent)
+
+// SetNotifier makes the repository report its changes to notify; nil stops
+// reporting them.
+func (imc *InMemoryCommandRepository) SetNotifier(notify KeyspaceNotifier) {
+	imc.mu.Lock()
+	defer imc.mu.Unlock()
+	imc.notify = notify
+}
+
+// emit reports event, if anyone listens. The caller must hold imc.mu.
+func (imc *InMemoryCommandRepository) emit(eventType KeyspaceEventType, key string) {
+	if imc.notify != nil {
+		imc.notify(KeyspaceEvent{Type: eventType, Key: key})
+	}
+}
@@ -0,0 +1,83 @@
+package core
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestInMemoryCommandRepository_Notifier(t *testing.T) {
+	ctx := context.Background()
+	repo := NewInMemoryCommandRepository()
+	var events []KeyspaceEvent
+	repo.SetNotifier(func(event KeyspaceEvent) { events = append(events, event) })
+
+	require.NoError(t, repo.Set(ctx, "a", "1", time.Time{}))
+	_, _, err := repo.GetSet(ctx, "b", "2", time.Now().Add(-time.Second))
+	require.NoError(t, err)
+	require.NoError(t, repo.Set(ctx, "c", "3", time.Time{}))
+	assert.Equal(t, int64(1), repo.Delete(ctx, "a"))
+	assert.Equal(t, int64(0), repo.Delete(ctx, "missing"))
+	deleted, err := repo.Cleanup(ctx)
+	require.NoError(t, err)
+	assert.Equal(t, int64(1), deleted)
+	assert.Equal(t, int64(1), repo.Evict(ctx, []string{"c", "missing"}))
+
+	assert.Equal(t, []KeyspaceEvent{
+		{Type: EventSet, Key: "a"},
+		{Type: EventSet, Key: "b"},
+		{Type: EventSet, Key: "c"},
+		{Type: EventDel, Key: "a"},
+		{Type: EventExpired, Key: "b"},
+		{Type: EventEvicted, Key: "c"},
+	}, events)
+
+	repo.SetNotifier(nil)
+	require.NoError(t, repo.Set(ctx, "d", "4", time.Time{}))
+	assert.Len(t, events, 6)
+}
+
+func TestInMemoryCommandRepository_Notifier_TypedValues(t *testing.T) {
+	ctx := context.Background()
+	repo := NewInMemoryCommandRepository()
+	var events []KeyspaceEvent
+	repo.SetNotifier(func(event KeyspaceEvent) { events = append(events, event) })
+
+	_, err := repo.Push(ctx, "list", []string{"a"}, false)
+	require.NoError(t, err)
+	_, _, _, err = repo.Pop(ctx, []string{"list"}, true)
+	require.NoError(t, err)
+	_, err = repo.XAdd(ctx, "stream", "", map[string]string{"f": "v"}, StreamTrim{})
+	require.NoError(t, err)
+	allowed, _, _, err := repo.RateLimit(ctx, "limiter", RateLimitPolicy{Algorithm: TokenBucket, Capacity: 1, RefillRate: 1}, 1)
+	require.NoError(t, err)
+	require.True(t, allowed)
+	allowed, _, _, err = repo.RateLimit(ctx, "limiter", RateLimitPolicy{Algorithm: TokenBucket, Capacity: 1, RefillRate: 1}, 1)
+	require.NoError(t, err)
+	require.False(t, allowed, "a denied call changes nothing")
+	_, err = repo.BFReserve(ctx, "bloom", 64, 2)
+	require.NoError(t, err)
+	_, err = repo.BFAdd(ctx, "bloom", []string{"x"}, 64, 2)
+	require.NoError(t, err)
+	_, err = repo.PFAdd(ctx, "hll", []string{"x"})
+	require.NoError(t, err)
+	require.NoError(t, repo.PFMerge(ctx, "merged", []string{"hll"}))
+	entry, ok := repo.Entry("merged")
+	require.True(t, ok)
+	repo.Put("copy", entry)
+
+	assert.Equal(t, []KeyspaceEvent{
+		{Type: EventSet, Key: "list"},
+		{Type: EventDel, Key: "list"},
+		{Type: EventSet, Key: "stream"},
+		{Type: EventSet, Key: "limiter"},
+		{Type: EventSet, Key: "bloom"},
+		{Type: EventSet, Key: "bloom"},
+		{Type: EventSet, Key: "hll"},
+		{Type: EventSet, Key: "merged"},
+		{Type: EventSet, Key: "copy"},
+	}, events)
+}
@@ -0,0 +1,278 @@
+// Package pubsub fans published messages out to the subscribers of a node.
+//
+// A Hub only knows about the subscribers connected to its own node. Getting
+// a message to every node of a cluster is up to the caller, which publishes
+// it on each one's Hub (see server.PubSubServer).
+package pubsub
+
+import (
+	"errors"
+	"fmt"
+	"sync"
+
+	"github.com/mateenbagheri/memorabilia/pkg/utils/glob"
+)
+
+// DefaultBufferSize is how many messages a subscriber may fall behind by
+// unless configured otherwise.
+const DefaultBufferSize = 1024
+
+var (
+	// ErrSlowConsumer ends a subscription that fell further behind than its
+	// buffer allows, under PolicyDisconnect.
+	ErrSlowConsumer = errors.New("subscriber is not keeping up")
+
+	// ErrHubClosed ends the subscriptions of a closed Hub.
+	ErrHubClosed = errors.New("pubsub hub closed")
+)
+
+// Policy is what a Hub does with a message for a subscriber whose buffer is
+// full.
+type Policy string
+
+const (
+	// PolicyDisconnect ends the subscription with ErrSlowConsumer, so the
+	// subscriber learns it missed messages and can resubscribe.
+	PolicyDisconnect Policy = "disconnect"
+
+	// PolicyDrop leaves the message out; the next one delivered says how
+	// many were.
+	PolicyDrop Policy = "drop"
+)
+
+// ParsePolicy parses the name of a Policy.
+func ParsePolicy(s string) (Policy, error) {
+	switch p := Policy(s); p {
+	case PolicyDisconnect, PolicyDrop:
+		return p, nil
+	default:
+		return "", fmt.Errorf("pubsub: unknown slow consumer policy %q (want %q or %q)", s, PolicyDisconnect, PolicyDrop)
+	}
+}
+
+// Message is a published message, as delivered to a subscriber.
+type Message struct {
+	Channel string
+
+	// Pattern is the pattern Channel matched, for a pattern subscription.
+	Pattern string
+
+	Payload string
+
+	// Dropped counts the messages left out for this subscriber since the
+	// previous one delivered, under PolicyDrop.
+	Dropped uint64
+}
+
+// Hub delivers the messages published on a node to the node's subscribers.
+// It is safe for concurrent use.
+type Hub struct {
+	bufferSize int
+	policy     Policy
+
+	mu       sync.RWMutex
+	channels map[string]map[*Subscriber]struct{}
+	patterns map[string]map[*Subscriber]struct{}
+	closed   bool
+}
+
+// NewHub returns a hub giving each subscriber a buffer of bufferSize
+// messages, and applying policy to those falling further behind. A
+// bufferSize below 1 means DefaultBufferSize.
+func NewHub(bufferSize int, policy Policy) *Hub {
+	if bufferSize < 1 {
+		bufferSize = DefaultBufferSize
+	}
+	return &Hub{
+		bufferSize: bufferSize,
+		policy:     policy,
+		channels:   make(map[string]map[*Subscriber]struct{}),
+		patterns:   make(map[string]map[*Subscriber]struct{}),
+	}
+}
+
+// Subscribe returns a subscriber receiving the messages published to the
+// given channels, and to channels matching the given glob patterns. A
+// message matching several of them is delivered once for each.
+func (h *Hub) Subscribe(channels, patterns []string) *Subscriber {
+	s := &Subscriber{
+		hub:      h,
+		channels: channels,
+		patterns: patterns,
+		messages: make(chan Message, h.bufferSize),
+		done:     make(chan struct{}),
+	}
+
+	h.mu.Lock()
+	defer h.mu.Unlock()
+	if h.closed {
+		s.close(ErrHubClosed)
+		return s
+	}
+	for _, channel := range channels {
+		add(h.channels, channel, s)
+	}
+	for _, pattern := range patterns {
+		add(h.patterns, pattern, s)
+	}
+	return s
+}
+
+// Publish delivers payload to the subscribers of channel, and returns how
+// many it was delivered to.
+func (h *Hub) Publish(channel, payload string) int {
+	h.mu.RLock()
+	defer h.mu.RUnlock()
+
+	delivered := 0
+	for s := range h.channels[channel] {
+		if s.deliver(Message{Channel: channel, Payload: payload}, h.policy) {
+			delivered++
+		}
+	}
+	for pattern, subs := range h.patterns {
+		if !glob.Match(pattern, channel) {
+			continue
+		}
+		for s := range subs {
+			if s.deliver(Message{Channel: channel, Pattern: pattern, Payload: payload}, h.policy) {
+				delivered++
+			}
+		}
+	}
+	return delivered
+}
+
+// Close ends every subscription with ErrHubClosed, and those made from now
+// on right away.
+func (h *Hub) Close() {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+	h.closed = true
+	for _, subs := range []map[string]map[*Subscriber]struct{}{h.channels, h.patterns} {
+		for _, set := range subs {
+			for s := range set {
+				s.close(ErrHubClosed)
+			}
+		}
+	}
+	clear(h.channels)
+	clear(h.patterns)
+}
+
+func (h *Hub) remove(s *Subscriber) {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+	for _, channel := range s.channels {
+		drop(h.channels, channel, s)
+	}
+	for _, pattern := range s.patterns {
+		drop(h.patterns, pattern, s)
+	}
+}
+
+func add(subs map[string]map[*Subscriber]struct{}, name string, s *Subscriber) {
+	set, ok := subs[name]
+	if !ok {
+		set = make(map[*Subscriber]struct{})
+		subs[name] = set
+	}
+	set[s] = struct{}{}
+}
+
+func drop(subs map[string]map[*Subscriber]struct{}, name string, s *Subscriber) {
+	delete(subs[name], s)
+	if len(subs[name]) == 0 {
+		delete(subs, name)
+	}
+}
+
+// Subscriber receives the messages of a Hub.Subscribe call. Close it once
+// done.
+type Subscriber struct {
+	hub      *Hub
+	channels []string
+	patterns []string
+
+	messages chan Message
+
+	mu      sync.Mutex
+	dropped uint64
+	done    chan struct{}
+	err     error
+}
+
+// Messages returns the channel the messages are delivered on. It is never
+// closed: wait on Done as well.
+func (s *Subscriber) Messages() <-chan Message {
+	return s.messages
+}
+
+// Done is closed when the subscription ends, see Err.
+func (s *Subscriber) Done() <-chan struct{} {
+	return s.done
+}
+
+// Err returns why the subscription ended: ErrSlowConsumer, ErrHubClosed, or
+// nil once closed by Close. Only valid once Done is closed.
+func (s *Subscriber) Err() error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return s.err
+}
+
+// Close ends the subscription.
+func (s *Subscriber) Close() {
+	s.hub.remove(s)
+	s.close(nil)
+}
+
+// deliver queues msg, applying policy when the buffer is full. It reports
+// whether msg was queued.
+func (s *Subscriber) deliver(msg Message, policy Policy) bool {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if isClosed(s.done) {
+		return false
+	}
+
+	msg.Dropped = s.dropped
+	select {
+	case s.messages <- msg:
+		s.dropped = 0
+		return true
+	default:
+	}
+
+	if policy == PolicyDrop {
+		s.dropped++
+		return false
+	}
+	s.closeLocked(ErrSlowConsumer)
+	// Deliveries stop now; the hub forgets it once the owner closes it.
+	return false
+}
+
+// close ends the subscription with err, unless it already ended.
+func (s *Subscriber) close(err error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.closeLocked(err)
+}
+
+func (s *Subscriber) closeLocked(err error) {
+	if isClosed(s.done) {
+		return
+	}
+	s.err = err
+	close(s.done)
+}
+
+func isClosed(ch chan struct{}) bool {
+	select {
+	case <-ch:
+		return true
+	default:
+		return false
+	}
+}
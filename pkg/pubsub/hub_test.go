@@ -0,0 +1,80 @@
+package pubsub
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func receive(t *testing.T, s *Subscriber) Message {
+	t.Helper()
+	select {
+	case msg := <-s.Messages():
+		return msg
+	default:
+		require.FailNow(t, "no message delivered")
+		return Message{}
+	}
+}
+
+func TestHub_ChannelsAndPatterns(t *testing.T) {
+	h := NewHub(8, PolicyDisconnect)
+	news := h.Subscribe([]string{"news"}, nil)
+	defer news.Close()
+	all := h.Subscribe(nil, []string{"n*", "*"})
+	defer all.Close()
+
+	assert.Equal(t, 3, h.Publish("news", "hello"))
+	assert.Equal(t, Message{Channel: "news", Payload: "hello"}, receive(t, news))
+	got := []string{receive(t, all).Pattern, receive(t, all).Pattern}
+	assert.ElementsMatch(t, []string{"n*", "*"}, got)
+
+	assert.Equal(t, 1, h.Publish("sports", "goal"))
+	assert.Equal(t, Message{Channel: "sports", Pattern: "*", Payload: "goal"}, receive(t, all))
+
+	news.Close()
+	assert.Equal(t, 2, h.Publish("news", "again"))
+	assert.Empty(t, h.channels)
+}
+
+func TestHub_SlowConsumer(t *testing.T) {
+	t.Run("drop", func(t *testing.T) {
+		h := NewHub(1, PolicyDrop)
+		s := h.Subscribe([]string{"c"}, nil)
+		defer s.Close()
+
+		assert.Equal(t, 1, h.Publish("c", "1"))
+		assert.Equal(t, 0, h.Publish("c", "2"))
+		assert.Equal(t, 0, h.Publish("c", "3"))
+		assert.Equal(t, "1", receive(t, s).Payload)
+
+		assert.Equal(t, 1, h.Publish("c", "4"))
+		assert.Equal(t, Message{Channel: "c", Payload: "4", Dropped: 2}, receive(t, s))
+	})
+
+	t.Run("disconnect", func(t *testing.T) {
+		h := NewHub(1, PolicyDisconnect)
+		s := h.Subscribe([]string{"c"}, nil)
+		defer s.Close()
+
+		h.Publish("c", "1")
+		h.Publish("c", "2")
+		<-s.Done()
+		assert.ErrorIs(t, s.Err(), ErrSlowConsumer)
+		assert.Equal(t, 0, h.Publish("c", "3"))
+	})
+}
+
+func TestHub_Close(t *testing.T) {
+	h := NewHub(1, PolicyDisconnect)
+	s := h.Subscribe([]string{"c"}, nil)
+	h.Close()
+	<-s.Done()
+	assert.ErrorIs(t, s.Err(), ErrHubClosed)
+	s.Close()
+
+	late := h.Subscribe([]string{"c"}, nil)
+	<-late.Done()
+	assert.ErrorIs(t, late.Err(), ErrHubClosed)
+}
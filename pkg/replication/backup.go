@@ -0,0 +1,135 @@
+package replication
+
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"log/slog"
+	"maps"
+
+	"github.com/hashicorp/raft"
+	"github.com/mateenbagheri/memorabilia/pkg/types"
+)
+
+// Backup responses from /raft/backup carry the index and term of the snapshot
+// they contain in these headers.
+const (
+	SnapshotIndexHeader = "X-Memorabilia-Snapshot-Index"
+	SnapshotTermHeader  = "X-Memorabilia-Snapshot-Term"
+)
+
+// ErrInvalidBackup is returned by Restore for input that isn't a backup.
+var ErrInvalidBackup = errors.New("invalid backup")
+
+// SnapshotInfo describes a Raft snapshot taken or opened by Node.
+type SnapshotInfo struct {
+	ID    string `json:"id"`
+	Index uint64 `json:"index"`
+	Term  uint64 `json:"term"`
+	Size  int64  `json:"size"`
+
+	// Created is false when nothing had been applied since the previous
+	// snapshot, so that one is described instead of a new one.
+	Created bool `json:"created"`
+}
+
+func snapshotInfo(meta *raft.SnapshotMeta, created bool) SnapshotInfo {
+	return SnapshotInfo{ID: meta.ID, Index: meta.Index, Term: meta.Term, Size: meta.Size, Created: created}
+}
+
+// Snapshot takes a snapshot of this node's FSM now, rather than waiting for
+// the snapshot interval, and lets Raft compact its log up to it.
+func (n *Node) Snapshot() (SnapshotInfo, error) {
+	info, rc, err := n.OpenSnapshot()
+	if err != nil {
+		return SnapshotInfo{}, err
+	}
+	rc.Close()
+	return info, nil
+}
+
+// OpenSnapshot takes a snapshot like Snapshot and opens it for reading. The
+// content is the FSM state at info.Index, in the format Restore reads back
+// (see snapshotState). The caller must close the reader.
+func (n *Node) OpenSnapshot() (SnapshotInfo, io.ReadCloser, error) {
+	f := n.raft.Snapshot()
+	err := f.Error()
+	if err == nil {
+		meta, rc, err := f.Open()
+		if err != nil {
+			return SnapshotInfo{}, nil, fmt.Errorf("node snapshot: open: %w", err)
+		}
+		return snapshotInfo(meta, true), rc, nil
+	}
+	if !errors.Is(err, raft.ErrNothingNewToSnapshot) {
+		return SnapshotInfo{}, nil, fmt.Errorf("node snapshot: %w", err)
+	}
+
+	// Nothing new since the latest snapshot, so it is still current.
+	metas, err := n.snapshots.List()
+	if err != nil {
+		return SnapshotInfo{}, nil, fmt.Errorf("node snapshot: list: %w", err)
+	}
+	if len(metas) == 0 {
+		return SnapshotInfo{}, nil, fmt.Errorf("node snapshot: %w", raft.ErrNothingNewToSnapshot)
+	}
+	meta, rc, err := n.snapshots.Open(metas[0].ID)
+	if err != nil {
+		return SnapshotInfo{}, nil, fmt.Errorf("node snapshot: open %q: %w", metas[0].ID, err)
+	}
+	return snapshotInfo(meta, false), rc, nil
+}
+
+// Restore replaces the state of the whole cluster with the backup read from
+// r, as written by OpenSnapshot. Impo: Must be called on the leader.
+//
+// The backup is decoded before anything is touched, so a corrupt or foreign
+// file fails here and not halfway through. Some things are not taken from it:
+//   - member metadata, which describes the cluster the backup came from and
+//     would point leader hints at the wrong addresses; the current members
+//     are kept,
+//   - the shard map and frozen slots, which describe where the other shards'
+//     data lives now,
+//   - a cluster clock earlier than the current one, so keys that have expired
+//     by now don't come back to life until the next tick.
+//
+// Raft installs the result as a snapshot on the leader and then on every
+// follower; Restore returns once a follower quorum has it too.
+func (n *Node) Restore(r io.Reader) error {
+	return n.restore(r, nil)
+}
+
+// restore is Restore keeping only the keys keep accepts, or every key when
+// keep is nil.
+func (n *Node) restore(r io.Reader, keep func(key string) bool) error {
+	state, err := decodeSnapshot(r)
+	if err != nil {
+		return fmt.Errorf("node restore: %w: %w", ErrInvalidBackup, err)
+	}
+	if keep != nil {
+		maps.DeleteFunc(state.Data, func(key string, _ types.ColumnValueWithTTL) bool { return !keep(key) })
+		maps.DeleteFunc(state.LockTokens, func(key string, _ uint64) bool { return !keep(key) })
+	}
+
+	state.Format = snapshotFormat
+	state.Nodes = n.fsm.Nodes()
+	state.ShardMap = n.fsm.ShardMap()
+	state.Frozen = n.fsm.FrozenSlots()
+	if now := n.fsm.Clock().Now(); state.Clock.Before(now) {
+		state.Clock = now
+	}
+
+	var buf bytes.Buffer
+	if err := json.NewEncoder(&buf).Encode(state); err != nil {
+		return fmt.Errorf("node restore: encode: %w", err)
+	}
+
+	meta := &raft.SnapshotMeta{Version: raft.SnapshotVersionMax, Size: int64(buf.Len())}
+	if err := n.raft.Restore(meta, &buf, n.applyTimeout); err != nil {
+		return fmt.Errorf("node restore: raft: %w", err)
+	}
+	n.logger.Info("restored cluster state from backup", slog.Int("keys", len(state.Data)))
+	return nil
+}
@@ -0,0 +1,202 @@
+package replication
+
+import (
+	"fmt"
+	"time"
+
+	"github.com/hashicorp/raft"
+)
+
+// DefaultApplyBatchSize is the number of commands coalesced into one log
+// entry when cluster.Config.ApplyBatchSize is not set.
+const DefaultApplyBatchSize = 64
+
+// batcher coalesces commands submitted concurrently through Node.Apply into
+// OpBatch log entries, so N writers cost one log append and one fsync
+// instead of N.
+//
+// A single goroutine (run) takes the first queued command, gathers whatever
+// else is queued — waiting up to linger for more, if set — and hands the
+// batch to raft.Apply. It doesn't wait for the batch to commit: a separate
+// goroutine awaits each future, so the next batch is already being appended
+// while the previous one replicates.
+type batcher struct {
+	raft    *raft.Raft
+	stamp   func() time.Time
+	maxSize int
+	linger  time.Duration
+	timeout time.Duration
+
+	queue      chan *pendingApply
+	shutdownCh <-chan struct{}
+}
+
+type pendingApply struct {
+	cmd  *RaftCommand
+	done chan applyResult // buffered, written exactly once
+}
+
+type applyResult struct {
+	resp ApplyResponse
+	err  error
+}
+
+func newBatcher(
+	r *raft.Raft,
+	stamp func() time.Time,
+	maxSize int,
+	linger, timeout time.Duration,
+	shutdownCh <-chan struct{},
+) *batcher {
+	return &batcher{
+		raft:       r,
+		stamp:      stamp,
+		maxSize:    maxSize,
+		linger:     linger,
+		timeout:    timeout,
+		queue:      make(chan *pendingApply, maxSize),
+		shutdownCh: shutdownCh,
+	}
+}
+
+// submit queues cmd for the next batch and waits for its result.
+func (b *batcher) submit(cmd *RaftCommand) (ApplyResponse, error) {
+	p := &pendingApply{cmd: cmd, done: make(chan applyResult, 1)}
+
+	timer := time.NewTimer(b.timeout)
+	defer timer.Stop()
+	select {
+	case b.queue <- p:
+	case <-timer.C:
+		return ApplyResponse{}, fmt.Errorf("node apply: %w", raft.ErrEnqueueTimeout)
+	case <-b.shutdownCh:
+		return ApplyResponse{}, fmt.Errorf("node apply: %w", raft.ErrRaftShutdown)
+	}
+
+	select {
+	case res := <-p.done:
+		return res.resp, res.err
+	case <-b.shutdownCh:
+		// The command may or may not have made it into the log.
+		return ApplyResponse{}, fmt.Errorf("node apply: %w", raft.ErrRaftShutdown)
+	}
+}
+
+func (b *batcher) run() {
+	for {
+		var first *pendingApply
+		select {
+		case first = <-b.queue:
+		case <-b.shutdownCh:
+			b.drain()
+			return
+		}
+		b.dispatch(b.collect([]*pendingApply{first}))
+	}
+}
+
+// collect tops batch up with queued commands until it is full, the queue is
+// empty (linger 0) or linger has passed.
+func (b *batcher) collect(batch []*pendingApply) []*pendingApply {
+	var lingerC <-chan time.Time
+	if b.linger > 0 {
+		t := time.NewTimer(b.linger)
+		defer t.Stop()
+		lingerC = t.C
+	}
+
+	for len(batch) < b.maxSize {
+		if lingerC == nil {
+			select {
+			case p := <-b.queue:
+				batch = append(batch, p)
+			default:
+				return batch
+			}
+			continue
+		}
+		select {
+		case p := <-b.queue:
+			batch = append(batch, p)
+		case <-lingerC:
+			return batch
+		case <-b.shutdownCh:
+			return batch
+		}
+	}
+	return batch
+}
+
+// dispatch appends batch to the log as one entry. A lone command is sent
+// as-is rather than wrapped, so batching costs nothing when idle.
+func (b *batcher) dispatch(batch []*pendingApply) {
+	cmd := batch[0].cmd
+	if len(batch) > 1 {
+		cmd = &RaftCommand{Op: OpBatch, Now: b.stamp(), Batch: make([]*RaftCommand, len(batch))}
+		for i, p := range batch {
+			cmd.Batch[i] = p.cmd
+		}
+	}
+
+	data, err := cmd.Encode()
+	if err != nil {
+		failAll(batch, fmt.Errorf("node apply: encode: %w", err))
+		return
+	}
+
+	f := b.raft.Apply(data, b.timeout)
+	go b.await(f, batch)
+}
+
+func (b *batcher) await(f raft.ApplyFuture, batch []*pendingApply) {
+	if err := f.Error(); err != nil {
+		failAll(batch, fmt.Errorf("node apply: raft: %w", err))
+		return
+	}
+
+	if len(batch) == 1 {
+		resp, err := toApplyResult(f.Response())
+		batch[0].done <- applyResult{resp: resp, err: err}
+		return
+	}
+
+	results, ok := f.Response().(BatchResponse)
+	if !ok || len(results) != len(batch) {
+		failAll(batch, fmt.Errorf("node apply: fsm: unexpected batch response %T", f.Response()))
+		return
+	}
+	for i, p := range batch {
+		resp, err := toApplyResult(results[i])
+		p.done <- applyResult{resp: resp, err: err}
+	}
+}
+
+// drain fails everything still queued once the node shuts down.
+func (b *batcher) drain() {
+	for {
+		select {
+		case p := <-b.queue:
+			p.done <- applyResult{err: fmt.Errorf("node apply: %w", raft.ErrRaftShutdown)}
+		default:
+			return
+		}
+	}
+}
+
+func failAll(batch []*pendingApply, err error) {
+	for _, p := range batch {
+		p.done <- applyResult{err: err}
+	}
+}
+
+// toApplyResult converts one FSM result into Node.Apply's return values.
+func toApplyResult(resp any) (ApplyResponse, error) {
+	switch resp := resp.(type) {
+	case ApplyResponse:
+		return resp, nil
+	case error:
+		return ApplyResponse{}, fmt.Errorf("node apply: fsm: %w", resp)
+	default:
+		return ApplyResponse{}, fmt.Errorf("node apply: fsm: unexpected response %T", resp)
+	}
+}
@@ -0,0 +1,140 @@
+package replication
+
+import (
+	"fmt"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/hashicorp/raft"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// newTestRaft starts a single-node, in-memory Raft cluster around fsm and
+// waits until it leads.
+func newTestRaft(t *testing.T, fsm *FSM) *raft.Raft {
+	t.Helper()
+
+	cfg := raft.DefaultConfig()
+	cfg.LocalID = "n1"
+	cfg.HeartbeatTimeout = 50 * time.Millisecond
+	cfg.ElectionTimeout = 50 * time.Millisecond
+	cfg.LeaderLeaseTimeout = 50 * time.Millisecond
+	cfg.LogLevel = "error"
+
+	store := raft.NewInmemStore()
+	_, transport := raft.NewInmemTransport("n1")
+	r, err := raft.NewRaft(cfg, fsm, store, store, raft.NewInmemSnapshotStore(), transport)
+	require.NoError(t, err)
+	t.Cleanup(func() { _ = r.Shutdown().Error() })
+
+	require.NoError(t, r.BootstrapCluster(raft.Configuration{
+		Servers: []raft.Server{{ID: cfg.LocalID, Address: transport.LocalAddr()}},
+	}).Error())
+
+	select {
+	case <-r.LeaderCh():
+	case <-time.After(5 * time.Second):
+		t.Fatal("test raft never became leader")
+	}
+	return r
+}
+
+func TestBatcher_CoalescesConcurrentWrites(t *testing.T) {
+	fsm := newTestFSM(t)
+	r := newTestRaft(t, fsm)
+
+	shutdownCh := make(chan struct{})
+	defer close(shutdownCh)
+	b := newBatcher(r, time.Now, 16, 5*time.Millisecond, time.Second, shutdownCh)
+	go b.run()
+
+	const writers = 64
+	startIndex := r.LastIndex()
+
+	var wg sync.WaitGroup
+	results := make([]ApplyResponse, writers)
+	errs := make([]error, writers)
+	for i := 0; i < writers; i++ {
+		wg.Add(1)
+		go func(i int) {
+			defer wg.Done()
+			results[i], errs[i] = b.submit(&RaftCommand{
+				Op:    OpSet,
+				Key:   fmt.Sprintf("k%d", i),
+				Value: "v",
+				Now:   time.Now(),
+			})
+		}(i)
+	}
+	wg.Wait()
+
+	for i := 0; i < writers; i++ {
+		require.NoError(t, errs[i])
+		assert.Equal(t, ApplyResponse{Applied: true}, results[i])
+	}
+
+	entries := r.LastIndex() - startIndex
+	assert.Less(t, entries, uint64(writers), "writes should share log entries")
+	assert.GreaterOrEqual(t, entries, uint64(writers/16), "batches can't exceed maxSize")
+}
+
+func TestBatcher_DemultiplexesResults(t *testing.T) {
+	fsm := newTestFSM(t)
+	r := newTestRaft(t, fsm)
+	applyCmd(t, fsm, &RaftCommand{Op: OpSet, Key: "a", Value: "old"})
+
+	shutdownCh := make(chan struct{})
+	defer close(shutdownCh)
+	// A long linger makes sure all three land in one batch.
+	b := newBatcher(r, time.Now, 3, time.Second, time.Second, shutdownCh)
+	go b.run()
+
+	cmds := []*RaftCommand{
+		{Op: OpSet, Key: "a", Value: "new"},
+		{Op: OpDelete, Key: "missing"},
+		{Op: OpType(99)},
+	}
+	type result struct {
+		resp ApplyResponse
+		err  error
+	}
+	out := make([]chan result, len(cmds))
+	for i, cmd := range cmds {
+		out[i] = make(chan result, 1)
+		go func(cmd *RaftCommand, ch chan result) {
+			resp, err := b.submit(cmd)
+			ch <- result{resp, err}
+		}(cmd, out[i])
+	}
+
+	set := <-out[0]
+	require.NoError(t, set.err)
+	assert.Equal(t, ApplyResponse{Applied: true, Previous: "old", HadPrevious: true}, set.resp)
+
+	del := <-out[1]
+	require.NoError(t, del.err)
+	assert.Equal(t, ApplyResponse{}, del.resp)
+
+	bad := <-out[2]
+	assert.Error(t, bad.err, "one failing command must not fail the rest of its batch")
+}
+
+func TestFSM_Apply_Batch(t *testing.T) {
+	fsm := newTestFSM(t)
+	b, err := (&RaftCommand{Op: OpBatch, Batch: []*RaftCommand{
+		{Op: OpSet, Key: "a", Value: "1"},
+		{Op: OpSet, Key: "a", Value: "2"},
+		{Op: OpBatch},
+	}}).Encode()
+	require.NoError(t, err)
+
+	results, ok := fsm.Apply(&raft.Log{Data: b}).(BatchResponse)
+	require.True(t, ok)
+	require.Len(t, results, 3)
+	assert.Equal(t, ApplyResponse{Applied: true}, results[0])
+	assert.Equal(t, ApplyResponse{Applied: true, Previous: "1", HadPrevious: true}, results[1])
+	_, isErr := results[2].(error)
+	assert.True(t, isErr, "nested batches must be rejected")
+}
@@ -0,0 +1,465 @@
+package replication
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"slices"
+	"sort"
+	"strings"
+	"sync"
+
+	"github.com/hashicorp/raft"
+	"github.com/mateenbagheri/memorabilia/pkg/core"
+	"github.com/mateenbagheri/memorabilia/pkg/types"
+)
+
+const (
+	// changeLogSize is how many changes a ChangeLog keeps in memory. Once
+	// it holds more, the oldest quarter is dropped; subscribers still
+	// reading them continue from the Raft log, when there is one.
+	changeLogSize = 4096
+
+	// historyChunk bounds how many Raft log entries a subscriber reads at a
+	// time.
+	historyChunk = 256
+)
+
+// ErrChangeLogClosed ends the subscriptions of a closed ChangeLog.
+var ErrChangeLogClosed = errors.New("change log closed")
+
+// Change is a committed write, as delivered to subscribers of a ChangeLog.
+type Change struct {
+	// Index and Term are those of the log entry that carried the write.
+	// Writes coalesced into one entry share them, and are always delivered
+	// back to back.
+	Index uint64
+	Term  uint64
+
+	// Command is the write: an OpSet, OpDelete, OpBatchDelete or OpPut (see
+	// Recorded). Nil on a snapshot boundary.
+	Command *RaftCommand
+
+	// Snapshot marks a snapshot boundary: the changes up to Index are no
+	// longer available, so the subscriber missed some of them. Only a copy
+	// of the whole state (a Scan, or a backup) reflects them.
+	Snapshot bool
+}
+
+// ChangeLog records the writes applied to a store, in order, for Subscribe
+// streams. The FSM keeps one; recent changes are served from memory and
+// older ones from the Raft log for as long as it retains them. In single-node
+// mode there is no Raft log, and the CommandServer records its writes with
+// Record instead.
+type ChangeLog struct {
+	mu sync.Mutex
+
+	// changes holds the changes with floor < Index <= applied, in order.
+	// Anything up to floor is read from log instead.
+	changes []Change
+	floor   uint64
+	applied uint64
+
+	// started is false until the first entry is appended after the log is
+	// created or the store restored from a snapshot; floor is meaningless
+	// until then.
+	started bool
+
+	// wake is closed, and replaced, on every append. done is closed by
+	// Close.
+	wake   chan struct{}
+	closed bool
+	done   chan struct{}
+
+	// log is the Raft log the changes came from; nil in single-node mode.
+	log raft.LogStore
+}
+
+// NewChangeLog returns an empty ChangeLog.
+func NewChangeLog() *ChangeLog {
+	return &ChangeLog{wake: make(chan struct{}), done: make(chan struct{})}
+}
+
+// Record appends cmd, as returned by Recorded, as the next change, in
+// single-node mode. Its index is one past the previous one, and its term is
+// 0.
+func (c *ChangeLog) Record(cmd *RaftCommand) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	c.appendLocked(c.applied+1, 0, []*RaftCommand{cmd})
+}
+
+// append records the log entry at index as applied, along with the writes it
+// carried as returned by Recorded, if any. The FSM calls it for every entry, so that subscribers
+// know how far the store has got.
+func (c *ChangeLog) append(index, term uint64, cmds []*RaftCommand) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	c.appendLocked(index, term, cmds)
+}
+
+func (c *ChangeLog) appendLocked(index, term uint64, cmds []*RaftCommand) {
+	if !c.started {
+		c.started = true
+		c.changes = nil
+		c.floor = 0
+		if index > 0 {
+			c.floor = index - 1
+		}
+	}
+	for _, cmd := range cmds {
+		c.changes = appendRecorded(c.changes, index, term, cmd)
+	}
+	c.applied = index
+
+	if len(c.changes) > changeLogSize {
+		cut := len(c.changes) - changeLogSize*3/4
+		c.floor = c.changes[cut-1].Index
+		c.changes = slices.Clone(c.changes[cut:])
+	}
+
+	close(c.wake)
+	c.wake = make(chan struct{})
+}
+
+// reset forgets the changes in memory after the store was restored from a
+// snapshot. Subscribers behind the next entry applied read the Raft log,
+// which signals the boundary when it no longer has what they missed.
+func (c *ChangeLog) reset() {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	c.started = false
+	c.changes = nil
+}
+
+// setLog makes c read changes it no longer holds from log.
+func (c *ChangeLog) setLog(log raft.LogStore) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	c.log = log
+}
+
+// Close ends every subscription with ErrChangeLogClosed. Changes are still
+// recorded, but can't be subscribed to anymore.
+func (c *ChangeLog) Close() {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	if !c.closed {
+		c.closed = true
+		close(c.done)
+		close(c.wake)
+		c.wake = make(chan struct{})
+	}
+}
+
+// Done returns a channel closed once the change log is closed.
+func (c *ChangeLog) Done() <-chan struct{} {
+	return c.done
+}
+
+// Subscribe returns a subscription to the changes with an index of at least
+// from, or, when from is 0, to those applied from now on. Only writes to keys
+// starting with prefix are delivered; a batch delete is delivered with its
+// other keys left out.
+func (c *ChangeLog) Subscribe(from uint64, prefix string) *Subscription {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	if from == 0 && c.started {
+		from = c.applied + 1
+	}
+	return &Subscription{changes: c, next: from, prefix: prefix}
+}
+
+// Subscription reads changes from a ChangeLog, see ChangeLog.Subscribe. It
+// holds no resources, and is not safe for concurrent use.
+type Subscription struct {
+	changes *ChangeLog
+	prefix  string
+
+	// next is the index of the next change to deliver; 0 until the change
+	// log has started, for a subscription to changes from now on.
+	next uint64
+}
+
+// Next blocks until there are changes to deliver, and returns them. It fails
+// with ctx's error once ctx is done, and with ErrChangeLogClosed once the
+// change log is closed.
+func (s *Subscription) Next(ctx context.Context) ([]Change, error) {
+	c := s.changes
+	for {
+		c.mu.Lock()
+		if c.closed {
+			c.mu.Unlock()
+			return nil, ErrChangeLogClosed
+		}
+		if s.next == 0 && c.started {
+			s.next = c.floor + 1
+		}
+
+		switch {
+		case !c.started || s.next == 0 || s.next > c.applied:
+			wake := c.wake
+			c.mu.Unlock()
+			select {
+			case <-wake:
+			case <-ctx.Done():
+				return nil, ctx.Err()
+			}
+
+		case s.next <= c.floor:
+			from, to, log := s.next, c.floor, c.log
+			c.mu.Unlock()
+			changes, next, err := readLog(log, from, to)
+			if err != nil {
+				return nil, err
+			}
+			s.next = next
+			if changes = s.filter(changes); len(changes) > 0 {
+				return changes, nil
+			}
+
+		default:
+			i := sort.Search(len(c.changes), func(i int) bool { return c.changes[i].Index >= s.next })
+			changes := slices.Clone(c.changes[i:])
+			s.next = c.applied + 1
+			c.mu.Unlock()
+			if changes = s.filter(changes); len(changes) > 0 {
+				return changes, nil
+			}
+		}
+	}
+}
+
+// Position returns the index up to which every change has been delivered.
+func (s *Subscription) Position() uint64 {
+	if s.next == 0 {
+		return 0
+	}
+	return s.next - 1
+}
+
+// filter drops the changes to keys outside the subscription's prefix.
+func (s *Subscription) filter(changes []Change) []Change {
+	if s.prefix == "" {
+		return changes
+	}
+	out := changes[:0]
+	for _, change := range changes {
+		cmd := change.Command
+		switch {
+		case cmd == nil:
+		case cmd.Op == OpBatchDelete:
+			var keys []string
+			for _, key := range cmd.Keys {
+				if strings.HasPrefix(key, s.prefix) {
+					keys = append(keys, key)
+				}
+			}
+			if len(keys) == 0 {
+				continue
+			}
+			filtered := *cmd
+			filtered.Keys = keys
+			change.Command = &filtered
+		case !strings.HasPrefix(cmd.Key, s.prefix):
+			continue
+		}
+		out = append(out, change)
+	}
+	return out
+}
+
+// readLog returns the changes in the Raft log entries from to to, reading at
+// most historyChunk of them, and the index to continue from. When the log no
+// longer has the first of them, it returns a snapshot boundary instead.
+//
+// The log doesn't record which writes the FSM refused, so writes to a slot
+// that was frozen at the time are returned too.
+func readLog(log raft.LogStore, from, to uint64) ([]Change, uint64, error) {
+	if log == nil {
+		return []Change{{Index: to, Snapshot: true}}, to + 1, nil
+	}
+	first, err := log.FirstIndex()
+	if err != nil {
+		return nil, 0, fmt.Errorf("change log: first index: %w", err)
+	}
+	if first == 0 || first > to {
+		return []Change{{Index: to, Snapshot: true}}, to + 1, nil
+	}
+	if from < first {
+		return []Change{{Index: first - 1, Snapshot: true}}, first, nil
+	}
+
+	var changes []Change
+	end := min(to, from+historyChunk-1)
+	for i := from; i <= end; i++ {
+		var entry raft.Log
+		if err := log.GetLog(i, &entry); err != nil {
+			if !errors.Is(err, raft.ErrLogNotFound) {
+				return nil, 0, fmt.Errorf("change log: get entry %d: %w", i, err)
+			}
+			// Compacted since FirstIndex was read.
+			changes = append(changes, Change{Index: i, Snapshot: true})
+			return changes, i + 1, nil
+		}
+		if entry.Type != raft.LogCommand {
+			continue
+		}
+		cmd, err := DecodeCommand(entry.Data)
+		if err != nil {
+			return nil, 0, fmt.Errorf("change log: decode entry %d: %w", i, err)
+		}
+		changes = logChanges(changes, entry.Index, entry.Term, cmd)
+	}
+	return changes, end + 1, nil
+}
+
+// appendRecorded appends cmd, a write as returned by Recorded, to changes, or
+// the writes it carries.
+func appendRecorded(changes []Change, index, term uint64, cmd *RaftCommand) []Change {
+	if cmd.Op == OpStandbyApply {
+		for _, sub := range cmd.Batch {
+			changes = appendRecorded(changes, index, term, sub)
+		}
+		return changes
+	}
+	return append(changes, Change{Index: index, Term: term, Command: cmd})
+}
+
+// logChanges appends cmd, read back from the Raft log, to changes if it is a
+// write to the keyspace, or the writes it carries. Moving slots between
+// shards isn't one: the keys only change groups.
+//
+// The writes Recorded records by their outcome are a snapshot boundary
+// instead, since only a copy of the state reflects them.
+func logChanges(changes []Change, index, term uint64, cmd *RaftCommand) []Change {
+	switch cmd.Op {
+	case OpSet, OpDelete, OpPut:
+		return append(changes, Change{Index: index, Term: term, Command: cmd})
+	case OpBatchDelete:
+		if cmd.Expired {
+			return appendBoundary(changes, index, term)
+		}
+		return append(changes, Change{Index: index, Term: term, Command: cmd})
+	case OpListPush, OpListPop, OpQueuePop, OpQueueAck, OpQueueNack, OpLock, OpUnlock, OpRefreshLock,
+		OpStreamAdd, OpStreamTrim, OpStreamCreateGroup, OpStreamReadGroup, OpStreamAck, OpStreamClaim,
+		OpRateLimit, OpBloomReserve, OpBloomAdd, OpHLLAdd, OpHLLMerge, OpSetBit, OpBitOp, OpBitField:
+		return appendBoundary(changes, index, term)
+	case OpCloseSession:
+		if len(cmd.Keys) == 0 {
+			return changes
+		}
+		return appendBoundary(changes, index, term)
+	case OpBatch, OpStandbyApply:
+		for _, sub := range cmd.Batch {
+			changes = logChanges(changes, index, term, sub)
+		}
+		return changes
+	default:
+		return changes
+	}
+}
+
+// appendBoundary appends a snapshot boundary at index to changes, unless
+// they already end with it.
+func appendBoundary(changes []Change, index, term uint64) []Change {
+	if n := len(changes); n > 0 && changes[n-1].Snapshot && changes[n-1].Index == index {
+		return changes
+	}
+	return append(changes, Change{Index: index, Term: term, Snapshot: true})
+}
+
+// Recorded returns the write the change log records for cmd, which repo
+// applied with the response resp, or nil when cmd isn't a write to the
+// keyspace or changed nothing. Some writes are recorded by their outcome,
+// since replaying them would take the state they applied to:
+//   - a write to a list, queue, lock, stream, rate limiter, Bloom filter or
+//     HyperLogLog, as an OpPut of the entry it left at its key, or as an
+//     OpDelete when it deleted the key; for a lock, replaying it would also
+//     take the fencing token the FSM gave it,
+//   - a bit write, as an OpSet of the string it left at its key, in
+//     RawValue, or as an OpDelete when an OpBitOp deleted the key,
+//   - the TTL cleanup's OpBatchDelete, as one of the keys still expired,
+//     which it deleted,
+//   - an OpCloseSession, as an OpBatchDelete of the keys still attached to
+//     the session, which it deleted.
+func Recorded(repo core.CommandsRepository, cmd *RaftCommand, resp ApplyResponse) *RaftCommand {
+	key := cmd.Key
+	switch cmd.Op {
+	case OpSet, OpDelete, OpStandbyApply, OpPut:
+		return cmd
+	case OpBatchDelete:
+		if !cmd.Expired {
+			return cmd
+		}
+		if len(resp.Keys) == 0 {
+			return nil
+		}
+		return &RaftCommand{Op: OpBatchDelete, Keys: resp.Keys, Expired: true, Now: cmd.Now}
+	case OpCloseSession:
+		if len(resp.Keys) == 0 {
+			return nil
+		}
+		return &RaftCommand{Op: OpBatchDelete, Keys: resp.Keys, Expired: cmd.Expired, Now: cmd.Now}
+	case OpSetBit, OpBitOp:
+		return recordedBitmap(repo, cmd)
+	case OpBitField:
+		if !wroteBitField(cmd.BitFields, resp.BitFields) {
+			return nil
+		}
+		return recordedBitmap(repo, cmd)
+	case OpListPush, OpStreamAdd, OpHLLMerge:
+	case OpListPop:
+		if !resp.Applied {
+			return nil
+		}
+		key = resp.Key
+	case OpQueuePop, OpQueueAck, OpQueueNack, OpLock, OpUnlock, OpRefreshLock,
+		OpStreamTrim, OpStreamCreateGroup, OpStreamReadGroup, OpStreamAck, OpStreamClaim,
+		OpRateLimit, OpBloomReserve, OpBloomAdd, OpHLLAdd:
+		if !resp.Applied {
+			return nil
+		}
+	default:
+		return nil
+	}
+
+	entry, ok := repo.Entry(key)
+	if !ok {
+		return &RaftCommand{Op: OpDelete, Key: key, Now: cmd.Now}
+	}
+	return &RaftCommand{
+		Op:         OpPut,
+		Key:        key,
+		Entries:    map[string]types.ColumnValueWithTTL{key: entry},
+		Expiration: entry.Expiration,
+		Now:        cmd.Now,
+	}
+}
+
+// recordedBitmap returns the write recording the string the bit write cmd
+// left at its key: an OpSet of its bytes, or an OpDelete when there is none.
+func recordedBitmap(repo core.CommandsRepository, cmd *RaftCommand) *RaftCommand {
+	entry, ok := repo.Entry(cmd.Key)
+	if !ok {
+		return &RaftCommand{Op: OpDelete, Key: cmd.Key, Now: cmd.Now}
+	}
+	return &RaftCommand{
+		Op:         OpSet,
+		Key:        cmd.Key,
+		RawValue:   []byte(entry.Column.ToString()),
+		Expiration: entry.Expiration,
+		Now:        cmd.Now,
+	}
+}
+
+// wroteBitField reports whether any of ops, which gave results, wrote to the
+// string: a BitFieldSet or BitFieldIncrBy that didn't fail.
+func wroteBitField(ops []core.BitFieldOp, results []core.BitFieldResult) bool {
+	for i, op := range ops {
+		if op.Op != core.BitFieldGet && i < len(results) && !results[i].Failed {
+			return true
+		}
+	}
+	return false
+}
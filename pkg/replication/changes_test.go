@@ -0,0 +1,291 @@
+package replication
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/hashicorp/raft"
+	"github.com/mateenbagheri/memorabilia/pkg/core"
+	"github.com/mateenbagheri/memorabilia/pkg/sharding"
+	"github.com/mateenbagheri/memorabilia/pkg/types"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// nextChanges reads the changes sub has ready, failing if there are none.
+func nextChanges(t *testing.T, sub *Subscription) []Change {
+	t.Helper()
+	ctx, cancel := context.WithTimeout(context.Background(), time.Second)
+	defer cancel()
+	changes, err := sub.Next(ctx)
+	require.NoError(t, err)
+	return changes
+}
+
+func TestChangeLog_ReplaysThenFollows(t *testing.T) {
+	c := NewChangeLog()
+	c.Record(&RaftCommand{Op: OpSet, Key: "a", Value: "1"})
+	c.Record(&RaftCommand{Op: OpSet, Key: "b", Value: "2"})
+
+	sub := c.Subscribe(2, "")
+	changes := nextChanges(t, sub)
+	require.Len(t, changes, 1)
+	assert.Equal(t, uint64(2), changes[0].Index)
+	assert.Equal(t, "b", changes[0].Command.Key)
+
+	live := c.Subscribe(0, "")
+	go c.Record(&RaftCommand{Op: OpDelete, Key: "a"})
+	for _, s := range []*Subscription{sub, live} {
+		changes = nextChanges(t, s)
+		require.Len(t, changes, 1)
+		assert.Equal(t, uint64(3), changes[0].Index)
+		assert.Equal(t, OpDelete, changes[0].Command.Op)
+	}
+
+	c.Close()
+	_, err := sub.Next(context.Background())
+	assert.ErrorIs(t, err, ErrChangeLogClosed)
+}
+
+func TestChangeLog_FiltersByPrefix(t *testing.T) {
+	c := NewChangeLog()
+	c.Record(&RaftCommand{Op: OpSet, Key: "user:1", Value: "x"})
+	c.Record(&RaftCommand{Op: OpSet, Key: "order:1", Value: "y"})
+	c.Record(&RaftCommand{Op: OpBatchDelete, Keys: []string{"order:1", "user:1", "user:2"}})
+
+	changes := nextChanges(t, c.Subscribe(1, "user:"))
+	require.Len(t, changes, 2)
+	assert.Equal(t, "user:1", changes[0].Command.Key)
+	assert.Equal(t, []string{"user:1", "user:2"}, changes[1].Command.Keys)
+}
+
+func TestChangeLog_SignalsSnapshotBoundary(t *testing.T) {
+	c := NewChangeLog()
+	for i := 0; i <= changeLogSize; i++ {
+		c.Record(&RaftCommand{Op: OpDelete, Key: "k"})
+	}
+	dropped := uint64(changeLogSize/4 + 1)
+
+	sub := c.Subscribe(1, "")
+	changes := nextChanges(t, sub)
+	assert.Equal(t, []Change{{Index: dropped, Snapshot: true}}, changes,
+		"without a Raft log, dropped changes are gone")
+
+	changes = nextChanges(t, sub)
+	assert.Equal(t, dropped+1, changes[0].Index)
+	assert.Equal(t, uint64(changeLogSize+1), changes[len(changes)-1].Index)
+}
+
+func TestChangeLog_ReadsRaftLog(t *testing.T) {
+	store := raft.NewInmemStore()
+	fsm := newTestFSM(t)
+	fsm.Changes().setLog(store)
+
+	frozen := sharding.SlotOf("frozen")
+	entries := []*RaftCommand{
+		{Op: OpSet, Key: "a", Value: "1"},
+		{Op: OpTick},
+		{Op: OpBatch, Batch: []*RaftCommand{
+			{Op: OpSet, Key: "b", Value: "2", TTL: time.Minute},
+			{Op: OpDelete, Key: "a"},
+		}},
+		{Op: OpFreezeSlots, Slots: []int{frozen}},
+		{Op: OpSet, Key: "frozen", Value: "refused"},
+	}
+	for i, cmd := range entries {
+		data, err := cmd.Encode()
+		require.NoError(t, err)
+		l := &raft.Log{Index: uint64(i + 1), Term: 2, Type: raft.LogCommand, Data: data}
+		require.NoError(t, store.StoreLog(l))
+		fsm.Apply(l)
+	}
+
+	changes := nextChanges(t, fsm.Changes().Subscribe(1, ""))
+	require.Len(t, changes, 3, "refused writes aren't changes")
+	assert.Equal(t, Change{Index: 1, Term: 2, Command: entries[0]}, changes[0])
+	assert.Equal(t, uint64(3), changes[1].Index)
+	assert.Equal(t, uint64(3), changes[2].Index)
+	assert.Equal(t, OpDelete, changes[2].Command.Op)
+
+	// After a restore, the changes before it are read from the Raft log,
+	// as far back as it goes.
+	require.NoError(t, store.DeleteRange(1, 2))
+	fsm.Changes().reset()
+	data, err := (&RaftCommand{Op: OpDelete, Key: "b"}).Encode()
+	require.NoError(t, err)
+	fsm.Apply(&raft.Log{Index: 6, Term: 3, Type: raft.LogCommand, Data: data})
+
+	sub := fsm.Changes().Subscribe(1, "")
+	assert.Equal(t, []Change{{Index: 2, Snapshot: true}}, nextChanges(t, sub))
+	changes = nextChanges(t, sub)
+	require.Len(t, changes, 3, "the entries from 3 are read back from the log")
+	assert.Equal(t, "b", changes[0].Command.Key)
+	assert.Equal(t, time.Minute, changes[0].Command.TTL)
+	assert.Equal(t, "frozen", changes[2].Command.Key,
+		"the log doesn't tell which writes were refused")
+	changes = nextChanges(t, sub)
+	assert.Equal(t, uint64(6), changes[0].Index)
+}
+
+func TestChangeLog_RecordsTypedWritesByOutcome(t *testing.T) {
+	store := raft.NewInmemStore()
+	fsm := newTestFSM(t)
+	fsm.Changes().setLog(store)
+
+	entries := []*RaftCommand{
+		{Op: OpListPush, Key: "q", Values: []string{"a", "b"}},
+		{Op: OpBatch, Batch: []*RaftCommand{
+			{Op: OpListPop, Keys: []string{"empty", "q"}, Left: true},
+			{Op: OpListPop, Keys: []string{"q"}, Left: true},
+		}},
+		{Op: OpListPop, Keys: []string{"q"}},
+		{Op: OpSet, Key: "s", Value: "v"},
+	}
+	for i, cmd := range entries {
+		data, err := cmd.Encode()
+		require.NoError(t, err)
+		l := &raft.Log{Index: uint64(i + 1), Term: 2, Type: raft.LogCommand, Data: data}
+		require.NoError(t, store.StoreLog(l))
+		fsm.Apply(l)
+	}
+
+	changes := nextChanges(t, fsm.Changes().Subscribe(1, ""))
+	require.Len(t, changes, 4, "a pop from an empty list changes nothing")
+	put := changes[0].Command
+	assert.Equal(t, OpPut, put.Op)
+	assert.Equal(t, "q", put.Key)
+	assert.Equal(t, types.List{Val: []string{"a", "b"}}, put.Entries["q"].Column)
+	assert.Equal(t, uint64(2), changes[1].Index)
+	assert.Equal(t, types.List{Val: []string{"b"}}, changes[1].Command.Entries["q"].Column,
+		"the pop from the first non-empty list is recorded at that list")
+	assert.Equal(t, &RaftCommand{Op: OpDelete, Key: "q"}, changes[2].Command,
+		"popping the last element deletes the list")
+	assert.Equal(t, uint64(2), changes[2].Index)
+	assert.Equal(t, uint64(4), changes[3].Index)
+
+	// The Raft log only has the commands: their outcome is only in memory.
+	fsm.Changes().reset()
+	data, err := (&RaftCommand{Op: OpDelete, Key: "s"}).Encode()
+	require.NoError(t, err)
+	fsm.Apply(&raft.Log{Index: 5, Term: 3, Type: raft.LogCommand, Data: data})
+
+	changes = nextChanges(t, fsm.Changes().Subscribe(1, ""))
+	require.Len(t, changes, 4)
+	for i, change := range changes[:3] {
+		assert.Equal(t, Change{Index: uint64(i + 1), Term: 2, Snapshot: true}, change)
+	}
+	assert.Equal(t, "s", changes[3].Command.Key)
+}
+
+func TestChangeLog_RecordsBitWritesAsSets(t *testing.T) {
+	store := raft.NewInmemStore()
+	fsm := newTestFSM(t)
+	fsm.Changes().setLog(store)
+
+	entries := []*RaftCommand{
+		{Op: OpSetBit, Key: "bits", Offset: 0, Bit: true},
+		{Op: OpBitField, Key: "bits", BitFields: []core.BitFieldOp{{Op: core.BitFieldGet, Width: 8}}},
+		{Op: OpBitField, Key: "bits", BitFields: []core.BitFieldOp{{Op: core.BitFieldSet, Width: 8, Offset: 8, Value: 255}}},
+		{Op: OpBitOp, Key: "dest", Value: core.BitAnd, Keys: []string{"missing"}},
+	}
+	for i, cmd := range entries {
+		data, err := cmd.Encode()
+		require.NoError(t, err)
+		l := &raft.Log{Index: uint64(i + 1), Term: 2, Type: raft.LogCommand, Data: data}
+		require.NoError(t, store.StoreLog(l))
+		fsm.Apply(l)
+	}
+
+	changes := nextChanges(t, fsm.Changes().Subscribe(1, ""))
+	require.Len(t, changes, 3, "a bitfield GET writes nothing")
+	assert.Equal(t, &RaftCommand{Op: OpSet, Key: "bits", RawValue: []byte{0x80}}, changes[0].Command)
+	assert.Equal(t, uint64(3), changes[1].Index)
+	assert.Equal(t, &RaftCommand{Op: OpSet, Key: "bits", RawValue: []byte{0x80, 0xff}}, changes[1].Command)
+	assert.Equal(t, &RaftCommand{Op: OpDelete, Key: "dest"}, changes[2].Command,
+		"a bit op of empty strings deletes its destination")
+
+	fsm.Changes().reset()
+	data, err := (&RaftCommand{Op: OpDelete, Key: "bits"}).Encode()
+	require.NoError(t, err)
+	fsm.Apply(&raft.Log{Index: 5, Term: 3, Type: raft.LogCommand, Data: data})
+
+	changes = nextChanges(t, fsm.Changes().Subscribe(1, ""))
+	require.Len(t, changes, 4)
+	for i, change := range changes {
+		assert.Equal(t, Change{Index: uint64(i + 1), Term: 2, Snapshot: true}, change)
+	}
+}
+
+func TestChangeLog_RecordsKeysTheCleanupDeleted(t *testing.T) {
+	store := raft.NewInmemStore()
+	fsm := newTestFSM(t)
+	fsm.Changes().setLog(store)
+
+	t0 := time.Date(2001, 1, 1, 0, 0, 0, 0, time.UTC)
+	entries := []*RaftCommand{
+		{Op: OpSet, Key: "a", Value: "1", Expiration: t0.Add(time.Second), Now: t0},
+		{Op: OpSet, Key: "b", Value: "2", Now: t0},
+		{Op: OpBatchDelete, Keys: []string{"a", "b", "missing"}, Expired: true, Now: t0.Add(2 * time.Second)},
+	}
+	for i, cmd := range entries {
+		data, err := cmd.Encode()
+		require.NoError(t, err)
+		l := &raft.Log{Index: uint64(i + 1), Term: 2, Type: raft.LogCommand, Data: data}
+		require.NoError(t, store.StoreLog(l))
+		fsm.Apply(l)
+	}
+
+	changes := nextChanges(t, fsm.Changes().Subscribe(3, ""))
+	require.Len(t, changes, 1)
+	assert.Equal(t, &RaftCommand{Op: OpBatchDelete, Keys: []string{"a"}, Expired: true, Now: t0.Add(2 * time.Second)},
+		changes[0].Command, "b hasn't expired")
+
+	fsm.Changes().reset()
+	data, err := (&RaftCommand{Op: OpDelete, Key: "b"}).Encode()
+	require.NoError(t, err)
+	fsm.Apply(&raft.Log{Index: 4, Term: 3, Type: raft.LogCommand, Data: data})
+
+	changes = nextChanges(t, fsm.Changes().Subscribe(3, ""))
+	assert.Equal(t, []Change{{Index: 3, Term: 2, Snapshot: true}}, changes,
+		"the log doesn't tell which keys the cleanup deleted")
+}
+
+func TestChangeLog_RecordsKeysAClosedSessionDeleted(t *testing.T) {
+	store := raft.NewInmemStore()
+	fsm := newTestFSM(t)
+	fsm.Changes().setLog(store)
+
+	t0 := time.Date(2001, 1, 1, 0, 0, 0, 0, time.UTC)
+	entries := []*RaftCommand{
+		{Op: OpCreateSession, Session: "s", TTL: time.Minute, Now: t0},
+		{Op: OpSet, Key: "svc/a", Value: "1", Session: "s", Now: t0},
+		{Op: OpSet, Key: "svc/b", Value: "1", Session: "s", Now: t0},
+		{Op: OpSet, Key: "svc/b", Value: "2", Now: t0},
+		{Op: OpCloseSession, Session: "s", Keys: []string{"svc/a", "svc/b"}, Now: t0},
+	}
+	for i, cmd := range entries {
+		data, err := cmd.Encode()
+		require.NoError(t, err)
+		l := &raft.Log{Index: uint64(i + 1), Term: 2, Type: raft.LogCommand, Data: data}
+		require.NoError(t, store.StoreLog(l))
+		fsm.Apply(l)
+	}
+
+	val, err := fsm.Repository().Get(context.Background(), "svc/b")
+	require.NoError(t, err)
+	assert.Equal(t, "2", val, "svc/b was set again without the session")
+
+	changes := nextChanges(t, fsm.Changes().Subscribe(5, ""))
+	require.Len(t, changes, 1)
+	assert.Equal(t, &RaftCommand{Op: OpBatchDelete, Keys: []string{"svc/a"}, Now: t0}, changes[0].Command)
+
+	fsm.Changes().reset()
+	data, err := (&RaftCommand{Op: OpDelete, Key: "svc/b"}).Encode()
+	require.NoError(t, err)
+	fsm.Apply(&raft.Log{Index: 6, Term: 3, Type: raft.LogCommand, Data: data})
+
+	changes = nextChanges(t, fsm.Changes().Subscribe(5, ""))
+	assert.Equal(t, []Change{{Index: 5, Term: 2, Snapshot: true}}, changes,
+		"the log doesn't tell which keys closing the session deleted")
+}
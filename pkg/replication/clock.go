@@ -0,0 +1,67 @@
+package replication
+
+import (
+	"sync/atomic"
+	"time"
+
+	"github.com/mateenbagheri/memorabilia/pkg/cluster"
+)
+
+// defaultClockTickInterval is how often an otherwise idle leader replicates
+// an OpTick so the cluster clock keeps moving, unless cluster.Config says
+// otherwise.
+const defaultClockTickInterval = time.Second
+
+// clockTickInterval returns how often the leader ticks the cluster clock.
+func clockTickInterval(cfg *cluster.Config) time.Duration {
+	if cfg.ClockTickInterval > 0 {
+		return cfg.ClockTickInterval
+	}
+	return defaultClockTickInterval
+}
+
+// ClusterClock is the replicated time every node evaluates expiry against.
+//
+// The leader stamps each RaftCommand with its wall clock (RaftCommand.Now)
+// and the FSM advances the clock as it applies them, so the clock is a pure
+// function of the applied log: two replicas at the same applied index agree
+// on the time, and therefore on which keys are live, whatever their own
+// wall clocks say. The clock never moves backwards, even when a new leader's
+// wall clock is behind the old one's.
+type ClusterClock struct {
+	nanos atomic.Int64
+}
+
+// Now returns the latest applied timestamp; the zero time before anything
+// has been applied.
+func (c *ClusterClock) Now() time.Time {
+	n := c.nanos.Load()
+	if n == 0 {
+		return time.Time{}
+	}
+	return time.Unix(0, n)
+}
+
+// observe advances the clock to t if t is later.
+func (c *ClusterClock) observe(t time.Time) {
+	if t.IsZero() {
+		return
+	}
+	n := t.UnixNano()
+	for {
+		cur := c.nanos.Load()
+		if n <= cur || c.nanos.CompareAndSwap(cur, n) {
+			return
+		}
+	}
+}
+
+// reset sets the clock to t, which may be earlier. Only used on restore,
+// where the snapshot replaces all state.
+func (c *ClusterClock) reset(t time.Time) {
+	if t.IsZero() {
+		c.nanos.Store(0)
+		return
+	}
+	c.nanos.Store(t.UnixNano())
+}
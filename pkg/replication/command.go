@@ -3,6 +3,11 @@ package replication
 import (
 	"encoding/json"
 	"time"
+
+	"github.com/mateenbagheri/memorabilia/pkg/cluster"
+	"github.com/mateenbagheri/memorabilia/pkg/core"
+	"github.com/mateenbagheri/memorabilia/pkg/sharding"
+	"github.com/mateenbagheri/memorabilia/pkg/types"
 )
 
 type OpType uint8
@@ -11,6 +16,124 @@ const (
 	OpSet OpType = iota
 	OpDelete
 	OpBatchDelete
+	OpSetNodeMeta
+	OpDeleteNodeMeta
+
+	// OpTick carries nothing but its timestamp. The leader replicates one
+	// when there were no other writes for a while, to keep the cluster clock
+	// moving (see ClusterClock).
+	OpTick
+
+	// OpBatch carries several commands in Batch, applied in order as one log
+	// entry. Node.Apply builds these to coalesce concurrent writes.
+	OpBatch
+
+	// OpSetShardMap replaces the shard map with ShardMap, provided it is the
+	// successor of the current one. Only applied in the metadata group.
+	OpSetShardMap
+
+	// The ops below move Slots out of or into a shard's group (see
+	// ShardHost.MoveSlots). OpFreezeSlots makes the group refuse writes to
+	// the slots, so that everything applied before it is everything there
+	// is to copy. OpLoad copies the moved Entries in on the destination.
+	// OpDropSlots deletes the moved keys from the source and unfreezes the
+	// slots; OpUnfreezeSlots only unfreezes them, when a move is aborted.
+	OpFreezeSlots
+	OpLoad
+	OpDropSlots
+	OpUnfreezeSlots
+
+	// OpStandbyApply applies the writes in Batch, received from the primary
+	// cluster a standby follows, and records Position as the primary's log
+	// index the standby has caught up to (see package standby). Batch may be
+	// empty, to only move Position.
+	OpStandbyApply
+
+	// OpListPush pushes Values to the list at Key, at its head when Left is
+	// set. OpListPop pops an element from the first of Keys holding a
+	// non-empty list, from its head when Left is set.
+	OpListPush
+	OpListPop
+
+	// The ops below implement reliable queues on lists (see
+	// core.CommandsRepository.QueuePop). OpQueuePop pops the head of the
+	// list at Key into its jobs in flight, for TTL. OpQueueAck and
+	// OpQueueNack remove the job whose ID is Value, the latter putting it
+	// back at the head of the list.
+	OpQueuePop
+	OpQueueAck
+	OpQueueNack
+
+	// The ops below implement locks (see core.CommandsRepository.Lock).
+	// OpLock acquires the lock at Key for Session with a lease of TTL; the
+	// fencing token is the index of the log entry carrying it, or one past
+	// the last token the key was given when that is higher. OpUnlock
+	// releases it and OpRefreshLock renews its lease for TTL, provided
+	// Session holds it with the fencing token Token.
+	OpLock
+	OpUnlock
+	OpRefreshLock
+
+	// The ops below implement client sessions (see
+	// core.CommandsRepository.CreateSession). OpCreateSession starts the
+	// session Session, ending TTL after Now unless OpKeepAlive keeps it
+	// alive. OpCloseSession ends it and deletes Keys, the ephemeral keys its
+	// proposer found attached to it, as expired when Expired is set; those
+	// set again since without the session are kept. The change log records
+	// it as an OpBatchDelete of the keys it deleted.
+	OpCreateSession
+	OpKeepAlive
+	OpCloseSession
+
+	// The ops below implement streams (see core.CommandsRepository.XAdd).
+	// OpStreamAdd appends Fields to the stream at Key under the entry ID
+	// Value, a new one when empty, and OpStreamTrim trims it; both trim to
+	// MaxLen and MinID. OpStreamCreateGroup creates the consumer group Group,
+	// delivered the entries after Value. OpStreamReadGroup reads up to Count
+	// entries as Consumer of Group, after Value; OpStreamAck acknowledges the
+	// entries Values of Group. OpStreamClaim hands up to Count entries of
+	// Group idle for TTL over to Consumer: Values, or the oldest ones when
+	// empty.
+	OpStreamAdd
+	OpStreamTrim
+	OpStreamCreateGroup
+	OpStreamReadGroup
+	OpStreamAck
+	OpStreamClaim
+
+	// OpRateLimit consumes Cost from the rate limiter at Key (see
+	// core.CommandsRepository.RateLimit), of the algorithm Value, holding
+	// Capacity and refilled at Rate per second, or over a window of TTL.
+	OpRateLimit
+
+	// The ops below implement Bloom filters and HyperLogLogs (see
+	// core.CommandsRepository.BFAdd and PFAdd). OpBloomReserve creates the
+	// filter at Key of Bits bits, setting Hashes per item, and OpBloomAdd
+	// adds Values to it, creating it the same way if needed. The proposer
+	// computes the geometry, so that every replica builds the same bitmap.
+	// OpHLLAdd adds Values to the HyperLogLog at Key, and OpHLLMerge merges
+	// the ones at Keys into it.
+	OpBloomReserve
+	OpBloomAdd
+	OpHLLAdd
+	OpHLLMerge
+
+	// The ops below implement bitmaps (see core.CommandsRepository.SetBit).
+	// OpSetBit sets the bit at Offset of the string at Key to Bit. OpBitOp
+	// stores the bitwise Value of the strings at Keys at Key. OpBitField runs
+	// BitFields on the string at Key. The change log records them as the
+	// OpSet of the string they left at Key, in RawValue, or as an OpDelete
+	// when an OpBitOp deleted it.
+	OpSetBit
+	OpBitOp
+	OpBitField
+
+	// OpPut stores the entry Entries holds for Key as it is. It is never
+	// proposed for a client: the change log records the writes to lists,
+	// queues, locks, streams, rate limiters, Bloom filters and HyperLogLogs
+	// as the OpPut of their outcome, which a standby cluster applies in an
+	// OpStandbyApply.
+	OpPut
 )
 
 type RaftCommand struct {
@@ -19,6 +142,100 @@ type RaftCommand struct {
 	Value      string    `json:"value,omitempty"`
 	Key        string    `json:"key,omitempty"`
 	Keys       []string  `json:"keys,omitempty"`
+
+	// RawValue is the value of an OpSet instead of Value when it may not be
+	// valid UTF-8, which a JSON string would mangle: a string written bit by
+	// bit, say. See SetValue.
+	RawValue []byte `json:"raw_value,omitempty"`
+
+	// Now is the leader's time when it proposed the command. Node.Apply
+	// stamps it; the FSM advances the cluster clock with it.
+	Now time.Time `json:"now,omitempty"`
+
+	// TTL makes an OpSet expire TTL after Now. Preferred over Expiration,
+	// since it ties the deadline to the replicated clock rather than to the
+	// wall clock of whoever built the command. It is the visibility timeout
+	// of an OpQueuePop, the lease of an OpLock or OpRefreshLock, the minimum
+	// idle time of an OpStreamClaim, and the window of an OpRateLimit.
+	TTL time.Duration `json:"ttl,omitempty"`
+
+	// Values and Left are the elements of an OpListPush, and the end of the
+	// list an OpListPush or OpListPop works on. Values are also the items of
+	// OpBloomAdd and OpHLLAdd.
+	Values []string `json:"values,omitempty"`
+	Left   bool     `json:"left,omitempty"`
+
+	// Session and Token identify the holder of the lock an OpLock, OpUnlock
+	// or OpRefreshLock works on. Session is also the client session of the
+	// session ops, and the one an OpSet attaches an ephemeral key to.
+	Session string `json:"session,omitempty"`
+	Token   uint64 `json:"token,omitempty"`
+
+	// Fields, MaxLen, MinID, Group, Consumer and Count are the arguments of
+	// the stream ops.
+	Fields   map[string]string `json:"fields,omitempty"`
+	MaxLen   int64             `json:"max_len,omitempty"`
+	MinID    string            `json:"min_id,omitempty"`
+	Group    string            `json:"group,omitempty"`
+	Consumer string            `json:"consumer,omitempty"`
+	Count    int64             `json:"count,omitempty"`
+
+	// Capacity, Rate and Cost are the arguments of an OpRateLimit.
+	Capacity int64   `json:"capacity,omitempty"`
+	Rate     float64 `json:"rate,omitempty"`
+	Cost     int64   `json:"cost,omitempty"`
+
+	// Bits and Hashes are the geometry of the filter an OpBloomReserve or
+	// OpBloomAdd creates.
+	Bits   int64 `json:"bits,omitempty"`
+	Hashes int64 `json:"hashes,omitempty"`
+
+	// Offset, Bit and BitFields are the arguments of the bitmap ops.
+	Offset    int64             `json:"offset,omitempty"`
+	Bit       bool              `json:"bit,omitempty"`
+	BitFields []core.BitFieldOp `json:"bit_fields,omitempty"`
+
+	// Batch holds the commands of an OpBatch. They can't be batches
+	// themselves.
+	Batch []*RaftCommand `json:"batch,omitempty"`
+
+	// Node carries the member metadata for OpSetNodeMeta. OpDeleteNodeMeta
+	// only needs the node ID, which travels in Key.
+	Node *cluster.NodeMeta `json:"node,omitempty"`
+
+	// ShardMap is the map installed by OpSetShardMap.
+	ShardMap *sharding.Map `json:"shard_map,omitempty"`
+
+	// Slots are the hash slots an OpFreezeSlots, OpDropSlots or
+	// OpUnfreezeSlots applies to.
+	Slots []int `json:"slots,omitempty"`
+
+	// Entries are the keys, with their typed values and expirations, that
+	// an OpLoad copies in or an OpPut stores.
+	Entries map[string]types.ColumnValueWithTTL `json:"entries,omitempty"`
+
+	// Position is the primary's log index recorded by an OpStandbyApply.
+	Position uint64 `json:"position,omitempty"`
+
+	// Expired marks an OpBatchDelete issued by the TTL cleanup, or an
+	// OpCloseSession closing a session that ended, whose keys are reported as
+	// expired rather than deleted. Of the Keys of such an OpBatchDelete, only
+	// those still expired when it is applied are deleted.
+	Expired bool `json:"expired,omitempty"`
+}
+
+// SetValue returns the value an OpSet stores: RawValue when set, Value
+// otherwise.
+func (rc *RaftCommand) SetValue() string {
+	if len(rc.RawValue) > 0 {
+		return string(rc.RawValue)
+	}
+	return rc.Value
+}
+
+// RateLimitPolicy returns the rate limiter an OpRateLimit consumes from.
+func (rc *RaftCommand) RateLimitPolicy() core.RateLimitPolicy {
+	return core.RateLimitPolicy{Algorithm: rc.Value, Capacity: rc.Capacity, RefillRate: rc.Rate, Window: rc.TTL}
 }
 
 // Encode serializes a raft command mainly for raft.Apply()
@@ -2,65 +2,592 @@ package replication
 
 import (
 	"context"
-	"encoding/json"
+	"errors"
 	"fmt"
 	"io"
+	"maps"
+	"slices"
+	"sync"
+	"sync/atomic"
 
 	"github.com/hashicorp/raft"
+	"github.com/mateenbagheri/memorabilia/pkg/cluster"
 	"github.com/mateenbagheri/memorabilia/pkg/core"
+	"github.com/mateenbagheri/memorabilia/pkg/sharding"
 	"github.com/mateenbagheri/memorabilia/pkg/types"
 )
 
 type FSM struct {
 	repo core.CommandsRepository
+
+	// nodes holds the replicated metadata of every cluster member, keyed by
+	// node ID. It is not user data, so it lives beside the repository
+	// rather than in it, but it is snapshotted together with it.
+	nodesMu sync.RWMutex
+	nodes   map[string]cluster.NodeMeta
+
+	// clock is the replicated time, see ClusterClock. The repository decides
+	// expiry with it instead of the local wall clock.
+	clock *ClusterClock
+
+	// shardMap is the replicated shard map; nil until the first shard is
+	// added, and only ever set in the metadata group. frozen holds the slots
+	// this group refuses writes to while they move to another shard.
+	shardMu  sync.RWMutex
+	shardMap *sharding.Map
+	frozen   map[int]bool
+
+	// changes records the applied writes for Subscribe streams.
+	changes *ChangeLog
+
+	// standbyPosition is the primary's log index a standby cluster has
+	// applied up to, see OpStandbyApply.
+	standbyPosition atomic.Uint64
+
+	// index is the index of the log entry being applied, which the fencing
+	// tokens of the locks it acquires start from. Only Apply's goroutine
+	// uses it.
+	index uint64
+
+	// lockTokens holds the last fencing token given to each lock key, kept
+	// after the lock is released, see lockToken. Only Apply's goroutine
+	// uses it; Snapshot and Restore run on it too.
+	lockTokens map[string]uint64
 }
 
+// ErrSlotFrozen is returned for writes to a key whose slot is being moved to
+// another shard. They succeed once the move has completed, on the new owner.
+var ErrSlotFrozen = errors.New("slot is being moved to another shard")
+
 func NewFSM(repo core.CommandsRepository) *FSM {
-	return &FSM{repo: repo}
+	fsm := &FSM{
+		repo:    repo,
+		nodes:   make(map[string]cluster.NodeMeta),
+		clock:   &ClusterClock{},
+		frozen:  make(map[int]bool),
+		changes: NewChangeLog(),
+
+		lockTokens: make(map[string]uint64),
+	}
+	repo.SetClock(fsm.clock)
+	return fsm
 }
 
 func (fsm *FSM) Repository() core.CommandsRepository {
 	return fsm.repo
 }
 
+// SetKeyspaceNotifier makes the FSM report the changes its commands make to
+// keys to notify. The repository is only changed by Apply, and Restore, which
+// reports nothing, so every replica reports the same events in the same
+// order.
+func (fsm *FSM) SetKeyspaceNotifier(notify core.KeyspaceNotifier) {
+	fsm.repo.SetNotifier(notify)
+}
+
+// Changes returns the log of the writes applied to the state.
+func (fsm *FSM) Changes() *ChangeLog {
+	return fsm.changes
+}
+
+// Clock returns the replicated cluster clock.
+func (fsm *FSM) Clock() *ClusterClock {
+	return fsm.clock
+}
+
+// Apply applies a committed RaftCommand to the state. It returns an
+// ApplyResponse on success and an error otherwise; for an OpBatch it returns
+// a BatchResponse holding one of those per command. The commands that
+// succeeded are recorded in the change log, see Recorded.
 func (fsm *FSM) Apply(l *raft.Log) any {
+	fsm.index = l.Index
 	cmd, err := DecodeCommand(l.Data)
 	if err != nil {
+		fsm.changes.append(l.Index, l.Term, nil)
 		return fmt.Errorf("fsm apply: decode: %w", err)
 	}
 
 	ctx := context.Background()
 
+	if cmd.Op == OpBatch {
+		fsm.clock.observe(cmd.Now)
+		results := make(BatchResponse, len(cmd.Batch))
+		applied := make([]*RaftCommand, 0, len(cmd.Batch))
+		for i, sub := range cmd.Batch {
+			if sub.Op == OpBatch {
+				results[i] = fmt.Errorf("fsm apply: nested batch")
+				continue
+			}
+			results[i] = fsm.apply(ctx, sub)
+			if resp, ok := results[i].(ApplyResponse); ok {
+				if recorded := Recorded(fsm.repo, sub, resp); recorded != nil {
+					applied = append(applied, recorded)
+				}
+			}
+		}
+		fsm.changes.append(l.Index, l.Term, applied)
+		return results
+	}
+
+	result := fsm.apply(ctx, cmd)
+	var applied []*RaftCommand
+	if resp, ok := result.(ApplyResponse); ok {
+		if recorded := Recorded(fsm.repo, cmd, resp); recorded != nil {
+			applied = append(applied, recorded)
+		}
+	}
+	fsm.changes.append(l.Index, l.Term, applied)
+	return result
+}
+
+// apply applies a single, non-batch command.
+func (fsm *FSM) apply(ctx context.Context, cmd *RaftCommand) any {
+	// Advance the clock first, so the op itself already sees its own time.
+	fsm.clock.observe(cmd.Now)
+
+	if err := fsm.checkFrozen(cmd); err != nil {
+		return err
+	}
+
 	switch cmd.Op {
 	case OpSet:
-		return fsm.repo.Set(ctx, cmd.Key, cmd.Value, cmd.Expiration)
+		expiration := cmd.Expiration
+		if cmd.TTL > 0 {
+			expiration = cmd.Now.Add(cmd.TTL)
+		}
+		var previous string
+		var existed bool
+		var err error
+		if cmd.Session != "" {
+			previous, existed, err = fsm.repo.GetSetEphemeral(ctx, cmd.Key, cmd.SetValue(), expiration, cmd.Session)
+		} else {
+			previous, existed, err = fsm.repo.GetSet(ctx, cmd.Key, cmd.SetValue(), expiration)
+		}
+		if err != nil {
+			return fmt.Errorf("fsm apply: set: %w", err)
+		}
+		return ApplyResponse{Applied: true, Previous: previous, HadPrevious: existed}
 	case OpDelete:
 		count := fsm.repo.Delete(ctx, cmd.Key)
-		return count
+		return ApplyResponse{Applied: count > 0, DeleteCount: count}
 	case OpBatchDelete:
+		if cmd.Expired {
+			deleted := fsm.repo.Expire(ctx, cmd.Keys)
+			return ApplyResponse{Applied: len(deleted) > 0, DeleteCount: int64(len(deleted)), Keys: deleted}
+		}
 		count := fsm.repo.BatchDelete(ctx, cmd.Keys)
-		return count
+		return ApplyResponse{Applied: count > 0, DeleteCount: count}
+	case OpSetNodeMeta:
+		if cmd.Node == nil {
+			return fmt.Errorf("fsm apply: set node meta: missing node")
+		}
+		fsm.nodesMu.Lock()
+		fsm.nodes[cmd.Node.NodeID] = *cmd.Node
+		fsm.nodesMu.Unlock()
+		return ApplyResponse{Applied: true}
+	case OpDeleteNodeMeta:
+		fsm.nodesMu.Lock()
+		_, existed := fsm.nodes[cmd.Key]
+		delete(fsm.nodes, cmd.Key)
+		fsm.nodesMu.Unlock()
+		return ApplyResponse{Applied: existed}
+	case OpTick:
+		return ApplyResponse{Applied: true}
+	case OpSetShardMap:
+		return fsm.setShardMap(cmd.ShardMap)
+	case OpFreezeSlots:
+		fsm.setFrozen(cmd.Slots, true)
+		return ApplyResponse{Applied: true}
+	case OpUnfreezeSlots:
+		fsm.setFrozen(cmd.Slots, false)
+		return ApplyResponse{Applied: true}
+	case OpLoad:
+		if err := fsm.repo.Merge(cmd.Entries); err != nil {
+			return fmt.Errorf("fsm apply: load: %w", err)
+		}
+		fsm.observeLockTokens(cmd.Entries)
+		return ApplyResponse{Applied: len(cmd.Entries) > 0}
+	case OpStandbyApply:
+		for _, sub := range cmd.Batch {
+			switch sub.Op {
+			case OpSet, OpDelete, OpBatchDelete, OpPut:
+			default:
+				return fmt.Errorf("fsm apply: standby apply: unexpected op %d", sub.Op)
+			}
+			if err, failed := fsm.apply(ctx, sub).(error); failed {
+				return fmt.Errorf("fsm apply: standby apply: %w", err)
+			}
+		}
+		fsm.standbyPosition.Store(cmd.Position)
+		return ApplyResponse{Applied: true}
+	case OpPut:
+		for key, entry := range cmd.Entries {
+			fsm.repo.Put(key, entry)
+		}
+		fsm.observeLockTokens(cmd.Entries)
+		return ApplyResponse{Applied: len(cmd.Entries) > 0}
+	case OpListPush:
+		length, err := fsm.repo.Push(ctx, cmd.Key, cmd.Values, cmd.Left)
+		if err != nil {
+			return fmt.Errorf("fsm apply: list push: %w", err)
+		}
+		return ApplyResponse{Applied: true, Length: length}
+	case OpListPop:
+		key, value, ok, err := fsm.repo.Pop(ctx, cmd.Keys, cmd.Left)
+		if err != nil {
+			return fmt.Errorf("fsm apply: list pop: %w", err)
+		}
+		return ApplyResponse{Applied: ok, Key: key, Value: value}
+	case OpQueuePop:
+		job, ok, err := fsm.repo.QueuePop(ctx, cmd.Key, cmd.TTL)
+		if err != nil {
+			return fmt.Errorf("fsm apply: queue pop: %w", err)
+		}
+		return ApplyResponse{Applied: ok, Key: cmd.Key, Value: job.Value, Job: job}
+	case OpQueueAck:
+		acked, err := fsm.repo.QueueAck(ctx, cmd.Key, cmd.Value)
+		if err != nil {
+			return fmt.Errorf("fsm apply: queue ack: %w", err)
+		}
+		return ApplyResponse{Applied: acked}
+	case OpQueueNack:
+		requeued, err := fsm.repo.QueueNack(ctx, cmd.Key, cmd.Value)
+		if err != nil {
+			return fmt.Errorf("fsm apply: queue nack: %w", err)
+		}
+		return ApplyResponse{Applied: requeued}
+	case OpLock:
+		token := fsm.lockToken(cmd.Key)
+		holder, leaseEnd, acquired, err := fsm.repo.Lock(ctx, cmd.Key, cmd.Session, cmd.TTL, token)
+		if err != nil {
+			return fmt.Errorf("fsm apply: lock: %w", err)
+		}
+		if acquired && holder.Token == token {
+			fsm.lockTokens[cmd.Key] = token
+		}
+		return ApplyResponse{Applied: acquired, Lock: holder, LeaseEnd: leaseEnd}
+	case OpUnlock:
+		released, err := fsm.repo.Unlock(ctx, cmd.Key, cmd.Session, cmd.Token)
+		if err != nil {
+			return fmt.Errorf("fsm apply: unlock: %w", err)
+		}
+		return ApplyResponse{Applied: released}
+	case OpRefreshLock:
+		leaseEnd, refreshed, err := fsm.repo.RefreshLock(ctx, cmd.Key, cmd.Session, cmd.Token, cmd.TTL)
+		if err != nil {
+			return fmt.Errorf("fsm apply: refresh lock: %w", err)
+		}
+		return ApplyResponse{Applied: refreshed, LeaseEnd: leaseEnd}
+	case OpCreateSession:
+		deadline, err := fsm.repo.CreateSession(ctx, cmd.Session, cmd.TTL)
+		if err != nil {
+			return fmt.Errorf("fsm apply: create session: %w", err)
+		}
+		return ApplyResponse{Applied: true, LeaseEnd: deadline}
+	case OpKeepAlive:
+		deadline, err := fsm.repo.KeepAlive(ctx, cmd.Session)
+		if err != nil {
+			return fmt.Errorf("fsm apply: keepalive: %w", err)
+		}
+		return ApplyResponse{Applied: true, LeaseEnd: deadline}
+	case OpCloseSession:
+		closed, deleted := fsm.repo.CloseSession(ctx, cmd.Session, cmd.Keys, cmd.Expired)
+		return ApplyResponse{Applied: closed, DeleteCount: int64(len(deleted)), Keys: deleted}
+	case OpStreamAdd:
+		id, err := fsm.repo.XAdd(ctx, cmd.Key, cmd.Value, cmd.Fields, core.StreamTrim{MaxLen: cmd.MaxLen, MinID: cmd.MinID})
+		if err != nil {
+			return fmt.Errorf("fsm apply: stream add: %w", err)
+		}
+		return ApplyResponse{Applied: true, Value: id}
+	case OpStreamTrim:
+		trimmed, err := fsm.repo.XTrim(ctx, cmd.Key, core.StreamTrim{MaxLen: cmd.MaxLen, MinID: cmd.MinID})
+		if err != nil {
+			return fmt.Errorf("fsm apply: stream trim: %w", err)
+		}
+		return ApplyResponse{Applied: trimmed > 0, Count: trimmed}
+	case OpStreamCreateGroup:
+		created, err := fsm.repo.XGroupCreate(ctx, cmd.Key, cmd.Group, cmd.Value)
+		if err != nil {
+			return fmt.Errorf("fsm apply: stream create group: %w", err)
+		}
+		return ApplyResponse{Applied: created}
+	case OpStreamReadGroup:
+		entries, err := fsm.repo.XReadGroup(ctx, cmd.Key, cmd.Group, cmd.Consumer, cmd.Value, cmd.Count)
+		if err != nil {
+			return fmt.Errorf("fsm apply: stream read group: %w", err)
+		}
+		return ApplyResponse{Applied: len(entries) > 0, StreamEntries: entries}
+	case OpStreamAck:
+		acked, err := fsm.repo.XAck(ctx, cmd.Key, cmd.Group, cmd.Values)
+		if err != nil {
+			return fmt.Errorf("fsm apply: stream ack: %w", err)
+		}
+		return ApplyResponse{Applied: acked > 0, Count: acked}
+	case OpStreamClaim:
+		entries, err := fsm.repo.XClaim(ctx, cmd.Key, cmd.Group, cmd.Consumer, cmd.TTL, cmd.Values, cmd.Count)
+		if err != nil {
+			return fmt.Errorf("fsm apply: stream claim: %w", err)
+		}
+		return ApplyResponse{Applied: len(entries) > 0, StreamEntries: entries}
+	case OpRateLimit:
+		allowed, remaining, retryAfter, err := fsm.repo.RateLimit(ctx, cmd.Key, cmd.RateLimitPolicy(), cmd.Cost)
+		if err != nil {
+			return fmt.Errorf("fsm apply: rate limit: %w", err)
+		}
+		return ApplyResponse{Applied: allowed, Allowed: allowed, Count: remaining, RetryAfter: retryAfter}
+	case OpBloomReserve:
+		created, err := fsm.repo.BFReserve(ctx, cmd.Key, cmd.Bits, cmd.Hashes)
+		if err != nil {
+			return fmt.Errorf("fsm apply: bloom reserve: %w", err)
+		}
+		return ApplyResponse{Applied: created}
+	case OpBloomAdd:
+		added, err := fsm.repo.BFAdd(ctx, cmd.Key, cmd.Values, cmd.Bits, cmd.Hashes)
+		if err != nil {
+			return fmt.Errorf("fsm apply: bloom add: %w", err)
+		}
+		return ApplyResponse{Applied: slices.Contains(added, true), Flags: added}
+	case OpHLLAdd:
+		changed, err := fsm.repo.PFAdd(ctx, cmd.Key, cmd.Values)
+		if err != nil {
+			return fmt.Errorf("fsm apply: hyperloglog add: %w", err)
+		}
+		return ApplyResponse{Applied: changed}
+	case OpHLLMerge:
+		if err := fsm.repo.PFMerge(ctx, cmd.Key, cmd.Keys); err != nil {
+			return fmt.Errorf("fsm apply: hyperloglog merge: %w", err)
+		}
+		return ApplyResponse{Applied: true}
+	case OpSetBit:
+		previous, err := fsm.repo.SetBit(ctx, cmd.Key, cmd.Offset, cmd.Bit)
+		if err != nil {
+			return fmt.Errorf("fsm apply: set bit: %w", err)
+		}
+		return ApplyResponse{Applied: true, PreviousBit: previous}
+	case OpBitOp:
+		length, err := fsm.repo.BitOp(ctx, cmd.Value, cmd.Key, cmd.Keys)
+		if err != nil {
+			return fmt.Errorf("fsm apply: bit op: %w", err)
+		}
+		return ApplyResponse{Applied: true, Count: length}
+	case OpBitField:
+		results, err := fsm.repo.BitField(ctx, cmd.Key, cmd.BitFields)
+		if err != nil {
+			return fmt.Errorf("fsm apply: bitfield: %w", err)
+		}
+		return ApplyResponse{Applied: true, BitFields: results}
+	case OpDropSlots:
+		entries, err := fsm.SlotEntries(cmd.Slots)
+		if err != nil {
+			return fmt.Errorf("fsm apply: drop slots: %w", err)
+		}
+		keys := make([]string, 0, len(entries))
+		for key := range entries {
+			keys = append(keys, key)
+		}
+		// The keys live on in the shard they moved to.
+		count := fsm.repo.Evict(ctx, keys)
+		fsm.setFrozen(cmd.Slots, false)
+		return ApplyResponse{Applied: count > 0, DeleteCount: count}
 	default:
 		return fmt.Errorf("fsm apply: unknown op %d", cmd.Op)
 	}
 }
 
+// lockToken returns the fencing token of a lock acquired at key by the entry
+// being applied: its index, unless the key's last token is as high. That
+// happens when one entry acquires the lock more than once, as an OpBatch
+// can, and when the lock was last acquired in another shard's log, whose
+// indexes run ahead of this one's.
+func (fsm *FSM) lockToken(key string) uint64 {
+	return max(fsm.index, fsm.lockTokens[key]+1)
+}
+
+// observeLockTokens raises the last fencing tokens to those of the locks in
+// entries, copied in from elsewhere.
+func (fsm *FSM) observeLockTokens(entries map[string]types.ColumnValueWithTTL) {
+	for key, entry := range entries {
+		if lock, ok := entry.Column.(types.Lock); ok && lock.Token > fsm.lockTokens[key] {
+			fsm.lockTokens[key] = lock.Token
+		}
+	}
+}
+
+// checkFrozen rejects a data write touching a frozen slot.
+func (fsm *FSM) checkFrozen(cmd *RaftCommand) error {
+	var keys []string
+	switch cmd.Op {
+	case OpSet, OpDelete, OpPut, OpListPush, OpQueuePop, OpQueueAck, OpQueueNack, OpLock, OpUnlock, OpRefreshLock,
+		OpStreamAdd, OpStreamTrim, OpStreamCreateGroup, OpStreamReadGroup, OpStreamAck, OpStreamClaim, OpRateLimit,
+		OpBloomReserve, OpBloomAdd, OpHLLAdd, OpSetBit, OpBitField:
+		keys = []string{cmd.Key}
+	case OpHLLMerge, OpBitOp:
+		keys = append([]string{cmd.Key}, cmd.Keys...)
+	case OpBatchDelete, OpListPop:
+		keys = cmd.Keys
+	default:
+		return nil
+	}
+
+	fsm.shardMu.RLock()
+	defer fsm.shardMu.RUnlock()
+	if len(fsm.frozen) == 0 {
+		return nil
+	}
+	for _, key := range keys {
+		if fsm.frozen[sharding.SlotOf(key)] {
+			return fmt.Errorf("fsm apply: key %q: %w", key, ErrSlotFrozen)
+		}
+	}
+	return nil
+}
+
+func (fsm *FSM) setShardMap(m *sharding.Map) any {
+	if m == nil {
+		return fmt.Errorf("fsm apply: set shard map: missing map")
+	}
+	fsm.shardMu.Lock()
+	defer fsm.shardMu.Unlock()
+
+	current := uint64(0)
+	if fsm.shardMap != nil {
+		current = fsm.shardMap.Version
+	}
+	if m.Version != current+1 {
+		return fmt.Errorf("fsm apply: set shard map version %d on %d: %w", m.Version, current, sharding.ErrConflict)
+	}
+	fsm.shardMap = m
+	return ApplyResponse{Applied: true}
+}
+
+func (fsm *FSM) setFrozen(slots []int, frozen bool) {
+	fsm.shardMu.Lock()
+	defer fsm.shardMu.Unlock()
+	for _, slot := range slots {
+		if frozen {
+			fsm.frozen[slot] = true
+		} else {
+			delete(fsm.frozen, slot)
+		}
+	}
+}
+
+// ShardMap returns the replicated shard map, or nil when the cluster isn't
+// sharded. The map must not be modified.
+func (fsm *FSM) ShardMap() *sharding.Map {
+	fsm.shardMu.RLock()
+	defer fsm.shardMu.RUnlock()
+	return fsm.shardMap
+}
+
+// FrozenSlots returns the slots this group refuses writes to, sorted.
+func (fsm *FSM) FrozenSlots() []int {
+	fsm.shardMu.RLock()
+	defer fsm.shardMu.RUnlock()
+	slots := make([]int, 0, len(fsm.frozen))
+	for slot := range fsm.frozen {
+		slots = append(slots, slot)
+	}
+	slices.Sort(slots)
+	return slots
+}
+
+// SlotEntries returns every key in the given slots, with its value and
+// expiration. Expired keys that haven't been cleaned up yet are included.
+func (fsm *FSM) SlotEntries(slots []int) (map[string]types.ColumnValueWithTTL, error) {
+	want := make(map[int]bool, len(slots))
+	for _, slot := range slots {
+		want[slot] = true
+	}
+	data, err := fsm.repo.Dump()
+	if err != nil {
+		return nil, err
+	}
+	for key := range data {
+		if !want[sharding.SlotOf(key)] {
+			delete(data, key)
+		}
+	}
+	return data, nil
+}
+
+// StandbyPosition returns the primary's log index this cluster has applied up
+// to as a standby, or 0.
+func (fsm *FSM) StandbyPosition() uint64 {
+	return fsm.standbyPosition.Load()
+}
+
+// NodeMeta returns the replicated metadata of the given node, if known.
+func (fsm *FSM) NodeMeta(nodeID string) (cluster.NodeMeta, bool) {
+	fsm.nodesMu.RLock()
+	defer fsm.nodesMu.RUnlock()
+	meta, ok := fsm.nodes[nodeID]
+	return meta, ok
+}
+
+// Nodes returns a copy of the metadata of every known member.
+func (fsm *FSM) Nodes() map[string]cluster.NodeMeta {
+	fsm.nodesMu.RLock()
+	defer fsm.nodesMu.RUnlock()
+	return maps.Clone(fsm.nodes)
+}
+
 func (fsm *FSM) Snapshot() (raft.FSMSnapshot, error) {
 	data, err := fsm.repo.Dump()
 	if err != nil {
 		return nil, fmt.Errorf("fsm snapshot: %w", err)
 	}
 
-	return &fsmSnapshot{data: data}, nil
+	return &fsmSnapshot{state: snapshotState{
+		Format:   snapshotFormat,
+		Data:     data,
+		Sessions: fsm.repo.DumpSessions(),
+		Nodes:    fsm.Nodes(),
+		Clock:    fsm.clock.Now(),
+
+		ShardMap: fsm.ShardMap(),
+		Frozen:   fsm.FrozenSlots(),
+
+		StandbyPosition: fsm.StandbyPosition(),
+		LockTokens:      maps.Clone(fsm.lockTokens),
+	}}, nil
 }
 
 func (fsm *FSM) Restore(rc io.ReadCloser) error {
 	defer rc.Close()
 
-	var data map[string]types.ColumnValueWithTTL
-	if err := json.NewDecoder(rc).Decode(&data); err != nil {
-		return fmt.Errorf("fsm restore: decode: %w", err)
+	state, err := decodeSnapshot(rc)
+	if err != nil {
+		return fmt.Errorf("fsm restore: %w", err)
+	}
+
+	if err := fsm.repo.Load(state.Data); err != nil {
+		return fmt.Errorf("fsm restore: load: %w", err)
+	}
+	fsm.repo.LoadSessions(state.Sessions)
+
+	nodes := state.Nodes
+	if nodes == nil {
+		nodes = make(map[string]cluster.NodeMeta)
+	}
+	fsm.nodesMu.Lock()
+	fsm.nodes = nodes
+	fsm.nodesMu.Unlock()
+
+	frozen := make(map[int]bool, len(state.Frozen))
+	for _, slot := range state.Frozen {
+		frozen[slot] = true
 	}
+	fsm.shardMu.Lock()
+	fsm.shardMap = state.ShardMap
+	fsm.frozen = frozen
+	fsm.shardMu.Unlock()
 
-	return fsm.repo.Load(data)
+	fsm.standbyPosition.Store(state.StandbyPosition)
+	fsm.lockTokens = make(map[string]uint64, len(state.LockTokens))
+	maps.Copy(fsm.lockTokens, state.LockTokens)
+	fsm.observeLockTokens(state.Data)
+	fsm.clock.reset(state.Clock)
+	fsm.changes.reset()
+	return nil
 }
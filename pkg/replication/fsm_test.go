@@ -8,11 +8,14 @@ import (
 	"bytes"
 	"context"
 	"io"
+	"strings"
 	"testing"
 	"time"
 
 	"github.com/hashicorp/raft"
+	"github.com/mateenbagheri/memorabilia/pkg/cluster"
 	"github.com/mateenbagheri/memorabilia/pkg/core"
+	"github.com/mateenbagheri/memorabilia/pkg/types"
 	"github.com/stretchr/testify/assert"
 	"github.com/stretchr/testify/require"
 )
@@ -62,12 +65,74 @@ func TestFSM_Set_WithTTL_Expires(t *testing.T) {
 		Key:        "temp",
 		Value:      "42",
 		Expiration: time.Now().Add(-1 * time.Second), // already expired
+		Now:        time.Now(),                       // leader's stamp, as Node.Apply sets it
 	})
 
 	_, err := fsm.Repository().Get(ctx, "temp")
 	assert.ErrorIs(t, err, core.ErrKeyExpiredForGetOp)
 }
 
+func TestFSM_Expiry_FollowsReplicatedClock(t *testing.T) {
+	ctx := context.Background()
+
+	// Timestamps far from the local clock: expiry must not depend on it.
+	t0 := time.Date(2001, 1, 1, 0, 0, 0, 0, time.UTC)
+	log := []*RaftCommand{
+		{Op: OpSet, Key: "k", Value: "v", TTL: time.Second, Now: t0},
+		{Op: OpTick, Now: t0.Add(500 * time.Millisecond)},
+		{Op: OpTick, Now: t0.Add(2 * time.Second)},
+	}
+
+	// Two replicas that applied the same prefix of the log agree.
+	behind, ahead := newTestFSM(t), newTestFSM(t)
+	for _, cmd := range log[:2] {
+		applyCmd(t, behind, cmd)
+		applyCmd(t, ahead, cmd)
+	}
+	applyCmd(t, ahead, log[2])
+
+	val, err := behind.Repository().Get(ctx, "k")
+	require.NoError(t, err)
+	assert.Equal(t, "v", val)
+
+	ttl, err := behind.Repository().TTL(ctx, "k")
+	require.NoError(t, err)
+	assert.Equal(t, 500*time.Millisecond, ttl)
+
+	_, err = ahead.Repository().Get(ctx, "k")
+	assert.ErrorIs(t, err, core.ErrKeyExpiredForGetOp)
+
+	expired, err := ahead.Repository().GetExpiredKeys(ctx)
+	require.NoError(t, err)
+	assert.Equal(t, []string{"k"}, expired)
+}
+
+func TestFSM_Clock_NeverMovesBackwards(t *testing.T) {
+	fsm := newTestFSM(t)
+	t0 := time.Date(2001, 1, 1, 0, 0, 0, 0, time.UTC)
+
+	applyCmd(t, fsm, &RaftCommand{Op: OpTick, Now: t0})
+	applyCmd(t, fsm, &RaftCommand{Op: OpTick, Now: t0.Add(-time.Minute)}) // slow new leader
+	applyCmd(t, fsm, &RaftCommand{Op: OpTick})                            // unstamped
+
+	assert.True(t, fsm.Clock().Now().Equal(t0))
+}
+
+func TestFSM_Snapshot_RestoresClock(t *testing.T) {
+	fsm1 := newTestFSM(t)
+	t0 := time.Date(2001, 1, 1, 0, 0, 0, 0, time.UTC)
+	applyCmd(t, fsm1, &RaftCommand{Op: OpTick, Now: t0})
+
+	snap, err := fsm1.Snapshot()
+	require.NoError(t, err)
+	buf := &bytes.Buffer{}
+	require.NoError(t, snap.Persist(&testSnapshotSink{buf: buf}))
+
+	fsm2 := newTestFSM(t)
+	require.NoError(t, fsm2.Restore(io.NopCloser(buf)))
+	assert.True(t, fsm2.Clock().Now().Equal(t0))
+}
+
 func TestFSM_Delete(t *testing.T) {
 	fsm := newTestFSM(t)
 	ctx := context.Background()
@@ -91,9 +156,9 @@ func TestFSM_BatchDelete(t *testing.T) {
 		b, _ := (&RaftCommand{Op: OpBatchDelete, Keys: []string{"a", "c"}}).Encode()
 		return b
 	}()})
-	count, ok := result.(int64)
+	resp, ok := result.(ApplyResponse)
 	require.True(t, ok)
-	assert.Equal(t, int64(2), count)
+	assert.Equal(t, ApplyResponse{Applied: true, DeleteCount: 2}, resp)
 
 	_, err := fsm.Repository().Get(ctx, "a")
 	assert.Error(t, err)
@@ -102,6 +167,60 @@ func TestFSM_BatchDelete(t *testing.T) {
 	assert.Equal(t, "2", val)
 }
 
+func TestFSM_KeyspaceNotifier(t *testing.T) {
+	fsm := newTestFSM(t)
+	var events []core.KeyspaceEvent
+	fsm.SetKeyspaceNotifier(func(event core.KeyspaceEvent) { events = append(events, event) })
+
+	t0 := time.Date(2025, 1, 1, 0, 0, 0, 0, time.UTC)
+	applyCmd(t, fsm, &RaftCommand{Op: OpSet, Key: "a", Value: "1", Expiration: t0.Add(time.Second), Now: t0})
+	applyCmd(t, fsm, &RaftCommand{Op: OpSet, Key: "b", Value: "2"})
+	applyCmd(t, fsm, &RaftCommand{Op: OpBatchDelete, Keys: []string{"a"}, Expired: true, Now: t0.Add(2 * time.Second)})
+	applyCmd(t, fsm, &RaftCommand{Op: OpBatchDelete, Keys: []string{"b"}})
+
+	assert.Equal(t, []core.KeyspaceEvent{
+		{Type: core.EventSet, Key: "a"},
+		{Type: core.EventSet, Key: "b"},
+		{Type: core.EventExpired, Key: "a"},
+		{Type: core.EventDel, Key: "b"},
+	}, events)
+}
+
+func TestFSM_Apply_Responses(t *testing.T) {
+	fsm := newTestFSM(t)
+
+	apply := func(cmd *RaftCommand) ApplyResponse {
+		t.Helper()
+		b, err := cmd.Encode()
+		require.NoError(t, err)
+		resp, ok := fsm.Apply(&raft.Log{Data: b}).(ApplyResponse)
+		require.True(t, ok, "expected ApplyResponse")
+		return resp
+	}
+
+	assert.Equal(t, ApplyResponse{Applied: true},
+		apply(&RaftCommand{Op: OpSet, Key: "k", Value: "v1"}))
+	assert.Equal(t, ApplyResponse{Applied: true, Previous: "v1", HadPrevious: true},
+		apply(&RaftCommand{Op: OpSet, Key: "k", Value: "v2"}))
+
+	assert.Equal(t, ApplyResponse{Applied: true, DeleteCount: 1},
+		apply(&RaftCommand{Op: OpDelete, Key: "k"}))
+	assert.Equal(t, ApplyResponse{Applied: false, DeleteCount: 0},
+		apply(&RaftCommand{Op: OpDelete, Key: "k"}))
+	assert.Equal(t, ApplyResponse{Applied: false, DeleteCount: 0},
+		apply(&RaftCommand{Op: OpBatchDelete, Keys: []string{"x", "y"}}))
+
+	// A denied rate limit request changes nothing.
+	limit := &RaftCommand{Op: OpRateLimit, Key: "rl", Value: core.TokenBucket, Capacity: 1, Cost: 1, Rate: 0.001, Now: time.Now()}
+	resp := apply(limit)
+	assert.True(t, resp.Allowed)
+	assert.True(t, resp.Applied)
+	resp = apply(limit)
+	assert.False(t, resp.Allowed)
+	assert.False(t, resp.Applied)
+	assert.Positive(t, resp.RetryAfter)
+}
+
 func TestFSM_Snapshot_And_Restore(t *testing.T) {
 	fsm1 := newTestFSM(t)
 
@@ -128,6 +247,310 @@ func TestFSM_Snapshot_And_Restore(t *testing.T) {
 	}
 }
 
+func TestFSM_Queue_SurvivesRestore(t *testing.T) {
+	fsm1 := newTestFSM(t)
+	ctx := context.Background()
+	t0 := time.Date(2001, 1, 1, 0, 0, 0, 0, time.UTC)
+
+	apply := func(fsm *FSM, cmd *RaftCommand) ApplyResponse {
+		t.Helper()
+		b, err := cmd.Encode()
+		require.NoError(t, err)
+		resp, ok := fsm.Apply(&raft.Log{Data: b}).(ApplyResponse)
+		require.True(t, ok)
+		return resp
+	}
+	resp := apply(fsm1, &RaftCommand{Op: OpListPush, Key: "q", Values: []string{"a", "b"}, Now: t0})
+	assert.Equal(t, int64(2), resp.Length)
+	popped := apply(fsm1, &RaftCommand{Op: OpQueuePop, Key: "q", TTL: time.Minute, Now: t0})
+	require.True(t, popped.Applied)
+	assert.Equal(t, "a", popped.Value)
+	assert.True(t, popped.Job.Deadline.Equal(t0.Add(time.Minute)))
+
+	snap, err := fsm1.Snapshot()
+	require.NoError(t, err)
+	var buf bytes.Buffer
+	require.NoError(t, snap.Persist(&testSnapshotSink{buf: &buf}))
+	fsm2 := newTestFSM(t)
+	require.NoError(t, fsm2.Restore(io.NopCloser(&buf)))
+
+	// The job in flight survives: acked on the restored replica, it is gone.
+	assert.True(t, apply(fsm2, &RaftCommand{Op: OpQueueAck, Key: "q", Value: popped.Job.ID, Now: t0}).Applied)
+	resp = apply(fsm2, &RaftCommand{Op: OpListPop, Keys: []string{"q"}, Left: true, Now: t0})
+	assert.Equal(t, "b", resp.Value)
+	length, err := fsm2.Repository().Len(ctx, "q")
+	require.NoError(t, err)
+	assert.Zero(t, length)
+
+	// Elsewhere, the job goes back once its deadline passes on the cluster
+	// clock.
+	popped = apply(fsm1, &RaftCommand{Op: OpQueuePop, Key: "q", TTL: time.Minute, Now: t0.Add(2 * time.Minute)})
+	assert.Equal(t, "a", popped.Value)
+}
+
+func TestFSM_Stream_SurvivesRestore(t *testing.T) {
+	fsm1 := newTestFSM(t)
+	ctx := context.Background()
+	t0 := time.UnixMilli(1000)
+
+	apply := func(fsm *FSM, cmd *RaftCommand) ApplyResponse {
+		t.Helper()
+		b, err := cmd.Encode()
+		require.NoError(t, err)
+		resp, ok := fsm.Apply(&raft.Log{Data: b}).(ApplyResponse)
+		require.True(t, ok)
+		return resp
+	}
+	// Entry IDs come from the replicated clock, so every replica picks the
+	// same ones.
+	for _, want := range []string{"1000-0", "1000-1"} {
+		resp := apply(fsm1, &RaftCommand{Op: OpStreamAdd, Key: "s", Fields: map[string]string{"k": "v"}, Now: t0})
+		assert.Equal(t, want, resp.Value)
+	}
+	assert.True(t, apply(fsm1, &RaftCommand{Op: OpStreamCreateGroup, Key: "s", Group: "g", Value: "0", Now: t0}).Applied)
+	read := apply(fsm1, &RaftCommand{Op: OpStreamReadGroup, Key: "s", Group: "g", Consumer: "c", Value: ">", Count: 1, Now: t0})
+	require.Len(t, read.StreamEntries, 1)
+	assert.Equal(t, map[string]string{"k": "v"}, read.StreamEntries[0].Fields)
+
+	snap, err := fsm1.Snapshot()
+	require.NoError(t, err)
+	var buf bytes.Buffer
+	require.NoError(t, snap.Persist(&testSnapshotSink{buf: &buf}))
+	fsm2 := newTestFSM(t)
+	require.NoError(t, fsm2.Restore(io.NopCloser(&buf)))
+
+	// The group and its pending entry survive.
+	read = apply(fsm2, &RaftCommand{Op: OpStreamReadGroup, Key: "s", Group: "g", Consumer: "c", Value: ">", Now: t0})
+	require.Len(t, read.StreamEntries, 1)
+	assert.Equal(t, "1000-1", read.StreamEntries[0].ID.String())
+	claimed := apply(fsm2, &RaftCommand{Op: OpStreamClaim, Key: "s", Group: "g", Consumer: "d", TTL: time.Minute, Now: t0.Add(2 * time.Minute)})
+	assert.Len(t, claimed.StreamEntries, 2)
+	acked := apply(fsm2, &RaftCommand{Op: OpStreamAck, Key: "s", Group: "g", Values: []string{"1000-0", "1000-1"}, Now: t0})
+	assert.Equal(t, int64(2), acked.Count)
+	resp := apply(fsm2, &RaftCommand{Op: OpStreamAdd, Key: "s", Fields: map[string]string{"k": "v"}, MaxLen: 1, Now: t0})
+	assert.Equal(t, "121000-0", resp.Value) // the clock doesn't go back
+	length, err := fsm2.Repository().XLen(ctx, "s")
+	require.NoError(t, err)
+	assert.Equal(t, int64(1), length)
+}
+
+func TestFSM_Lock_FencingToken(t *testing.T) {
+	fsm1 := newTestFSM(t)
+	t0 := time.Date(2001, 1, 1, 0, 0, 0, 0, time.UTC)
+	apply := func(fsm *FSM, index uint64, cmd *RaftCommand) ApplyResponse {
+		t.Helper()
+		b, err := cmd.Encode()
+		require.NoError(t, err)
+		resp, ok := fsm.Apply(&raft.Log{Index: index, Data: b}).(ApplyResponse)
+		require.True(t, ok)
+		return resp
+	}
+
+	// The token is the index of the entry that acquired the lock.
+	resp := apply(fsm1, 7, &RaftCommand{Op: OpLock, Key: "l", Session: "a", TTL: time.Minute, Now: t0})
+	require.True(t, resp.Applied)
+	assert.Equal(t, uint64(7), resp.Lock.Token)
+	assert.True(t, resp.LeaseEnd.Equal(t0.Add(time.Minute)))
+	resp = apply(fsm1, 8, &RaftCommand{Op: OpLock, Key: "l", Session: "b", TTL: time.Minute, Now: t0})
+	assert.False(t, resp.Applied)
+	assert.Equal(t, types.Lock{Session: "a", Token: 7}, resp.Lock)
+
+	snap, err := fsm1.Snapshot()
+	require.NoError(t, err)
+	var buf bytes.Buffer
+	require.NoError(t, snap.Persist(&testSnapshotSink{buf: &buf}))
+	fsm2 := newTestFSM(t)
+	require.NoError(t, fsm2.Restore(io.NopCloser(&buf)))
+
+	// On the restored replica, the lock is released and acquired again, in
+	// one batch: the next holder's token is past the first one's.
+	b, err := (&RaftCommand{Op: OpBatch, Now: t0, Batch: []*RaftCommand{
+		{Op: OpUnlock, Key: "l", Session: "a", Token: 7, Now: t0},
+		{Op: OpLock, Key: "l", Session: "b", TTL: time.Minute, Now: t0},
+	}}).Encode()
+	require.NoError(t, err)
+	results := fsm2.Apply(&raft.Log{Index: 9, Data: b}).(BatchResponse)
+	require.Len(t, results, 2)
+	assert.True(t, results[0].(ApplyResponse).Applied)
+	assert.Equal(t, types.Lock{Session: "b", Token: 9}, results[1].(ApplyResponse).Lock)
+
+	// The lease runs out on the cluster clock.
+	resp = apply(fsm2, 10, &RaftCommand{Op: OpRefreshLock, Key: "l", Session: "b", Token: 9, TTL: time.Minute, Now: t0.Add(2 * time.Minute)})
+	assert.False(t, resp.Applied)
+}
+
+func TestFSM_Lock_FencingTokenNeverRepeats(t *testing.T) {
+	t0 := time.Date(2001, 1, 1, 0, 0, 0, 0, time.UTC)
+	apply := func(fsm *FSM, index uint64, cmd *RaftCommand) any {
+		t.Helper()
+		b, err := cmd.Encode()
+		require.NoError(t, err)
+		return fsm.Apply(&raft.Log{Index: index, Data: b})
+	}
+	lock := func(session string) *RaftCommand {
+		return &RaftCommand{Op: OpLock, Key: "l", Session: session, TTL: time.Minute, Now: t0}
+	}
+
+	// Every acquisition in one batch shares its index, but not its token.
+	fsm1 := newTestFSM(t)
+	results := apply(fsm1, 5, &RaftCommand{Op: OpBatch, Now: t0, Batch: []*RaftCommand{
+		lock("a"),
+		{Op: OpUnlock, Key: "l", Session: "a", Token: 5, Now: t0},
+		lock("b"),
+		{Op: OpUnlock, Key: "l", Session: "b", Token: 6, Now: t0},
+	}}).(BatchResponse)
+	require.Len(t, results, 4)
+	assert.Equal(t, types.Lock{Session: "a", Token: 5}, results[0].(ApplyResponse).Lock)
+	assert.Equal(t, types.Lock{Session: "b", Token: 6}, results[2].(ApplyResponse).Lock)
+	assert.True(t, results[3].(ApplyResponse).Applied)
+
+	// The last token outlives the lock, and a snapshot.
+	snap, err := fsm1.Snapshot()
+	require.NoError(t, err)
+	var buf bytes.Buffer
+	require.NoError(t, snap.Persist(&testSnapshotSink{buf: &buf}))
+	fsm2 := newTestFSM(t)
+	require.NoError(t, fsm2.Restore(io.NopCloser(&buf)))
+	resp := apply(fsm2, 6, lock("c")).(ApplyResponse)
+	assert.Equal(t, types.Lock{Session: "c", Token: 7}, resp.Lock)
+
+	// A lock moved in from a shard whose log is further along keeps its
+	// token, and the next holder's is higher, however far behind this
+	// shard's index is.
+	fsm3 := newTestFSM(t)
+	moved := types.ColumnValueWithTTL{Column: types.Lock{Session: "d", Token: 50}, Expiration: t0.Add(time.Minute)}
+	apply(fsm3, 2, &RaftCommand{Op: OpLoad, Entries: map[string]types.ColumnValueWithTTL{"l": moved}, Now: t0})
+	resp = apply(fsm3, 3, &RaftCommand{Op: OpUnlock, Key: "l", Session: "d", Token: 50, Now: t0}).(ApplyResponse)
+	require.True(t, resp.Applied)
+	resp = apply(fsm3, 4, lock("e")).(ApplyResponse)
+	assert.Equal(t, types.Lock{Session: "e", Token: 51}, resp.Lock)
+	resp = apply(fsm3, 60, &RaftCommand{Op: OpLock, Key: "other", Session: "e", TTL: time.Minute, Now: t0}).(ApplyResponse)
+	assert.Equal(t, uint64(60), resp.Lock.Token, "other keys still get the index")
+}
+
+func TestFSM_Session_SurvivesRestore(t *testing.T) {
+	fsm1 := newTestFSM(t)
+	t0 := time.Date(2001, 1, 1, 0, 0, 0, 0, time.UTC)
+	applyCmd(t, fsm1, &RaftCommand{Op: OpCreateSession, Session: "s", TTL: 10 * time.Second, Now: t0})
+	applyCmd(t, fsm1, &RaftCommand{Op: OpSet, Key: "svc/a", Value: "10.0.0.1", Session: "s", Now: t0})
+
+	b, err := (&RaftCommand{Op: OpSet, Key: "svc/b", Value: "10.0.0.2", Session: "nope", Now: t0}).Encode()
+	require.NoError(t, err)
+	err, _ = fsm1.Apply(&raft.Log{Index: 3, Data: b}).(error)
+	assert.ErrorIs(t, err, core.ErrSessionNotFound)
+
+	snap, err := fsm1.Snapshot()
+	require.NoError(t, err)
+	var buf bytes.Buffer
+	require.NoError(t, snap.Persist(&testSnapshotSink{buf: &buf}))
+	fsm2 := newTestFSM(t)
+	require.NoError(t, fsm2.Restore(io.NopCloser(&buf)))
+
+	// The session ends on the cluster clock, and closing it deletes its keys
+	// and shows up in the change log as their deletion.
+	repo := fsm2.Repository()
+	ctx := context.Background()
+	apply := func(index uint64, cmd *RaftCommand) {
+		t.Helper()
+		b, err := cmd.Encode()
+		require.NoError(t, err)
+		_, ok := fsm2.Apply(&raft.Log{Index: index, Data: b}).(ApplyResponse)
+		require.True(t, ok)
+	}
+	apply(4, &RaftCommand{Op: OpTick, Now: t0.Add(11 * time.Second)})
+	require.Equal(t, []string{"s"}, repo.ExpiredSessions(ctx))
+	keys := repo.SessionKeys(ctx, "s")
+	assert.Equal(t, []string{"svc/a"}, keys)
+	apply(5, &RaftCommand{Op: OpCloseSession, Session: "s", Keys: keys, Expired: true, Now: t0.Add(11 * time.Second)})
+	_, err = repo.Get(ctx, "svc/a")
+	assert.ErrorIs(t, err, core.ErrNotFoundForGetOp)
+	assert.Empty(t, repo.ExpiredSessions(ctx))
+
+	changes := nextChanges(t, fsm2.Changes().Subscribe(4, ""))
+	require.Len(t, changes, 1)
+	assert.Equal(t, OpBatchDelete, changes[0].Command.Op)
+	assert.Equal(t, []string{"svc/a"}, changes[0].Command.Keys)
+	assert.True(t, changes[0].Command.Expired)
+}
+
+func TestFSM_NodeMeta(t *testing.T) {
+	fsm := newTestFSM(t)
+
+	meta := cluster.NodeMeta{NodeID: "n2", RaftAddr: "10.0.0.2:7000", HTTPAddr: "10.0.0.2:8081"}
+	applyCmd(t, fsm, &RaftCommand{Op: OpSetNodeMeta, Node: &meta})
+
+	got, ok := fsm.NodeMeta("n2")
+	require.True(t, ok)
+	assert.Equal(t, meta, got)
+
+	applyCmd(t, fsm, &RaftCommand{Op: OpDeleteNodeMeta, Key: "n2"})
+	_, ok = fsm.NodeMeta("n2")
+	assert.False(t, ok)
+}
+
+func TestFSM_Snapshot_IncludesNodeMeta(t *testing.T) {
+	fsm1 := newTestFSM(t)
+	meta := cluster.NodeMeta{NodeID: "n1", RaftAddr: "10.0.0.1:7000", HTTPAddr: "10.0.0.1:8081"}
+	applyCmd(t, fsm1, &RaftCommand{Op: OpSetNodeMeta, Node: &meta})
+
+	snap, err := fsm1.Snapshot()
+	require.NoError(t, err)
+	var buf bytes.Buffer
+	require.NoError(t, snap.Persist(&testSnapshotSink{buf: &buf}))
+
+	fsm2 := newTestFSM(t)
+	require.NoError(t, fsm2.Restore(io.NopCloser(&buf)))
+
+	got, ok := fsm2.NodeMeta("n1")
+	require.True(t, ok)
+	assert.Equal(t, meta, got)
+}
+
+func TestFSM_StandbyApply(t *testing.T) {
+	fsm1 := newTestFSM(t)
+	ctx := context.Background()
+	applyCmd(t, fsm1, &RaftCommand{Op: OpStandbyApply, Position: 41, Batch: []*RaftCommand{
+		{Op: OpSet, Key: "a", Value: "1"},
+		{Op: OpSet, Key: "b", Value: "2"},
+		{Op: OpDelete, Key: "a"},
+	}})
+	applyCmd(t, fsm1, &RaftCommand{Op: OpStandbyApply, Position: 42})
+
+	_, err := fsm1.Repository().Get(ctx, "a")
+	assert.Error(t, err)
+	val, err := fsm1.Repository().Get(ctx, "b")
+	require.NoError(t, err)
+	assert.Equal(t, "2", val)
+	assert.Equal(t, uint64(42), fsm1.StandbyPosition())
+
+	snap, err := fsm1.Snapshot()
+	require.NoError(t, err)
+	var buf bytes.Buffer
+	require.NoError(t, snap.Persist(&testSnapshotSink{buf: &buf}))
+
+	fsm2 := newTestFSM(t)
+	require.NoError(t, fsm2.Restore(io.NopCloser(&buf)))
+	assert.Equal(t, uint64(42), fsm2.StandbyPosition())
+}
+
+func TestFSM_Restore_LegacySnapshot(t *testing.T) {
+	// Snapshots written before the envelope existed are a bare data map.
+	// A user key named "format" must not confuse the format probe.
+	legacy := `{"format":{"type":"string","value":{"Val":"x"},"expiration":"0001-01-01T00:00:00Z"},` +
+		`"k":{"type":"int","value":{"Val":7},"expiration":"0001-01-01T00:00:00Z"}}`
+
+	fsm := newTestFSM(t)
+	require.NoError(t, fsm.Restore(io.NopCloser(strings.NewReader(legacy))))
+
+	ctx := context.Background()
+	got, err := fsm.Repository().Get(ctx, "k")
+	require.NoError(t, err)
+	assert.Equal(t, "7", got)
+	got, err = fsm.Repository().Get(ctx, "format")
+	require.NoError(t, err)
+	assert.Equal(t, "x", got)
+}
+
 func TestFSM_Apply_UnknownOp_ReturnsError(t *testing.T) {
 	fsm := newTestFSM(t)
 	b, _ := (&RaftCommand{Op: OpType(99)}).Encode()
@@ -147,3 +570,87 @@ func (s *testSnapshotSink) ID() string                  { return "test-sink" }
 func (s *testSnapshotSink) Cancel() error               { return nil }
 
 var _ raft.SnapshotSink = (*testSnapshotSink)(nil)
+
+func TestFSM_Probabilistic_IdenticalAcrossReplicas(t *testing.T) {
+	ctx := context.Background()
+	apply := func(fsm *FSM, cmd *RaftCommand) ApplyResponse {
+		t.Helper()
+		b, err := cmd.Encode()
+		require.NoError(t, err)
+		resp, ok := fsm.Apply(&raft.Log{Data: b}).(ApplyResponse)
+		require.True(t, ok)
+		return resp
+	}
+	log := []*RaftCommand{
+		{Op: OpBloomReserve, Key: "bf", Bits: 1024, Hashes: 4},
+		{Op: OpBloomAdd, Key: "bf", Values: []string{"a", "b"}},
+		{Op: OpHLLAdd, Key: "h1", Values: []string{"a", "b", "c"}},
+		{Op: OpHLLAdd, Key: "h2", Values: []string{"c", "d"}},
+		{Op: OpHLLMerge, Key: "h1", Keys: []string{"h2"}},
+	}
+	fsm1, fsm2 := newTestFSM(t), newTestFSM(t)
+	for _, cmd := range log {
+		apply(fsm1, cmd)
+		apply(fsm2, cmd)
+	}
+	added := apply(fsm1, &RaftCommand{Op: OpBloomAdd, Key: "bf", Values: []string{"a", "c"}})
+	assert.Equal(t, []bool{false, true}, added.Flags)
+
+	// Replicas applying the same log hold the same bytes, and so do the
+	// snapshots they take.
+	persist := func(fsm *FSM) []byte {
+		t.Helper()
+		snap, err := fsm.Snapshot()
+		require.NoError(t, err)
+		var buf bytes.Buffer
+		require.NoError(t, snap.Persist(&testSnapshotSink{buf: &buf}))
+		return buf.Bytes()
+	}
+	apply(fsm2, &RaftCommand{Op: OpBloomAdd, Key: "bf", Values: []string{"a", "c"}})
+	snapshot := persist(fsm1)
+	assert.Equal(t, snapshot, persist(fsm2))
+
+	fsm3 := newTestFSM(t)
+	require.NoError(t, fsm3.Restore(io.NopCloser(bytes.NewReader(snapshot))))
+	exists, err := fsm3.Repository().BFExists(ctx, "bf", []string{"a", "b", "c", "d"})
+	require.NoError(t, err)
+	assert.Equal(t, []bool{true, true, true, false}, exists)
+	count, err := fsm3.Repository().PFCount(ctx, []string{"h1"})
+	require.NoError(t, err)
+	assert.Equal(t, int64(4), count)
+}
+
+func TestFSM_Bitmap_SurvivesRestore(t *testing.T) {
+	fsm1 := newTestFSM(t)
+	ctx := context.Background()
+	apply := func(fsm *FSM, cmd *RaftCommand) ApplyResponse {
+		t.Helper()
+		b, err := cmd.Encode()
+		require.NoError(t, err)
+		resp, ok := fsm.Apply(&raft.Log{Data: b}).(ApplyResponse)
+		require.True(t, ok)
+		return resp
+	}
+	assert.False(t, apply(fsm1, &RaftCommand{Op: OpSetBit, Key: "a", Offset: 0, Bit: true}).PreviousBit)
+	assert.True(t, apply(fsm1, &RaftCommand{Op: OpSetBit, Key: "a", Offset: 0, Bit: true}).PreviousBit)
+	fields := apply(fsm1, &RaftCommand{Op: OpBitField, Key: "b", BitFields: []core.BitFieldOp{
+		{Op: core.BitFieldSet, Width: 16, Value: 0xfffe},
+		{Op: core.BitFieldIncrBy, Width: 16, Value: 2},
+	}})
+	assert.Equal(t, []core.BitFieldResult{{Value: 0}, {Value: 0}}, fields.BitFields)
+	resp := apply(fsm1, &RaftCommand{Op: OpBitOp, Key: "c", Value: core.BitNot, Keys: []string{"a"}})
+	assert.Equal(t, int64(1), resp.Count)
+
+	// Bytes that aren't valid UTF-8 come back from a snapshot unchanged.
+	snap, err := fsm1.Snapshot()
+	require.NoError(t, err)
+	var buf bytes.Buffer
+	require.NoError(t, snap.Persist(&testSnapshotSink{buf: &buf}))
+	fsm2 := newTestFSM(t)
+	require.NoError(t, fsm2.Restore(io.NopCloser(&buf)))
+	for key, want := range map[string]string{"a": "\x80", "b": "\x00\x00", "c": "\x7f"} {
+		value, err := fsm2.Repository().Get(ctx, key)
+		require.NoError(t, err)
+		assert.Equal(t, want, value, key)
+	}
+}
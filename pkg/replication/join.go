@@ -0,0 +1,185 @@
+package replication
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"log/slog"
+	"net/http"
+	"time"
+
+	"github.com/hashicorp/raft"
+	"github.com/mateenbagheri/memorabilia/pkg/cluster"
+)
+
+const (
+	joinInitialBackoff = 500 * time.Millisecond
+	joinMaxBackoff     = 10 * time.Second
+
+	// joinMaxRedirects bounds how many 421 redirects a single join attempt
+	// follows, in case nodes disagree about who leads during an election.
+	joinMaxRedirects = 3
+)
+
+type JoinRequest struct {
+	NodeID   string `json:"node_id"`
+	RaftAddr string `json:"raft_addr"`
+
+	// HTTPAddr is the joining node's dialable management address. Optional,
+	// but without it requests can't be forwarded to the node once it leads.
+	HTTPAddr string `json:"http_addr,omitempty"`
+
+	// GRPCAddr is the joining node's dialable gRPC address, used as the
+	// leader hint in errors once the node leads. Optional.
+	GRPCAddr string `json:"grpc_addr,omitempty"`
+
+	// NonVoter joins the node as a read replica instead of a voter.
+	NonVoter bool `json:"non_voter,omitempty"`
+}
+
+// Join adds nodeID/raftAddr as a new voting member. Impo: Must be called on the leader.
+//
+// Joining is idempotent, see reconcileMember.
+func (n *Node) Join(nodeID, raftAddr string) error {
+	return n.joinMember(nodeID, raftAddr, raft.Voter)
+}
+
+// JoinNonvoter adds nodeID/raftAddr as a non-voting member. Impo: Must be called
+// on the leader.
+func (n *Node) JoinNonvoter(nodeID, raftAddr string) error {
+	return n.joinMember(nodeID, raftAddr, raft.Nonvoter)
+}
+
+func (n *Node) joinMember(nodeID, raftAddr string, suffrage raft.ServerSuffrage) error {
+	done, err := n.reconcileMember(nodeID, raftAddr, suffrage)
+	if err != nil {
+		return fmt.Errorf("node join: %w", err)
+	}
+	if done {
+		n.logger.Info("node already a member with the same address",
+			slog.String("nodeID", nodeID), slog.String("raftAddr", raftAddr))
+		return nil
+	}
+
+	n.logger.Info("adding member",
+		slog.String("nodeID", nodeID),
+		slog.String("raftAddr", raftAddr),
+		slog.String("role", cluster.RoleOf(suffrage)),
+	)
+	m := cluster.NewMembership(n)
+	if suffrage == raft.Nonvoter {
+		err = m.AddNonvoter(nodeID, raftAddr)
+	} else {
+		err = n.raft.AddVoter(raft.ServerID(nodeID), raft.ServerAddress(raftAddr), 0, 0).Error()
+	}
+	if err != nil {
+		return fmt.Errorf("node join %q: %w", nodeID, err)
+	}
+	return nil
+}
+
+// reconcileMember compares a join request with the current configuration so
+// that nodes can simply re-join on every start:
+//
+//   - same ID, same address, same role: nothing to do (done is true)
+//   - same ID, different address: the node restarted somewhere else (e.g. a
+//     container that came back with a new IP). The stale entry is removed so
+//     the node can be re-added under its new address.
+//   - different ID, same address: a previous incarnation left an entry
+//     behind under another name. It is removed so the two don't fight over
+//     one address.
+//
+// A role change on an unchanged address is applied in place: a nonvoter that
+// re-joins as a voter is promoted by the caller's AddVoter, a voter that
+// re-joins as a nonvoter is demoted here (AddNonvoter won't do it).
+func (n *Node) reconcileMember(nodeID, raftAddr string, suffrage raft.ServerSuffrage) (done bool, err error) {
+	servers, err := cluster.NewMembership(n).Servers()
+	if err != nil {
+		return false, err
+	}
+
+	for _, srv := range servers {
+		sameID := srv.ID == raft.ServerID(nodeID)
+		sameAddr := srv.Address == raft.ServerAddress(raftAddr)
+		switch {
+		case sameID && sameAddr:
+			if srv.Suffrage == suffrage {
+				return true, nil
+			}
+			if suffrage == raft.Nonvoter {
+				return true, cluster.NewMembership(n).Demote(nodeID)
+			}
+		case sameID || sameAddr:
+			n.logger.Warn("removing stale cluster member before re-join",
+				slog.String("staleID", string(srv.ID)),
+				slog.String("staleAddr", string(srv.Address)),
+				slog.String("nodeID", nodeID),
+				slog.String("raftAddr", raftAddr),
+			)
+			f := n.raft.RemoveServer(srv.ID, 0, 0)
+			if err := f.Error(); err != nil {
+				return false, fmt.Errorf("remove stale member %q: %w", srv.ID, err)
+			}
+		}
+	}
+	return false, nil
+}
+
+// JoinCluster registers this node with the cluster through any of the given
+// seed management addresses, retrying until it succeeds or ctx expires.
+//
+// Each attempt walks the seeds in order. A seed that isn't the leader answers
+// 421 with the leader's management address, which is followed straight away.
+// Seeds that are unreachable, still electing (503) or otherwise failing are
+// skipped; when every seed failed the whole round is retried with
+// exponential backoff.
+func (n *Node) JoinCluster(ctx context.Context, seeds []string, join JoinRequest) error {
+	return joinCluster(ctx, seeds, join, n.logger)
+}
+
+func joinCluster(ctx context.Context, seeds []string, join JoinRequest, logger *slog.Logger) error {
+	if len(seeds) == 0 {
+		return errors.New("node join cluster: no seed addresses")
+	}
+
+	backoff := joinInitialBackoff
+	for attempt := 1; ; attempt++ {
+		var lastErr error
+		for _, seed := range seeds {
+			err := joinVia(ctx, seed, join)
+			if err == nil {
+				return nil
+			}
+			lastErr = err
+			logger.Warn("join attempt failed",
+				slog.Int("attempt", attempt),
+				slog.String("seed", seed),
+				slog.String("error", err.Error()),
+			)
+		}
+
+		select {
+		case <-ctx.Done():
+			return fmt.Errorf("node join cluster: giving up after %d attempts: %w", attempt, lastErr)
+		case <-time.After(backoff):
+		}
+		backoff = min(backoff*2, joinMaxBackoff)
+	}
+}
+
+// joinVia sends join to seed, following 421 redirects towards the leader.
+func joinVia(ctx context.Context, seed string, join JoinRequest) error {
+	addr := seed
+	for redirects := 0; ; redirects++ {
+		err := postJSON(ctx, addr, "/raft/join", join, "")
+
+		var statusErr *StatusError
+		if !errors.As(err, &statusErr) ||
+			statusErr.StatusCode != http.StatusMisdirectedRequest ||
+			statusErr.LeaderHTTP == "" ||
+			redirects == joinMaxRedirects {
+			return err
+		}
+		addr = statusErr.LeaderHTTP
+	}
+}
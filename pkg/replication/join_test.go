@@ -0,0 +1,114 @@
+package replication
+
+import (
+	"context"
+	"encoding/json"
+	"io"
+	"log/slog"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func discardLogger() *slog.Logger {
+	return slog.New(slog.NewTextHandler(io.Discard, nil))
+}
+
+func addrOf(srv *httptest.Server) string {
+	return strings.TrimPrefix(srv.URL, "http://")
+}
+
+func TestJoinCluster_FollowsLeaderRedirect(t *testing.T) {
+	var got JoinRequest
+	leader := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		require.NoError(t, json.NewDecoder(r.Body).Decode(&got))
+		w.WriteHeader(http.StatusOK)
+	}))
+	defer leader.Close()
+
+	follower := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set(LeaderHTTPHeader, addrOf(leader))
+		http.Error(w, "not the leader", http.StatusMisdirectedRequest)
+	}))
+	defer follower.Close()
+
+	join := JoinRequest{NodeID: "n3", RaftAddr: "10.0.0.3:7000", HTTPAddr: "10.0.0.3:8081"}
+	err := joinCluster(context.Background(), []string{addrOf(follower)}, join, discardLogger())
+	require.NoError(t, err)
+	assert.Equal(t, join, got)
+}
+
+func TestJoinCluster_SkipsUnreachableSeed(t *testing.T) {
+	dead := httptest.NewServer(http.NotFoundHandler())
+	deadAddr := addrOf(dead)
+	dead.Close()
+
+	var calls atomic.Int32
+	live := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		calls.Add(1)
+		w.WriteHeader(http.StatusOK)
+	}))
+	defer live.Close()
+
+	err := joinCluster(context.Background(), []string{deadAddr, addrOf(live)}, JoinRequest{NodeID: "n2"}, discardLogger())
+	require.NoError(t, err)
+	assert.Equal(t, int32(1), calls.Load())
+}
+
+func TestJoinCluster_RetriesUntilLeaderElected(t *testing.T) {
+	var calls atomic.Int32
+	seed := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if calls.Add(1) < 3 {
+			http.Error(w, "no leader elected yet", http.StatusServiceUnavailable)
+			return
+		}
+		w.WriteHeader(http.StatusOK)
+	}))
+	defer seed.Close()
+
+	err := joinCluster(context.Background(), []string{addrOf(seed)}, JoinRequest{NodeID: "n2"}, discardLogger())
+	require.NoError(t, err)
+	assert.Equal(t, int32(3), calls.Load())
+}
+
+func TestJoinCluster_GivesUpWhenContextExpires(t *testing.T) {
+	seed := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		http.Error(w, "no leader elected yet", http.StatusServiceUnavailable)
+	}))
+	defer seed.Close()
+
+	ctx, cancel := context.WithTimeout(context.Background(), 100*time.Millisecond)
+	defer cancel()
+
+	err := joinCluster(ctx, []string{addrOf(seed)}, JoinRequest{NodeID: "n2"}, discardLogger())
+	require.Error(t, err)
+
+	var statusErr *StatusError
+	require.ErrorAs(t, err, &statusErr)
+	assert.Equal(t, http.StatusServiceUnavailable, statusErr.StatusCode)
+}
+
+func TestJoinCluster_StopsFollowingRedirectLoops(t *testing.T) {
+	var calls atomic.Int32
+	var self string
+	loop := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		calls.Add(1)
+		w.Header().Set(LeaderHTTPHeader, self)
+		http.Error(w, "not the leader", http.StatusMisdirectedRequest)
+	}))
+	defer loop.Close()
+	self = addrOf(loop)
+
+	ctx, cancel := context.WithTimeout(context.Background(), 100*time.Millisecond)
+	defer cancel()
+
+	err := joinCluster(ctx, []string{self}, JoinRequest{NodeID: "n2"}, discardLogger())
+	require.Error(t, err)
+	assert.Equal(t, int32(joinMaxRedirects+1), calls.Load())
+}
@@ -4,66 +4,76 @@ import (
 	"bytes"
 	"context"
 	"encoding/json"
+	"errors"
 	"fmt"
+	"io"
 	"log/slog"
 	"net/http"
-	"os"
-	"path/filepath"
+	"sync"
+	"sync/atomic"
 	"time"
 
 	"github.com/hashicorp/raft"
-	raftboltdb "github.com/hashicorp/raft-boltdb"
 	"github.com/mateenbagheri/memorabilia/pkg/cluster"
 )
 
-const (
-	applyTimeout   = 5 * time.Second
-	snapshotRetain = 3
-)
+// ForwardedHeader marks a management request that has already been forwarded
+// once, so a stale leader view on two nodes can't bounce it back and forth.
+const ForwardedHeader = "X-Memorabilia-Forwarded"
+
+// LeaderHTTPHeader carries the leader's management address on 421 Misdirected
+// Request responses, so callers can retry against the leader directly.
+const LeaderHTTPHeader = "X-Memorabilia-Leader-HTTP"
+
+// ErrLeaderUnknown is returned when a request has to reach the leader but
+// there is no leader, or its management address hasn't been replicated yet.
+var ErrLeaderUnknown = errors.New("leader management address unknown")
 
 type Node struct {
 	raft      *raft.Raft
 	transport *raft.NetworkTransport
+	snapshots raft.SnapshotStore
+	fsm       *FSM
 	cfg       *cluster.Config
 	logger    *slog.Logger
-}
 
-// NewNode simply creates, configures, and starts a Raft node.
-func NewNode(cfg *cluster.Config, fsm *FSM, logger *slog.Logger) (*Node, error) {
-	if err := os.MkdirAll(cfg.DataDir, 0o755); err != nil {
-		return nil, fmt.Errorf("node: mkdir %q: %w", cfg.DataDir, err)
-	}
+	shutdownCh   chan struct{}
+	shutdownOnce sync.Once
+	shutdownErr  error
 
-	transport, err := NewTCPTransport(cfg.RaftBindAddr, cfg.AdvertiseAddr)
-	if err != nil {
-		return nil, err
-	}
+	// applyTimeout bounds how long Apply waits to enqueue a command.
+	applyTimeout time.Duration
 
-	logStore, err := raftboltdb.NewBoltStore(filepath.Join(cfg.DataDir, "raft-log.db"))
-	if err != nil {
-		return nil, err
-	}
+	// batcher coalesces concurrent Apply calls; nil when batching is off.
+	batcher *batcher
 
-	stableStore, err := raftboltdb.NewBoltStore(filepath.Join(cfg.DataDir, "raft-stable.db"))
+	// lastStamp is when this node last stamped a command, in local Unix
+	// nanoseconds. The clock ticker skips its tick while writes keep coming.
+	lastStamp atomic.Int64
+}
+
+// NewNode simply creates, configures, and starts a Raft node.
+func NewNode(cfg *cluster.Config, fsm *FSM, logger *slog.Logger) (*Node, error) {
+	stores, err := newRaftStores(cfg)
 	if err != nil {
 		return nil, err
 	}
 
-	snapshotStore, err := raft.NewFileSnapshotStore(cfg.DataDir, snapshotRetain, os.Stderr)
+	transport, err := NewTCPTransport(cfg.RaftBindAddr, cfg.AdvertiseAddr)
 	if err != nil {
 		return nil, err
 	}
 
-	raftCfg := raft.DefaultConfig()
-	raftCfg.LocalID = raft.ServerID(cfg.NodeID)
+	raftCfg := raftConfig(cfg)
+	fsm.changes.setLog(stores.log)
 
-	r, err := raft.NewRaft(raftCfg, fsm, logStore, stableStore, snapshotStore, transport)
+	r, err := raft.NewRaft(raftCfg, fsm, stores.log, stores.stable, stores.snapshot, transport)
 	if err != nil {
 		return nil, fmt.Errorf("node: new raft: %w", err)
 	}
 
 	if cfg.Bootstrap {
-		hasState, err := raft.HasExistingState(logStore, stableStore, snapshotStore)
+		hasState, err := raft.HasExistingState(stores.log, stores.stable, stores.snapshot)
 		if err != nil {
 			return nil, fmt.Errorf("node: check existing state: %w", err)
 		}
@@ -73,9 +83,21 @@ func NewNode(cfg *cluster.Config, fsm *FSM, logger *slog.Logger) (*Node, error)
 					{ID: raftCfg.LocalID, Address: transport.LocalAddr()},
 				},
 			}
+			if len(cfg.BootstrapPeers) > 0 {
+				bootCfg.Servers = bootCfg.Servers[:0]
+				for id, addr := range cfg.BootstrapPeers {
+					bootCfg.Servers = append(bootCfg.Servers, raft.Server{
+						ID:      raft.ServerID(id),
+						Address: raft.ServerAddress(addr),
+					})
+				}
+			}
 			if f := r.BootstrapCluster(bootCfg); f.Error() != nil {
 				return nil, fmt.Errorf("node: bootstrap: %w", f.Error())
 			}
+			if err := markBootstrapped(cfg); err != nil {
+				return nil, err
+			}
 			logger.Info("bootstrapped new Raft cluster",
 				slog.String("nodeID", cfg.NodeID),
 				slog.String("raftAddr", cfg.RaftBindAddr),
@@ -83,7 +105,94 @@ func NewNode(cfg *cluster.Config, fsm *FSM, logger *slog.Logger) (*Node, error)
 		}
 	}
 
-	return &Node{raft: r, transport: transport, cfg: cfg, logger: logger}, nil
+	n := &Node{
+		raft:         r,
+		transport:    transport,
+		snapshots:    stores.snapshot,
+		fsm:          fsm,
+		cfg:          cfg,
+		logger:       logger,
+		shutdownCh:   make(chan struct{}),
+		applyTimeout: applyTimeout(cfg),
+	}
+	if size := applyBatchSize(cfg); size > 1 {
+		n.batcher = newBatcher(r, n.stamp, size, cfg.ApplyBatchLinger, n.applyTimeout, n.shutdownCh)
+		go n.batcher.run()
+	}
+	go n.monitorLeadership()
+	go n.runClock()
+
+	return n, nil
+}
+
+// monitorLeadership reacts to this node gaining leadership. Every new leader
+// (re-)registers its own metadata, which covers the bootstrap node — it never
+// goes through /raft/join — and any address change across restarts, and
+// forgets the nodes removed from the cluster.
+func (n *Node) monitorLeadership() {
+	for {
+		select {
+		case isLeader := <-n.raft.LeaderCh():
+			if !isLeader {
+				continue
+			}
+			if err := n.forgetRemoved(); err != nil {
+				n.logger.Error("failed to forget removed nodes", slog.String("error", err.Error()))
+			}
+			meta := n.cfg.Meta()
+			if known, ok := n.fsm.NodeMeta(meta.NodeID); ok && known == meta {
+				continue
+			}
+			if err := n.RegisterMeta(meta); err != nil {
+				n.logger.Error("failed to register own node metadata", slog.String("error", err.Error()))
+			}
+		case <-n.shutdownCh:
+			return
+		}
+	}
+}
+
+// runClock keeps the cluster clock moving while this node leads: on every
+// tick interval without a write in between, it replicates an OpTick.
+// Without it an idle cluster's clock would stand still and nothing would
+// expire. It doesn't tick while nothing has a deadline: a clock nobody
+// waits on may as well stand still until the next write moves it.
+func (n *Node) runClock() {
+	interval := clockTickInterval(n.cfg)
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+	for {
+		select {
+		case <-ticker.C:
+			// Half the interval, so a write just after the previous tick
+			// doesn't make this one skip and the clock go 2 intervals stale.
+			sinceStamp := time.Since(time.Unix(0, n.lastStamp.Load()))
+			if !n.IsLeader() || sinceStamp < interval/2 || !n.fsm.Repository().HasDeadlines() {
+				continue
+			}
+			if _, err := n.Apply(&RaftCommand{Op: OpTick}); err != nil {
+				n.logger.Debug("clock tick failed", slog.String("error", err.Error()))
+			}
+		case <-n.shutdownCh:
+			return
+		}
+	}
+}
+
+// stamp returns the time to stamp on a new command: the local wall clock,
+// unless that is behind the cluster clock (e.g. a new leader whose clock is
+// slow), in which case the cluster clock is reused so time doesn't stall.
+func (n *Node) stamp() time.Time {
+	now := time.Now()
+	n.lastStamp.Store(now.UnixNano())
+	if clock := n.fsm.Clock().Now(); now.Before(clock) {
+		return clock
+	}
+	return now
+}
+
+func (n *Node) NodeID() string {
+	return n.cfg.NodeID
 }
 
 func (n *Node) IsLeader() bool {
@@ -99,74 +208,285 @@ func (n *Node) Raft() *raft.Raft {
 	return n.raft
 }
 
-func (n *Node) Apply(cmd *RaftCommand) error {
+// Apply replicates cmd through Raft and returns the FSM's response once it
+// has been applied on this node. Must be called on the leader.
+//
+// With batching on, concurrent calls are coalesced into one log entry (see
+// batcher); each caller still gets its own command's response.
+func (n *Node) Apply(cmd *RaftCommand) (ApplyResponse, error) {
+	if cmd.Now.IsZero() {
+		cmd.Now = n.stamp()
+	}
+	if n.batcher != nil {
+		return n.batcher.submit(cmd)
+	}
+
 	b, err := cmd.Encode()
 	if err != nil {
-		return fmt.Errorf("node apply: encode: %w", err)
+		return ApplyResponse{}, fmt.Errorf("node apply: encode: %w", err)
 	}
 
-	f := n.raft.Apply(b, applyTimeout)
+	f := n.raft.Apply(b, n.applyTimeout)
 	if err := f.Error(); err != nil {
-		return fmt.Errorf("node apply: raft: %w", err)
+		return ApplyResponse{}, fmt.Errorf("node apply: raft: %w", err)
 	}
+	return toApplyResult(f.Response())
+}
 
-	// TODO: check if this can be bettered
-	if resp, ok := f.Response().(error); ok && resp != nil {
-		return fmt.Errorf("node apply: fsm: %w", resp)
+// applyBatchSize returns the configured batch size, defaulting to
+// DefaultApplyBatchSize. A size of 1 turns batching off.
+func applyBatchSize(cfg *cluster.Config) int {
+	if cfg.ApplyBatchSize <= 0 {
+		return DefaultApplyBatchSize
 	}
+	return cfg.ApplyBatchSize
+}
+
+// MemberRequest is the body of /raft/promote and /raft/demote.
+type MemberRequest struct {
+	NodeID string `json:"node_id"`
+}
+
+// RemoveRequest is the body of /raft/remove.
+type RemoveRequest struct {
+	NodeID string `json:"node_id"`
+}
 
+// TransferLeadershipRequest is the body of /raft/transfer-leadership. An
+// empty NodeID lets Raft pick the most up-to-date follower.
+type TransferLeadershipRequest struct {
+	NodeID string `json:"node_id,omitempty"`
+}
+
+// Staleness estimates how far behind the leader this node's state may be:
+// zero on the leader, otherwise the time since the leader was last heard
+// from. Followers apply entries as soon as a heartbeat tells them they are
+// committed, so this bounds the age of the newest write they can be missing.
+// ok is false when this node has never heard from a leader at all.
+func (n *Node) Staleness() (staleness time.Duration, ok bool) {
+	if n.IsLeader() {
+		return 0, true
+	}
+	last := n.raft.LastContact()
+	if last.IsZero() {
+		return 0, false
+	}
+	return time.Since(last), true
+}
+
+// RegisterMeta replicates a member's metadata. Must be called on the leader.
+func (n *Node) RegisterMeta(meta cluster.NodeMeta) error {
+	if _, err := n.Apply(&RaftCommand{Op: OpSetNodeMeta, Node: &meta}); err != nil {
+		return fmt.Errorf("node register meta %q: %w", meta.NodeID, err)
+	}
 	return nil
 }
 
-type JoinRequest struct {
-	NodeID   string `json:"node_id"`
-	RaftAddr string `json:"raft_addr"`
+// NodeMeta returns the replicated metadata of the given member, if known.
+func (n *Node) NodeMeta(nodeID string) (cluster.NodeMeta, bool) {
+	return n.fsm.NodeMeta(nodeID)
 }
 
-// Join adds nodeID/raftAddr as a new voting member. Impo: Must be called on the leader.
-func (n *Node) Join(nodeID, raftAddr string) error {
-	n.logger.Info("adding voter", slog.String("nodeID", nodeID), slog.String("raftAddr", raftAddr))
-	f := n.raft.AddVoter(
-		raft.ServerID(nodeID),
-		raft.ServerAddress(raftAddr),
-		0, 0,
-	)
-	if err := f.Error(); err != nil {
-		return fmt.Errorf("node join: AddVoter %q: %w", nodeID, err)
+// LeaderHTTPAddr returns the management address of the current leader, or ""
+// when there is no leader or it never registered one.
+func (n *Node) LeaderHTTPAddr() string {
+	meta, _ := n.LeaderMeta()
+	return meta.HTTPAddr
+}
+
+// LeaderMeta returns the replicated metadata of the current leader. ok is
+// false when there is no leader or it hasn't registered its metadata yet.
+func (n *Node) LeaderMeta() (meta cluster.NodeMeta, ok bool) {
+	_, id := n.raft.LeaderWithID()
+	if id == "" {
+		return cluster.NodeMeta{}, false
+	}
+	return n.fsm.NodeMeta(string(id))
+}
+
+// Remove takes nodeID out of the cluster configuration and forgets its
+// metadata. Impo: Must be called on the leader.
+//
+// The metadata only goes once the node is out, so a failed removal leaves a
+// member that can still be forwarded to. A leader removing itself steps down
+// as soon as the new configuration commits and can't apply anything more:
+// the next leader forgets it, see forgetRemoved.
+func (n *Node) Remove(nodeID string) error {
+	n.logger.Info("removing server", slog.String("nodeID", nodeID))
+	if err := cluster.NewMembership(n).RemoveServer(nodeID); err != nil {
+		return fmt.Errorf("node remove %q: %w", nodeID, err)
+	}
+	if nodeID == n.cfg.NodeID {
+		return nil
+	}
+	if _, err := n.Apply(&RaftCommand{Op: OpDeleteNodeMeta, Key: nodeID}); err != nil {
+		return fmt.Errorf("node remove %q: forget metadata: %w", nodeID, err)
 	}
 	return nil
 }
 
-func (n *Node) JoinViaLeader(ctx context.Context, leaderHTTPAddr, nodeID, raftAddr string) error {
-	body, err := json.Marshal(JoinRequest{NodeID: nodeID, RaftAddr: raftAddr})
+// forgetRemoved deletes the metadata of the nodes that are no longer
+// members: a leader that removed itself, or one that failed before it could
+// delete a removed node's. Members join before registering theirs, so the
+// metadata of one never outlives it otherwise.
+func (n *Node) forgetRemoved() error {
+	servers, err := cluster.NewMembership(n).Servers()
 	if err != nil {
-		return fmt.Errorf("node joinViaLeader: marshal: %w", err)
+		return fmt.Errorf("node forget removed: %w", err)
+	}
+	members := make(map[string]bool, len(servers))
+	for _, srv := range servers {
+		members[string(srv.ID)] = true
+	}
+	for nodeID := range n.fsm.Nodes() {
+		if members[nodeID] {
+			continue
+		}
+		if _, err := n.Apply(&RaftCommand{Op: OpDeleteNodeMeta, Key: nodeID}); err != nil {
+			return fmt.Errorf("node forget removed %q: %w", nodeID, err)
+		}
+	}
+	return nil
+}
+
+// TransferLeadership hands leadership over to nodeID, or to the most
+// up-to-date follower when nodeID is empty. Impo: Must be called on the leader.
+// It returns once the target has taken over or the attempt timed out.
+func (n *Node) TransferLeadership(nodeID string) error {
+	if nodeID == "" {
+		n.logger.Info("transferring leadership")
+		if err := n.raft.LeadershipTransfer().Error(); err != nil {
+			return fmt.Errorf("node transfer leadership: %w", err)
+		}
+		return nil
+	}
+
+	servers, err := cluster.NewMembership(n).Servers()
+	if err != nil {
+		return fmt.Errorf("node transfer leadership: %w", err)
+	}
+	for _, srv := range servers {
+		if srv.ID != raft.ServerID(nodeID) {
+			continue
+		}
+		if srv.Suffrage != raft.Voter {
+			return fmt.Errorf("node transfer leadership: %q is not a voter", nodeID)
+		}
+		n.logger.Info("transferring leadership", slog.String("target", nodeID))
+		if err := n.raft.LeadershipTransferToServer(srv.ID, srv.Address).Error(); err != nil {
+			return fmt.Errorf("node transfer leadership to %q: %w", nodeID, err)
+		}
+		return nil
+	}
+	return fmt.Errorf("node transfer leadership: %q is not a cluster member", nodeID)
+}
+
+// Leave removes this node from the cluster: directly when it is the leader,
+// otherwise by asking the leader through its management API.
+func (n *Node) Leave(ctx context.Context) error {
+	if n.IsLeader() {
+		return n.Remove(n.cfg.NodeID)
+	}
+	if err := n.ForwardToLeader(ctx, "/raft/remove", RemoveRequest{NodeID: n.cfg.NodeID}); err != nil {
+		return fmt.Errorf("node leave: %w", err)
+	}
+	return nil
+}
+
+// ForwardToLeader POSTs body as JSON to path on the current leader's
+// management API. It fails when the leader's address isn't known yet.
+func (n *Node) ForwardToLeader(ctx context.Context, path string, body any) error {
+	leaderHTTP := n.LeaderHTTPAddr()
+	if leaderHTTP == "" {
+		return ErrLeaderUnknown
+	}
+	return n.postToLeader(ctx, leaderHTTP, path, body)
+}
+
+// postToLeader POSTs body as JSON to the management API at leaderHTTPAddr,
+// marking the request as forwarded by this node.
+func (n *Node) postToLeader(ctx context.Context, leaderHTTPAddr, path string, body any) error {
+	return postJSON(ctx, leaderHTTPAddr, path, body, n.cfg.NodeID)
+}
+
+// StatusError is returned by management API calls answered with a status
+// other than 200 OK.
+type StatusError struct {
+	Path       string
+	StatusCode int
+	Message    string
+
+	// LeaderHTTP is the leader's management address when the callee
+	// answered 421 Misdirected Request and knew it.
+	LeaderHTTP string
+}
+
+func (e *StatusError) Error() string {
+	return fmt.Sprintf("%s rejected with status %d: %s", e.Path, e.StatusCode, e.Message)
+}
+
+// postJSON POSTs body as JSON to path on the management API at addr.
+// forwardedBy, when set, is sent as ForwardedHeader.
+func postJSON(ctx context.Context, addr, path string, body any, forwardedBy string) error {
+	return exchangeJSON(ctx, addr, path, body, nil, forwardedBy)
+}
+
+// exchangeJSON is postJSON that also decodes the JSON response into out,
+// unless out is nil.
+func exchangeJSON(ctx context.Context, addr, path string, body, out any, forwardedBy string) error {
+	b, err := json.Marshal(body)
+	if err != nil {
+		return fmt.Errorf("marshal: %w", err)
 	}
 
 	req, err := http.NewRequestWithContext(
 		ctx,
 		http.MethodPost,
-		"http://"+leaderHTTPAddr+"/raft/join",
-		bytes.NewReader(body),
+		"http://"+addr+path,
+		bytes.NewReader(b),
 	)
 	if err != nil {
-		return fmt.Errorf("node joinViaLeader: build request: %w", err)
+		return fmt.Errorf("build request: %w", err)
 	}
 	req.Header.Set("Content-Type", "application/json")
+	if forwardedBy != "" {
+		req.Header.Set(ForwardedHeader, forwardedBy)
+	}
 
 	resp, err := http.DefaultClient.Do(req)
 	if err != nil {
-		return fmt.Errorf("node joinViaLeader: POST %q: %w", leaderHTTPAddr, err)
+		return fmt.Errorf("POST %q: %w", addr+path, err)
 	}
 	defer resp.Body.Close()
 
 	if resp.StatusCode != http.StatusOK {
-		return fmt.Errorf("node joinViaLeader: leader rejected join with status %d", resp.StatusCode)
+		msg, _ := io.ReadAll(io.LimitReader(resp.Body, 1024))
+		return &StatusError{
+			Path:       path,
+			StatusCode: resp.StatusCode,
+			Message:    string(bytes.TrimSpace(msg)),
+			LeaderHTTP: resp.Header.Get(LeaderHTTPHeader),
+		}
+	}
+	if out != nil {
+		if err := json.NewDecoder(resp.Body).Decode(out); err != nil {
+			return fmt.Errorf("decode %s response: %w", path, err)
+		}
 	}
 	return nil
 }
 
+// Shutdown stops the node. Only the first call does it, so the signal
+// handler and the server's shutdown can both call it; later ones return its
+// error.
 func (n *Node) Shutdown() error {
+	n.shutdownOnce.Do(func() { n.shutdownErr = n.shutdown() })
+	return n.shutdownErr
+}
+
+func (n *Node) shutdown() error {
+	close(n.shutdownCh)
+	n.fsm.changes.Close()
 	if f := n.raft.Shutdown(); f.Error() != nil {
 		return fmt.Errorf("node shutdown raft: %w", f.Error())
 	}
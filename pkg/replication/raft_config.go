@@ -0,0 +1,139 @@
+package replication
+
+import (
+	"errors"
+	"fmt"
+	"io/fs"
+	"os"
+	"path/filepath"
+	"time"
+
+	"github.com/hashicorp/raft"
+	raftboltdb "github.com/hashicorp/raft-boltdb"
+	"github.com/mateenbagheri/memorabilia/pkg/cluster"
+)
+
+const (
+	defaultApplyTimeout   = 5 * time.Second
+	defaultSnapshotRetain = 3
+)
+
+// raftConfig returns hashicorp/raft's default configuration with every
+// tunable that cfg sets overridden. Zero values keep the Raft default.
+func raftConfig(cfg *cluster.Config) *raft.Config {
+	rc := raft.DefaultConfig()
+	rc.LocalID = raft.ServerID(cfg.NodeID)
+
+	if cfg.HeartbeatTimeout > 0 {
+		rc.HeartbeatTimeout = cfg.HeartbeatTimeout
+	}
+	if cfg.ElectionTimeout > 0 {
+		rc.ElectionTimeout = cfg.ElectionTimeout
+	}
+	if cfg.LeaderLeaseTimeout > 0 {
+		rc.LeaderLeaseTimeout = cfg.LeaderLeaseTimeout
+	}
+	if cfg.SnapshotInterval > 0 {
+		rc.SnapshotInterval = cfg.SnapshotInterval
+	}
+	if cfg.SnapshotThreshold > 0 {
+		rc.SnapshotThreshold = cfg.SnapshotThreshold
+	}
+	if cfg.TrailingLogs > 0 {
+		rc.TrailingLogs = cfg.TrailingLogs
+	}
+	return rc
+}
+
+// applyTimeout returns how long Apply waits to enqueue a command.
+func applyTimeout(cfg *cluster.Config) time.Duration {
+	if cfg.ApplyTimeout > 0 {
+		return cfg.ApplyTimeout
+	}
+	return defaultApplyTimeout
+}
+
+// raftStores holds the storage a Raft node runs on.
+type raftStores struct {
+	log      raft.LogStore
+	stable   raft.StableStore
+	snapshot raft.SnapshotStore
+}
+
+// bootstrapMarker is the file in DataDir recording that a node on memory
+// storage bootstrapped a cluster, which its Raft state forgets on restart.
+const bootstrapMarker = "bootstrapped"
+
+// newRaftStores opens the stores selected by cfg.Storage.
+//
+// StorageBolt keeps the log and stable store in BoltDB files and snapshots
+// as files, all under DataDir. StorageMemory keeps everything in memory:
+// nothing survives a restart, so a restarted node comes back empty and
+// catches up from the leader like a new one. The exception is a node that
+// bootstrapped a cluster: it leaves a marker in DataDir, and refuses to start
+// with Bootstrap again, since it would bootstrap a second cluster next to the
+// one its peers still run.
+func newRaftStores(cfg *cluster.Config) (raftStores, error) {
+	switch cfg.Storage {
+	case cluster.StorageMemory:
+		if cfg.Bootstrap && cfg.DataDir != "" {
+			_, err := os.Stat(filepath.Join(cfg.DataDir, bootstrapMarker))
+			if err == nil {
+				return raftStores{}, fmt.Errorf("node: %q bootstrapped a cluster before and memory storage lost it: "+
+					"join the cluster instead of bootstrapping another one, or delete %q to start over",
+					cfg.NodeID, filepath.Join(cfg.DataDir, bootstrapMarker))
+			}
+			if !errors.Is(err, fs.ErrNotExist) {
+				return raftStores{}, fmt.Errorf("node: check bootstrap marker: %w", err)
+			}
+		}
+		store := raft.NewInmemStore()
+		return raftStores{log: store, stable: store, snapshot: raft.NewInmemSnapshotStore()}, nil
+
+	case cluster.StorageBolt, "":
+		if err := os.MkdirAll(cfg.DataDir, 0o755); err != nil {
+			return raftStores{}, fmt.Errorf("node: mkdir %q: %w", cfg.DataDir, err)
+		}
+
+		logStore, err := raftboltdb.NewBoltStore(filepath.Join(cfg.DataDir, "raft-log.db"))
+		if err != nil {
+			return raftStores{}, err
+		}
+
+		stableStore, err := raftboltdb.NewBoltStore(filepath.Join(cfg.DataDir, "raft-stable.db"))
+		if err != nil {
+			return raftStores{}, err
+		}
+
+		retain := cfg.SnapshotRetain
+		if retain <= 0 {
+			retain = defaultSnapshotRetain
+		}
+		snapshotStore, err := raft.NewFileSnapshotStore(cfg.DataDir, retain, os.Stderr)
+		if err != nil {
+			return raftStores{}, err
+		}
+		return raftStores{log: logStore, stable: stableStore, snapshot: snapshotStore}, nil
+
+	default:
+		return raftStores{}, fmt.Errorf("node: unknown storage %q (want %q or %q)",
+			cfg.Storage, cluster.StorageBolt, cluster.StorageMemory)
+	}
+}
+
+// markBootstrapped leaves the marker newRaftStores looks for once a node on
+// memory storage bootstrapped a cluster. Bolt storage needs none: the state it
+// keeps tells Raft the cluster exists. Without a DataDir there is nowhere to
+// leave it, which only tests do.
+func markBootstrapped(cfg *cluster.Config) error {
+	if cfg.Storage != cluster.StorageMemory || cfg.DataDir == "" {
+		return nil
+	}
+	if err := os.MkdirAll(cfg.DataDir, 0o755); err != nil {
+		return fmt.Errorf("node: mkdir %q: %w", cfg.DataDir, err)
+	}
+	if err := os.WriteFile(filepath.Join(cfg.DataDir, bootstrapMarker), nil, 0o644); err != nil {
+		return fmt.Errorf("node: write bootstrap marker: %w", err)
+	}
+	return nil
+}
@@ -0,0 +1,75 @@
+package replication
+
+import (
+	"testing"
+	"time"
+
+	"github.com/hashicorp/raft"
+	"github.com/mateenbagheri/memorabilia/pkg/cluster"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestRaftConfig_Defaults(t *testing.T) {
+	rc := raftConfig(&cluster.Config{NodeID: "n1"})
+	def := raft.DefaultConfig()
+
+	assert.Equal(t, raft.ServerID("n1"), rc.LocalID)
+	assert.Equal(t, def.HeartbeatTimeout, rc.HeartbeatTimeout)
+	assert.Equal(t, def.ElectionTimeout, rc.ElectionTimeout)
+	assert.Equal(t, def.SnapshotThreshold, rc.SnapshotThreshold)
+	assert.Equal(t, defaultApplyTimeout, applyTimeout(&cluster.Config{}))
+}
+
+func TestRaftConfig_Overrides(t *testing.T) {
+	cfg := &cluster.Config{
+		NodeID:             "n1",
+		HeartbeatTimeout:   200 * time.Millisecond,
+		ElectionTimeout:    300 * time.Millisecond,
+		LeaderLeaseTimeout: 100 * time.Millisecond,
+		SnapshotInterval:   30 * time.Second,
+		SnapshotThreshold:  1000,
+		TrailingLogs:       500,
+		ApplyTimeout:       time.Second,
+	}
+	rc := raftConfig(cfg)
+
+	assert.Equal(t, 200*time.Millisecond, rc.HeartbeatTimeout)
+	assert.Equal(t, 300*time.Millisecond, rc.ElectionTimeout)
+	assert.Equal(t, 100*time.Millisecond, rc.LeaderLeaseTimeout)
+	assert.Equal(t, 30*time.Second, rc.SnapshotInterval)
+	assert.Equal(t, uint64(1000), rc.SnapshotThreshold)
+	assert.Equal(t, uint64(500), rc.TrailingLogs)
+	assert.NoError(t, raft.ValidateConfig(rc))
+	assert.Equal(t, time.Second, applyTimeout(cfg))
+}
+
+func TestNewRaftStores(t *testing.T) {
+	dir := t.TempDir()
+	stores, err := newRaftStores(&cluster.Config{Storage: cluster.StorageMemory, DataDir: dir + "/unused"})
+	require.NoError(t, err)
+	assert.IsType(t, &raft.InmemStore{}, stores.log)
+	assert.NoDirExists(t, dir+"/unused", "memory storage must not touch the disk")
+
+	stores, err = newRaftStores(&cluster.Config{DataDir: dir + "/bolt"})
+	require.NoError(t, err)
+	assert.IsType(t, &raft.FileSnapshotStore{}, stores.snapshot)
+	assert.FileExists(t, dir+"/bolt/raft-log.db")
+
+	_, err = newRaftStores(&cluster.Config{Storage: "tape"})
+	assert.Error(t, err)
+}
+
+func TestNewRaftStores_MemoryRefusesToBootstrapTwice(t *testing.T) {
+	cfg := &cluster.Config{NodeID: "n1", Storage: cluster.StorageMemory, DataDir: t.TempDir(), Bootstrap: true}
+	_, err := newRaftStores(cfg)
+	require.NoError(t, err, "a brand-new node may bootstrap")
+
+	require.NoError(t, markBootstrapped(cfg))
+	_, err = newRaftStores(cfg)
+	assert.ErrorContains(t, err, "bootstrapped a cluster before")
+
+	cfg.Bootstrap = false
+	_, err = newRaftStores(cfg)
+	assert.NoError(t, err, "it may still start to rejoin")
+}
@@ -0,0 +1,77 @@
+package replication
+
+import (
+	"time"
+
+	"github.com/mateenbagheri/memorabilia/pkg/core"
+	"github.com/mateenbagheri/memorabilia/pkg/types"
+)
+
+// ApplyResponse is what the FSM returns for every successfully applied
+// RaftCommand, and what Node.Apply hands back to the caller. Using one type
+// for all ops keeps Node.Apply free of per-op type switches; each op just
+// fills in the fields that mean something for it.
+//
+// Failures are not reported here: the FSM returns a plain error for those,
+// which Node.Apply turns into its error result.
+type ApplyResponse struct {
+	// Applied reports whether the op changed any state. A delete of a
+	// missing key is committed to the log but not applied.
+	Applied bool
+
+	// DeleteCount is the number of keys removed by OpDelete/OpBatchDelete,
+	// or by OpCloseSession. Keys are the keys an expiring OpBatchDelete or an
+	// OpCloseSession removed.
+	DeleteCount int64
+	Keys        []string
+
+	// Previous is the value an OpSet replaced, valid when HadPrevious is set.
+	Previous    string
+	HadPrevious bool
+
+	// Length is the length of the list after an OpListPush.
+	Length int64
+
+	// Key and Value are the key an OpListPop popped from and the element it
+	// popped, valid when Applied is set. OpQueuePop fills in Value, and Job
+	// with the job it created.
+	Key   string
+	Value string
+	Job   types.Job
+
+	// Lock is the lock an OpLock acquired, or its current holder when it
+	// didn't, and LeaseEnd the end of that lock's lease. OpRefreshLock fills
+	// in LeaseEnd, and so do OpCreateSession and OpKeepAlive with the
+	// session's deadline.
+	Lock     types.Lock
+	LeaseEnd time.Time
+
+	// Count is the number of entries an OpStreamTrim or OpStreamAdd trimmed,
+	// or an OpStreamAck acknowledged. StreamEntries are the entries an
+	// OpStreamReadGroup read or an OpStreamClaim claimed. OpStreamAdd fills in
+	// Value with the ID of the entry it added.
+	Count         int64
+	StreamEntries []types.StreamEntry
+
+	// Allowed reports whether an OpRateLimit let its cost through, which is
+	// also when it is Applied: a denied request leaves the limiter as it was.
+	// Count is what the limiter has left, and RetryAfter, when it didn't let
+	// the cost through, how long until it would.
+	Allowed    bool
+	RetryAfter time.Duration
+
+	// Flags reports, for each item of an OpBloomAdd, whether it was new to
+	// the filter. Applied reports whether an OpBloomReserve created the
+	// filter, or an OpHLLAdd changed the HyperLogLog.
+	Flags []bool
+
+	// PreviousBit is the previous value of the bit an OpSetBit set. Count is
+	// the length of the string an OpBitOp stored, and BitFields the results
+	// of an OpBitField.
+	PreviousBit bool
+	BitFields   []core.BitFieldResult
+}
+
+// BatchResponse is what the FSM returns for an OpBatch: for each command, in
+// order, either an ApplyResponse or an error.
+type BatchResponse []any
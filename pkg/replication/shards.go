@@ -0,0 +1,798 @@
+package replication
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"log/slog"
+	"net/http"
+	"os"
+	"path/filepath"
+	"slices"
+	"sync"
+	"time"
+
+	"github.com/hashicorp/raft"
+	"github.com/mateenbagheri/memorabilia/pkg/cluster"
+	"github.com/mateenbagheri/memorabilia/pkg/core"
+	"github.com/mateenbagheri/memorabilia/pkg/sharding"
+	"github.com/mateenbagheri/memorabilia/pkg/types"
+)
+
+const (
+	// shardReconcileInterval is how often ShardHost compares the shard map
+	// with the groups it runs.
+	shardReconcileInterval = time.Second
+
+	// shardRetireGrace is how long a node keeps running a group it is no
+	// longer a member of while waiting for the group's leader to remove it.
+	shardRetireGrace = 30 * time.Second
+
+	// shardLoadChunk caps the entries per OpLoad log entry.
+	shardLoadChunk = 512
+
+	shardCallInitialBackoff = 200 * time.Millisecond
+	shardCallMaxBackoff     = 2 * time.Second
+)
+
+var (
+	// ErrNotShardMember is returned for an operation on a shard group this
+	// node doesn't run.
+	ErrNotShardMember = errors.New("not a member of the shard")
+
+	// ErrInvalidShardChange is returned when a requested change to the shard
+	// map is rejected before anything was changed.
+	ErrInvalidShardChange = errors.New("invalid shard change")
+)
+
+// Shard is a shard group running on this node.
+type Shard struct {
+	ID   sharding.ShardID
+	Node *Node
+	FSM  *FSM
+
+	dataDir string
+
+	// retiringSince is when this node was first seen missing from the
+	// shard's members in the map.
+	retiringSince time.Time
+}
+
+// ShardHost runs the Raft groups of the shards this node is a member of, next
+// to the metadata group run by meta, and orchestrates changes to the shard
+// map when meta leads.
+//
+// It converges on the shard map on its own: every shardReconcileInterval it
+// starts the groups this node was added to, stops (and deletes) the ones it
+// was removed from, and in the groups it leads adds and removes Raft members
+// until they match the map.
+type ShardHost struct {
+	meta    *Node
+	cfg     *cluster.Config
+	logger  *slog.Logger
+	newRepo func() core.CommandsRepository
+
+	mu     sync.RWMutex
+	shards map[sharding.ShardID]*Shard
+	notify core.KeyspaceNotifier
+
+	shutdownCh chan struct{}
+	done       chan struct{}
+}
+
+// NewShardHost starts managing the shard groups of the node running meta.
+// newRepo creates the empty repository of each group started.
+func NewShardHost(meta *Node, newRepo func() core.CommandsRepository) *ShardHost {
+	h := &ShardHost{
+		meta:       meta,
+		cfg:        meta.cfg,
+		logger:     meta.logger,
+		newRepo:    newRepo,
+		shards:     make(map[sharding.ShardID]*Shard),
+		shutdownCh: make(chan struct{}),
+		done:       make(chan struct{}),
+	}
+	go h.run()
+	return h
+}
+
+// Map returns the current shard map; nil when the cluster isn't sharded.
+func (h *ShardHost) Map() *sharding.Map {
+	return h.meta.fsm.ShardMap()
+}
+
+// Meta returns the node of the metadata group.
+func (h *ShardHost) Meta() *Node {
+	return h.meta
+}
+
+// Group returns the node and FSM of shard id on this node. MetaShard is
+// always there.
+func (h *ShardHost) Group(id sharding.ShardID) (*Node, *FSM, bool) {
+	if id == sharding.MetaShard {
+		return h.meta, h.meta.fsm, true
+	}
+	h.mu.RLock()
+	defer h.mu.RUnlock()
+	shard, ok := h.shards[id]
+	if !ok {
+		return nil, nil, false
+	}
+	return shard.Node, shard.FSM, true
+}
+
+// Shards returns the shard groups running on this node, MetaShard excluded.
+func (h *ShardHost) Shards() []*Shard {
+	h.mu.RLock()
+	defer h.mu.RUnlock()
+	shards := make([]*Shard, 0, len(h.shards))
+	for _, shard := range h.shards {
+		shards = append(shards, shard)
+	}
+	slices.SortFunc(shards, func(a, b *Shard) int { return int(a.ID) - int(b.ID) })
+	return shards
+}
+
+// SetKeyspaceNotifier makes every group on this node, including those started
+// later, report the changes to their keys to notify.
+func (h *ShardHost) SetKeyspaceNotifier(notify core.KeyspaceNotifier) {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+	h.notify = notify
+	h.meta.fsm.SetKeyspaceNotifier(notify)
+	for _, shard := range h.shards {
+		shard.FSM.SetKeyspaceNotifier(notify)
+	}
+}
+
+func (h *ShardHost) run() {
+	defer close(h.done)
+	ticker := time.NewTicker(shardReconcileInterval)
+	defer ticker.Stop()
+	for {
+		select {
+		case <-ticker.C:
+			h.reconcile()
+		case <-h.shutdownCh:
+			return
+		}
+	}
+}
+
+// reconcile brings the groups on this node in line with the shard map.
+func (h *ShardHost) reconcile() {
+	m := h.Map()
+	if m == nil {
+		return
+	}
+	self := h.meta.NodeID()
+
+	for _, id := range m.IDs() {
+		shard := m.Shards[id]
+		if id == sharding.MetaShard || !shard.HasMember(self) {
+			continue
+		}
+		if _, _, ok := h.Group(id); ok {
+			continue
+		}
+		if err := h.start(m, shard); err != nil {
+			h.logger.Warn("failed to start shard", slog.Uint64("shard", uint64(id)), slog.String("error", err.Error()))
+		}
+	}
+
+	for _, local := range h.Shards() {
+		shard, inMap := m.Shards[local.ID]
+		if !inMap || !shard.HasMember(self) {
+			h.retire(local, inMap)
+			continue
+		}
+		local.retiringSince = time.Time{}
+		if local.Node.IsLeader() {
+			h.syncMembers(m, shard, local)
+		}
+	}
+}
+
+// start creates this node's replica of shard. A member listed in
+// shard.Bootstrap forms the group together with the other listed members;
+// any other member waits for the group's leader to add it.
+func (h *ShardHost) start(m *sharding.Map, shard sharding.Shard) error {
+	bindAddr, err := m.RaftAddr(h.cfg.RaftBindAddr, shard.ID)
+	if err != nil {
+		return err
+	}
+	cfg := *h.cfg
+	cfg.RaftBindAddr = bindAddr
+	cfg.AdvertiseAddr = ""
+	if h.cfg.AdvertiseAddr != "" {
+		if cfg.AdvertiseAddr, err = m.RaftAddr(h.cfg.AdvertiseAddr, shard.ID); err != nil {
+			return err
+		}
+	}
+	cfg.DataDir = filepath.Join(h.cfg.DataDir, fmt.Sprintf("shard-%d", shard.ID))
+	cfg.LeaderHTTPAddr = ""
+	cfg.JoinSeeds = nil
+	cfg.LeaveOnShutdown = false
+	cfg.Bootstrap = slices.Contains(shard.Bootstrap, h.meta.NodeID())
+	cfg.BootstrapPeers = nil
+	if cfg.Bootstrap {
+		if cfg.BootstrapPeers, err = h.raftAddrs(m, shard.ID, shard.Bootstrap); err != nil {
+			return err
+		}
+	}
+
+	fsm := NewFSM(h.newRepo())
+	h.mu.RLock()
+	fsm.SetKeyspaceNotifier(h.notify)
+	h.mu.RUnlock()
+	node, err := NewNode(&cfg, fsm, h.logger.With(slog.Uint64("shard", uint64(shard.ID))))
+	if err != nil {
+		return err
+	}
+
+	h.mu.Lock()
+	h.shards[shard.ID] = &Shard{ID: shard.ID, Node: node, FSM: fsm, dataDir: cfg.DataDir}
+	h.mu.Unlock()
+	h.logger.Info("started shard", slog.Uint64("shard", uint64(shard.ID)), slog.String("raftAddr", bindAddr))
+	return nil
+}
+
+// raftAddrs returns the shard id Raft address of each of members.
+func (h *ShardHost) raftAddrs(m *sharding.Map, id sharding.ShardID, members []string) (map[string]string, error) {
+	addrs := make(map[string]string, len(members))
+	for _, member := range members {
+		meta, ok := h.meta.NodeMeta(member)
+		if !ok {
+			return nil, fmt.Errorf("shard %d: no metadata for member %q yet", id, member)
+		}
+		addr, err := m.RaftAddr(meta.RaftAddr, id)
+		if err != nil {
+			return nil, err
+		}
+		addrs[member] = addr
+	}
+	return addrs, nil
+}
+
+// syncMembers makes the Raft configuration of a shard this node leads match
+// its members in the map. New members are added first, and get the group's
+// state as a snapshot; members no longer listed are removed only once every
+// listed one is in, so the group never shrinks below its target size.
+func (h *ShardHost) syncMembers(m *sharding.Map, shard sharding.Shard, local *Shard) {
+	logger := h.logger.With(slog.Uint64("shard", uint64(shard.ID)))
+
+	desired, err := h.raftAddrs(m, shard.ID, shard.Members)
+	if err != nil {
+		logger.Warn("can't sync shard members", slog.String("error", err.Error()))
+		return
+	}
+	servers, err := cluster.NewMembership(local.Node).Servers()
+	if err != nil {
+		logger.Warn("can't read shard configuration", slog.String("error", err.Error()))
+		return
+	}
+
+	current := make(map[string]raft.ServerAddress, len(servers))
+	for _, srv := range servers {
+		current[string(srv.ID)] = srv.Address
+	}
+
+	added := false
+	for id, addr := range desired {
+		if current[id] == raft.ServerAddress(addr) {
+			continue
+		}
+		added = true
+		logger.Info("adding shard member", slog.String("nodeID", id), slog.String("raftAddr", addr))
+		if err := local.Node.Join(id, addr); err != nil {
+			logger.Warn("failed to add shard member", slog.String("nodeID", id), slog.String("error", err.Error()))
+		}
+	}
+	if added {
+		return
+	}
+
+	for id := range current {
+		if _, ok := desired[id]; ok {
+			continue
+		}
+		if id == local.Node.NodeID() {
+			// Leave the removal of ourselves to the next leader.
+			logger.Info("handing over leadership of shard this node is leaving")
+			if err := local.Node.TransferLeadership(""); err != nil {
+				logger.Warn("leadership transfer failed", slog.String("error", err.Error()))
+			}
+			return
+		}
+		logger.Info("removing shard member", slog.String("nodeID", id))
+		if err := cluster.NewMembership(local.Node).RemoveServer(id); err != nil {
+			logger.Warn("failed to remove shard member", slog.String("nodeID", id), slog.String("error", err.Error()))
+		}
+	}
+}
+
+// retire stops this node's replica of a shard it no longer belongs to and
+// deletes its data. A shard that was removed from the map goes right away;
+// otherwise the replica keeps running until the group's leader has removed
+// it from the configuration, or shardRetireGrace has passed.
+func (h *ShardHost) retire(local *Shard, inMap bool) {
+	if inMap {
+		if local.retiringSince.IsZero() {
+			local.retiringSince = time.Now()
+		}
+		if h.inConfiguration(local) && time.Since(local.retiringSince) < shardRetireGrace {
+			return
+		}
+	}
+
+	h.mu.Lock()
+	delete(h.shards, local.ID)
+	h.mu.Unlock()
+
+	logger := h.logger.With(slog.Uint64("shard", uint64(local.ID)))
+	if err := local.Node.Shutdown(); err != nil {
+		logger.Warn("shard shutdown failed", slog.String("error", err.Error()))
+	}
+	if err := os.RemoveAll(local.dataDir); err != nil {
+		logger.Warn("failed to delete shard data", slog.String("error", err.Error()))
+	}
+	logger.Info("stopped shard this node is no longer a member of")
+}
+
+func (h *ShardHost) inConfiguration(local *Shard) bool {
+	servers, err := cluster.NewMembership(local.Node).Servers()
+	if err != nil {
+		return true
+	}
+	for _, srv := range servers {
+		if string(srv.ID) == local.Node.NodeID() {
+			return true
+		}
+	}
+	return false
+}
+
+// Shutdown stops every shard group on this node, keeping their data. Groups
+// this node leads hand leadership over first, like the metadata group does.
+func (h *ShardHost) Shutdown() error {
+	close(h.shutdownCh)
+	<-h.done
+
+	var errs []error
+	for _, shard := range h.Shards() {
+		if shard.Node.IsLeader() {
+			if err := shard.Node.TransferLeadership(""); err != nil {
+				h.logger.Warn("shard leadership transfer before shutdown failed",
+					slog.Uint64("shard", uint64(shard.ID)), slog.String("error", err.Error()))
+			}
+		}
+		if err := shard.Node.Shutdown(); err != nil {
+			errs = append(errs, fmt.Errorf("shard %d: %w", shard.ID, err))
+		}
+	}
+	return errors.Join(errs...)
+}
+
+// -- Group-local operations, run on a shard group's leader. --
+
+// leader returns the local group id if this node leads it.
+func (h *ShardHost) leader(id sharding.ShardID) (*Node, *FSM, error) {
+	node, fsm, ok := h.Group(id)
+	if !ok {
+		return nil, nil, fmt.Errorf("shard %d: %w", id, ErrNotShardMember)
+	}
+	if !node.IsLeader() {
+		return nil, nil, fmt.Errorf("shard %d: %w", id, raft.ErrNotLeader)
+	}
+	return node, fsm, nil
+}
+
+// FreezeSlots makes shard id refuse writes to slots and returns every key in
+// them. The freeze is a log entry, so the keys returned are exactly those
+// written before it, and none can change afterwards.
+func (h *ShardHost) FreezeSlots(id sharding.ShardID, slots []int) (map[string]types.ColumnValueWithTTL, error) {
+	node, fsm, err := h.leader(id)
+	if err != nil {
+		return nil, err
+	}
+	if _, err := node.Apply(&RaftCommand{Op: OpFreezeSlots, Slots: slots}); err != nil {
+		return nil, fmt.Errorf("shard %d freeze: %w", id, err)
+	}
+	entries, err := fsm.SlotEntries(slots)
+	if err != nil {
+		return nil, fmt.Errorf("shard %d freeze: %w", id, err)
+	}
+	return entries, nil
+}
+
+// LoadEntries copies entries into shard id.
+func (h *ShardHost) LoadEntries(id sharding.ShardID, entries map[string]types.ColumnValueWithTTL) error {
+	node, _, err := h.leader(id)
+	if err != nil {
+		return err
+	}
+	chunk := make(map[string]types.ColumnValueWithTTL, min(len(entries), shardLoadChunk))
+	flush := func() error {
+		if len(chunk) == 0 {
+			return nil
+		}
+		if _, err := node.Apply(&RaftCommand{Op: OpLoad, Entries: chunk}); err != nil {
+			return fmt.Errorf("shard %d load: %w", id, err)
+		}
+		chunk = make(map[string]types.ColumnValueWithTTL, shardLoadChunk)
+		return nil
+	}
+	for key, val := range entries {
+		chunk[key] = val
+		if len(chunk) == shardLoadChunk {
+			if err := flush(); err != nil {
+				return err
+			}
+		}
+	}
+	return flush()
+}
+
+// DropSlots deletes every key in slots from shard id and unfreezes them.
+func (h *ShardHost) DropSlots(id sharding.ShardID, slots []int) (int64, error) {
+	node, _, err := h.leader(id)
+	if err != nil {
+		return 0, err
+	}
+	resp, err := node.Apply(&RaftCommand{Op: OpDropSlots, Slots: slots})
+	if err != nil {
+		return 0, fmt.Errorf("shard %d drop: %w", id, err)
+	}
+	return resp.DeleteCount, nil
+}
+
+// UnfreezeSlots lets shard id accept writes to slots again.
+func (h *ShardHost) UnfreezeSlots(id sharding.ShardID, slots []int) error {
+	node, _, err := h.leader(id)
+	if err != nil {
+		return err
+	}
+	if _, err := node.Apply(&RaftCommand{Op: OpUnfreezeSlots, Slots: slots}); err != nil {
+		return fmt.Errorf("shard %d unfreeze: %w", id, err)
+	}
+	return nil
+}
+
+// RestoreShard replaces the state of shard id with the backup read from r,
+// as written by the OpenSnapshot of one of its members; see Node.Restore for
+// what is kept from the current state. Keys in slots the shard doesn't own
+// are left out: nothing could reach them, and they would come back stale if
+// their slots were moved here later.
+func (h *ShardHost) RestoreShard(id sharding.ShardID, r io.Reader) error {
+	node, _, err := h.leader(id)
+	if err != nil {
+		return err
+	}
+	m := h.Map()
+	if err := node.restore(r, func(key string) bool { return m.ShardFor(key) == id }); err != nil {
+		return fmt.Errorf("shard %d: %w", id, err)
+	}
+	return nil
+}
+
+// Leave takes this node out of every shard it is a member of, through the
+// metadata group's leader. The groups' leaders then remove it from their
+// configurations, so they stop counting it towards quorum.
+func (h *ShardHost) Leave(ctx context.Context) error {
+	req := LeaveShardsRequest{NodeID: h.meta.NodeID()}
+	if h.meta.IsLeader() {
+		return h.RemoveNode(req.NodeID)
+	}
+	if err := h.meta.ForwardToLeader(ctx, "/shards/leave", req); err != nil {
+		return fmt.Errorf("leave shards: %w", err)
+	}
+	return nil
+}
+
+// -- Map changes, run on the metadata group's leader. --
+
+// AddShardRequest is the body of /shards/add.
+type AddShardRequest struct {
+	ShardID sharding.ShardID `json:"shard_id"`
+	Members []string         `json:"members"`
+	Slots   []int            `json:"slots,omitempty"`
+}
+
+// MoveSlotsRequest is the body of /shards/move-slots.
+type MoveSlotsRequest struct {
+	Slots []int            `json:"slots"`
+	To    sharding.ShardID `json:"to"`
+}
+
+// MoveReplicaRequest is the body of /shards/move-replica.
+type MoveReplicaRequest struct {
+	ShardID sharding.ShardID `json:"shard_id"`
+	From    string           `json:"from"`
+	To      string           `json:"to"`
+}
+
+// RemoveShardRequest is the body of /shards/remove.
+type RemoveShardRequest struct {
+	ShardID sharding.ShardID `json:"shard_id"`
+}
+
+// LeaveShardsRequest is the body of /shards/leave.
+type LeaveShardsRequest struct {
+	NodeID string `json:"node_id"`
+}
+
+// SlotsRequest is the body of /shards/freeze, /shards/drop and
+// /shards/unfreeze.
+type SlotsRequest struct {
+	ShardID sharding.ShardID `json:"shard_id"`
+	Slots   []int            `json:"slots"`
+}
+
+// EntriesResponse is the response of /shards/freeze.
+type EntriesResponse struct {
+	Entries map[string]types.ColumnValueWithTTL `json:"entries"`
+}
+
+// LoadRequest is the body of /shards/load.
+type LoadRequest struct {
+	ShardID sharding.ShardID                    `json:"shard_id"`
+	Entries map[string]types.ColumnValueWithTTL `json:"entries"`
+}
+
+// applyMap replicates next through the metadata group.
+func (h *ShardHost) applyMap(next *sharding.Map) error {
+	if _, err := h.meta.Apply(&RaftCommand{Op: OpSetShardMap, ShardMap: next}); err != nil {
+		return fmt.Errorf("shard map v%d: %w", next.Version, err)
+	}
+	return nil
+}
+
+// currentMap returns the shard map to derive the next version from: an
+// unsharded map at version 0 when there is none yet, so the first map
+// applied is version 1.
+func (h *ShardHost) currentMap() *sharding.Map {
+	if m := h.Map(); m != nil {
+		return m
+	}
+	m := sharding.NewMap(h.cfg.ShardPortStride)
+	m.Version = 0
+	return m
+}
+
+func (h *ShardHost) checkKnown(nodeIDs ...string) error {
+	for _, id := range nodeIDs {
+		if _, ok := h.meta.NodeMeta(id); !ok {
+			return fmt.Errorf("%w: unknown node %q", ErrInvalidShardChange, id)
+		}
+	}
+	return nil
+}
+
+func invalidChange(err error) error {
+	return fmt.Errorf("%w: %w", ErrInvalidShardChange, err)
+}
+
+// AddShard creates shard id on members, then moves slots, if any, to it.
+// Impo: Must be called on the metadata group's leader.
+func (h *ShardHost) AddShard(ctx context.Context, id sharding.ShardID, members []string, slots []int) error {
+	if err := h.checkKnown(members...); err != nil {
+		return fmt.Errorf("add shard %d: %w", id, err)
+	}
+	next, err := h.currentMap().AddShard(id, members)
+	if err != nil {
+		return invalidChange(err)
+	}
+	if err := h.applyMap(next); err != nil {
+		return fmt.Errorf("add shard %d: %w", id, err)
+	}
+	h.logger.Info("added shard", slog.Uint64("shard", uint64(id)), slog.Any("members", members))
+
+	if len(slots) == 0 {
+		return nil
+	}
+	return h.MoveSlots(ctx, slots, id)
+}
+
+// RemoveShard deletes shard id, which must own no slots, from the map. Its
+// members stop the group and delete its data.
+// Impo: Must be called on the metadata group's leader.
+func (h *ShardHost) RemoveShard(id sharding.ShardID) error {
+	next, err := h.currentMap().RemoveShard(id)
+	if err != nil {
+		return invalidChange(err)
+	}
+	return h.applyMap(next)
+}
+
+// MoveReplica replaces member from of shard id by to. The group's leader
+// adds to, which receives the group's snapshot, then removes from, which
+// deletes its copy. It returns once the map has changed; follow progress
+// with GET /shards.
+// Impo: Must be called on the metadata group's leader.
+func (h *ShardHost) MoveReplica(id sharding.ShardID, from, to string) error {
+	if err := h.checkKnown(to); err != nil {
+		return fmt.Errorf("move replica of shard %d: %w", id, err)
+	}
+	next, err := h.currentMap().MoveReplica(id, from, to)
+	if err != nil {
+		return invalidChange(err)
+	}
+	return h.applyMap(next)
+}
+
+// RemoveNode takes nodeID out of every shard it is a member of. It fails,
+// changing nothing, when nodeID is the only member of a shard.
+// Impo: Must be called on the metadata group's leader.
+func (h *ShardHost) RemoveNode(nodeID string) error {
+	m := h.currentMap()
+	member := false
+	for _, shard := range m.Shards {
+		member = member || shard.HasMember(nodeID)
+	}
+	if !member {
+		return nil
+	}
+	next, err := m.RemoveNode(nodeID)
+	if err != nil {
+		return invalidChange(err)
+	}
+	return h.applyMap(next)
+}
+
+// MoveSlots moves slots, and the keys in them, to shard to.
+// Impo: Must be called on the metadata group's leader.
+//
+// The slots are marked migrating in the map, so routers refuse writes to
+// them; each source group then freezes them, which fences off writes that
+// slipped past a router with an older map. The frozen keys are copied to to,
+// the map is switched over, and finally the sources drop their copies. If
+// copying fails the move is rolled back and the sources keep the slots.
+func (h *ShardHost) MoveSlots(ctx context.Context, slots []int, to sharding.ShardID) error {
+	m := h.currentMap()
+	begin, err := m.BeginMove(slots, to)
+	if err != nil {
+		return invalidChange(err)
+	}
+	if err := h.applyMap(begin); err != nil {
+		return fmt.Errorf("move slots: %w", err)
+	}
+	logger := h.logger.With(slog.Uint64("to", uint64(to)), slog.Int("slots", len(slots)))
+	logger.Info("moving slots")
+
+	sources := begin.BySource(slots)
+	if err := h.copySlots(ctx, sources, to); err != nil {
+		logger.Error("moving slots failed, rolling back", slog.String("error", err.Error()))
+		h.rollbackMove(sources, slots)
+		return fmt.Errorf("move slots: %w", err)
+	}
+
+	complete, err := h.currentMap().CompleteMove(slots)
+	if err != nil {
+		return fmt.Errorf("move slots: %w", err)
+	}
+	if err := h.applyMap(complete); err != nil {
+		return fmt.Errorf("move slots: %w", err)
+	}
+
+	for src, srcSlots := range sources {
+		var resp ApplyResponse
+		req := SlotsRequest{ShardID: src, Slots: srcSlots}
+		if err := h.callGroup(ctx, src, "/shards/drop", req, &resp); err != nil {
+			// The keys are unreachable now, just not freed.
+			logger.Warn("failed to drop moved keys from source shard",
+				slog.Uint64("from", uint64(src)), slog.String("error", err.Error()))
+			continue
+		}
+		logger.Info("moved slots", slog.Uint64("from", uint64(src)), slog.Int64("keys", resp.DeleteCount))
+	}
+	return nil
+}
+
+func (h *ShardHost) copySlots(ctx context.Context, sources map[sharding.ShardID][]int, to sharding.ShardID) error {
+	for src, srcSlots := range sources {
+		var exported EntriesResponse
+		if err := h.callGroup(ctx, src, "/shards/freeze", SlotsRequest{ShardID: src, Slots: srcSlots}, &exported); err != nil {
+			return fmt.Errorf("freeze on shard %d: %w", src, err)
+		}
+		if err := h.callGroup(ctx, to, "/shards/load", LoadRequest{ShardID: to, Entries: exported.Entries}, nil); err != nil {
+			return fmt.Errorf("load into shard %d: %w", to, err)
+		}
+	}
+	return nil
+}
+
+// rollbackMove unfreezes the slots on their sources and takes them out of
+// migration. Best effort: a source that can't be reached keeps the slots
+// frozen, and a retried move picks up from there.
+func (h *ShardHost) rollbackMove(sources map[sharding.ShardID][]int, slots []int) {
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+	defer cancel()
+	for src, srcSlots := range sources {
+		if err := h.callGroup(ctx, src, "/shards/unfreeze", SlotsRequest{ShardID: src, Slots: srcSlots}, nil); err != nil {
+			h.logger.Warn("failed to unfreeze slots", slog.Uint64("shard", uint64(src)), slog.String("error", err.Error()))
+		}
+	}
+	if err := h.applyMap(h.currentMap().AbortMove(slots)); err != nil {
+		h.logger.Warn("failed to abort slot move", slog.String("error", err.Error()))
+	}
+}
+
+// callGroup POSTs body to path on the leader of shard id and decodes the
+// response into out. It starts from the management address of any member,
+// follows 421 redirects to the leader, and retries with backoff while the
+// group has no leader or isn't running on its members yet.
+func (h *ShardHost) callGroup(ctx context.Context, id sharding.ShardID, path string, body, out any) error {
+	backoff := shardCallInitialBackoff
+	for {
+		var lastErr error
+		for _, addr := range h.groupAddrs(id) {
+			lastErr = callLeader(ctx, addr, path, body, out, h.meta.NodeID())
+			if lastErr == nil {
+				return nil
+			}
+			if !retryableStatus(lastErr) {
+				return lastErr
+			}
+		}
+		if lastErr == nil {
+			lastErr = fmt.Errorf("shard %d: no member addresses known", id)
+		}
+
+		select {
+		case <-ctx.Done():
+			return fmt.Errorf("%s on shard %d: %w", path, id, lastErr)
+		case <-time.After(backoff):
+		}
+		backoff = min(backoff*2, shardCallMaxBackoff)
+	}
+}
+
+// groupAddrs returns the management addresses of the members of shard id.
+func (h *ShardHost) groupAddrs(id sharding.ShardID) []string {
+	var members []string
+	if id == sharding.MetaShard {
+		members = []string{h.meta.NodeID()}
+	} else if shard, ok := h.currentMap().Shards[id]; ok {
+		members = shard.Members
+	}
+	var addrs []string
+	for _, member := range members {
+		if meta, ok := h.meta.NodeMeta(member); ok && meta.HTTPAddr != "" {
+			addrs = append(addrs, meta.HTTPAddr)
+		}
+	}
+	return addrs
+}
+
+// callLeader is exchangeJSON following up to joinMaxRedirects 421 redirects.
+func callLeader(ctx context.Context, addr, path string, body, out any, forwardedBy string) error {
+	for redirects := 0; ; redirects++ {
+		err := exchangeJSON(ctx, addr, path, body, out, forwardedBy)
+
+		var statusErr *StatusError
+		if !errors.As(err, &statusErr) ||
+			statusErr.StatusCode != http.StatusMisdirectedRequest ||
+			statusErr.LeaderHTTP == "" ||
+			redirects == joinMaxRedirects {
+			return err
+		}
+		addr = statusErr.LeaderHTTP
+	}
+}
+
+// retryableStatus reports whether a failed management call may succeed
+// later: the node was unreachable, had no leader, didn't run the group yet or
+// didn't know where its leader was.
+func retryableStatus(err error) bool {
+	var statusErr *StatusError
+	if !errors.As(err, &statusErr) {
+		return true
+	}
+	switch statusErr.StatusCode {
+	case http.StatusServiceUnavailable, http.StatusNotFound, http.StatusMisdirectedRequest:
+		return true
+	default:
+		return false
+	}
+}
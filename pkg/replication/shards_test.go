@@ -0,0 +1,98 @@
+package replication
+
+import (
+	"bytes"
+	"context"
+	"io"
+	"testing"
+
+	"github.com/hashicorp/raft"
+	"github.com/mateenbagheri/memorabilia/pkg/sharding"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// applyRaw is applyCmd returning the FSM's response, errors included.
+func applyRaw(t *testing.T, fsm *FSM, cmd *RaftCommand) any {
+	t.Helper()
+	b, err := cmd.Encode()
+	require.NoError(t, err)
+	return fsm.Apply(&raft.Log{Data: b})
+}
+
+func TestFSM_SetShardMap_RequiresNextVersion(t *testing.T) {
+	fsm := newTestFSM(t)
+	assert.Nil(t, fsm.ShardMap())
+
+	m := sharding.NewMap(0)
+	applyCmd(t, fsm, &RaftCommand{Op: OpSetShardMap, ShardMap: m})
+	assert.Equal(t, uint64(1), fsm.ShardMap().Version)
+
+	// Derived from version 1, like another admin's concurrent change.
+	a, err := m.AddShard(1, []string{"n1"})
+	require.NoError(t, err)
+	b, err := m.AddShard(2, []string{"n2"})
+	require.NoError(t, err)
+
+	applyCmd(t, fsm, &RaftCommand{Op: OpSetShardMap, ShardMap: a})
+	res := applyRaw(t, fsm, &RaftCommand{Op: OpSetShardMap, ShardMap: b})
+	err, _ = res.(error)
+	assert.ErrorIs(t, err, sharding.ErrConflict)
+	assert.Contains(t, fsm.ShardMap().Shards, sharding.ShardID(1))
+	assert.NotContains(t, fsm.ShardMap().Shards, sharding.ShardID(2))
+}
+
+func TestFSM_MoveSlotOps(t *testing.T) {
+	src, dst := newTestFSM(t), newTestFSM(t)
+	ctx := context.Background()
+	slot := sharding.SlotOf("moving")
+
+	applyCmd(t, src, &RaftCommand{Op: OpSet, Key: "moving", Value: "v"})
+	applyCmd(t, src, &RaftCommand{Op: OpSet, Key: "staying", Value: "v"})
+	require.NotEqual(t, slot, sharding.SlotOf("staying"))
+
+	applyCmd(t, src, &RaftCommand{Op: OpFreezeSlots, Slots: []int{slot}})
+	assert.Equal(t, []int{slot}, src.FrozenSlots())
+
+	res := applyRaw(t, src, &RaftCommand{Op: OpSet, Key: "moving", Value: "late"})
+	err, _ := res.(error)
+	assert.ErrorIs(t, err, ErrSlotFrozen, "writes to a frozen slot are refused")
+	applyCmd(t, src, &RaftCommand{Op: OpSet, Key: "staying", Value: "still writable"})
+
+	entries, err := src.SlotEntries([]int{slot})
+	require.NoError(t, err)
+	require.Len(t, entries, 1)
+	assert.Contains(t, entries, "moving")
+
+	applyCmd(t, dst, &RaftCommand{Op: OpLoad, Entries: entries})
+	got, err := dst.Repository().Get(ctx, "moving")
+	require.NoError(t, err)
+	assert.Equal(t, "v", got)
+
+	res = applyRaw(t, src, &RaftCommand{Op: OpDropSlots, Slots: []int{slot}})
+	assert.Equal(t, ApplyResponse{Applied: true, DeleteCount: 1}, res)
+	assert.Empty(t, src.FrozenSlots(), "dropping unfreezes")
+	_, err = src.Repository().Get(ctx, "moving")
+	assert.Error(t, err)
+	_, err = src.Repository().Get(ctx, "staying")
+	assert.NoError(t, err)
+}
+
+func TestFSM_Snapshot_IncludesShardState(t *testing.T) {
+	fsm := newTestFSM(t)
+	m, err := sharding.NewMap(0).AddShard(1, []string{"n1"})
+	require.NoError(t, err)
+	m.Version = 1
+	applyCmd(t, fsm, &RaftCommand{Op: OpSetShardMap, ShardMap: m})
+	applyCmd(t, fsm, &RaftCommand{Op: OpFreezeSlots, Slots: []int{3, 4}})
+
+	snap, err := fsm.Snapshot()
+	require.NoError(t, err)
+	var buf bytes.Buffer
+	require.NoError(t, snap.Persist(&testSnapshotSink{buf: &buf}))
+
+	restored := newTestFSM(t)
+	require.NoError(t, restored.Restore(io.NopCloser(&buf)))
+	assert.Equal(t, m, restored.ShardMap())
+	assert.Equal(t, []int{3, 4}, restored.FrozenSlots())
+}
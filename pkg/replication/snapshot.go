@@ -1,19 +1,62 @@
 package replication
 
 import (
+	"bytes"
 	"encoding/json"
 	"fmt"
+	"io"
+	"time"
 
 	"github.com/hashicorp/raft"
+	"github.com/mateenbagheri/memorabilia/pkg/cluster"
+	"github.com/mateenbagheri/memorabilia/pkg/sharding"
 	"github.com/mateenbagheri/memorabilia/pkg/types"
 )
 
+// snapshotFormat is the version written into every snapshot envelope.
+// Bump it whenever snapshotState changes shape in a way older code can't read.
+const snapshotFormat = 2
+
+// snapshotState is the on-disk layout of an FSM snapshot.
+//
+// The first snapshots were just the repository's data map encoded as a JSON
+// object. Once the FSM grew state of its own (cluster member metadata) it
+// needed an envelope; decodeSnapshot still understands the bare-map layout,
+// so nodes restoring from snapshots written by older builds keep working.
+type snapshotState struct {
+	Format int                                 `json:"format"`
+	Data   map[string]types.ColumnValueWithTTL `json:"data"`
+	Nodes  map[string]cluster.NodeMeta         `json:"nodes,omitempty"`
+
+	// Sessions are the client sessions of the ephemeral keys in Data.
+	Sessions map[string]types.Session `json:"sessions,omitempty"`
+
+	// Clock is the cluster clock at the snapshot's index. Zero in snapshots
+	// written before the clock existed; the first applied entry sets it.
+	Clock time.Time `json:"clock"`
+
+	// ShardMap is the shard map, in the metadata group of a sharded
+	// cluster. Frozen are the slots the group refuses writes to because
+	// they are being moved to another shard.
+	ShardMap *sharding.Map `json:"shard_map,omitempty"`
+	Frozen   []int         `json:"frozen_slots,omitempty"`
+
+	// StandbyPosition is how far a standby cluster has replicated its
+	// primary's log; 0 on a cluster that isn't one.
+	StandbyPosition uint64 `json:"standby_position,omitempty"`
+
+	// LockTokens are the last fencing tokens given to each lock key, held
+	// or not. Missing from older snapshots, which only have those of the
+	// locks held, in Data.
+	LockTokens map[string]uint64 `json:"lock_tokens,omitempty"`
+}
+
 type fsmSnapshot struct {
-	data map[string]types.ColumnValueWithTTL
+	state snapshotState
 }
 
 func (f *fsmSnapshot) Persist(sink raft.SnapshotSink) error {
-	if err := json.NewEncoder(sink).Encode(f.data); err != nil {
+	if err := json.NewEncoder(sink).Encode(f.state); err != nil {
 		_ = sink.Cancel()
 		return fmt.Errorf("snapshot persist: %w", err)
 	}
@@ -21,3 +64,33 @@ func (f *fsmSnapshot) Persist(sink raft.SnapshotSink) error {
 }
 
 func (f *fsmSnapshot) Release() {}
+
+// decodeSnapshot reads either snapshot layout from r.
+func decodeSnapshot(r io.Reader) (snapshotState, error) {
+	raw, err := io.ReadAll(r)
+	if err != nil {
+		return snapshotState{}, fmt.Errorf("read: %w", err)
+	}
+
+	// In the legacy layout "format" would be a user key whose value is an
+	// object, so probing for a numeric format field can't be fooled by data.
+	var probe struct {
+		Format int `json:"format"`
+	}
+	if err := json.Unmarshal(raw, &probe); err == nil && probe.Format > 0 {
+		if probe.Format > snapshotFormat {
+			return snapshotState{}, fmt.Errorf("unsupported snapshot format %d", probe.Format)
+		}
+		var state snapshotState
+		if err := json.NewDecoder(bytes.NewReader(raw)).Decode(&state); err != nil {
+			return snapshotState{}, fmt.Errorf("decode: %w", err)
+		}
+		return state, nil
+	}
+
+	var data map[string]types.ColumnValueWithTTL
+	if err := json.Unmarshal(raw, &data); err != nil {
+		return snapshotState{}, fmt.Errorf("decode legacy: %w", err)
+	}
+	return snapshotState{Format: 1, Data: data}, nil
+}
@@ -0,0 +1,132 @@
+package replication
+
+import (
+	"errors"
+	"fmt"
+	"strconv"
+	"time"
+
+	"github.com/hashicorp/raft"
+)
+
+var (
+	// ErrNotRunning is returned by Readiness once Raft has shut down.
+	ErrNotRunning = errors.New("raft is shut down")
+
+	// ErrNoLeader is returned by Readiness while no leader is known.
+	ErrNoLeader = errors.New("no leader")
+)
+
+// Stats is a snapshot of this node's Raft and FSM state, as served on
+// /raft/stats.
+type Stats struct {
+	NodeID         string `json:"node_id"`
+	State          string `json:"state"`
+	LeaderID       string `json:"leader_id"`
+	LeaderRaftAddr string `json:"leader_raft_addr"`
+	Term           uint64 `json:"term"`
+
+	LastLogIndex uint64 `json:"last_log_index"`
+	LastLogTerm  uint64 `json:"last_log_term"`
+	CommitIndex  uint64 `json:"commit_index"`
+	AppliedIndex uint64 `json:"applied_index"`
+
+	// FSMPending is the number of committed batches queued for the FSM.
+	FSMPending uint64 `json:"fsm_pending"`
+
+	LastSnapshotIndex uint64 `json:"last_snapshot_index"`
+	LastSnapshotTerm  uint64 `json:"last_snapshot_term"`
+	NumPeers          uint64 `json:"num_peers"`
+
+	// LastContact is when the leader was last heard from: zero on the leader
+	// itself and on a node that never heard from one. StalenessMs is the
+	// same as an age (see Node.Staleness), -1 for never.
+	LastContact time.Time `json:"last_contact"`
+	StalenessMs int64     `json:"staleness_ms"`
+
+	// ClusterClock is the replicated time expiry is evaluated against.
+	ClusterClock time.Time `json:"cluster_clock"`
+
+	// Raft is raft.Stats() verbatim, for everything not broken out above.
+	Raft map[string]string `json:"raft"`
+}
+
+// Stats collects this node's current Raft and FSM state.
+func (n *Node) Stats() Stats {
+	raw := n.raft.Stats()
+	leaderAddr, leaderID := n.raft.LeaderWithID()
+
+	s := Stats{
+		NodeID:            n.cfg.NodeID,
+		State:             n.raft.State().String(),
+		LeaderID:          string(leaderID),
+		LeaderRaftAddr:    string(leaderAddr),
+		Term:              statUint(raw, "term"),
+		LastLogIndex:      statUint(raw, "last_log_index"),
+		LastLogTerm:       statUint(raw, "last_log_term"),
+		CommitIndex:       statUint(raw, "commit_index"),
+		AppliedIndex:      statUint(raw, "applied_index"),
+		FSMPending:        statUint(raw, "fsm_pending"),
+		LastSnapshotIndex: statUint(raw, "last_snapshot_index"),
+		LastSnapshotTerm:  statUint(raw, "last_snapshot_term"),
+		NumPeers:          statUint(raw, "num_peers"),
+		StalenessMs:       -1,
+		ClusterClock:      n.fsm.Clock().Now(),
+		Raft:              raw,
+	}
+	if !n.IsLeader() {
+		s.LastContact = n.raft.LastContact()
+	}
+	if staleness, ok := n.Staleness(); ok {
+		s.StalenessMs = staleness.Milliseconds()
+	}
+	return s
+}
+
+// statUint reads a numeric raft.Stats entry; missing or malformed reads as 0.
+func statUint(stats map[string]string, key string) uint64 {
+	v, _ := strconv.ParseUint(stats[key], 10, 64)
+	return v
+}
+
+// Readiness reports why this node shouldn't be sent traffic yet, or nil when
+// it should. A node is ready once
+//   - Raft is running and a leader is known,
+//   - a follower has heard from that leader within the heartbeat timeout,
+//   - it has applied everything it knows to be committed, give or take
+//     cluster.Config.ReadyMaxLag entries, and has applied anything at all: a
+//     node that just joined and is still waiting for its first entries or a
+//     snapshot would otherwise look caught up at index 0.
+func (n *Node) Readiness() error {
+	if n.raft.State() == raft.Shutdown {
+		return ErrNotRunning
+	}
+	if _, id := n.raft.LeaderWithID(); id == "" {
+		return ErrNoLeader
+	}
+
+	if !n.IsLeader() {
+		limit := n.raft.ReloadableConfig().HeartbeatTimeout
+		staleness, ok := n.Staleness()
+		if !ok {
+			return errors.New("never heard from the leader")
+		}
+		if staleness > limit {
+			return fmt.Errorf("leader last heard from %s ago, more than %s", staleness.Round(time.Millisecond), limit)
+		}
+	}
+
+	return applyLag(n.raft.AppliedIndex(), n.raft.CommitIndex(), n.cfg.ReadyMaxLag)
+}
+
+// applyLag is Readiness' check of the applied index against the commit
+// index, allowing maxLag entries still to be applied.
+func applyLag(applied, commit, maxLag uint64) error {
+	if applied == 0 {
+		return errors.New("nothing applied yet")
+	}
+	if applied < commit && commit-applied > maxLag {
+		return fmt.Errorf("applied index %d behind commit index %d by more than %d", applied, commit, maxLag)
+	}
+	return nil
+}
@@ -0,0 +1,153 @@
+package replication
+
+import (
+	"net"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/mateenbagheri/memorabilia/pkg/cluster"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// newTestNode starts an in-memory Node on a loopback port. bootstrap makes it
+// a single-node cluster of its own; otherwise it waits for a leader forever.
+func newTestNode(t *testing.T, bootstrap bool) *Node {
+	t.Helper()
+	return startTestNode(t, testNodeConfig(t, bootstrap))
+}
+
+// testNodeConfig returns the configuration newTestNode starts a node with.
+func testNodeConfig(t *testing.T, bootstrap bool) *cluster.Config {
+	t.Helper()
+
+	l, err := net.Listen("tcp", "127.0.0.1:0")
+	require.NoError(t, err)
+	addr := l.Addr().String()
+	require.NoError(t, l.Close())
+
+	cfg := &cluster.Config{
+		NodeID:             "n1",
+		RaftBindAddr:       addr,
+		Bootstrap:          bootstrap,
+		Storage:            cluster.StorageMemory,
+		HeartbeatTimeout:   50 * time.Millisecond,
+		ElectionTimeout:    50 * time.Millisecond,
+		LeaderLeaseTimeout: 50 * time.Millisecond,
+	}
+	return cfg
+}
+
+// startTestNode starts a Node with cfg.
+func startTestNode(t *testing.T, cfg *cluster.Config) *Node {
+	t.Helper()
+
+	node, err := NewNode(cfg, newTestFSM(t), discardLogger())
+	require.NoError(t, err)
+	return node
+}
+
+func TestNode_Readiness_Leader(t *testing.T) {
+	node := newTestNode(t, true)
+	defer node.Shutdown()
+
+	require.Eventually(t, func() bool { return node.Readiness() == nil }, 5*time.Second, 10*time.Millisecond)
+
+	stats := node.Stats()
+	assert.Equal(t, "n1", stats.NodeID)
+	assert.Equal(t, "Leader", stats.State)
+	assert.Equal(t, "n1", stats.LeaderID)
+	assert.NotZero(t, stats.Term)
+	assert.GreaterOrEqual(t, stats.AppliedIndex, stats.CommitIndex)
+	assert.Zero(t, stats.StalenessMs)
+	assert.True(t, stats.LastContact.IsZero())
+	assert.Contains(t, stats.Raft, "latest_configuration", "raw raft stats are included")
+}
+
+func TestNode_Readiness_NoLeader(t *testing.T) {
+	node := newTestNode(t, false)
+	defer node.Shutdown()
+
+	assert.ErrorIs(t, node.Readiness(), ErrNoLeader)
+
+	stats := node.Stats()
+	assert.Equal(t, "Follower", stats.State)
+	assert.Equal(t, int64(-1), stats.StalenessMs)
+}
+
+func TestNode_Readiness_AfterShutdown(t *testing.T) {
+	node := newTestNode(t, true)
+	require.NoError(t, node.Shutdown())
+
+	assert.ErrorIs(t, node.Readiness(), ErrNotRunning)
+}
+
+func TestNode_Shutdown_Twice(t *testing.T) {
+	node := newTestNode(t, true)
+	require.NoError(t, node.Shutdown())
+	assert.NoError(t, node.Shutdown())
+}
+
+func TestNode_ForgetRemoved(t *testing.T) {
+	node := newTestNode(t, true)
+	defer node.Shutdown()
+	require.Eventually(t, node.IsLeader, 5*time.Second, 10*time.Millisecond)
+
+	// n2 was removed, but its metadata outlived it.
+	require.NoError(t, node.RegisterMeta(cluster.NodeMeta{NodeID: "n2", RaftAddr: "127.0.0.1:1"}))
+	require.NoError(t, node.RegisterMeta(node.cfg.Meta()))
+
+	require.NoError(t, node.forgetRemoved())
+	_, ok := node.NodeMeta("n2")
+	assert.False(t, ok)
+	_, ok = node.NodeMeta("n1")
+	assert.True(t, ok, "a member keeps its metadata")
+}
+
+func TestNode_RunClock_TicksOnlyWhileDeadlinesPend(t *testing.T) {
+	cfg := testNodeConfig(t, true)
+	cfg.ClockTickInterval = 20 * time.Millisecond
+	node := startTestNode(t, cfg)
+	defer node.Shutdown()
+	// Let the new leader register its metadata, which moves the clock too.
+	require.Eventually(t, func() bool {
+		_, ok := node.NodeMeta("n1")
+		return ok
+	}, 5*time.Second, 10*time.Millisecond)
+
+	_, err := node.Apply(&RaftCommand{Op: OpSet, Key: "k", Value: "v"})
+	require.NoError(t, err)
+	idle := node.fsm.Clock().Now()
+	time.Sleep(10 * cfg.ClockTickInterval)
+	assert.Equal(t, idle, node.fsm.Clock().Now(), "nothing waits on the clock")
+
+	_, err = node.Apply(&RaftCommand{Op: OpSet, Key: "k", Value: "v", Expiration: time.Now().Add(time.Minute)})
+	require.NoError(t, err)
+	set := node.fsm.Clock().Now()
+	assert.Eventually(t, func() bool { return node.fsm.Clock().Now().After(set) }, 5*time.Second, 10*time.Millisecond,
+		"a key with a TTL keeps the clock moving")
+}
+
+func TestNewNode_MemoryBootstrapsOnce(t *testing.T) {
+	cfg := testNodeConfig(t, true)
+	cfg.DataDir = t.TempDir()
+	node := startTestNode(t, cfg)
+	require.NoError(t, node.Shutdown())
+	assert.FileExists(t, filepath.Join(cfg.DataDir, bootstrapMarker))
+
+	_, err := NewNode(cfg, newTestFSM(t), discardLogger())
+	assert.Error(t, err, "a restart must not bootstrap a second cluster")
+}
+
+func TestApplyLag(t *testing.T) {
+	assert.Error(t, applyLag(0, 0, 10), "nothing applied yet")
+	assert.NoError(t, applyLag(5, 5, 0))
+	assert.NoError(t, applyLag(6, 5, 0), "the commit index a follower knows can trail its applied index")
+	assert.Error(t, applyLag(4, 5, 0))
+
+	assert.NoError(t, applyLag(90, 100, 10), "within the allowed lag")
+	err := applyLag(89, 100, 10)
+	require.Error(t, err)
+	assert.Contains(t, err.Error(), "applied index 89 behind commit index 100")
+}
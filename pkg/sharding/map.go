@@ -0,0 +1,420 @@
+// Package sharding partitions the keyspace across independent Raft groups.
+//
+// Every key hashes to one of SlotCount slots, and every slot is owned by one
+// shard. A shard is a Raft group of its own, replicated on the nodes listed
+// as its members. Shard 0 is the metadata group every node belongs to: it
+// replicates the Map itself and, like before sharding existed, owns every
+// slot until some are moved elsewhere.
+//
+// Map values are immutable. The methods that change one return a copy with
+// Version bumped, which the metadata group applies only on top of the version
+// it was built from, so two concurrent admin operations can't overwrite each
+// other.
+package sharding
+
+import (
+	"errors"
+	"fmt"
+	"hash/crc32"
+	"net"
+	"slices"
+	"sort"
+	"strconv"
+	"strings"
+)
+
+// SlotCount is the number of hash slots the keyspace is divided into. It
+// bounds how finely keys can be spread and can't change on a live cluster.
+const SlotCount = 1024
+
+// MetaShard is the metadata group.
+const MetaShard ShardID = 0
+
+// DefaultPortStride is the default distance between the Raft ports of a
+// node's shards, see Map.RaftAddr.
+const DefaultPortStride = 100
+
+var (
+	// ErrConflict is returned when a map is applied on top of a version other
+	// than the one it was derived from.
+	ErrConflict = errors.New("shard map changed concurrently")
+
+	ErrUnknownShard = errors.New("unknown shard")
+)
+
+// ShardID identifies a shard.
+type ShardID uint32
+
+// Shard is one Raft group's entry in the map.
+type Shard struct {
+	ID ShardID `json:"id"`
+
+	// Members are the node IDs the group runs on. Empty for MetaShard, whose
+	// members are simply the cluster's.
+	Members []string `json:"members,omitempty"`
+
+	// Bootstrap lists the members that form a brand-new group together.
+	// It is cleared by the first membership change; members added after
+	// that join through the group's leader and receive its snapshot.
+	Bootstrap []string `json:"bootstrap,omitempty"`
+}
+
+// HasMember reports whether nodeID is one of the shard's members.
+func (s Shard) HasMember(nodeID string) bool {
+	return slices.Contains(s.Members, nodeID)
+}
+
+// Map assigns every slot to a shard. The zero value is not valid; use
+// NewMap, and treat a nil *Map as "not sharded": everything in MetaShard.
+type Map struct {
+	Version uint64            `json:"version"`
+	Shards  map[ShardID]Shard `json:"shards"`
+
+	// Slots[i] is the shard that owns slot i.
+	Slots []ShardID `json:"slots"`
+
+	// Migrating maps slots being moved to their destination shard. Until the
+	// move completes they are still served by their owner, which refuses
+	// writes to them.
+	Migrating map[int]ShardID `json:"migrating,omitempty"`
+
+	// PortStride is the distance between the Raft ports of a node's shards.
+	PortStride int `json:"port_stride"`
+}
+
+// NewMap returns version 1 of a map in which MetaShard owns every slot.
+func NewMap(portStride int) *Map {
+	if portStride <= 0 {
+		portStride = DefaultPortStride
+	}
+	return &Map{
+		Version:    1,
+		Shards:     map[ShardID]Shard{MetaShard: {ID: MetaShard}},
+		Slots:      make([]ShardID, SlotCount),
+		PortStride: portStride,
+	}
+}
+
+// SlotOf returns the slot key hashes to.
+func SlotOf(key string) int {
+	return int(crc32.ChecksumIEEE([]byte(key)) % SlotCount)
+}
+
+// ShardFor returns the shard owning key. A nil map puts every key in
+// MetaShard.
+func (m *Map) ShardFor(key string) ShardID {
+	if m == nil {
+		return MetaShard
+	}
+	return m.Slots[SlotOf(key)]
+}
+
+// IsMigrating reports whether key's slot is being moved to another shard.
+func (m *Map) IsMigrating(key string) bool {
+	if m == nil {
+		return false
+	}
+	_, ok := m.Migrating[SlotOf(key)]
+	return ok
+}
+
+// IDs returns the IDs of every shard, in ascending order.
+func (m *Map) IDs() []ShardID {
+	if m == nil {
+		return []ShardID{MetaShard}
+	}
+	ids := make([]ShardID, 0, len(m.Shards))
+	for id := range m.Shards {
+		ids = append(ids, id)
+	}
+	slices.Sort(ids)
+	return ids
+}
+
+// SlotsOf returns the slots owned by shard id, in ascending order.
+func (m *Map) SlotsOf(id ShardID) []int {
+	var slots []int
+	for slot, owner := range m.Slots {
+		if owner == id {
+			slots = append(slots, slot)
+		}
+	}
+	return slots
+}
+
+// RaftAddr returns the Raft address of shard id on the node whose metadata
+// group listens on addr: the same host, PortStride*id ports higher.
+func (m *Map) RaftAddr(addr string, id ShardID) (string, error) {
+	host, portStr, err := net.SplitHostPort(addr)
+	if err != nil {
+		return "", fmt.Errorf("shard raft addr: %w", err)
+	}
+	port, err := strconv.Atoi(portStr)
+	if err != nil {
+		return "", fmt.Errorf("shard raft addr: port %q: %w", portStr, err)
+	}
+	port += m.PortStride * int(id)
+	if port > 65535 {
+		return "", fmt.Errorf("shard raft addr: port %d of shard %d out of range", port, id)
+	}
+	return net.JoinHostPort(host, strconv.Itoa(port)), nil
+}
+
+// Clone returns a deep copy of m.
+func (m *Map) Clone() *Map {
+	c := &Map{
+		Version:    m.Version,
+		Shards:     make(map[ShardID]Shard, len(m.Shards)),
+		Slots:      slices.Clone(m.Slots),
+		PortStride: m.PortStride,
+	}
+	for id, s := range m.Shards {
+		s.Members = slices.Clone(s.Members)
+		s.Bootstrap = slices.Clone(s.Bootstrap)
+		c.Shards[id] = s
+	}
+	if len(m.Migrating) > 0 {
+		c.Migrating = make(map[int]ShardID, len(m.Migrating))
+		for slot, to := range m.Migrating {
+			c.Migrating[slot] = to
+		}
+	}
+	return c
+}
+
+// next returns a copy of m to modify into the following version.
+func (m *Map) next() *Map {
+	c := m.Clone()
+	c.Version++
+	return c
+}
+
+// AddShard returns a map with a new, empty shard id running on members. The
+// members bootstrap the group together.
+func (m *Map) AddShard(id ShardID, members []string) (*Map, error) {
+	if _, ok := m.Shards[id]; ok || id == MetaShard {
+		return nil, fmt.Errorf("add shard %d: already exists", id)
+	}
+	if len(members) == 0 {
+		return nil, fmt.Errorf("add shard %d: no members", id)
+	}
+	if err := checkMembers(members); err != nil {
+		return nil, fmt.Errorf("add shard %d: %w", id, err)
+	}
+	c := m.next()
+	c.Shards[id] = Shard{ID: id, Members: slices.Clone(members), Bootstrap: slices.Clone(members)}
+	return c, nil
+}
+
+// RemoveShard returns a map without shard id, which must own no slots.
+func (m *Map) RemoveShard(id ShardID) (*Map, error) {
+	if _, ok := m.Shards[id]; !ok {
+		return nil, fmt.Errorf("remove shard %d: %w", id, ErrUnknownShard)
+	}
+	if id == MetaShard {
+		return nil, fmt.Errorf("remove shard %d: the metadata shard can't be removed", id)
+	}
+	if slots := m.SlotsOf(id); len(slots) > 0 {
+		return nil, fmt.Errorf("remove shard %d: still owns %d slots", id, len(slots))
+	}
+	for _, to := range m.Migrating {
+		if to == id {
+			return nil, fmt.Errorf("remove shard %d: slots are migrating to it", id)
+		}
+	}
+	c := m.next()
+	delete(c.Shards, id)
+	return c, nil
+}
+
+// MoveReplica returns a map in which member from of shard id is replaced by
+// to. The group's leader then adds to, which catches up from its snapshot,
+// and removes from.
+func (m *Map) MoveReplica(id ShardID, from, to string) (*Map, error) {
+	shard, ok := m.Shards[id]
+	if !ok {
+		return nil, fmt.Errorf("move replica of shard %d: %w", id, ErrUnknownShard)
+	}
+	if id == MetaShard {
+		return nil, fmt.Errorf("move replica of shard %d: metadata shard members are managed with /raft/join and /raft/remove", id)
+	}
+	i := slices.Index(shard.Members, from)
+	if i < 0 {
+		return nil, fmt.Errorf("move replica of shard %d: %q is not a member", id, from)
+	}
+	if to == "" || shard.HasMember(to) {
+		return nil, fmt.Errorf("move replica of shard %d: %q is empty or already a member", id, to)
+	}
+	c := m.next()
+	shard = c.Shards[id]
+	shard.Members[i] = to
+	shard.Bootstrap = nil
+	c.Shards[id] = shard
+	return c, nil
+}
+
+// RemoveNode returns a map in which nodeID is no longer a member of any
+// shard. The groups' leaders then remove it from their configurations. It
+// fails when nodeID is the only member of a shard, whose data would go with
+// it; move that replica elsewhere first.
+func (m *Map) RemoveNode(nodeID string) (*Map, error) {
+	c := m.next()
+	for id, shard := range c.Shards {
+		i := slices.Index(shard.Members, nodeID)
+		if i < 0 {
+			continue
+		}
+		if len(shard.Members) == 1 {
+			return nil, fmt.Errorf("remove node %q: it is the only member of shard %d", nodeID, id)
+		}
+		shard.Members = slices.Delete(shard.Members, i, i+1)
+		shard.Bootstrap = nil
+		c.Shards[id] = shard
+	}
+	return c, nil
+}
+
+// BeginMove returns a map in which slots are marked as migrating to shard
+// to. None of them may be migrating already or owned by to.
+func (m *Map) BeginMove(slots []int, to ShardID) (*Map, error) {
+	if _, ok := m.Shards[to]; !ok {
+		return nil, fmt.Errorf("move slots to shard %d: %w", to, ErrUnknownShard)
+	}
+	if len(slots) == 0 {
+		return nil, fmt.Errorf("move slots to shard %d: no slots", to)
+	}
+	for _, slot := range slots {
+		if slot < 0 || slot >= SlotCount {
+			return nil, fmt.Errorf("move slots: slot %d out of range", slot)
+		}
+		if m.Slots[slot] == to {
+			return nil, fmt.Errorf("move slots: slot %d is already owned by shard %d", slot, to)
+		}
+		if _, ok := m.Migrating[slot]; ok {
+			return nil, fmt.Errorf("move slots: slot %d is already migrating", slot)
+		}
+	}
+	c := m.next()
+	if c.Migrating == nil {
+		c.Migrating = make(map[int]ShardID, len(slots))
+	}
+	for _, slot := range slots {
+		c.Migrating[slot] = to
+	}
+	return c, nil
+}
+
+// CompleteMove returns a map in which the migrating slots are owned by their
+// destination.
+func (m *Map) CompleteMove(slots []int) (*Map, error) {
+	c := m.next()
+	for _, slot := range slots {
+		to, ok := c.Migrating[slot]
+		if !ok {
+			return nil, fmt.Errorf("complete move: slot %d is not migrating", slot)
+		}
+		c.Slots[slot] = to
+		delete(c.Migrating, slot)
+	}
+	if len(c.Migrating) == 0 {
+		c.Migrating = nil
+	}
+	return c, nil
+}
+
+// AbortMove returns a map in which slots are no longer migrating and stay
+// with their owner.
+func (m *Map) AbortMove(slots []int) *Map {
+	c := m.next()
+	for _, slot := range slots {
+		delete(c.Migrating, slot)
+	}
+	if len(c.Migrating) == 0 {
+		c.Migrating = nil
+	}
+	return c
+}
+
+// BySource groups slots by the shard that currently owns them.
+func (m *Map) BySource(slots []int) map[ShardID][]int {
+	groups := make(map[ShardID][]int)
+	for _, slot := range slots {
+		owner := m.Slots[slot]
+		groups[owner] = append(groups[owner], slot)
+	}
+	return groups
+}
+
+func checkMembers(members []string) error {
+	seen := make(map[string]bool, len(members))
+	for _, id := range members {
+		if id == "" {
+			return errors.New("empty member ID")
+		}
+		if seen[id] {
+			return fmt.Errorf("member %q listed twice", id)
+		}
+		seen[id] = true
+	}
+	return nil
+}
+
+// FormatSlots formats sorted slots the way ParseSlots reads them, collapsing
+// runs into ranges.
+func FormatSlots(slots []int) string {
+	var b strings.Builder
+	for i := 0; i < len(slots); {
+		j := i
+		for j+1 < len(slots) && slots[j+1] == slots[j]+1 {
+			j++
+		}
+		if b.Len() > 0 {
+			b.WriteByte(',')
+		}
+		b.WriteString(strconv.Itoa(slots[i]))
+		if j > i {
+			b.WriteByte('-')
+			b.WriteString(strconv.Itoa(slots[j]))
+		}
+		i = j + 1
+	}
+	return b.String()
+}
+
+// ParseSlots parses a comma-separated list of slots and inclusive ranges,
+// such as "0-99,512,700-701", into sorted, distinct slots.
+func ParseSlots(s string) ([]int, error) {
+	set := make(map[int]bool)
+	for _, part := range strings.Split(s, ",") {
+		part = strings.TrimSpace(part)
+		if part == "" {
+			continue
+		}
+		lo, hi, isRange := strings.Cut(part, "-")
+		first, err := strconv.Atoi(lo)
+		if err != nil {
+			return nil, fmt.Errorf("parse slots: %q: %w", part, err)
+		}
+		last := first
+		if isRange {
+			if last, err = strconv.Atoi(hi); err != nil {
+				return nil, fmt.Errorf("parse slots: %q: %w", part, err)
+			}
+		}
+		if first < 0 || last >= SlotCount || first > last {
+			return nil, fmt.Errorf("parse slots: %q: want slots between 0 and %d", part, SlotCount-1)
+		}
+		for slot := first; slot <= last; slot++ {
+			set[slot] = true
+		}
+	}
+	if len(set) == 0 {
+		return nil, errors.New("parse slots: no slots given")
+	}
+	slots := make([]int, 0, len(set))
+	for slot := range set {
+		slots = append(slots, slot)
+	}
+	sort.Ints(slots)
+	return slots, nil
+}
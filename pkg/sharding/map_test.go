@@ -0,0 +1,140 @@
+package sharding
+
+import (
+	"encoding/json"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestNilMap_IsUnsharded(t *testing.T) {
+	var m *Map
+	assert.Equal(t, MetaShard, m.ShardFor("anything"))
+	assert.False(t, m.IsMigrating("anything"))
+	assert.Equal(t, []ShardID{MetaShard}, m.IDs())
+}
+
+func TestSlotOf_IsStableAndInRange(t *testing.T) {
+	for _, key := range []string{"", "a", "user:1", "user:2"} {
+		slot := SlotOf(key)
+		assert.GreaterOrEqual(t, slot, 0)
+		assert.Less(t, slot, SlotCount)
+		assert.Equal(t, slot, SlotOf(key))
+	}
+}
+
+func TestMap_AddAndMoveSlots(t *testing.T) {
+	m := NewMap(0)
+	assert.Equal(t, DefaultPortStride, m.PortStride)
+	assert.Len(t, m.SlotsOf(MetaShard), SlotCount)
+
+	m2, err := m.AddShard(1, []string{"n1", "n2"})
+	require.NoError(t, err)
+	assert.Equal(t, m.Version+1, m2.Version)
+	assert.NotContains(t, m.Shards, ShardID(1), "the original is unchanged")
+	assert.Equal(t, []string{"n1", "n2"}, m2.Shards[1].Bootstrap)
+
+	_, err = m2.AddShard(1, []string{"n3"})
+	assert.Error(t, err)
+	_, err = m2.AddShard(2, []string{"n3", "n3"})
+	assert.Error(t, err)
+
+	key := "user:1"
+	slot := SlotOf(key)
+
+	m3, err := m2.BeginMove([]int{slot}, 1)
+	require.NoError(t, err)
+	assert.True(t, m3.IsMigrating(key))
+	assert.Equal(t, MetaShard, m3.ShardFor(key), "the owner serves a slot until the move completes")
+
+	_, err = m3.BeginMove([]int{slot}, 1)
+	assert.Error(t, err, "already migrating")
+
+	m4, err := m3.CompleteMove([]int{slot})
+	require.NoError(t, err)
+	assert.False(t, m4.IsMigrating(key))
+	assert.Nil(t, m4.Migrating)
+	assert.Equal(t, ShardID(1), m4.ShardFor(key))
+	assert.Equal(t, []int{slot}, m4.SlotsOf(1))
+
+	_, err = m4.RemoveShard(1)
+	assert.Error(t, err, "shard still owns a slot")
+
+	aborted := m3.AbortMove([]int{slot})
+	assert.False(t, aborted.IsMigrating(key))
+	assert.Equal(t, MetaShard, aborted.ShardFor(key))
+}
+
+func TestMap_MoveReplica(t *testing.T) {
+	m, err := NewMap(0).AddShard(1, []string{"n1", "n2"})
+	require.NoError(t, err)
+
+	moved, err := m.MoveReplica(1, "n2", "n3")
+	require.NoError(t, err)
+	assert.Equal(t, []string{"n1", "n3"}, moved.Shards[1].Members)
+	assert.Nil(t, moved.Shards[1].Bootstrap, "later members join rather than bootstrap")
+	assert.Equal(t, []string{"n1", "n2"}, m.Shards[1].Members, "the original is unchanged")
+
+	_, err = m.MoveReplica(1, "n9", "n3")
+	assert.Error(t, err)
+	_, err = m.MoveReplica(1, "n1", "n2")
+	assert.Error(t, err)
+	_, err = m.MoveReplica(MetaShard, "n1", "n3")
+	assert.Error(t, err)
+}
+
+func TestMap_RemoveNode(t *testing.T) {
+	m, err := NewMap(0).AddShard(1, []string{"n1", "n2"})
+	require.NoError(t, err)
+	m, err = m.AddShard(2, []string{"n2", "n3"})
+	require.NoError(t, err)
+
+	removed, err := m.RemoveNode("n2")
+	require.NoError(t, err)
+	assert.Equal(t, []string{"n1"}, removed.Shards[1].Members)
+	assert.Equal(t, []string{"n3"}, removed.Shards[2].Members)
+	assert.Nil(t, removed.Shards[1].Bootstrap)
+	assert.Equal(t, m.Version+1, removed.Version)
+	assert.Equal(t, []string{"n1", "n2"}, m.Shards[1].Members, "the original is unchanged")
+
+	_, err = removed.RemoveNode("n1")
+	assert.Error(t, err, "n1 is shard 1's only member")
+}
+
+func TestMap_RaftAddr(t *testing.T) {
+	m := NewMap(100)
+	addr, err := m.RaftAddr("10.0.0.1:7001", 2)
+	require.NoError(t, err)
+	assert.Equal(t, "10.0.0.1:7201", addr)
+
+	_, err = m.RaftAddr("10.0.0.1:65500", 1)
+	assert.Error(t, err)
+}
+
+func TestMap_JSONRoundTrip(t *testing.T) {
+	m, err := NewMap(0).AddShard(3, []string{"n1"})
+	require.NoError(t, err)
+	m, err = m.BeginMove([]int{7, 8}, 3)
+	require.NoError(t, err)
+
+	b, err := json.Marshal(m)
+	require.NoError(t, err)
+	var decoded Map
+	require.NoError(t, json.Unmarshal(b, &decoded))
+	assert.Equal(t, m, &decoded)
+}
+
+func TestParseSlots(t *testing.T) {
+	slots, err := ParseSlots("5, 0-2,2")
+	require.NoError(t, err)
+	assert.Equal(t, []int{0, 1, 2, 5}, slots)
+
+	assert.Equal(t, "0-2,5", FormatSlots(slots))
+	assert.Equal(t, "", FormatSlots(nil))
+
+	for _, bad := range []string{"", "x", "3-1", "0-1024", "-1"} {
+		_, err := ParseSlots(bad)
+		assert.Error(t, err, "input %q", bad)
+	}
+}
@@ -0,0 +1,505 @@
+// Package standby makes a cluster follow another one, for disaster recovery.
+//
+// The standby cluster's leader runs an Agent, which subscribes to the
+// committed writes of the primary cluster (see the Subscribe RPC) and applies
+// them through the standby's own Raft log. Each batch of writes is applied in
+// the same log entry as the primary's log index it brings the standby up to,
+// so that position survives restarts and leader changes along with the data,
+// and the new leader resumes where the old one stopped. When the primary no
+// longer has the writes the standby needs, the agent restores a backup of the
+// primary instead and carries on from there.
+package standby
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"log/slog"
+	"net/http"
+	"strconv"
+	"sync"
+	"time"
+
+	"github.com/mateenbagheri/memorabilia/api"
+	"github.com/mateenbagheri/memorabilia/pkg/replication"
+	"github.com/mateenbagheri/memorabilia/pkg/types"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/credentials/insecure"
+)
+
+const (
+	// progressInterval is how often an idle primary confirms the standby
+	// has all of its writes.
+	progressInterval = time.Second
+
+	// maxBatch caps how many writes go into one log entry of the standby.
+	maxBatch = 256
+
+	// leaderPollInterval is how often a node checks whether it has become,
+	// or stopped being, the standby's leader.
+	leaderPollInterval = 500 * time.Millisecond
+
+	// statsInterval is how often the primary's applied index is read, to
+	// measure how far behind the standby is.
+	statsInterval = 5 * time.Second
+
+	minBackoff = 500 * time.Millisecond
+	maxBackoff = 30 * time.Second
+)
+
+// ErrShardedStandby is returned when the standby cluster has shards: the
+// agent applies everything through the metadata group.
+var ErrShardedStandby = errors.New("a standby cluster can't be sharded")
+
+// Config points an Agent at the primary cluster. Any node of it will do:
+// log indexes are the same on every member.
+type Config struct {
+	// PrimaryAddr is the gRPC address of a primary node, streamed the
+	// writes from.
+	PrimaryAddr string
+
+	// PrimaryMgmtAddr is the HTTP management address of a primary node,
+	// used to download a backup when the standby must resynchronise and to
+	// read how far the primary has got.
+	PrimaryMgmtAddr string
+}
+
+// State is what an Agent is doing.
+type State string
+
+const (
+	// StateIdle is the state on the standby's followers: the leader's agent
+	// does the replicating.
+	StateIdle State = "idle"
+
+	StateFollowing  State = "following"
+	StateResyncing  State = "resyncing"
+	StateRetrying   State = "retrying"
+	StateStopped    State = "stopped"
+	stateNotStarted State = ""
+)
+
+// Status describes an Agent, for the /standby route and metrics.
+type Status struct {
+	Primary string `json:"primary"`
+	State   State  `json:"state"`
+
+	// Position is the primary's log index the standby has applied every
+	// write up to.
+	Position uint64 `json:"position"`
+
+	// PrimaryIndex is the latest index the primary is known to have
+	// applied, and LagEntries how far Position is behind it.
+	PrimaryIndex uint64 `json:"primary_index"`
+	LagEntries   uint64 `json:"lag_entries"`
+
+	// CaughtUpAt is when the standby last had every write of the primary,
+	// and LagSeconds how long ago that was; -1 until it first happens. The
+	// primary confirms it about every second when idle, so a standby that
+	// keeps up shows up to a second or so.
+	CaughtUpAt time.Time `json:"caught_up_at"`
+	LagSeconds float64   `json:"lag_seconds"`
+
+	// AppliedWrites and Resyncs count the writes applied and the backups
+	// restored since this node started.
+	AppliedWrites uint64 `json:"applied_writes"`
+	Resyncs       uint64 `json:"resyncs"`
+
+	LastError string `json:"last_error,omitempty"`
+}
+
+// Agent replicates the primary cluster's writes into the standby cluster
+// whose node it runs on, while that node is the leader.
+type Agent struct {
+	cfg    Config
+	node   *replication.Node
+	fsm    *replication.FSM
+	logger *slog.Logger
+
+	conn     *grpc.ClientConn
+	commands api.CommandsClient
+	http     *http.Client
+
+	mu sync.Mutex
+	// status holds everything but the position while idle, which the FSM
+	// knows better.
+	status Status
+
+	// statsIndex is the applied index last read from the primary's stats.
+	// It counts log entries that carry no writes, such as barriers, which
+	// the stream never confirms; it only raises status.PrimaryIndex when it
+	// changes, or an idle standby would always look a few entries behind.
+	statsIndex uint64
+}
+
+// NewAgent returns an agent applying the writes of the primary described by
+// cfg through node, the standby's metadata group, whose FSM is fsm. Call Run
+// to start it.
+func NewAgent(cfg Config, node *replication.Node, fsm *replication.FSM, logger *slog.Logger) (*Agent, error) {
+	if cfg.PrimaryAddr == "" || cfg.PrimaryMgmtAddr == "" {
+		return nil, fmt.Errorf("standby: both the primary's gRPC and management addresses are needed")
+	}
+	conn, err := grpc.NewClient(cfg.PrimaryAddr, grpc.WithTransportCredentials(insecure.NewCredentials()))
+	if err != nil {
+		return nil, fmt.Errorf("standby: dial %s: %w", cfg.PrimaryAddr, err)
+	}
+	return &Agent{
+		cfg:      cfg,
+		node:     node,
+		fsm:      fsm,
+		logger:   logger.With(slog.String("primary", cfg.PrimaryAddr)),
+		conn:     conn,
+		commands: api.NewCommandsClient(conn),
+		http:     &http.Client{},
+		status:   Status{Primary: cfg.PrimaryAddr, LagSeconds: -1},
+	}, nil
+}
+
+// Close releases the connection to the primary. Stop Run first.
+func (a *Agent) Close() error {
+	return a.conn.Close()
+}
+
+// Run replicates until ctx is done, whenever this node leads the standby.
+func (a *Agent) Run(ctx context.Context) {
+	defer a.update(func(s *Status) { s.State = StateStopped })
+
+	backoff := minBackoff
+	for ctx.Err() == nil {
+		if !a.node.IsLeader() {
+			a.update(func(s *Status) { s.State = StateIdle })
+			sleep(ctx, leaderPollInterval)
+			continue
+		}
+
+		started := time.Now()
+		err := a.follow(ctx)
+		if ctx.Err() != nil {
+			return
+		}
+		if time.Since(started) > maxBackoff {
+			backoff = minBackoff
+		}
+		if err == nil {
+			continue
+		}
+		a.logger.Warn("standby replication failed, retrying",
+			slog.String("error", err.Error()), slog.Duration("backoff", backoff))
+		a.update(func(s *Status) {
+			s.State = StateRetrying
+			s.LastError = err.Error()
+		})
+		sleep(ctx, backoff)
+		backoff = min(backoff*2, maxBackoff)
+	}
+}
+
+// follow streams the primary's writes from the standby's position on, until
+// this node stops leading, the stream fails, or a resync was needed; it
+// returns nil in the latter case, to start over from the new position.
+func (a *Agent) follow(ctx context.Context) error {
+	ctx, cancel := context.WithCancel(ctx)
+	defer cancel()
+	go a.watch(ctx, cancel)
+
+	if m := a.fsm.ShardMap(); m != nil && len(m.Shards) > 1 {
+		return ErrShardedStandby
+	}
+
+	position := a.fsm.StandbyPosition()
+	stream, err := a.commands.Subscribe(ctx, &api.SubscribeRequest{
+		FromIndex:          position + 1,
+		ProgressIntervalMs: progressInterval.Milliseconds(),
+	})
+	if err != nil {
+		return fmt.Errorf("standby: subscribe: %w", err)
+	}
+	a.update(func(s *Status) {
+		s.State = StateFollowing
+		s.Position = position
+	})
+	a.logger.Info("following the primary", slog.Uint64("from_index", position+1))
+
+	events := make(chan *api.ChangeEvent, maxBatch)
+	var recvErr error
+	go func() {
+		defer close(events)
+		for {
+			event, err := stream.Recv()
+			if err != nil {
+				recvErr = err
+				return
+			}
+			select {
+			case events <- event:
+			case <-ctx.Done():
+				return
+			}
+		}
+	}()
+
+	var held *api.ChangeEvent
+	for {
+		event := held
+		held = nil
+		if event == nil {
+			var ok bool
+			if event, ok = <-events; !ok {
+				if ctx.Err() != nil {
+					// Leadership lost, or stopping.
+					return nil
+				}
+				return fmt.Errorf("standby: stream: %w", recvErr)
+			}
+		}
+
+		switch event.GetOp() {
+		case api.ChangeOp_CHANGE_OP_SNAPSHOT:
+			return a.resync(ctx, event.GetIndex())
+		case api.ChangeOp_CHANGE_OP_PROGRESS:
+			a.caughtUp(event.GetIndex())
+			continue
+		}
+
+		// Writes from one log entry come back to back. Only once one from
+		// another entry follows is the last entry known to be complete;
+		// until then the position stays before it, and a restart applies
+		// that entry again, which is harmless.
+		batch := []*api.ChangeEvent{event}
+	drain:
+		for {
+			select {
+			case next, ok := <-events:
+				if !ok {
+					break drain
+				}
+				last := batch[len(batch)-1].GetIndex()
+				if !isWrite(next) || (len(batch) >= maxBatch && next.GetIndex() != last) {
+					held = next
+					break drain
+				}
+				batch = append(batch, next)
+			default:
+				break drain
+			}
+		}
+
+		last := batch[len(batch)-1].GetIndex()
+		complete := held != nil && held.GetIndex() != last
+		if err := a.apply(batch, complete); err != nil {
+			return err
+		}
+	}
+}
+
+// apply applies batch through the standby's log. complete says whether the
+// log entry of its last write is known to be complete.
+func (a *Agent) apply(batch []*api.ChangeEvent, complete bool) error {
+	cmds := make([]*replication.RaftCommand, 0, len(batch))
+	for _, event := range batch {
+		cmd, err := command(event)
+		if err != nil {
+			return err
+		}
+		cmds = append(cmds, cmd)
+	}
+
+	last := batch[len(batch)-1]
+	position := last.GetIndex() - 1
+	if complete {
+		position = last.GetIndex()
+	}
+	position = max(position, a.fsm.StandbyPosition())
+
+	_, err := a.node.Apply(&replication.RaftCommand{
+		Op:       replication.OpStandbyApply,
+		Batch:    cmds,
+		Position: position,
+	})
+	if err != nil {
+		return fmt.Errorf("standby: apply: %w", err)
+	}
+	a.update(func(s *Status) {
+		s.Position = max(s.Position, position)
+		s.PrimaryIndex = max(s.PrimaryIndex, last.GetIndex())
+		s.AppliedWrites += uint64(len(batch))
+	})
+	return nil
+}
+
+// caughtUp records that every write of the primary up to index has been
+// applied, index being the last entry the primary applied.
+func (a *Agent) caughtUp(index uint64) {
+	a.update(func(s *Status) {
+		s.Position = max(s.Position, index)
+		s.PrimaryIndex = s.Position
+		s.CaughtUpAt = time.Now()
+	})
+}
+
+// resync replaces the standby's state with a backup of the primary, after
+// the primary signalled that it no longer has the writes after index.
+func (a *Agent) resync(ctx context.Context, index uint64) error {
+	a.update(func(s *Status) { s.State = StateResyncing })
+	a.logger.Warn("the primary no longer has the writes the standby needs, restoring its backup",
+		slog.Uint64("position", a.fsm.StandbyPosition()), slog.Uint64("boundary", index))
+
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, "http://"+a.cfg.PrimaryMgmtAddr+"/raft/backup", nil)
+	if err != nil {
+		return fmt.Errorf("standby: resync: %w", err)
+	}
+	resp, err := a.http.Do(req)
+	if err != nil {
+		return fmt.Errorf("standby: resync: download backup: %w", err)
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode != http.StatusOK {
+		msg, _ := io.ReadAll(io.LimitReader(resp.Body, 1024))
+		return fmt.Errorf("standby: resync: download backup: %s: %s", resp.Status, msg)
+	}
+	snapshotIndex, err := strconv.ParseUint(resp.Header.Get(replication.SnapshotIndexHeader), 10, 64)
+	if err != nil {
+		return fmt.Errorf("standby: resync: backup index: %w", err)
+	}
+
+	if err := a.node.Restore(resp.Body); err != nil {
+		return fmt.Errorf("standby: resync: %w", err)
+	}
+	// Should this fail, the position is the backup's own, and the next
+	// attempt resyncs again.
+	_, err = a.node.Apply(&replication.RaftCommand{Op: replication.OpStandbyApply, Position: snapshotIndex})
+	if err != nil {
+		return fmt.Errorf("standby: resync: set position: %w", err)
+	}
+
+	a.update(func(s *Status) {
+		s.Position = snapshotIndex
+		s.PrimaryIndex = max(s.PrimaryIndex, snapshotIndex)
+		s.Resyncs++
+	})
+	a.logger.Info("restored the primary's backup", slog.Uint64("index", snapshotIndex))
+	return nil
+}
+
+// watch cancels ctx when this node stops leading, and keeps the primary's
+// applied index up to date meanwhile.
+func (a *Agent) watch(ctx context.Context, cancel context.CancelFunc) {
+	leader := time.NewTicker(leaderPollInterval)
+	defer leader.Stop()
+	stats := time.NewTicker(statsInterval)
+	defer stats.Stop()
+
+	a.readPrimaryIndex(ctx)
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-leader.C:
+			if !a.node.IsLeader() {
+				cancel()
+				return
+			}
+		case <-stats.C:
+			a.readPrimaryIndex(ctx)
+		}
+	}
+}
+
+// readPrimaryIndex reads the primary's applied index from /raft/stats.
+// Failures only leave the last known index in place.
+func (a *Agent) readPrimaryIndex(ctx context.Context) {
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, "http://"+a.cfg.PrimaryMgmtAddr+"/raft/stats", nil)
+	if err != nil {
+		return
+	}
+	resp, err := a.http.Do(req)
+	if err != nil {
+		a.logger.Debug("reading the primary's stats failed", slog.String("error", err.Error()))
+		return
+	}
+	defer resp.Body.Close()
+	var stats replication.Stats
+	if resp.StatusCode != http.StatusOK || json.NewDecoder(resp.Body).Decode(&stats) != nil {
+		return
+	}
+	a.mu.Lock()
+	defer a.mu.Unlock()
+	if stats.AppliedIndex != a.statsIndex {
+		a.statsIndex = stats.AppliedIndex
+		a.status.PrimaryIndex = max(a.status.PrimaryIndex, stats.AppliedIndex)
+	}
+}
+
+// Status returns the agent's current status. On the standby's followers
+// only the position is meaningful; read the rest on its leader.
+func (a *Agent) Status() Status {
+	a.mu.Lock()
+	s := a.status
+	a.mu.Unlock()
+
+	if s.State != StateFollowing {
+		s.Position = a.fsm.StandbyPosition()
+	}
+	if s.PrimaryIndex > s.Position {
+		s.LagEntries = s.PrimaryIndex - s.Position
+	}
+	if !s.CaughtUpAt.IsZero() {
+		s.LagSeconds = time.Since(s.CaughtUpAt).Seconds()
+	}
+	return s
+}
+
+func (a *Agent) update(f func(s *Status)) {
+	a.mu.Lock()
+	defer a.mu.Unlock()
+	f(&a.status)
+}
+
+// command converts a write event back to the command applying it.
+func command(event *api.ChangeEvent) (*replication.RaftCommand, error) {
+	switch event.GetOp() {
+	case api.ChangeOp_CHANGE_OP_SET:
+		cmd := &replication.RaftCommand{Op: replication.OpSet, Key: event.GetId(), Value: event.GetValue(), RawValue: event.GetRawValue()}
+		if ms := event.GetExpiresAtMs(); ms > 0 {
+			cmd.Expiration = time.UnixMilli(ms)
+		}
+		return cmd, nil
+	case api.ChangeOp_CHANGE_OP_PUT:
+		var entry types.ColumnValueWithTTL
+		if err := json.Unmarshal(event.GetEntry(), &entry); err != nil {
+			return nil, fmt.Errorf("standby: decode entry at index %d: %w", event.GetIndex(), err)
+		}
+		entries := map[string]types.ColumnValueWithTTL{event.GetId(): entry}
+		return &replication.RaftCommand{Op: replication.OpPut, Key: event.GetId(), Entries: entries}, nil
+	case api.ChangeOp_CHANGE_OP_DELETE:
+		return &replication.RaftCommand{Op: replication.OpDelete, Key: event.GetId()}, nil
+	case api.ChangeOp_CHANGE_OP_BATCH_DELETE:
+		// The ids of an expired batch delete are the keys the primary found
+		// still expired: delete them, whatever their expiry here.
+		return &replication.RaftCommand{Op: replication.OpBatchDelete, Keys: event.GetIds()}, nil
+	default:
+		return nil, fmt.Errorf("standby: unexpected change op %s at index %d", event.GetOp(), event.GetIndex())
+	}
+}
+
+func isWrite(event *api.ChangeEvent) bool {
+	switch event.GetOp() {
+	case api.ChangeOp_CHANGE_OP_SET, api.ChangeOp_CHANGE_OP_PUT, api.ChangeOp_CHANGE_OP_DELETE,
+		api.ChangeOp_CHANGE_OP_BATCH_DELETE:
+		return true
+	default:
+		return false
+	}
+}
+
+// sleep waits for d, or until ctx is done.
+func sleep(ctx context.Context, d time.Duration) {
+	t := time.NewTimer(d)
+	defer t.Stop()
+	select {
+	case <-t.C:
+	case <-ctx.Done():
+	}
+}
@@ -0,0 +1,296 @@
+package standby
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"io"
+	"log/slog"
+	"net"
+	"net/http"
+	"net/http/httptest"
+	"strconv"
+	"testing"
+	"time"
+
+	"github.com/mateenbagheri/memorabilia/api"
+	"github.com/mateenbagheri/memorabilia/pkg/cluster"
+	"github.com/mateenbagheri/memorabilia/pkg/core"
+	"github.com/mateenbagheri/memorabilia/pkg/replication"
+	"github.com/mateenbagheri/memorabilia/pkg/types"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"google.golang.org/grpc"
+)
+
+func discardLogger() *slog.Logger {
+	return slog.New(slog.NewTextHandler(io.Discard, nil))
+}
+
+// newTestNode starts a single-node in-memory cluster on a loopback port and
+// waits for it to lead.
+func newTestNode(t *testing.T, nodeID string) (*replication.Node, *replication.FSM) {
+	t.Helper()
+
+	l, err := net.Listen("tcp", "127.0.0.1:0")
+	require.NoError(t, err)
+	addr := l.Addr().String()
+	require.NoError(t, l.Close())
+
+	cfg := &cluster.Config{
+		NodeID:             nodeID,
+		RaftBindAddr:       addr,
+		Bootstrap:          true,
+		Storage:            cluster.StorageMemory,
+		HeartbeatTimeout:   50 * time.Millisecond,
+		ElectionTimeout:    50 * time.Millisecond,
+		LeaderLeaseTimeout: 50 * time.Millisecond,
+	}
+	fsm := replication.NewFSM(core.NewInMemoryCommandRepository())
+	node, err := replication.NewNode(cfg, fsm, discardLogger())
+	require.NoError(t, err)
+	t.Cleanup(func() { node.Shutdown() })
+
+	require.Eventually(t, node.IsLeader, 5*time.Second, 10*time.Millisecond)
+	return node, fsm
+}
+
+// fakePrimary serves a scripted Subscribe stream: every call sends events,
+// then waits for the client to go away. It records the requests.
+type fakePrimary struct {
+	api.UnimplementedCommandsServer
+
+	events   []*api.ChangeEvent
+	requests chan *api.SubscribeRequest
+}
+
+func (p *fakePrimary) Subscribe(req *api.SubscribeRequest, stream grpc.ServerStreamingServer[api.ChangeEvent]) error {
+	p.requests <- req
+	for _, event := range p.events {
+		if err := stream.Send(event); err != nil {
+			return err
+		}
+	}
+	<-stream.Context().Done()
+	return nil
+}
+
+// newTestAgent returns an agent for node following primary over gRPC, with
+// mgmt as the primary's management API.
+func newTestAgent(t *testing.T, node *replication.Node, fsm *replication.FSM, primary *fakePrimary, mgmt http.Handler) *Agent {
+	t.Helper()
+
+	lis, err := net.Listen("tcp", "127.0.0.1:0")
+	require.NoError(t, err)
+	s := grpc.NewServer()
+	api.RegisterCommandsServer(s, primary)
+	go s.Serve(lis)
+	t.Cleanup(s.Stop)
+
+	if mgmt == nil {
+		mgmt = http.NotFoundHandler()
+	}
+	httpSrv := httptest.NewServer(mgmt)
+	t.Cleanup(httpSrv.Close)
+
+	agent, err := NewAgent(Config{
+		PrimaryAddr:     lis.Addr().String(),
+		PrimaryMgmtAddr: httpSrv.Listener.Addr().String(),
+	}, node, fsm, discardLogger())
+	require.NoError(t, err)
+	t.Cleanup(func() { agent.Close() })
+	return agent
+}
+
+func set(index uint64, key, value string) *api.ChangeEvent {
+	return &api.ChangeEvent{Op: api.ChangeOp_CHANGE_OP_SET, Index: index, Id: key, Value: value}
+}
+
+// put returns the event of a write that left entry at key.
+func put(t *testing.T, index uint64, key string, entry types.ColumnValueWithTTL) *api.ChangeEvent {
+	t.Helper()
+	data, err := json.Marshal(entry)
+	require.NoError(t, err)
+	return &api.ChangeEvent{Op: api.ChangeOp_CHANGE_OP_PUT, Index: index, Id: key, Entry: data}
+}
+
+func TestAgent_Follow_AppliesWritesAndAdvancesPosition(t *testing.T) {
+	node, fsm := newTestNode(t, "standby")
+	primary := &fakePrimary{
+		events: []*api.ChangeEvent{
+			set(5, "k1", "v1"),
+			set(5, "k2", "v2"),
+			{Op: api.ChangeOp_CHANGE_OP_DELETE, Index: 6, Id: "k1"},
+			put(t, 6, "list", types.ColumnValueWithTTL{Column: types.List{Val: []string{"a", "b"}}}),
+			set(7, "k3", "v3"),
+			{Op: api.ChangeOp_CHANGE_OP_PROGRESS, Index: 7},
+		},
+		requests: make(chan *api.SubscribeRequest, 2),
+	}
+	agent := newTestAgent(t, node, fsm, primary, nil)
+
+	ctx, cancel := context.WithCancel(context.Background())
+	done := make(chan error, 1)
+	go func() { done <- agent.follow(ctx) }()
+
+	req := <-primary.requests
+	assert.Equal(t, uint64(1), req.GetFromIndex(), "a new standby starts from the first entry")
+	assert.Equal(t, progressInterval.Milliseconds(), req.GetProgressIntervalMs())
+
+	require.Eventually(t, func() bool { return agent.Status().Position == 7 }, 5*time.Second, 10*time.Millisecond)
+	cancel()
+	require.NoError(t, <-done, "losing leadership or stopping isn't an error")
+
+	repo := fsm.Repository()
+	_, err := repo.Get(context.Background(), "k1")
+	assert.Error(t, err, "k1 was deleted after being set")
+	got, err := repo.Get(context.Background(), "k2")
+	require.NoError(t, err)
+	assert.Equal(t, "v2", got)
+	got, err = repo.Get(context.Background(), "k3")
+	require.NoError(t, err)
+	assert.Equal(t, "v3", got)
+	length, err := repo.Len(context.Background(), "list")
+	require.NoError(t, err)
+	assert.Equal(t, int64(2), length)
+
+	// Entry 7 wasn't followed by a write of another entry, so it isn't
+	// known to be complete and will be applied again.
+	assert.Equal(t, uint64(6), fsm.StandbyPosition())
+	status := agent.Status()
+	assert.Equal(t, uint64(5), status.AppliedWrites)
+	assert.Equal(t, uint64(7), status.PrimaryIndex)
+	assert.False(t, status.CaughtUpAt.IsZero(), "the progress event confirmed the standby is caught up")
+
+	// Following again resumes after the stored position.
+	ctx, cancel = context.WithCancel(context.Background())
+	defer cancel()
+	go func() { done <- agent.follow(ctx) }()
+	req = <-primary.requests
+	assert.Equal(t, uint64(7), req.GetFromIndex())
+	cancel()
+	require.NoError(t, <-done)
+}
+
+func TestAgent_Follow_RejectsUnknownEvents(t *testing.T) {
+	node, fsm := newTestNode(t, "standby")
+	primary := &fakePrimary{
+		events:   []*api.ChangeEvent{{Op: api.ChangeOp_CHANGE_OP_UNSPECIFIED, Index: 3}},
+		requests: make(chan *api.SubscribeRequest, 1),
+	}
+	agent := newTestAgent(t, node, fsm, primary, nil)
+
+	err := agent.follow(context.Background())
+	require.Error(t, err)
+	assert.Contains(t, err.Error(), "unexpected change op")
+	assert.Zero(t, fsm.StandbyPosition(), "nothing was applied")
+}
+
+func TestAgent_Resync_RestoresPrimaryBackup(t *testing.T) {
+	primaryNode, _ := newTestNode(t, "primary")
+	_, err := primaryNode.Apply(&replication.RaftCommand{Op: replication.OpSet, Key: "restored", Value: "from-backup"})
+	require.NoError(t, err)
+	info, rc, err := primaryNode.OpenSnapshot()
+	require.NoError(t, err)
+	backup, err := io.ReadAll(rc)
+	rc.Close()
+	require.NoError(t, err)
+
+	mgmt := http.NewServeMux()
+	mgmt.HandleFunc("/raft/backup", func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set(replication.SnapshotIndexHeader, strconv.FormatUint(info.Index, 10))
+		io.Copy(w, bytes.NewReader(backup))
+	})
+
+	node, fsm := newTestNode(t, "standby")
+	_, err = node.Apply(&replication.RaftCommand{Op: replication.OpSet, Key: "stale", Value: "v"})
+	require.NoError(t, err)
+	primary := &fakePrimary{
+		events:   []*api.ChangeEvent{{Op: api.ChangeOp_CHANGE_OP_SNAPSHOT, Index: info.Index}},
+		requests: make(chan *api.SubscribeRequest, 1),
+	}
+	agent := newTestAgent(t, node, fsm, primary, mgmt)
+
+	require.NoError(t, agent.follow(context.Background()), "follow returns to start over after a resync")
+
+	repo := fsm.Repository()
+	got, err := repo.Get(context.Background(), "restored")
+	require.NoError(t, err)
+	assert.Equal(t, "from-backup", got)
+	_, err = repo.Get(context.Background(), "stale")
+	assert.Error(t, err, "the backup replaced the standby's state")
+
+	assert.Equal(t, info.Index, fsm.StandbyPosition())
+	status := agent.Status()
+	assert.Equal(t, uint64(1), status.Resyncs)
+	assert.Equal(t, info.Index, status.Position)
+}
+
+func TestAgent_Resync_FailsWithoutBackup(t *testing.T) {
+	node, fsm := newTestNode(t, "standby")
+	primary := &fakePrimary{requests: make(chan *api.SubscribeRequest, 1)}
+	agent := newTestAgent(t, node, fsm, primary, nil)
+
+	err := agent.resync(context.Background(), 10)
+	require.Error(t, err)
+	assert.Contains(t, err.Error(), "download backup")
+	assert.Zero(t, fsm.StandbyPosition())
+	assert.Zero(t, agent.Status().Resyncs)
+}
+
+func TestCommand(t *testing.T) {
+	expiresAt := time.UnixMilli(1_700_000_000_000)
+
+	cmd, err := command(&api.ChangeEvent{
+		Op: api.ChangeOp_CHANGE_OP_SET, Id: "k", Value: "v", ExpiresAtMs: expiresAt.UnixMilli(),
+	})
+	require.NoError(t, err)
+	assert.Equal(t, replication.OpSet, cmd.Op)
+	assert.Equal(t, "k", cmd.Key)
+	assert.Equal(t, "v", cmd.Value)
+	assert.True(t, expiresAt.Equal(cmd.Expiration))
+
+	cmd, err = command(set(1, "k", "v"))
+	require.NoError(t, err)
+	assert.True(t, cmd.Expiration.IsZero(), "no expiry")
+
+	cmd, err = command(&api.ChangeEvent{Op: api.ChangeOp_CHANGE_OP_SET, Id: "bits", RawValue: []byte{0x80, 0xff}})
+	require.NoError(t, err)
+	assert.Equal(t, string([]byte{0x80, 0xff}), cmd.SetValue())
+	data, err := cmd.Encode()
+	require.NoError(t, err)
+	decoded, err := replication.DecodeCommand(data)
+	require.NoError(t, err)
+	assert.Equal(t, cmd.SetValue(), decoded.SetValue(), "raw bytes survive the Raft log")
+
+	cmd, err = command(&api.ChangeEvent{Op: api.ChangeOp_CHANGE_OP_DELETE, Id: "k"})
+	require.NoError(t, err)
+	assert.Equal(t, &replication.RaftCommand{Op: replication.OpDelete, Key: "k"}, cmd)
+
+	lock := types.ColumnValueWithTTL{Column: types.Lock{Session: "s", Token: 3}, Expiration: expiresAt.UTC()}
+	cmd, err = command(put(t, 1, "lock", lock))
+	require.NoError(t, err)
+	assert.Equal(t, replication.OpPut, cmd.Op)
+	assert.Equal(t, "lock", cmd.Key)
+	assert.Equal(t, map[string]types.ColumnValueWithTTL{"lock": lock}, cmd.Entries)
+
+	_, err = command(&api.ChangeEvent{Op: api.ChangeOp_CHANGE_OP_PUT, Id: "k", Entry: []byte("{")})
+	assert.Error(t, err, "an entry that doesn't decode")
+
+	cmd, err = command(&api.ChangeEvent{Op: api.ChangeOp_CHANGE_OP_BATCH_DELETE, Ids: []string{"a", "b"}})
+	require.NoError(t, err)
+	assert.Equal(t, &replication.RaftCommand{Op: replication.OpBatchDelete, Keys: []string{"a", "b"}}, cmd)
+
+	cmd, err = command(&api.ChangeEvent{
+		Op: api.ChangeOp_CHANGE_OP_BATCH_DELETE, Ids: []string{"a"}, Expired: true, TimestampMs: expiresAt.UnixMilli(),
+	})
+	require.NoError(t, err)
+	assert.Equal(t, &replication.RaftCommand{Op: replication.OpBatchDelete, Keys: []string{"a"}}, cmd,
+		"the primary only lists the keys it deleted")
+
+	for _, op := range []api.ChangeOp{api.ChangeOp_CHANGE_OP_PROGRESS, api.ChangeOp_CHANGE_OP_SNAPSHOT} {
+		_, err = command(&api.ChangeEvent{Op: op, Index: 9})
+		assert.Error(t, err, op.String())
+		assert.False(t, isWrite(&api.ChangeEvent{Op: op}), op.String())
+	}
+}
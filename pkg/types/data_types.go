@@ -1,11 +1,15 @@
 package types
 
 import (
+	"cmp"
 	"encoding/json"
 	"errors"
 	"fmt"
+	"math"
 	"strconv"
+	"strings"
 	"time"
+	"unicode/utf8"
 )
 
 // ErrNoneCastable is an error indicating that a value cannot be cast to the desired type.
@@ -21,6 +25,20 @@ const (
 	StringType
 	// FloatType represents floating-point data type.
 	FloatType
+	// ListType represents a list of strings.
+	ListType
+	// LockType represents a lock held by a session.
+	LockType
+	// StreamType represents an append-only stream of entries.
+	StreamType
+	// RateLimiterType represents the state of a rate limiter.
+	RateLimiterType
+	// BloomFilterType represents a Bloom filter.
+	BloomFilterType
+	// HyperLogLogType represents a HyperLogLog.
+	HyperLogLogType
+	// BytesType represents a string of raw bytes.
+	BytesType
 )
 
 // ColumnValue is an interface that defines methods for working with column values.
@@ -41,6 +59,17 @@ type ColumnValue interface {
 type ColumnValueWithTTL struct {
 	Column     ColumnValue
 	Expiration time.Time
+
+	// Session is the client session an ephemeral key belongs to: the key is
+	// deleted when the session ends. Empty for other keys.
+	Session string
+}
+
+// Session is a client session. It ends when it is closed, or when Deadline
+// passes: TTL after it was created or last kept alive.
+type Session struct {
+	TTL      time.Duration
+	Deadline time.Time
 }
 
 // columnValueWithTTLJSON is the wire format used when marshaling ColumnValueWithTTL.
@@ -54,6 +83,7 @@ type columnValueWithTTLJSON struct {
 	Type       string          `json:"type"`
 	Value      json.RawMessage `json:"value"`
 	Expiration time.Time       `json:"expiration"`
+	Session    string          `json:"session,omitempty"`
 }
 
 // MarshalJSON implements json.Marshaler for ColumnValueWithTTL.
@@ -85,6 +115,7 @@ func (c ColumnValueWithTTL) MarshalJSON() ([]byte, error) {
 		Type:       typeTag,
 		Value:      json.RawMessage(valueBytes),
 		Expiration: c.Expiration,
+		Session:    c.Session,
 	})
 }
 
@@ -105,6 +136,7 @@ func (c *ColumnValueWithTTL) UnmarshalJSON(data []byte) error {
 
 	c.Column = col
 	c.Expiration = envelope.Expiration
+	c.Session = envelope.Session
 	return nil
 }
 
@@ -117,6 +149,20 @@ func columnTypeToTag(ct ColumnType) (string, error) {
 		return "string", nil
 	case FloatType:
 		return "float", nil
+	case ListType:
+		return "list", nil
+	case LockType:
+		return "lock", nil
+	case StreamType:
+		return "stream", nil
+	case RateLimiterType:
+		return "ratelimiter", nil
+	case BloomFilterType:
+		return "bloom", nil
+	case HyperLogLogType:
+		return "hyperloglog", nil
+	case BytesType:
+		return "bytes", nil
 	default:
 		return "", fmt.Errorf("unknown ColumnType %d", ct)
 	}
@@ -144,6 +190,48 @@ func unmarshalColumnValue(typeTag string, valueBytes json.RawMessage) (ColumnVal
 			return nil, fmt.Errorf("ColumnValueWithTTL unmarshal float value: %w", err)
 		}
 		return v, nil
+	case "list":
+		var v List
+		if err := json.Unmarshal(valueBytes, &v); err != nil {
+			return nil, fmt.Errorf("ColumnValueWithTTL unmarshal list value: %w", err)
+		}
+		return v, nil
+	case "lock":
+		var v Lock
+		if err := json.Unmarshal(valueBytes, &v); err != nil {
+			return nil, fmt.Errorf("ColumnValueWithTTL unmarshal lock value: %w", err)
+		}
+		return v, nil
+	case "stream":
+		var v Stream
+		if err := json.Unmarshal(valueBytes, &v); err != nil {
+			return nil, fmt.Errorf("ColumnValueWithTTL unmarshal stream value: %w", err)
+		}
+		return v, nil
+	case "ratelimiter":
+		var v RateLimiter
+		if err := json.Unmarshal(valueBytes, &v); err != nil {
+			return nil, fmt.Errorf("ColumnValueWithTTL unmarshal rate limiter value: %w", err)
+		}
+		return v, nil
+	case "bloom":
+		var v BloomFilter
+		if err := json.Unmarshal(valueBytes, &v); err != nil {
+			return nil, fmt.Errorf("ColumnValueWithTTL unmarshal bloom filter value: %w", err)
+		}
+		return v, nil
+	case "hyperloglog":
+		var v HyperLogLog
+		if err := json.Unmarshal(valueBytes, &v); err != nil {
+			return nil, fmt.Errorf("ColumnValueWithTTL unmarshal hyperloglog value: %w", err)
+		}
+		return v, nil
+	case "bytes":
+		var v Bytes
+		if err := json.Unmarshal(valueBytes, &v); err != nil {
+			return nil, fmt.Errorf("ColumnValueWithTTL unmarshal bytes value: %w", err)
+		}
+		return v, nil
 	default:
 		return nil, fmt.Errorf("ColumnValueWithTTL unmarshal: unknown type tag %q", typeTag)
 	}
@@ -197,13 +285,260 @@ func (v Float) ToString() string          { return fmt.Sprint(v.Val) }
 func (v Float) ToFloat() (float64, error) { return v.Val, nil }
 func (v Float) Type() ColumnType          { return FloatType }
 
+// Bytes represents a column value of raw bytes, which may not be valid UTF-8:
+// a string written bit by bit, say. It is a string to everything but JSON,
+// which encodes it in base64 so that no byte is lost. Like List, values are
+// shared with store snapshots: Val is never modified in place.
+type Bytes struct {
+	Val []byte
+}
+
+func (v Bytes) Value() any       { return v.Val }
+func (v Bytes) ToString() string { return string(v.Val) }
+func (v Bytes) ToInt() (int, error) {
+	return String{Val: string(v.Val)}.ToInt()
+}
+func (v Bytes) ToFloat() (float64, error) {
+	return String{Val: string(v.Val)}.ToFloat()
+}
+func (v Bytes) Type() ColumnType { return BytesType }
+
+// List represents a column value holding a list of strings. A list used as a
+// reliable queue also holds the jobs popped from it that haven't been
+// acknowledged yet, see Job.
+//
+// Values are shared with store snapshots, so the slices are never modified
+// in place: changes reslice them, append to them or build new ones.
+type List struct {
+	Val      []string
+	InFlight []Job `json:",omitempty"`
+
+	// NextJob numbers the jobs popped from the list.
+	NextJob uint64 `json:",omitempty"`
+}
+
+// Job is an element popped from a reliable queue and not acknowledged yet.
+// It goes back to the head of the queue if it isn't by Deadline.
+type Job struct {
+	ID       string
+	Value    string
+	Deadline time.Time
+}
+
+func (v List) Value() any                { return v.Val }
+func (v List) ToInt() (int, error)       { return 0, ErrNoneCastable }
+func (v List) ToFloat() (float64, error) { return 0, ErrNoneCastable }
+func (v List) Type() ColumnType          { return ListType }
+
+// ToString returns the elements as a JSON array.
+func (v List) ToString() string {
+	b, _ := json.Marshal(v.Val)
+	return string(b)
+}
+
+// Lock represents a column value holding a lock, acquired by Session with
+// the fencing token Token. The lease of the lock is the key's expiration.
+type Lock struct {
+	Session string
+	Token   uint64
+}
+
+func (v Lock) Value() any                { return v.Session }
+func (v Lock) ToString() string          { return v.Session }
+func (v Lock) ToInt() (int, error)       { return 0, ErrNoneCastable }
+func (v Lock) ToFloat() (float64, error) { return 0, ErrNoneCastable }
+func (v Lock) Type() ColumnType          { return LockType }
+
+// Stream represents a column value holding an append-only stream, like a
+// Redis stream. Entries are in ascending ID order; LastID is the highest ID
+// ever added, which new IDs must exceed even once its entry was trimmed.
+//
+// Like List, values are shared with store snapshots: the slices and the map
+// are never modified in place.
+type Stream struct {
+	Entries []StreamEntry
+	LastID  StreamID
+	Groups  map[string]ConsumerGroup `json:",omitempty"`
+}
+
+// StreamEntry is an entry of a stream: a set of fields and their values.
+type StreamEntry struct {
+	ID     StreamID
+	Fields map[string]string
+}
+
+// ConsumerGroup is a group of consumers sharing the entries of a stream:
+// each entry past LastDelivered goes to one of them, and stays pending until
+// that consumer acknowledges it.
+type ConsumerGroup struct {
+	LastDelivered StreamID
+	Pending       []PendingEntry `json:",omitempty"`
+}
+
+// PendingEntry is an entry delivered to Consumer and not acknowledged yet,
+// in ascending ID order within its group. Delivered is when it was last
+// delivered, and Deliveries how many times it was.
+type PendingEntry struct {
+	ID         StreamID
+	Consumer   string
+	Delivered  time.Time
+	Deliveries int64
+}
+
+func (v Stream) Value() any                { return v.Entries }
+func (v Stream) ToInt() (int, error)       { return 0, ErrNoneCastable }
+func (v Stream) ToFloat() (float64, error) { return 0, ErrNoneCastable }
+func (v Stream) Type() ColumnType          { return StreamType }
+
+// ToString returns the entries as a JSON array.
+func (v Stream) ToString() string {
+	b, _ := json.Marshal(v.Entries)
+	return string(b)
+}
+
+// StreamID identifies a stream entry: the time it was added, in Unix
+// milliseconds, and a sequence number telling apart entries added within
+// the same millisecond. Its text form is "<ms>-<seq>".
+type StreamID struct {
+	Ms  uint64
+	Seq uint64
+}
+
+// MaxStreamID is the highest possible StreamID.
+var MaxStreamID = StreamID{Ms: math.MaxUint64, Seq: math.MaxUint64}
+
+// ErrInvalidStreamID is returned for text that isn't a stream ID.
+var ErrInvalidStreamID = errors.New("invalid stream ID")
+
+// ParseStreamID parses "<ms>-<seq>", or "<ms>" for "<ms>-0".
+func ParseStreamID(s string) (StreamID, error) {
+	msText, seqText, hasSeq := strings.Cut(s, "-")
+	ms, err := strconv.ParseUint(msText, 10, 64)
+	if err != nil {
+		return StreamID{}, fmt.Errorf("%w: %q", ErrInvalidStreamID, s)
+	}
+	var seq uint64
+	if hasSeq {
+		if seq, err = strconv.ParseUint(seqText, 10, 64); err != nil {
+			return StreamID{}, fmt.Errorf("%w: %q", ErrInvalidStreamID, s)
+		}
+	}
+	return StreamID{Ms: ms, Seq: seq}, nil
+}
+
+func (id StreamID) String() string {
+	return strconv.FormatUint(id.Ms, 10) + "-" + strconv.FormatUint(id.Seq, 10)
+}
+
+// MarshalText encodes id in its text form, so that IDs read the same in
+// snapshots and API responses.
+func (id StreamID) MarshalText() ([]byte, error) {
+	return []byte(id.String()), nil
+}
+
+func (id *StreamID) UnmarshalText(text []byte) error {
+	parsed, err := ParseStreamID(string(text))
+	if err != nil {
+		return err
+	}
+	*id = parsed
+	return nil
+}
+
+// Compare returns -1, 0 or +1 as id is less than, equal to or greater than
+// other.
+func (id StreamID) Compare(other StreamID) int {
+	switch {
+	case id.Ms != other.Ms:
+		return cmp.Compare(id.Ms, other.Ms)
+	default:
+		return cmp.Compare(id.Seq, other.Seq)
+	}
+}
+
+// Next returns the ID right after id, or id itself if it is MaxStreamID.
+func (id StreamID) Next() StreamID {
+	switch {
+	case id.Seq < math.MaxUint64:
+		return StreamID{Ms: id.Ms, Seq: id.Seq + 1}
+	case id.Ms < math.MaxUint64:
+		return StreamID{Ms: id.Ms + 1}
+	default:
+		return id
+	}
+}
+
+// Prev returns the ID right before id, or id itself if it is the zero ID.
+func (id StreamID) Prev() StreamID {
+	switch {
+	case id.Seq > 0:
+		return StreamID{Ms: id.Ms, Seq: id.Seq - 1}
+	case id.Ms > 0:
+		return StreamID{Ms: id.Ms - 1, Seq: math.MaxUint64}
+	default:
+		return id
+	}
+}
+
+// RateLimiter represents a column value holding the state of a rate limiter
+// as of Updated. A token bucket holds Tokens; a sliding window holds Count,
+// consumed in the window starting at Updated, and Previous, consumed in the
+// window before it.
+type RateLimiter struct {
+	Algorithm string
+	Updated   time.Time
+	Tokens    float64 `json:",omitempty"`
+	Count     int64   `json:",omitempty"`
+	Previous  int64   `json:",omitempty"`
+}
+
+func (v RateLimiter) Value() any                { return v.Algorithm }
+func (v RateLimiter) ToString() string          { return v.Algorithm }
+func (v RateLimiter) ToInt() (int, error)       { return 0, ErrNoneCastable }
+func (v RateLimiter) ToFloat() (float64, error) { return 0, ErrNoneCastable }
+func (v RateLimiter) Type() ColumnType          { return RateLimiterType }
+
+// BloomFilter represents a column value holding a Bloom filter: a bitmap of
+// len(Bits)*8 bits, where each item sets Hashes of them. Like List, values are
+// shared with store snapshots: Bits is never modified in place.
+type BloomFilter struct {
+	Bits   []byte
+	Hashes int64
+}
+
+func (v BloomFilter) Value() any                { return v.Bits }
+func (v BloomFilter) ToString() string          { return string(v.Bits) }
+func (v BloomFilter) ToInt() (int, error)       { return 0, ErrNoneCastable }
+func (v BloomFilter) ToFloat() (float64, error) { return 0, ErrNoneCastable }
+func (v BloomFilter) Type() ColumnType          { return BloomFilterType }
+
+// HyperLogLog represents a column value holding a HyperLogLog, counting the
+// distinct items added to it: Registers holds, for each of its buckets, the
+// longest run of leading zeros seen, plus one. Like List, values are shared
+// with store snapshots: Registers is never modified in place.
+type HyperLogLog struct {
+	Registers []byte
+}
+
+func (v HyperLogLog) Value() any                { return v.Registers }
+func (v HyperLogLog) ToString() string          { return string(v.Registers) }
+func (v HyperLogLog) ToInt() (int, error)       { return 0, ErrNoneCastable }
+func (v HyperLogLog) ToFloat() (float64, error) { return 0, ErrNoneCastable }
+func (v HyperLogLog) Type() ColumnType          { return HyperLogLogType }
+
 // DetectColumnType takes a string input and determines its appropriate ColumnType.
+// Numbers are only detected in their canonical form, so that ToString gives
+// back input byte for byte: "007" and "1.50" stay strings. Input that isn't
+// valid UTF-8 is Bytes, which survives JSON unchanged where String wouldn't.
 func DetectColumnType(input string) (ColumnType, ColumnValue) {
-	if i, err := strconv.Atoi(input); err == nil {
+	if i, err := strconv.Atoi(input); err == nil && strconv.Itoa(i) == input {
 		return IntType, Integer{Val: i}
 	}
-	if f, err := strconv.ParseFloat(input, 64); err == nil {
+	if f, err := strconv.ParseFloat(input, 64); err == nil && fmt.Sprint(f) == input {
 		return FloatType, Float{Val: f}
 	}
+	if !utf8.ValidString(input) {
+		return BytesType, Bytes{Val: []byte(input)}
+	}
 	return StringType, String{Val: input}
 }
@@ -2,6 +2,7 @@ package types_test
 
 import (
 	"encoding/json"
+	"math"
 	"testing"
 	"time"
 
@@ -57,6 +58,109 @@ func TestColumnValueWithTTL_JSON_Float(t *testing.T) {
 	assert.InDelta(t, 3.14, f, 0.0001)
 }
 
+func TestColumnValueWithTTL_JSON_List(t *testing.T) {
+	deadline := time.Date(2025, 6, 1, 12, 0, 0, 0, time.UTC)
+	list := types.List{
+		Val:      []string{"a", "b"},
+		InFlight: []types.Job{{ID: "1", Value: "c", Deadline: deadline}},
+		NextJob:  2,
+	}
+	got := roundTrip(t, types.ColumnValueWithTTL{Column: list})
+
+	require.IsType(t, types.List{}, got.Column)
+	restored := got.Column.(types.List)
+	assert.Equal(t, list.Val, restored.Val)
+	assert.Equal(t, list.NextJob, restored.NextJob)
+	require.Len(t, restored.InFlight, 1)
+	assert.True(t, restored.InFlight[0].Deadline.Equal(deadline))
+	assert.Equal(t, `["a","b"]`, got.Column.ToString())
+}
+
+func TestColumnValueWithTTL_JSON_Lock(t *testing.T) {
+	lease := time.Date(2025, 6, 1, 12, 0, 0, 0, time.UTC)
+	got := roundTrip(t, types.ColumnValueWithTTL{Column: types.Lock{Session: "worker-1", Token: 42}, Expiration: lease})
+
+	assert.Equal(t, types.Lock{Session: "worker-1", Token: 42}, got.Column)
+	assert.True(t, got.Expiration.Equal(lease))
+}
+
+func TestColumnValueWithTTL_JSON_Stream(t *testing.T) {
+	delivered := time.Date(2025, 6, 1, 12, 0, 0, 0, time.UTC)
+	stream := types.Stream{
+		Entries: []types.StreamEntry{{ID: types.StreamID{Ms: 5, Seq: 1}, Fields: map[string]string{"a": "1"}}},
+		LastID:  types.StreamID{Ms: 5, Seq: 2},
+		Groups: map[string]types.ConsumerGroup{"g": {
+			LastDelivered: types.StreamID{Ms: 5, Seq: 1},
+			Pending:       []types.PendingEntry{{ID: types.StreamID{Ms: 5, Seq: 1}, Consumer: "c", Delivered: delivered, Deliveries: 1}},
+		}},
+	}
+	got := roundTrip(t, types.ColumnValueWithTTL{Column: stream})
+
+	assert.Equal(t, stream, got.Column)
+	assert.Equal(t, `[{"ID":"5-1","Fields":{"a":"1"}}]`, got.Column.ToString())
+}
+
+func TestColumnValueWithTTL_JSON_RateLimiter(t *testing.T) {
+	updated := time.Date(2025, 6, 1, 12, 0, 0, 0, time.UTC)
+	limiter := types.RateLimiter{Algorithm: "token_bucket", Updated: updated, Tokens: 2.5}
+	got := roundTrip(t, types.ColumnValueWithTTL{Column: limiter, Expiration: updated.Add(time.Second)})
+
+	assert.Equal(t, limiter, got.Column)
+}
+
+func TestColumnValueWithTTL_JSON_Probabilistic(t *testing.T) {
+	bloom := types.BloomFilter{Bits: []byte{0x01, 0x80}, Hashes: 3}
+	assert.Equal(t, bloom, roundTrip(t, types.ColumnValueWithTTL{Column: bloom}).Column)
+	hll := types.HyperLogLog{Registers: []byte{0, 3, 1}}
+	assert.Equal(t, hll, roundTrip(t, types.ColumnValueWithTTL{Column: hll}).Column)
+}
+
+func TestColumnValueWithTTL_JSON_Bytes(t *testing.T) {
+	original := types.ColumnValueWithTTL{Column: types.Bytes{Val: []byte{0xff, 0x00, 'a'}}}
+	got := roundTrip(t, original)
+
+	assert.Equal(t, types.BytesType, got.Column.Type())
+	assert.Equal(t, "\xff\x00a", got.Column.ToString())
+}
+
+func TestDetectColumnType(t *testing.T) {
+	tests := []struct {
+		in   string
+		want types.ColumnType
+	}{
+		{"42", types.IntType},
+		{"-7", types.IntType},
+		{"3.14", types.FloatType},
+		{"007", types.StringType},
+		{"+5", types.StringType},
+		{"1.50", types.StringType},
+		{"1e3", types.StringType},
+		{"hello", types.StringType},
+		{"\x80\x01", types.BytesType},
+	}
+	for _, tt := range tests {
+		typ, value := types.DetectColumnType(tt.in)
+		assert.Equal(t, tt.want, typ, "input %q", tt.in)
+		assert.Equal(t, tt.in, value.ToString(), "input %q", tt.in)
+	}
+}
+
+func TestParseStreamID(t *testing.T) {
+	id, err := types.ParseStreamID("1526919030474-55")
+	require.NoError(t, err)
+	assert.Equal(t, types.StreamID{Ms: 1526919030474, Seq: 55}, id)
+	id, err = types.ParseStreamID("7")
+	require.NoError(t, err)
+	assert.Equal(t, "7-0", id.String())
+	for _, bad := range []string{"", "-", "1-", "a-1", "1-2-3"} {
+		_, err = types.ParseStreamID(bad)
+		assert.ErrorIs(t, err, types.ErrInvalidStreamID, bad)
+	}
+	assert.Equal(t, types.StreamID{Ms: 8}, types.StreamID{Ms: 7, Seq: math.MaxUint64}.Next())
+	assert.Equal(t, types.StreamID{Ms: 7, Seq: math.MaxUint64}, types.StreamID{Ms: 8}.Prev())
+	assert.Equal(t, -1, id.Compare(types.StreamID{Ms: 7, Seq: 1}))
+}
+
 func TestColumnValueWithTTL_JSON_WithExpiration(t *testing.T) {
 	expiry := time.Date(2025, 6, 1, 12, 0, 0, 0, time.UTC)
 	original := types.ColumnValueWithTTL{
@@ -70,6 +174,14 @@ func TestColumnValueWithTTL_JSON_WithExpiration(t *testing.T) {
 		"expiration mismatch: want %v got %v", expiry, got.Expiration)
 }
 
+func TestColumnValueWithTTL_JSON_Session(t *testing.T) {
+	got := roundTrip(t, types.ColumnValueWithTTL{Column: types.String{Val: "10.0.0.1:80"}, Session: "s1"})
+	assert.Equal(t, "s1", got.Session)
+
+	got = roundTrip(t, types.ColumnValueWithTTL{Column: types.String{Val: "v"}})
+	assert.Empty(t, got.Session)
+}
+
 func TestColumnValueWithTTL_JSON_MapRoundTrip(t *testing.T) {
 	// This is the exact scenario the Raft snapshot uses:
 	// marshal a whole map, unmarshal it back, assert every entry survives.
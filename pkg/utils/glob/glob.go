@@ -0,0 +1,120 @@
+package glob
+
+// Match reports whether s matches the Redis-style glob pattern.
+//
+// Unlike path.Match, '/' has no special meaning here, so "user:*" matches
+// "user:1/profile". Supported syntax:
+//
+//	x*y     '*' matches any sequence of characters, including the empty one
+//	x?y     '?' matches exactly one character
+//	[abc]   one character from the set
+//	[^abc]  one character not in the set
+//	[a-z]   one character in the range
+//	\x      the literal character x
+//
+// A malformed pattern (e.g. an unterminated '[') simply never matches.
+//
+// Patterns come from clients, so '*' isn't matched by recursion, which takes
+// exponential time on patterns like "*a*a*a*b": only the last '*' seen is
+// retried, one more character at a time, bounding the work by
+// len(pattern)*len(s).
+func Match(pattern, s string) bool {
+	p, i := 0, 0
+	// star is the pattern position after the last '*' seen, -1 before
+	// any; starI is where in s it currently stops matching.
+	star, starI := -1, 0
+	for i < len(s) {
+		if p < len(pattern) && pattern[p] == '*' {
+			for p < len(pattern) && pattern[p] == '*' {
+				p++
+			}
+			star, starI = p, i
+			continue
+		}
+		if next, ok := matchOne(pattern, p, s[i]); ok {
+			p, i = next, i+1
+			continue
+		}
+		if star < 0 {
+			return false
+		}
+		starI++
+		p, i = star, starI
+	}
+	for p < len(pattern) && pattern[p] == '*' {
+		p++
+	}
+	return p == len(pattern)
+}
+
+// matchOne matches c against the single-character element of pattern at p:
+// '?', a character class or a possibly escaped literal. It returns the
+// position after the element and whether c matches it.
+func matchOne(pattern string, p int, c byte) (next int, ok bool) {
+	if p >= len(pattern) {
+		return p, false
+	}
+	switch pattern[p] {
+	case '?':
+		return p + 1, true
+	case '[':
+		rest, ok := matchClass(pattern[p+1:], c)
+		return len(pattern) - len(rest), ok
+	case '\\':
+		if p+1 < len(pattern) {
+			p++
+		}
+	}
+	return p + 1, pattern[p] == c
+}
+
+// HasMeta reports whether pattern contains any glob metacharacter. Callers
+// use it to skip pattern matching for plain keys.
+func HasMeta(pattern string) bool {
+	for i := 0; i < len(pattern); i++ {
+		switch pattern[i] {
+		case '*', '?', '[', '\\':
+			return true
+		}
+	}
+	return false
+}
+
+// matchClass matches c against the character class at the start of pattern
+// (just after the opening '['). It returns the pattern remaining after the
+// closing ']' and whether c belongs to the class.
+func matchClass(pattern string, c byte) (rest string, ok bool) {
+	negate := false
+	if len(pattern) > 0 && pattern[0] == '^' {
+		negate = true
+		pattern = pattern[1:]
+	}
+
+	matched := false
+	for i := 0; i < len(pattern); i++ {
+		switch {
+		case pattern[i] == ']' && i > 0:
+			return pattern[i+1:], matched != negate
+		case pattern[i] == '\\' && i+1 < len(pattern):
+			i++
+			if pattern[i] == c {
+				matched = true
+			}
+		case i+2 < len(pattern) && pattern[i+1] == '-' && pattern[i+2] != ']':
+			lo, hi := pattern[i], pattern[i+2]
+			if lo > hi {
+				lo, hi = hi, lo
+			}
+			if c >= lo && c <= hi {
+				matched = true
+			}
+			i += 2
+		default:
+			if pattern[i] == c {
+				matched = true
+			}
+		}
+	}
+	// unterminated class
+	return "", false
+}
@@ -0,0 +1,72 @@
+package glob
+
+import (
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestMatch(t *testing.T) {
+	tests := []struct {
+		pattern string
+		input   string
+		want    bool
+	}{
+		{"*", "", true},
+		{"*", "anything/at:all", true},
+		{"user:*", "user:42", true},
+		{"user:*", "user:42/profile", true},
+		{"user:*", "session:42", false},
+		{"h?llo", "hello", true},
+		{"h?llo", "hllo", false},
+		{"h[ae]llo", "hallo", true},
+		{"h[ae]llo", "hillo", false},
+		{"h[^e]llo", "hallo", true},
+		{"h[^e]llo", "hello", false},
+		{"h[a-c]llo", "hbllo", true},
+		{"h[a-c]llo", "hdllo", false},
+		{"*:*:end", "a:b:end", true},
+		{"*:*:end", "a:end", false},
+		{`\*literal`, "*literal", true},
+		{`\*literal`, "xliteral", false},
+		{"exact", "exact", true},
+		{"exact", "exactly", false},
+		{"[abc", "a", false},
+		{"*[abc", "xa", false},
+		{"a*b*c", "axxbyyc", true},
+		{"a*b*c", "axxbyyd", false},
+		{"*?", "", false},
+		{"**a**", "bab", true},
+		{`a\`, `a\`, true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.pattern+"_"+tt.input, func(t *testing.T) {
+			assert.Equal(t, tt.want, Match(tt.pattern, tt.input))
+		})
+	}
+}
+
+func TestMatch_PathologicalPattern(t *testing.T) {
+	s := strings.Repeat("a", 10000)
+	pattern := strings.Repeat("*a", 20) + "*b"
+
+	done := make(chan bool)
+	go func() { done <- Match(pattern, s) }()
+	select {
+	case matched := <-done:
+		assert.False(t, matched)
+	case <-time.After(5 * time.Second):
+		t.Fatal("matching took exponential time")
+	}
+	assert.True(t, Match(pattern, s+"b"))
+}
+
+func TestHasMeta(t *testing.T) {
+	assert.False(t, HasMeta("plain:key"))
+	assert.True(t, HasMeta("user:*"))
+	assert.True(t, HasMeta("a?c"))
+	assert.True(t, HasMeta("[ab]"))
+}
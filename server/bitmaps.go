@@ -0,0 +1,324 @@
+package server
+
+import (
+	"context"
+	"fmt"
+
+	"github.com/mateenbagheri/memorabilia/api"
+	"github.com/mateenbagheri/memorabilia/pkg/core"
+	"github.com/mateenbagheri/memorabilia/pkg/replication"
+)
+
+func (cs *CommandServer) SetBit(ctx context.Context, in *api.SetBitRequest) (*api.SetBitResponse, error) {
+	if in.GetId() == "" {
+		return nil, invalidArgument("setbit", "id", "must not be empty")
+	}
+	if err := validateBitOffset("setbit", "offset", in.GetOffset()); err != nil {
+		return nil, err
+	}
+	resp, err := cs.applyCommand(ctx, "setbit", in.GetId(), &replication.RaftCommand{
+		Op:     replication.OpSetBit,
+		Key:    in.GetId(),
+		Offset: in.GetOffset(),
+		Bit:    in.GetValue(),
+	})
+	if err != nil {
+		return nil, err
+	}
+	return &api.SetBitResponse{Previous: resp.PreviousBit}, nil
+}
+
+func (cs *CommandServer) GetBit(ctx context.Context, in *api.GetBitRequest) (*api.GetBitResponse, error) {
+	if in.GetId() == "" {
+		return nil, invalidArgument("getbit", "id", "must not be empty")
+	}
+	if err := validateBitOffset("getbit", "offset", in.GetOffset()); err != nil {
+		return nil, err
+	}
+	if err := cs.checkStaleness(in.GetMaxStalenessMs()); err != nil {
+		return nil, err
+	}
+	bit, err := cs.repo.GetBit(ctx, in.GetId(), in.GetOffset())
+	if err != nil {
+		return nil, repoError("getbit", in.GetId(), err)
+	}
+	return &api.GetBitResponse{Value: bit}, nil
+}
+
+func (cs *CommandServer) BitCount(ctx context.Context, in *api.BitCountRequest) (*api.BitCountResponse, error) {
+	if in.GetId() == "" {
+		return nil, invalidArgument("bitcount", "id", "must not be empty")
+	}
+	if err := cs.checkStaleness(in.GetMaxStalenessMs()); err != nil {
+		return nil, err
+	}
+	count, err := cs.repo.BitCount(ctx, in.GetId(), bitRange(in.GetRange()))
+	if err != nil {
+		return nil, repoError("bitcount", in.GetId(), err)
+	}
+	return &api.BitCountResponse{Count: count}, nil
+}
+
+func (cs *CommandServer) BitPos(ctx context.Context, in *api.BitPosRequest) (*api.BitPosResponse, error) {
+	if in.GetId() == "" {
+		return nil, invalidArgument("bitpos", "id", "must not be empty")
+	}
+	if err := cs.checkStaleness(in.GetMaxStalenessMs()); err != nil {
+		return nil, err
+	}
+	position, err := cs.repo.BitPos(ctx, in.GetId(), in.GetBit(), bitRange(in.GetRange()))
+	if err != nil {
+		return nil, repoError("bitpos", in.GetId(), err)
+	}
+	return &api.BitPosResponse{Position: position}, nil
+}
+
+// bitRange converts rng, the whole string when unset.
+func bitRange(rng *api.BitRange) core.BitRange {
+	if rng == nil {
+		return core.BitRange{End: -1}
+	}
+	return core.BitRange{Start: rng.GetStart(), End: rng.GetEnd(), Bits: rng.GetBits()}
+}
+
+func (cs *CommandServer) BitOp(ctx context.Context, in *api.BitOpRequest) (*api.BitOpResponse, error) {
+	op, err := validateBitOp(in)
+	if err != nil {
+		return nil, err
+	}
+	resp, err := cs.applyCommand(ctx, "bitop", in.GetDest(), &replication.RaftCommand{
+		Op:    replication.OpBitOp,
+		Key:   in.GetDest(),
+		Value: op,
+		Keys:  in.GetSources(),
+	})
+	if err != nil {
+		return nil, err
+	}
+	return &api.BitOpResponse{Length: resp.Count}, nil
+}
+
+// validateBitOp checks in, and returns its operation.
+func validateBitOp(in *api.BitOpRequest) (string, error) {
+	if in.GetDest() == "" {
+		return "", invalidArgument("bitop", "dest", "must not be empty")
+	}
+	if err := validateIDs("bitop", "sources", in.GetSources()); err != nil {
+		return "", err
+	}
+	switch in.GetOperation() {
+	case api.BitOperation_BIT_AND:
+		return core.BitAnd, nil
+	case api.BitOperation_BIT_OR:
+		return core.BitOr, nil
+	case api.BitOperation_BIT_XOR:
+		return core.BitXor, nil
+	case api.BitOperation_BIT_NOT:
+		if len(in.GetSources()) != 1 {
+			return "", invalidArgument("bitop", "sources", "BIT_NOT takes a single source")
+		}
+		return core.BitNot, nil
+	default:
+		return "", invalidArgument("bitop", "operation", "unknown operation")
+	}
+}
+
+func (cs *CommandServer) BitField(ctx context.Context, in *api.BitFieldRequest) (*api.BitFieldResponse, error) {
+	if in.GetId() == "" {
+		return nil, invalidArgument("bitfield", "id", "must not be empty")
+	}
+	ops, err := bitFieldOps(in.GetOps())
+	if err != nil {
+		return nil, err
+	}
+	resp, err := cs.applyCommand(ctx, "bitfield", in.GetId(), &replication.RaftCommand{
+		Op:        replication.OpBitField,
+		Key:       in.GetId(),
+		BitFields: ops,
+	})
+	if err != nil {
+		return nil, err
+	}
+	results := make([]*api.BitFieldResult, len(resp.BitFields))
+	for i, result := range resp.BitFields {
+		results[i] = &api.BitFieldResult{Value: result.Value, Failed: result.Failed}
+	}
+	return &api.BitFieldResponse{Results: results}, nil
+}
+
+// bitFieldOps validates ops and converts them.
+func bitFieldOps(ops []*api.BitFieldOp) ([]core.BitFieldOp, error) {
+	if len(ops) == 0 {
+		return nil, invalidArgument("bitfield", "ops", "must not be empty")
+	}
+	converted := make([]core.BitFieldOp, len(ops))
+	for i, op := range ops {
+		field := fmt.Sprintf("ops[%d]", i)
+		maxWidth := int64(63)
+		if op.GetSigned() {
+			maxWidth = 64
+		}
+		if op.GetWidth() < 1 || op.GetWidth() > maxWidth {
+			return nil, invalidArgument("bitfield", field+".width", fmt.Sprintf("must be between 1 and %d", maxWidth))
+		}
+		offset := op.GetOffset()
+		if offset < 0 {
+			return nil, invalidArgument("bitfield", field+".offset", "must not be negative")
+		}
+		if op.GetOffsetInUnits() {
+			if offset > core.MaxBitOffset/op.GetWidth() {
+				return nil, invalidArgument("bitfield", field+".offset", fmt.Sprintf("must not exceed %d", core.MaxBitOffset))
+			}
+			offset *= op.GetWidth()
+		}
+		if err := validateBitOffset("bitfield", field+".offset", offset+op.GetWidth()-1); err != nil {
+			return nil, err
+		}
+		converted[i] = core.BitFieldOp{Signed: op.GetSigned(), Width: op.GetWidth(), Offset: offset, Value: op.GetValue()}
+		switch op.GetKind() {
+		case api.BitFieldOp_GET:
+			converted[i].Op = core.BitFieldGet
+		case api.BitFieldOp_SET:
+			converted[i].Op = core.BitFieldSet
+		case api.BitFieldOp_INCRBY:
+			converted[i].Op = core.BitFieldIncrBy
+		default:
+			return nil, invalidArgument("bitfield", field+".kind", "unknown kind")
+		}
+		switch op.GetOverflow() {
+		case api.BitFieldOp_WRAP:
+			converted[i].Overflow = core.OverflowWrap
+		case api.BitFieldOp_SAT:
+			converted[i].Overflow = core.OverflowSat
+		case api.BitFieldOp_FAIL:
+			converted[i].Overflow = core.OverflowFail
+		default:
+			return nil, invalidArgument("bitfield", field+".overflow", "unknown overflow")
+		}
+	}
+	return converted, nil
+}
+
+// validateBitOffset checks that offset, the field of the request named
+// field, is a bit offset a string can have.
+func validateBitOffset(op, field string, offset int64) error {
+	if offset < 0 || offset > core.MaxBitOffset {
+		return invalidArgument(op, field, fmt.Sprintf("must be between 0 and %d", core.MaxBitOffset))
+	}
+	return nil
+}
+
+func (r *ShardRouter) SetBit(ctx context.Context, in *api.SetBitRequest) (*api.SetBitResponse, error) {
+	if in.GetId() == "" {
+		return nil, invalidArgument("setbit", "id", "must not be empty")
+	}
+	cs, shard, err := r.route(ctx, in.GetId(), true)
+	if err != nil {
+		return nil, err
+	}
+	if cs != nil {
+		return cs.SetBit(ctx, in)
+	}
+	var resp *api.SetBitResponse
+	err = r.forward(ctx, shard, func(ctx context.Context, c api.CommandsClient) (err error) {
+		resp, err = c.SetBit(ctx, in)
+		return err
+	})
+	return resp, err
+}
+
+func (r *ShardRouter) GetBit(ctx context.Context, in *api.GetBitRequest) (*api.GetBitResponse, error) {
+	if in.GetId() == "" {
+		return nil, invalidArgument("getbit", "id", "must not be empty")
+	}
+	cs, shard, err := r.route(ctx, in.GetId(), false)
+	if err != nil {
+		return nil, err
+	}
+	if cs != nil {
+		return cs.GetBit(ctx, in)
+	}
+	var resp *api.GetBitResponse
+	err = r.forward(ctx, shard, func(ctx context.Context, c api.CommandsClient) (err error) {
+		resp, err = c.GetBit(ctx, in)
+		return err
+	})
+	return resp, err
+}
+
+func (r *ShardRouter) BitCount(ctx context.Context, in *api.BitCountRequest) (*api.BitCountResponse, error) {
+	if in.GetId() == "" {
+		return nil, invalidArgument("bitcount", "id", "must not be empty")
+	}
+	cs, shard, err := r.route(ctx, in.GetId(), false)
+	if err != nil {
+		return nil, err
+	}
+	if cs != nil {
+		return cs.BitCount(ctx, in)
+	}
+	var resp *api.BitCountResponse
+	err = r.forward(ctx, shard, func(ctx context.Context, c api.CommandsClient) (err error) {
+		resp, err = c.BitCount(ctx, in)
+		return err
+	})
+	return resp, err
+}
+
+func (r *ShardRouter) BitPos(ctx context.Context, in *api.BitPosRequest) (*api.BitPosResponse, error) {
+	if in.GetId() == "" {
+		return nil, invalidArgument("bitpos", "id", "must not be empty")
+	}
+	cs, shard, err := r.route(ctx, in.GetId(), false)
+	if err != nil {
+		return nil, err
+	}
+	if cs != nil {
+		return cs.BitPos(ctx, in)
+	}
+	var resp *api.BitPosResponse
+	err = r.forward(ctx, shard, func(ctx context.Context, c api.CommandsClient) (err error) {
+		resp, err = c.BitPos(ctx, in)
+		return err
+	})
+	return resp, err
+}
+
+func (r *ShardRouter) BitOp(ctx context.Context, in *api.BitOpRequest) (*api.BitOpResponse, error) {
+	if _, err := validateBitOp(in); err != nil {
+		return nil, err
+	}
+	ids := append([]string{in.GetDest()}, in.GetSources()...)
+	cs, shard, err := r.routeSameShard(ctx, "bitop", "sources", ids, true)
+	if err != nil {
+		return nil, err
+	}
+	if cs != nil {
+		return cs.BitOp(ctx, in)
+	}
+	var resp *api.BitOpResponse
+	err = r.forward(ctx, shard, func(ctx context.Context, c api.CommandsClient) (err error) {
+		resp, err = c.BitOp(ctx, in)
+		return err
+	})
+	return resp, err
+}
+
+func (r *ShardRouter) BitField(ctx context.Context, in *api.BitFieldRequest) (*api.BitFieldResponse, error) {
+	if in.GetId() == "" {
+		return nil, invalidArgument("bitfield", "id", "must not be empty")
+	}
+	cs, shard, err := r.route(ctx, in.GetId(), true)
+	if err != nil {
+		return nil, err
+	}
+	if cs != nil {
+		return cs.BitField(ctx, in)
+	}
+	var resp *api.BitFieldResponse
+	err = r.forward(ctx, shard, func(ctx context.Context, c api.CommandsClient) (err error) {
+		resp, err = c.BitField(ctx, in)
+		return err
+	})
+	return resp, err
+}
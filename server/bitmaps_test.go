@@ -0,0 +1,150 @@
+package server
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/mateenbagheri/memorabilia/api"
+	"github.com/mateenbagheri/memorabilia/pkg/core"
+	"github.com/mateenbagheri/memorabilia/pkg/sharding"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"google.golang.org/grpc/codes"
+)
+
+func TestCommandServer_Bitmap(t *testing.T) {
+	cs := NewCommandServer(core.NewInMemoryCommandRepository())
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+
+	for _, offset := range []int64{0, 3, 9} {
+		set, err := cs.SetBit(ctx, &api.SetBitRequest{Id: "dau", Offset: offset, Value: true})
+		require.NoError(t, err)
+		assert.False(t, set.GetPrevious())
+	}
+	bit, err := cs.GetBit(ctx, &api.GetBitRequest{Id: "dau", Offset: 3})
+	require.NoError(t, err)
+	assert.True(t, bit.GetValue())
+	count, err := cs.BitCount(ctx, &api.BitCountRequest{Id: "dau"})
+	require.NoError(t, err)
+	assert.Equal(t, int64(3), count.GetCount())
+	count, err = cs.BitCount(ctx, &api.BitCountRequest{Id: "dau", Range: &api.BitRange{Start: 1, End: -1}})
+	require.NoError(t, err)
+	assert.Equal(t, int64(1), count.GetCount())
+	pos, err := cs.BitPos(ctx, &api.BitPosRequest{Id: "dau", Bit: false})
+	require.NoError(t, err)
+	assert.Equal(t, int64(1), pos.GetPosition())
+
+	// The bitmap isn't valid UTF-8, so Get returns it as raw bytes.
+	got, err := cs.Get(ctx, &api.GetRequest{Id: "dau"})
+	require.NoError(t, err)
+	assert.Empty(t, got.GetValue())
+	assert.Equal(t, []byte{0x90, 0x40}, got.GetRawValue())
+
+	_, err = cs.Set(ctx, &api.SetRequest{Id: "other", Value: "A"})
+	require.NoError(t, err)
+	op, err := cs.BitOp(ctx, &api.BitOpRequest{Operation: api.BitOperation_BIT_OR, Dest: "both", Sources: []string{"dau", "other"}})
+	require.NoError(t, err)
+	assert.Equal(t, int64(2), op.GetLength())
+	got, err = cs.Get(ctx, &api.GetRequest{Id: "both"})
+	require.NoError(t, err)
+	assert.Equal(t, []byte{0xd1, 0x40}, got.GetRawValue())
+
+	fields, err := cs.BitField(ctx, &api.BitFieldRequest{Id: "counters", Ops: []*api.BitFieldOp{
+		{Kind: api.BitFieldOp_INCRBY, Width: 4, Offset: 1, OffsetInUnits: true, Value: 15},
+		{Kind: api.BitFieldOp_INCRBY, Width: 4, Offset: 1, OffsetInUnits: true, Value: 1, Overflow: api.BitFieldOp_FAIL},
+		{Kind: api.BitFieldOp_GET, Signed: true, Width: 8, Offset: 0},
+	}})
+	require.NoError(t, err)
+	require.Len(t, fields.GetResults(), 3)
+	assert.Equal(t, int64(15), fields.GetResults()[0].GetValue())
+	assert.True(t, fields.GetResults()[1].GetFailed())
+	assert.Equal(t, int64(15), fields.GetResults()[2].GetValue())
+	got, err = cs.Get(ctx, &api.GetRequest{Id: "counters"})
+	require.NoError(t, err)
+	assert.Equal(t, "\x0f", got.GetValue())
+
+	_, err = cs.SetBit(ctx, &api.SetBitRequest{Id: "k", Offset: core.MaxBitOffset + 1})
+	code, _, _, _ := errorDetails(t, err)
+	assert.Equal(t, codes.InvalidArgument, code)
+	_, err = cs.BitOp(ctx, &api.BitOpRequest{Operation: api.BitOperation_BIT_NOT, Dest: "d", Sources: []string{"a", "b"}})
+	code, _, _, _ = errorDetails(t, err)
+	assert.Equal(t, codes.InvalidArgument, code)
+	_, err = cs.BitField(ctx, &api.BitFieldRequest{Id: "k", Ops: []*api.BitFieldOp{{Width: 64}}})
+	code, _, _, _ = errorDetails(t, err)
+	assert.Equal(t, codes.InvalidArgument, code)
+
+	_, err = cs.PFAdd(ctx, &api.PFAddRequest{Id: "hll", Items: []string{"a"}})
+	require.NoError(t, err)
+	_, err = cs.SetBit(ctx, &api.SetBitRequest{Id: "hll", Offset: 1})
+	code, info, _, _ := errorDetails(t, err)
+	assert.Equal(t, codes.FailedPrecondition, code)
+	assert.Equal(t, ReasonWrongType, info.GetReason())
+}
+
+func TestShardRouter_Bitmap(t *testing.T) {
+	host := newTestShardHost(t)
+	router := NewShardRouter(host)
+	defer router.Close()
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+	defer cancel()
+	require.NoError(t, host.AddShard(ctx, 1, []string{"n1"}, []int{
+		sharding.SlotOf("a"), sharding.SlotOf("b"),
+	}))
+
+	_, err := router.SetBit(ctx, &api.SetBitRequest{Id: "a", Offset: 7, Value: true})
+	require.NoError(t, err)
+	set, err := router.SetBit(ctx, &api.SetBitRequest{Id: "a", Offset: 7})
+	require.NoError(t, err)
+	assert.True(t, set.GetPrevious())
+	fields, err := router.BitField(ctx, &api.BitFieldRequest{Id: "a", Ops: []*api.BitFieldOp{
+		{Kind: api.BitFieldOp_SET, Width: 8, Value: 0xff},
+	}})
+	require.NoError(t, err)
+	assert.Zero(t, fields.GetResults()[0].GetValue())
+	op, err := router.BitOp(ctx, &api.BitOpRequest{Operation: api.BitOperation_BIT_NOT, Dest: "b", Sources: []string{"a"}})
+	require.NoError(t, err)
+	assert.Equal(t, int64(1), op.GetLength())
+	count, err := router.BitCount(ctx, &api.BitCountRequest{Id: "b"})
+	require.NoError(t, err)
+	assert.Zero(t, count.GetCount())
+	got, err := router.Get(ctx, &api.GetRequest{Id: "b"})
+	require.NoError(t, err)
+	assert.Equal(t, "\x00", got.GetValue())
+
+	_, err = router.BitOp(ctx, &api.BitOpRequest{Operation: api.BitOperation_BIT_AND, Dest: "b", Sources: []string{"a", "elsewhere"}})
+	code, info, _, _ := errorDetails(t, err)
+	assert.Equal(t, codes.InvalidArgument, code)
+	assert.Equal(t, ReasonInvalidArgument, info.GetReason())
+}
+
+func TestCommandServer_BitWritesReachChangeLog(t *testing.T) {
+	cs := NewCommandServer(core.NewInMemoryCommandRepository())
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+
+	_, err := cs.SetBit(ctx, &api.SetBitRequest{Id: "dau", Offset: 0, Value: true})
+	require.NoError(t, err)
+	_, err = cs.BitField(ctx, &api.BitFieldRequest{Id: "dau", Ops: []*api.BitFieldOp{{Kind: api.BitFieldOp_GET, Width: 8}}})
+	require.NoError(t, err)
+
+	changes, err := cs.changes.Subscribe(1, "").Next(ctx)
+	require.NoError(t, err)
+	require.Len(t, changes, 1, "the bitfield GET wrote nothing")
+	event := changeEvent(changes[0])
+	assert.Equal(t, api.ChangeOp_CHANGE_OP_SET, event.GetOp())
+	assert.Equal(t, "dau", event.GetId())
+	assert.Empty(t, event.GetValue())
+	assert.Equal(t, []byte{0x80}, event.GetRawValue())
+
+	// What a change event carries can be stored back with Set.
+	_, err = cs.Set(ctx, &api.SetRequest{Id: "copy", RawValue: event.GetRawValue()})
+	require.NoError(t, err)
+	got, err := cs.Get(ctx, &api.GetRequest{Id: "copy"})
+	require.NoError(t, err)
+	assert.Equal(t, []byte{0x80}, got.GetRawValue())
+	bit, err := cs.GetBit(ctx, &api.GetBitRequest{Id: "copy", Offset: 0})
+	require.NoError(t, err)
+	assert.True(t, bit.GetValue())
+}
@@ -38,38 +38,54 @@ func (s *Server) ScheduleCleanup() {
 
 // runReplicatedCleanup scans for expired keys and replicates their deletion
 // through Raft. Only the leader performs the scan — see ScheduleCleanup for why.
+// With shards, each group is cleaned up by its own leader.
 func (s *Server) runReplicatedCleanup() {
-	if !s.raftNode.IsLeader() {
+	if s.shardHost == nil {
+		s.cleanupGroup(s.raftNode, s.raftFSM, s.logger)
+		return
+	}
+	for _, id := range s.shardHost.Map().IDs() {
+		if node, fsm, ok := s.shardHost.Group(id); ok {
+			s.cleanupGroup(node, fsm, s.logger.With(slog.Uint64("shard", uint64(id))))
+		}
+	}
+}
+
+func (s *Server) cleanupGroup(node *replication.Node, fsm *replication.FSM, logger *slog.Logger) {
+	if !node.IsLeader() {
 		return
 	}
 
 	ctx := context.Background()
-	keys, err := s.raftFSM.Repository().GetExpiredKeys(ctx)
+	keys, err := fsm.Repository().GetExpiredKeys(ctx)
 	if err != nil {
-		s.logger.Error("cleanup: get expired keys failed", slog.String("error", err.Error()))
+		logger.Error("cleanup: get expired keys failed", slog.String("error", err.Error()))
 		return
 	}
 	if len(keys) == 0 {
 		return
 	}
 
-	if err := s.raftNode.Apply(&replication.RaftCommand{
-		Op:   replication.OpBatchDelete,
-		Keys: keys,
-	}); err != nil {
-		s.logger.Error("cleanup: replicated batch delete failed", slog.String("error", err.Error()))
+	resp, err := node.Apply(&replication.RaftCommand{
+		Op:      replication.OpBatchDelete,
+		Keys:    keys,
+		Expired: true,
+	})
+	if err != nil {
+		logger.Error("cleanup: replicated batch delete failed", slog.String("error", err.Error()))
 		return
 	}
 
-	s.logger.Info("replicated TTL cleanup", slog.Int("keys_deleted", len(keys)))
+	logger.Info("replicated TTL cleanup", slog.Int64("keys_deleted", resp.DeleteCount))
 }
 
 // runDirectCleanup scans for expired keys and removes them from the local
 // store directly. This is the original implementation before raft became a
-// thing in the project
+// thing in the project; it goes through the CommandServer so that Subscribe
+// streams see the deletions.
 func (s *Server) runDirectCleanup() {
 	ctx := context.Background()
-	deleteCount, err := s.commandsRepository.Cleanup(ctx)
+	deleteCount, err := s.direct.cleanup(ctx)
 	if err != nil {
 		s.logger.Error("cleanup failed", slog.String("error", err.Error()))
 		return
@@ -78,3 +94,70 @@ func (s *Server) runDirectCleanup() {
 		s.logger.Info("cleaned up expired keys", slog.Int64("keys_deleted", deleteCount))
 	}
 }
+
+// sessionExpiryInterval is how often ended sessions are looked for. Sessions
+// live for seconds rather than for the minute between TTL cleanups, so
+// they get a job of their own.
+const sessionExpiryInterval = "1s"
+
+// ScheduleSessionExpiry registers a recurring job that closes the client
+// sessions that ended, deleting their ephemeral keys. Like ScheduleCleanup,
+// it runs on the leader only in Raft mode, and replicates each session's
+// closing as one command, so its keys go at once on every node.
+func (s *Server) ScheduleSessionExpiry() {
+	s.scheduler.ScheduleIntervalJob(sessionExpiryInterval, func() {
+		if s.raftNode != nil {
+			s.runReplicatedSessionExpiry()
+			return
+		}
+		s.runDirectSessionExpiry()
+	})
+}
+
+// runReplicatedSessionExpiry closes the ended sessions through Raft, on the
+// leader; with shards, each group's leader closes them in its group.
+func (s *Server) runReplicatedSessionExpiry() {
+	if s.shardHost == nil {
+		s.expireSessionsGroup(s.raftNode, s.raftFSM, s.logger)
+		return
+	}
+	for _, id := range s.shardHost.Map().IDs() {
+		if node, fsm, ok := s.shardHost.Group(id); ok {
+			s.expireSessionsGroup(node, fsm, s.logger.With(slog.Uint64("shard", uint64(id))))
+		}
+	}
+}
+
+func (s *Server) expireSessionsGroup(node *replication.Node, fsm *replication.FSM, logger *slog.Logger) {
+	if !node.IsLeader() {
+		return
+	}
+
+	// A session that ended can't be kept alive or given keys anymore, so
+	// the keys collected here are all it has.
+	ctx := context.Background()
+	repo := fsm.Repository()
+	for _, id := range repo.ExpiredSessions(ctx) {
+		resp, err := node.Apply(closeSessionCommand(id, repo.SessionKeys(ctx, id), true))
+		if err != nil {
+			logger.Error("session expiry: replicated close failed",
+				slog.String("session", id), slog.String("error", err.Error()))
+			return
+		}
+		logger.Info("replicated session expiry",
+			slog.String("session", id), slog.Int64("keys_deleted", resp.DeleteCount))
+	}
+}
+
+// runDirectSessionExpiry closes the ended sessions in the local store.
+func (s *Server) runDirectSessionExpiry() {
+	closed, deleteCount, err := s.direct.expireSessions(context.Background())
+	if err != nil {
+		s.logger.Error("session expiry failed", slog.String("error", err.Error()))
+		return
+	}
+	if closed > 0 {
+		s.logger.Info("expired sessions",
+			slog.Int("sessions", closed), slog.Int64("keys_deleted", deleteCount))
+	}
+}
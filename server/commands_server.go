@@ -2,21 +2,20 @@ package server
 
 import (
 	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"slices"
+	"sync"
 	"time"
+	"unicode/utf8"
 
 	"github.com/mateenbagheri/memorabilia/api"
 	"github.com/mateenbagheri/memorabilia/pkg/core"
 	"github.com/mateenbagheri/memorabilia/pkg/replication"
-	"google.golang.org/grpc/codes"
-	"google.golang.org/grpc/status"
 	"google.golang.org/protobuf/types/known/emptypb"
 )
 
-const (
-	applyTimeout   = 5 * time.Second
-	snapshotRetain = 3
-)
-
 type CommandServer struct {
 	api.UnimplementedCommandsServer
 
@@ -26,12 +25,26 @@ type CommandServer struct {
 	repo core.CommandsRepository
 	fsm  *replication.FSM
 	node *replication.Node
+
+	// changes serves Subscribe. In Raft mode it is the FSM's; in direct mode
+	// writes record themselves in it, holding writeMu so that the changes
+	// are recorded in the order the writes happened in.
+	changes *replication.ChangeLog
+	writeMu sync.Mutex
+
+	// waiters are the requests waiting for this store's lists and locks.
+	waiters *keyWaiters
+
+	// lockToken numbers the locks acquired in direct mode, standing in for
+	// the log index that is their fencing token in Raft mode. Guarded by
+	// writeMu.
+	lockToken uint64
 }
 
 func NewCommandServer(
 	repo core.CommandsRepository,
 ) *CommandServer {
-	return &CommandServer{repo: repo}
+	return &CommandServer{repo: repo, changes: replication.NewChangeLog(), waiters: newKeyWaiters()}
 }
 
 func NewCommandServerWithRaft(
@@ -39,9 +52,11 @@ func NewCommandServerWithRaft(
 	node *replication.Node,
 ) *CommandServer {
 	return &CommandServer{
-		fsm:  fsm,
-		node: node,
-		repo: fsm.Repository(),
+		fsm:     fsm,
+		node:    node,
+		repo:    fsm.Repository(),
+		changes: fsm.Changes(),
+		waiters: newKeyWaiters(),
 	}
 }
 
@@ -56,14 +71,37 @@ func (cs *CommandServer) Echo(ctx context.Context, in *api.EchoRequest) (*api.Ec
 }
 
 func (cs *CommandServer) Get(ctx context.Context, in *api.GetRequest) (*api.GetResponse, error) {
+	if in.GetId() == "" {
+		return nil, invalidArgument("get", "id", "must not be empty")
+	}
+	if err := cs.checkStaleness(in.GetMaxStalenessMs()); err != nil {
+		return nil, err
+	}
 	val, err := cs.repo.Get(ctx, in.GetId())
 	if err != nil {
-		return nil, err
+		return nil, repoError("get", in.GetId(), err)
+	}
+	text, raw := textValue(val)
+	return &api.GetResponse{Value: text, RawValue: raw}, nil
+}
+
+// textValue splits value into the string and bytes fields of a response:
+// protobuf strings must be valid UTF-8, which strings written bit by bit may
+// not be.
+func textValue(value string) (text string, raw []byte) {
+	if utf8.ValidString(value) {
+		return value, nil
 	}
-	return &api.GetResponse{Value: val}, nil
+	return "", []byte(value)
 }
 
-func (cs *CommandServer) Set(ctx context.Context, in *api.SetRequest) (*emptypb.Empty, error) {
+func (cs *CommandServer) Set(ctx context.Context, in *api.SetRequest) (*api.SetResponse, error) {
+	if in.GetId() == "" {
+		return nil, invalidArgument("set", "id", "must not be empty")
+	}
+	if in.GetTtl() < 0 {
+		return nil, invalidArgument("set", "ttl", "must not be negative")
+	}
 	ttl := time.Duration(in.Ttl)
 	var expiration time.Time
 	if in.GetTtl() == 0 {
@@ -76,84 +114,440 @@ func (cs *CommandServer) Set(ctx context.Context, in *api.SetRequest) (*emptypb.
 			return nil, err
 		}
 
+		// The FSM computes the deadline from the command's replicated
+		// timestamp, so every node agrees on it.
 		command := &replication.RaftCommand{
-			Op:    replication.OpSet,
-			Key:   in.GetId(),
-			Value: in.GetValue(),
+			Op:       replication.OpSet,
+			Key:      in.GetId(),
+			Value:    in.GetValue(),
+			RawValue: in.GetRawValue(),
+			TTL:      ttl * time.Millisecond,
+			Session:  in.GetSession(),
 		}
 
-		if ttl > 0 {
-			command.Expiration = expiration
+		resp, err := cs.node.Apply(command)
+		if errors.Is(err, core.ErrSessionNotFound) {
+			return nil, sessionNotFoundError("set", in.GetSession())
 		}
-
-		if err := cs.node.Apply(command); err != nil {
-			return nil, status.Errorf(codes.Internal, "set (raft): %v", err)
+		if err != nil {
+			return nil, applyError("set (raft)", err)
 		}
-		return &emptypb.Empty{}, nil
+		text, raw := textValue(resp.Previous)
+		return &api.SetResponse{
+			Applied:          resp.Applied,
+			PreviousValue:    text,
+			RawPreviousValue: raw,
+			HadPrevious:      resp.HadPrevious,
+		}, nil
 	}
 
-	err := cs.repo.Set(ctx, in.GetId(), in.GetValue(), expiration)
+	cs.writeMu.Lock()
+	defer cs.writeMu.Unlock()
+	command := &replication.RaftCommand{
+		Op:         replication.OpSet,
+		Key:        in.GetId(),
+		Value:      in.GetValue(),
+		RawValue:   in.GetRawValue(),
+		Expiration: expiration,
+		Session:    in.GetSession(),
+	}
+	var previous string
+	var existed bool
+	var err error
+	if in.GetSession() != "" {
+		previous, existed, err = cs.repo.GetSetEphemeral(ctx, in.GetId(), command.SetValue(), expiration, in.GetSession())
+	} else {
+		previous, existed, err = cs.repo.GetSet(ctx, in.GetId(), command.SetValue(), expiration)
+	}
+	if errors.Is(err, core.ErrSessionNotFound) {
+		return nil, sessionNotFoundError("set", in.GetSession())
+	}
 	if err != nil {
-		return nil, status.Errorf(codes.Internal, "set: %v", err)
+		return nil, internalError("set", err)
 	}
+	command.Now = time.Now()
+	cs.changes.Record(command)
 
-	return &emptypb.Empty{}, nil
+	text, raw := textValue(previous)
+	return &api.SetResponse{Applied: true, PreviousValue: text, RawPreviousValue: raw, HadPrevious: existed}, nil
 }
 
 func (cs *CommandServer) Delete(ctx context.Context, in *api.DeleteRequest) (*api.DeleteResponse, error) {
+	if in.GetId() == "" {
+		return nil, invalidArgument("delete", "id", "must not be empty")
+	}
 	if cs.isRaftMode() {
 		if err := cs.requireleader(); err != nil {
 			return nil, err
 		}
 
-		if err := cs.node.Apply(&replication.RaftCommand{
+		resp, err := cs.node.Apply(&replication.RaftCommand{
 			Op:  replication.OpDelete,
 			Key: in.GetId(),
-		}); err != nil {
-			return nil, status.Errorf(codes.Internal, "delete (raft): %v", err)
+		})
+		if err != nil {
+			return nil, applyError("delete (raft)", err)
 		}
-		return &api.DeleteResponse{DeleteCount: 1}, nil
+		return &api.DeleteResponse{DeleteCount: resp.DeleteCount, Applied: resp.Applied}, nil
 	}
+	cs.writeMu.Lock()
+	defer cs.writeMu.Unlock()
 	deleteCount := cs.repo.Delete(ctx, in.GetId())
-	return &api.DeleteResponse{DeleteCount: deleteCount}, nil
+	cs.changes.Record(&replication.RaftCommand{Op: replication.OpDelete, Key: in.GetId(), Now: time.Now()})
+	return &api.DeleteResponse{DeleteCount: deleteCount, Applied: deleteCount > 0}, nil
 }
 
 func (cs *CommandServer) BatchDelete(ctx context.Context, in *api.BatchDeleteRequest) (*api.BatchDeleteResponse, error) {
+	for i, id := range in.GetIds() {
+		if id == "" {
+			return nil, invalidArgument("batch delete", fmt.Sprintf("ids[%d]", i), "must not be empty")
+		}
+	}
 	if cs.isRaftMode() {
 		if err := cs.requireleader(); err != nil {
 			return nil, err
 		}
-		if err := cs.node.Apply(&replication.RaftCommand{
+		resp, err := cs.node.Apply(&replication.RaftCommand{
 			Op:   replication.OpBatchDelete,
 			Keys: in.GetIds(),
-		}); err != nil {
-			return nil, status.Errorf(codes.Internal, "batch delete (raft): %v", err)
+		})
+		if err != nil {
+			return nil, applyError("batch delete (raft)", err)
 		}
-		return &api.BatchDeleteResponse{DeleteCount: int64(len(in.GetIds()))}, nil
+		return &api.BatchDeleteResponse{DeleteCount: resp.DeleteCount, Applied: resp.Applied}, nil
 	}
-	deleteCount := cs.fsm.Repository().BatchDelete(ctx, in.GetIds())
-	return &api.BatchDeleteResponse{DeleteCount: deleteCount}, nil
+	cs.writeMu.Lock()
+	defer cs.writeMu.Unlock()
+	deleteCount := cs.repo.BatchDelete(ctx, in.GetIds())
+	cs.changes.Record(&replication.RaftCommand{Op: replication.OpBatchDelete, Keys: in.GetIds(), Now: time.Now()})
+	return &api.BatchDeleteResponse{DeleteCount: deleteCount, Applied: deleteCount > 0}, nil
 }
 
 func (cs *CommandServer) GetExpiredKeys(ctx context.Context, in *emptypb.Empty) (*api.GetExpiredKeysResponse, error) {
-	expiredKeys, err := cs.fsm.Repository().GetExpiredKeys(ctx)
+	expiredKeys, err := cs.repo.GetExpiredKeys(ctx)
 	if err != nil {
+		return nil, internalError("get expired keys", err)
+	}
+	return &api.GetExpiredKeysResponse{Ids: expiredKeys}, nil
+}
+
+func (cs *CommandServer) Scan(ctx context.Context, in *api.ScanRequest) (*api.ScanResponse, error) {
+	if in.GetCount() < 0 {
+		return nil, invalidArgument("scan", "count", "must not be negative")
+	}
+	if err := cs.checkStaleness(in.GetMaxStalenessMs()); err != nil {
 		return nil, err
 	}
-	return &api.GetExpiredKeysResponse{Ids: expiredKeys}, err
+	keys, next, err := cs.repo.Scan(ctx, in.GetPattern(), in.GetCursor(), in.GetCount())
+	if err != nil {
+		return nil, internalError("scan", err)
+	}
+	return &api.ScanResponse{Ids: keys, NextCursor: next}, nil
+}
+
+func (cs *CommandServer) TTL(ctx context.Context, in *api.TTLRequest) (*api.TTLResponse, error) {
+	if in.GetId() == "" {
+		return nil, invalidArgument("ttl", "id", "must not be empty")
+	}
+	if err := cs.checkStaleness(in.GetMaxStalenessMs()); err != nil {
+		return nil, err
+	}
+	ttl, err := cs.repo.TTL(ctx, in.GetId())
+	if err != nil {
+		return nil, repoError("ttl", in.GetId(), err)
+	}
+	if ttl == core.NoExpiration {
+		return &api.TTLResponse{Ttl: -1}, nil
+	}
+	return &api.TTLResponse{Ttl: ttl.Milliseconds()}, nil
+}
+
+// Subscribe streams the changes recorded from in.FromIndex on. Any node
+// serves it in Raft mode, from the writes it has applied so far.
+func (cs *CommandServer) Subscribe(in *api.SubscribeRequest, stream api.Commands_SubscribeServer) error {
+	if in.GetProgressIntervalMs() < 0 {
+		return invalidArgument("subscribe", "progress_interval_ms", "must not be negative")
+	}
+	interval := time.Duration(in.GetProgressIntervalMs()) * time.Millisecond
+
+	sub := cs.changes.Subscribe(in.GetFromIndex(), in.GetKeyPrefix())
+	for {
+		changes, err := cs.nextChanges(stream.Context(), sub, interval)
+		if errors.Is(err, context.DeadlineExceeded) && stream.Context().Err() == nil {
+			progress := &api.ChangeEvent{Index: sub.Position(), Op: api.ChangeOp_CHANGE_OP_PROGRESS}
+			if err := stream.Send(progress); err != nil {
+				return err
+			}
+			continue
+		}
+		if err != nil {
+			return subscribeError(err)
+		}
+		for _, change := range changes {
+			if err := stream.Send(changeEvent(change)); err != nil {
+				return err
+			}
+		}
+	}
+}
+
+// nextChanges waits for sub's next changes, for at most interval unless it
+// is 0.
+func (cs *CommandServer) nextChanges(ctx context.Context, sub *replication.Subscription, interval time.Duration) ([]replication.Change, error) {
+	if interval > 0 {
+		var cancel context.CancelFunc
+		ctx, cancel = context.WithTimeout(ctx, interval)
+		defer cancel()
+	}
+	return sub.Next(ctx)
+}
+
+// cleanup deletes the expired keys in direct mode, recording their deletion
+// like a BatchDelete's.
+func (cs *CommandServer) cleanup(ctx context.Context) (int64, error) {
+	cs.writeMu.Lock()
+	defer cs.writeMu.Unlock()
+	keys, err := cs.repo.GetExpiredKeys(ctx)
+	if err != nil || len(keys) == 0 {
+		return 0, err
+	}
+	deleted := cs.repo.Expire(ctx, keys)
+	if len(deleted) == 0 {
+		return 0, nil
+	}
+	cs.changes.Record(&replication.RaftCommand{Op: replication.OpBatchDelete, Keys: deleted, Expired: true, Now: time.Now()})
+	return int64(len(deleted)), nil
+}
+
+// applyCommand applies a write to a list, queue, lock, stream, rate limiter,
+// Bloom filter, HyperLogLog or bitmap at key, or to a session: through Raft
+// in Raft mode, to the repository directly otherwise. Then it wakes the
+// requests waiting for the elements it pushed or the lock it released.
+//
+// The change log records these writes by their outcome (see
+// replication.Recorded). A bitmap write is recorded as an OpSet of the value
+// it leaves, and closing a session as the deletion of its keys.
+func (cs *CommandServer) applyCommand(ctx context.Context, op, key string, cmd *replication.RaftCommand) (replication.ApplyResponse, error) {
+	var resp replication.ApplyResponse
+	var err error
+	if cs.isRaftMode() {
+		if err := cs.requireleader(); err != nil {
+			return replication.ApplyResponse{}, err
+		}
+		resp, err = cs.node.Apply(cmd)
+		if err != nil && !isRequestError(err) {
+			return replication.ApplyResponse{}, applyError(op+" (raft)", err)
+		}
+	} else {
+		resp, err = cs.applyDirect(ctx, cmd)
+	}
+	if errors.Is(err, core.ErrSessionNotFound) {
+		return replication.ApplyResponse{}, sessionNotFoundError(op, cmd.Session)
+	}
+	if err != nil {
+		return replication.ApplyResponse{}, repoError(op, key, err)
+	}
+
+	switch {
+	case cmd.Op == replication.OpListPush:
+		cs.waiters.wake(cmd.Key, len(cmd.Values))
+	case cmd.Op == replication.OpQueueNack && resp.Applied,
+		cmd.Op == replication.OpUnlock && resp.Applied:
+		cs.waiters.wake(cmd.Key, 1)
+	}
+	return resp, nil
+}
+
+// applyDirect applies a write built for applyCommand to the repository, the
+// way the FSM does in Raft mode, and records it in the change log.
+func (cs *CommandServer) applyDirect(ctx context.Context, cmd *replication.RaftCommand) (replication.ApplyResponse, error) {
+	cs.writeMu.Lock()
+	defer cs.writeMu.Unlock()
+
+	resp, err := cs.applyRepo(ctx, cmd)
+	if err != nil {
+		return resp, err
+	}
+	if written := replication.Recorded(cs.repo, cmd, resp); written != nil {
+		recorded := *written
+		recorded.Now = time.Now()
+		cs.changes.Record(&recorded)
+	}
+	return resp, nil
+}
+
+// applyRepo applies cmd to the repository for applyDirect.
+func (cs *CommandServer) applyRepo(ctx context.Context, cmd *replication.RaftCommand) (replication.ApplyResponse, error) {
+	switch cmd.Op {
+	case replication.OpListPush:
+		length, err := cs.repo.Push(ctx, cmd.Key, cmd.Values, cmd.Left)
+		return replication.ApplyResponse{Applied: err == nil, Length: length}, err
+	case replication.OpListPop:
+		key, value, ok, err := cs.repo.Pop(ctx, cmd.Keys, cmd.Left)
+		return replication.ApplyResponse{Applied: ok, Key: key, Value: value}, err
+	case replication.OpQueuePop:
+		job, ok, err := cs.repo.QueuePop(ctx, cmd.Key, cmd.TTL)
+		return replication.ApplyResponse{Applied: ok, Key: cmd.Key, Value: job.Value, Job: job}, err
+	case replication.OpQueueAck:
+		acked, err := cs.repo.QueueAck(ctx, cmd.Key, cmd.Value)
+		return replication.ApplyResponse{Applied: acked}, err
+	case replication.OpQueueNack:
+		requeued, err := cs.repo.QueueNack(ctx, cmd.Key, cmd.Value)
+		return replication.ApplyResponse{Applied: requeued}, err
+	case replication.OpLock:
+		cs.lockToken++
+		holder, leaseEnd, acquired, err := cs.repo.Lock(ctx, cmd.Key, cmd.Session, cmd.TTL, cs.lockToken)
+		return replication.ApplyResponse{Applied: acquired, Lock: holder, LeaseEnd: leaseEnd}, err
+	case replication.OpUnlock:
+		released, err := cs.repo.Unlock(ctx, cmd.Key, cmd.Session, cmd.Token)
+		return replication.ApplyResponse{Applied: released}, err
+	case replication.OpRefreshLock:
+		leaseEnd, refreshed, err := cs.repo.RefreshLock(ctx, cmd.Key, cmd.Session, cmd.Token, cmd.TTL)
+		return replication.ApplyResponse{Applied: refreshed, LeaseEnd: leaseEnd}, err
+	case replication.OpCreateSession:
+		deadline, err := cs.repo.CreateSession(ctx, cmd.Session, cmd.TTL)
+		return replication.ApplyResponse{Applied: err == nil, LeaseEnd: deadline}, err
+	case replication.OpKeepAlive:
+		deadline, err := cs.repo.KeepAlive(ctx, cmd.Session)
+		return replication.ApplyResponse{Applied: err == nil, LeaseEnd: deadline}, err
+	case replication.OpCloseSession:
+		closed, deleted := cs.repo.CloseSession(ctx, cmd.Session, cmd.Keys, cmd.Expired)
+		return replication.ApplyResponse{Applied: closed, DeleteCount: int64(len(deleted)), Keys: deleted}, nil
+	case replication.OpStreamAdd:
+		id, err := cs.repo.XAdd(ctx, cmd.Key, cmd.Value, cmd.Fields, core.StreamTrim{MaxLen: cmd.MaxLen, MinID: cmd.MinID})
+		return replication.ApplyResponse{Applied: err == nil, Value: id}, err
+	case replication.OpStreamTrim:
+		trimmed, err := cs.repo.XTrim(ctx, cmd.Key, core.StreamTrim{MaxLen: cmd.MaxLen, MinID: cmd.MinID})
+		return replication.ApplyResponse{Applied: trimmed > 0, Count: trimmed}, err
+	case replication.OpStreamCreateGroup:
+		created, err := cs.repo.XGroupCreate(ctx, cmd.Key, cmd.Group, cmd.Value)
+		return replication.ApplyResponse{Applied: created}, err
+	case replication.OpStreamReadGroup:
+		entries, err := cs.repo.XReadGroup(ctx, cmd.Key, cmd.Group, cmd.Consumer, cmd.Value, cmd.Count)
+		return replication.ApplyResponse{Applied: len(entries) > 0, StreamEntries: entries}, err
+	case replication.OpStreamAck:
+		acked, err := cs.repo.XAck(ctx, cmd.Key, cmd.Group, cmd.Values)
+		return replication.ApplyResponse{Applied: acked > 0, Count: acked}, err
+	case replication.OpStreamClaim:
+		entries, err := cs.repo.XClaim(ctx, cmd.Key, cmd.Group, cmd.Consumer, cmd.TTL, cmd.Values, cmd.Count)
+		return replication.ApplyResponse{Applied: len(entries) > 0, StreamEntries: entries}, err
+	case replication.OpRateLimit:
+		allowed, remaining, retryAfter, err := cs.repo.RateLimit(ctx, cmd.Key, cmd.RateLimitPolicy(), cmd.Cost)
+		return replication.ApplyResponse{Applied: allowed, Allowed: allowed, Count: remaining, RetryAfter: retryAfter}, err
+	case replication.OpBloomReserve:
+		created, err := cs.repo.BFReserve(ctx, cmd.Key, cmd.Bits, cmd.Hashes)
+		return replication.ApplyResponse{Applied: created}, err
+	case replication.OpBloomAdd:
+		added, err := cs.repo.BFAdd(ctx, cmd.Key, cmd.Values, cmd.Bits, cmd.Hashes)
+		return replication.ApplyResponse{Applied: slices.Contains(added, true), Flags: added}, err
+	case replication.OpHLLAdd:
+		changed, err := cs.repo.PFAdd(ctx, cmd.Key, cmd.Values)
+		return replication.ApplyResponse{Applied: changed}, err
+	case replication.OpHLLMerge:
+		return replication.ApplyResponse{Applied: true}, cs.repo.PFMerge(ctx, cmd.Key, cmd.Keys)
+	case replication.OpSetBit:
+		previous, err := cs.repo.SetBit(ctx, cmd.Key, cmd.Offset, cmd.Bit)
+		return replication.ApplyResponse{Applied: true, PreviousBit: previous}, err
+	case replication.OpBitOp:
+		length, err := cs.repo.BitOp(ctx, cmd.Value, cmd.Key, cmd.Keys)
+		return replication.ApplyResponse{Applied: true, Count: length}, err
+	case replication.OpBitField:
+		results, err := cs.repo.BitField(ctx, cmd.Key, cmd.BitFields)
+		return replication.ApplyResponse{Applied: true, BitFields: results}, err
+	default:
+		return replication.ApplyResponse{}, fmt.Errorf("apply direct: unexpected op %d", cmd.Op)
+	}
+}
+
+// changeEvent converts a change to its API form.
+func changeEvent(change replication.Change) *api.ChangeEvent {
+	event := &api.ChangeEvent{Index: change.Index, Term: change.Term}
+	cmd := change.Command
+	if cmd != nil && !cmd.Now.IsZero() {
+		event.TimestampMs = cmd.Now.UnixMilli()
+	}
+	switch {
+	case change.Snapshot:
+		event.Op = api.ChangeOp_CHANGE_OP_SNAPSHOT
+	case cmd.Op == replication.OpSet:
+		event.Op = api.ChangeOp_CHANGE_OP_SET
+		event.Id = cmd.Key
+		event.Value, event.RawValue = textValue(cmd.SetValue())
+		event.ExpiresAtMs = expiresAtMs(cmd)
+	case cmd.Op == replication.OpPut:
+		event.Op = api.ChangeOp_CHANGE_OP_PUT
+		event.Id = cmd.Key
+		event.Entry = entryJSON(cmd)
+		event.ExpiresAtMs = expiresAtMs(cmd)
+	case cmd.Op == replication.OpDelete:
+		event.Op = api.ChangeOp_CHANGE_OP_DELETE
+		event.Id = cmd.Key
+	case cmd.Op == replication.OpBatchDelete:
+		event.Op = api.ChangeOp_CHANGE_OP_BATCH_DELETE
+		event.Ids = cmd.Keys
+		event.Expired = cmd.Expired
+	}
+	return event
+}
+
+// expiresAtMs returns when the key set by cmd expires, in Unix milliseconds,
+// or 0 when it never does.
+func expiresAtMs(cmd *replication.RaftCommand) int64 {
+	expiration := cmd.Expiration
+	if cmd.TTL > 0 {
+		expiration = cmd.Now.Add(cmd.TTL)
+	}
+	if expiration.IsZero() {
+		return 0
+	}
+	return expiration.UnixMilli()
+}
+
+// entryJSON returns the entry an OpPut stores, in the JSON encoding of
+// backups.
+func entryJSON(cmd *replication.RaftCommand) []byte {
+	// Every typed value the change log records has one, snapshots rely on
+	// it.
+	data, _ := json.Marshal(cmd.Entries[cmd.Key])
+	return data
 }
 
 // requireLeader returns a gRPC FailedPrecondition error when this node is not
-// the leader. The error message includes the leader's Raft address so clients
-// can locate the leader and retry.
+// the leader. The error carries the leader's ID and addresses in its
+// ErrorInfo metadata so clients can locate the leader and retry.
 func (cs *CommandServer) requireleader() error {
 	if cs.node.IsLeader() {
 		return nil
 	}
 	leader := cs.node.LeaderRaftAddr()
 	if leader == "" {
-		return status.Error(codes.Unavailable, "no leader elected yet, retry shortly")
+		return noLeaderError()
+	}
+	meta, _ := cs.node.LeaderMeta()
+	return notLeaderError(meta.NodeID, leader, meta.GRPCAddr)
+}
+
+// checkStaleness enforces a read's max_staleness_ms in Raft mode. Any node,
+// including non-voting read replicas, answers reads from its own copy of the
+// state; this only rejects the read when that copy may be older than the
+// client is willing to accept, so it can retry on another node.
+func (cs *CommandServer) checkStaleness(maxStalenessMs int64) error {
+	if maxStalenessMs < 0 {
+		return invalidArgument("read", "max_staleness_ms", "must not be negative")
+	}
+	if !cs.isRaftMode() || maxStalenessMs == 0 {
+		return nil
+	}
+	bound := time.Duration(maxStalenessMs) * time.Millisecond
+	staleness, ok := cs.node.Staleness()
+	leader, _ := cs.node.LeaderMeta()
+	if !ok {
+		return staleReplicaError("replica may be stale: no contact with a leader yet", bound, -1, leader.GRPCAddr)
+	}
+	if staleness > bound {
+		return staleReplicaError(fmt.Sprintf(
+			"replica may be stale: last contact with leader %s ago exceeds max staleness %s",
+			staleness.Truncate(time.Millisecond), bound), bound, staleness, leader.GRPCAddr)
 	}
-	return status.Errorf(codes.FailedPrecondition,
-		"not the leader; current leader raft addr is %q", leader)
+	return nil
 }
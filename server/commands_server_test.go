@@ -2,17 +2,22 @@ package server
 
 import (
 	"context"
+	"fmt"
 	"net"
 	"testing"
 	"time"
 
+	"github.com/hashicorp/raft"
 	"github.com/mateenbagheri/memorabilia/api"
 	"github.com/mateenbagheri/memorabilia/pkg/core"
 	"github.com/mateenbagheri/memorabilia/pkg/utils/testutil"
 	"github.com/stretchr/testify/assert"
 	"github.com/stretchr/testify/require"
+	"google.golang.org/genproto/googleapis/rpc/errdetails"
 	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
 	"google.golang.org/grpc/credentials/insecure"
+	"google.golang.org/grpc/status"
 )
 
 func TestCommandServer_Echo_RandomString(t *testing.T) {
@@ -97,3 +102,259 @@ func startTestServer(t *testing.T) (*grpc.Server, chan error) {
 	}()
 	return s, errCh
 }
+
+func TestCommandServer_WriteResults(t *testing.T) {
+	ctx := context.Background()
+	server := NewCommandServer(core.NewInMemoryCommandRepository())
+
+	setResp, err := server.Set(ctx, &api.SetRequest{Id: "a", Value: "1"})
+	require.NoError(t, err)
+	assert.True(t, setResp.GetApplied())
+	assert.False(t, setResp.GetHadPrevious())
+
+	setResp, err = server.Set(ctx, &api.SetRequest{Id: "a", Value: "2"})
+	require.NoError(t, err)
+	assert.True(t, setResp.GetHadPrevious())
+	assert.Equal(t, "1", setResp.GetPreviousValue())
+
+	_, err = server.Set(ctx, &api.SetRequest{Id: "b", Value: "3"})
+	require.NoError(t, err)
+
+	delResp, err := server.Delete(ctx, &api.DeleteRequest{Id: "missing"})
+	require.NoError(t, err)
+	assert.Equal(t, int64(0), delResp.GetDeleteCount())
+	assert.False(t, delResp.GetApplied())
+
+	batchResp, err := server.BatchDelete(ctx, &api.BatchDeleteRequest{Ids: []string{"a", "b", "missing"}})
+	require.NoError(t, err)
+	assert.Equal(t, int64(2), batchResp.GetDeleteCount())
+	assert.True(t, batchResp.GetApplied())
+}
+
+// errorDetails extracts the status code and structured details of err.
+func errorDetails(t *testing.T, err error) (codes.Code, *errdetails.ErrorInfo, *errdetails.RetryInfo, *errdetails.BadRequest) {
+	t.Helper()
+	st, ok := status.FromError(err)
+	require.True(t, ok, "expected a gRPC status error, got %v", err)
+
+	var (
+		info  *errdetails.ErrorInfo
+		retry *errdetails.RetryInfo
+		bad   *errdetails.BadRequest
+	)
+	for _, d := range st.Details() {
+		switch d := d.(type) {
+		case *errdetails.ErrorInfo:
+			info = d
+		case *errdetails.RetryInfo:
+			retry = d
+		case *errdetails.BadRequest:
+			bad = d
+		}
+	}
+	require.NotNil(t, info, "every error must carry ErrorInfo")
+	assert.Equal(t, ErrorDomain, info.GetDomain())
+	return st.Code(), info, retry, bad
+}
+
+func TestCommandServer_ErrorMapping(t *testing.T) {
+	ctx := context.Background()
+	repo := core.NewInMemoryCommandRepository()
+	require.NoError(t, repo.Set(ctx, "expired", "v", time.Now().Add(-time.Second)))
+	server := NewCommandServer(repo)
+
+	tests := []struct {
+		name       string
+		call       func() error
+		wantCode   codes.Code
+		wantReason string
+		wantKey    string
+		wantField  string
+	}{
+		{
+			name: "get missing key",
+			call: func() error {
+				_, err := server.Get(ctx, &api.GetRequest{Id: "missing"})
+				return err
+			},
+			wantCode:   codes.NotFound,
+			wantReason: ReasonKeyNotFound,
+			wantKey:    "missing",
+		},
+		{
+			name: "get expired key",
+			call: func() error {
+				_, err := server.Get(ctx, &api.GetRequest{Id: "expired"})
+				return err
+			},
+			wantCode:   codes.NotFound,
+			wantReason: ReasonKeyExpired,
+			wantKey:    "expired",
+		},
+		{
+			name: "ttl of missing key",
+			call: func() error {
+				_, err := server.TTL(ctx, &api.TTLRequest{Id: "missing"})
+				return err
+			},
+			wantCode:   codes.NotFound,
+			wantReason: ReasonKeyNotFound,
+			wantKey:    "missing",
+		},
+		{
+			name: "get with empty id",
+			call: func() error {
+				_, err := server.Get(ctx, &api.GetRequest{})
+				return err
+			},
+			wantCode:   codes.InvalidArgument,
+			wantReason: ReasonInvalidArgument,
+			wantField:  "id",
+		},
+		{
+			name: "set with negative ttl",
+			call: func() error {
+				_, err := server.Set(ctx, &api.SetRequest{Id: "k", Value: "v", Ttl: -1})
+				return err
+			},
+			wantCode:   codes.InvalidArgument,
+			wantReason: ReasonInvalidArgument,
+			wantField:  "ttl",
+		},
+		{
+			name: "batch delete with empty id",
+			call: func() error {
+				_, err := server.BatchDelete(ctx, &api.BatchDeleteRequest{Ids: []string{"a", ""}})
+				return err
+			},
+			wantCode:   codes.InvalidArgument,
+			wantReason: ReasonInvalidArgument,
+			wantField:  "ids[1]",
+		},
+		{
+			name: "scan with negative count",
+			call: func() error {
+				_, err := server.Scan(ctx, &api.ScanRequest{Count: -5})
+				return err
+			},
+			wantCode:   codes.InvalidArgument,
+			wantReason: ReasonInvalidArgument,
+			wantField:  "count",
+		},
+		{
+			name: "negative max staleness",
+			call: func() error {
+				_, err := server.Get(ctx, &api.GetRequest{Id: "k", MaxStalenessMs: -1})
+				return err
+			},
+			wantCode:   codes.InvalidArgument,
+			wantReason: ReasonInvalidArgument,
+			wantField:  "max_staleness_ms",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			code, info, _, bad := errorDetails(t, tt.call())
+			assert.Equal(t, tt.wantCode, code)
+			assert.Equal(t, tt.wantReason, info.GetReason())
+			if tt.wantKey != "" {
+				assert.Equal(t, tt.wantKey, info.GetMetadata()[MetaKey])
+			}
+			if tt.wantField != "" {
+				require.NotNil(t, bad)
+				require.Len(t, bad.GetFieldViolations(), 1)
+				assert.Equal(t, tt.wantField, bad.GetFieldViolations()[0].GetField())
+			}
+		})
+	}
+}
+
+func TestNotLeaderError(t *testing.T) {
+	code, info, retry, _ := errorDetails(t, notLeaderError("n1", "10.0.0.1:7000", "10.0.0.1:50051"))
+	assert.Equal(t, codes.FailedPrecondition, code)
+	assert.Equal(t, ReasonNotLeader, info.GetReason())
+	assert.Equal(t, map[string]string{
+		MetaLeaderID:       "n1",
+		MetaLeaderRaftAddr: "10.0.0.1:7000",
+		MetaLeaderGRPCAddr: "10.0.0.1:50051",
+	}, info.GetMetadata())
+	assert.Nil(t, retry)
+}
+
+func TestNoLeaderError_SuggestsRetry(t *testing.T) {
+	code, info, retry, _ := errorDetails(t, noLeaderError())
+	assert.Equal(t, codes.Unavailable, code)
+	assert.Equal(t, ReasonNoLeader, info.GetReason())
+	require.NotNil(t, retry)
+	assert.Equal(t, electionRetryDelay, retry.GetRetryDelay().AsDuration())
+}
+
+func TestApplyError(t *testing.T) {
+	code, info, retry, _ := errorDetails(t, applyError("set (raft)", fmt.Errorf("node apply: raft: %w", raft.ErrLeadershipLost)))
+	assert.Equal(t, codes.Unavailable, code)
+	assert.Equal(t, ReasonNoLeader, info.GetReason())
+	assert.NotNil(t, retry)
+
+	code, info, _, _ = errorDetails(t, applyError("set (raft)", fmt.Errorf("disk on fire")))
+	assert.Equal(t, codes.Internal, code)
+	assert.Equal(t, ReasonInternal, info.GetReason())
+}
+
+func TestStaleReplicaError(t *testing.T) {
+	code, info, retry, _ := errorDetails(t, staleReplicaError("stale", 100*time.Millisecond, 250*time.Millisecond, "10.0.0.1:50051"))
+	assert.Equal(t, codes.Unavailable, code)
+	assert.Equal(t, ReasonStaleReplica, info.GetReason())
+	assert.Equal(t, map[string]string{
+		MetaMaxStalenessMs: "100",
+		MetaStalenessMs:    "250",
+		MetaLeaderGRPCAddr: "10.0.0.1:50051",
+	}, info.GetMetadata())
+	assert.NotNil(t, retry)
+}
+
+func TestCommandServer_Subscribe(t *testing.T) {
+	cs := NewCommandServer(core.NewInMemoryCommandRepository())
+	lis, err := net.Listen("tcp", "127.0.0.1:0")
+	require.NoError(t, err)
+	s := grpc.NewServer()
+	api.RegisterCommandsServer(s, cs)
+	go s.Serve(lis)
+	defer s.Stop()
+
+	conn, err := grpc.NewClient(lis.Addr().String(), grpc.WithTransportCredentials(insecure.NewCredentials()))
+	require.NoError(t, err)
+	defer conn.Close()
+	c := api.NewCommandsClient(conn)
+
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+	_, err = c.Set(ctx, &api.SetRequest{Id: "user:1", Value: "a", Ttl: 60000})
+	require.NoError(t, err)
+	_, err = c.Set(ctx, &api.SetRequest{Id: "order:1", Value: "b"})
+	require.NoError(t, err)
+
+	stream, err := c.Subscribe(ctx, &api.SubscribeRequest{FromIndex: 1, KeyPrefix: "user:"})
+	require.NoError(t, err)
+	event, err := stream.Recv()
+	require.NoError(t, err)
+	assert.Equal(t, uint64(1), event.GetIndex())
+	assert.Equal(t, api.ChangeOp_CHANGE_OP_SET, event.GetOp())
+	assert.Equal(t, "user:1", event.GetId())
+	assert.Equal(t, "a", event.GetValue())
+	assert.InDelta(t, time.Now().Add(time.Minute).UnixMilli(), event.GetExpiresAtMs(), 5000)
+
+	_, err = c.BatchDelete(ctx, &api.BatchDeleteRequest{Ids: []string{"order:1", "user:1"}})
+	require.NoError(t, err)
+	event, err = stream.Recv()
+	require.NoError(t, err)
+	assert.Equal(t, uint64(3), event.GetIndex())
+	assert.Equal(t, api.ChangeOp_CHANGE_OP_BATCH_DELETE, event.GetOp())
+	assert.Equal(t, []string{"user:1"}, event.GetIds())
+
+	cs.changes.Close()
+	_, err = stream.Recv()
+	code, info, _, _ := errorDetails(t, err)
+	assert.Equal(t, codes.Unavailable, code)
+	assert.Equal(t, ReasonShuttingDown, info.GetReason())
+}
@@ -0,0 +1,312 @@
+package server
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"time"
+
+	"github.com/hashicorp/raft"
+	"github.com/mateenbagheri/memorabilia/pkg/core"
+	"github.com/mateenbagheri/memorabilia/pkg/pubsub"
+	"github.com/mateenbagheri/memorabilia/pkg/replication"
+	"github.com/mateenbagheri/memorabilia/pkg/sharding"
+	"google.golang.org/genproto/googleapis/rpc/errdetails"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+	"google.golang.org/protobuf/protoadapt"
+	"google.golang.org/protobuf/types/known/durationpb"
+)
+
+// ErrorDomain is the google.rpc.ErrorInfo domain of every error returned by
+// the Commands and PubSub services.
+const ErrorDomain = "memorabilia"
+
+// Reasons set in google.rpc.ErrorInfo. Clients should switch on these rather
+// than on error messages, which are meant for humans and may change.
+const (
+	ReasonKeyNotFound     = "KEY_NOT_FOUND"
+	ReasonKeyExpired      = "KEY_EXPIRED"
+	ReasonWrongType       = "WRONG_TYPE"
+	ReasonSessionNotFound = "SESSION_NOT_FOUND"
+	ReasonNoSuchGroup     = "NO_SUCH_GROUP"
+	ReasonInvalidArgument = "INVALID_ARGUMENT"
+	ReasonNotLeader       = "NOT_LEADER"
+	ReasonNoLeader        = "NO_LEADER"
+	ReasonStaleReplica    = "STALE_REPLICA"
+	ReasonSlotMigrating   = "SLOT_MIGRATING"
+	ReasonWrongShard      = "WRONG_SHARD"
+	ReasonShuttingDown    = "SHUTTING_DOWN"
+	ReasonSlowConsumer    = "SLOW_CONSUMER"
+	ReasonInternal        = "INTERNAL"
+)
+
+// Keys set in google.rpc.ErrorInfo metadata.
+const (
+	MetaKey            = "key"
+	MetaLeaderID       = "leader_id"
+	MetaLeaderRaftAddr = "leader_raft_addr"
+	MetaLeaderGRPCAddr = "leader_grpc_addr"
+	MetaMaxStalenessMs = "max_staleness_ms"
+	MetaStalenessMs    = "staleness_ms"
+	MetaSlot           = "slot"
+	MetaShard          = "shard"
+	MetaSession        = "session"
+)
+
+// Retry delays suggested through google.rpc.RetryInfo.
+const (
+	// electionRetryDelay is about one election timeout, long enough for a new
+	// leader to be elected.
+	electionRetryDelay = 500 * time.Millisecond
+
+	// staleRetryDelay gives a lagging replica a moment to catch up.
+	staleRetryDelay = 100 * time.Millisecond
+
+	// migrationRetryDelay is a guess at how long moving a slot takes.
+	migrationRetryDelay = time.Second
+)
+
+// rpcError describes a gRPC error with its structured details. Build it in
+// place and call err() to get the status error.
+type rpcError struct {
+	code       codes.Code
+	reason     string
+	msg        string
+	metadata   map[string]string
+	retryDelay time.Duration
+	violations []*errdetails.BadRequest_FieldViolation
+}
+
+func (e rpcError) err() error {
+	details := []protoadapt.MessageV1{
+		&errdetails.ErrorInfo{Reason: e.reason, Domain: ErrorDomain, Metadata: e.metadata},
+	}
+	if e.retryDelay > 0 {
+		details = append(details, &errdetails.RetryInfo{RetryDelay: durationpb.New(e.retryDelay)})
+	}
+	if len(e.violations) > 0 {
+		details = append(details, &errdetails.BadRequest{FieldViolations: e.violations})
+	}
+
+	st, err := status.New(e.code, e.msg).WithDetails(details...)
+	if err != nil {
+		// Only fails if a detail can't be marshalled; the code and message
+		// still get through.
+		return status.Error(e.code, e.msg)
+	}
+	return st.Err()
+}
+
+// repoError maps an error returned by the repository for key to a gRPC
+// status. Errors the mapping doesn't know become Internal.
+func repoError(op, key string, err error) error {
+	meta := map[string]string{MetaKey: key}
+	switch {
+	case errors.Is(err, core.ErrNotFoundForGetOp), errors.Is(err, core.ErrNotFoundForTTLOp):
+		return rpcError{
+			code:     codes.NotFound,
+			reason:   ReasonKeyNotFound,
+			msg:      fmt.Sprintf("%s: key %q not found", op, key),
+			metadata: meta,
+		}.err()
+	case errors.Is(err, core.ErrKeyExpiredForGetOp):
+		return rpcError{
+			code:     codes.NotFound,
+			reason:   ReasonKeyExpired,
+			msg:      fmt.Sprintf("%s: key %q has expired", op, key),
+			metadata: meta,
+		}.err()
+	case errors.Is(err, core.ErrWrongType):
+		return rpcError{
+			code:     codes.FailedPrecondition,
+			reason:   ReasonWrongType,
+			msg:      fmt.Sprintf("%s: key %q holds the wrong kind of value", op, key),
+			metadata: meta,
+		}.err()
+	case errors.Is(err, core.ErrNoSuchGroup):
+		return rpcError{
+			code:     codes.NotFound,
+			reason:   ReasonNoSuchGroup,
+			msg:      fmt.Sprintf("%s: no such consumer group of stream %q", op, key),
+			metadata: meta,
+		}.err()
+	case errors.Is(err, core.ErrInvalidStreamID):
+		return rpcError{
+			code:     codes.InvalidArgument,
+			reason:   ReasonInvalidArgument,
+			msg:      fmt.Sprintf("%s: %v", op, err),
+			metadata: meta,
+		}.err()
+	default:
+		return internalError(op, err)
+	}
+}
+
+// isRequestError reports whether err, returned by the FSM, was caused by the
+// request rather than by replicating it, so that repoError maps it.
+func isRequestError(err error) bool {
+	return errors.Is(err, core.ErrWrongType) ||
+		errors.Is(err, core.ErrSessionNotFound) ||
+		errors.Is(err, core.ErrNoSuchGroup) ||
+		errors.Is(err, core.ErrInvalidStreamID)
+}
+
+// applyError maps an error from replicating a write through Raft. Losing
+// leadership mid-write or hitting a slot being moved is retryable, anything
+// else is Internal.
+func applyError(op string, err error) error {
+	switch {
+	case errors.Is(err, replication.ErrSlotFrozen):
+		return rpcError{
+			code:       codes.Unavailable,
+			reason:     ReasonSlotMigrating,
+			msg:        fmt.Sprintf("%s: %v", op, err),
+			retryDelay: migrationRetryDelay,
+		}.err()
+	case errors.Is(err, raft.ErrNotLeader),
+		errors.Is(err, raft.ErrLeadershipLost),
+		errors.Is(err, raft.ErrLeadershipTransferInProgress),
+		errors.Is(err, raft.ErrEnqueueTimeout):
+		return rpcError{
+			code:       codes.Unavailable,
+			reason:     ReasonNoLeader,
+			msg:        fmt.Sprintf("%s: %v", op, err),
+			retryDelay: electionRetryDelay,
+		}.err()
+	default:
+		return internalError(op, err)
+	}
+}
+
+func internalError(op string, err error) error {
+	return rpcError{
+		code:   codes.Internal,
+		reason: ReasonInternal,
+		msg:    fmt.Sprintf("%s: %v", op, err),
+	}.err()
+}
+
+// sessionNotFoundError is returned for requests naming a session that doesn't
+// exist or has ended. The client must create a new one, and set its
+// ephemeral keys again.
+func sessionNotFoundError(op, session string) error {
+	return rpcError{
+		code:     codes.NotFound,
+		reason:   ReasonSessionNotFound,
+		msg:      fmt.Sprintf("%s: session %q not found or ended", op, session),
+		metadata: map[string]string{MetaSession: session},
+	}.err()
+}
+
+// invalidArgument reports a malformed request field.
+func invalidArgument(op, field, description string) error {
+	return rpcError{
+		code:       codes.InvalidArgument,
+		reason:     ReasonInvalidArgument,
+		msg:        fmt.Sprintf("%s: %s %s", op, field, description),
+		violations: []*errdetails.BadRequest_FieldViolation{{Field: field, Description: description}},
+	}.err()
+}
+
+// notLeaderError is returned for writes sent to a follower. The leader's
+// addresses travel in the ErrorInfo metadata so the client can redirect;
+// leaderGRPC is empty when the leader hasn't registered it.
+func notLeaderError(leaderID, leaderRaft, leaderGRPC string) error {
+	meta := map[string]string{MetaLeaderRaftAddr: leaderRaft}
+	if leaderID != "" {
+		meta[MetaLeaderID] = leaderID
+	}
+	if leaderGRPC != "" {
+		meta[MetaLeaderGRPCAddr] = leaderGRPC
+	}
+	return rpcError{
+		code:     codes.FailedPrecondition,
+		reason:   ReasonNotLeader,
+		msg:      fmt.Sprintf("not the leader; current leader raft addr is %q", leaderRaft),
+		metadata: meta,
+	}.err()
+}
+
+// noLeaderError is returned while an election is in progress.
+func noLeaderError() error {
+	return rpcError{
+		code:       codes.Unavailable,
+		reason:     ReasonNoLeader,
+		msg:        "no leader elected yet, retry shortly",
+		retryDelay: electionRetryDelay,
+	}.err()
+}
+
+// staleReplicaError is returned when a bounded-staleness read can't be served
+// by this replica. leaderGRPC, when known, lets the client retry on the
+// leader instead of waiting for this node to catch up.
+func staleReplicaError(msg string, maxStaleness, staleness time.Duration, leaderGRPC string) error {
+	meta := map[string]string{MetaMaxStalenessMs: fmt.Sprint(maxStaleness.Milliseconds())}
+	if staleness >= 0 {
+		meta[MetaStalenessMs] = fmt.Sprint(staleness.Milliseconds())
+	}
+	if leaderGRPC != "" {
+		meta[MetaLeaderGRPCAddr] = leaderGRPC
+	}
+	return rpcError{
+		code:       codes.Unavailable,
+		reason:     ReasonStaleReplica,
+		msg:        msg,
+		metadata:   meta,
+		retryDelay: staleRetryDelay,
+	}.err()
+}
+
+// slotMigratingError is returned for writes to a key whose slot is being
+// moved to another shard. The write succeeds once the move completes.
+func slotMigratingError(key string) error {
+	slot := sharding.SlotOf(key)
+	return rpcError{
+		code:       codes.Unavailable,
+		reason:     ReasonSlotMigrating,
+		msg:        fmt.Sprintf("slot %d of key %q is moving to another shard, retry shortly", slot, key),
+		metadata:   map[string]string{MetaKey: key, MetaSlot: fmt.Sprint(slot)},
+		retryDelay: migrationRetryDelay,
+	}.err()
+}
+
+// wrongShardError is returned when a request forwarded to shard reached a
+// node that doesn't serve it there, because the sender's shard map was out
+// of date. owner is the shard this node maps it to.
+func wrongShardError(key string, shard, owner sharding.ShardID) error {
+	meta := map[string]string{MetaShard: fmt.Sprint(owner)}
+	if key != "" {
+		meta[MetaKey] = key
+	}
+	return rpcError{
+		code:       codes.FailedPrecondition,
+		reason:     ReasonWrongShard,
+		msg:        fmt.Sprintf("not served by shard %d here; owner is shard %d", shard, owner),
+		metadata:   meta,
+		retryDelay: staleRetryDelay,
+	}.err()
+}
+
+// subscribeError maps an error ending a Subscribe, PSubscribe or Watch
+// stream.
+func subscribeError(err error) error {
+	switch {
+	case errors.Is(err, context.Canceled), errors.Is(err, context.DeadlineExceeded):
+		return status.FromContextError(err).Err()
+	case errors.Is(err, pubsub.ErrSlowConsumer):
+		return rpcError{
+			code:   codes.ResourceExhausted,
+			reason: ReasonSlowConsumer,
+			msg:    "subscribe: messages arrived faster than they were read; subscribe again",
+		}.err()
+	case errors.Is(err, replication.ErrChangeLogClosed), errors.Is(err, pubsub.ErrHubClosed):
+		return rpcError{
+			code:   codes.Unavailable,
+			reason: ReasonShuttingDown,
+			msg:    "subscribe: server is shutting down; resume on another node",
+		}.err()
+	default:
+		return internalError("subscribe", err)
+	}
+}
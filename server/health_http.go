@@ -0,0 +1,104 @@
+package server
+
+import (
+	"encoding/json"
+	"errors"
+	"net/http"
+
+	"github.com/mateenbagheri/memorabilia/pkg/replication"
+)
+
+// HealthHTTPHandler serves liveness and readiness probes:
+//
+//	GET /healthz  200 while the process is up and, in Raft mode, Raft runs
+//	GET /readyz   200 when the node should receive traffic, 503 otherwise
+//
+// Readiness means the gRPC server is serving and, in Raft mode, the node
+// knows a leader and has caught up with it (see replication.Node.Readiness).
+// Both answer with a JSON body listing each check, so a failing probe says
+// why.
+//
+// Unlike RaftHTTPHandler it is registered in single-node mode too, where node
+// is nil and only the gRPC check applies.
+type HealthHTTPHandler struct {
+	node    *replication.Node
+	serving func() bool
+}
+
+// NewHealthHTTPHandler constructs a handler. node may be nil; serving reports
+// whether the gRPC server is accepting requests.
+func NewHealthHTTPHandler(node *replication.Node, serving func() bool) *HealthHTTPHandler {
+	return &HealthHTTPHandler{node: node, serving: serving}
+}
+
+// RegisterRoutes registers the probe routes on mux.
+func (h *HealthHTTPHandler) RegisterRoutes(mux *http.ServeMux) {
+	mux.HandleFunc("/healthz", h.handleHealthz)
+	mux.HandleFunc("/readyz", h.handleReadyz)
+}
+
+// ProbeCheck is one named condition of a probe response.
+type ProbeCheck struct {
+	Name  string `json:"name"`
+	OK    bool   `json:"ok"`
+	Error string `json:"error,omitempty"`
+}
+
+// ProbeResponse is the body of /healthz and /readyz.
+type ProbeResponse struct {
+	OK     bool         `json:"ok"`
+	Checks []ProbeCheck `json:"checks"`
+}
+
+func (h *HealthHTTPHandler) handleHealthz(w http.ResponseWriter, r *http.Request) {
+	checks := []ProbeCheck{{Name: "process", OK: true}}
+	if h.node != nil {
+		checks = append(checks, check("raft", h.raftRunning()))
+	}
+	writeProbe(w, checks)
+}
+
+func (h *HealthHTTPHandler) handleReadyz(w http.ResponseWriter, r *http.Request) {
+	var grpcErr error
+	if !h.serving() {
+		grpcErr = errNotServing
+	}
+	checks := []ProbeCheck{check("grpc", grpcErr)}
+	if h.node != nil {
+		checks = append(checks, check("raft", h.node.Readiness()))
+	}
+	writeProbe(w, checks)
+}
+
+// raftRunning is the liveness half of Readiness: only a Raft that has shut
+// down makes the process unhealthy; having no leader is not a reason to
+// restart it.
+func (h *HealthHTTPHandler) raftRunning() error {
+	if err := h.node.Readiness(); errors.Is(err, replication.ErrNotRunning) {
+		return err
+	}
+	return nil
+}
+
+var errNotServing = errors.New("gRPC server is not serving")
+
+func check(name string, err error) ProbeCheck {
+	if err != nil {
+		return ProbeCheck{Name: name, Error: err.Error()}
+	}
+	return ProbeCheck{Name: name, OK: true}
+}
+
+// writeProbe answers 200 when every check passed and 503 otherwise.
+func writeProbe(w http.ResponseWriter, checks []ProbeCheck) {
+	resp := ProbeResponse{OK: true, Checks: checks}
+	for _, c := range checks {
+		resp.OK = resp.OK && c.OK
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	if !resp.OK {
+		w.WriteHeader(http.StatusServiceUnavailable)
+	}
+	json.NewEncoder(w).Encode(resp)
+}
@@ -0,0 +1,100 @@
+package server
+
+import (
+	"encoding/json"
+	"io"
+	"log/slog"
+	"net"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/mateenbagheri/memorabilia/pkg/cluster"
+	"github.com/mateenbagheri/memorabilia/pkg/core"
+	"github.com/mateenbagheri/memorabilia/pkg/replication"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func probe(t *testing.T, mux *http.ServeMux, path string) (int, ProbeResponse) {
+	t.Helper()
+	rec := httptest.NewRecorder()
+	mux.ServeHTTP(rec, httptest.NewRequest(http.MethodGet, path, nil))
+
+	var resp ProbeResponse
+	require.NoError(t, json.NewDecoder(rec.Body).Decode(&resp))
+	return rec.Code, resp
+}
+
+func TestHealthHTTP_SingleNode(t *testing.T) {
+	serving := false
+	mux := http.NewServeMux()
+	NewHealthHTTPHandler(nil, func() bool { return serving }).RegisterRoutes(mux)
+
+	code, _ := probe(t, mux, "/healthz")
+	assert.Equal(t, http.StatusOK, code)
+
+	code, resp := probe(t, mux, "/readyz")
+	assert.Equal(t, http.StatusServiceUnavailable, code)
+	assert.False(t, resp.OK)
+	assert.Equal(t, []ProbeCheck{{Name: "grpc", Error: errNotServing.Error()}}, resp.Checks)
+
+	serving = true
+	code, resp = probe(t, mux, "/readyz")
+	assert.Equal(t, http.StatusOK, code)
+	assert.True(t, resp.OK)
+}
+
+func newTestRaftNode(t *testing.T) *replication.Node {
+	t.Helper()
+
+	l, err := net.Listen("tcp", "127.0.0.1:0")
+	require.NoError(t, err)
+	addr := l.Addr().String()
+	require.NoError(t, l.Close())
+
+	cfg := &cluster.Config{
+		NodeID:             "n1",
+		RaftBindAddr:       addr,
+		Bootstrap:          true,
+		Storage:            cluster.StorageMemory,
+		HeartbeatTimeout:   50 * time.Millisecond,
+		ElectionTimeout:    50 * time.Millisecond,
+		LeaderLeaseTimeout: 50 * time.Millisecond,
+	}
+	fsm := replication.NewFSM(core.NewInMemoryCommandRepository())
+	node, err := replication.NewNode(cfg, fsm, slog.New(slog.NewTextHandler(io.Discard, nil)))
+	require.NoError(t, err)
+	return node
+}
+
+func TestHealthHTTP_Raft(t *testing.T) {
+	node := newTestRaftNode(t)
+	mux := http.NewServeMux()
+	NewHealthHTTPHandler(node, func() bool { return true }).RegisterRoutes(mux)
+	NewRaftHTTPHandler(node, slog.New(slog.NewTextHandler(io.Discard, nil))).RegisterRoutes(mux)
+
+	require.Eventually(t, func() bool {
+		code, _ := probe(t, mux, "/readyz")
+		return code == http.StatusOK
+	}, 5*time.Second, 10*time.Millisecond)
+
+	rec := httptest.NewRecorder()
+	mux.ServeHTTP(rec, httptest.NewRequest(http.MethodGet, "/raft/stats", nil))
+	require.Equal(t, http.StatusOK, rec.Code)
+	var stats replication.Stats
+	require.NoError(t, json.NewDecoder(rec.Body).Decode(&stats))
+	assert.Equal(t, "Leader", stats.State)
+	assert.Equal(t, "n1", stats.LeaderID)
+	assert.NotZero(t, stats.AppliedIndex)
+
+	require.NoError(t, node.Shutdown())
+
+	code, resp := probe(t, mux, "/healthz")
+	assert.Equal(t, http.StatusServiceUnavailable, code)
+	assert.Equal(t, ProbeCheck{Name: "raft", Error: replication.ErrNotRunning.Error()}, resp.Checks[1])
+
+	code, _ = probe(t, mux, "/readyz")
+	assert.Equal(t, http.StatusServiceUnavailable, code)
+}
@@ -0,0 +1,410 @@
+package server
+
+import (
+	"context"
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/mateenbagheri/memorabilia/api"
+	"github.com/mateenbagheri/memorabilia/pkg/replication"
+	"github.com/mateenbagheri/memorabilia/pkg/sharding"
+)
+
+func (cs *CommandServer) LPush(ctx context.Context, in *api.PushRequest) (*api.PushResponse, error) {
+	return cs.push(ctx, "lpush", in, true)
+}
+
+func (cs *CommandServer) RPush(ctx context.Context, in *api.PushRequest) (*api.PushResponse, error) {
+	return cs.push(ctx, "rpush", in, false)
+}
+
+func (cs *CommandServer) push(ctx context.Context, op string, in *api.PushRequest, left bool) (*api.PushResponse, error) {
+	if in.GetId() == "" {
+		return nil, invalidArgument(op, "id", "must not be empty")
+	}
+	if len(in.GetValues()) == 0 {
+		return nil, invalidArgument(op, "values", "must not be empty")
+	}
+	resp, err := cs.applyCommand(ctx, op, in.GetId(), &replication.RaftCommand{
+		Op:     replication.OpListPush,
+		Key:    in.GetId(),
+		Values: in.GetValues(),
+		Left:   left,
+	})
+	if err != nil {
+		return nil, err
+	}
+	return &api.PushResponse{Length: resp.Length}, nil
+}
+
+func (cs *CommandServer) LPop(ctx context.Context, in *api.PopRequest) (*api.PopResponse, error) {
+	return cs.pop(ctx, "lpop", in, true)
+}
+
+func (cs *CommandServer) RPop(ctx context.Context, in *api.PopRequest) (*api.PopResponse, error) {
+	return cs.pop(ctx, "rpop", in, false)
+}
+
+func (cs *CommandServer) pop(ctx context.Context, op string, in *api.PopRequest, left bool) (*api.PopResponse, error) {
+	if in.GetId() == "" {
+		return nil, invalidArgument(op, "id", "must not be empty")
+	}
+	resp, err := cs.applyCommand(ctx, op, in.GetId(), &replication.RaftCommand{
+		Op:   replication.OpListPop,
+		Keys: []string{in.GetId()},
+		Left: left,
+	})
+	if err != nil {
+		return nil, err
+	}
+	return &api.PopResponse{Found: resp.Applied, Id: resp.Key, Value: resp.Value}, nil
+}
+
+func (cs *CommandServer) BLPop(ctx context.Context, in *api.BlockingPopRequest) (*api.PopResponse, error) {
+	return cs.blockingListPop(ctx, "blpop", in, true)
+}
+
+func (cs *CommandServer) BRPop(ctx context.Context, in *api.BlockingPopRequest) (*api.PopResponse, error) {
+	return cs.blockingListPop(ctx, "brpop", in, false)
+}
+
+func (cs *CommandServer) blockingListPop(ctx context.Context, op string, in *api.BlockingPopRequest, left bool) (*api.PopResponse, error) {
+	if err := validateBlockingPop(op, in); err != nil {
+		return nil, err
+	}
+	ids := in.GetIds()
+	ready := func() (bool, error) {
+		for _, id := range ids {
+			length, err := cs.repo.Len(ctx, id)
+			if err != nil {
+				return false, repoError(op, id, err)
+			}
+			if length > 0 {
+				return true, nil
+			}
+		}
+		return false, nil
+	}
+	pop := func() (replication.ApplyResponse, error) {
+		return cs.applyCommand(ctx, op, strings.Join(ids, ","), &replication.RaftCommand{
+			Op:   replication.OpListPop,
+			Keys: ids,
+			Left: left,
+		})
+	}
+	resp, err := cs.blockingApply(ctx, op, ids, time.Duration(in.GetTimeoutMs())*time.Millisecond, ready, pop)
+	if err != nil {
+		return nil, err
+	}
+	return &api.PopResponse{Found: resp.Applied, Id: resp.Key, Value: resp.Value}, nil
+}
+
+func validateBlockingPop(op string, in *api.BlockingPopRequest) error {
+	if err := validateIDs(op, "ids", in.GetIds()); err != nil {
+		return err
+	}
+	if in.GetTimeoutMs() < 0 {
+		return invalidArgument(op, "timeout_ms", "must not be negative")
+	}
+	return nil
+}
+
+func (cs *CommandServer) LLen(ctx context.Context, in *api.LLenRequest) (*api.LLenResponse, error) {
+	if in.GetId() == "" {
+		return nil, invalidArgument("llen", "id", "must not be empty")
+	}
+	if err := cs.checkStaleness(in.GetMaxStalenessMs()); err != nil {
+		return nil, err
+	}
+	length, err := cs.repo.Len(ctx, in.GetId())
+	if err != nil {
+		return nil, repoError("llen", in.GetId(), err)
+	}
+	return &api.LLenResponse{Length: length}, nil
+}
+
+func (cs *CommandServer) QueuePop(ctx context.Context, in *api.QueuePopRequest) (*api.QueuePopResponse, error) {
+	if in.GetId() == "" {
+		return nil, invalidArgument("queue pop", "id", "must not be empty")
+	}
+	if in.GetVisibilityTimeoutMs() <= 0 {
+		return nil, invalidArgument("queue pop", "visibility_timeout_ms", "must be positive")
+	}
+	if in.GetTimeoutMs() < 0 {
+		return nil, invalidArgument("queue pop", "timeout_ms", "must not be negative")
+	}
+
+	// The FSM computes the job's deadline from the command's replicated
+	// timestamp, so every node agrees on when it goes back to the queue.
+	pop := func() (replication.ApplyResponse, error) {
+		return cs.applyCommand(ctx, "queue pop", in.GetId(), &replication.RaftCommand{
+			Op:  replication.OpQueuePop,
+			Key: in.GetId(),
+			TTL: time.Duration(in.GetVisibilityTimeoutMs()) * time.Millisecond,
+		})
+	}
+	var resp replication.ApplyResponse
+	var err error
+	if in.GetBlock() {
+		ready := func() (bool, error) {
+			ready, _, err := cs.repo.QueueLen(ctx, in.GetId())
+			if err != nil {
+				return false, repoError("queue pop", in.GetId(), err)
+			}
+			return ready > 0, nil
+		}
+		resp, err = cs.blockingApply(ctx, "queue pop", []string{in.GetId()}, time.Duration(in.GetTimeoutMs())*time.Millisecond, ready, pop)
+	} else {
+		resp, err = pop()
+	}
+	if err != nil {
+		return nil, err
+	}
+	if !resp.Applied {
+		return &api.QueuePopResponse{}, nil
+	}
+	return &api.QueuePopResponse{
+		Found:      true,
+		JobId:      resp.Job.ID,
+		Value:      resp.Job.Value,
+		DeadlineMs: resp.Job.Deadline.UnixMilli(),
+	}, nil
+}
+
+func (cs *CommandServer) QueueAck(ctx context.Context, in *api.QueueJobRequest) (*api.QueueJobResponse, error) {
+	return cs.finishJob(ctx, "queue ack", in, replication.OpQueueAck)
+}
+
+func (cs *CommandServer) QueueNack(ctx context.Context, in *api.QueueJobRequest) (*api.QueueJobResponse, error) {
+	return cs.finishJob(ctx, "queue nack", in, replication.OpQueueNack)
+}
+
+func (cs *CommandServer) finishJob(ctx context.Context, op string, in *api.QueueJobRequest, jobOp replication.OpType) (*api.QueueJobResponse, error) {
+	if in.GetId() == "" {
+		return nil, invalidArgument(op, "id", "must not be empty")
+	}
+	if in.GetJobId() == "" {
+		return nil, invalidArgument(op, "job_id", "must not be empty")
+	}
+	resp, err := cs.applyCommand(ctx, op, in.GetId(), &replication.RaftCommand{
+		Op:    jobOp,
+		Key:   in.GetId(),
+		Value: in.GetJobId(),
+	})
+	if err != nil {
+		return nil, err
+	}
+	return &api.QueueJobResponse{Applied: resp.Applied}, nil
+}
+
+// -- ShardRouter --
+
+func (r *ShardRouter) LPush(ctx context.Context, in *api.PushRequest) (*api.PushResponse, error) {
+	if in.GetId() == "" {
+		return nil, invalidArgument("lpush", "id", "must not be empty")
+	}
+	cs, shard, err := r.route(ctx, in.GetId(), true)
+	if err != nil {
+		return nil, err
+	}
+	if cs != nil {
+		return cs.LPush(ctx, in)
+	}
+	var resp *api.PushResponse
+	err = r.forward(ctx, shard, func(ctx context.Context, c api.CommandsClient) (err error) {
+		resp, err = c.LPush(ctx, in)
+		return err
+	})
+	return resp, err
+}
+
+func (r *ShardRouter) RPush(ctx context.Context, in *api.PushRequest) (*api.PushResponse, error) {
+	if in.GetId() == "" {
+		return nil, invalidArgument("rpush", "id", "must not be empty")
+	}
+	cs, shard, err := r.route(ctx, in.GetId(), true)
+	if err != nil {
+		return nil, err
+	}
+	if cs != nil {
+		return cs.RPush(ctx, in)
+	}
+	var resp *api.PushResponse
+	err = r.forward(ctx, shard, func(ctx context.Context, c api.CommandsClient) (err error) {
+		resp, err = c.RPush(ctx, in)
+		return err
+	})
+	return resp, err
+}
+
+func (r *ShardRouter) LPop(ctx context.Context, in *api.PopRequest) (*api.PopResponse, error) {
+	if in.GetId() == "" {
+		return nil, invalidArgument("lpop", "id", "must not be empty")
+	}
+	cs, shard, err := r.route(ctx, in.GetId(), true)
+	if err != nil {
+		return nil, err
+	}
+	if cs != nil {
+		return cs.LPop(ctx, in)
+	}
+	var resp *api.PopResponse
+	err = r.forward(ctx, shard, func(ctx context.Context, c api.CommandsClient) (err error) {
+		resp, err = c.LPop(ctx, in)
+		return err
+	})
+	return resp, err
+}
+
+func (r *ShardRouter) RPop(ctx context.Context, in *api.PopRequest) (*api.PopResponse, error) {
+	if in.GetId() == "" {
+		return nil, invalidArgument("rpop", "id", "must not be empty")
+	}
+	cs, shard, err := r.route(ctx, in.GetId(), true)
+	if err != nil {
+		return nil, err
+	}
+	if cs != nil {
+		return cs.RPop(ctx, in)
+	}
+	var resp *api.PopResponse
+	err = r.forward(ctx, shard, func(ctx context.Context, c api.CommandsClient) (err error) {
+		resp, err = c.RPop(ctx, in)
+		return err
+	})
+	return resp, err
+}
+
+func (r *ShardRouter) BLPop(ctx context.Context, in *api.BlockingPopRequest) (*api.PopResponse, error) {
+	cs, shard, err := r.routeBlockingPop(ctx, "blpop", in)
+	if err != nil {
+		return nil, err
+	}
+	if cs != nil {
+		return cs.BLPop(ctx, in)
+	}
+	var resp *api.PopResponse
+	err = r.forward(ctx, shard, func(ctx context.Context, c api.CommandsClient) (err error) {
+		resp, err = c.BLPop(ctx, in)
+		return err
+	})
+	return resp, err
+}
+
+func (r *ShardRouter) BRPop(ctx context.Context, in *api.BlockingPopRequest) (*api.PopResponse, error) {
+	cs, shard, err := r.routeBlockingPop(ctx, "brpop", in)
+	if err != nil {
+		return nil, err
+	}
+	if cs != nil {
+		return cs.BRPop(ctx, in)
+	}
+	var resp *api.PopResponse
+	err = r.forward(ctx, shard, func(ctx context.Context, c api.CommandsClient) (err error) {
+		resp, err = c.BRPop(ctx, in)
+		return err
+	})
+	return resp, err
+}
+
+// routeBlockingPop routes a blocking pop to the shard owning its lists. A
+// pop is one write, so they must all be in the same shard.
+func (r *ShardRouter) routeBlockingPop(ctx context.Context, op string, in *api.BlockingPopRequest) (*CommandServer, sharding.ShardID, error) {
+	if err := validateBlockingPop(op, in); err != nil {
+		return nil, 0, err
+	}
+	return r.routeSameShard(ctx, op, "ids", in.GetIds(), true)
+}
+
+// routeSameShard routes a request on several keys, ids, to the shard owning
+// them all: one that can't be split, so they must all be in the same shard.
+// field names ids in the request.
+func (r *ShardRouter) routeSameShard(ctx context.Context, op, field string, ids []string, write bool) (*CommandServer, sharding.ShardID, error) {
+	m := r.host.Map()
+	for _, id := range ids[1:] {
+		if m.ShardFor(id) != m.ShardFor(ids[0]) {
+			return nil, 0, invalidArgument(op, field, fmt.Sprintf("%q and %q are in different shards", ids[0], id))
+		}
+		if m.IsMigrating(id) {
+			return nil, 0, slotMigratingError(id)
+		}
+	}
+	return r.route(ctx, ids[0], write)
+}
+
+func (r *ShardRouter) LLen(ctx context.Context, in *api.LLenRequest) (*api.LLenResponse, error) {
+	if in.GetId() == "" {
+		return nil, invalidArgument("llen", "id", "must not be empty")
+	}
+	cs, shard, err := r.route(ctx, in.GetId(), false)
+	if err != nil {
+		return nil, err
+	}
+	if cs != nil {
+		return cs.LLen(ctx, in)
+	}
+	var resp *api.LLenResponse
+	err = r.forward(ctx, shard, func(ctx context.Context, c api.CommandsClient) (err error) {
+		resp, err = c.LLen(ctx, in)
+		return err
+	})
+	return resp, err
+}
+
+func (r *ShardRouter) QueuePop(ctx context.Context, in *api.QueuePopRequest) (*api.QueuePopResponse, error) {
+	if in.GetId() == "" {
+		return nil, invalidArgument("queue pop", "id", "must not be empty")
+	}
+	cs, shard, err := r.route(ctx, in.GetId(), true)
+	if err != nil {
+		return nil, err
+	}
+	if cs != nil {
+		return cs.QueuePop(ctx, in)
+	}
+	var resp *api.QueuePopResponse
+	err = r.forward(ctx, shard, func(ctx context.Context, c api.CommandsClient) (err error) {
+		resp, err = c.QueuePop(ctx, in)
+		return err
+	})
+	return resp, err
+}
+
+func (r *ShardRouter) QueueAck(ctx context.Context, in *api.QueueJobRequest) (*api.QueueJobResponse, error) {
+	if in.GetId() == "" {
+		return nil, invalidArgument("queue ack", "id", "must not be empty")
+	}
+	cs, shard, err := r.route(ctx, in.GetId(), true)
+	if err != nil {
+		return nil, err
+	}
+	if cs != nil {
+		return cs.QueueAck(ctx, in)
+	}
+	var resp *api.QueueJobResponse
+	err = r.forward(ctx, shard, func(ctx context.Context, c api.CommandsClient) (err error) {
+		resp, err = c.QueueAck(ctx, in)
+		return err
+	})
+	return resp, err
+}
+
+func (r *ShardRouter) QueueNack(ctx context.Context, in *api.QueueJobRequest) (*api.QueueJobResponse, error) {
+	if in.GetId() == "" {
+		return nil, invalidArgument("queue nack", "id", "must not be empty")
+	}
+	cs, shard, err := r.route(ctx, in.GetId(), true)
+	if err != nil {
+		return nil, err
+	}
+	if cs != nil {
+		return cs.QueueNack(ctx, in)
+	}
+	var resp *api.QueueJobResponse
+	err = r.forward(ctx, shard, func(ctx context.Context, c api.CommandsClient) (err error) {
+		resp, err = c.QueueNack(ctx, in)
+		return err
+	})
+	return resp, err
+}
@@ -0,0 +1,180 @@
+package server
+
+import (
+	"context"
+	"encoding/json"
+	"testing"
+	"time"
+
+	"github.com/mateenbagheri/memorabilia/api"
+	"github.com/mateenbagheri/memorabilia/pkg/core"
+	"github.com/mateenbagheri/memorabilia/pkg/replication"
+	"github.com/mateenbagheri/memorabilia/pkg/types"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"google.golang.org/grpc/codes"
+)
+
+// waitingOn returns how many requests wait on key.
+func waitingOn(cs *CommandServer, key string) int {
+	cs.waiters.mu.Lock()
+	defer cs.waiters.mu.Unlock()
+	return len(cs.waiters.queues[key])
+}
+
+func TestCommandServer_BlockingPop(t *testing.T) {
+	cs := NewCommandServer(core.NewInMemoryCommandRepository())
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+
+	// Elements already there are popped right away, from the first list
+	// holding one.
+	_, err := cs.RPush(ctx, &api.PushRequest{Id: "b", Values: []string{"1", "2"}})
+	require.NoError(t, err)
+	resp, err := cs.BRPop(ctx, &api.BlockingPopRequest{Ids: []string{"a", "b"}})
+	require.NoError(t, err)
+	assert.True(t, resp.GetFound())
+	assert.Equal(t, "b", resp.GetId())
+	assert.Equal(t, "2", resp.GetValue())
+
+	// The oldest waiter gets the first element pushed, the next one the
+	// second.
+	type result struct {
+		resp *api.PopResponse
+		err  error
+	}
+	popped := make([]chan result, 3)
+	for i := range popped {
+		done := make(chan result, 1)
+		popped[i] = done
+		go func() {
+			resp, err := cs.BLPop(ctx, &api.BlockingPopRequest{Ids: []string{"q"}})
+			done <- result{resp, err}
+		}()
+		require.Eventually(t, func() bool { return waitingOn(cs, "q") == i+1 }, time.Second, time.Millisecond)
+	}
+	for i, value := range []string{"x", "y"} {
+		_, err = cs.RPush(ctx, &api.PushRequest{Id: "q", Values: []string{value}})
+		require.NoError(t, err)
+		got := <-popped[i]
+		require.NoError(t, got.err)
+		assert.Equal(t, value, got.resp.GetValue())
+	}
+	select {
+	case got := <-popped[2]:
+		t.Fatalf("third waiter popped %v", got)
+	case <-time.After(50 * time.Millisecond):
+	}
+	_, err = cs.LPush(ctx, &api.PushRequest{Id: "q", Values: []string{"z"}})
+	require.NoError(t, err)
+	got := <-popped[2]
+	require.NoError(t, got.err)
+	assert.Equal(t, "z", got.resp.GetValue())
+
+	// A timeout isn't an error.
+	start := time.Now()
+	resp, err = cs.BLPop(ctx, &api.BlockingPopRequest{Ids: []string{"q"}, TimeoutMs: 20})
+	require.NoError(t, err)
+	assert.False(t, resp.GetFound())
+	assert.GreaterOrEqual(t, time.Since(start), 20*time.Millisecond)
+	assert.Zero(t, waitingOn(cs, "q"))
+
+	_, err = cs.Set(ctx, &api.SetRequest{Id: "s", Value: "v"})
+	require.NoError(t, err)
+	_, err = cs.BLPop(ctx, &api.BlockingPopRequest{Ids: []string{"q", "s"}})
+	code, info, _, _ := errorDetails(t, err)
+	assert.Equal(t, codes.FailedPrecondition, code)
+	assert.Equal(t, ReasonWrongType, info.GetReason())
+	assert.Equal(t, "s", info.GetMetadata()[MetaKey])
+
+	_, err = cs.BLPop(ctx, &api.BlockingPopRequest{})
+	code, _, _, _ = errorDetails(t, err)
+	assert.Equal(t, codes.InvalidArgument, code)
+}
+
+func TestCommandServer_Queue(t *testing.T) {
+	cs := NewCommandServer(core.NewInMemoryCommandRepository())
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+
+	_, err := cs.RPush(ctx, &api.PushRequest{Id: "jobs", Values: []string{"a", "b"}})
+	require.NoError(t, err)
+	a, err := cs.QueuePop(ctx, &api.QueuePopRequest{Id: "jobs", VisibilityTimeoutMs: 60_000})
+	require.NoError(t, err)
+	require.True(t, a.GetFound())
+	assert.Equal(t, "a", a.GetValue())
+	assert.Greater(t, a.GetDeadlineMs(), time.Now().UnixMilli())
+	b, err := cs.QueuePop(ctx, &api.QueuePopRequest{Id: "jobs", VisibilityTimeoutMs: 60_000})
+	require.NoError(t, err)
+	assert.Equal(t, "b", b.GetValue())
+	length, err := cs.LLen(ctx, &api.LLenRequest{Id: "jobs"})
+	require.NoError(t, err)
+	assert.Zero(t, length.GetLength())
+
+	// A nack wakes a waiting pop.
+	popped := make(chan *api.QueuePopResponse, 1)
+	go func() {
+		resp, err := cs.QueuePop(ctx, &api.QueuePopRequest{Id: "jobs", VisibilityTimeoutMs: 10, Block: true})
+		assert.NoError(t, err)
+		popped <- resp
+	}()
+	require.Eventually(t, func() bool { return waitingOn(cs, "jobs") == 1 }, time.Second, time.Millisecond)
+	nacked, err := cs.QueueNack(ctx, &api.QueueJobRequest{Id: "jobs", JobId: a.GetJobId()})
+	require.NoError(t, err)
+	assert.True(t, nacked.GetApplied())
+	again := <-popped
+	assert.Equal(t, "a", again.GetValue())
+	assert.NotEqual(t, a.GetJobId(), again.GetJobId())
+
+	// Unacknowledged, it goes back after its visibility timeout and a
+	// blocking pop picks it up.
+	resp, err := cs.QueuePop(ctx, &api.QueuePopRequest{Id: "jobs", VisibilityTimeoutMs: 60_000, Block: true, TimeoutMs: 3000})
+	require.NoError(t, err)
+	require.True(t, resp.GetFound())
+	assert.Equal(t, "a", resp.GetValue())
+	acked, err := cs.QueueAck(ctx, &api.QueueJobRequest{Id: "jobs", JobId: again.GetJobId()})
+	require.NoError(t, err)
+	assert.False(t, acked.GetApplied())
+
+	for _, id := range []string{resp.GetJobId(), b.GetJobId()} {
+		acked, err = cs.QueueAck(ctx, &api.QueueJobRequest{Id: "jobs", JobId: id})
+		require.NoError(t, err)
+		assert.True(t, acked.GetApplied())
+	}
+	empty, err := cs.QueuePop(ctx, &api.QueuePopRequest{Id: "jobs", VisibilityTimeoutMs: 10})
+	require.NoError(t, err)
+	assert.False(t, empty.GetFound())
+
+	_, err = cs.QueuePop(ctx, &api.QueuePopRequest{Id: "jobs"})
+	code, _, _, _ := errorDetails(t, err)
+	assert.Equal(t, codes.InvalidArgument, code)
+}
+
+func TestCommandServer_ListWritesReachChangeLog(t *testing.T) {
+	cs := NewCommandServer(core.NewInMemoryCommandRepository())
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+
+	_, err := cs.RPush(ctx, &api.PushRequest{Id: "q", Values: []string{"a"}})
+	require.NoError(t, err)
+	_, err = cs.LPop(ctx, &api.PopRequest{Id: "q"})
+	require.NoError(t, err)
+	resp, err := cs.LPop(ctx, &api.PopRequest{Id: "q"})
+	require.NoError(t, err)
+	require.False(t, resp.GetFound())
+
+	changes, err := cs.changes.Subscribe(1, "").Next(ctx)
+	require.NoError(t, err)
+	require.Len(t, changes, 2, "the pop from an empty list changed nothing")
+
+	event := changeEvent(changes[0])
+	assert.Equal(t, api.ChangeOp_CHANGE_OP_PUT, event.GetOp())
+	assert.Equal(t, "q", event.GetId())
+	assert.NotZero(t, event.GetTimestampMs())
+	var entry types.ColumnValueWithTTL
+	require.NoError(t, json.Unmarshal(event.GetEntry(), &entry))
+	assert.Equal(t, types.List{Val: []string{"a"}}, entry.Column)
+
+	assert.Equal(t, replication.OpDelete, changes[1].Command.Op, "the pop emptied the list")
+	assert.Equal(t, uint64(2), changes[1].Index)
+}
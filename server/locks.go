@@ -0,0 +1,189 @@
+package server
+
+import (
+	"context"
+	"time"
+
+	"github.com/mateenbagheri/memorabilia/api"
+	"github.com/mateenbagheri/memorabilia/pkg/replication"
+)
+
+func (cs *CommandServer) Lock(ctx context.Context, in *api.LockRequest) (*api.LockResponse, error) {
+	if err := validateLock("lock", in.GetId(), in.GetSession(), in.GetLeaseMs()); err != nil {
+		return nil, err
+	}
+	if in.GetTimeoutMs() < 0 {
+		return nil, invalidArgument("lock", "timeout_ms", "must not be negative")
+	}
+
+	// The FSM computes the lease from the command's replicated timestamp,
+	// and takes the fencing token from the log entry carrying it.
+	lock := func() (replication.ApplyResponse, error) {
+		return cs.applyCommand(ctx, "lock", in.GetId(), &replication.RaftCommand{
+			Op:      replication.OpLock,
+			Key:     in.GetId(),
+			Session: in.GetSession(),
+			TTL:     time.Duration(in.GetLeaseMs()) * time.Millisecond,
+		})
+	}
+	var resp replication.ApplyResponse
+	var err error
+	if in.GetWait() {
+		ready := func() (bool, error) {
+			holder, _, held, err := cs.repo.LockHolder(ctx, in.GetId())
+			if err != nil {
+				return false, repoError("lock", in.GetId(), err)
+			}
+			return !held || holder.Session == in.GetSession(), nil
+		}
+		resp, err = cs.blockingApply(ctx, "lock", []string{in.GetId()}, time.Duration(in.GetTimeoutMs())*time.Millisecond, ready, lock)
+	} else {
+		resp, err = lock()
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	if !resp.Applied && resp.Lock.Session == "" {
+		// The wait timed out; report who holds the lock now, as far as
+		// this node knows.
+		resp.Lock, resp.LeaseEnd, _, err = cs.repo.LockHolder(ctx, in.GetId())
+		if err != nil {
+			return nil, repoError("lock", in.GetId(), err)
+		}
+	}
+	out := &api.LockResponse{Acquired: resp.Applied}
+	if resp.Applied {
+		out.FencingToken = resp.Lock.Token
+	} else {
+		out.HolderSession = resp.Lock.Session
+	}
+	if !resp.LeaseEnd.IsZero() {
+		out.LeaseExpiresAtMs = resp.LeaseEnd.UnixMilli()
+	}
+	return out, nil
+}
+
+func (cs *CommandServer) Unlock(ctx context.Context, in *api.UnlockRequest) (*api.UnlockResponse, error) {
+	if err := validateLockHolder("unlock", in.GetId(), in.GetSession(), in.GetFencingToken()); err != nil {
+		return nil, err
+	}
+	resp, err := cs.applyCommand(ctx, "unlock", in.GetId(), &replication.RaftCommand{
+		Op:      replication.OpUnlock,
+		Key:     in.GetId(),
+		Session: in.GetSession(),
+		Token:   in.GetFencingToken(),
+	})
+	if err != nil {
+		return nil, err
+	}
+	return &api.UnlockResponse{Released: resp.Applied}, nil
+}
+
+func (cs *CommandServer) RefreshLock(ctx context.Context, in *api.RefreshLockRequest) (*api.RefreshLockResponse, error) {
+	if err := validateLockHolder("refresh lock", in.GetId(), in.GetSession(), in.GetFencingToken()); err != nil {
+		return nil, err
+	}
+	if in.GetLeaseMs() <= 0 {
+		return nil, invalidArgument("refresh lock", "lease_ms", "must be positive")
+	}
+	resp, err := cs.applyCommand(ctx, "refresh lock", in.GetId(), &replication.RaftCommand{
+		Op:      replication.OpRefreshLock,
+		Key:     in.GetId(),
+		Session: in.GetSession(),
+		Token:   in.GetFencingToken(),
+		TTL:     time.Duration(in.GetLeaseMs()) * time.Millisecond,
+	})
+	if err != nil {
+		return nil, err
+	}
+	if !resp.Applied {
+		return &api.RefreshLockResponse{}, nil
+	}
+	return &api.RefreshLockResponse{Refreshed: true, LeaseExpiresAtMs: resp.LeaseEnd.UnixMilli()}, nil
+}
+
+func validateLock(op, id, session string, leaseMs int64) error {
+	if id == "" {
+		return invalidArgument(op, "id", "must not be empty")
+	}
+	if session == "" {
+		return invalidArgument(op, "session", "must not be empty")
+	}
+	if leaseMs <= 0 {
+		return invalidArgument(op, "lease_ms", "must be positive")
+	}
+	return nil
+}
+
+func validateLockHolder(op, id, session string, token uint64) error {
+	if id == "" {
+		return invalidArgument(op, "id", "must not be empty")
+	}
+	if session == "" {
+		return invalidArgument(op, "session", "must not be empty")
+	}
+	if token == 0 {
+		return invalidArgument(op, "fencing_token", "must not be 0")
+	}
+	return nil
+}
+
+// -- ShardRouter --
+
+func (r *ShardRouter) Lock(ctx context.Context, in *api.LockRequest) (*api.LockResponse, error) {
+	if in.GetId() == "" {
+		return nil, invalidArgument("lock", "id", "must not be empty")
+	}
+	cs, shard, err := r.route(ctx, in.GetId(), true)
+	if err != nil {
+		return nil, err
+	}
+	if cs != nil {
+		return cs.Lock(ctx, in)
+	}
+	var resp *api.LockResponse
+	err = r.forward(ctx, shard, func(ctx context.Context, c api.CommandsClient) (err error) {
+		resp, err = c.Lock(ctx, in)
+		return err
+	})
+	return resp, err
+}
+
+func (r *ShardRouter) Unlock(ctx context.Context, in *api.UnlockRequest) (*api.UnlockResponse, error) {
+	if in.GetId() == "" {
+		return nil, invalidArgument("unlock", "id", "must not be empty")
+	}
+	cs, shard, err := r.route(ctx, in.GetId(), true)
+	if err != nil {
+		return nil, err
+	}
+	if cs != nil {
+		return cs.Unlock(ctx, in)
+	}
+	var resp *api.UnlockResponse
+	err = r.forward(ctx, shard, func(ctx context.Context, c api.CommandsClient) (err error) {
+		resp, err = c.Unlock(ctx, in)
+		return err
+	})
+	return resp, err
+}
+
+func (r *ShardRouter) RefreshLock(ctx context.Context, in *api.RefreshLockRequest) (*api.RefreshLockResponse, error) {
+	if in.GetId() == "" {
+		return nil, invalidArgument("refresh lock", "id", "must not be empty")
+	}
+	cs, shard, err := r.route(ctx, in.GetId(), true)
+	if err != nil {
+		return nil, err
+	}
+	if cs != nil {
+		return cs.RefreshLock(ctx, in)
+	}
+	var resp *api.RefreshLockResponse
+	err = r.forward(ctx, shard, func(ctx context.Context, c api.CommandsClient) (err error) {
+		resp, err = c.RefreshLock(ctx, in)
+		return err
+	})
+	return resp, err
+}
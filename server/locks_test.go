@@ -0,0 +1,84 @@
+package server
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/mateenbagheri/memorabilia/api"
+	"github.com/mateenbagheri/memorabilia/pkg/core"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"google.golang.org/grpc/codes"
+)
+
+func TestCommandServer_Lock(t *testing.T) {
+	cs := NewCommandServer(core.NewInMemoryCommandRepository())
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+
+	a, err := cs.Lock(ctx, &api.LockRequest{Id: "l", Session: "a", LeaseMs: 60_000})
+	require.NoError(t, err)
+	require.True(t, a.GetAcquired())
+	assert.NotZero(t, a.GetFencingToken())
+	b, err := cs.Lock(ctx, &api.LockRequest{Id: "l", Session: "b", LeaseMs: 60_000})
+	require.NoError(t, err)
+	assert.False(t, b.GetAcquired())
+	assert.Equal(t, "a", b.GetHolderSession())
+	assert.Equal(t, a.GetLeaseExpiresAtMs(), b.GetLeaseExpiresAtMs())
+
+	// Waiting contenders get the lock in the order they arrived, each time
+	// it is released, with a greater token.
+	type result struct {
+		resp *api.LockResponse
+		err  error
+	}
+	waiting := make([]chan result, 2)
+	for i, session := range []string{"b", "c"} {
+		done := make(chan result, 1)
+		waiting[i] = done
+		go func() {
+			resp, err := cs.Lock(ctx, &api.LockRequest{Id: "l", Session: session, LeaseMs: 60_000, Wait: true})
+			done <- result{resp, err}
+		}()
+		require.Eventually(t, func() bool { return waitingOn(cs, "l") == i+1 }, time.Second, time.Millisecond)
+	}
+	refreshed, err := cs.RefreshLock(ctx, &api.RefreshLockRequest{Id: "l", Session: "a", FencingToken: a.GetFencingToken(), LeaseMs: 120_000})
+	require.NoError(t, err)
+	assert.True(t, refreshed.GetRefreshed())
+	released, err := cs.Unlock(ctx, &api.UnlockRequest{Id: "l", Session: "a", FencingToken: a.GetFencingToken()})
+	require.NoError(t, err)
+	assert.True(t, released.GetReleased())
+
+	got := <-waiting[0]
+	require.NoError(t, got.err)
+	require.True(t, got.resp.GetAcquired())
+	assert.Greater(t, got.resp.GetFencingToken(), a.GetFencingToken())
+	select {
+	case got := <-waiting[1]:
+		t.Fatalf("second contender got %v", got)
+	case <-time.After(50 * time.Millisecond):
+	}
+
+	// A stale holder can't release or refresh the lock anymore.
+	released, err = cs.Unlock(ctx, &api.UnlockRequest{Id: "l", Session: "a", FencingToken: a.GetFencingToken()})
+	require.NoError(t, err)
+	assert.False(t, released.GetReleased())
+
+	_, err = cs.Unlock(ctx, &api.UnlockRequest{Id: "l", Session: "b", FencingToken: got.resp.GetFencingToken()})
+	require.NoError(t, err)
+	last := <-waiting[1]
+	require.NoError(t, last.err)
+	assert.True(t, last.resp.GetAcquired())
+	assert.Greater(t, last.resp.GetFencingToken(), got.resp.GetFencingToken())
+
+	// A wait that times out reports the holder.
+	timedOut, err := cs.Lock(ctx, &api.LockRequest{Id: "l", Session: "d", LeaseMs: 1000, Wait: true, TimeoutMs: 20})
+	require.NoError(t, err)
+	assert.False(t, timedOut.GetAcquired())
+	assert.Equal(t, "c", timedOut.GetHolderSession())
+
+	_, err = cs.Lock(ctx, &api.LockRequest{Id: "l", Session: "d"})
+	code, _, _, _ := errorDetails(t, err)
+	assert.Equal(t, codes.InvalidArgument, code)
+}
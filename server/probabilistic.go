@@ -0,0 +1,287 @@
+package server
+
+import (
+	"context"
+	"errors"
+
+	"github.com/mateenbagheri/memorabilia/api"
+	"github.com/mateenbagheri/memorabilia/pkg/core"
+	"github.com/mateenbagheri/memorabilia/pkg/replication"
+)
+
+// The size of the Bloom filters BFAdd creates when the request doesn't say.
+const (
+	defaultBloomErrorRate = 0.01
+	defaultBloomCapacity  = 100
+)
+
+func (cs *CommandServer) BFReserve(ctx context.Context, in *api.BFReserveRequest) (*api.BFReserveResponse, error) {
+	if in.GetId() == "" {
+		return nil, invalidArgument("bf reserve", "id", "must not be empty")
+	}
+	if in.GetErrorRate() <= 0 || in.GetErrorRate() >= 1 {
+		return nil, invalidArgument("bf reserve", "error_rate", "must be between 0 and 1")
+	}
+	if in.GetCapacity() <= 0 {
+		return nil, invalidArgument("bf reserve", "capacity", "must be positive")
+	}
+	bits, hashes, err := bloomGeometry("bf reserve", in.GetErrorRate(), in.GetCapacity())
+	if err != nil {
+		return nil, err
+	}
+	resp, err := cs.applyCommand(ctx, "bf reserve", in.GetId(), &replication.RaftCommand{
+		Op:     replication.OpBloomReserve,
+		Key:    in.GetId(),
+		Bits:   bits,
+		Hashes: hashes,
+	})
+	if err != nil {
+		return nil, err
+	}
+	return &api.BFReserveResponse{Created: resp.Applied}, nil
+}
+
+func (cs *CommandServer) BFAdd(ctx context.Context, in *api.BFAddRequest) (*api.BFAddResponse, error) {
+	if in.GetId() == "" {
+		return nil, invalidArgument("bf add", "id", "must not be empty")
+	}
+	if len(in.GetItems()) == 0 {
+		return nil, invalidArgument("bf add", "items", "must not be empty")
+	}
+	errorRate, capacity := in.GetErrorRate(), in.GetCapacity()
+	if errorRate == 0 {
+		errorRate = defaultBloomErrorRate
+	}
+	if capacity == 0 {
+		capacity = defaultBloomCapacity
+	}
+	if errorRate < 0 || errorRate >= 1 {
+		return nil, invalidArgument("bf add", "error_rate", "must be between 0 and 1")
+	}
+	if capacity < 0 {
+		return nil, invalidArgument("bf add", "capacity", "must not be negative")
+	}
+	bits, hashes, err := bloomGeometry("bf add", errorRate, capacity)
+	if err != nil {
+		return nil, err
+	}
+	resp, err := cs.applyCommand(ctx, "bf add", in.GetId(), &replication.RaftCommand{
+		Op:     replication.OpBloomAdd,
+		Key:    in.GetId(),
+		Values: in.GetItems(),
+		Bits:   bits,
+		Hashes: hashes,
+	})
+	if err != nil {
+		return nil, err
+	}
+	return &api.BFAddResponse{Added: resp.Flags}, nil
+}
+
+// bloomGeometry sizes a Bloom filter for a request, on the node that
+// proposes it: replicas use the size the command carries.
+func bloomGeometry(op string, errorRate float64, capacity int64) (bits, hashes int64, err error) {
+	bits, hashes, err = core.BloomGeometry(errorRate, capacity)
+	if errors.Is(err, core.ErrBloomTooLarge) {
+		return 0, 0, invalidArgument(op, "capacity", "needs too large a filter at this error_rate")
+	}
+	return bits, hashes, err
+}
+
+func (cs *CommandServer) BFExists(ctx context.Context, in *api.BFExistsRequest) (*api.BFExistsResponse, error) {
+	if in.GetId() == "" {
+		return nil, invalidArgument("bf exists", "id", "must not be empty")
+	}
+	if err := cs.checkStaleness(in.GetMaxStalenessMs()); err != nil {
+		return nil, err
+	}
+	exists, err := cs.repo.BFExists(ctx, in.GetId(), in.GetItems())
+	if err != nil {
+		return nil, repoError("bf exists", in.GetId(), err)
+	}
+	return &api.BFExistsResponse{Exists: exists}, nil
+}
+
+func (cs *CommandServer) PFAdd(ctx context.Context, in *api.PFAddRequest) (*api.PFAddResponse, error) {
+	if in.GetId() == "" {
+		return nil, invalidArgument("pfadd", "id", "must not be empty")
+	}
+	resp, err := cs.applyCommand(ctx, "pfadd", in.GetId(), &replication.RaftCommand{
+		Op:     replication.OpHLLAdd,
+		Key:    in.GetId(),
+		Values: in.GetItems(),
+	})
+	if err != nil {
+		return nil, err
+	}
+	return &api.PFAddResponse{Changed: resp.Applied}, nil
+}
+
+func (cs *CommandServer) PFCount(ctx context.Context, in *api.PFCountRequest) (*api.PFCountResponse, error) {
+	if err := validateIDs("pfcount", "ids", in.GetIds()); err != nil {
+		return nil, err
+	}
+	if err := cs.checkStaleness(in.GetMaxStalenessMs()); err != nil {
+		return nil, err
+	}
+	count, err := cs.repo.PFCount(ctx, in.GetIds())
+	if err != nil {
+		return nil, repoError("pfcount", in.GetIds()[0], err)
+	}
+	return &api.PFCountResponse{Count: count}, nil
+}
+
+func (cs *CommandServer) PFMerge(ctx context.Context, in *api.PFMergeRequest) (*api.PFMergeResponse, error) {
+	if in.GetDest() == "" {
+		return nil, invalidArgument("pfmerge", "dest", "must not be empty")
+	}
+	for _, id := range in.GetSources() {
+		if id == "" {
+			return nil, invalidArgument("pfmerge", "sources", "must not contain an empty id")
+		}
+	}
+	_, err := cs.applyCommand(ctx, "pfmerge", in.GetDest(), &replication.RaftCommand{
+		Op:   replication.OpHLLMerge,
+		Key:  in.GetDest(),
+		Keys: in.GetSources(),
+	})
+	if err != nil {
+		return nil, err
+	}
+	return &api.PFMergeResponse{}, nil
+}
+
+// validateIDs checks that ids, the field of the request named field, holds
+// ids and no empty one.
+func validateIDs(op, field string, ids []string) error {
+	if len(ids) == 0 {
+		return invalidArgument(op, field, "must not be empty")
+	}
+	for _, id := range ids {
+		if id == "" {
+			return invalidArgument(op, field, "must not contain an empty id")
+		}
+	}
+	return nil
+}
+
+func (r *ShardRouter) BFReserve(ctx context.Context, in *api.BFReserveRequest) (*api.BFReserveResponse, error) {
+	if in.GetId() == "" {
+		return nil, invalidArgument("bf reserve", "id", "must not be empty")
+	}
+	cs, shard, err := r.route(ctx, in.GetId(), true)
+	if err != nil {
+		return nil, err
+	}
+	if cs != nil {
+		return cs.BFReserve(ctx, in)
+	}
+	var resp *api.BFReserveResponse
+	err = r.forward(ctx, shard, func(ctx context.Context, c api.CommandsClient) (err error) {
+		resp, err = c.BFReserve(ctx, in)
+		return err
+	})
+	return resp, err
+}
+
+func (r *ShardRouter) BFAdd(ctx context.Context, in *api.BFAddRequest) (*api.BFAddResponse, error) {
+	if in.GetId() == "" {
+		return nil, invalidArgument("bf add", "id", "must not be empty")
+	}
+	cs, shard, err := r.route(ctx, in.GetId(), true)
+	if err != nil {
+		return nil, err
+	}
+	if cs != nil {
+		return cs.BFAdd(ctx, in)
+	}
+	var resp *api.BFAddResponse
+	err = r.forward(ctx, shard, func(ctx context.Context, c api.CommandsClient) (err error) {
+		resp, err = c.BFAdd(ctx, in)
+		return err
+	})
+	return resp, err
+}
+
+func (r *ShardRouter) BFExists(ctx context.Context, in *api.BFExistsRequest) (*api.BFExistsResponse, error) {
+	if in.GetId() == "" {
+		return nil, invalidArgument("bf exists", "id", "must not be empty")
+	}
+	cs, shard, err := r.route(ctx, in.GetId(), false)
+	if err != nil {
+		return nil, err
+	}
+	if cs != nil {
+		return cs.BFExists(ctx, in)
+	}
+	var resp *api.BFExistsResponse
+	err = r.forward(ctx, shard, func(ctx context.Context, c api.CommandsClient) (err error) {
+		resp, err = c.BFExists(ctx, in)
+		return err
+	})
+	return resp, err
+}
+
+func (r *ShardRouter) PFAdd(ctx context.Context, in *api.PFAddRequest) (*api.PFAddResponse, error) {
+	if in.GetId() == "" {
+		return nil, invalidArgument("pfadd", "id", "must not be empty")
+	}
+	cs, shard, err := r.route(ctx, in.GetId(), true)
+	if err != nil {
+		return nil, err
+	}
+	if cs != nil {
+		return cs.PFAdd(ctx, in)
+	}
+	var resp *api.PFAddResponse
+	err = r.forward(ctx, shard, func(ctx context.Context, c api.CommandsClient) (err error) {
+		resp, err = c.PFAdd(ctx, in)
+		return err
+	})
+	return resp, err
+}
+
+// PFCount counts the union of HyperLogLogs in one shard: merging registers
+// across shards would need them shipped to one place, which PFMerge into a
+// key of the right shard already does.
+func (r *ShardRouter) PFCount(ctx context.Context, in *api.PFCountRequest) (*api.PFCountResponse, error) {
+	if err := validateIDs("pfcount", "ids", in.GetIds()); err != nil {
+		return nil, err
+	}
+	cs, shard, err := r.routeSameShard(ctx, "pfcount", "ids", in.GetIds(), false)
+	if err != nil {
+		return nil, err
+	}
+	if cs != nil {
+		return cs.PFCount(ctx, in)
+	}
+	var resp *api.PFCountResponse
+	err = r.forward(ctx, shard, func(ctx context.Context, c api.CommandsClient) (err error) {
+		resp, err = c.PFCount(ctx, in)
+		return err
+	})
+	return resp, err
+}
+
+func (r *ShardRouter) PFMerge(ctx context.Context, in *api.PFMergeRequest) (*api.PFMergeResponse, error) {
+	if in.GetDest() == "" {
+		return nil, invalidArgument("pfmerge", "dest", "must not be empty")
+	}
+	ids := append([]string{in.GetDest()}, in.GetSources()...)
+	if err := validateIDs("pfmerge", "sources", ids); err != nil {
+		return nil, err
+	}
+	cs, shard, err := r.routeSameShard(ctx, "pfmerge", "sources", ids, true)
+	if err != nil {
+		return nil, err
+	}
+	if cs != nil {
+		return cs.PFMerge(ctx, in)
+	}
+	var resp *api.PFMergeResponse
+	err = r.forward(ctx, shard, func(ctx context.Context, c api.CommandsClient) (err error) {
+		resp, err = c.PFMerge(ctx, in)
+		return err
+	})
+	return resp, err
+}
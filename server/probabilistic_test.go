@@ -0,0 +1,106 @@
+package server
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/mateenbagheri/memorabilia/api"
+	"github.com/mateenbagheri/memorabilia/pkg/core"
+	"github.com/mateenbagheri/memorabilia/pkg/sharding"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"google.golang.org/grpc/codes"
+)
+
+func TestCommandServer_Bloom(t *testing.T) {
+	cs := NewCommandServer(core.NewInMemoryCommandRepository())
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+
+	reserved, err := cs.BFReserve(ctx, &api.BFReserveRequest{Id: "bf", ErrorRate: 0.001, Capacity: 1000})
+	require.NoError(t, err)
+	assert.True(t, reserved.GetCreated())
+	added, err := cs.BFAdd(ctx, &api.BFAddRequest{Id: "bf", Items: []string{"a", "b", "a"}})
+	require.NoError(t, err)
+	assert.Equal(t, []bool{true, true, false}, added.GetAdded())
+	exists, err := cs.BFExists(ctx, &api.BFExistsRequest{Id: "bf", Items: []string{"a", "c"}})
+	require.NoError(t, err)
+	assert.Equal(t, []bool{true, false}, exists.GetExists())
+
+	for _, bad := range []*api.BFReserveRequest{
+		{Id: "k", Capacity: 10},
+		{Id: "k", ErrorRate: 1, Capacity: 10},
+		{Id: "k", ErrorRate: 0.01},
+		{Id: "k", ErrorRate: 1e-12, Capacity: 1 << 40},
+	} {
+		_, err = cs.BFReserve(ctx, bad)
+		code, _, _, _ := errorDetails(t, err)
+		assert.Equal(t, codes.InvalidArgument, code)
+	}
+
+	_, err = cs.PFAdd(ctx, &api.PFAddRequest{Id: "hll", Items: []string{"a"}})
+	require.NoError(t, err)
+	_, err = cs.BFAdd(ctx, &api.BFAddRequest{Id: "hll", Items: []string{"a"}})
+	code, info, _, _ := errorDetails(t, err)
+	assert.Equal(t, codes.FailedPrecondition, code)
+	assert.Equal(t, ReasonWrongType, info.GetReason())
+}
+
+func TestCommandServer_HyperLogLog(t *testing.T) {
+	cs := NewCommandServer(core.NewInMemoryCommandRepository())
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+
+	added, err := cs.PFAdd(ctx, &api.PFAddRequest{Id: "h1", Items: []string{"a", "b", "c"}})
+	require.NoError(t, err)
+	assert.True(t, added.GetChanged())
+	_, err = cs.PFAdd(ctx, &api.PFAddRequest{Id: "h2", Items: []string{"c", "d"}})
+	require.NoError(t, err)
+	count, err := cs.PFCount(ctx, &api.PFCountRequest{Ids: []string{"h1", "h2"}})
+	require.NoError(t, err)
+	assert.Equal(t, int64(4), count.GetCount())
+
+	_, err = cs.PFMerge(ctx, &api.PFMergeRequest{Dest: "all", Sources: []string{"h1", "h2"}})
+	require.NoError(t, err)
+	count, err = cs.PFCount(ctx, &api.PFCountRequest{Ids: []string{"all"}})
+	require.NoError(t, err)
+	assert.Equal(t, int64(4), count.GetCount())
+
+	_, err = cs.PFCount(ctx, &api.PFCountRequest{})
+	code, _, _, _ := errorDetails(t, err)
+	assert.Equal(t, codes.InvalidArgument, code)
+}
+
+func TestShardRouter_Probabilistic(t *testing.T) {
+	host := newTestShardHost(t)
+	router := NewShardRouter(host)
+	defer router.Close()
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+	defer cancel()
+	require.NoError(t, host.AddShard(ctx, 1, []string{"n1"}, []int{
+		sharding.SlotOf("bf"), sharding.SlotOf("h1"), sharding.SlotOf("h2"), sharding.SlotOf("h3"),
+	}))
+
+	added, err := router.BFAdd(ctx, &api.BFAddRequest{Id: "bf", Items: []string{"a", "a"}})
+	require.NoError(t, err)
+	assert.Equal(t, []bool{true, false}, added.GetAdded())
+	exists, err := router.BFExists(ctx, &api.BFExistsRequest{Id: "bf", Items: []string{"a", "b"}})
+	require.NoError(t, err)
+	assert.Equal(t, []bool{true, false}, exists.GetExists())
+
+	_, err = router.PFAdd(ctx, &api.PFAddRequest{Id: "h1", Items: []string{"a", "b"}})
+	require.NoError(t, err)
+	_, err = router.PFAdd(ctx, &api.PFAddRequest{Id: "h2", Items: []string{"b", "c"}})
+	require.NoError(t, err)
+	_, err = router.PFMerge(ctx, &api.PFMergeRequest{Dest: "h3", Sources: []string{"h1", "h2"}})
+	require.NoError(t, err)
+	count, err := router.PFCount(ctx, &api.PFCountRequest{Ids: []string{"h3"}})
+	require.NoError(t, err)
+	assert.Equal(t, int64(3), count.GetCount())
+
+	_, err = router.PFCount(ctx, &api.PFCountRequest{Ids: []string{"h1", "elsewhere"}})
+	code, info, _, _ := errorDetails(t, err)
+	assert.Equal(t, codes.InvalidArgument, code)
+	assert.Equal(t, ReasonInvalidArgument, info.GetReason())
+}
@@ -0,0 +1,241 @@
+package server
+
+import (
+	"context"
+	"fmt"
+	"log/slog"
+	"sync"
+
+	"github.com/mateenbagheri/memorabilia/api"
+	"github.com/mateenbagheri/memorabilia/pkg/cluster"
+	"github.com/mateenbagheri/memorabilia/pkg/core"
+	"github.com/mateenbagheri/memorabilia/pkg/pubsub"
+	"github.com/mateenbagheri/memorabilia/pkg/replication"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/credentials/insecure"
+	"google.golang.org/grpc/metadata"
+)
+
+// RelayHeader is the gRPC metadata key set on a Publish one node relays to
+// the others. It names the relaying node, and keeps the message from being
+// relayed again.
+const RelayHeader = "x-memorabilia-relayed-by"
+
+// PubSubServer serves the PubSub service. Subscribers are served from this
+// node's Hub; a message published here is delivered to them, and in Raft mode
+// relayed to every other member of the cluster, which delivers it to its own.
+// Keyspace watchers are served from a second Hub, which the node's
+// repositories report their changes to (see Server.notifyKeyspace): the
+// channels are keys, and the messages the types of event.
+//
+// Messages don't go through the Raft log: they aren't stored, so a node that
+// is unreachable when one is published never receives it, and messages from
+// different publishers may reach different nodes in a different order.
+type PubSubServer struct {
+	api.UnimplementedPubSubServer
+
+	hub      *pubsub.Hub
+	keyspace *pubsub.Hub
+	node     *replication.Node // nil in single-node mode
+	logger   *slog.Logger
+
+	mu    sync.Mutex
+	conns map[string]*grpc.ClientConn
+}
+
+// NewPubSubServer constructs a server delivering messages through hub, and
+// relaying them to the other members of node's cluster, and keyspace events
+// through keyspace. node may be nil.
+func NewPubSubServer(hub, keyspace *pubsub.Hub, node *replication.Node, logger *slog.Logger) *PubSubServer {
+	return &PubSubServer{
+		hub:      hub,
+		keyspace: keyspace,
+		node:     node,
+		logger:   logger,
+		conns:    make(map[string]*grpc.ClientConn),
+	}
+}
+
+// Close closes the connections used to relay messages.
+func (s *PubSubServer) Close() error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	var err error
+	for addr, conn := range s.conns {
+		if cerr := conn.Close(); err == nil {
+			err = cerr
+		}
+		delete(s.conns, addr)
+	}
+	return err
+}
+
+func (s *PubSubServer) Publish(ctx context.Context, in *api.PublishRequest) (*api.PublishResponse, error) {
+	if in.GetChannel() == "" {
+		return nil, invalidArgument("publish", "channel", "must not be empty")
+	}
+
+	receivers := int64(s.hub.Publish(in.GetChannel(), in.GetMessage()))
+	if s.node != nil && !relayed(ctx) {
+		receivers += s.relay(ctx, in)
+	}
+	return &api.PublishResponse{Receivers: receivers}, nil
+}
+
+func (s *PubSubServer) Subscribe(in *api.ChannelSubscribeRequest, stream api.PubSub_SubscribeServer) error {
+	if len(in.GetChannels()) == 0 {
+		return invalidArgument("subscribe", "channels", "at least one channel is required")
+	}
+	return serve(s.hub.Subscribe(in.GetChannels(), nil), stream, pubSubMessage)
+}
+
+func (s *PubSubServer) PSubscribe(in *api.PatternSubscribeRequest, stream api.PubSub_PSubscribeServer) error {
+	if len(in.GetPatterns()) == 0 {
+		return invalidArgument("psubscribe", "patterns", "at least one pattern is required")
+	}
+	return serve(s.hub.Subscribe(nil, in.GetPatterns()), stream, pubSubMessage)
+}
+
+func (s *PubSubServer) WatchKeyspace(in *api.WatchKeyspaceRequest, stream api.PubSub_WatchKeyspaceServer) error {
+	types := make(map[api.KeyspaceEventType]bool, len(in.GetEvents()))
+	for _, t := range in.GetEvents() {
+		if _, ok := keyspaceEventTypes[t]; !ok {
+			return invalidArgument("watch keyspace", "events", fmt.Sprintf("unknown event type %s", t))
+		}
+		types[t] = true
+	}
+	pattern := in.GetPattern()
+	if pattern == "" {
+		pattern = "*"
+	}
+
+	return serve(s.keyspace.Subscribe(nil, []string{pattern}), stream, func(msg pubsub.Message) *api.KeyspaceEvent {
+		t := keyspaceEventType(core.KeyspaceEventType(msg.Payload))
+		if len(types) > 0 && !types[t] {
+			return nil
+		}
+		return &api.KeyspaceEvent{Type: t, Key: msg.Channel, Dropped: msg.Dropped}
+	})
+}
+
+// keyspaceEventTypes maps the API's keyspace event types to the repository's.
+var keyspaceEventTypes = map[api.KeyspaceEventType]core.KeyspaceEventType{
+	api.KeyspaceEventType_KEYSPACE_EVENT_SET:     core.EventSet,
+	api.KeyspaceEventType_KEYSPACE_EVENT_DEL:     core.EventDel,
+	api.KeyspaceEventType_KEYSPACE_EVENT_EXPIRED: core.EventExpired,
+	api.KeyspaceEventType_KEYSPACE_EVENT_EVICTED: core.EventEvicted,
+}
+
+func keyspaceEventType(t core.KeyspaceEventType) api.KeyspaceEventType {
+	for apiType, coreType := range keyspaceEventTypes {
+		if coreType == t {
+			return apiType
+		}
+	}
+	return api.KeyspaceEventType_KEYSPACE_EVENT_UNSPECIFIED
+}
+
+func pubSubMessage(msg pubsub.Message) *api.PubSubMessage {
+	return &api.PubSubMessage{
+		Channel: msg.Channel,
+		Pattern: msg.Pattern,
+		Message: msg.Payload,
+		Dropped: msg.Dropped,
+	}
+}
+
+// serve streams sub's messages, converted by convert, until the client goes
+// away or the subscription ends. Messages converted to nil are skipped.
+func serve[T any](sub *pubsub.Subscriber, stream grpc.ServerStreamingServer[T], convert func(pubsub.Message) *T) error {
+	defer sub.Close()
+
+	// Tell the client the subscription is in place: messages published from
+	// now on reach it.
+	if err := stream.SendHeader(nil); err != nil {
+		return err
+	}
+	for {
+		select {
+		case msg := <-sub.Messages():
+			out := convert(msg)
+			if out == nil {
+				continue
+			}
+			if err := stream.Send(out); err != nil {
+				return err
+			}
+		case <-sub.Done():
+			return subscribeError(sub.Err())
+		case <-stream.Context().Done():
+			return subscribeError(stream.Context().Err())
+		}
+	}
+}
+
+// relay publishes in on every other member of the cluster, and returns how
+// many subscribers they delivered it to. Members that can't be reached are
+// skipped.
+func (s *PubSubServer) relay(ctx context.Context, in *api.PublishRequest) int64 {
+	servers, err := cluster.NewMembership(s.node).Servers()
+	if err != nil {
+		s.logger.Warn("publish: can't list the cluster's members, not relaying", slog.String("error", err.Error()))
+		return 0
+	}
+	ctx = metadata.AppendToOutgoingContext(ctx, RelayHeader, s.node.NodeID())
+
+	var (
+		wg        sync.WaitGroup
+		mu        sync.Mutex
+		receivers int64
+	)
+	for _, srv := range servers {
+		id := string(srv.ID)
+		if id == s.node.NodeID() {
+			continue
+		}
+		meta, ok := s.node.NodeMeta(id)
+		if !ok || meta.GRPCAddr == "" {
+			s.logger.Warn("publish: member has no gRPC address, not relaying to it", slog.String("nodeID", id))
+			continue
+		}
+
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			conn, err := s.conn(meta.GRPCAddr)
+			if err == nil {
+				var resp *api.PublishResponse
+				if resp, err = api.NewPubSubClient(conn).Publish(ctx, in); err == nil {
+					mu.Lock()
+					receivers += resp.GetReceivers()
+					mu.Unlock()
+					return
+				}
+			}
+			s.logger.Warn("publish: relaying to member failed",
+				slog.String("nodeID", id), slog.String("error", err.Error()))
+		}()
+	}
+	wg.Wait()
+	return receivers
+}
+
+func (s *PubSubServer) conn(addr string) (*grpc.ClientConn, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if conn, ok := s.conns[addr]; ok {
+		return conn, nil
+	}
+	conn, err := grpc.NewClient(addr, grpc.WithTransportCredentials(insecure.NewCredentials()))
+	if err != nil {
+		return nil, err
+	}
+	s.conns[addr] = conn
+	return conn, nil
+}
+
+// relayed reports whether an incoming Publish was relayed by another node.
+func relayed(ctx context.Context) bool {
+	md, ok := metadata.FromIncomingContext(ctx)
+	return ok && len(md.Get(RelayHeader)) > 0
+}
@@ -0,0 +1,160 @@
+package server
+
+import (
+	"context"
+	"io"
+	"log/slog"
+	"net"
+	"testing"
+	"time"
+
+	"github.com/mateenbagheri/memorabilia/api"
+	"github.com/mateenbagheri/memorabilia/pkg/cluster"
+	"github.com/mateenbagheri/memorabilia/pkg/core"
+	"github.com/mateenbagheri/memorabilia/pkg/pubsub"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/credentials/insecure"
+	"google.golang.org/grpc/status"
+)
+
+// startPubSubServer serves srv on a loopback port and returns a client.
+func startPubSubServer(t *testing.T, srv *PubSubServer) (api.PubSubClient, string) {
+	t.Helper()
+	lis, err := net.Listen("tcp", "127.0.0.1:0")
+	require.NoError(t, err)
+	s := grpc.NewServer()
+	api.RegisterPubSubServer(s, srv)
+	go s.Serve(lis)
+	t.Cleanup(s.Stop)
+
+	conn, err := grpc.NewClient(lis.Addr().String(), grpc.WithTransportCredentials(insecure.NewCredentials()))
+	require.NoError(t, err)
+	t.Cleanup(func() { conn.Close() })
+	return api.NewPubSubClient(conn), lis.Addr().String()
+}
+
+func TestPubSubServer_PublishSubscribe(t *testing.T) {
+	logger := slog.New(slog.NewTextHandler(io.Discard, nil))
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+
+	// n2 is a cluster member as far as n1 knows; it serves its own hub.
+	remote, remoteAddr := startPubSubServer(t, NewPubSubServer(pubsub.NewHub(8, pubsub.PolicyDisconnect), pubsub.NewHub(8, pubsub.PolicyDisconnect), nil, logger))
+	node := newTestRaftNode(t)
+	defer node.Shutdown()
+	require.Eventually(t, func() bool { return node.Readiness() == nil }, 5*time.Second, 10*time.Millisecond)
+	require.NoError(t, node.JoinNonvoter("n2", freeAddr(t)))
+	require.NoError(t, node.RegisterMeta(cluster.NodeMeta{NodeID: "n2", GRPCAddr: remoteAddr}))
+	srv := NewPubSubServer(pubsub.NewHub(8, pubsub.PolicyDisconnect), pubsub.NewHub(8, pubsub.PolicyDisconnect), node, logger)
+	defer srv.Close()
+	local, _ := startPubSubServer(t, srv)
+
+	subscribe := func(c api.PubSubClient, patterns bool, names ...string) api.PubSub_SubscribeClient {
+		var stream api.PubSub_SubscribeClient
+		var err error
+		if patterns {
+			stream, err = c.PSubscribe(ctx, &api.PatternSubscribeRequest{Patterns: names})
+		} else {
+			stream, err = c.Subscribe(ctx, &api.ChannelSubscribeRequest{Channels: names})
+		}
+		require.NoError(t, err)
+		_, err = stream.Header()
+		require.NoError(t, err)
+		return stream
+	}
+	onLocal := subscribe(local, false, "news")
+	onRemote := subscribe(remote, true, "n*")
+
+	resp, err := local.Publish(ctx, &api.PublishRequest{Channel: "news", Message: "hello"})
+	require.NoError(t, err)
+	assert.Equal(t, int64(2), resp.GetReceivers())
+
+	msg, err := onLocal.Recv()
+	require.NoError(t, err)
+	assert.Equal(t, "news", msg.GetChannel())
+	assert.Equal(t, "hello", msg.GetMessage())
+	msg, err = onRemote.Recv()
+	require.NoError(t, err)
+	assert.Equal(t, "n*", msg.GetPattern())
+	assert.Equal(t, "hello", msg.GetMessage())
+
+	// The remote node has no cluster to relay to.
+	resp, err = remote.Publish(ctx, &api.PublishRequest{Channel: "nope", Message: "x"})
+	require.NoError(t, err)
+	assert.Equal(t, int64(1), resp.GetReceivers())
+
+	_, err = local.Publish(ctx, &api.PublishRequest{Message: "x"})
+	assert.Equal(t, codes.InvalidArgument, status.Code(err))
+}
+
+func TestPubSubServer_SlowConsumer(t *testing.T) {
+	logger := slog.New(slog.NewTextHandler(io.Discard, nil))
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+
+	hub := pubsub.NewHub(1, pubsub.PolicyDisconnect)
+	c, _ := startPubSubServer(t, NewPubSubServer(hub, pubsub.NewHub(1, pubsub.PolicyDisconnect), nil, logger))
+	stream, err := c.Subscribe(ctx, &api.ChannelSubscribeRequest{Channels: []string{"c"}})
+	require.NoError(t, err)
+	_, err = stream.Header()
+	require.NoError(t, err)
+
+	// Nothing reads the stream while publishing, but gRPC's own buffers
+	// hold some messages: publish until the hub's overflows.
+	for i := 0; i < 100000; i++ {
+		hub.Publish("c", "m")
+	}
+	for {
+		if _, err = stream.Recv(); err != nil {
+			break
+		}
+	}
+	assert.Equal(t, codes.ResourceExhausted, status.Code(err))
+	assert.Equal(t, ReasonSlowConsumer, errorReason(err))
+}
+
+func TestPubSubServer_WatchKeyspace(t *testing.T) {
+	logger := slog.New(slog.NewTextHandler(io.Discard, nil))
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+
+	keyspace := pubsub.NewHub(8, pubsub.PolicyDisconnect)
+	c, _ := startPubSubServer(t, NewPubSubServer(pubsub.NewHub(8, pubsub.PolicyDisconnect), keyspace, nil, logger))
+	repo := core.NewInMemoryCommandRepository()
+	repo.SetNotifier(func(event core.KeyspaceEvent) { keyspace.Publish(event.Key, string(event.Type)) })
+
+	stream, err := c.WatchKeyspace(ctx, &api.WatchKeyspaceRequest{
+		Pattern: "session:*",
+		Events:  []api.KeyspaceEventType{api.KeyspaceEventType_KEYSPACE_EVENT_EXPIRED, api.KeyspaceEventType_KEYSPACE_EVENT_DEL},
+	})
+	require.NoError(t, err)
+	_, err = stream.Header()
+	require.NoError(t, err)
+
+	require.NoError(t, repo.Set(ctx, "session:1", "a", time.Now().Add(-time.Second)))
+	require.NoError(t, repo.Set(ctx, "session:2", "b", time.Time{}))
+	require.NoError(t, repo.Set(ctx, "other", "c", time.Time{}))
+	repo.Delete(ctx, "other")
+	_, err = repo.Cleanup(ctx)
+	require.NoError(t, err)
+	repo.Delete(ctx, "session:2")
+
+	event, err := stream.Recv()
+	require.NoError(t, err)
+	assert.Equal(t, api.KeyspaceEventType_KEYSPACE_EVENT_EXPIRED, event.GetType())
+	assert.Equal(t, "session:1", event.GetKey())
+	event, err = stream.Recv()
+	require.NoError(t, err)
+	assert.Equal(t, api.KeyspaceEventType_KEYSPACE_EVENT_DEL, event.GetType())
+	assert.Equal(t, "session:2", event.GetKey())
+
+	stream, err = c.WatchKeyspace(ctx, &api.WatchKeyspaceRequest{
+		Events: []api.KeyspaceEventType{api.KeyspaceEventType_KEYSPACE_EVENT_UNSPECIFIED},
+	})
+	require.NoError(t, err)
+	_, err = stream.Recv()
+	assert.Equal(t, codes.InvalidArgument, status.Code(err))
+}
@@ -2,9 +2,12 @@ package server
 
 import (
 	"encoding/json"
+	"errors"
 	"fmt"
+	"io"
 	"log/slog"
 	"net/http"
+	"strconv"
 
 	"github.com/mateenbagheri/memorabilia/pkg/cluster"
 	"github.com/mateenbagheri/memorabilia/pkg/replication"
@@ -12,17 +15,28 @@ import (
 
 // RaftHTTPHandler exposes Raft cluster management operations over plain HTTP:
 //
-//	POST /raft/join    add a new voting peer (leader only)
+//	POST /raft/join    add a new voting or non-voting peer (leader only)
+//	POST /raft/remove  remove a peer by node ID (forwarded to the leader)
+//	POST /raft/leave   remove this node itself (forwarded to the leader)
+//	POST /raft/transfer-leadership  move leadership to another node
+//	                                (forwarded to the leader)
+//	POST /raft/promote make a non-voter a voter (forwarded to the leader)
+//	POST /raft/demote  make a voter a non-voter (forwarded to the leader)
 //	GET  /raft/leader  return the current leader's Raft address
-//	GET  /raft/peers   return the full cluster configuration as JSON
+//	GET  /raft/peers   return the full cluster configuration, with each
+//	                   member's role, as JSON
+//	GET  /raft/stats   return this node's Raft and FSM state as JSON
+//	POST /raft/snapshot take a snapshot of this node now
+//	GET  /raft/backup  stream a consistent backup of this node's state
+//	POST /raft/restore replace the cluster's state with a backup (leader only)
 //
 // This is the HTTP-transport equivalent of CommandServer: CommandServer
 // exposes data operations (Get/Set/Delete) over gRPC, RaftHTTPHandler
-// exposes cluster operations (join/leader/peers) over HTTP.
+// exposes cluster operations (join/remove/leader/peers) over HTTP.
 //
 // Its only dependency is *replication.Node — it knows nothing about gRPC,
 // the scheduler, or the FSM's underlying repository. That makes it possible
-// to unit test these three routes with httptest against a node fixture,
+// to unit test these routes with httptest against a node fixture,
 // without booting the rest of the server.
 type RaftHTTPHandler struct {
 	node   *replication.Node
@@ -38,16 +52,31 @@ func NewRaftHTTPHandler(node *replication.Node, logger *slog.Logger) *RaftHTTPHa
 // Call this once during server startup before starting the HTTP server.
 func (h *RaftHTTPHandler) RegisterRoutes(mux *http.ServeMux) {
 	mux.HandleFunc("/raft/join", h.handleJoin)
+	mux.HandleFunc("/raft/remove", h.handleRemove)
+	mux.HandleFunc("/raft/leave", h.handleLeave)
+	mux.HandleFunc("/raft/transfer-leadership", h.handleTransferLeadership)
+	mux.HandleFunc("/raft/promote", h.handlePromote)
+	mux.HandleFunc("/raft/demote", h.handleDemote)
 	mux.HandleFunc("/raft/leader", h.handleLeader)
 	mux.HandleFunc("/raft/peers", h.handlePeers)
+	mux.HandleFunc("/raft/stats", h.handleStats)
+	mux.HandleFunc("/raft/snapshot", h.handleSnapshot)
+	mux.HandleFunc("/raft/backup", h.handleBackup)
+	mux.HandleFunc("/raft/restore", h.handleRestore)
 }
 
 // handleJoin accepts a JSON-encoded replication.JoinRequest body and adds the
-// caller as a voting peer via raft.AddVoter.
+// caller as a voting peer via raft.AddVoter, or as a read replica via
+// raft.AddNonvoter when the request sets non_voter.
 //
 // Only the leader can add voters. If this node is not the leader, it responds
-// 421 Misdirected Request with the current leader's Raft address in the body,
-// so the caller can retry against the correct node.
+// 421 Misdirected Request with the current leader's Raft address in the body
+// and, when known, its management address in the X-Memorabilia-Leader-HTTP
+// header, so the caller can retry against the correct node.
+//
+// Joining is idempotent: a node that is already a member under the same
+// address gets 200, and one that comes back under a new address replaces its
+// stale entry. Nodes can therefore (re-)join unconditionally on every start.
 func (h *RaftHTTPHandler) handleJoin(w http.ResponseWriter, r *http.Request) {
 	if r.Method != http.MethodPost {
 		http.Error(w, "method not allowed", http.StatusMethodNotAllowed)
@@ -66,26 +95,218 @@ func (h *RaftHTTPHandler) handleJoin(w http.ResponseWriter, r *http.Request) {
 			http.Error(w, "no leader elected yet", http.StatusServiceUnavailable)
 			return
 		}
-		http.Error(w,
-			fmt.Sprintf("not the leader; forward to leader raft addr %q", leader),
-			http.StatusMisdirectedRequest,
-		)
+		h.misdirected(w, leader)
 		return
 	}
 
-	if err := h.node.Join(req.NodeID, req.RaftAddr); err != nil {
+	join := h.node.Join
+	if req.NonVoter {
+		join = h.node.JoinNonvoter
+	}
+	if err := join(req.NodeID, req.RaftAddr); err != nil {
 		h.logger.Error("join failed", slog.String("error", err.Error()))
 		http.Error(w, err.Error(), http.StatusInternalServerError)
 		return
 	}
 
+	if req.HTTPAddr != "" || req.GRPCAddr != "" {
+		meta := cluster.NodeMeta{
+			NodeID:   req.NodeID,
+			RaftAddr: req.RaftAddr,
+			HTTPAddr: req.HTTPAddr,
+			GRPCAddr: req.GRPCAddr,
+		}
+		if err := h.node.RegisterMeta(meta); err != nil {
+			// The node is a member already; it just can't be forwarded to
+			// until it re-registers on becoming leader.
+			h.logger.Warn("join: failed to register node metadata", slog.String("error", err.Error()))
+		}
+	}
+
 	h.logger.Info("node joined cluster",
 		slog.String("nodeID", req.NodeID),
 		slog.String("raftAddr", req.RaftAddr),
+		slog.Bool("nonVoter", req.NonVoter),
 	)
 	w.WriteHeader(http.StatusOK)
 }
 
+// handleRemove accepts a JSON-encoded replication.RemoveRequest body and
+// removes that node from the Raft configuration.
+//
+// Unlike join, a follower does not bounce the caller: it forwards the request
+// to the leader itself, so scripts can target any node when scaling down.
+func (h *RaftHTTPHandler) handleRemove(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodPost {
+		http.Error(w, "method not allowed", http.StatusMethodNotAllowed)
+		return
+	}
+
+	var req replication.RemoveRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		http.Error(w, "bad request: "+err.Error(), http.StatusBadRequest)
+		return
+	}
+	if req.NodeID == "" {
+		http.Error(w, "bad request: node_id is required", http.StatusBadRequest)
+		return
+	}
+
+	h.removeOrForward(w, r, req)
+}
+
+// handleLeave removes the node serving the request from the cluster. It is
+// the HTTP equivalent of the leave-on-shutdown mode, for operators who want
+// to drain a node before stopping it.
+func (h *RaftHTTPHandler) handleLeave(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodPost {
+		http.Error(w, "method not allowed", http.StatusMethodNotAllowed)
+		return
+	}
+
+	h.removeOrForward(w, r, replication.RemoveRequest{NodeID: h.node.NodeID()})
+}
+
+// handleTransferLeadership moves leadership to the node named in the
+// optional JSON-encoded replication.TransferLeadershipRequest body, or to
+// whichever follower Raft considers most up to date when none is named.
+// Use it before taking the leader down for maintenance.
+func (h *RaftHTTPHandler) handleTransferLeadership(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodPost {
+		http.Error(w, "method not allowed", http.StatusMethodNotAllowed)
+		return
+	}
+
+	var req replication.TransferLeadershipRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil && !errors.Is(err, io.EOF) {
+		http.Error(w, "bad request: "+err.Error(), http.StatusBadRequest)
+		return
+	}
+
+	if !h.node.IsLeader() {
+		h.forwardToLeader(w, r, "/raft/transfer-leadership", req)
+		return
+	}
+
+	if err := h.node.TransferLeadership(req.NodeID); err != nil {
+		h.logger.Error("leadership transfer failed", slog.String("error", err.Error()))
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+		return
+	}
+
+	h.logger.Info("leadership transferred", slog.String("target", req.NodeID))
+	w.WriteHeader(http.StatusOK)
+}
+
+// handlePromote turns the non-voter named in the JSON-encoded
+// replication.MemberRequest body into a voter.
+func (h *RaftHTTPHandler) handlePromote(w http.ResponseWriter, r *http.Request) {
+	h.handleMembershipChange(w, r, "/raft/promote", func(m *cluster.Membership, nodeID string) error {
+		return m.Promote(nodeID)
+	})
+}
+
+// handleDemote turns the voter named in the JSON-encoded
+// replication.MemberRequest body into a non-voter.
+func (h *RaftHTTPHandler) handleDemote(w http.ResponseWriter, r *http.Request) {
+	h.handleMembershipChange(w, r, "/raft/demote", func(m *cluster.Membership, nodeID string) error {
+		return m.Demote(nodeID)
+	})
+}
+
+// handleMembershipChange is the shared body of promote and demote: decode the
+// target, forward to the leader when needed, then apply change there.
+func (h *RaftHTTPHandler) handleMembershipChange(
+	w http.ResponseWriter,
+	r *http.Request,
+	path string,
+	change func(m *cluster.Membership, nodeID string) error,
+) {
+	if r.Method != http.MethodPost {
+		http.Error(w, "method not allowed", http.StatusMethodNotAllowed)
+		return
+	}
+
+	var req replication.MemberRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		http.Error(w, "bad request: "+err.Error(), http.StatusBadRequest)
+		return
+	}
+	if req.NodeID == "" {
+		http.Error(w, "bad request: node_id is required", http.StatusBadRequest)
+		return
+	}
+
+	if !h.node.IsLeader() {
+		h.forwardToLeader(w, r, path, req)
+		return
+	}
+
+	if err := change(cluster.NewMembership(h.node), req.NodeID); err != nil {
+		h.logger.Error("membership change failed", slog.String("path", path), slog.String("error", err.Error()))
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+		return
+	}
+
+	h.logger.Info("membership changed", slog.String("path", path), slog.String("nodeID", req.NodeID))
+	w.WriteHeader(http.StatusOK)
+}
+
+func (h *RaftHTTPHandler) removeOrForward(w http.ResponseWriter, r *http.Request, req replication.RemoveRequest) {
+	if !h.node.IsLeader() {
+		h.forwardToLeader(w, r, "/raft/remove", req)
+		return
+	}
+
+	if err := h.node.Remove(req.NodeID); err != nil {
+		h.logger.Error("remove failed", slog.String("nodeID", req.NodeID), slog.String("error", err.Error()))
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+		return
+	}
+
+	h.logger.Info("node removed from cluster", slog.String("nodeID", req.NodeID))
+	w.WriteHeader(http.StatusOK)
+}
+
+// forwardToLeader relays body to path on the leader and mirrors the outcome.
+// A request that was already forwarded once is not forwarded again; the
+// caller gets 421 with the leader's Raft address instead, as for join.
+func (h *RaftHTTPHandler) forwardToLeader(w http.ResponseWriter, r *http.Request, path string, body any) {
+	leader := h.node.LeaderRaftAddr()
+	if leader == "" {
+		http.Error(w, "no leader elected yet", http.StatusServiceUnavailable)
+		return
+	}
+	if r.Header.Get(replication.ForwardedHeader) != "" {
+		h.misdirected(w, leader)
+		return
+	}
+
+	if err := h.node.ForwardToLeader(r.Context(), path, body); err != nil {
+		if errors.Is(err, replication.ErrLeaderUnknown) {
+			h.misdirected(w, leader)
+			return
+		}
+		h.logger.Error("forward to leader failed", slog.String("path", path), slog.String("error", err.Error()))
+		http.Error(w, err.Error(), http.StatusBadGateway)
+		return
+	}
+	w.WriteHeader(http.StatusOK)
+}
+
+// misdirected answers 421 Misdirected Request, pointing the caller at the
+// leader: its Raft address in the body, and its management address in
+// X-Memorabilia-Leader-HTTP when it has been replicated.
+func (h *RaftHTTPHandler) misdirected(w http.ResponseWriter, leaderRaftAddr string) {
+	if leaderHTTP := h.node.LeaderHTTPAddr(); leaderHTTP != "" {
+		w.Header().Set(replication.LeaderHTTPHeader, leaderHTTP)
+	}
+	http.Error(w,
+		fmt.Sprintf("not the leader; forward to leader raft addr %q", leaderRaftAddr),
+		http.StatusMisdirectedRequest,
+	)
+}
+
 // handleLeader returns the current leader's Raft transport address as plain text.
 // Responds 503 if no leader has been elected yet.
 func (h *RaftHTTPHandler) handleLeader(w http.ResponseWriter, r *http.Request) {
@@ -97,15 +318,120 @@ func (h *RaftHTTPHandler) handleLeader(w http.ResponseWriter, r *http.Request) {
 	fmt.Fprintln(w, leader)
 }
 
-// handlePeers returns the full cluster configuration (every known server and
-// its Raft address) as JSON.
+// handlePeers returns the full cluster configuration (every known server, its
+// Raft address, role and management address) as JSON.
 func (h *RaftHTTPHandler) handlePeers(w http.ResponseWriter, r *http.Request) {
 	m := cluster.NewMembership(h.node)
-	servers, err := m.Servers()
+	peers, err := m.Peers()
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+		return
+	}
+	for i := range peers {
+		if meta, ok := h.node.NodeMeta(string(peers[i].ID)); ok {
+			peers[i].HTTPAddr = meta.HTTPAddr
+			peers[i].GRPCAddr = meta.GRPCAddr
+		}
+	}
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(peers)
+}
+
+// handleStats returns replication.Stats for this node as JSON: term, log,
+// commit and applied indexes, FSM backlog, snapshot and leader contact info.
+// Unlike the other routes it describes the node serving the request, so
+// it is never forwarded.
+func (h *RaftHTTPHandler) handleStats(w http.ResponseWriter, r *http.Request) {
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(h.node.Stats())
+}
+
+// handleSnapshot takes a snapshot of this node's state right away and returns
+// a replication.SnapshotInfo describing it. Each node snapshots on its own,
+// so it is never forwarded.
+func (h *RaftHTTPHandler) handleSnapshot(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodPost {
+		http.Error(w, "method not allowed", http.StatusMethodNotAllowed)
+		return
+	}
+
+	info, err := h.node.Snapshot()
 	if err != nil {
+		h.logger.Error("snapshot failed", slog.String("error", err.Error()))
 		http.Error(w, err.Error(), http.StatusInternalServerError)
 		return
 	}
+
+	h.logger.Info("snapshot taken", slog.String("id", info.ID), slog.Uint64("index", info.Index))
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(info)
+}
+
+// handleBackup snapshots this node and streams the snapshot back as the
+// response body; its index and term are in the X-Memorabilia-Snapshot-Index
+// and -Term headers. The result is consistent as of that index. On a
+// follower it may trail the leader slightly, so back up the leader for the
+// latest state.
+func (h *RaftHTTPHandler) handleBackup(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodGet {
+		http.Error(w, "method not allowed", http.StatusMethodNotAllowed)
+		return
+	}
+
+	info, rc, err := h.node.OpenSnapshot()
+	if err != nil {
+		h.logger.Error("backup failed", slog.String("error", err.Error()))
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+		return
+	}
+	defer rc.Close()
+
 	w.Header().Set("Content-Type", "application/json")
-	json.NewEncoder(w).Encode(servers)
+	w.Header().Set("Content-Disposition", fmt.Sprintf("attachment; filename=%q", backupFileName(info)))
+	w.Header().Set(replication.SnapshotIndexHeader, strconv.FormatUint(info.Index, 10))
+	w.Header().Set(replication.SnapshotTermHeader, strconv.FormatUint(info.Term, 10))
+	if _, err := io.Copy(w, rc); err != nil {
+		// Headers are out already; all we can do is cut the body short.
+		h.logger.Error("backup stream failed", slog.String("error", err.Error()))
+		return
+	}
+	h.logger.Info("backup streamed", slog.String("id", info.ID), slog.Uint64("index", info.Index))
+}
+
+func backupFileName(info replication.SnapshotInfo) string {
+	return fmt.Sprintf("memorabilia-%d-%d.backup.json", info.Term, info.Index)
+}
+
+// handleRestore replaces the state of the entire cluster with the backup in
+// the request body (as produced by /raft/backup). Current member metadata is
+// kept; see replication.Node.Restore.
+//
+// Like join it must reach the leader, and a follower answers 421 rather than
+// relaying a possibly large body.
+func (h *RaftHTTPHandler) handleRestore(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodPost {
+		http.Error(w, "method not allowed", http.StatusMethodNotAllowed)
+		return
+	}
+
+	if !h.node.IsLeader() {
+		leader := h.node.LeaderRaftAddr()
+		if leader == "" {
+			http.Error(w, "no leader elected yet", http.StatusServiceUnavailable)
+			return
+		}
+		h.misdirected(w, leader)
+		return
+	}
+
+	if err := h.node.Restore(r.Body); err != nil {
+		if errors.Is(err, replication.ErrInvalidBackup) {
+			http.Error(w, "bad request: "+err.Error(), http.StatusBadRequest)
+			return
+		}
+		h.logger.Error("restore failed", slog.String("error", err.Error()))
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+		return
+	}
+	w.WriteHeader(http.StatusOK)
 }
@@ -0,0 +1,55 @@
+package server
+
+import (
+	"bytes"
+	"encoding/json"
+	"io"
+	"log/slog"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/mateenbagheri/memorabilia/pkg/replication"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestRaftHTTP_SnapshotBackupRestore(t *testing.T) {
+	node := newTestRaftNode(t)
+	defer node.Shutdown()
+	require.Eventually(t, func() bool { return node.Readiness() == nil }, 5*time.Second, 10*time.Millisecond)
+
+	mux := http.NewServeMux()
+	NewRaftHTTPHandler(node, slog.New(slog.NewTextHandler(io.Discard, nil))).RegisterRoutes(mux)
+	serve := func(method, path string, body io.Reader) *httptest.ResponseRecorder {
+		rec := httptest.NewRecorder()
+		mux.ServeHTTP(rec, httptest.NewRequest(method, path, body))
+		return rec
+	}
+
+	_, err := node.Apply(&replication.RaftCommand{Op: replication.OpSet, Key: "k", Value: "v"})
+	require.NoError(t, err)
+
+	rec := serve(http.MethodPost, "/raft/snapshot", nil)
+	require.Equal(t, http.StatusOK, rec.Code, rec.Body.String())
+	var info replication.SnapshotInfo
+	require.NoError(t, json.NewDecoder(rec.Body).Decode(&info))
+	assert.NotZero(t, info.Index)
+
+	rec = serve(http.MethodGet, "/raft/backup", nil)
+	require.Equal(t, http.StatusOK, rec.Code, rec.Body.String())
+	assert.NotEmpty(t, rec.Header().Get(replication.SnapshotIndexHeader))
+	assert.Contains(t, rec.Header().Get("Content-Disposition"), "attachment")
+	backup := rec.Body.Bytes()
+
+	rec = serve(http.MethodPost, "/raft/restore", bytes.NewReader(backup))
+	assert.Equal(t, http.StatusOK, rec.Code, rec.Body.String())
+
+	rec = serve(http.MethodPost, "/raft/restore", strings.NewReader("garbage"))
+	assert.Equal(t, http.StatusBadRequest, rec.Code)
+
+	rec = serve(http.MethodGet, "/raft/snapshot", nil)
+	assert.Equal(t, http.StatusMethodNotAllowed, rec.Code)
+}
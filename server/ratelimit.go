@@ -0,0 +1,88 @@
+package server
+
+import (
+	"context"
+	"time"
+
+	"github.com/mateenbagheri/memorabilia/api"
+	"github.com/mateenbagheri/memorabilia/pkg/core"
+	"github.com/mateenbagheri/memorabilia/pkg/replication"
+)
+
+// RateLimit consumes from a rate limiter. Even a request that isn't allowed
+// is a write: the FSM evaluates it on the cluster clock, so the limiter
+// behaves the same whichever node serves it and after a leader failover.
+func (cs *CommandServer) RateLimit(ctx context.Context, in *api.RateLimitRequest) (*api.RateLimitResponse, error) {
+	cmd, err := rateLimitCommand(in)
+	if err != nil {
+		return nil, err
+	}
+	resp, err := cs.applyCommand(ctx, "rate limit", in.GetId(), cmd)
+	if err != nil {
+		return nil, err
+	}
+	return &api.RateLimitResponse{
+		Allowed:      resp.Allowed,
+		Remaining:    resp.Count,
+		RetryAfterMs: (resp.RetryAfter + time.Millisecond - 1).Milliseconds(),
+	}, nil
+}
+
+// rateLimitCommand validates in and builds its OpRateLimit.
+func rateLimitCommand(in *api.RateLimitRequest) (*replication.RaftCommand, error) {
+	if in.GetId() == "" {
+		return nil, invalidArgument("rate limit", "id", "must not be empty")
+	}
+	if in.GetCapacity() <= 0 {
+		return nil, invalidArgument("rate limit", "capacity", "must be positive")
+	}
+	cost := in.GetCost()
+	if cost == 0 {
+		cost = 1
+	}
+	if cost < 0 || cost > in.GetCapacity() {
+		return nil, invalidArgument("rate limit", "cost", "must be between 1 and capacity")
+	}
+	cmd := &replication.RaftCommand{
+		Op:       replication.OpRateLimit,
+		Key:      in.GetId(),
+		Capacity: in.GetCapacity(),
+		Cost:     cost,
+	}
+	switch in.GetAlgorithm() {
+	case api.RateLimitAlgorithm_TOKEN_BUCKET:
+		if in.GetRefillPerSecond() <= 0 {
+			return nil, invalidArgument("rate limit", "refill_per_second", "must be positive")
+		}
+		cmd.Value = core.TokenBucket
+		cmd.Rate = in.GetRefillPerSecond()
+	case api.RateLimitAlgorithm_SLIDING_WINDOW:
+		if in.GetWindowMs() <= 0 {
+			return nil, invalidArgument("rate limit", "window_ms", "must be positive")
+		}
+		cmd.Value = core.SlidingWindow
+		cmd.TTL = time.Duration(in.GetWindowMs()) * time.Millisecond
+	default:
+		return nil, invalidArgument("rate limit", "algorithm", "unknown algorithm")
+	}
+	return cmd, nil
+}
+
+func (r *ShardRouter) RateLimit(ctx context.Context, in *api.RateLimitRequest) (*api.RateLimitResponse, error) {
+	if in.GetId() == "" {
+		return nil, invalidArgument("rate limit", "id", "must not be empty")
+	}
+	cs, shard, err := r.route(ctx, in.GetId(), true)
+	if err != nil {
+		return nil, err
+	}
+	if cs != nil {
+		return cs.RateLimit(ctx, in)
+	}
+	var resp *api.RateLimitResponse
+	err = r.forward(ctx, shard, func(ctx context.Context, c api.CommandsClient) (err error) {
+		resp, err = c.RateLimit(ctx, in)
+		return err
+	})
+	return resp, err
+}
@@ -0,0 +1,77 @@
+package server
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/mateenbagheri/memorabilia/api"
+	"github.com/mateenbagheri/memorabilia/pkg/core"
+	"github.com/mateenbagheri/memorabilia/pkg/sharding"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"google.golang.org/grpc/codes"
+)
+
+func TestCommandServer_RateLimit(t *testing.T) {
+	cs := NewCommandServer(core.NewInMemoryCommandRepository())
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+
+	req := &api.RateLimitRequest{Id: "api:alice", Capacity: 3, RefillPerSecond: 1}
+	for _, want := range []int64{2, 1, 0} {
+		resp, err := cs.RateLimit(ctx, req)
+		require.NoError(t, err)
+		assert.True(t, resp.GetAllowed())
+		assert.Equal(t, want, resp.GetRemaining())
+	}
+	resp, err := cs.RateLimit(ctx, req)
+	require.NoError(t, err)
+	assert.False(t, resp.GetAllowed())
+	assert.InDelta(t, 1000, resp.GetRetryAfterMs(), 100)
+	ttl, err := cs.TTL(ctx, &api.TTLRequest{Id: "api:alice"})
+	require.NoError(t, err)
+	assert.InDelta(t, 3000, ttl.GetTtl(), 100)
+
+	resp, err = cs.RateLimit(ctx, &api.RateLimitRequest{
+		Id: "api:bob", Algorithm: api.RateLimitAlgorithm_SLIDING_WINDOW, Capacity: 5, WindowMs: 60_000, Cost: 5,
+	})
+	require.NoError(t, err)
+	assert.True(t, resp.GetAllowed())
+	assert.Zero(t, resp.GetRemaining())
+
+	for _, bad := range []*api.RateLimitRequest{
+		{Id: "k", Capacity: 1},
+		{Id: "k", Capacity: 1, RefillPerSecond: 1, Cost: 2},
+		{Id: "k", Capacity: 1, Algorithm: api.RateLimitAlgorithm_SLIDING_WINDOW},
+	} {
+		_, err = cs.RateLimit(ctx, bad)
+		code, _, _, _ := errorDetails(t, err)
+		assert.Equal(t, codes.InvalidArgument, code)
+	}
+}
+
+func TestShardRouter_RateLimit(t *testing.T) {
+	host := newTestShardHost(t)
+	router := NewShardRouter(host)
+	defer router.Close()
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+	defer cancel()
+	require.NoError(t, host.AddShard(ctx, 1, []string{"n1"}, []int{sharding.SlotOf("k")}))
+
+	req := &api.RateLimitRequest{Id: "k", Capacity: 1, RefillPerSecond: 0.001}
+	resp, err := router.RateLimit(ctx, req)
+	require.NoError(t, err)
+	assert.True(t, resp.GetAllowed())
+	resp, err = router.RateLimit(ctx, req)
+	require.NoError(t, err)
+	assert.False(t, resp.GetAllowed())
+	assert.Positive(t, resp.GetRetryAfterMs())
+
+	_, err = router.Set(ctx, &api.SetRequest{Id: "k", Value: "v"})
+	require.NoError(t, err)
+	_, err = router.RateLimit(ctx, req)
+	code, info, _, _ := errorDetails(t, err)
+	assert.Equal(t, codes.FailedPrecondition, code)
+	assert.Equal(t, ReasonWrongType, info.GetReason())
+}
@@ -7,27 +7,41 @@ import (
 	"net/http"
 	"os"
 	"os/signal"
+	"sync/atomic"
 	"syscall"
 	"time"
 
 	"github.com/mateenbagheri/memorabilia/api"
 	"github.com/mateenbagheri/memorabilia/pkg/cluster"
 	"github.com/mateenbagheri/memorabilia/pkg/core"
+	"github.com/mateenbagheri/memorabilia/pkg/pubsub"
 	"github.com/mateenbagheri/memorabilia/pkg/replication"
+	"github.com/mateenbagheri/memorabilia/pkg/standby"
 	"github.com/mateenbagheri/memorabilia/pkg/utils/schedule"
 	"google.golang.org/grpc"
 )
 
+// leaveTimeout bounds how long a leave-on-shutdown may delay process exit.
+const leaveTimeout = 10 * time.Second
+
 // Server is the top-level process container. It owns the gRPC server, the
-// optional HTTP management server (Raft cluster operations), the TTL cleanup
+// HTTP management server (health probes, plus Raft cluster operations when
+// replicating), the TTL cleanup
 // scheduler, and — when replication is enabled — the Raft node.
 //
 // Server itself contains no business logic. It is purely a lifecycle:
 // construct the dependent servers, start them, wait for a signal, shut
 // everything down in order. The actual logic lives in:
 //   - CommandServer       (commands_server.go) — gRPC data operations
+//   - PubSubServer        (pubsub_server.go)   — gRPC publish/subscribe
 //   - RaftHTTPHandler     (raft_http.go)        — HTTP cluster management
+//   - HealthHTTPHandler   (health_http.go)      — liveness/readiness probes
+//   - ShardRouter         (shard_router.go)     — routes data operations to
+//     the shard owning each key
+//   - ShardHTTPHandler    (shard_http.go)       — HTTP shard management
+//   - StandbyHTTPHandler  (standby_http.go)     — standby status and metrics
 //   - ScheduleCleanup     (cleanup.go)          — TTL expiry cleanup job
+//   - ScheduleSessionExpiry (cleanup.go)        — closes ended client sessions
 type Server struct {
 	ttlCleanupTime     int64 // milliseconds
 	logger             *slog.Logger
@@ -38,10 +52,36 @@ type Server struct {
 	scheduler          schedule.CronjobRepository
 	commandsRepository core.CommandsRepository
 
+	// serving is true between the gRPC server starting and shutdown
+	// beginning; /readyz reports not ready outside of that window.
+	serving atomic.Bool
+
 	// Raft fields — nil when running in single-node mode without replication.
 	raftNode   *replication.Node
 	raftFSM    *replication.FSM
 	clusterCfg *cluster.Config
+
+	// shardHost runs this node's shard groups; nil without Raft. router
+	// is set when the gRPC server is built.
+	shardHost *replication.ShardHost
+	router    *ShardRouter
+
+	// direct is the CommandServer in single-node mode, set when the gRPC
+	// server is built.
+	direct *CommandServer
+
+	// hub fans published messages out to this node's subscribers, and
+	// keyspace the changes to keys to its keyspace watchers; pubsub serves
+	// both, and is set when the gRPC server is built.
+	hub      *pubsub.Hub
+	keyspace *pubsub.Hub
+	pubsub   *PubSubServer
+
+	// standby replicates a primary cluster into this one; nil unless this
+	// cluster is a standby. stopStandby stops it.
+	standby     *standby.Agent
+	stopStandby context.CancelFunc
+	standbyDone chan struct{}
 }
 
 // Option configures a Server using the functional-options pattern.
@@ -75,6 +115,27 @@ func WithRaft(node *replication.Node, fsm *replication.FSM, cfg *cluster.Config)
 	}
 }
 
+// WithShards routes data operations across the shard groups run by host,
+// next to the Raft node set with WithRaft.
+func WithShards(host *replication.ShardHost) Option {
+	return func(s *Server) { s.shardHost = host }
+}
+
+// WithPubSub gives each Pub/Sub subscriber and keyspace watcher a buffer of
+// bufferSize messages, and applies policy to those falling further behind.
+func WithPubSub(bufferSize int, policy pubsub.Policy) Option {
+	return func(s *Server) {
+		s.hub = pubsub.NewHub(bufferSize, policy)
+		s.keyspace = pubsub.NewHub(bufferSize, policy)
+	}
+}
+
+// WithStandby makes this node run agent, following a primary cluster, next to
+// the Raft node set with WithRaft.
+func WithStandby(agent *standby.Agent) Option {
+	return func(s *Server) { s.standby = agent }
+}
+
 func WithHTTPMgmtAddr(addr string) Option {
 	return func(s *Server) { s.httpMgmtAddr = addr }
 }
@@ -89,6 +150,8 @@ func New(options ...Option) *Server {
 		scheduler:          schedule.GetRobfigSchedulerInstance(),
 		ttlCleanupTime:     60000,
 		commandsRepository: core.NewInMemoryCommandRepository(),
+		hub:                pubsub.NewHub(pubsub.DefaultBufferSize, pubsub.PolicyDisconnect),
+		keyspace:           pubsub.NewHub(pubsub.DefaultBufferSize, pubsub.PolicyDisconnect),
 	}
 
 	for _, opt := range options {
@@ -106,31 +169,62 @@ func (s *Server) Start() {
 	}
 
 	api.RegisterCommandsServer(s.grpcServer, s.buildCommandServer())
+	s.pubsub = NewPubSubServer(s.hub, s.keyspace, s.raftNode, s.logger)
+	api.RegisterPubSubServer(s.grpcServer, s.pubsub)
+	s.notifyKeyspace()
 
 	stop := make(chan os.Signal, 1)
 	signal.Notify(stop, os.Interrupt, syscall.SIGTERM)
 
 	s.startGRPCServer(lis)
 	s.startHTTPManagementServer()
+	s.startStandby()
 
 	s.ScheduleCleanup()
+	s.ScheduleSessionExpiry()
 	s.scheduler.Start()
 
 	<-stop
 	s.shutdown()
 }
 
-// buildCommandServer returns the gRPC CommandServer in the correct mode:
-// Raft-replicated if a node is configured, direct-to-repo otherwise.
-func (s *Server) buildCommandServer() *CommandServer {
+// buildCommandServer returns the gRPC Commands service in the correct mode:
+// routed across shards if a shard host is configured, Raft-replicated if a
+// node is, direct-to-repo otherwise.
+func (s *Server) buildCommandServer() api.CommandsServer {
+	if s.shardHost != nil {
+		s.router = NewShardRouter(s.shardHost)
+		return s.router
+	}
 	if s.raftNode != nil {
 		return NewCommandServerWithRaft(s.raftFSM, s.raftNode)
 	}
-	return NewCommandServer(s.commandsRepository)
+	s.direct = NewCommandServer(s.commandsRepository)
+	return s.direct
+}
+
+// notifyKeyspace makes the repositories of this node report the changes to
+// their keys to the keyspace watchers. In Raft mode they are reported as the
+// FSMs apply them.
+func (s *Server) notifyKeyspace() {
+	notify := func(event core.KeyspaceEvent) {
+		s.keyspace.Publish(event.Key, string(event.Type))
+	}
+	switch {
+	case s.shardHost != nil:
+		s.shardHost.SetKeyspaceNotifier(notify)
+	case s.raftFSM != nil:
+		s.raftFSM.SetKeyspaceNotifier(notify)
+	default:
+		s.commandsRepository.SetNotifier(notify)
+	}
 }
 
 // startGRPCServer launches the gRPC server on a background goroutine.
 func (s *Server) startGRPCServer(lis net.Listener) {
+	// lis is already bound, so connections made from here on are queued
+	// until Serve picks them up.
+	s.serving.Store(true)
 	go func() {
 		s.logger.Info("starting gRPC server", slog.String("address", lis.Addr().String()))
 		if err := s.grpcServer.Serve(lis); err != nil {
@@ -139,17 +233,22 @@ func (s *Server) startGRPCServer(lis net.Listener) {
 	}()
 }
 
-// startHTTPManagementServer launches the Raft cluster management HTTP server
-// (/raft/join, /raft/leader, /raft/peers) on a background goroutine.
-// It is a no-op when Raft is not enabled — single-node mode has nothing to
-// manage and binds no extra port.
+// startHTTPManagementServer launches the management HTTP server on a
+// background goroutine. It always serves the health probes (/healthz,
+// /readyz); the Raft cluster routes (/raft/join, /raft/peers, ...) are only
+// registered when Raft is enabled.
 func (s *Server) startHTTPManagementServer() {
-	if s.raftNode == nil {
-		return
-	}
-
 	mux := http.NewServeMux()
-	NewRaftHTTPHandler(s.raftNode, s.logger).RegisterRoutes(mux)
+	NewHealthHTTPHandler(s.raftNode, s.serving.Load).RegisterRoutes(mux)
+	if s.raftNode != nil {
+		NewRaftHTTPHandler(s.raftNode, s.logger).RegisterRoutes(mux)
+	}
+	if s.shardHost != nil {
+		NewShardHTTPHandler(s.shardHost, s.logger).RegisterRoutes(mux)
+	}
+	if s.standby != nil {
+		NewStandbyHTTPHandler(s.standby).RegisterRoutes(mux)
+	}
 
 	s.httpServer = &http.Server{
 		Addr:    s.httpMgmtAddr,
@@ -164,19 +263,74 @@ func (s *Server) startHTTPManagementServer() {
 	}()
 }
 
-// shutdown stops all subsystems in dependency order: refuse new gRPC work
-// first, then close the HTTP management server, then stop Raft itself last
+// startStandby launches the standby agent, if any, on a background goroutine.
+func (s *Server) startStandby() {
+	if s.standby == nil {
+		return
+	}
+	ctx, cancel := context.WithCancel(context.Background())
+	s.stopStandby = cancel
+	s.standbyDone = make(chan struct{})
+	go func() {
+		defer close(s.standbyDone)
+		s.standby.Run(ctx)
+	}()
+}
+
+// shutdown stops all subsystems in dependency order: hand leadership over if
+// this node leads, refuse new gRPC work, leave the cluster if configured to,
+// then close the HTTP management server, then stop Raft itself last
 func (s *Server) shutdown() {
 	s.logger.Info("shutting down...")
 
+	// Fail readiness first, so load balancers stop routing here while
+	// in-flight requests drain.
+	s.serving.Store(false)
+
+	// Stop applying the primary's writes before leadership moves; the next
+	// leader resumes from the position in the log.
+	if s.standby != nil {
+		s.stopStandby()
+		<-s.standbyDone
+		s.standby.Close()
+	}
+
+	// Transfer while gRPC is still up: writes arriving in the meantime get a
+	// not-the-leader error pointing at the new leader instead of a refused
+	// connection followed by a full election timeout.
+	if s.raftNode != nil && s.raftNode.IsLeader() {
+		s.handOverLeadership()
+	}
+
+	// Subscribe streams only end when their clients go away; end them, or
+	// GracefulStop would wait for them.
+	s.closeChangeLogs()
+	s.hub.Close()
+	s.keyspace.Close()
 	s.grpcServer.GracefulStop()
 
+	if s.raftNode != nil && s.clusterCfg != nil && s.clusterCfg.LeaveOnShutdown {
+		s.leaveCluster()
+	}
+
 	if s.httpServer != nil {
 		ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
 		defer cancel()
 		s.httpServer.Shutdown(ctx)
 	}
 
+	if s.router != nil {
+		s.router.Close()
+	}
+	if s.pubsub != nil {
+		s.pubsub.Close()
+	}
+	if s.shardHost != nil {
+		if err := s.shardHost.Shutdown(); err != nil {
+			s.logger.Error("shard shutdown error", slog.String("error", err.Error()))
+		}
+	}
+
 	if s.raftNode != nil {
 		if err := s.raftNode.Shutdown(); err != nil {
 			s.logger.Error("raft shutdown error", slog.String("error", err.Error()))
@@ -186,3 +340,55 @@ func (s *Server) shutdown() {
 	time.Sleep(2 * time.Second) // TODO: replace this with channel or waitgroup?
 	s.logger.Info("application stopped")
 }
+
+// closeChangeLogs ends the Subscribe streams served by this node.
+func (s *Server) closeChangeLogs() {
+	if s.direct != nil {
+		s.direct.changes.Close()
+	}
+	if s.raftFSM != nil {
+		s.raftFSM.Changes().Close()
+	}
+	if s.shardHost != nil {
+		for _, shard := range s.shardHost.Shards() {
+			shard.FSM.Changes().Close()
+		}
+	}
+}
+
+// handOverLeadership moves leadership to another voter so the cluster keeps
+// accepting writes while this node stops. A single-node cluster has nobody to
+// hand over to; that and any other failure is logged and shutdown continues.
+func (s *Server) handOverLeadership() {
+	if err := s.raftNode.TransferLeadership(""); err != nil {
+		s.logger.Warn("leadership transfer before shutdown failed", slog.String("error", err.Error()))
+		return
+	}
+	s.logger.Info("leadership transferred before shutdown")
+}
+
+// leaveCluster removes this node from the Raft configuration so the remaining
+// members stop counting it towards quorum. In a sharded cluster it leaves its
+// shards first, and stays in the metadata group if that fails, since the
+// shard map still names it. Failing to leave is logged but doesn't block
+// shutdown; the node can still be removed with /shards/leave and
+// /raft/remove.
+func (s *Server) leaveCluster() {
+	ctx, cancel := context.WithTimeout(context.Background(), leaveTimeout)
+	defer cancel()
+
+	if s.shardHost != nil {
+		s.logger.Info("leaving shards", slog.String("nodeID", s.clusterCfg.NodeID))
+		if err := s.shardHost.Leave(ctx); err != nil {
+			s.logger.Error("failed to leave shards", slog.String("error", err.Error()))
+			return
+		}
+	}
+
+	s.logger.Info("leaving cluster", slog.String("nodeID", s.clusterCfg.NodeID))
+	if err := s.raftNode.Leave(ctx); err != nil {
+		s.logger.Error("failed to leave cluster", slog.String("error", err.Error()))
+		return
+	}
+	s.logger.Info("left cluster")
+}
@@ -0,0 +1,200 @@
+package server
+
+import (
+	"context"
+	"crypto/rand"
+	"encoding/hex"
+	"time"
+
+	"github.com/mateenbagheri/memorabilia/api"
+	"github.com/mateenbagheri/memorabilia/pkg/replication"
+)
+
+// CreateSession starts a session, under the ID the client picked or a
+// random one. The FSM computes its deadline from the command's replicated
+// timestamp.
+func (cs *CommandServer) CreateSession(ctx context.Context, in *api.CreateSessionRequest) (*api.CreateSessionResponse, error) {
+	if in.GetTtlMs() <= 0 {
+		return nil, invalidArgument("create session", "ttl_ms", "must be positive")
+	}
+	id := in.GetId()
+	if id == "" {
+		id = newSessionID()
+	}
+	resp, err := cs.applyCommand(ctx, "create session", id, &replication.RaftCommand{
+		Op:      replication.OpCreateSession,
+		Session: id,
+		TTL:     time.Duration(in.GetTtlMs()) * time.Millisecond,
+	})
+	if err != nil {
+		return nil, err
+	}
+	return &api.CreateSessionResponse{Id: id, ExpiresAtMs: resp.LeaseEnd.UnixMilli()}, nil
+}
+
+func (cs *CommandServer) KeepAlive(ctx context.Context, in *api.KeepAliveRequest) (*api.KeepAliveResponse, error) {
+	if in.GetId() == "" {
+		return nil, invalidArgument("keepalive", "id", "must not be empty")
+	}
+	resp, err := cs.applyCommand(ctx, "keepalive", in.GetId(), &replication.RaftCommand{
+		Op:      replication.OpKeepAlive,
+		Session: in.GetId(),
+	})
+	if err != nil {
+		return nil, err
+	}
+	return &api.KeepAliveResponse{ExpiresAtMs: resp.LeaseEnd.UnixMilli()}, nil
+}
+
+// CloseSession closes a session and deletes its ephemeral keys, in one
+// write.
+func (cs *CommandServer) CloseSession(ctx context.Context, in *api.CloseSessionRequest) (*api.CloseSessionResponse, error) {
+	if in.GetId() == "" {
+		return nil, invalidArgument("close session", "id", "must not be empty")
+	}
+	resp, err := cs.applyCommand(ctx, "close session", in.GetId(), closeSessionCommand(in.GetId(), cs.repo.SessionKeys(ctx, in.GetId()), false))
+	if err != nil {
+		return nil, err
+	}
+	return &api.CloseSessionResponse{Closed: resp.Applied, DeleteCount: resp.DeleteCount}, nil
+}
+
+// expireSessions closes the sessions that ended in direct mode, recording
+// the deletion of their keys like a cleanup's. It returns how many it
+// closed, and how many keys it deleted.
+func (cs *CommandServer) expireSessions(ctx context.Context) (closed int, deleteCount int64, err error) {
+	for _, id := range cs.repo.ExpiredSessions(ctx) {
+		resp, err := cs.applyDirect(ctx, closeSessionCommand(id, cs.repo.SessionKeys(ctx, id), true))
+		if err != nil {
+			return closed, deleteCount, err
+		}
+		closed++
+		deleteCount += resp.DeleteCount
+	}
+	return closed, deleteCount, nil
+}
+
+// closeSessionCommand builds the command closing session and deleting keys,
+// the ephemeral keys attached to it. The keys travel in the command so that
+// applying it doesn't take a scan of the store: a key attached after they
+// were collected outlives the session until the TTL cleanup finds it
+// orphaned, and one set again without the session is kept.
+func closeSessionCommand(session string, keys []string, expired bool) *replication.RaftCommand {
+	return &replication.RaftCommand{
+		Op:      replication.OpCloseSession,
+		Session: session,
+		Keys:    keys,
+		Expired: expired,
+	}
+}
+
+// newSessionID returns a random session ID.
+func newSessionID() string {
+	b := make([]byte, 16)
+	_, _ = rand.Read(b)
+	return hex.EncodeToString(b)
+}
+
+// CreateSession starts the session on every shard, since its ephemeral keys
+// may land on any of them. Each shard ends it on its own, deleting the keys
+// it holds, so a session that ends goes from the shards one after the other;
+// keeping it alive and closing it go to every shard too. Shards added later
+// don't know the session: keys set there with it are refused.
+func (r *ShardRouter) CreateSession(ctx context.Context, in *api.CreateSessionRequest) (*api.CreateSessionResponse, error) {
+	if in.GetTtlMs() <= 0 {
+		return nil, invalidArgument("create session", "ttl_ms", "must be positive")
+	}
+	req := &api.CreateSessionRequest{Id: in.GetId(), TtlMs: in.GetTtlMs()}
+	if req.Id == "" {
+		req.Id = newSessionID()
+	}
+	total := &api.CreateSessionResponse{Id: req.Id}
+	merge := func(resp *api.CreateSessionResponse, err error) error {
+		if err == nil {
+			total.ExpiresAtMs = earliestExpiry(total.ExpiresAtMs, resp.GetExpiresAtMs())
+		}
+		return err
+	}
+	err := r.onEveryShard(ctx, func(cs *CommandServer) error {
+		return merge(cs.CreateSession(ctx, req))
+	}, func(ctx context.Context, c api.CommandsClient) error {
+		return merge(c.CreateSession(ctx, req))
+	})
+	if err != nil {
+		return nil, err
+	}
+	return total, nil
+}
+
+// KeepAlive keeps the session alive on every shard; see CreateSession.
+func (r *ShardRouter) KeepAlive(ctx context.Context, in *api.KeepAliveRequest) (*api.KeepAliveResponse, error) {
+	if in.GetId() == "" {
+		return nil, invalidArgument("keepalive", "id", "must not be empty")
+	}
+	total := &api.KeepAliveResponse{}
+	merge := func(resp *api.KeepAliveResponse, err error) error {
+		if err == nil {
+			total.ExpiresAtMs = earliestExpiry(total.ExpiresAtMs, resp.GetExpiresAtMs())
+		}
+		return err
+	}
+	err := r.onEveryShard(ctx, func(cs *CommandServer) error {
+		return merge(cs.KeepAlive(ctx, in))
+	}, func(ctx context.Context, c api.CommandsClient) error {
+		return merge(c.KeepAlive(ctx, in))
+	})
+	if err != nil {
+		return nil, err
+	}
+	return total, nil
+}
+
+// CloseSession closes the session on every shard; see CreateSession. Each
+// shard deletes the keys it holds in one write, but the shards do it one
+// after the other.
+func (r *ShardRouter) CloseSession(ctx context.Context, in *api.CloseSessionRequest) (*api.CloseSessionResponse, error) {
+	if in.GetId() == "" {
+		return nil, invalidArgument("close session", "id", "must not be empty")
+	}
+	total := &api.CloseSessionResponse{}
+	merge := func(resp *api.CloseSessionResponse, err error) error {
+		if err == nil {
+			total.Closed = total.Closed || resp.GetClosed()
+			total.DeleteCount += resp.GetDeleteCount()
+		}
+		return err
+	}
+	err := r.onEveryShard(ctx, func(cs *CommandServer) error {
+		return merge(cs.CloseSession(ctx, in))
+	}, func(ctx context.Context, c api.CommandsClient) error {
+		return merge(c.CloseSession(ctx, in))
+	})
+	if err != nil {
+		return nil, err
+	}
+	return total, nil
+}
+
+// onEveryShard runs a request on each of the target shards in turn (see
+// targetShards), stopping at the first error.
+func (r *ShardRouter) onEveryShard(
+	ctx context.Context,
+	local func(cs *CommandServer) error,
+	remote func(ctx context.Context, c api.CommandsClient) error,
+) error {
+	for _, shard := range r.targetShards(ctx) {
+		if err := r.onShard(ctx, shard, local, remote); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// earliestExpiry returns the earlier of two expiries in Unix milliseconds,
+// where 0 stands for none yet.
+func earliestExpiry(a, b int64) int64 {
+	if a == 0 || b < a {
+		return b
+	}
+	return a
+}
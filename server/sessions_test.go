@@ -0,0 +1,112 @@
+package server
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/mateenbagheri/memorabilia/api"
+	"github.com/mateenbagheri/memorabilia/pkg/core"
+	"github.com/mateenbagheri/memorabilia/pkg/replication"
+	"github.com/mateenbagheri/memorabilia/pkg/sharding"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"google.golang.org/grpc/codes"
+)
+
+func TestCommandServer_Session(t *testing.T) {
+	cs := NewCommandServer(core.NewInMemoryCommandRepository())
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+
+	_, err := cs.Set(ctx, &api.SetRequest{Id: "svc/a", Value: "10.0.0.1", Session: "nope"})
+	code, info, _, _ := errorDetails(t, err)
+	assert.Equal(t, codes.NotFound, code)
+	assert.Equal(t, ReasonSessionNotFound, info.GetReason())
+	assert.Equal(t, "nope", info.GetMetadata()[MetaSession])
+
+	created, err := cs.CreateSession(ctx, &api.CreateSessionRequest{TtlMs: 60_000})
+	require.NoError(t, err)
+	require.NotEmpty(t, created.GetId())
+	assert.InDelta(t, time.Now().Add(time.Minute).UnixMilli(), created.GetExpiresAtMs(), 5000)
+	for _, key := range []string{"svc/a", "svc/b"} {
+		_, err = cs.Set(ctx, &api.SetRequest{Id: key, Value: "10.0.0.1", Session: created.GetId()})
+		require.NoError(t, err)
+	}
+	_, err = cs.KeepAlive(ctx, &api.KeepAliveRequest{Id: created.GetId()})
+	require.NoError(t, err)
+
+	// Closing the session deletes its keys, and shows up in the change log
+	// as their deletion.
+	sub := cs.changes.Subscribe(3, "") // after the two sets
+	closed, err := cs.CloseSession(ctx, &api.CloseSessionRequest{Id: created.GetId()})
+	require.NoError(t, err)
+	assert.True(t, closed.GetClosed())
+	assert.Equal(t, int64(2), closed.GetDeleteCount())
+	_, err = cs.Get(ctx, &api.GetRequest{Id: "svc/a"})
+	code, _, _, _ = errorDetails(t, err)
+	assert.Equal(t, codes.NotFound, code)
+	changes, err := sub.Next(ctx)
+	require.NoError(t, err)
+	require.Len(t, changes, 1)
+	assert.Equal(t, replication.OpBatchDelete, changes[0].Command.Op)
+	assert.ElementsMatch(t, []string{"svc/a", "svc/b"}, changes[0].Command.Keys)
+
+	_, err = cs.KeepAlive(ctx, &api.KeepAliveRequest{Id: created.GetId()})
+	code, _, _, _ = errorDetails(t, err)
+	assert.Equal(t, codes.NotFound, code)
+
+	// A session that isn't kept alive ends, and the expiry job deletes its
+	// keys.
+	_, err = cs.CreateSession(ctx, &api.CreateSessionRequest{Id: "short", TtlMs: 20})
+	require.NoError(t, err)
+	_, err = cs.Set(ctx, &api.SetRequest{Id: "svc/c", Value: "10.0.0.3", Session: "short"})
+	require.NoError(t, err)
+	time.Sleep(30 * time.Millisecond)
+	n, deleted, err := cs.expireSessions(ctx)
+	require.NoError(t, err)
+	assert.Equal(t, 1, n)
+	assert.Equal(t, int64(1), deleted)
+	changes, err = sub.Next(ctx)
+	require.NoError(t, err)
+	require.Len(t, changes, 2)
+	assert.Equal(t, "svc/c", changes[0].Command.Key)
+	assert.Equal(t, []string{"svc/c"}, changes[1].Command.Keys)
+	assert.True(t, changes[1].Command.Expired)
+
+	_, err = cs.CreateSession(ctx, &api.CreateSessionRequest{})
+	code, _, _, _ = errorDetails(t, err)
+	assert.Equal(t, codes.InvalidArgument, code)
+}
+
+func TestShardRouter_Session(t *testing.T) {
+	host := newTestShardHost(t)
+	router := NewShardRouter(host)
+	defer router.Close()
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+	defer cancel()
+	require.NoError(t, host.AddShard(ctx, 1, []string{"n1"}, []int{sharding.SlotOf("a")}))
+
+	// The session exists on every shard, so keys on any of them can be
+	// attached to it, and closing it deletes them all.
+	created, err := router.CreateSession(ctx, &api.CreateSessionRequest{Id: "s", TtlMs: 60_000})
+	require.NoError(t, err)
+	assert.Equal(t, "s", created.GetId())
+	for _, key := range []string{"a", "b"} {
+		_, err = router.Set(ctx, &api.SetRequest{Id: key, Value: "v", Session: "s"})
+		require.NoError(t, err)
+	}
+	kept, err := router.KeepAlive(ctx, &api.KeepAliveRequest{Id: "s"})
+	require.NoError(t, err)
+	assert.GreaterOrEqual(t, kept.GetExpiresAtMs(), created.GetExpiresAtMs())
+
+	closed, err := router.CloseSession(ctx, &api.CloseSessionRequest{Id: "s"})
+	require.NoError(t, err)
+	assert.True(t, closed.GetClosed())
+	assert.Equal(t, int64(2), closed.GetDeleteCount())
+	for _, key := range []string{"a", "b"} {
+		_, err = router.Get(ctx, &api.GetRequest{Id: key})
+		code, _, _, _ := errorDetails(t, err)
+		assert.Equal(t, codes.NotFound, code)
+	}
+}
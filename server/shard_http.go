@@ -0,0 +1,367 @@
+package server
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"log/slog"
+	"net/http"
+	"strconv"
+
+	"github.com/mateenbagheri/memorabilia/pkg/replication"
+	"github.com/mateenbagheri/memorabilia/pkg/sharding"
+)
+
+// ShardHTTPHandler exposes the shard map and its management over HTTP:
+//
+//	GET  /shards              return the shard map and this node's shards
+//	POST /shards/add          add a shard, optionally moving slots to it
+//	                          (forwarded to the leader)
+//	POST /shards/move-slots   move slots, and their keys, to another shard
+//	                          (forwarded to the leader)
+//	POST /shards/move-replica replace a member of a shard by another node
+//	                          (forwarded to the leader)
+//	POST /shards/remove       remove a shard that owns no slots
+//	                          (forwarded to the leader)
+//	POST /shards/leave        take a node out of every shard it is a member
+//	                          of (forwarded to the leader)
+//	GET  /shards/backup?shard=N   stream a backup of this node's replica of
+//	                              shard N, like /raft/backup
+//	POST /shards/restore?shard=N  replace shard N's state with a backup
+//	                              (shard N's leader only)
+//
+// "The leader" above is the metadata group's. The routes below are used
+// between nodes while slots move, and must reach the leader of the shard
+// named in the body; other members answer 421:
+//
+//	POST /shards/freeze    refuse writes to slots and return their keys
+//	POST /shards/load      copy keys into the shard
+//	POST /shards/drop      delete the keys in slots and unfreeze them
+//	POST /shards/unfreeze  accept writes to slots again
+type ShardHTTPHandler struct {
+	host   *replication.ShardHost
+	raft   *RaftHTTPHandler
+	logger *slog.Logger
+}
+
+// NewShardHTTPHandler constructs a handler for the shards run by host.
+func NewShardHTTPHandler(host *replication.ShardHost, logger *slog.Logger) *ShardHTTPHandler {
+	return &ShardHTTPHandler{
+		host:   host,
+		raft:   NewRaftHTTPHandler(host.Meta(), logger),
+		logger: logger,
+	}
+}
+
+// RegisterRoutes registers all shard routes on mux.
+func (h *ShardHTTPHandler) RegisterRoutes(mux *http.ServeMux) {
+	mux.HandleFunc("/shards", h.handleShards)
+	mux.HandleFunc("/shards/add", h.handleAdd)
+	mux.HandleFunc("/shards/move-slots", h.handleMoveSlots)
+	mux.HandleFunc("/shards/move-replica", h.handleMoveReplica)
+	mux.HandleFunc("/shards/remove", h.handleRemove)
+	mux.HandleFunc("/shards/leave", h.handleLeave)
+	mux.HandleFunc("/shards/backup", h.handleBackup)
+	mux.HandleFunc("/shards/restore", h.handleRestore)
+	mux.HandleFunc("/shards/freeze", h.handleFreeze)
+	mux.HandleFunc("/shards/load", h.handleLoad)
+	mux.HandleFunc("/shards/drop", h.handleDrop)
+	mux.HandleFunc("/shards/unfreeze", h.handleUnfreeze)
+}
+
+// ShardsResponse is the response of GET /shards.
+type ShardsResponse struct {
+	// Version is the shard map's version; 0 until the first shard is added.
+	Version uint64 `json:"version"`
+
+	Shards []ShardInfo `json:"shards"`
+
+	// Migrating maps slots being moved to their destination shard.
+	Migrating map[int]sharding.ShardID `json:"migrating,omitempty"`
+
+	// Local describes the replicas of shards running on the node serving
+	// the request.
+	Local []LocalShardInfo `json:"local"`
+}
+
+// ShardInfo is a shard's entry in the map.
+type ShardInfo struct {
+	ID        sharding.ShardID `json:"id"`
+	Members   []string         `json:"members,omitempty"`
+	Slots     string           `json:"slots"`
+	SlotCount int              `json:"slot_count"`
+}
+
+// LocalShardInfo describes this node's replica of a shard.
+type LocalShardInfo struct {
+	ID           sharding.ShardID `json:"id"`
+	State        string           `json:"state"`
+	LeaderID     string           `json:"leader_id"`
+	AppliedIndex uint64           `json:"applied_index"`
+	FrozenSlots  string           `json:"frozen_slots,omitempty"`
+}
+
+// handleShards returns a ShardsResponse. The map is this node's replicated
+// copy; the local part describes this node only, so it is never forwarded.
+func (h *ShardHTTPHandler) handleShards(w http.ResponseWriter, r *http.Request) {
+	m := h.host.Map()
+	resp := ShardsResponse{}
+	if m != nil {
+		resp.Version = m.Version
+		resp.Migrating = m.Migrating
+	}
+	for _, id := range m.IDs() {
+		info := ShardInfo{ID: id}
+		if m == nil {
+			info.SlotCount = sharding.SlotCount
+			info.Slots = fmt.Sprintf("0-%d", sharding.SlotCount-1)
+		} else {
+			slots := m.SlotsOf(id)
+			info.Members = m.Shards[id].Members
+			info.SlotCount = len(slots)
+			info.Slots = sharding.FormatSlots(slots)
+		}
+		resp.Shards = append(resp.Shards, info)
+
+		node, fsm, ok := h.host.Group(id)
+		if !ok {
+			continue
+		}
+		stats := node.Stats()
+		resp.Local = append(resp.Local, LocalShardInfo{
+			ID:           id,
+			State:        stats.State,
+			LeaderID:     stats.LeaderID,
+			AppliedIndex: stats.AppliedIndex,
+			FrozenSlots:  sharding.FormatSlots(fsm.FrozenSlots()),
+		})
+	}
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(resp)
+}
+
+// handleAdd accepts a JSON-encoded replication.AddShardRequest body. It
+// returns once the shard is in the map and its slots, if any, have moved;
+// the members start the group within a second or so.
+func (h *ShardHTTPHandler) handleAdd(w http.ResponseWriter, r *http.Request) {
+	var req replication.AddShardRequest
+	h.handleChange(w, r, "/shards/add", &req, func(ctx context.Context) error {
+		return h.host.AddShard(ctx, req.ShardID, req.Members, req.Slots)
+	})
+}
+
+// handleMoveSlots accepts a JSON-encoded replication.MoveSlotsRequest body
+// and returns once the slots have moved. Writes to them fail with
+// SLOT_MIGRATING in the meantime.
+func (h *ShardHTTPHandler) handleMoveSlots(w http.ResponseWriter, r *http.Request) {
+	var req replication.MoveSlotsRequest
+	h.handleChange(w, r, "/shards/move-slots", &req, func(ctx context.Context) error {
+		return h.host.MoveSlots(ctx, req.Slots, req.To)
+	})
+}
+
+// handleMoveReplica accepts a JSON-encoded replication.MoveReplicaRequest
+// body. It returns once the map has changed; the new member then catches up
+// from the group's snapshot in the background, see GET /shards.
+func (h *ShardHTTPHandler) handleMoveReplica(w http.ResponseWriter, r *http.Request) {
+	var req replication.MoveReplicaRequest
+	h.handleChange(w, r, "/shards/move-replica", &req, func(ctx context.Context) error {
+		return h.host.MoveReplica(req.ShardID, req.From, req.To)
+	})
+}
+
+// handleRemove accepts a JSON-encoded replication.RemoveShardRequest body.
+func (h *ShardHTTPHandler) handleRemove(w http.ResponseWriter, r *http.Request) {
+	var req replication.RemoveShardRequest
+	h.handleChange(w, r, "/shards/remove", &req, func(ctx context.Context) error {
+		return h.host.RemoveShard(req.ShardID)
+	})
+}
+
+// handleLeave accepts a JSON-encoded replication.LeaveShardsRequest body.
+func (h *ShardHTTPHandler) handleLeave(w http.ResponseWriter, r *http.Request) {
+	var req replication.LeaveShardsRequest
+	h.handleChange(w, r, "/shards/leave", &req, func(ctx context.Context) error {
+		return h.host.RemoveNode(req.NodeID)
+	})
+}
+
+// handleBackup streams a backup of this node's replica of the shard named by
+// the shard query parameter, in the format /shards/restore reads back. Each
+// shard group is backed up on its own; back up every shard for the whole
+// cluster.
+func (h *ShardHTTPHandler) handleBackup(w http.ResponseWriter, r *http.Request) {
+	id, ok := shardParam(w, r)
+	if !ok {
+		return
+	}
+	node, _, ok := h.host.Group(id)
+	if !ok {
+		http.Error(w, replication.ErrNotShardMember.Error(), http.StatusNotFound)
+		return
+	}
+	NewRaftHTTPHandler(node, h.logger).handleBackup(w, r)
+}
+
+// handleRestore replaces the state of the shard named by the shard query
+// parameter with the backup in the request body; see
+// replication.ShardHost.RestoreShard. Like /raft/restore it must reach the
+// shard's leader, and other members answer 421.
+func (h *ShardHTTPHandler) handleRestore(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodPost {
+		http.Error(w, "method not allowed", http.StatusMethodNotAllowed)
+		return
+	}
+	id, ok := shardParam(w, r)
+	if !ok || !h.requireShardLeader(w, id) {
+		return
+	}
+
+	if err := h.host.RestoreShard(id, r.Body); err != nil {
+		if errors.Is(err, replication.ErrInvalidBackup) {
+			http.Error(w, "bad request: "+err.Error(), http.StatusBadRequest)
+			return
+		}
+		h.logger.Error("shard restore failed", slog.Uint64("shard", uint64(id)), slog.String("error", err.Error()))
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+		return
+	}
+	w.WriteHeader(http.StatusOK)
+}
+
+// shardParam reads the shard query parameter, answering 400 when it is
+// missing or not a shard ID.
+func shardParam(w http.ResponseWriter, r *http.Request) (sharding.ShardID, bool) {
+	id, err := strconv.ParseUint(r.URL.Query().Get("shard"), 10, 32)
+	if err != nil {
+		http.Error(w, "bad request: shard: "+err.Error(), http.StatusBadRequest)
+		return 0, false
+	}
+	return sharding.ShardID(id), true
+}
+
+// handleChange is the shared body of the map-changing routes: decode req,
+// forward to the metadata group's leader when needed, then run change there.
+func (h *ShardHTTPHandler) handleChange(
+	w http.ResponseWriter,
+	r *http.Request,
+	path string,
+	req any,
+	change func(ctx context.Context) error,
+) {
+	if !decodePost(w, r, req) {
+		return
+	}
+
+	if !h.host.Meta().IsLeader() {
+		h.raft.forwardToLeader(w, r, path, req)
+		return
+	}
+
+	if err := change(r.Context()); err != nil {
+		switch {
+		case errors.Is(err, replication.ErrInvalidShardChange):
+			http.Error(w, "bad request: "+err.Error(), http.StatusBadRequest)
+		case errors.Is(err, sharding.ErrConflict):
+			http.Error(w, err.Error(), http.StatusConflict)
+		default:
+			h.logger.Error("shard change failed", slog.String("path", path), slog.String("error", err.Error()))
+			http.Error(w, err.Error(), http.StatusInternalServerError)
+		}
+		return
+	}
+
+	h.logger.Info("shard map changed", slog.String("path", path))
+	w.WriteHeader(http.StatusOK)
+}
+
+// handleFreeze accepts a JSON-encoded replication.SlotsRequest body and
+// returns the frozen keys as a replication.EntriesResponse.
+func (h *ShardHTTPHandler) handleFreeze(w http.ResponseWriter, r *http.Request) {
+	var req replication.SlotsRequest
+	if !decodePost(w, r, &req) || !h.requireShardLeader(w, req.ShardID) {
+		return
+	}
+	entries, err := h.host.FreezeSlots(req.ShardID, req.Slots)
+	h.writeResult(w, r, replication.EntriesResponse{Entries: entries}, err)
+}
+
+// handleLoad accepts a JSON-encoded replication.LoadRequest body.
+func (h *ShardHTTPHandler) handleLoad(w http.ResponseWriter, r *http.Request) {
+	var req replication.LoadRequest
+	if !decodePost(w, r, &req) || !h.requireShardLeader(w, req.ShardID) {
+		return
+	}
+	h.writeResult(w, r, nil, h.host.LoadEntries(req.ShardID, req.Entries))
+}
+
+// handleDrop accepts a JSON-encoded replication.SlotsRequest body and
+// returns a replication.ApplyResponse with the number of keys deleted.
+func (h *ShardHTTPHandler) handleDrop(w http.ResponseWriter, r *http.Request) {
+	var req replication.SlotsRequest
+	if !decodePost(w, r, &req) || !h.requireShardLeader(w, req.ShardID) {
+		return
+	}
+	count, err := h.host.DropSlots(req.ShardID, req.Slots)
+	h.writeResult(w, r, replication.ApplyResponse{Applied: true, DeleteCount: count}, err)
+}
+
+// handleUnfreeze accepts a JSON-encoded replication.SlotsRequest body.
+func (h *ShardHTTPHandler) handleUnfreeze(w http.ResponseWriter, r *http.Request) {
+	var req replication.SlotsRequest
+	if !decodePost(w, r, &req) || !h.requireShardLeader(w, req.ShardID) {
+		return
+	}
+	h.writeResult(w, r, nil, h.host.UnfreezeSlots(req.ShardID, req.Slots))
+}
+
+// decodePost rejects anything but a POST with a JSON body decoding into req.
+func decodePost(w http.ResponseWriter, r *http.Request, req any) bool {
+	if r.Method != http.MethodPost {
+		http.Error(w, "method not allowed", http.StatusMethodNotAllowed)
+		return false
+	}
+	if err := json.NewDecoder(r.Body).Decode(req); err != nil {
+		http.Error(w, "bad request: "+err.Error(), http.StatusBadRequest)
+		return false
+	}
+	return true
+}
+
+// requireShardLeader answers the request unless this node leads shard: 404
+// when it doesn't run the shard, 421 pointing at the shard's leader on a
+// follower, and 503 while the shard has no leader.
+func (h *ShardHTTPHandler) requireShardLeader(w http.ResponseWriter, shard sharding.ShardID) bool {
+	node, _, ok := h.host.Group(shard)
+	if !ok {
+		http.Error(w, replication.ErrNotShardMember.Error(), http.StatusNotFound)
+		return false
+	}
+	if node.IsLeader() {
+		return true
+	}
+	leader := node.LeaderRaftAddr()
+	if leader == "" {
+		http.Error(w, "no leader elected yet", http.StatusServiceUnavailable)
+		return false
+	}
+	NewRaftHTTPHandler(node, h.logger).misdirected(w, leader)
+	return false
+}
+
+// writeResult writes resp as JSON, or just 200 when it is nil, unless err is
+// set.
+func (h *ShardHTTPHandler) writeResult(w http.ResponseWriter, r *http.Request, resp any, err error) {
+	if err != nil {
+		h.logger.Error("shard operation failed", slog.String("path", r.URL.Path), slog.String("error", err.Error()))
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+		return
+	}
+	if resp == nil {
+		w.WriteHeader(http.StatusOK)
+		return
+	}
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(resp)
+}
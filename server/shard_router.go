@@ -0,0 +1,516 @@
+package server
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"slices"
+	"strconv"
+	"sync"
+
+	"github.com/mateenbagheri/memorabilia/api"
+	"github.com/mateenbagheri/memorabilia/pkg/core"
+	"github.com/mateenbagheri/memorabilia/pkg/replication"
+	"github.com/mateenbagheri/memorabilia/pkg/sharding"
+	"google.golang.org/genproto/googleapis/rpc/errdetails"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/credentials/insecure"
+	"google.golang.org/grpc/metadata"
+	"google.golang.org/grpc/status"
+	"google.golang.org/protobuf/proto"
+	"google.golang.org/protobuf/types/known/emptypb"
+)
+
+// ShardHeader is the gRPC metadata key set on requests one node forwards to
+// another. It names the shard the receiving node must serve the request
+// from, and keeps the request from being forwarded again.
+const ShardHeader = "x-memorabilia-shard"
+
+// ShardRouter serves the Commands service on a cluster whose keyspace is
+// split across shard groups (see package sharding). Each request is routed to
+// the shard owning its key: served by this node's CommandServer for that
+// group when it runs it, and forwarded to a member of the group otherwise.
+// Requests spanning keys (BatchDelete, Scan, GetExpiredKeys) fan out to every
+// shard involved and merge the results.
+//
+// Until a shard is added the map is empty and everything lives in the
+// metadata group, so a router behaves exactly like a CommandServer in Raft
+// mode.
+type ShardRouter struct {
+	api.UnimplementedCommandsServer
+
+	host *replication.ShardHost
+
+	mu sync.Mutex
+	// local holds a CommandServer per group running on this node. A group
+	// restarted after being retired gets a new node, so entries are checked
+	// against it.
+	local map[sharding.ShardID]*CommandServer
+	conns map[string]*grpc.ClientConn
+}
+
+func NewShardRouter(host *replication.ShardHost) *ShardRouter {
+	return &ShardRouter{
+		host:  host,
+		local: make(map[sharding.ShardID]*CommandServer),
+		conns: make(map[string]*grpc.ClientConn),
+	}
+}
+
+// Close closes the connections used to forward requests.
+func (r *ShardRouter) Close() error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	var errs []error
+	for addr, conn := range r.conns {
+		errs = append(errs, conn.Close())
+		delete(r.conns, addr)
+	}
+	return errors.Join(errs...)
+}
+
+// -- Handlers --
+
+func (r *ShardRouter) Echo(ctx context.Context, in *api.EchoRequest) (*api.EchoResponse, error) {
+	return &api.EchoResponse{Message: in.GetMessage()}, nil
+}
+
+func (r *ShardRouter) Get(ctx context.Context, in *api.GetRequest) (*api.GetResponse, error) {
+	if in.GetId() == "" {
+		return nil, invalidArgument("get", "id", "must not be empty")
+	}
+	cs, shard, err := r.route(ctx, in.GetId(), false)
+	if err != nil {
+		return nil, err
+	}
+	if cs != nil {
+		return cs.Get(ctx, in)
+	}
+	var resp *api.GetResponse
+	err = r.forward(ctx, shard, func(ctx context.Context, c api.CommandsClient) (err error) {
+		resp, err = c.Get(ctx, in)
+		return err
+	})
+	return resp, err
+}
+
+func (r *ShardRouter) Set(ctx context.Context, in *api.SetRequest) (*api.SetResponse, error) {
+	if in.GetId() == "" {
+		return nil, invalidArgument("set", "id", "must not be empty")
+	}
+	cs, shard, err := r.route(ctx, in.GetId(), true)
+	if err != nil {
+		return nil, err
+	}
+	if cs != nil {
+		return cs.Set(ctx, in)
+	}
+	var resp *api.SetResponse
+	err = r.forward(ctx, shard, func(ctx context.Context, c api.CommandsClient) (err error) {
+		resp, err = c.Set(ctx, in)
+		return err
+	})
+	return resp, err
+}
+
+func (r *ShardRouter) Delete(ctx context.Context, in *api.DeleteRequest) (*api.DeleteResponse, error) {
+	if in.GetId() == "" {
+		return nil, invalidArgument("delete", "id", "must not be empty")
+	}
+	cs, shard, err := r.route(ctx, in.GetId(), true)
+	if err != nil {
+		return nil, err
+	}
+	if cs != nil {
+		return cs.Delete(ctx, in)
+	}
+	var resp *api.DeleteResponse
+	err = r.forward(ctx, shard, func(ctx context.Context, c api.CommandsClient) (err error) {
+		resp, err = c.Delete(ctx, in)
+		return err
+	})
+	return resp, err
+}
+
+func (r *ShardRouter) TTL(ctx context.Context, in *api.TTLRequest) (*api.TTLResponse, error) {
+	if in.GetId() == "" {
+		return nil, invalidArgument("ttl", "id", "must not be empty")
+	}
+	cs, shard, err := r.route(ctx, in.GetId(), false)
+	if err != nil {
+		return nil, err
+	}
+	if cs != nil {
+		return cs.TTL(ctx, in)
+	}
+	var resp *api.TTLResponse
+	err = r.forward(ctx, shard, func(ctx context.Context, c api.CommandsClient) (err error) {
+		resp, err = c.TTL(ctx, in)
+		return err
+	})
+	return resp, err
+}
+
+// BatchDelete splits the keys by shard and deletes each part in its shard.
+// The parts are separate writes: if one fails, the others may have been
+// applied already.
+func (r *ShardRouter) BatchDelete(ctx context.Context, in *api.BatchDeleteRequest) (*api.BatchDeleteResponse, error) {
+	m := r.host.Map()
+	byShard := make(map[sharding.ShardID][]string)
+	for i, id := range in.GetIds() {
+		if id == "" {
+			return nil, invalidArgument("batch delete", fmt.Sprintf("ids[%d]", i), "must not be empty")
+		}
+		if m.IsMigrating(id) {
+			return nil, slotMigratingError(id)
+		}
+		owner := m.ShardFor(id)
+		byShard[owner] = append(byShard[owner], id)
+	}
+	if target, ok := forwardedShard(ctx); ok {
+		for owner, ids := range byShard {
+			if owner != target {
+				return nil, wrongShardError(ids[0], target, owner)
+			}
+		}
+	}
+
+	total := &api.BatchDeleteResponse{}
+	for _, shard := range sortedShards(byShard) {
+		req := &api.BatchDeleteRequest{Ids: byShard[shard]}
+		var resp *api.BatchDeleteResponse
+		err := r.onShard(ctx, shard, func(cs *CommandServer) (err error) {
+			resp, err = cs.BatchDelete(ctx, req)
+			return err
+		}, func(ctx context.Context, c api.CommandsClient) (err error) {
+			resp, err = c.BatchDelete(ctx, req)
+			return err
+		})
+		if err != nil {
+			return nil, err
+		}
+		total.DeleteCount += resp.GetDeleteCount()
+		total.Applied = total.Applied || resp.GetApplied()
+	}
+	return total, nil
+}
+
+func (r *ShardRouter) GetExpiredKeys(ctx context.Context, in *emptypb.Empty) (*api.GetExpiredKeysResponse, error) {
+	var ids []string
+	for _, shard := range r.targetShards(ctx) {
+		var resp *api.GetExpiredKeysResponse
+		err := r.onShard(ctx, shard, func(cs *CommandServer) (err error) {
+			resp, err = cs.GetExpiredKeys(ctx, in)
+			return err
+		}, func(ctx context.Context, c api.CommandsClient) (err error) {
+			resp, err = c.GetExpiredKeys(ctx, in)
+			return err
+		})
+		if err != nil {
+			return nil, err
+		}
+		ids = append(ids, resp.GetIds()...)
+	}
+	return &api.GetExpiredKeysResponse{Ids: ids}, nil
+}
+
+// Subscribe streams the changes of the shard named in the request, from a
+// member of the shard; this node when it is one. A stream forwarded from
+// another member resumes on the next one if the member goes away, from the
+// last index sent, so the writes of that entry may be sent twice.
+func (r *ShardRouter) Subscribe(in *api.SubscribeRequest, stream api.Commands_SubscribeServer) error {
+	ctx := stream.Context()
+	shard := sharding.ShardID(in.GetShard())
+	if !slices.Contains(r.host.Map().IDs(), shard) {
+		return invalidArgument("subscribe", "shard", fmt.Sprintf("%d is not a shard", shard))
+	}
+
+	req := proto.Clone(in).(*api.SubscribeRequest)
+	return r.onShard(ctx, shard, func(cs *CommandServer) error {
+		return cs.Subscribe(in, stream)
+	}, func(ctx context.Context, c api.CommandsClient) error {
+		events, err := c.Subscribe(ctx, req)
+		if err != nil {
+			return err
+		}
+		for {
+			event, err := events.Recv()
+			if errors.Is(err, io.EOF) {
+				return nil
+			}
+			if err != nil {
+				return err
+			}
+			if err := stream.Send(event); err != nil {
+				return err
+			}
+			req.FromIndex = event.GetIndex()
+			if op := event.GetOp(); op == api.ChangeOp_CHANGE_OP_SNAPSHOT || op == api.ChangeOp_CHANGE_OP_PROGRESS {
+				req.FromIndex++
+			}
+		}
+	})
+}
+
+// Scan pages through every shard at once. Cursors are the last key returned,
+// so the same cursor works on every shard; each one returns a page after it,
+// and the pages are merged.
+//
+// A shard that had more keys stops its page at its last key. Keys past the
+// lowest such key may come before ones that shard hasn't returned yet, so
+// the merged page is cut there and the rest is returned again next time.
+func (r *ShardRouter) Scan(ctx context.Context, in *api.ScanRequest) (*api.ScanResponse, error) {
+	if in.GetCount() < 0 {
+		return nil, invalidArgument("scan", "count", "must not be negative")
+	}
+	count := in.GetCount()
+	if count == 0 {
+		count = core.DefaultScanCount
+	}
+
+	m := r.host.Map()
+	var (
+		keys   []string
+		cutoff string
+		more   bool
+	)
+	for _, shard := range r.targetShards(ctx) {
+		var resp *api.ScanResponse
+		err := r.onShard(ctx, shard, func(cs *CommandServer) (err error) {
+			resp, err = cs.Scan(ctx, in)
+			return err
+		}, func(ctx context.Context, c api.CommandsClient) (err error) {
+			resp, err = c.Scan(ctx, in)
+			return err
+		})
+		if err != nil {
+			return nil, err
+		}
+		for _, key := range resp.GetIds() {
+			// A slot's keys exist on both sides while it is being moved.
+			if m.ShardFor(key) == shard {
+				keys = append(keys, key)
+			}
+		}
+		if next := resp.GetNextCursor(); next != "" {
+			if !more || next < cutoff {
+				cutoff = next
+			}
+			more = true
+		}
+	}
+
+	slices.Sort(keys)
+	if more {
+		keys = keys[:sortedPrefixLen(keys, cutoff)]
+	}
+	if int64(len(keys)) > count {
+		keys, more = keys[:count], true
+	}
+	next := ""
+	if more && len(keys) > 0 {
+		next = keys[len(keys)-1]
+	} else if more {
+		// Every key up to cutoff was filtered out as not owned.
+		next = cutoff
+	}
+	return &api.ScanResponse{Ids: keys, NextCursor: next}, nil
+}
+
+// sortedPrefixLen returns how many of the sorted keys are <= cutoff.
+func sortedPrefixLen(keys []string, cutoff string) int {
+	i, found := slices.BinarySearch(keys, cutoff)
+	if found {
+		i++
+	}
+	return i
+}
+
+// -- Routing --
+
+// route finds where to serve a request for key: this node's CommandServer
+// for the owning shard, or, when cs is nil, shard on another node.
+func (r *ShardRouter) route(ctx context.Context, key string, write bool) (cs *CommandServer, shard sharding.ShardID, err error) {
+	m := r.host.Map()
+	owner := m.ShardFor(key)
+
+	if target, ok := forwardedShard(ctx); ok {
+		if target != owner {
+			return nil, 0, wrongShardError(key, target, owner)
+		}
+		if cs = r.commandServer(owner); cs == nil {
+			return nil, 0, wrongShardError(key, target, owner)
+		}
+	}
+	if write && m.IsMigrating(key) {
+		return nil, 0, slotMigratingError(key)
+	}
+	if cs != nil {
+		return cs, owner, nil
+	}
+	return r.commandServer(owner), owner, nil
+}
+
+// onShard runs local when shard is served by this node, and remote on a
+// member of shard otherwise.
+func (r *ShardRouter) onShard(
+	ctx context.Context,
+	shard sharding.ShardID,
+	local func(cs *CommandServer) error,
+	remote func(ctx context.Context, c api.CommandsClient) error,
+) error {
+	if cs := r.commandServer(shard); cs != nil {
+		return local(cs)
+	}
+	if _, ok := forwardedShard(ctx); ok {
+		return wrongShardError("", shard, shard)
+	}
+	return r.forward(ctx, shard, remote)
+}
+
+// targetShards returns the shards a request spanning every key goes to: all
+// of them, or just the one it was forwarded to.
+func (r *ShardRouter) targetShards(ctx context.Context) []sharding.ShardID {
+	if target, ok := forwardedShard(ctx); ok {
+		return []sharding.ShardID{target}
+	}
+	return r.host.Map().IDs()
+}
+
+// commandServer returns the CommandServer of shard on this node, or nil when
+// this node doesn't run it.
+func (r *ShardRouter) commandServer(shard sharding.ShardID) *CommandServer {
+	node, fsm, ok := r.host.Group(shard)
+	if !ok {
+		return nil
+	}
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	cs, ok := r.local[shard]
+	if !ok || cs.node != node {
+		cs = NewCommandServerWithRaft(fsm, node)
+		r.local[shard] = cs
+	}
+	return cs
+}
+
+// forward runs call against the members of shard in turn, until one answers.
+// A member that isn't the group's leader rejects writes with the leader's
+// address, and the call is retried there once.
+func (r *ShardRouter) forward(ctx context.Context, shard sharding.ShardID, call func(ctx context.Context, c api.CommandsClient) error) error {
+	ctx = metadata.AppendToOutgoingContext(ctx, ShardHeader, strconv.FormatUint(uint64(shard), 10))
+
+	addrs := r.memberAddrs(shard)
+	if len(addrs) == 0 {
+		return rpcError{
+			code:       codes.Unavailable,
+			reason:     ReasonNoLeader,
+			msg:        fmt.Sprintf("no reachable member of shard %d", shard),
+			retryDelay: electionRetryDelay,
+		}.err()
+	}
+
+	var err error
+	for _, addr := range addrs {
+		if err = r.call(ctx, addr, call); err == nil {
+			return nil
+		}
+		if leader, ok := leaderHint(err); ok {
+			return r.call(ctx, leader, call)
+		}
+		if status.Code(err) != codes.Unavailable || errorReason(err) != "" {
+			// An answer from the shard rather than a failure to reach it.
+			return err
+		}
+	}
+	return err
+}
+
+func (r *ShardRouter) call(ctx context.Context, addr string, call func(ctx context.Context, c api.CommandsClient) error) error {
+	conn, err := r.conn(addr)
+	if err != nil {
+		return internalError("forward", err)
+	}
+	return call(ctx, api.NewCommandsClient(conn))
+}
+
+// memberAddrs returns the gRPC addresses of the members of shard.
+func (r *ShardRouter) memberAddrs(shard sharding.ShardID) []string {
+	var members []string
+	if s, ok := r.host.Map().Shards[shard]; ok {
+		members = s.Members
+	}
+	var addrs []string
+	for _, member := range members {
+		if meta, ok := r.host.Meta().NodeMeta(member); ok && meta.GRPCAddr != "" {
+			addrs = append(addrs, meta.GRPCAddr)
+		}
+	}
+	return addrs
+}
+
+func (r *ShardRouter) conn(addr string) (*grpc.ClientConn, error) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	if conn, ok := r.conns[addr]; ok {
+		return conn, nil
+	}
+	conn, err := grpc.NewClient(addr, grpc.WithTransportCredentials(insecure.NewCredentials()))
+	if err != nil {
+		return nil, err
+	}
+	r.conns[addr] = conn
+	return conn, nil
+}
+
+// forwardedShard returns the shard named by ShardHeader on an incoming
+// request.
+func forwardedShard(ctx context.Context) (sharding.ShardID, bool) {
+	md, ok := metadata.FromIncomingContext(ctx)
+	if !ok {
+		return 0, false
+	}
+	values := md.Get(ShardHeader)
+	if len(values) == 0 {
+		return 0, false
+	}
+	id, err := strconv.ParseUint(values[0], 10, 32)
+	if err != nil {
+		return 0, false
+	}
+	return sharding.ShardID(id), true
+}
+
+// errorReason returns the ErrorInfo reason of a gRPC error, if any.
+func errorReason(err error) string {
+	for _, detail := range status.Convert(err).Details() {
+		if info, ok := detail.(*errdetails.ErrorInfo); ok {
+			return info.GetReason()
+		}
+	}
+	return ""
+}
+
+// leaderHint returns the leader's gRPC address from a NOT_LEADER error.
+func leaderHint(err error) (string, bool) {
+	for _, detail := range status.Convert(err).Details() {
+		info, ok := detail.(*errdetails.ErrorInfo)
+		if !ok || info.GetReason() != ReasonNotLeader {
+			continue
+		}
+		addr := info.GetMetadata()[MetaLeaderGRPCAddr]
+		return addr, addr != ""
+	}
+	return "", false
+}
+
+func sortedShards[V any](m map[sharding.ShardID]V) []sharding.ShardID {
+	ids := make([]sharding.ShardID, 0, len(m))
+	for id := range m {
+		ids = append(ids, id)
+	}
+	slices.Sort(ids)
+	return ids
+}
@@ -0,0 +1,248 @@
+package server
+
+import (
+	"context"
+	"io"
+	"log/slog"
+	"net"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/mateenbagheri/memorabilia/api"
+	"github.com/mateenbagheri/memorabilia/pkg/cluster"
+	"github.com/mateenbagheri/memorabilia/pkg/core"
+	"github.com/mateenbagheri/memorabilia/pkg/replication"
+	"github.com/mateenbagheri/memorabilia/pkg/sharding"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/credentials/insecure"
+	"google.golang.org/grpc/status"
+)
+
+// newTestShardHost starts a single-node cluster serving the shard routes on
+// a loopback port, which ShardHost calls while moving slots.
+func newTestShardHost(t *testing.T) *replication.ShardHost {
+	t.Helper()
+	logger := slog.New(slog.NewTextHandler(io.Discard, nil))
+
+	raftAddr := freeAddr(t)
+	httpLis, err := net.Listen("tcp", "127.0.0.1:0")
+	require.NoError(t, err)
+
+	cfg := &cluster.Config{
+		NodeID:             "n1",
+		RaftBindAddr:       raftAddr,
+		HTTPMgmtAddr:       httpLis.Addr().String(),
+		Bootstrap:          true,
+		Storage:            cluster.StorageMemory,
+		DataDir:            t.TempDir(),
+		HeartbeatTimeout:   50 * time.Millisecond,
+		ElectionTimeout:    50 * time.Millisecond,
+		LeaderLeaseTimeout: 50 * time.Millisecond,
+		ShardPortStride:    1,
+	}
+	node, err := replication.NewNode(cfg, replication.NewFSM(core.NewInMemoryCommandRepository()), logger)
+	require.NoError(t, err)
+	host := replication.NewShardHost(node, func() core.CommandsRepository {
+		return core.NewInMemoryCommandRepository()
+	})
+
+	mux := http.NewServeMux()
+	NewShardHTTPHandler(host, logger).RegisterRoutes(mux)
+	srv := &http.Server{Handler: mux}
+	go srv.Serve(httpLis)
+
+	t.Cleanup(func() {
+		srv.Close()
+		host.Shutdown()
+		node.Shutdown()
+	})
+
+	require.Eventually(t, func() bool {
+		_, ok := node.NodeMeta("n1")
+		return ok && node.Readiness() == nil
+	}, 5*time.Second, 10*time.Millisecond)
+	return host
+}
+
+func freeAddr(t *testing.T) string {
+	t.Helper()
+	l, err := net.Listen("tcp", "127.0.0.1:0")
+	require.NoError(t, err)
+	addr := l.Addr().String()
+	require.NoError(t, l.Close())
+	return addr
+}
+
+func TestShardRouter_AddShardMovesKeys(t *testing.T) {
+	host := newTestShardHost(t)
+	router := NewShardRouter(host)
+	defer router.Close()
+	ctx := context.Background()
+
+	for _, key := range []string{"a", "b", "c"} {
+		_, err := router.Set(ctx, &api.SetRequest{Id: key, Value: "v-" + key})
+		require.NoError(t, err)
+	}
+
+	moved := sharding.SlotOf("a")
+	require.NoError(t, host.AddShard(ctx, 1, []string{"n1"}, []int{moved}))
+
+	m := host.Map()
+	require.NotNil(t, m)
+	assert.Equal(t, sharding.ShardID(1), m.ShardFor("a"))
+	assert.Empty(t, m.Migrating)
+
+	_, shardFSM, ok := host.Group(1)
+	require.True(t, ok)
+	got, err := shardFSM.Repository().Get(ctx, "a")
+	require.NoError(t, err, "the key was copied to the new shard")
+	assert.Equal(t, "v-a", got)
+	_, metaFSM, _ := host.Group(sharding.MetaShard)
+	_, err = metaFSM.Repository().Get(ctx, "a")
+	assert.Error(t, err, "and dropped from the old one")
+
+	resp, err := router.Get(ctx, &api.GetRequest{Id: "a"})
+	require.NoError(t, err)
+	assert.Equal(t, "v-a", resp.GetValue())
+
+	_, err = router.Set(ctx, &api.SetRequest{Id: "a", Value: "new"})
+	require.NoError(t, err)
+
+	// Scan pages merge across shards, in order.
+	var keys []string
+	req := &api.ScanRequest{Count: 1}
+	for {
+		page, err := router.Scan(ctx, req)
+		require.NoError(t, err)
+		keys = append(keys, page.GetIds()...)
+		if req.Cursor = page.GetNextCursor(); req.Cursor == "" {
+			break
+		}
+	}
+	assert.Equal(t, []string{"a", "b", "c"}, keys)
+
+	del, err := router.BatchDelete(ctx, &api.BatchDeleteRequest{Ids: []string{"a", "b"}})
+	require.NoError(t, err)
+	assert.Equal(t, int64(2), del.GetDeleteCount())
+}
+
+func TestShardRouter_RefusesWritesToMigratingSlot(t *testing.T) {
+	host := newTestShardHost(t)
+	router := NewShardRouter(host)
+	defer router.Close()
+
+	m, err := sharding.NewMap(1).AddShard(1, []string{"n1"})
+	require.NoError(t, err)
+	m, err = m.BeginMove([]int{sharding.SlotOf("k")}, 1)
+	require.NoError(t, err)
+	m.Version = 1
+	_, err = host.Meta().Apply(&replication.RaftCommand{Op: replication.OpSetShardMap, ShardMap: m})
+	require.NoError(t, err)
+
+	_, err = router.Set(context.Background(), &api.SetRequest{Id: "k", Value: "v"})
+	assert.Equal(t, codes.Unavailable, status.Code(err))
+	assert.Equal(t, ReasonSlotMigrating, errorReason(err))
+
+	_, err = router.Get(context.Background(), &api.GetRequest{Id: "k"})
+	assert.Equal(t, codes.NotFound, status.Code(err), "reads are still served by the owner")
+}
+
+func TestShardRouter_Subscribe(t *testing.T) {
+	host := newTestShardHost(t)
+	router := NewShardRouter(host)
+	defer router.Close()
+
+	lis, err := net.Listen("tcp", "127.0.0.1:0")
+	require.NoError(t, err)
+	s := grpc.NewServer()
+	api.RegisterCommandsServer(s, router)
+	go s.Serve(lis)
+	defer s.Stop()
+	conn, err := grpc.NewClient(lis.Addr().String(), grpc.WithTransportCredentials(insecure.NewCredentials()))
+	require.NoError(t, err)
+	defer conn.Close()
+	c := api.NewCommandsClient(conn)
+
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+	_, err = router.Set(ctx, &api.SetRequest{Id: "k", Value: "v"})
+	require.NoError(t, err)
+
+	stream, err := c.Subscribe(ctx, &api.SubscribeRequest{FromIndex: 1})
+	require.NoError(t, err)
+	event, err := stream.Recv()
+	require.NoError(t, err)
+	assert.Equal(t, api.ChangeOp_CHANGE_OP_SET, event.GetOp())
+	assert.Equal(t, "k", event.GetId())
+	assert.NotZero(t, event.GetIndex())
+	assert.NotZero(t, event.GetTerm())
+
+	stream, err = c.Subscribe(ctx, &api.SubscribeRequest{Shard: 7})
+	require.NoError(t, err)
+	_, err = stream.Recv()
+	assert.Equal(t, codes.InvalidArgument, status.Code(err))
+}
+
+func TestShardHTTP_BackupAndRestoreShard(t *testing.T) {
+	host := newTestShardHost(t)
+	router := NewShardRouter(host)
+	defer router.Close()
+	ctx := context.Background()
+
+	for _, key := range []string{"a", "b"} {
+		_, err := router.Set(ctx, &api.SetRequest{Id: key, Value: "v-" + key})
+		require.NoError(t, err)
+	}
+	require.NoError(t, host.AddShard(ctx, 1, []string{"n1"}, []int{sharding.SlotOf("a")}))
+
+	mux := http.NewServeMux()
+	NewShardHTTPHandler(host, slog.New(slog.NewTextHandler(io.Discard, nil))).RegisterRoutes(mux)
+	serve := func(method, target string, body io.Reader) *httptest.ResponseRecorder {
+		rec := httptest.NewRecorder()
+		mux.ServeHTTP(rec, httptest.NewRequest(method, target, body))
+		return rec
+	}
+
+	shardBackup := serve(http.MethodGet, "/shards/backup?shard=1", nil)
+	require.Equal(t, http.StatusOK, shardBackup.Code, shardBackup.Body.String())
+	assert.NotEmpty(t, shardBackup.Header().Get(replication.SnapshotIndexHeader))
+	metaBackup := serve(http.MethodGet, "/shards/backup?shard=0", nil)
+	require.Equal(t, http.StatusOK, metaBackup.Code, metaBackup.Body.String())
+
+	_, err := router.Set(ctx, &api.SetRequest{Id: "a", Value: "changed"})
+	require.NoError(t, err)
+
+	rec := serve(http.MethodPost, "/shards/restore?shard=1", shardBackup.Body)
+	require.Equal(t, http.StatusOK, rec.Code, rec.Body.String())
+	resp, err := router.Get(ctx, &api.GetRequest{Id: "a"})
+	require.NoError(t, err)
+	assert.Equal(t, "v-a", resp.GetValue())
+
+	// The metadata group's backup holds b, whose slot shard 1 doesn't own.
+	rec = serve(http.MethodPost, "/shards/restore?shard=1", metaBackup.Body)
+	require.Equal(t, http.StatusOK, rec.Code, rec.Body.String())
+	_, shardFSM, _ := host.Group(1)
+	_, err = shardFSM.Repository().Get(ctx, "b")
+	assert.Error(t, err, "keys of other shards are left out")
+
+	assert.Equal(t, http.StatusNotFound, serve(http.MethodGet, "/shards/backup?shard=7", nil).Code)
+	assert.Equal(t, http.StatusBadRequest, serve(http.MethodGet, "/shards/backup", nil).Code)
+	assert.Equal(t, http.StatusBadRequest, serve(http.MethodPost, "/shards/restore?shard=1", strings.NewReader("junk")).Code)
+}
+
+func TestShardHost_RemoveNode(t *testing.T) {
+	host := newTestShardHost(t)
+	require.NoError(t, host.RemoveNode("n9"), "a node in no shard has nothing to leave")
+
+	require.NoError(t, host.AddShard(context.Background(), 1, []string{"n1"}, nil))
+	version := host.Map().Version
+	err := host.RemoveNode("n1")
+	assert.ErrorIs(t, err, replication.ErrInvalidShardChange, "n1 is shard 1's only member")
+	assert.Equal(t, version, host.Map().Version)
+}
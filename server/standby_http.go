@@ -0,0 +1,61 @@
+package server
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/http"
+
+	"github.com/mateenbagheri/memorabilia/pkg/standby"
+)
+
+// StandbyHTTPHandler exposes the standby agent of a node following a primary
+// cluster:
+//
+//	GET /standby  return the agent's standby.Status as JSON
+//	GET /metrics  return the same figures in the Prometheus text format
+//
+// Only the standby's leader replicates, so read its figures for the lag; the
+// other members report state "idle" and the position they have applied.
+type StandbyHTTPHandler struct {
+	agent *standby.Agent
+}
+
+// NewStandbyHTTPHandler constructs a handler for agent.
+func NewStandbyHTTPHandler(agent *standby.Agent) *StandbyHTTPHandler {
+	return &StandbyHTTPHandler{agent: agent}
+}
+
+// RegisterRoutes registers the standby routes on mux.
+func (h *StandbyHTTPHandler) RegisterRoutes(mux *http.ServeMux) {
+	mux.HandleFunc("/standby", h.handleStatus)
+	mux.HandleFunc("/metrics", h.handleMetrics)
+}
+
+func (h *StandbyHTTPHandler) handleStatus(w http.ResponseWriter, r *http.Request) {
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(h.agent.Status())
+}
+
+func (h *StandbyHTTPHandler) handleMetrics(w http.ResponseWriter, r *http.Request) {
+	s := h.agent.Status()
+	following := 0
+	if s.State == standby.StateFollowing {
+		following = 1
+	}
+
+	w.Header().Set("Content-Type", "text/plain; version=0.0.4")
+	for _, m := range []struct {
+		name, kind, help string
+		value            any
+	}{
+		{"memorabilia_standby_following", "gauge", "Whether this node is replicating the primary's writes.", following},
+		{"memorabilia_standby_position", "gauge", "Primary log index the standby has applied every write up to.", s.Position},
+		{"memorabilia_standby_primary_index", "gauge", "Latest log index the primary is known to have applied.", s.PrimaryIndex},
+		{"memorabilia_standby_lag_entries", "gauge", "Primary log entries the standby is behind by.", s.LagEntries},
+		{"memorabilia_standby_lag_seconds", "gauge", "Seconds since the standby last had every write of the primary; -1 until it first has.", s.LagSeconds},
+		{"memorabilia_standby_applied_writes_total", "counter", "Writes of the primary applied by this node.", s.AppliedWrites},
+		{"memorabilia_standby_resyncs_total", "counter", "Backups of the primary restored by this node.", s.Resyncs},
+	} {
+		fmt.Fprintf(w, "# HELP %s %s\n# TYPE %s %s\n%s %v\n", m.name, m.help, m.name, m.kind, m.name, m.value)
+	}
+}
@@ -0,0 +1,140 @@
+package server
+
+import (
+	"context"
+	"encoding/json"
+	"io"
+	"log/slog"
+	"net"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/mateenbagheri/memorabilia/api"
+	"github.com/mateenbagheri/memorabilia/pkg/cluster"
+	"github.com/mateenbagheri/memorabilia/pkg/core"
+	"github.com/mateenbagheri/memorabilia/pkg/replication"
+	"github.com/mateenbagheri/memorabilia/pkg/standby"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"google.golang.org/grpc"
+)
+
+// newTestCluster starts a single-node cluster keeping trailingLogs log
+// entries after a snapshot.
+func newTestCluster(t *testing.T, trailingLogs uint64) (*replication.Node, *replication.FSM) {
+	t.Helper()
+	cfg := &cluster.Config{
+		NodeID:             "n1",
+		RaftBindAddr:       freeAddr(t),
+		Bootstrap:          true,
+		Storage:            cluster.StorageMemory,
+		HeartbeatTimeout:   50 * time.Millisecond,
+		ElectionTimeout:    50 * time.Millisecond,
+		LeaderLeaseTimeout: 50 * time.Millisecond,
+		TrailingLogs:       trailingLogs,
+	}
+	fsm := replication.NewFSM(core.NewInMemoryCommandRepository())
+	node, err := replication.NewNode(cfg, fsm, slog.New(slog.NewTextHandler(io.Discard, nil)))
+	require.NoError(t, err)
+	t.Cleanup(func() { node.Shutdown() })
+	require.Eventually(t, func() bool { return node.Readiness() == nil }, 5*time.Second, 10*time.Millisecond)
+	return node, fsm
+}
+
+func TestStandby_FollowsPrimary(t *testing.T) {
+	logger := slog.New(slog.NewTextHandler(io.Discard, nil))
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+	defer cancel()
+
+	// The primary serves Subscribe over gRPC and its backups over HTTP.
+	primary, primaryFSM := newTestCluster(t, 1)
+	lis, err := net.Listen("tcp", "127.0.0.1:0")
+	require.NoError(t, err)
+	grpcServer := grpc.NewServer()
+	api.RegisterCommandsServer(grpcServer, NewCommandServerWithRaft(primaryFSM, primary))
+	go grpcServer.Serve(lis)
+	defer grpcServer.Stop()
+	mux := http.NewServeMux()
+	NewRaftHTTPHandler(primary, logger).RegisterRoutes(mux)
+	mgmt := httptest.NewServer(mux)
+	defer mgmt.Close()
+
+	// Compact the primary's log, so the standby starts from a backup.
+	_, err = primary.Apply(&replication.RaftCommand{Op: replication.OpSet, Key: "before", Value: "1"})
+	require.NoError(t, err)
+	_, err = primary.Snapshot()
+	require.NoError(t, err)
+
+	node, fsm := newTestCluster(t, 0)
+	agent, err := standby.NewAgent(standby.Config{
+		PrimaryAddr:     lis.Addr().String(),
+		PrimaryMgmtAddr: strings.TrimPrefix(mgmt.URL, "http://"),
+	}, node, fsm, logger)
+	require.NoError(t, err)
+	defer agent.Close()
+	agentCtx, stop := context.WithCancel(ctx)
+	done := make(chan struct{})
+	go func() {
+		defer close(done)
+		agent.Run(agentCtx)
+	}()
+	defer func() {
+		stop()
+		<-done
+	}()
+
+	require.Eventually(t, func() bool {
+		s := agent.Status()
+		return s.State == standby.StateFollowing && s.Resyncs == 1
+	}, 5*time.Second, 10*time.Millisecond)
+	value, err := fsm.Repository().Get(ctx, "before")
+	require.NoError(t, err)
+	assert.Equal(t, "1", value)
+
+	_, err = primary.Apply(&replication.RaftCommand{Op: replication.OpSet, Key: "after", Value: "2", Expiration: time.Now().Add(time.Hour)})
+	require.NoError(t, err)
+	_, err = primary.Apply(&replication.RaftCommand{Op: replication.OpDelete, Key: "before"})
+	require.NoError(t, err)
+
+	require.Eventually(t, func() bool {
+		value, err := fsm.Repository().Get(ctx, "after")
+		if err != nil || value != "2" {
+			return false
+		}
+		_, err = fsm.Repository().Get(ctx, "before")
+		return err != nil
+	}, 5*time.Second, 10*time.Millisecond)
+	ttl, err := fsm.Repository().TTL(ctx, "after")
+	require.NoError(t, err)
+	assert.Greater(t, ttl, 59*time.Minute)
+
+	require.Eventually(t, func() bool {
+		s := agent.Status()
+		return s.State == standby.StateFollowing && s.LagEntries == 0 && s.LagSeconds >= 0
+	}, 5*time.Second, 10*time.Millisecond)
+
+	mux = http.NewServeMux()
+	NewStandbyHTTPHandler(agent).RegisterRoutes(mux)
+
+	rec := httptest.NewRecorder()
+	mux.ServeHTTP(rec, httptest.NewRequest(http.MethodGet, "/standby", nil))
+	require.Equal(t, http.StatusOK, rec.Code)
+	var status standby.Status
+	require.NoError(t, json.NewDecoder(rec.Body).Decode(&status))
+	assert.Equal(t, uint64(1), status.Resyncs)
+	assert.Equal(t, uint64(2), status.AppliedWrites)
+	// Progress confirmations aren't written to the log; only applied writes
+	// move the position kept there.
+	assert.NotZero(t, fsm.StandbyPosition())
+	assert.LessOrEqual(t, fsm.StandbyPosition(), status.Position)
+
+	rec = httptest.NewRecorder()
+	mux.ServeHTTP(rec, httptest.NewRequest(http.MethodGet, "/metrics", nil))
+	require.Equal(t, http.StatusOK, rec.Code)
+	assert.Contains(t, rec.Body.String(), "memorabilia_standby_following 1\n")
+	assert.Contains(t, rec.Body.String(), "memorabilia_standby_resyncs_total 1\n")
+	assert.Contains(t, rec.Body.String(), "memorabilia_standby_lag_entries 0\n")
+}
@@ -0,0 +1,461 @@
+package server
+
+import (
+	"context"
+	"time"
+
+	"github.com/mateenbagheri/memorabilia/api"
+	"github.com/mateenbagheri/memorabilia/pkg/replication"
+	"github.com/mateenbagheri/memorabilia/pkg/types"
+)
+
+// XAdd appends an entry to the stream. The FSM picks the ID of an entry added
+// without one from the command's replicated timestamp, so every node agrees
+// on it.
+func (cs *CommandServer) XAdd(ctx context.Context, in *api.XAddRequest) (*api.XAddResponse, error) {
+	if in.GetId() == "" {
+		return nil, invalidArgument("xadd", "id", "must not be empty")
+	}
+	if len(in.GetFields()) == 0 {
+		return nil, invalidArgument("xadd", "fields", "must not be empty")
+	}
+	if id := in.GetEntryId(); id != "" && id != "*" {
+		if err := validateStreamID("xadd", "entry_id", id); err != nil {
+			return nil, err
+		}
+	}
+	if err := validateStreamTrim("xadd", in.GetTrim()); err != nil {
+		return nil, err
+	}
+	resp, err := cs.applyCommand(ctx, "xadd", in.GetId(), &replication.RaftCommand{
+		Op:     replication.OpStreamAdd,
+		Key:    in.GetId(),
+		Value:  in.GetEntryId(),
+		Fields: in.GetFields(),
+		MaxLen: in.GetTrim().GetMaxLen(),
+		MinID:  in.GetTrim().GetMinId(),
+	})
+	if err != nil {
+		return nil, err
+	}
+	return &api.XAddResponse{EntryId: resp.Value}, nil
+}
+
+// XRange reads the stream from the local replica. There are no blocking
+// reads: consumers waiting for new entries poll, passing the last ID they saw
+// as an exclusive start.
+func (cs *CommandServer) XRange(ctx context.Context, in *api.XRangeRequest) (*api.StreamEntriesResponse, error) {
+	if in.GetId() == "" {
+		return nil, invalidArgument("xrange", "id", "must not be empty")
+	}
+	if in.GetCount() < 0 {
+		return nil, invalidArgument("xrange", "count", "must not be negative")
+	}
+	if err := cs.checkStaleness(in.GetMaxStalenessMs()); err != nil {
+		return nil, err
+	}
+	start, end := in.GetStart(), in.GetEnd()
+	if start == "" {
+		start = "-"
+	}
+	if end == "" {
+		end = "+"
+	}
+	entries, err := cs.repo.XRange(ctx, in.GetId(), start, end, in.GetCount(), in.GetReverse())
+	if err != nil {
+		return nil, repoError("xrange", in.GetId(), err)
+	}
+	return &api.StreamEntriesResponse{Entries: streamEntries(entries)}, nil
+}
+
+func (cs *CommandServer) XLen(ctx context.Context, in *api.XLenRequest) (*api.XLenResponse, error) {
+	if in.GetId() == "" {
+		return nil, invalidArgument("xlen", "id", "must not be empty")
+	}
+	if err := cs.checkStaleness(in.GetMaxStalenessMs()); err != nil {
+		return nil, err
+	}
+	length, err := cs.repo.XLen(ctx, in.GetId())
+	if err != nil {
+		return nil, repoError("xlen", in.GetId(), err)
+	}
+	return &api.XLenResponse{Length: length}, nil
+}
+
+func (cs *CommandServer) XTrim(ctx context.Context, in *api.XTrimRequest) (*api.XTrimResponse, error) {
+	if in.GetId() == "" {
+		return nil, invalidArgument("xtrim", "id", "must not be empty")
+	}
+	if in.GetTrim().GetMaxLen() == 0 && in.GetTrim().GetMinId() == "" {
+		return nil, invalidArgument("xtrim", "trim", "must set max_len or min_id")
+	}
+	if err := validateStreamTrim("xtrim", in.GetTrim()); err != nil {
+		return nil, err
+	}
+	resp, err := cs.applyCommand(ctx, "xtrim", in.GetId(), &replication.RaftCommand{
+		Op:     replication.OpStreamTrim,
+		Key:    in.GetId(),
+		MaxLen: in.GetTrim().GetMaxLen(),
+		MinID:  in.GetTrim().GetMinId(),
+	})
+	if err != nil {
+		return nil, err
+	}
+	return &api.XTrimResponse{Trimmed: resp.Count}, nil
+}
+
+func (cs *CommandServer) XGroupCreate(ctx context.Context, in *api.XGroupCreateRequest) (*api.XGroupCreateResponse, error) {
+	if in.GetId() == "" {
+		return nil, invalidArgument("xgroup create", "id", "must not be empty")
+	}
+	if in.GetGroup() == "" {
+		return nil, invalidArgument("xgroup create", "group", "must not be empty")
+	}
+	start := in.GetStart()
+	if start == "" {
+		start = "$"
+	}
+	if start != "$" {
+		if err := validateStreamID("xgroup create", "start", start); err != nil {
+			return nil, err
+		}
+	}
+	resp, err := cs.applyCommand(ctx, "xgroup create", in.GetId(), &replication.RaftCommand{
+		Op:    replication.OpStreamCreateGroup,
+		Key:   in.GetId(),
+		Group: in.GetGroup(),
+		Value: start,
+	})
+	if err != nil {
+		return nil, err
+	}
+	return &api.XGroupCreateResponse{Created: resp.Applied}, nil
+}
+
+// XReadGroup is a write even when it only rereads pending entries, so that
+// it goes through the leader and sees every delivery made so far.
+func (cs *CommandServer) XReadGroup(ctx context.Context, in *api.XReadGroupRequest) (*api.StreamEntriesResponse, error) {
+	if err := validateGroupRequest("xreadgroup", in.GetId(), in.GetGroup()); err != nil {
+		return nil, err
+	}
+	if in.GetConsumer() == "" {
+		return nil, invalidArgument("xreadgroup", "consumer", "must not be empty")
+	}
+	if in.GetCount() < 0 {
+		return nil, invalidArgument("xreadgroup", "count", "must not be negative")
+	}
+	after := in.GetAfter()
+	if after == "" {
+		after = ">"
+	}
+	if after != ">" {
+		if err := validateStreamID("xreadgroup", "after", after); err != nil {
+			return nil, err
+		}
+	}
+	resp, err := cs.applyCommand(ctx, "xreadgroup", in.GetId(), &replication.RaftCommand{
+		Op:       replication.OpStreamReadGroup,
+		Key:      in.GetId(),
+		Group:    in.GetGroup(),
+		Consumer: in.GetConsumer(),
+		Value:    after,
+		Count:    in.GetCount(),
+	})
+	if err != nil {
+		return nil, err
+	}
+	return &api.StreamEntriesResponse{Entries: streamEntries(resp.StreamEntries)}, nil
+}
+
+func (cs *CommandServer) XAck(ctx context.Context, in *api.XAckRequest) (*api.XAckResponse, error) {
+	if err := validateGroupRequest("xack", in.GetId(), in.GetGroup()); err != nil {
+		return nil, err
+	}
+	if len(in.GetEntryIds()) == 0 {
+		return nil, invalidArgument("xack", "entry_ids", "must not be empty")
+	}
+	if err := validateStreamID("xack", "entry_ids", in.GetEntryIds()...); err != nil {
+		return nil, err
+	}
+	resp, err := cs.applyCommand(ctx, "xack", in.GetId(), &replication.RaftCommand{
+		Op:     replication.OpStreamAck,
+		Key:    in.GetId(),
+		Group:  in.GetGroup(),
+		Values: in.GetEntryIds(),
+	})
+	if err != nil {
+		return nil, err
+	}
+	return &api.XAckResponse{Acked: resp.Count}, nil
+}
+
+// XClaim hands idle pending entries over. The FSM measures idleness on the
+// cluster clock, so every node claims the same entries.
+func (cs *CommandServer) XClaim(ctx context.Context, in *api.XClaimRequest) (*api.StreamEntriesResponse, error) {
+	if err := validateGroupRequest("xclaim", in.GetId(), in.GetGroup()); err != nil {
+		return nil, err
+	}
+	if in.GetConsumer() == "" {
+		return nil, invalidArgument("xclaim", "consumer", "must not be empty")
+	}
+	if in.GetMinIdleMs() < 0 {
+		return nil, invalidArgument("xclaim", "min_idle_ms", "must not be negative")
+	}
+	if in.GetCount() < 0 {
+		return nil, invalidArgument("xclaim", "count", "must not be negative")
+	}
+	if err := validateStreamID("xclaim", "entry_ids", in.GetEntryIds()...); err != nil {
+		return nil, err
+	}
+	resp, err := cs.applyCommand(ctx, "xclaim", in.GetId(), &replication.RaftCommand{
+		Op:       replication.OpStreamClaim,
+		Key:      in.GetId(),
+		Group:    in.GetGroup(),
+		Consumer: in.GetConsumer(),
+		TTL:      time.Duration(in.GetMinIdleMs()) * time.Millisecond,
+		Values:   in.GetEntryIds(),
+		Count:    in.GetCount(),
+	})
+	if err != nil {
+		return nil, err
+	}
+	return &api.StreamEntriesResponse{Entries: streamEntries(resp.StreamEntries)}, nil
+}
+
+func (cs *CommandServer) XPending(ctx context.Context, in *api.XPendingRequest) (*api.XPendingResponse, error) {
+	if err := validateGroupRequest("xpending", in.GetId(), in.GetGroup()); err != nil {
+		return nil, err
+	}
+	if err := cs.checkStaleness(in.GetMaxStalenessMs()); err != nil {
+		return nil, err
+	}
+	pending, err := cs.repo.XPending(ctx, in.GetId(), in.GetGroup())
+	if err != nil {
+		return nil, repoError("xpending", in.GetId(), err)
+	}
+	// Deliveries are stamped with the cluster clock, which follows the
+	// leader's wall clock.
+	now := time.Now()
+	out := make([]*api.PendingEntry, len(pending))
+	for i, p := range pending {
+		out[i] = &api.PendingEntry{
+			EntryId:    p.ID.String(),
+			Consumer:   p.Consumer,
+			IdleMs:     max(now.Sub(p.Delivered), 0).Milliseconds(),
+			Deliveries: p.Deliveries,
+		}
+	}
+	return &api.XPendingResponse{Entries: out}, nil
+}
+
+func validateGroupRequest(op, id, group string) error {
+	if id == "" {
+		return invalidArgument(op, "id", "must not be empty")
+	}
+	if group == "" {
+		return invalidArgument(op, "group", "must not be empty")
+	}
+	return nil
+}
+
+// validateStreamID checks that ids are stream IDs, before their write goes
+// through Raft.
+func validateStreamID(op, field string, ids ...string) error {
+	for _, id := range ids {
+		if _, err := types.ParseStreamID(id); err != nil {
+			return invalidArgument(op, field, `must be "<ms>-<seq>"`)
+		}
+	}
+	return nil
+}
+
+func validateStreamTrim(op string, trim *api.StreamTrim) error {
+	if trim.GetMaxLen() < 0 {
+		return invalidArgument(op, "trim.max_len", "must not be negative")
+	}
+	if trim.GetMinId() != "" {
+		return validateStreamID(op, "trim.min_id", trim.GetMinId())
+	}
+	return nil
+}
+
+func streamEntries(entries []types.StreamEntry) []*api.StreamEntry {
+	out := make([]*api.StreamEntry, len(entries))
+	for i, entry := range entries {
+		out[i] = &api.StreamEntry{EntryId: entry.ID.String(), Fields: entry.Fields}
+	}
+	return out
+}
+
+// -- ShardRouter --
+
+func (r *ShardRouter) XAdd(ctx context.Context, in *api.XAddRequest) (*api.XAddResponse, error) {
+	if in.GetId() == "" {
+		return nil, invalidArgument("xadd", "id", "must not be empty")
+	}
+	cs, shard, err := r.route(ctx, in.GetId(), true)
+	if err != nil {
+		return nil, err
+	}
+	if cs != nil {
+		return cs.XAdd(ctx, in)
+	}
+	var resp *api.XAddResponse
+	err = r.forward(ctx, shard, func(ctx context.Context, c api.CommandsClient) (err error) {
+		resp, err = c.XAdd(ctx, in)
+		return err
+	})
+	return resp, err
+}
+
+func (r *ShardRouter) XRange(ctx context.Context, in *api.XRangeRequest) (*api.StreamEntriesResponse, error) {
+	if in.GetId() == "" {
+		return nil, invalidArgument("xrange", "id", "must not be empty")
+	}
+	cs, shard, err := r.route(ctx, in.GetId(), false)
+	if err != nil {
+		return nil, err
+	}
+	if cs != nil {
+		return cs.XRange(ctx, in)
+	}
+	var resp *api.StreamEntriesResponse
+	err = r.forward(ctx, shard, func(ctx context.Context, c api.CommandsClient) (err error) {
+		resp, err = c.XRange(ctx, in)
+		return err
+	})
+	return resp, err
+}
+
+func (r *ShardRouter) XLen(ctx context.Context, in *api.XLenRequest) (*api.XLenResponse, error) {
+	if in.GetId() == "" {
+		return nil, invalidArgument("xlen", "id", "must not be empty")
+	}
+	cs, shard, err := r.route(ctx, in.GetId(), false)
+	if err != nil {
+		return nil, err
+	}
+	if cs != nil {
+		return cs.XLen(ctx, in)
+	}
+	var resp *api.XLenResponse
+	err = r.forward(ctx, shard, func(ctx context.Context, c api.CommandsClient) (err error) {
+		resp, err = c.XLen(ctx, in)
+		return err
+	})
+	return resp, err
+}
+
+func (r *ShardRouter) XTrim(ctx context.Context, in *api.XTrimRequest) (*api.XTrimResponse, error) {
+	if in.GetId() == "" {
+		return nil, invalidArgument("xtrim", "id", "must not be empty")
+	}
+	cs, shard, err := r.route(ctx, in.GetId(), true)
+	if err != nil {
+		return nil, err
+	}
+	if cs != nil {
+		return cs.XTrim(ctx, in)
+	}
+	var resp *api.XTrimResponse
+	err = r.forward(ctx, shard, func(ctx context.Context, c api.CommandsClient) (err error) {
+		resp, err = c.XTrim(ctx, in)
+		return err
+	})
+	return resp, err
+}
+
+func (r *ShardRouter) XGroupCreate(ctx context.Context, in *api.XGroupCreateRequest) (*api.XGroupCreateResponse, error) {
+	if in.GetId() == "" {
+		return nil, invalidArgument("xgroup create", "id", "must not be empty")
+	}
+	cs, shard, err := r.route(ctx, in.GetId(), true)
+	if err != nil {
+		return nil, err
+	}
+	if cs != nil {
+		return cs.XGroupCreate(ctx, in)
+	}
+	var resp *api.XGroupCreateResponse
+	err = r.forward(ctx, shard, func(ctx context.Context, c api.CommandsClient) (err error) {
+		resp, err = c.XGroupCreate(ctx, in)
+		return err
+	})
+	return resp, err
+}
+
+func (r *ShardRouter) XReadGroup(ctx context.Context, in *api.XReadGroupRequest) (*api.StreamEntriesResponse, error) {
+	if in.GetId() == "" {
+		return nil, invalidArgument("xreadgroup", "id", "must not be empty")
+	}
+	cs, shard, err := r.route(ctx, in.GetId(), true)
+	if err != nil {
+		return nil, err
+	}
+	if cs != nil {
+		return cs.XReadGroup(ctx, in)
+	}
+	var resp *api.StreamEntriesResponse
+	err = r.forward(ctx, shard, func(ctx context.Context, c api.CommandsClient) (err error) {
+		resp, err = c.XReadGroup(ctx, in)
+		return err
+	})
+	return resp, err
+}
+
+func (r *ShardRouter) XAck(ctx context.Context, in *api.XAckRequest) (*api.XAckResponse, error) {
+	if in.GetId() == "" {
+		return nil, invalidArgument("xack", "id", "must not be empty")
+	}
+	cs, shard, err := r.route(ctx, in.GetId(), true)
+	if err != nil {
+		return nil, err
+	}
+	if cs != nil {
+		return cs.XAck(ctx, in)
+	}
+	var resp *api.XAckResponse
+	err = r.forward(ctx, shard, func(ctx context.Context, c api.CommandsClient) (err error) {
+		resp, err = c.XAck(ctx, in)
+		return err
+	})
+	return resp, err
+}
+
+func (r *ShardRouter) XClaim(ctx context.Context, in *api.XClaimRequest) (*api.StreamEntriesResponse, error) {
+	if in.GetId() == "" {
+		return nil, invalidArgument("xclaim", "id", "must not be empty")
+	}
+	cs, shard, err := r.route(ctx, in.GetId(), true)
+	if err != nil {
+		return nil, err
+	}
+	if cs != nil {
+		return cs.XClaim(ctx, in)
+	}
+	var resp *api.StreamEntriesResponse
+	err = r.forward(ctx, shard, func(ctx context.Context, c api.CommandsClient) (err error) {
+		resp, err = c.XClaim(ctx, in)
+		return err
+	})
+	return resp, err
+}
+
+func (r *ShardRouter) XPending(ctx context.Context, in *api.XPendingRequest) (*api.XPendingResponse, error) {
+	if in.GetId() == "" {
+		return nil, invalidArgument("xpending", "id", "must not be empty")
+	}
+	cs, shard, err := r.route(ctx, in.GetId(), false)
+	if err != nil {
+		return nil, err
+	}
+	if cs != nil {
+		return cs.XPending(ctx, in)
+	}
+	var resp *api.XPendingResponse
+	err = r.forward(ctx, shard, func(ctx context.Context, c api.CommandsClient) (err error) {
+		resp, err = c.XPending(ctx, in)
+		return err
+	})
+	return resp, err
+}
@@ -0,0 +1,115 @@
+package server
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/mateenbagheri/memorabilia/api"
+	"github.com/mateenbagheri/memorabilia/pkg/core"
+	"github.com/mateenbagheri/memorabilia/pkg/sharding"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"google.golang.org/grpc/codes"
+)
+
+func entryIDs(entries []*api.StreamEntry) []string {
+	ids := make([]string, len(entries))
+	for i, entry := range entries {
+		ids[i] = entry.GetEntryId()
+	}
+	return ids
+}
+
+func TestCommandServer_Stream(t *testing.T) {
+	cs := NewCommandServer(core.NewInMemoryCommandRepository())
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+
+	var ids []string
+	for i := 0; i < 3; i++ {
+		added, err := cs.XAdd(ctx, &api.XAddRequest{Id: "s", Fields: map[string]string{"n": "1"}})
+		require.NoError(t, err)
+		ids = append(ids, added.GetEntryId())
+	}
+	_, err := cs.XAdd(ctx, &api.XAddRequest{Id: "s", EntryId: "1-1", Fields: map[string]string{"n": "1"}})
+	code, info, _, _ := errorDetails(t, err)
+	assert.Equal(t, codes.InvalidArgument, code)
+	assert.Equal(t, ReasonInvalidArgument, info.GetReason())
+	_, err = cs.XAdd(ctx, &api.XAddRequest{Id: "s", EntryId: "x", Fields: map[string]string{"n": "1"}})
+	code, _, _, _ = errorDetails(t, err)
+	assert.Equal(t, codes.InvalidArgument, code)
+
+	read, err := cs.XRange(ctx, &api.XRangeRequest{Id: "s", Start: "(" + ids[0]})
+	require.NoError(t, err)
+	assert.Equal(t, ids[1:], entryIDs(read.GetEntries()))
+	assert.Equal(t, map[string]string{"n": "1"}, read.GetEntries()[0].GetFields())
+
+	// A group created at "$" only sees the entries added after it.
+	created, err := cs.XGroupCreate(ctx, &api.XGroupCreateRequest{Id: "s", Group: "g"})
+	require.NoError(t, err)
+	assert.True(t, created.GetCreated())
+	delivered, err := cs.XReadGroup(ctx, &api.XReadGroupRequest{Id: "s", Group: "g", Consumer: "c"})
+	require.NoError(t, err)
+	assert.Empty(t, delivered.GetEntries())
+	added, err := cs.XAdd(ctx, &api.XAddRequest{Id: "s", Fields: map[string]string{"n": "2"}, Trim: &api.StreamTrim{MaxLen: 2}})
+	require.NoError(t, err)
+	delivered, err = cs.XReadGroup(ctx, &api.XReadGroupRequest{Id: "s", Group: "g", Consumer: "c"})
+	require.NoError(t, err)
+	assert.Equal(t, []string{added.GetEntryId()}, entryIDs(delivered.GetEntries()))
+	length, err := cs.XLen(ctx, &api.XLenRequest{Id: "s"})
+	require.NoError(t, err)
+	assert.Equal(t, int64(2), length.GetLength())
+
+	pending, err := cs.XPending(ctx, &api.XPendingRequest{Id: "s", Group: "g"})
+	require.NoError(t, err)
+	require.Len(t, pending.GetEntries(), 1)
+	assert.Equal(t, "c", pending.GetEntries()[0].GetConsumer())
+	claimed, err := cs.XClaim(ctx, &api.XClaimRequest{Id: "s", Group: "g", Consumer: "d"})
+	require.NoError(t, err)
+	assert.Equal(t, []string{added.GetEntryId()}, entryIDs(claimed.GetEntries()))
+	acked, err := cs.XAck(ctx, &api.XAckRequest{Id: "s", Group: "g", EntryIds: []string{added.GetEntryId()}})
+	require.NoError(t, err)
+	assert.Equal(t, int64(1), acked.GetAcked())
+
+	_, err = cs.XReadGroup(ctx, &api.XReadGroupRequest{Id: "s", Group: "nope", Consumer: "c"})
+	code, info, _, _ = errorDetails(t, err)
+	assert.Equal(t, codes.NotFound, code)
+	assert.Equal(t, ReasonNoSuchGroup, info.GetReason())
+	_, err = cs.Get(ctx, &api.GetRequest{Id: "s"})
+	code, _, _, _ = errorDetails(t, err)
+	assert.Equal(t, codes.FailedPrecondition, code)
+}
+
+func TestShardRouter_Stream(t *testing.T) {
+	host := newTestShardHost(t)
+	router := NewShardRouter(host)
+	defer router.Close()
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+	defer cancel()
+	require.NoError(t, host.AddShard(ctx, 1, []string{"n1"}, []int{sharding.SlotOf("s")}))
+
+	added, err := router.XAdd(ctx, &api.XAddRequest{Id: "s", Fields: map[string]string{"k": "v"}})
+	require.NoError(t, err)
+	_, err = router.XGroupCreate(ctx, &api.XGroupCreateRequest{Id: "s", Group: "g", Start: "0"})
+	require.NoError(t, err)
+	delivered, err := router.XReadGroup(ctx, &api.XReadGroupRequest{Id: "s", Group: "g", Consumer: "c"})
+	require.NoError(t, err)
+	assert.Equal(t, []string{added.GetEntryId()}, entryIDs(delivered.GetEntries()))
+	acked, err := router.XAck(ctx, &api.XAckRequest{Id: "s", Group: "g", EntryIds: []string{added.GetEntryId()}})
+	require.NoError(t, err)
+	assert.Equal(t, int64(1), acked.GetAcked())
+
+	// Errors of the FSM map like the repository's.
+	_, err = router.XClaim(ctx, &api.XClaimRequest{Id: "s", Group: "nope", Consumer: "c"})
+	code, info, _, _ := errorDetails(t, err)
+	assert.Equal(t, codes.NotFound, code)
+	assert.Equal(t, ReasonNoSuchGroup, info.GetReason())
+	_, err = router.XAdd(ctx, &api.XAddRequest{Id: "s", EntryId: "1", Fields: map[string]string{"k": "v"}})
+	code, _, _, _ = errorDetails(t, err)
+	assert.Equal(t, codes.InvalidArgument, code)
+
+	trimmed, err := router.XTrim(ctx, &api.XTrimRequest{Id: "s", Trim: &api.StreamTrim{MaxLen: 1}})
+	require.NoError(t, err)
+	assert.Zero(t, trimmed.GetTrimmed())
+}
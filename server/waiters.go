@@ -0,0 +1,197 @@
+package server
+
+import (
+	"context"
+	"fmt"
+	"slices"
+	"sync"
+	"time"
+
+	"github.com/mateenbagheri/memorabilia/pkg/replication"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+)
+
+// waitRecheck bounds how long a waiting request goes without looking at the
+// state: nothing wakes it when a job's visibility timeout or a lock's lease
+// runs out, or when this node lost leadership.
+const waitRecheck = time.Second
+
+// keyWaiters holds the requests waiting on keys: blocking pops waiting for an
+// element to be pushed to one of their lists, and locks waiting to be
+// released. Each key has its waiters in the order they arrived; a push wakes
+// as many of the oldest as it pushed elements, and a release the oldest one,
+// so that a single element doesn't send every waiter racing for it and the
+// one waiting the longest gets it.
+type keyWaiters struct {
+	mu     sync.Mutex
+	queues map[string][]*keyWaiter
+}
+
+// keyWaiter is a request waiting on keys. ready is signaled when it is
+// woken.
+type keyWaiter struct {
+	keys  []string
+	woken bool
+	ready chan struct{}
+}
+
+func newKeyWaiters() *keyWaiters {
+	return &keyWaiters{queues: make(map[string][]*keyWaiter)}
+}
+
+// add queues a new waiter on keys, behind those already waiting.
+func (w *keyWaiters) add(keys []string) *keyWaiter {
+	keys = slices.Clone(keys)
+	slices.Sort(keys)
+	waiter := &keyWaiter{keys: slices.Compact(keys), ready: make(chan struct{}, 1)}
+
+	w.mu.Lock()
+	defer w.mu.Unlock()
+	for _, key := range waiter.keys {
+		w.queues[key] = append(w.queues[key], waiter)
+	}
+	return waiter
+}
+
+// remove takes waiter out of the queues for good. When it was woken and
+// gives up without trying, the next waiters are woken in its place.
+func (w *keyWaiters) remove(waiter *keyWaiter) {
+	w.mu.Lock()
+	defer w.mu.Unlock()
+	for _, key := range waiter.keys {
+		queue := w.queues[key]
+		if i := slices.Index(queue, waiter); i >= 0 {
+			queue = slices.Delete(queue, i, i+1)
+		}
+		if len(queue) == 0 {
+			delete(w.queues, key)
+		} else {
+			w.queues[key] = queue
+		}
+		if waiter.woken {
+			w.wakeLocked(key, 1)
+		}
+	}
+}
+
+// wake wakes the n oldest waiters on key that aren't awake already, after n
+// elements were pushed to it or its lock was released.
+func (w *keyWaiters) wake(key string, n int) {
+	w.mu.Lock()
+	defer w.mu.Unlock()
+	w.wakeLocked(key, n)
+}
+
+func (w *keyWaiters) wakeLocked(key string, n int) {
+	for _, waiter := range w.queues[key] {
+		if n == 0 {
+			return
+		}
+		if waiter.woken {
+			continue
+		}
+		waiter.woken = true
+		n--
+		select {
+		case waiter.ready <- struct{}{}:
+		default:
+		}
+	}
+}
+
+// mayTry reports whether waiter may try again: not while a waiter that
+// arrived before it on one of its keys was woken and hasn't tried yet, so
+// that it doesn't take the element or the lock that one was woken for.
+func (w *keyWaiters) mayTry(waiter *keyWaiter) bool {
+	w.mu.Lock()
+	defer w.mu.Unlock()
+	for _, key := range waiter.keys {
+		for _, other := range w.queues[key] {
+			if other == waiter {
+				break
+			}
+			if other.woken {
+				return false
+			}
+		}
+	}
+	return true
+}
+
+// settle marks waiter as done with what it was woken for, whether it got it
+// or not.
+func (w *keyWaiters) settle(waiter *keyWaiter) {
+	w.mu.Lock()
+	defer w.mu.Unlock()
+	waiter.woken = false
+}
+
+// blockingApply runs apply until it is Applied, waiting in between to be
+// woken for one of keys (see keyWaiters). ready reports from this node's copy
+// of the state whether apply may succeed, so that a waiting request doesn't
+// write to the log for nothing. It gives up after timeout, unless it is 0,
+// returning a response that isn't Applied.
+func (cs *CommandServer) blockingApply(
+	ctx context.Context,
+	op string,
+	keys []string,
+	timeout time.Duration,
+	ready func() (bool, error),
+	apply func() (replication.ApplyResponse, error),
+) (replication.ApplyResponse, error) {
+	var expired <-chan time.Time
+	if timeout > 0 {
+		timer := time.NewTimer(timeout)
+		defer timer.Stop()
+		expired = timer.C
+	}
+	recheck := time.NewTicker(waitRecheck)
+	defer recheck.Stop()
+
+	// Queued before looking at the state, so that a write in between wakes
+	// it.
+	waiter := cs.waiters.add(keys)
+	defer cs.waiters.remove(waiter)
+	for {
+		if cs.waiters.mayTry(waiter) {
+			resp, err := cs.applyIfReady(ready, apply)
+			cs.waiters.settle(waiter)
+			if err != nil || resp.Applied {
+				return resp, err
+			}
+		}
+
+		select {
+		case <-waiter.ready:
+		case <-recheck.C:
+		case <-expired:
+			return replication.ApplyResponse{}, nil
+		case <-cs.changes.Done():
+			return replication.ApplyResponse{}, rpcError{
+				code:   codes.Unavailable,
+				reason: ReasonShuttingDown,
+				msg:    fmt.Sprintf("%s: server is shutting down; retry on another node", op),
+			}.err()
+		case <-ctx.Done():
+			return replication.ApplyResponse{}, status.FromContextError(ctx.Err()).Err()
+		}
+	}
+}
+
+// applyIfReady runs apply if ready reports it may succeed.
+func (cs *CommandServer) applyIfReady(ready func() (bool, error), apply func() (replication.ApplyResponse, error)) (replication.ApplyResponse, error) {
+	ok, err := ready()
+	if err != nil {
+		return replication.ApplyResponse{}, err
+	}
+	if ok {
+		return apply()
+	}
+	if cs.isRaftMode() {
+		// Only the leader's writes wake the requests waiting on it; one
+		// waiting on a follower would wait for nothing.
+		return replication.ApplyResponse{}, cs.requireleader()
+	}
+	return replication.ApplyResponse{}, nil
+}
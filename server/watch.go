@@ -0,0 +1,346 @@
+package server
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"slices"
+	"sync"
+	"time"
+
+	"github.com/mateenbagheri/memorabilia/api"
+	"github.com/mateenbagheri/memorabilia/pkg/replication"
+	"github.com/mateenbagheri/memorabilia/pkg/sharding"
+	"google.golang.org/grpc/status"
+	"google.golang.org/protobuf/proto"
+)
+
+// watchFunc runs one watch, sending its responses through send until ctx is
+// done or the watch can't go on. The first response it sends is the created
+// one. It returns nil after sending a canceled response itself, and an error
+// for the caller to report otherwise.
+type watchFunc func(ctx context.Context, in *api.WatchCreateRequest, send func(*api.WatchResponse) error) error
+
+// Watch serves the watches created on stream from this node's changes. The
+// revisions are the indexes of the change log, see Subscribe.
+func (cs *CommandServer) Watch(stream api.Commands_WatchServer) error {
+	return serveWatch(stream, cs.watch, cs.changes.Done())
+}
+
+// Watch serves the watches created on stream, each from a member of the shard
+// it follows: the one owning its key, or the one named in the request for a
+// prefix watch. Revisions are those of that shard's log.
+func (r *ShardRouter) Watch(stream api.Commands_WatchServer) error {
+	_, meta, _ := r.host.Group(sharding.MetaShard)
+	return serveWatch(stream, r.watch, meta.Changes().Done())
+}
+
+// watch runs a watch on this store's changes, see watchFunc. All the changes
+// of a revision are sent in one response.
+func (cs *CommandServer) watch(ctx context.Context, in *api.WatchCreateRequest, send func(*api.WatchResponse) error) error {
+	if in.GetProgressIntervalMs() < 0 {
+		return invalidArgument("watch", "progress_interval_ms", "must not be negative")
+	}
+	interval := time.Duration(in.GetProgressIntervalMs()) * time.Millisecond
+
+	sub := cs.changes.Subscribe(in.GetStartRevision(), in.GetKey())
+	if err := send(&api.WatchResponse{Created: true, Revision: sub.Position()}); err != nil {
+		return err
+	}
+	for {
+		changes, err := cs.nextChanges(ctx, sub, interval)
+		if errors.Is(err, context.DeadlineExceeded) && ctx.Err() == nil {
+			if err := send(&api.WatchResponse{Revision: sub.Position()}); err != nil {
+				return err
+			}
+			continue
+		}
+		if err != nil {
+			return err
+		}
+
+		var resp *api.WatchResponse
+		for _, change := range changes {
+			if change.Snapshot {
+				if resp != nil {
+					if err := send(resp); err != nil {
+						return err
+					}
+				}
+				return send(&api.WatchResponse{
+					Canceled:        true,
+					CancelReason:    fmt.Sprintf("watch: the changes up to revision %d are no longer retained", change.Index),
+					CompactRevision: change.Index + 1,
+				})
+			}
+			events := watchEvents(change, in)
+			if len(events) == 0 {
+				continue
+			}
+			if resp != nil && resp.Revision != change.Index {
+				if err := send(resp); err != nil {
+					return err
+				}
+				resp = nil
+			}
+			if resp == nil {
+				resp = &api.WatchResponse{Revision: change.Index}
+			}
+			resp.Events = append(resp.Events, events...)
+		}
+		if resp != nil {
+			if err := send(resp); err != nil {
+				return err
+			}
+		}
+	}
+}
+
+// watchEvents converts a change to the events of a watch. The change is
+// already limited to keys starting with the watch's key.
+func watchEvents(change replication.Change, in *api.WatchCreateRequest) []*api.WatchEvent {
+	match := func(key string) bool { return in.GetPrefix() || key == in.GetKey() }
+	var events []*api.WatchEvent
+	deleted := func(key string) {
+		if match(key) {
+			events = append(events, &api.WatchEvent{Type: api.WatchEventType_WATCH_EVENT_DELETE, Key: key, Revision: change.Index})
+		}
+	}
+
+	cmd := change.Command
+	switch cmd.Op {
+	case replication.OpSet:
+		if match(cmd.Key) {
+			text, raw := textValue(cmd.SetValue())
+			events = append(events, &api.WatchEvent{
+				Type:        api.WatchEventType_WATCH_EVENT_PUT,
+				Key:         cmd.Key,
+				Value:       text,
+				RawValue:    raw,
+				Revision:    change.Index,
+				ExpiresAtMs: expiresAtMs(cmd),
+			})
+		}
+	case replication.OpPut:
+		if match(cmd.Key) {
+			events = append(events, &api.WatchEvent{
+				Type:        api.WatchEventType_WATCH_EVENT_PUT,
+				Key:         cmd.Key,
+				Entry:       entryJSON(cmd),
+				Revision:    change.Index,
+				ExpiresAtMs: expiresAtMs(cmd),
+			})
+		}
+	case replication.OpDelete:
+		deleted(cmd.Key)
+	case replication.OpBatchDelete:
+		for _, key := range cmd.Keys {
+			deleted(key)
+		}
+	}
+	return events
+}
+
+// watch runs a watch on the shard it follows, see watchFunc. One forwarded to
+// another node carries on from the revision after the last one sent when
+// the member serving it can't be reached.
+func (r *ShardRouter) watch(ctx context.Context, in *api.WatchCreateRequest, send func(*api.WatchResponse) error) error {
+	shard := sharding.ShardID(in.GetShard())
+	if !in.GetPrefix() {
+		shard = r.host.Map().ShardFor(in.GetKey())
+	} else if !slices.Contains(r.host.Map().IDs(), shard) {
+		return invalidArgument("watch", "shard", fmt.Sprintf("%d is not a shard", shard))
+	}
+
+	req := proto.Clone(in).(*api.WatchCreateRequest)
+	req.WatchId = 0
+	created := false
+	return r.onShard(ctx, shard, func(cs *CommandServer) error {
+		return cs.watch(ctx, in, send)
+	}, func(ctx context.Context, c api.CommandsClient) error {
+		stream, err := c.Watch(ctx)
+		if err != nil {
+			return err
+		}
+		create := &api.WatchRequest{Request: &api.WatchRequest_CreateRequest{CreateRequest: req}}
+		if err := stream.Send(create); err != nil {
+			return err
+		}
+		for {
+			resp, err := stream.Recv()
+			if err != nil {
+				return err
+			}
+			if resp.GetCreated() && created {
+				continue
+			}
+			created = created || resp.GetCreated()
+			if err := send(resp); err != nil {
+				return err
+			}
+			if resp.GetCanceled() {
+				return nil
+			}
+			if resp.GetRevision() > 0 {
+				req.StartRevision = resp.GetRevision() + 1
+			}
+		}
+	})
+}
+
+// serveWatch serves a Watch stream, running each watch the client creates
+// with run until the client cancels it or the stream ends. The stream ends
+// with an error once closed is, when this node shuts down.
+func serveWatch(stream api.Commands_WatchServer, run watchFunc, closed <-chan struct{}) error {
+	ctx, cancel := context.WithCancel(stream.Context())
+	w := &watchStream{
+		stream:  stream,
+		run:     run,
+		watches: make(map[int64]*activeWatch),
+	}
+	defer w.wg.Wait()
+	defer cancel()
+
+	reqs := make(chan *api.WatchRequest)
+	recvErr := make(chan error, 1)
+	go func() {
+		for {
+			req, err := stream.Recv()
+			if err != nil {
+				recvErr <- err
+				return
+			}
+			select {
+			case reqs <- req:
+			case <-ctx.Done():
+				return
+			}
+		}
+	}()
+
+	for {
+		select {
+		case req := <-reqs:
+			switch r := req.GetRequest().(type) {
+			case *api.WatchRequest_CreateRequest:
+				w.create(ctx, r.CreateRequest)
+			case *api.WatchRequest_CancelRequest:
+				w.cancel(r.CancelRequest.GetWatchId())
+			}
+		case err := <-recvErr:
+			if !errors.Is(err, io.EOF) {
+				return err
+			}
+			// The client won't create or cancel watches anymore; those it
+			// has carry on.
+			recvErr = nil
+		case <-closed:
+			return subscribeError(replication.ErrChangeLogClosed)
+		case <-ctx.Done():
+			return subscribeError(ctx.Err())
+		}
+	}
+}
+
+// watchStream holds the watches of one Watch stream.
+type watchStream struct {
+	stream api.Commands_WatchServer
+	run    watchFunc
+
+	// sendMu serializes the responses of the watches.
+	sendMu sync.Mutex
+
+	mu      sync.Mutex
+	watches map[int64]*activeWatch
+	lastID  int64
+
+	wg sync.WaitGroup
+}
+
+type activeWatch struct {
+	stop func()
+	done chan struct{}
+
+	// canceled is set when the client canceled the watch.
+	canceled bool
+}
+
+func (w *watchStream) send(resp *api.WatchResponse) error {
+	w.sendMu.Lock()
+	defer w.sendMu.Unlock()
+	return w.stream.Send(resp)
+}
+
+// create starts a watch, or answers with a canceled response when it can't.
+func (w *watchStream) create(ctx context.Context, in *api.WatchCreateRequest) {
+	w.mu.Lock()
+	id := in.GetWatchId()
+	var reason string
+	switch {
+	case id < 0:
+		reason = "watch: watch_id must not be negative"
+	case id == 0:
+		for id = w.lastID + 1; w.watches[id] != nil; id++ {
+		}
+		w.lastID = id
+	case w.watches[id] != nil:
+		reason = fmt.Sprintf("watch: watch %d already exists", id)
+	}
+	if reason == "" && !in.GetPrefix() && in.GetKey() == "" {
+		reason = "watch: key must not be empty unless prefix is set"
+	}
+	if reason != "" {
+		w.mu.Unlock()
+		w.send(&api.WatchResponse{WatchId: id, Canceled: true, CancelReason: reason})
+		return
+	}
+
+	watchCtx, stop := context.WithCancel(ctx)
+	watch := &activeWatch{stop: stop, done: make(chan struct{})}
+	w.watches[id] = watch
+	w.mu.Unlock()
+
+	w.wg.Add(1)
+	go func() {
+		defer w.wg.Done()
+		defer close(watch.done)
+		defer stop()
+
+		err := w.run(watchCtx, in, func(resp *api.WatchResponse) error {
+			resp.WatchId = id
+			return w.send(resp)
+		})
+
+		w.mu.Lock()
+		delete(w.watches, id)
+		canceled := watch.canceled
+		w.mu.Unlock()
+
+		switch {
+		case err == nil, ctx.Err() != nil:
+		case errors.Is(err, replication.ErrChangeLogClosed):
+			// The node is shutting down, which ends the stream.
+		case canceled:
+			w.send(&api.WatchResponse{WatchId: id, Canceled: true})
+		default:
+			w.send(&api.WatchResponse{WatchId: id, Canceled: true, CancelReason: status.Convert(err).Message()})
+		}
+	}()
+}
+
+// cancel stops a watch, once it has sent its last response.
+func (w *watchStream) cancel(id int64) {
+	w.mu.Lock()
+	watch, ok := w.watches[id]
+	if ok {
+		watch.canceled = true
+	}
+	w.mu.Unlock()
+	if !ok {
+		w.send(&api.WatchResponse{WatchId: id, Canceled: true, CancelReason: fmt.Sprintf("watch: no watch %d", id)})
+		return
+	}
+	watch.stop()
+	<-watch.done
+}
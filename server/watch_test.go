@@ -0,0 +1,117 @@
+package server
+
+import (
+	"context"
+	"fmt"
+	"net"
+	"testing"
+	"time"
+
+	"github.com/mateenbagheri/memorabilia/api"
+	"github.com/mateenbagheri/memorabilia/pkg/core"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/credentials/insecure"
+)
+
+func TestCommandServer_Watch(t *testing.T) {
+	cs := NewCommandServer(core.NewInMemoryCommandRepository())
+	lis, err := net.Listen("tcp", "127.0.0.1:0")
+	require.NoError(t, err)
+	s := grpc.NewServer()
+	api.RegisterCommandsServer(s, cs)
+	go s.Serve(lis)
+	defer s.Stop()
+
+	conn, err := grpc.NewClient(lis.Addr().String(), grpc.WithTransportCredentials(insecure.NewCredentials()))
+	require.NoError(t, err)
+	defer conn.Close()
+	c := api.NewCommandsClient(conn)
+
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+	for _, key := range []string{"user:1", "order:1", "user:2"} {
+		_, err = c.Set(ctx, &api.SetRequest{Id: key, Value: "v"})
+		require.NoError(t, err)
+	}
+
+	stream, err := c.Watch(ctx)
+	require.NoError(t, err)
+	create := func(in *api.WatchCreateRequest) {
+		require.NoError(t, stream.Send(&api.WatchRequest{Request: &api.WatchRequest_CreateRequest{CreateRequest: in}}))
+	}
+	recv := func() *api.WatchResponse {
+		resp, err := stream.Recv()
+		require.NoError(t, err)
+		return resp
+	}
+
+	// A key watch resuming from the first revision.
+	create(&api.WatchCreateRequest{Key: "user:1", StartRevision: 1})
+	resp := recv()
+	assert.True(t, resp.GetCreated())
+	assert.Equal(t, int64(1), resp.GetWatchId())
+	resp = recv()
+	require.Len(t, resp.GetEvents(), 1)
+	assert.Equal(t, uint64(1), resp.GetRevision())
+	assert.Equal(t, api.WatchEventType_WATCH_EVENT_PUT, resp.GetEvents()[0].GetType())
+	assert.Equal(t, "user:1", resp.GetEvents()[0].GetKey())
+	assert.Equal(t, "v", resp.GetEvents()[0].GetValue())
+
+	// A prefix watch from now on.
+	create(&api.WatchCreateRequest{Key: "user:", Prefix: true, WatchId: 7})
+	resp = recv()
+	assert.Equal(t, int64(7), resp.GetWatchId())
+	assert.True(t, resp.GetCreated())
+	assert.Equal(t, uint64(3), resp.GetRevision())
+
+	_, err = c.BatchDelete(ctx, &api.BatchDeleteRequest{Ids: []string{"user:1", "order:1", "user:2"}})
+	require.NoError(t, err)
+	byWatch := map[int64]*api.WatchResponse{}
+	for i := 0; i < 2; i++ {
+		resp = recv()
+		byWatch[resp.GetWatchId()] = resp
+	}
+	require.Len(t, byWatch[1].GetEvents(), 1)
+	assert.Equal(t, "user:1", byWatch[1].GetEvents()[0].GetKey())
+	require.Len(t, byWatch[7].GetEvents(), 2)
+	assert.Equal(t, uint64(4), byWatch[7].GetRevision())
+	for _, event := range byWatch[7].GetEvents() {
+		assert.Equal(t, api.WatchEventType_WATCH_EVENT_DELETE, event.GetType())
+		assert.Equal(t, uint64(4), event.GetRevision())
+	}
+
+	create(&api.WatchCreateRequest{Key: "x", WatchId: 7})
+	resp = recv()
+	assert.True(t, resp.GetCanceled())
+	assert.Contains(t, resp.GetCancelReason(), "already exists")
+
+	for _, id := range []int64{1, 7} {
+		require.NoError(t, stream.Send(&api.WatchRequest{Request: &api.WatchRequest_CancelRequest{CancelRequest: &api.WatchCancelRequest{WatchId: id}}}))
+		resp = recv()
+		assert.Equal(t, id, resp.GetWatchId())
+		assert.True(t, resp.GetCanceled())
+		assert.Empty(t, resp.GetCancelReason())
+	}
+
+	// Once the change log has dropped the first writes, resuming from them
+	// is refused.
+	for i := 0; i < 5000; i++ {
+		_, err := cs.Set(ctx, &api.SetRequest{Id: fmt.Sprintf("k%d", i), Value: "v"})
+		require.NoError(t, err)
+	}
+	create(&api.WatchCreateRequest{Key: "user:1", StartRevision: 1, WatchId: 8})
+	assert.True(t, recv().GetCreated())
+	resp = recv()
+	assert.Equal(t, int64(8), resp.GetWatchId())
+	assert.True(t, resp.GetCanceled())
+	assert.Greater(t, resp.GetCompactRevision(), uint64(1))
+
+	cs.changes.Close()
+	_, err = stream.Recv()
+	code, info, _, _ := errorDetails(t, err)
+	assert.Equal(t, codes.Unavailable, code)
+	assert.Equal(t, ReasonShuttingDown, info.GetReason())
+}